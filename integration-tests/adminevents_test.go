@@ -0,0 +1,96 @@
+package integration_tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/admin"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdminEventStream(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {})}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {})}
+	err = testSetup.Start(conf, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	auditLog, err := admin.NewAuditLog("")
+	require.Nil(t, err)
+	api := admin.NewApi(testSetup.Proxy, map[string]common.AdminRole{"token": common.AdminRoleReadOnly}, auditLog)
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/admin/events", nil)
+	require.Nil(t, err)
+	req.Header.Set("Authorization", "Bearer token")
+	rsp, err := http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	defer rsp.Body.Close()
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+	require.Equal(t, "text/event-stream", rsp.Header.Get("Content-Type"))
+
+	events := make(chan *zdmproxy.ProxyEvent, 10)
+	go func() {
+		scanner := bufio.NewScanner(rsp.Body)
+		for scanner.Scan() {
+			if data := strings.TrimPrefix(scanner.Text(), "data: "); data != scanner.Text() {
+				event := &zdmproxy.ProxyEvent{}
+				if json.Unmarshal([]byte(data), event) == nil {
+					events <- event
+				}
+			}
+		}
+	}()
+	nextEvent := func() *zdmproxy.ProxyEvent {
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for event")
+			return nil
+		}
+	}
+
+	testClient := client.NewCqlClient("127.0.0.1:14002", &client.AuthCredentials{
+		Username: conf.TargetUsername,
+		Password: conf.TargetPassword,
+	})
+	conn, err := testClient.ConnectAndInit(context.Background(), primitive.ProtocolVersion4, 1)
+	require.Nil(t, err)
+	defer conn.Close()
+	clientAddr := conn.LocalAddr().String()
+
+	event := nextEvent()
+	require.Equal(t, zdmproxy.ProxyEventClientConnected, event.Type)
+	require.Contains(t, event.Message, clientAddr)
+
+	require.Nil(t, testSetup.Proxy.GetTableReadRouting().Set("ks.t", common.ClusterTypeTarget))
+	event = nextEvent()
+	require.Equal(t, zdmproxy.ProxyEventReadRoutingChanged, event.Type)
+	require.Equal(t, "reads of table ks.t routed to TARGET", event.Message)
+
+	_, err = testSetup.Proxy.DrainClientConnections(clientAddr)
+	require.Nil(t, err)
+	require.Equal(t, zdmproxy.ProxyEventClientDraining, nextEvent().Type)
+	event = nextEvent()
+	require.Equal(t, zdmproxy.ProxyEventClientDisconnected, event.Type)
+	require.Contains(t, event.Message, clientAddr)
+}
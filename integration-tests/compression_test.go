@@ -0,0 +1,100 @@
+package integration_tests
+
+import (
+	"context"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCompression(t *testing.T) {
+	tests := []struct {
+		compression primitive.Compression
+		version     primitive.ProtocolVersion
+	}{
+		{primitive.CompressionLz4, primitive.ProtocolVersion4},
+		{primitive.CompressionSnappy, primitive.ProtocolVersion4},
+		{primitive.CompressionSnappy, primitive.ProtocolVersion3},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%v v%d", tt.compression, tt.version), func(t *testing.T) {
+			testCompression(t, tt.compression, tt.version)
+		})
+	}
+}
+
+func testCompression(t *testing.T, compression primitive.Compression, version primitive.ProtocolVersion) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	targetReads := int32(0)
+	requestHandler := func(cluster string) client.RequestHandler {
+		return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+			switch msg := request.Body.Message.(type) {
+			case *message.Prepare:
+				return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.PreparedResult{
+					PreparedQueryId: []byte(cluster + msg.Query)})
+			case *message.Execute:
+				return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+			case *message.Query:
+				if msg.Query == "SELECT * FROM ks.t" && cluster == "target" {
+					atomic.AddInt32(&targetReads, 1)
+				}
+				return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+			}
+			return nil
+		}
+	}
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+		requestHandler("origin")}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+		requestHandler("target")}
+
+	err = testSetup.Start(conf, false, version)
+	require.Nil(t, err)
+
+	testClient := client.NewCqlClient("127.0.0.1:14002", &client.AuthCredentials{
+		Username: conf.TargetUsername,
+		Password: conf.TargetPassword,
+	})
+	testClient.Compression = compression
+	conn, err := testClient.ConnectAndInit(context.Background(), version, 1)
+	require.Nil(t, err)
+	defer conn.Close()
+
+	// the proxy must decompress the requests to route them, reads are only sent to origin
+	rsp, err := conn.SendAndReceive(frame.NewFrame(version, 2, &message.Query{Query: "SELECT * FROM ks.t"}))
+	require.Nil(t, err)
+	require.Equal(t, primitive.OpCodeResult, rsp.Header.OpCode)
+	require.Equal(t, int32(0), atomic.LoadInt32(&targetReads))
+
+	// the proxy decodes the PREPARED responses of both clusters and translates the prepared id of EXECUTE requests
+	rsp, err = conn.SendAndReceive(frame.NewFrame(version, 3, &message.Prepare{Query: "INSERT INTO ks.t (a) VALUES (?)"}))
+	require.Nil(t, err)
+	prepared, ok := rsp.Body.Message.(*message.PreparedResult)
+	require.True(t, ok, rsp.Body.Message)
+	rsp, err = conn.SendAndReceive(frame.NewFrame(version, 4, &message.Execute{
+		QueryId: prepared.PreparedQueryId, Options: &message.QueryOptions{PositionalValues: []*primitive.Value{primitive.NewValue([]byte{1})}}}))
+	require.Nil(t, err)
+	require.Equal(t, primitive.OpCodeResult, rsp.Header.OpCode)
+	require.IsType(t, &message.VoidResult{}, rsp.Body.Message)
+	require.True(t, rsp.Header.Flags.Contains(primitive.HeaderFlagCompressed))
+
+	// responses generated by the proxy are compressed too
+	for i, query := range []string{"SELECT * FROM system.local", "SELECT * FROM system.peers"} {
+		rsp, err = conn.SendAndReceive(frame.NewFrame(version, int16(5+i), &message.Query{Query: query}))
+		require.Nil(t, err)
+		require.IsType(t, &message.RowsResult{}, rsp.Body.Message, query)
+		require.True(t, rsp.Header.Flags.Contains(primitive.HeaderFlagCompressed), query)
+	}
+}
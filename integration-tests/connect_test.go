@@ -15,8 +15,11 @@ import (
 	"github.com/rs/zerolog"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
+	"io"
+	"net"
 	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestGoCqlConnect(t *testing.T) {
@@ -170,14 +173,14 @@ func TestRequestedProtocolVersionUnsupportedByProxy(t *testing.T) {
 			primitive.ProtocolVersion5,
 			"4",
 			primitive.ProtocolVersion4,
-			"Invalid or unsupported protocol version (5)",
+			"Invalid or unsupported protocol version (5); supported versions are (2/v2, 3/v3, 4/v4)",
 		},
 		{
 			"request v1, response v4",
 			primitive.ProtocolVersion(0x1),
 			"4",
 			primitive.ProtocolVersion4,
-			"Invalid or unsupported protocol version (1)",
+			"Invalid or unsupported protocol version (1); supported versions are (2/v2, 3/v3, 4/v4)",
 		},
 	}
 
@@ -236,7 +239,7 @@ func TestReturnedProtocolVersionUnsupportedByProxy(t *testing.T) {
 			"4",
 			primitive.ProtocolVersion5,
 			primitive.ProtocolVersion4,
-			"Invalid or unsupported protocol version (5)",
+			"Invalid or unsupported protocol version (5); supported versions are (2/v2, 3/v3, 4/v4)",
 		},
 		{
 			"DSE_V2 request, v1 returned, v4 expected",
@@ -244,7 +247,7 @@ func TestReturnedProtocolVersionUnsupportedByProxy(t *testing.T) {
 			"4",
 			primitive.ProtocolVersion(0x01),
 			primitive.ProtocolVersion4,
-			"Invalid or unsupported protocol version (1)",
+			"Invalid or unsupported protocol version (1); supported versions are (2/v2, 3/v3, 4/v4)",
 		},
 	}
 
@@ -335,3 +338,38 @@ func createFrameWithUnsupportedVersion(version primitive.ProtocolVersion, stream
 	encoded[1] = 0
 	return encoded, nil
 }
+
+func TestClientHandshakeTimeout(t *testing.T) {
+	cfg := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	cfg.ProxyClientHandshakeTimeoutMs = 500
+	testSetup, err := setup.NewCqlServerTestSetup(t, cfg, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	testSetup.Origin.CqlServer.RequestHandlers = []cqlClient.RequestHandler{cqlClient.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {})}
+	testSetup.Target.CqlServer.RequestHandlers = []cqlClient.RequestHandler{cqlClient.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {})}
+
+	err = testSetup.Start(cfg, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	// connection that never sends STARTUP is closed by the proxy
+	conn, err := net.Dial("tcp", "127.0.0.1:14002")
+	require.Nil(t, err)
+	defer conn.Close()
+	require.Nil(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, err = conn.Read(make([]byte, 1))
+	require.ErrorIs(t, err, io.EOF)
+
+	// connection that completed the handshake is not affected by the handshake timeout
+	testClient := cqlClient.NewCqlClient("127.0.0.1:14002", &cqlClient.AuthCredentials{
+		Username: cfg.TargetUsername,
+		Password: cfg.TargetPassword,
+	})
+	cqlConn, err := testClient.ConnectAndInit(context.Background(), primitive.ProtocolVersion4, 1)
+	require.Nil(t, err)
+	defer cqlConn.Close()
+	time.Sleep(1 * time.Second)
+	rsp, err := cqlConn.SendAndReceive(frame.NewFrame(primitive.ProtocolVersion4, 2, &message.Options{}))
+	require.Nil(t, err)
+	require.Equal(t, primitive.OpCodeSupported, rsp.Header.OpCode)
+}
@@ -0,0 +1,69 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCounterWriteMode(t *testing.T) {
+	const counterUpdate = "UPDATE ks.tbl SET c = c + 1 WHERE pk = 1"
+	// answers the updates with the provided response and counts them
+	newHandler := func(response message.Message, received *int32) client.RequestHandler {
+		return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+			query, ok := request.Body.Message.(*message.Query)
+			if !ok || !strings.HasPrefix(query.Query, "UPDATE") {
+				return nil
+			}
+			atomic.AddInt32(received, 1)
+			return frame.NewFrame(request.Header.Version, request.Header.StreamId, response)
+		}
+	}
+
+	tests := []struct {
+		name             string
+		mode             string
+		expectedResponse message.Message
+		expectedTarget   int32
+	}{
+		// the OVERLOADED failure of the target cluster is retryable but counter updates are not retried
+		{"dual", config.CounterWriteModeDual, &message.Overloaded{}, 1},
+		{"primary only", config.CounterWriteModePrimaryOnly, &message.VoidResult{}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			conf.CounterWriteMode = tt.mode
+			conf.SecondaryWriteRetryAttempts = 2
+			conf.SecondaryWriteRetryBackoffMs = 10
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			var originReceived, targetReceived int32
+			testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+				newHandler(&message.VoidResult{}, &originReceived),
+				client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {})}
+			testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+				newHandler(&message.Overloaded{ErrorMessage: "overloaded"}, &targetReceived),
+				client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {})}
+
+			err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+
+			response, err := testSetup.Client.CqlConnection.SendAndReceive(
+				frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{Query: counterUpdate}))
+			require.Nil(t, err)
+			require.IsType(t, tt.expectedResponse, response.Body.Message)
+			require.Equal(t, int32(1), atomic.LoadInt32(&originReceived))
+			require.Equal(t, tt.expectedTarget, atomic.LoadInt32(&targetReceived))
+		})
+	}
+}
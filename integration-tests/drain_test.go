@@ -0,0 +1,104 @@
+package integration_tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/integration-tests/utils"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestDrainClientConnection(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	slowQueryHandler := func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		if query, ok := request.Body.Message.(*message.Query); ok && query.Query == "SELECT * FROM ks.slow" {
+			time.Sleep(1 * time.Second)
+			return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+		}
+		return nil
+	}
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}), slowQueryHandler}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}), slowQueryHandler}
+
+	err = testSetup.Start(conf, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+	proxy := testSetup.Proxy
+
+	testClient := client.NewCqlClient("127.0.0.1:14002", &client.AuthCredentials{
+		Username: conf.TargetUsername,
+		Password: conf.TargetPassword,
+	})
+	drainedConn, err := testClient.ConnectAndInit(context.Background(), primitive.ProtocolVersion4, 1)
+	require.Nil(t, err)
+	defer drainedConn.Close()
+	otherConn, err := testClient.ConnectAndInit(context.Background(), primitive.ProtocolVersion4, 1)
+	require.Nil(t, err)
+	defer otherConn.Close()
+
+	drainedAddr := drainedConn.LocalAddr().String()
+	otherAddr := otherConn.LocalAddr().String()
+	utils.RequireWithRetries(t, func() (err error, fatal bool) {
+		if len(proxy.GetClientConnections()) != 2 {
+			return fmt.Errorf("expected 2 client connections but got %v", proxy.GetClientConnections()), false
+		}
+		return nil, false
+	}, 10, 100*time.Millisecond)
+
+	_, err = proxy.DrainClientConnections("127.0.0.1:1")
+	require.Nil(t, err)
+	_, err = proxy.DrainClientConnections("not an address")
+	require.NotNil(t, err)
+
+	slowQuery := frame.NewFrame(primitive.ProtocolVersion4, 2, &message.Query{Query: "SELECT * FROM ks.slow"})
+	inFlightRequest, err := drainedConn.Send(slowQuery)
+	require.Nil(t, err)
+	time.Sleep(200 * time.Millisecond)
+
+	drained, err := proxy.DrainClientConnections(drainedAddr)
+	require.Nil(t, err)
+	require.Equal(t, []string{drainedAddr}, drained)
+
+	// new requests on a draining connection are rejected so that the client retries on another proxy instance
+	rejectedRequest, err := drainedConn.Send(frame.NewFrame(primitive.ProtocolVersion4, 3, slowQuery.Body.Message))
+	require.Nil(t, err)
+	select {
+	case rsp := <-rejectedRequest.Incoming():
+		_, ok := rsp.Body.Message.(*message.Overloaded)
+		require.True(t, ok, rsp.Body.Message)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for the response of the rejected request")
+	}
+
+	// requests that were in flight are completed
+	select {
+	case rsp := <-inFlightRequest.Incoming():
+		require.Equal(t, primitive.OpCodeResult, rsp.Header.OpCode)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for the response of the in flight request")
+	}
+
+	utils.RequireWithRetries(t, func() (err error, fatal bool) {
+		if !drainedConn.IsClosed() {
+			return errors.New("expected drained connection to be closed"), false
+		}
+		return nil, false
+	}, 20, 100*time.Millisecond)
+	require.Equal(t, []string{otherAddr}, proxy.GetClientConnections())
+
+	rsp, err := otherConn.SendAndReceive(frame.NewFrame(primitive.ProtocolVersion4, 2, slowQuery.Body.Message))
+	require.Nil(t, err)
+	require.Equal(t, primitive.OpCodeResult, rsp.Header.OpCode)
+}
@@ -0,0 +1,74 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestDualWriteFailureMode(t *testing.T) {
+	const (
+		originFailure = "INSERT INTO ks.tbl (pk) VALUES (1)"
+		targetFailure = "INSERT INTO ks.tbl (pk) VALUES (2)"
+	)
+	// fails the writes in failedWrite with INVALID
+	newHandler := func(failedWrite string) client.RequestHandler {
+		return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+			query, ok := request.Body.Message.(*message.Query)
+			if !ok || !strings.HasPrefix(query.Query, "INSERT") {
+				return nil
+			}
+			var response message.Message = &message.VoidResult{}
+			if query.Query == failedWrite {
+				response = &message.Invalid{ErrorMessage: "invalid"}
+			}
+			return frame.NewFrame(request.Header.Version, request.Header.StreamId, response)
+		}
+	}
+
+	tests := []struct {
+		name                  string
+		mode                  string
+		expectedOriginFailure message.Message
+		expectedTargetFailure message.Message
+	}{
+		{"either", config.DualWriteFailureModeEither, &message.Invalid{}, &message.Invalid{}},
+		{"origin", config.DualWriteFailureModeOrigin, &message.Invalid{}, &message.VoidResult{}},
+		{"target", config.DualWriteFailureModeTarget, &message.VoidResult{}, &message.Invalid{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			conf.DualWriteFailureMode = tt.mode
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+				newHandler(originFailure),
+				client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {})}
+			testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+				newHandler(targetFailure),
+				client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {})}
+
+			err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+
+			response, err := testSetup.Client.CqlConnection.SendAndReceive(
+				frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{Query: originFailure}))
+			require.Nil(t, err)
+			require.IsType(t, tt.expectedOriginFailure, response.Body.Message)
+
+			response, err = testSetup.Client.CqlConnection.SendAndReceive(
+				frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{Query: targetFailure}))
+			require.Nil(t, err)
+			require.IsType(t, tt.expectedTargetFailure, response.Body.Message)
+		})
+	}
+}
@@ -3,17 +3,163 @@ package integration_tests
 import (
 	"context"
 	"fmt"
+	cqlClient "github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
 	"github.com/datastax/go-cassandra-native-protocol/message"
 	"github.com/datastax/go-cassandra-native-protocol/primitive"
 	"github.com/datastax/zdm-proxy/integration-tests/ccm"
 	"github.com/datastax/zdm-proxy/integration-tests/client"
+	"github.com/datastax/zdm-proxy/integration-tests/cqlserver"
 	"github.com/datastax/zdm-proxy/integration-tests/env"
 	"github.com/datastax/zdm-proxy/integration-tests/setup"
 	"github.com/stretchr/testify/require"
+	"net"
 	"testing"
 	"time"
 )
 
+// TestEventForwarding tests that ZDM_EVENT_FORWARDING selects the cluster whose events are forwarded and that only the
+// event types that the client registered for are forwarded
+func TestEventForwarding(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.EventForwarding = "SCHEMA_CHANGE=TARGET"
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+	testSetup.Origin.CqlServer.RequestHandlers = []cqlClient.RequestHandler{
+		cqlClient.RegisterHandler, cqlClient.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {})}
+	testSetup.Target.CqlServer.RequestHandlers = []cqlClient.RequestHandler{
+		cqlClient.RegisterHandler, cqlClient.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {})}
+
+	err = testSetup.Start(conf, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	testClient := cqlClient.NewCqlClient("127.0.0.1:14002", &cqlClient.AuthCredentials{
+		Username: conf.TargetUsername,
+		Password: conf.TargetPassword,
+	})
+	testClient.ReadTimeout = 500 * time.Millisecond
+	conn, err := testClient.ConnectAndInit(context.Background(), primitive.ProtocolVersion4, 1)
+	require.Nil(t, err)
+	defer conn.Close()
+
+	response, err := conn.SendAndReceive(frame.NewFrame(primitive.ProtocolVersion4, 1, &message.Register{
+		EventTypes: []primitive.EventType{primitive.EventTypeSchemaChange}}))
+	require.Nil(t, err)
+	require.IsType(t, &message.Ready{}, response.Body.Message)
+
+	sendEvent := func(server *cqlClient.CqlServer, event message.Message) {
+		serverConns, err := server.AllAcceptedClients()
+		require.Nil(t, err)
+		for _, serverConn := range serverConns {
+			require.Nil(t, serverConn.Send(frame.NewFrame(primitive.ProtocolVersion4, -1, event)))
+		}
+	}
+	schemaChange := func(keyspace string) *message.SchemaChangeEvent {
+		return &message.SchemaChangeEvent{
+			ChangeType: primitive.SchemaChangeTypeCreated,
+			Target:     primitive.SchemaChangeTargetKeyspace,
+			Keyspace:   keyspace,
+		}
+	}
+
+	sendEvent(testSetup.Origin.CqlServer, schemaChange("origin_ks"))
+	sendEvent(testSetup.Target.CqlServer, schemaChange("target_ks"))
+	event, err := conn.ReceiveEvent()
+	require.Nil(t, err)
+	require.Equal(t, schemaChange("target_ks"), event.Body.Message)
+	event, err = conn.ReceiveEvent()
+	require.NotNil(t, err, "did not expect a second event: %v", event)
+
+	// the client didn't register for status changes
+	sendEvent(testSetup.Target.CqlServer, &message.StatusChangeEvent{
+		ChangeType: primitive.StatusChangeTypeDown,
+		Address:    &primitive.Inet{Addr: net.ParseIP("127.0.1.2"), Port: 9042},
+	})
+	event, err = conn.ReceiveEvent()
+	require.NotNil(t, err, "did not expect an event: %v", event)
+}
+
+// TestChainedProxyEvents tests that a proxy whose origin is another proxy (ZDM_ORIGIN_IS_ZDM_PROXY) doesn't forward
+// the topology and status events received from the upstream proxy, those describe the clusters behind the upstream proxy
+func TestChainedProxyEvents(t *testing.T) {
+	upstreamConf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	testSetup, err := setup.NewCqlServerTestSetup(t, upstreamConf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+	testSetup.Origin.CqlServer.RequestHandlers = []cqlClient.RequestHandler{
+		cqlClient.RegisterHandler, cqlClient.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {})}
+	testSetup.Target.CqlServer.RequestHandlers = []cqlClient.RequestHandler{
+		cqlClient.RegisterHandler, cqlClient.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {})}
+	err = testSetup.Start(upstreamConf, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	downstreamTarget, err := cqlserver.NewCqlServerCluster("127.0.1.3", 9042, "cassandra", "cassandra", false)
+	require.Nil(t, err)
+	defer downstreamTarget.Close()
+	downstreamTarget.CqlServer.RequestHandlers = []cqlClient.RequestHandler{
+		cqlClient.RegisterHandler, cqlClient.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {})}
+	require.Nil(t, downstreamTarget.Start())
+
+	downstreamConf := setup.NewTestConfig("127.0.0.1", "127.0.1.3")
+	downstreamConf.OriginPort = upstreamConf.ProxyListenPort
+	downstreamConf.OriginIsZdmProxy = true
+	downstreamConf.OriginEnableHostAssignment = false
+	downstreamConf.ProxyListenPort = 14003
+	downstreamConf.MetricsEnabled = false
+	downstreamConf.EventForwarding = "TOPOLOGY_CHANGE=ORIGIN, STATUS_CHANGE=ORIGIN"
+	downstreamProxy, err := setup.NewProxyInstanceWithConfig(downstreamConf)
+	require.Nil(t, err)
+	defer downstreamProxy.Shutdown()
+
+	testClient := cqlClient.NewCqlClient("127.0.0.1:14003", &cqlClient.AuthCredentials{
+		Username: downstreamConf.TargetUsername,
+		Password: downstreamConf.TargetPassword,
+	})
+	testClient.ReadTimeout = 500 * time.Millisecond
+	conn, err := testClient.ConnectAndInit(context.Background(), primitive.ProtocolVersion4, 1)
+	require.Nil(t, err)
+	defer conn.Close()
+
+	response, err := conn.SendAndReceive(frame.NewFrame(primitive.ProtocolVersion4, 1, &message.Register{
+		EventTypes: []primitive.EventType{
+			primitive.EventTypeSchemaChange, primitive.EventTypeTopologyChange, primitive.EventTypeStatusChange}}))
+	require.Nil(t, err)
+	require.IsType(t, &message.Ready{}, response.Body.Message)
+
+	sendEvent := func(server *cqlClient.CqlServer, event message.Message) {
+		serverConns, err := server.AllAcceptedClients()
+		require.Nil(t, err)
+		for _, serverConn := range serverConns {
+			require.Nil(t, serverConn.Send(frame.NewFrame(primitive.ProtocolVersion4, -1, event)))
+		}
+	}
+
+	for _, server := range []*cqlClient.CqlServer{testSetup.Origin.CqlServer, testSetup.Target.CqlServer} {
+		sendEvent(server, &message.StatusChangeEvent{
+			ChangeType: primitive.StatusChangeTypeDown,
+			Address:    &primitive.Inet{Addr: net.ParseIP("127.0.1.4"), Port: 9042},
+		})
+		sendEvent(server, &message.TopologyChangeEvent{
+			ChangeType: primitive.TopologyChangeTypeNewNode,
+			Address:    &primitive.Inet{Addr: net.ParseIP("127.0.1.4"), Port: 9042},
+		})
+	}
+	event, err := conn.ReceiveEvent()
+	require.NotNil(t, err, "did not expect an event: %v", event)
+
+	// schema changes still go through the chain
+	schemaChange := &message.SchemaChangeEvent{
+		ChangeType: primitive.SchemaChangeTypeCreated,
+		Target:     primitive.SchemaChangeTargetKeyspace,
+		Keyspace:   "origin_ks",
+	}
+	sendEvent(testSetup.Origin.CqlServer, schemaChange)
+	event, err = conn.ReceiveEvent()
+	require.Nil(t, err)
+	require.Equal(t, schemaChange, event.Body.Message)
+}
+
 // TestSchemaEvents tests the schema event message handling
 func TestSchemaEvents(t *testing.T) {
 	if !env.RunCcmTests {
@@ -0,0 +1,80 @@
+package integration_tests
+
+import (
+	"context"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/integration-tests/utils"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAsyncReadsFeatureFlag(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.ReadMode = config.ReadModeDualAsyncOnSecondary
+	conf.FeatureFlags = "async_reads=0"
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	targetReads := int32(0)
+	readHandler := func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		query, ok := request.Body.Message.(*message.Query)
+		if !ok || !strings.HasPrefix(query.Query, "SELECT * FROM ks.") {
+			return nil
+		}
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+	}
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+		readHandler}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+		func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+			rsp := readHandler(request, conn, ctx)
+			if rsp != nil {
+				atomic.AddInt32(&targetReads, 1)
+			}
+			return rsp
+		}}
+
+	err = testSetup.Start(conf, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	testClient := client.NewCqlClient("127.0.0.1:14002", &client.AuthCredentials{
+		Username: conf.TargetUsername,
+		Password: conf.TargetPassword,
+	})
+	conn, err := testClient.ConnectAndInit(context.Background(), primitive.ProtocolVersion4, 1)
+	require.Nil(t, err)
+	defer conn.Close()
+
+	read := func() {
+		rsp, err := conn.SendAndReceive(frame.NewFrame(primitive.ProtocolVersion4, 2, &message.Query{Query: "SELECT * FROM ks.t"}))
+		require.Nil(t, err)
+		require.Equal(t, primitive.OpCodeResult, rsp.Header.OpCode)
+	}
+
+	read()
+	time.Sleep(200 * time.Millisecond)
+	require.Equal(t, int32(0), atomic.LoadInt32(&targetReads))
+
+	// the change applies to client connections that are already open
+	require.Nil(t, testSetup.Proxy.GetFeatureFlags().Set(common.FeatureAsyncReads, 100))
+	read()
+	utils.RequireWithRetries(t, func() (err error, fatal bool) {
+		if atomic.LoadInt32(&targetReads) != 1 {
+			return fmt.Errorf("expected 1 async read on target but got %v", atomic.LoadInt32(&targetReads)), false
+		}
+		return nil, false
+	}, 20, 100*time.Millisecond)
+}
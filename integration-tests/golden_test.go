@@ -0,0 +1,326 @@
+package integration_tests
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	"github.com/stretchr/testify/require"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var rawCodec = frame.NewRawCodec()
+
+// TestGoldenResponses replays the recordings in testdata/golden (see ZDM_TRAFFIC_RECORDING_FILE) against the proxy and
+// checks that the responses sent to the client are the same as the recorded ones.
+func TestGoldenResponses(t *testing.T) {
+	fileNames, err := filepath.Glob(filepath.Join("testdata", "golden", "*.jsonl"))
+	require.Nil(t, err)
+	require.NotEmpty(t, fileNames)
+	for _, fileName := range fileNames {
+		t.Run(strings.TrimSuffix(filepath.Base(fileName), ".jsonl"), func(t *testing.T) {
+			exchanges, err := zdmproxy.ReadTrafficRecording(fileName)
+			require.Nil(t, err)
+			replayTrafficRecording(t, exchanges)
+		})
+	}
+}
+
+func TestTrafficRecording(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "recording.jsonl")
+	recordTrafficScenario(t, fileName)
+	exchanges, err := zdmproxy.ReadTrafficRecording(fileName)
+	require.Nil(t, err)
+	require.Equal(t, 6, len(exchanges))
+	for i, exchange := range exchanges {
+		if i < 3 {
+			require.Equal(t, exchanges[0].Connection, exchange.Connection)
+		} else {
+			require.NotEqual(t, exchanges[0].Connection, exchange.Connection)
+		}
+		require.NotNil(t, exchange.Request)
+		require.NotNil(t, exchange.OriginResponse)
+		require.NotNil(t, exchange.Response)
+	}
+	// reads are only sent to origin
+	require.Nil(t, exchanges[1].TargetResponse)
+	require.NotNil(t, exchanges[2].TargetResponse)
+
+	replayTrafficRecording(t, exchanges)
+}
+
+// recordTrafficScenario sends a few requests of each type through a proxy that records them to the provided file, the
+// recording is complete once this function returns because the proxy is shut down.
+func recordTrafficScenario(t *testing.T, fileName string) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.TrafficRecordingFile = fileName
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+		recordingScenarioHandler("origin")}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+		recordingScenarioHandler("target")}
+
+	err = testSetup.Start(conf, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	testClient := client.NewCqlClient("127.0.0.1:14002", &client.AuthCredentials{
+		Username: conf.TargetUsername,
+		Password: conf.TargetPassword,
+	})
+	var prepared *message.PreparedResult
+	for _, requests := range [][]message.Message{
+		{
+			&message.Query{Query: "USE ks"},
+			&message.Query{Query: "SELECT * FROM t"},
+			&message.Prepare{Query: "INSERT INTO t (a, b) VALUES (?, ?)"},
+		},
+		{
+			&message.Execute{Options: &message.QueryOptions{PositionalValues: []*primitive.Value{
+				primitive.NewValue([]byte{0, 0, 0, 1}), primitive.NewValue([]byte("b"))}}},
+			&message.Query{Query: "INSERT INTO ks.t (a, b) VALUES (2, 'c')"},
+			&message.Batch{Type: primitive.BatchTypeLogged, Children: []*message.BatchChild{
+				{Query: "INSERT INTO ks.t (a, b) VALUES (3, 'd')"}, {Query: "INSERT INTO ks.t (a, b) VALUES (4, 'e')"}}},
+		},
+	} {
+		conn, err := testClient.ConnectAndInit(context.Background(), primitive.ProtocolVersion4, 1)
+		require.Nil(t, err)
+		for i, request := range requests {
+			if execute, ok := request.(*message.Execute); ok {
+				execute.QueryId = prepared.PreparedQueryId
+			}
+			rsp, err := conn.SendAndReceive(frame.NewFrame(primitive.ProtocolVersion4, int16(i+2), request))
+			require.Nil(t, err)
+			if result, ok := rsp.Body.Message.(*message.PreparedResult); ok {
+				prepared = result
+			}
+		}
+		require.Nil(t, conn.Close())
+	}
+}
+
+// recordingScenarioHandler returns responses that depend on the cluster so that the recording can only be replayed if
+// the proxy still sends each request to the same clusters (e.g. prepared ids are translated for the target cluster).
+func recordingScenarioHandler(cluster string) client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		var response message.Message
+		switch msg := request.Body.Message.(type) {
+		case *message.Query:
+			switch {
+			case msg.Query == "USE ks":
+				response = &message.SetKeyspaceResult{Keyspace: "ks"}
+			case msg.Query == "SELECT * FROM t":
+				response = &message.RowsResult{
+					Metadata: &message.RowsMetadata{ColumnCount: 1, Columns: []*message.ColumnMetadata{
+						{Keyspace: "ks", Table: "t", Name: "b", Type: datatype.Varchar}}},
+					Data: message.RowSet{{[]byte(cluster)}},
+				}
+			case strings.HasPrefix(msg.Query, "INSERT INTO ks.t") && cluster == "target":
+				response = &message.WriteTimeout{
+					ErrorMessage: "write timeout", Consistency: primitive.ConsistencyLevelLocalQuorum,
+					Received: 1, BlockFor: 2, WriteType: primitive.WriteTypeSimple}
+			case strings.HasPrefix(msg.Query, "INSERT INTO ks.t"):
+				response = &message.VoidResult{}
+			}
+		case *message.Prepare:
+			response = &message.PreparedResult{
+				PreparedQueryId: []byte(cluster + "-insert"),
+				VariablesMetadata: &message.VariablesMetadata{PkIndices: []uint16{0}, Columns: []*message.ColumnMetadata{
+					{Keyspace: "ks", Table: "t", Name: "a", Index: 0, Type: datatype.Int},
+					{Keyspace: "ks", Table: "t", Name: "b", Index: 1, Type: datatype.Varchar}}},
+				ResultMetadata: &message.RowsMetadata{},
+			}
+		case *message.Execute:
+			if string(msg.QueryId) == cluster+"-insert" {
+				response = &message.VoidResult{}
+			}
+		case *message.Batch:
+			response = &message.VoidResult{}
+		}
+		if response == nil {
+			return nil
+		}
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId, response)
+	}
+}
+
+// replayTrafficRecording sends the recorded requests to the proxy one at a time, each on the connection it was
+// recorded on, while fake clusters return the recorded responses. The responses sent to the client must be equal to
+// the recorded ones byte by byte, except for tracing ids.
+func replayTrafficRecording(t *testing.T, exchanges []*zdmproxy.RecordedExchange) {
+	require.NotEmpty(t, exchanges)
+	version := decodeRecordedFrame(t, exchanges[0].Request).Header.Version
+
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	origin := newGoldenCluster()
+	target := newGoldenCluster()
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+		unexpectedStatementHandler}
+	testSetup.Origin.CqlServer.RequestRawHandlers = []client.RawRequestHandler{origin.handleRequest}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+		unexpectedStatementHandler}
+	testSetup.Target.CqlServer.RequestRawHandlers = []client.RawRequestHandler{target.handleRequest}
+
+	err = testSetup.Start(conf, false, version)
+	require.Nil(t, err)
+
+	clients := make(map[uint64]*goldenClient)
+	defer func() {
+		for _, c := range clients {
+			_ = c.conn.Close()
+		}
+	}()
+	for i, exchange := range exchanges {
+		request := decodeRecordedFrame(t, exchange.Request)
+		origin.expect(request.Header.OpCode, decodeRecordedFrame(t, exchange.OriginResponse))
+		target.expect(request.Header.OpCode, decodeRecordedFrame(t, exchange.TargetResponse))
+
+		c, ok := clients[exchange.Connection]
+		if !ok {
+			c = newGoldenClient(t, conf, request.Header.Version)
+			clients[exchange.Connection] = c
+		}
+		response := c.send(t, exchange.Request)
+		require.Equal(t, normalizeRecordedResponse(t, exchange.Response), normalizeRecordedResponse(t, response),
+			"response of exchange %d (%v) doesn't match the recording", i+1, request.Header)
+		require.False(t, origin.pending(), "exchange %d (%v) was not sent to origin", i+1, request.Header)
+		require.False(t, target.pending(), "exchange %d (%v) was not sent to target", i+1, request.Header)
+	}
+}
+
+// goldenCluster returns the recorded response of a cluster to the next request with the same opcode as the recorded
+// request, the other requests (e.g. handshakes and heartbeats) are left to the other handlers.
+type goldenCluster struct {
+	lock     *sync.Mutex
+	opCode   primitive.OpCode
+	response *frame.RawFrame
+}
+
+func newGoldenCluster() *goldenCluster {
+	return &goldenCluster{lock: &sync.Mutex{}}
+}
+
+func (recv *goldenCluster) expect(opCode primitive.OpCode, response *frame.RawFrame) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	recv.opCode = opCode
+	recv.response = response
+}
+
+func (recv *goldenCluster) pending() bool {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return recv.response != nil
+}
+
+func (recv *goldenCluster) handleRequest(
+	request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) []byte {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if recv.response == nil || request.Header.OpCode != recv.opCode {
+		return nil
+	}
+	header := *recv.response.Header
+	header.StreamId = request.Header.StreamId
+	buf := &bytes.Buffer{}
+	if err := rawCodec.EncodeRawFrame(&frame.RawFrame{Header: &header, Body: recv.response.Body}, buf); err != nil {
+		return nil
+	}
+	recv.response = nil
+	return buf.Bytes()
+}
+
+func unexpectedStatementHandler(
+	request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+	switch request.Header.OpCode {
+	case primitive.OpCodeQuery, primitive.OpCodePrepare, primitive.OpCodeExecute, primitive.OpCodeBatch:
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId,
+			&message.ServerError{ErrorMessage: "no recorded response for this request"})
+	}
+	return nil
+}
+
+// goldenClient sends already encoded requests to the proxy and returns the responses without decoding their body.
+type goldenClient struct {
+	conn net.Conn
+}
+
+func newGoldenClient(t *testing.T, conf *config.Config, version primitive.ProtocolVersion) *goldenClient {
+	conn, err := net.Dial("tcp", "127.0.0.1:14002")
+	require.Nil(t, err)
+	c := &goldenClient{conn: conn}
+
+	codec := frame.NewCodec()
+	buf := &bytes.Buffer{}
+	require.Nil(t, codec.EncodeFrame(frame.NewFrame(version, 0, message.NewStartup()), buf))
+	response := decodeRecordedFrame(t, c.send(t, buf.Bytes()))
+	if response.Header.OpCode == primitive.OpCodeAuthenticate {
+		buf.Reset()
+		token := []byte(fmt.Sprintf("\x00%v\x00%v", conf.TargetUsername, conf.TargetPassword))
+		require.Nil(t, codec.EncodeFrame(frame.NewFrame(version, 0, &message.AuthResponse{Token: token}), buf))
+		response = decodeRecordedFrame(t, c.send(t, buf.Bytes()))
+		require.Equal(t, primitive.OpCodeAuthSuccess, response.Header.OpCode)
+	} else {
+		require.Equal(t, primitive.OpCodeReady, response.Header.OpCode)
+	}
+	return c
+}
+
+func (recv *goldenClient) send(t *testing.T, request []byte) []byte {
+	require.Nil(t, recv.conn.SetDeadline(time.Now().Add(10*time.Second)))
+	_, err := recv.conn.Write(request)
+	require.Nil(t, err)
+	for {
+		response, err := rawCodec.DecodeRawFrame(recv.conn)
+		require.Nil(t, err)
+		if response.Header.OpCode == primitive.OpCodeEvent {
+			continue
+		}
+		buf := &bytes.Buffer{}
+		require.Nil(t, rawCodec.EncodeRawFrame(response, buf))
+		return buf.Bytes()
+	}
+}
+
+func decodeRecordedFrame(t *testing.T, encoded []byte) *frame.RawFrame {
+	if encoded == nil {
+		return nil
+	}
+	f, err := rawCodec.DecodeRawFrame(bytes.NewReader(encoded))
+	require.Nil(t, err)
+	return f
+}
+
+// normalizeRecordedResponse clears the tracing id of a response because it is a time based uuid that differs every
+// time a request is traced.
+func normalizeRecordedResponse(t *testing.T, encoded []byte) []byte {
+	f := decodeRecordedFrame(t, encoded)
+	if f.Header.Flags.Contains(primitive.HeaderFlagTracing) && len(f.Body) >= primitive.LengthOfUuid {
+		f.Body = append(make([]byte, primitive.LengthOfUuid), f.Body[primitive.LengthOfUuid:]...)
+	}
+	buf := &bytes.Buffer{}
+	require.Nil(t, rawCodec.EncodeRawFrame(f, buf))
+	return buf.Bytes()
+}
@@ -0,0 +1,126 @@
+package integration_tests
+
+import (
+	"context"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIdleClusterConnectionHeartbeats(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.HeartbeatIntervalMs = 100
+	conf.HeartbeatTimeoutMs = 300
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	originHeartbeats := int32(0)
+	targetHeartbeats := int32(0)
+	targetUnresponsive := int32(0)
+	negativeStreamIds := int32(0)
+	unblock := make(chan bool)
+	defer close(unblock)
+	heartbeatHandler := func(heartbeats *int32, unresponsive *int32) client.RequestHandler {
+		return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+			if _, ok := request.Body.Message.(*message.Options); !ok {
+				return nil
+			}
+			if request.Header.StreamId < 0 {
+				atomic.AddInt32(&negativeStreamIds, 1)
+			}
+			if atomic.LoadInt32(unresponsive) == 1 {
+				<-unblock
+			}
+			atomic.AddInt32(heartbeats, 1)
+			return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.Supported{})
+		}
+	}
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		heartbeatHandler(&originHeartbeats, new(int32)),
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {})}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		heartbeatHandler(&targetHeartbeats, &targetUnresponsive),
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {})}
+
+	err = testSetup.Start(conf, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	testClient := client.NewCqlClient("127.0.0.1:14002", &client.AuthCredentials{
+		Username: conf.TargetUsername,
+		Password: conf.TargetPassword,
+	})
+	conn, err := testClient.ConnectAndInit(context.Background(), primitive.ProtocolVersion4, 1)
+	require.Nil(t, err)
+	defer conn.Close()
+
+	// idle connections are kept alive with heartbeats
+	originBefore := atomic.LoadInt32(&originHeartbeats)
+	targetBefore := atomic.LoadInt32(&targetHeartbeats)
+	time.Sleep(500 * time.Millisecond)
+	require.Greater(t, atomic.LoadInt32(&originHeartbeats), originBefore)
+	require.Greater(t, atomic.LoadInt32(&targetHeartbeats), targetBefore)
+	require.False(t, conn.IsClosed())
+	require.Equal(t, int32(0), atomic.LoadInt32(&negativeStreamIds))
+
+	// a connection whose node stops answering is closed
+	atomic.StoreInt32(&targetUnresponsive, 1)
+	require.Eventually(t, conn.IsClosed, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestIdleClusterConnectionHeartbeatErrorResponse(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.HeartbeatIntervalMs = 100
+	conf.HeartbeatTimeoutMs = 300
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	heartbeats := int32(0)
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+			if _, ok := request.Body.Message.(*message.Options); !ok {
+				return nil
+			}
+			atomic.AddInt32(&heartbeats, 1)
+			return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.Overloaded{ErrorMessage: "busy"})
+		},
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+		client.RegisterHandler,
+		client.HeartbeatHandler}
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+			if query, ok := request.Body.Message.(*message.Query); ok && query.Query == "SELECT * FROM ks.tbl" {
+				return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+			}
+			return nil
+		},
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+		client.RegisterHandler,
+		client.HeartbeatHandler}
+
+	err = testSetup.Start(conf, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	testClient := client.NewCqlClient("127.0.0.1:14002", &client.AuthCredentials{
+		Username: conf.TargetUsername,
+		Password: conf.TargetPassword,
+	})
+	conn, err := testClient.ConnectAndInit(context.Background(), primitive.ProtocolVersion4, 1)
+	require.Nil(t, err)
+	defer conn.Close()
+
+	// error responses to heartbeats are consumed by the proxy, the client only gets responses to its own requests
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&heartbeats) >= 2 }, 5*time.Second, 50*time.Millisecond)
+	response, err := conn.SendAndReceive(
+		frame.NewFrame(primitive.ProtocolVersion4, 0, &message.Query{Query: "SELECT * FROM ks.tbl"}))
+	require.Nil(t, err)
+	require.IsType(t, &message.VoidResult{}, response.Body.Message)
+	require.False(t, conn.IsClosed())
+}
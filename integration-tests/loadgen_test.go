@@ -0,0 +1,105 @@
+package integration_tests
+
+import (
+	"bytes"
+	"context"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/loadgen"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoadgen(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	column := func(name string, index int32, dataType datatype.DataType) *message.ColumnMetadata {
+		return &message.ColumnMetadata{Keyspace: "zdm_loadgen", Table: "kv", Name: name, Index: index, Type: dataType}
+	}
+	selectMetadata := &message.RowsMetadata{ColumnCount: 1, Columns: []*message.ColumnMetadata{column("payload", 0, datatype.Blob)}}
+	reads := map[string]*int32{"origin": new(int32), "target": new(int32)}
+	writes := map[string]*int32{"origin": new(int32), "target": new(int32)}
+	requestHandler := func(cluster string) client.RequestHandler {
+		return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+			switch msg := request.Body.Message.(type) {
+			case *message.Prepare:
+				if strings.HasPrefix(msg.Query, "SELECT") {
+					return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.PreparedResult{
+						PreparedQueryId: []byte("select"),
+						VariablesMetadata: &message.VariablesMetadata{PkIndices: []uint16{0}, Columns: []*message.ColumnMetadata{
+							column("pk", 0, datatype.Bigint), column("ck", 1, datatype.Int)}},
+						ResultMetadata: selectMetadata,
+					})
+				}
+				return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.PreparedResult{
+					PreparedQueryId: []byte("insert"),
+					VariablesMetadata: &message.VariablesMetadata{PkIndices: []uint16{0}, Columns: []*message.ColumnMetadata{
+						column("pk", 0, datatype.Bigint), column("ck", 1, datatype.Int), column("payload", 2, datatype.Blob)}},
+					ResultMetadata: &message.RowsMetadata{},
+				})
+			case *message.Execute:
+				if string(msg.QueryId) == "select" {
+					atomic.AddInt32(reads[cluster], 1)
+					return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.RowsResult{
+						Metadata: selectMetadata, Data: message.RowSet{}})
+				}
+				atomic.AddInt32(writes[cluster], 1)
+				return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+			}
+			return nil
+		}
+	}
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+		requestHandler("origin")}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+		requestHandler("target")}
+
+	err = testSetup.Start(conf, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	out := &bytes.Buffer{}
+	err = loadgen.Run(context.Background(), out, loadgen.Options{
+		Hosts:            []string{"127.0.0.1"},
+		Port:             14002,
+		Username:         conf.TargetUsername,
+		Password:         conf.TargetPassword,
+		Keyspace:         "zdm_loadgen",
+		Table:            "kv",
+		Consistency:      "LOCAL_QUORUM",
+		Concurrency:      4,
+		Duration:         time.Second,
+		Rate:             200,
+		ReadRatio:        0.5,
+		Partitions:       100,
+		RowsPerPartition: 10,
+		Distribution:     loadgen.DistributionZipf,
+		PayloadMinBytes:  10,
+		PayloadMaxBytes:  100,
+		ReportInterval:   500 * time.Millisecond,
+	})
+	require.Nil(t, err)
+
+	report := out.String()
+	require.Contains(t, report, "Summary after")
+	require.Regexp(t, `reads +count=[1-9][0-9]* rate=[0-9]+/s errors=0 `, report)
+	require.Regexp(t, `writes +count=[1-9][0-9]* rate=[0-9]+/s errors=0 `, report)
+	require.NotContains(t, report, "last error")
+
+	// reads are only forwarded to the primary cluster and writes to both clusters
+	require.Greater(t, atomic.LoadInt32(reads["origin"]), int32(0))
+	require.Equal(t, int32(0), atomic.LoadInt32(reads["target"]))
+	require.Greater(t, atomic.LoadInt32(writes["target"]), int32(0))
+	require.Equal(t, atomic.LoadInt32(writes["origin"]), atomic.LoadInt32(writes["target"]))
+}
@@ -0,0 +1,88 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestLwtMode(t *testing.T) {
+	const conditionalWrite = "INSERT INTO ks.tbl (pk) VALUES (1) IF NOT EXISTS"
+	// answers the conditional writes with the provided [applied] flag and counts them
+	newHandler := func(applied bool, received *int32) client.RequestHandler {
+		return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+			query, ok := request.Body.Message.(*message.Query)
+			if !ok || !strings.HasPrefix(query.Query, "INSERT") {
+				return nil
+			}
+			atomic.AddInt32(received, 1)
+			value := []byte{0}
+			if applied {
+				value = []byte{1}
+			}
+			return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.RowsResult{
+				Metadata: &message.RowsMetadata{
+					ColumnCount: 1,
+					Columns: []*message.ColumnMetadata{
+						{Keyspace: "ks", Table: "tbl", Name: "[applied]", Type: datatype.Boolean}},
+				},
+				Data: message.RowSet{{value}},
+			})
+		}
+	}
+
+	tests := []struct {
+		name             string
+		mode             string
+		expectedResponse message.Message
+		expectedOrigin   int32
+		expectedTarget   int32
+		expectedWarning  string
+	}{
+		{"dual", config.LwtModeDual, &message.RowsResult{}, 1, 1,
+			"Conditional write was applied on ORIGIN but not on TARGET"},
+		{"primary only", config.LwtModePrimaryOnly, &message.RowsResult{}, 1, 0, ""},
+		{"reject", config.LwtModeReject, &message.Invalid{}, 0, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			conf.LwtMode = tt.mode
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			var originReceived, targetReceived int32
+			testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+				newHandler(true, &originReceived),
+				client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {})}
+			testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+				newHandler(false, &targetReceived),
+				client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {})}
+
+			err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+
+			response, err := testSetup.Client.CqlConnection.SendAndReceive(
+				frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{Query: conditionalWrite}))
+			require.Nil(t, err)
+			require.IsType(t, tt.expectedResponse, response.Body.Message)
+			require.Equal(t, tt.expectedOrigin, atomic.LoadInt32(&originReceived))
+			require.Equal(t, tt.expectedTarget, atomic.LoadInt32(&targetReceived))
+			if tt.expectedWarning == "" {
+				require.Empty(t, response.Body.Warnings)
+			} else {
+				require.Len(t, response.Body.Warnings, 1)
+				require.Contains(t, response.Body.Warnings[0], tt.expectedWarning)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package integration_tests
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestMaxFrameSize(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.ProxyMaxFrameSizeBytes = 1024
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	var received int32
+	handler := func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		query, ok := request.Body.Message.(*message.Query)
+		if !ok || !strings.HasPrefix(query.Query, "INSERT") {
+			return nil
+		}
+		atomic.AddInt32(&received, 1)
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+	}
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		handler, client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {})}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		handler, client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {})}
+
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	largeQuery := fmt.Sprintf("INSERT INTO ks.tbl (pk, v) VALUES (1, '%s')", strings.Repeat("a", 2048))
+	response, err := testSetup.Client.CqlConnection.SendAndReceive(
+		frame.NewFrame(primitive.ProtocolVersion4, 1, &message.Query{Query: largeQuery}))
+	require.Nil(t, err)
+	require.Equal(t, int16(1), response.Header.StreamId)
+	invalid, ok := response.Body.Message.(*message.Invalid)
+	require.True(t, ok, "expected INVALID but got %v", response.Body.Message)
+	require.Contains(t, invalid.ErrorMessage, "exceeds maximum allowed length 1024")
+	require.Equal(t, int32(0), atomic.LoadInt32(&received))
+
+	// the connection is still usable after the large frame was skipped
+	response, err = testSetup.Client.CqlConnection.SendAndReceive(
+		frame.NewFrame(primitive.ProtocolVersion4, 2, &message.Query{Query: "INSERT INTO ks.tbl (pk, v) VALUES (2, 'a')"}))
+	require.Nil(t, err)
+	require.IsType(t, &message.VoidResult{}, response.Body.Message)
+	require.Equal(t, int32(2), atomic.LoadInt32(&received))
+}
@@ -59,10 +59,12 @@ var proxyMetrics = []metrics.Metric{
 	metrics.ProxyReadsTargetDuration,
 	metrics.ProxyReadsOriginDuration,
 	metrics.ProxyWritesDuration,
+	metrics.ProxyMonitoringDuration,
 
 	metrics.InFlightReadsTarget,
 	metrics.InFlightReadsOrigin,
 	metrics.InFlightWrites,
+	metrics.InFlightMonitoring,
 
 	metrics.OpenClientConnections,
 }
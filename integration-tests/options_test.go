@@ -7,6 +7,7 @@ import (
 	"github.com/datastax/go-cassandra-native-protocol/primitive"
 	"github.com/datastax/zdm-proxy/integration-tests/setup"
 	"github.com/stretchr/testify/require"
+	"sync/atomic"
 	"testing"
 )
 
@@ -31,6 +32,52 @@ func TestOptionsShouldComeFromTarget(t *testing.T) {
 
 }
 
+func TestOptionsAnsweredLocally(t *testing.T) {
+
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.ProxyLocalHeartbeats = true
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+	originOptions := int32(0)
+	targetOptions := int32(0)
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{client.RegisterHandler, newCountingOptionsHandler("origin", &originOptions), client.HandshakeHandler, client.NewSystemTablesHandler("cluster2", "dc2")}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{client.RegisterHandler, newCountingOptionsHandler("target", &targetOptions), client.HandshakeHandler, client.NewSystemTablesHandler("cluster1", "dc1")}
+
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+	originOptionsBefore := atomic.LoadInt32(&originOptions)
+	targetOptionsBefore := atomic.LoadInt32(&targetOptions)
+
+	for i := int16(1); i <= 3; i++ {
+		request := frame.NewFrame(primitive.ProtocolVersion4, i, &message.Options{})
+		response, err := testSetup.Client.CqlConnection.SendAndReceive(request)
+		require.Nil(t, err)
+		require.Equal(t, i, response.Header.StreamId)
+		require.IsType(t, &message.Supported{}, response.Body.Message)
+		require.Equal(t, "target", response.Body.Message.(*message.Supported).Options["FROM"][0])
+	}
+
+	// only the first OPTIONS request is forwarded
+	require.Equal(t, originOptionsBefore+1, atomic.LoadInt32(&originOptions))
+	require.Equal(t, targetOptionsBefore+1, atomic.LoadInt32(&targetOptions))
+}
+
+func newCountingOptionsHandler(from string, count *int32) client.RequestHandler {
+	optionsHandler := newOptionsHandler(from)
+	return func(
+		request *frame.Frame,
+		conn *client.CqlServerConnection,
+		ctx client.RequestHandlerContext,
+	) (response *frame.Frame) {
+		response = optionsHandler(request, conn, ctx)
+		if response != nil {
+			atomic.AddInt32(count, 1)
+		}
+		return
+	}
+}
+
 func newOptionsHandler(from string) client.RequestHandler {
 	return func(
 		request *frame.Frame,
@@ -0,0 +1,71 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaxInFlightRequests(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.ProxyMaxInFlightRequests = 1
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	received := make(chan bool, 1)
+	unblock := make(chan bool)
+	blockingHandler := func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		query, ok := request.Body.Message.(*message.Query)
+		if !ok || !strings.HasPrefix(query.Query, "INSERT") {
+			return nil
+		}
+		if query.Query == "INSERT INTO ks.tbl (pk) VALUES (1)" {
+			received <- true
+			<-unblock
+		}
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+	}
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		blockingHandler,
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {})}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		blockingHandler,
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {})}
+
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	blocked, err := testSetup.Client.CqlConnection.Send(
+		frame.NewFrame(primitive.ProtocolVersion4, 1, &message.Query{Query: "INSERT INTO ks.tbl (pk) VALUES (1)"}))
+	require.Nil(t, err)
+	for i := 0; i < 2; i++ {
+		select {
+		case <-received:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the blocked request to reach the clusters")
+		}
+	}
+
+	response, err := testSetup.Client.CqlConnection.SendAndReceive(
+		frame.NewFrame(primitive.ProtocolVersion4, 2, &message.Query{Query: "INSERT INTO ks.tbl (pk) VALUES (2)"}))
+	require.Nil(t, err)
+	require.IsType(t, &message.Overloaded{}, response.Body.Message)
+
+	unblock <- true
+	unblock <- true
+	response, err = testSetup.Client.CqlConnection.Receive(blocked)
+	require.Nil(t, err)
+	require.IsType(t, &message.VoidResult{}, response.Body.Message)
+
+	response, err = testSetup.Client.CqlConnection.SendAndReceive(
+		frame.NewFrame(primitive.ProtocolVersion4, 3, &message.Query{Query: "INSERT INTO ks.tbl (pk) VALUES (3)"}))
+	require.Nil(t, err)
+	require.IsType(t, &message.VoidResult{}, response.Body.Message)
+}
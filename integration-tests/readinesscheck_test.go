@@ -0,0 +1,120 @@
+package integration_tests
+
+import (
+	"context"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestReadinessCheck(t *testing.T) {
+	originSchema := [][]string{
+		{"ks", "users", "id", "uuid"},
+		{"ks", "users", "name", "text"},
+		{"system_auth", "roles", "role", "text"},
+	}
+
+	tests := []struct {
+		name            string
+		targetSchema    [][]string
+		startTarget     bool
+		expectedFailure string
+	}{
+		{
+			name: "ready",
+			targetSchema: [][]string{
+				{"ks", "users", "id", "uuid"},
+				{"ks", "users", "name", "text"},
+				{"ks", "other", "id", "int"},
+			},
+			startTarget: true,
+		},
+		{
+			name:            "missing column in target",
+			targetSchema:    [][]string{{"ks", "users", "id", "uuid"}},
+			startTarget:     true,
+			expectedFailure: "Schema parity",
+		},
+		{
+			name: "different column type in target",
+			targetSchema: [][]string{
+				{"ks", "users", "id", "timeuuid"},
+				{"ks", "users", "name", "text"},
+			},
+			startTarget:     true,
+			expectedFailure: "Schema parity",
+		},
+		{
+			name:            "target not reachable",
+			startTarget:     false,
+			expectedFailure: "TARGET connectivity",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+				newRefreshTopologyTestHandler("cluster1", "datacenter1", "127.0.1.1", nil),
+				newSchemaColumnsHandler(originSchema)}
+			testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+				newRefreshTopologyTestHandler("cluster2", "datacenter1", "127.0.1.2", nil),
+				newSchemaColumnsHandler(tt.targetSchema)}
+
+			require.Nil(t, testSetup.Origin.Start())
+			if tt.startTarget {
+				require.Nil(t, testSetup.Target.Start())
+			}
+
+			report := zdmproxy.RunReadinessCheck(conf, context.Background())
+			output := &strings.Builder{}
+			report.Print(output)
+
+			if tt.expectedFailure == "" {
+				require.False(t, report.HasBlockers(), output.String())
+				require.Contains(t, output.String(), "[PASS] Schema parity: all 1 origin table(s) exist in target")
+				require.Contains(t, output.String(), "Result: READY")
+				return
+			}
+			require.True(t, report.HasBlockers(), output.String())
+			require.Contains(t, output.String(), "[FAIL] "+tt.expectedFailure)
+			require.Contains(t, output.String(), "Result: NOT READY")
+		})
+	}
+}
+
+func newSchemaColumnsHandler(columns [][]string) client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		query, ok := request.Body.Message.(*message.Query)
+		if !ok || !strings.Contains(query.Query, "FROM system_schema.columns") {
+			return nil
+		}
+		columnsMetadata := make([]*message.ColumnMetadata, 0, 4)
+		for _, name := range []string{"keyspace_name", "table_name", "column_name", "type"} {
+			columnsMetadata = append(columnsMetadata,
+				&message.ColumnMetadata{Keyspace: "system_schema", Table: "columns", Name: name, Type: datatype.Varchar})
+		}
+		rows := message.RowSet{}
+		for _, column := range columns {
+			row := message.Row{}
+			for _, value := range column {
+				row = append(row, message.Column(value))
+			}
+			rows = append(rows, row)
+		}
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.RowsResult{
+			Metadata: &message.RowsMetadata{ColumnCount: 4, Columns: columnsMetadata},
+			Data:     rows,
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestRequestIdPayload(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.ProxyRequestIdPayloadKey = "zdm-request-id"
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	originPayloads := make(chan map[string][]byte, 10)
+	targetPayloads := make(chan map[string][]byte, 10)
+	payloadHandler := func(payloads chan map[string][]byte) client.RequestHandler {
+		return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+			query, ok := request.Body.Message.(*message.Query)
+			if !ok || !strings.HasPrefix(query.Query, "INSERT") {
+				return nil
+			}
+			payloads <- request.Body.CustomPayload
+			return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+		}
+	}
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		payloadHandler(originPayloads),
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {})}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		payloadHandler(targetPayloads),
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {})}
+
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	var requestIds []string
+	for i := int16(1); i <= 2; i++ {
+		query := frame.NewFrame(primitive.ProtocolVersion4, i, &message.Query{Query: "INSERT INTO ks.tbl (pk) VALUES (1)"})
+		query.SetCustomPayload(map[string][]byte{"client": []byte("value")})
+		response, err := testSetup.Client.CqlConnection.SendAndReceive(query)
+		require.Nil(t, err)
+		require.IsType(t, &message.VoidResult{}, response.Body.Message)
+
+		originPayload := <-originPayloads
+		targetPayload := <-targetPayloads
+		require.Equal(t, []byte("value"), originPayload["client"])
+		require.NotEmpty(t, originPayload["zdm-request-id"])
+		// both clusters get the same id for a request
+		require.Equal(t, originPayload, targetPayload)
+		requestIds = append(requestIds, string(originPayload["zdm-request-id"]))
+	}
+	require.NotEqual(t, requestIds[0], requestIds[1])
+}
@@ -0,0 +1,84 @@
+package integration_tests
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/integration-tests/utils"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestSampling(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.RequestSamplingRate = 1
+	conf.RequestSamplingFile = filepath.Join(t.TempDir(), "samples.csv")
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	requestHandler := func(cluster string) client.RequestHandler {
+		return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+			query, ok := request.Body.Message.(*message.Query)
+			if !ok || !strings.HasPrefix(query.Query, "INSERT INTO ks.") {
+				return nil
+			}
+			if cluster == "target" {
+				return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.Overloaded{ErrorMessage: "overloaded"})
+			}
+			return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+		}
+	}
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+		requestHandler("origin")}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+		requestHandler("target")}
+
+	err = testSetup.Start(conf, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	testClient := client.NewCqlClient("127.0.0.1:14002", &client.AuthCredentials{
+		Username: conf.TargetUsername,
+		Password: conf.TargetPassword,
+	})
+	conn, err := testClient.ConnectAndInit(context.Background(), primitive.ProtocolVersion4, 1)
+	require.Nil(t, err)
+	defer conn.Close()
+
+	rsp, err := conn.SendAndReceive(frame.NewFrame(primitive.ProtocolVersion4, 2, &message.Query{Query: "INSERT INTO ks.t (a) VALUES (1)"}))
+	require.Nil(t, err)
+	require.Equal(t, primitive.OpCodeError, rsp.Header.OpCode)
+
+	var rows [][]string
+	utils.RequireWithRetries(t, func() (err error, fatal bool) {
+		file, err := os.Open(conf.RequestSamplingFile)
+		if err != nil {
+			return err, true
+		}
+		defer file.Close()
+		rows, err = csv.NewReader(file).ReadAll()
+		if err != nil {
+			return err, true
+		}
+		if len(rows) != 2 {
+			return fmt.Errorf("expected header and 1 sample but got %v rows", len(rows)), false
+		}
+		return nil, false
+	}, 20, 100*time.Millisecond)
+	require.Equal(t, "QUERY", rows[1][1])
+	require.Equal(t, "both", rows[1][2])
+	require.Equal(t, "ks.t", rows[1][4])
+	require.Equal(t, "SUCCESS", rows[1][6])
+	require.Equal(t, "Overloaded", rows[1][7])
+}
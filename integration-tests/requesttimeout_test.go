@@ -0,0 +1,55 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestRequestTimeoutErrors(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.ProxyRequestTimeoutMs = 500
+	conf.ProxyRequestTimeoutErrors = true
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	// origin answers the writes but target never does
+	newHandler := func(answerWrites bool) client.RequestHandler {
+		return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+			query, ok := request.Body.Message.(*message.Query)
+			if !ok || !strings.HasPrefix(query.Query, "INSERT") || !answerWrites {
+				return nil
+			}
+			return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+		}
+	}
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		newHandler(true),
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {})}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		newHandler(false),
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {})}
+
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	query := &message.Query{
+		Query:   "INSERT INTO ks.tbl (pk) VALUES (1)",
+		Options: &message.QueryOptions{Consistency: primitive.ConsistencyLevelLocalQuorum},
+	}
+	response, err := testSetup.Client.CqlConnection.SendAndReceive(
+		frame.NewFrame(primitive.ProtocolVersion4, 1, query))
+	require.Nil(t, err)
+	require.Equal(t, int16(1), response.Header.StreamId)
+	writeTimeout, ok := response.Body.Message.(*message.WriteTimeout)
+	require.True(t, ok, "expected WRITE_TIMEOUT but got %v", response.Body.Message)
+	require.Equal(t, primitive.ConsistencyLevelLocalQuorum, writeTimeout.Consistency)
+	require.Equal(t, primitive.WriteTypeSimple, writeTimeout.WriteType)
+	require.Equal(t, "Request timed out after 500 ms at proxy level.", writeTimeout.ErrorMessage)
+}
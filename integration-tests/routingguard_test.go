@@ -0,0 +1,306 @@
+package integration_tests
+
+import (
+	"encoding/json"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/admin"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startRoutingGuardTest starts a proxy whose writes fail on failingCluster and an admin API in front of it, writes
+// that succeed on the other cluster are only written there because of the configured ZDM_DUAL_WRITE_FAILURE_MODE.
+func startRoutingGuardTest(
+	t *testing.T, conf *config.Config, failingCluster common.ClusterType) (*setup.CqlServerTestSetup, *admin.AuditLog,
+	func(method string, path string, body string) *http.Response, func()) {
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+
+	newWriteHandler := func(fail bool) client.RequestHandler {
+		return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+			query, ok := request.Body.Message.(*message.Query)
+			if !ok || !strings.HasPrefix(query.Query, "INSERT") {
+				return nil
+			}
+			var response message.Message = &message.VoidResult{}
+			if fail {
+				response = &message.WriteTimeout{ErrorMessage: "timeout", Consistency: primitive.ConsistencyLevelOne,
+					WriteType: primitive.WriteTypeSimple}
+			}
+			return frame.NewFrame(request.Header.Version, request.Header.StreamId, response)
+		}
+	}
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		newWriteHandler(failingCluster == common.ClusterTypeOrigin),
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {})}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		newWriteHandler(failingCluster == common.ClusterTypeTarget),
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {})}
+
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	auditLog, err := admin.NewAuditLog("")
+	require.Nil(t, err)
+	api := admin.NewApi(testSetup.Proxy, map[string]common.AdminRole{"token": common.AdminRoleOperator}, auditLog)
+	srv := httptest.NewServer(api)
+
+	send := func(method string, path string, body string) *http.Response {
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		require.Nil(t, err)
+		req.Header.Set("Authorization", "Bearer token")
+		rsp, err := http.DefaultClient.Do(req)
+		require.Nil(t, err)
+		return rsp
+	}
+	return testSetup, auditLog, send, func() {
+		srv.Close()
+		testSetup.Cleanup()
+	}
+}
+
+func sendSingleSidedWrite(t *testing.T, testSetup *setup.CqlServerTestSetup) {
+	response, err := testSetup.Client.CqlConnection.SendAndReceive(frame.NewFrame(
+		primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{Query: "INSERT INTO ks.tbl (pk) VALUES (1)"}))
+	require.Nil(t, err)
+	require.IsType(t, &message.VoidResult{}, response.Body.Message)
+}
+
+func withQueryParameter(path string, parameter string) string {
+	if strings.Contains(path, "?") {
+		return path + "&" + parameter
+	}
+	return path + "?" + parameter
+}
+
+// previewRoutingChange sends a routing change with dry_run=true and returns the preview.
+func previewRoutingChange(t *testing.T, send func(method string, path string, body string) *http.Response,
+	method string, path string, body string) *admin.RoutingChangePreview {
+	rsp := send(method, withQueryParameter(path, "dry_run=true"), body)
+	defer rsp.Body.Close()
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+	preview := &admin.RoutingChangePreview{}
+	require.Nil(t, json.NewDecoder(rsp.Body).Decode(preview))
+	require.NotEmpty(t, preview.ConfirmationToken)
+	return preview
+}
+
+// sendRoutingChange previews a routing change and applies it with the confirmation token of the preview.
+func sendRoutingChange(t *testing.T, send func(method string, path string, body string) *http.Response,
+	method string, path string, body string) *http.Response {
+	preview := previewRoutingChange(t, send, method, path, body)
+	return send(method, withQueryParameter(path, "confirm="+preview.ConfirmationToken), body)
+}
+
+func TestReadRoutingMissingWrites(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.DualWriteFailureMode = config.DualWriteFailureModeTarget
+	testSetup, auditLog, send, cleanup := startRoutingGuardTest(t, conf, common.ClusterTypeOrigin)
+	defer cleanup()
+
+	rsp := sendRoutingChange(t, send, http.MethodPut, "/admin/routing/tables", `{"Table":"ks.tbl","Cluster":"TARGET"}`)
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+	rsp.Body.Close()
+
+	sendSingleSidedWrite(t, testSetup)
+
+	// rolling the reads back to origin must be acknowledged
+	rsp = sendRoutingChange(t, send, http.MethodPut, "/admin/routing/tables", `{"Table":"ks.tbl","Cluster":"ORIGIN"}`)
+	require.Equal(t, http.StatusConflict, rsp.StatusCode)
+	conflict := &admin.MissingWritesConflict{}
+	require.Nil(t, json.NewDecoder(rsp.Body).Decode(conflict))
+	rsp.Body.Close()
+	require.Equal(t, int64(1), conflict.MissingWrites)
+	require.Equal(t, string(common.ClusterTypeOrigin), conflict.Cluster)
+
+	rsp = sendRoutingChange(t, send, http.MethodDelete, "/admin/routing/tables?table=ks.tbl", "")
+	require.Equal(t, http.StatusConflict, rsp.StatusCode)
+	rsp.Body.Close()
+	cluster, ok := testSetup.Proxy.GetTableReadRouting().Get("ks.tbl")
+	require.True(t, ok)
+	require.Equal(t, common.ClusterTypeTarget, cluster)
+
+	rsp = sendRoutingChange(t, send, http.MethodDelete, "/admin/routing/tables?table=ks.tbl&acknowledge_missing_writes=true", "")
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+	rsp.Body.Close()
+	_, ok = testSetup.Proxy.GetTableReadRouting().Get("ks.tbl")
+	require.False(t, ok)
+
+	rsp = sendRoutingChange(t, send, http.MethodPut, "/admin/routing/weighted", `{"Percentage":50}`)
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+	rsp.Body.Close()
+	rsp = sendRoutingChange(t, send, http.MethodPut, "/admin/routing/weighted", `{"Percentage":0}`)
+	require.Equal(t, http.StatusConflict, rsp.StatusCode)
+	rsp.Body.Close()
+	require.Equal(t, 50, testSetup.Proxy.GetReadShift().GetPercentage())
+
+	entries := auditLog.Tail(0)
+	require.Equal(t, admin.AuditOutcomeFailure, entries[len(entries)-1].Outcome)
+	require.Contains(t, entries[len(entries)-1].Error, "acknowledge_missing_writes=true")
+}
+
+func TestReadCutoverMissingWrites(t *testing.T) {
+	tests := []struct {
+		name               string
+		maxMissingWrites   int
+		expectedStatusCode int
+	}{
+		{"above the threshold", 0, http.StatusConflict},
+		{"within the threshold", 1, http.StatusOK},
+		{"disabled", -1, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			conf.DualWriteFailureMode = config.DualWriteFailureModeOrigin
+			conf.TargetReadCutoverMaxMissingWrites = tt.maxMissingWrites
+			testSetup, _, send, cleanup := startRoutingGuardTest(t, conf, common.ClusterTypeTarget)
+			defer cleanup()
+
+			sendSingleSidedWrite(t, testSetup)
+
+			rsp := sendRoutingChange(t, send, http.MethodPut, "/admin/routing/tables", `{"Table":"ks.tbl","Cluster":"TARGET"}`)
+			require.Equal(t, tt.expectedStatusCode, rsp.StatusCode)
+			rsp.Body.Close()
+			rsp = sendRoutingChange(t, send, http.MethodPut, "/admin/routing/weighted", `{"Percentage":10}`)
+			require.Equal(t, tt.expectedStatusCode, rsp.StatusCode)
+			rsp.Body.Close()
+
+			rsp = sendRoutingChange(t, send, http.MethodPut, "/admin/routing/weighted?acknowledge_missing_writes=true",
+				`{"Percentage":10}`)
+			require.Equal(t, http.StatusOK, rsp.StatusCode)
+			rsp.Body.Close()
+			require.Equal(t, 10, testSetup.Proxy.GetReadShift().GetPercentage())
+		})
+	}
+}
+
+func TestRoutingChangeConfirmation(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.DualWriteFailureMode = config.DualWriteFailureModeOrigin
+	testSetup, auditLog, send, cleanup := startRoutingGuardTest(t, conf, common.ClusterTypeTarget)
+	defer cleanup()
+
+	sendSingleSidedWrite(t, testSetup)
+
+	// a dry run returns the routing change without applying it
+	const routeToTarget = `{"Table":"ks.tbl","Cluster":"TARGET"}`
+	preview := previewRoutingChange(t, send, http.MethodPut, "/admin/routing/tables", routeToTarget)
+	require.Equal(t, map[string]interface{}{"PrimaryCluster": "ORIGIN", "Tables": map[string]interface{}{}},
+		preview.Before)
+	require.Equal(t, map[string]interface{}{"PrimaryCluster": "ORIGIN", "Tables": map[string]interface{}{
+		"ks.tbl": "TARGET"}}, preview.After)
+	require.Equal(t, int64(1), preview.MissingWrites)
+	require.True(t, preview.MissingWritesRequireAcknowledgement)
+	require.True(t, preview.ExpiresAt.After(time.Now()))
+	_, ok := testSetup.Proxy.GetTableReadRouting().Get("ks.tbl")
+	require.False(t, ok)
+	require.Empty(t, auditLog.Tail(0))
+
+	rsp := send(http.MethodPut, "/admin/routing/tables?dry_run=true", `{"Table":"system.local","Cluster":"TARGET"}`)
+	require.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+	rsp.Body.Close()
+
+	refused := []struct {
+		name          string
+		method        string
+		path          string
+		body          string
+		expectedCode  int
+		expectedError string
+	}{
+		{"without token", http.MethodPut, "/admin/routing/tables?acknowledge_missing_writes=true", routeToTarget,
+			http.StatusBadRequest, "confirm=<token>"},
+		{"with fixed value", http.MethodPut, "/admin/routing/tables?confirm=true&acknowledge_missing_writes=true",
+			routeToTarget, http.StatusBadRequest, "unknown or expired confirmation token"},
+		{"with token of another change", http.MethodPut,
+			"/admin/routing/tables?acknowledge_missing_writes=true&confirm=" + preview.ConfirmationToken,
+			`{"Table":"ks.other","Cluster":"TARGET"}`, http.StatusBadRequest, "issued for another routing change"},
+		{"with used token", http.MethodPut,
+			"/admin/routing/tables?acknowledge_missing_writes=true&confirm=" + preview.ConfirmationToken,
+			routeToTarget, http.StatusBadRequest, "unknown or expired confirmation token"},
+	}
+	for _, tt := range refused {
+		rsp = send(tt.method, tt.path, tt.body)
+		require.Equal(t, tt.expectedCode, rsp.StatusCode, tt.name)
+		rsp.Body.Close()
+		entries := auditLog.Tail(0)
+		require.Equal(t, admin.AuditOutcomeFailure, entries[len(entries)-1].Outcome, tt.name)
+		require.Contains(t, entries[len(entries)-1].Error, tt.expectedError, tt.name)
+	}
+	_, ok = testSetup.Proxy.GetTableReadRouting().Get("ks.tbl")
+	require.False(t, ok)
+
+	// the token is bound to the routing that was previewed
+	preview = previewRoutingChange(t, send, http.MethodPut, "/admin/routing/tables", routeToTarget)
+	require.Nil(t, testSetup.Proxy.GetTableReadRouting().Set("ks.other", common.ClusterTypeTarget))
+	rsp = send(http.MethodPut, "/admin/routing/tables?acknowledge_missing_writes=true&confirm="+
+		preview.ConfirmationToken, routeToTarget)
+	require.Equal(t, http.StatusConflict, rsp.StatusCode)
+	rsp.Body.Close()
+	entries := auditLog.Tail(0)
+	require.Contains(t, entries[len(entries)-1].Error, "the routing changed since the preview")
+
+	rsp = sendRoutingChange(t, send, http.MethodPut, "/admin/routing/tables?acknowledge_missing_writes=true",
+		routeToTarget)
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+	rsp.Body.Close()
+	cluster, ok := testSetup.Proxy.GetTableReadRouting().Get("ks.tbl")
+	require.True(t, ok)
+	require.Equal(t, common.ClusterTypeTarget, cluster)
+
+	rsp = send(http.MethodDelete, "/admin/routing/tables?table=ks.tbl", "")
+	require.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+	rsp.Body.Close()
+	preview = previewRoutingChange(t, send, http.MethodDelete, "/admin/routing/tables?table=ks.tbl", "")
+	require.Equal(t, map[string]interface{}{"PrimaryCluster": "ORIGIN", "Tables": map[string]interface{}{
+		"ks.other": "TARGET"}}, preview.After)
+	_, ok = testSetup.Proxy.GetTableReadRouting().Get("ks.tbl")
+	require.True(t, ok)
+
+	preview = previewRoutingChange(t, send, http.MethodPut, "/admin/routing/weighted", `{"Percentage":10}`)
+	require.Equal(t, float64(0), preview.Before.(map[string]interface{})["Percentage"])
+	require.Equal(t, float64(10), preview.After.(map[string]interface{})["Percentage"])
+	require.Equal(t, int64(1), preview.MissingWrites)
+	require.True(t, preview.MissingWritesRequireAcknowledgement)
+
+	rsp = send(http.MethodPut, "/admin/routing/weighted?dry_run=true", `{"Percentage":101}`)
+	require.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+	rsp.Body.Close()
+	rsp = send(http.MethodPut, "/admin/routing/weighted?acknowledge_missing_writes=true", `{"Percentage":10}`)
+	require.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+	rsp.Body.Close()
+	require.Equal(t, 0, testSetup.Proxy.GetReadShift().GetPercentage())
+
+	// who applied the change is published to the event stream
+	events, unsubscribe := testSetup.Proxy.GetEvents().Subscribe()
+	defer unsubscribe()
+	rsp = send(http.MethodPut, "/admin/routing/weighted?acknowledge_missing_writes=true&confirm="+
+		preview.ConfirmationToken, `{"Percentage":10}`)
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+	rsp.Body.Close()
+	require.Equal(t, 10, testSetup.Proxy.GetReadShift().GetPercentage())
+	var routingEvents []*zdmproxy.ProxyEvent
+	for len(events) > 0 {
+		event := <-events
+		if event.Type == zdmproxy.ProxyEventReadRoutingChanged || event.Type == zdmproxy.ProxyEventRoutingChangeApplied {
+			routingEvents = append(routingEvents, event)
+		}
+	}
+	require.Len(t, routingEvents, 2)
+	require.Equal(t, zdmproxy.ProxyEventReadRoutingChanged, routingEvents[0].Type)
+	require.Equal(t, "10% of the reads shifted to TARGET", routingEvents[0].Message)
+	require.Equal(t, zdmproxy.ProxyEventRoutingChangeApplied, routingEvents[1].Type)
+	require.Contains(t, routingEvents[1].Message, "SetTargetReadPercentage applied through the admin API by 127.0.0.1:")
+}
@@ -94,3 +94,21 @@ func TestRunWithRetries(t *testing.T) {
 		return nil, false
 	}, 10, 100*time.Millisecond)
 }
+
+// TestRunWithRetries_StartupTimeout tests that the proxy stops retrying once ZDM_PROXY_STARTUP_TIMEOUT_MS elapses
+func TestRunWithRetries_StartupTimeout(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2") // nothing is listening on these addresses
+	conf.ProxyStartupTimeoutMs = 1000
+	b := &backoff.Backoff{
+		Factor: 2,
+		Jitter: false,
+		Min:    100 * time.Millisecond,
+		Max:    200 * time.Millisecond,
+	}
+
+	start := time.Now()
+	p, err := zdmproxy.RunWithRetries(conf, context.Background(), b)
+	require.Nil(t, p)
+	require.True(t, errors.Is(err, zdmproxy.StartupTimeoutErr), err)
+	require.Less(t, time.Since(start), 5*time.Second)
+}
@@ -0,0 +1,86 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestSchemaChangeWarnings(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		newSchemaChangeHandler(nil, false),
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {})}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		newSchemaChangeHandler([]string{"target warning"}, true),
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {})}
+
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	tests := []struct {
+		name             string
+		query            string
+		expectedMessage  message.Message
+		expectedWarnings []string
+	}{
+		{
+			name:             "succeeded on both clusters",
+			query:            "CREATE TABLE ks.tbl (pk int PRIMARY KEY)",
+			expectedMessage:  &message.SchemaChangeResult{},
+			expectedWarnings: []string{"TARGET: target warning"},
+		},
+		{
+			name:            "failed on target only",
+			query:           "CREATE TABLE ks.fail (pk int PRIMARY KEY)",
+			expectedMessage: &message.Invalid{},
+			expectedWarnings: []string{
+				"target warning",
+				"Schema change was applied on ORIGIN but failed on TARGET, the schemas of the two clusters may now differ."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{Query: tt.query})
+			response, err := testSetup.Client.CqlConnection.SendAndReceive(request)
+			require.Nil(t, err)
+			require.IsType(t, tt.expectedMessage, response.Body.Message)
+			require.Equal(t, tt.expectedWarnings, response.Body.Warnings)
+		})
+	}
+}
+
+// Answers CREATE TABLE queries with a SCHEMA_CHANGE result or, if failOnFailTable is true and the table is named
+// "fail", with an error. The provided warnings are added to every response.
+func newSchemaChangeHandler(warnings []string, failOnFailTable bool) client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		query, ok := request.Body.Message.(*message.Query)
+		if !ok || !strings.HasPrefix(query.Query, "CREATE TABLE") {
+			return nil
+		}
+		var response *frame.Frame
+		if failOnFailTable && query.Query == "CREATE TABLE ks.fail (pk int PRIMARY KEY)" {
+			response = frame.NewFrame(request.Header.Version, request.Header.StreamId,
+				&message.Invalid{ErrorMessage: "invalid table"})
+		} else {
+			response = frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.SchemaChangeResult{
+				ChangeType: primitive.SchemaChangeTypeCreated,
+				Target:     primitive.SchemaChangeTargetTable,
+				Keyspace:   "ks",
+				Object:     "tbl",
+			})
+		}
+		response.SetWarnings(warnings)
+		return response
+	}
+}
@@ -437,6 +437,7 @@ func NewTestConfig(originHost string, targetHost string) *config.Config {
 
 	conf.ProxyMaxClientConnections = 1000
 	conf.ProxyMaxStreamIds = 2048
+	conf.ProxyMaxFrameSizeBytes = 268435456
 
 	conf.RequestResponseMaxWorkers = -1
 	conf.WriteMaxWorkers = -1
@@ -451,10 +452,26 @@ func NewTestConfig(originHost string, targetHost string) *config.Config {
 	conf.PrimaryCluster = config.PrimaryClusterOrigin
 	conf.ReadMode = config.ReadModePrimaryOnly
 	conf.SystemQueriesMode = config.SystemQueriesModeOrigin
+	conf.TargetReadShiftKey = config.TargetReadShiftKeyStatement
+	conf.WriteTimestampFloorMode = config.WriteTimestampFloorModeWarn
+	conf.DualWriteFailureMode = config.DualWriteFailureModeEither
+	conf.LwtMode = config.LwtModeDual
+	conf.CounterWriteMode = config.CounterWriteModeDual
+	conf.SecondaryWriteRetryBackoffMs = 100
+	conf.SecondaryWriteRetryErrorCodes = "OVERLOADED,IS_BOOTSTRAPPING,UNAVAILABLE"
+	conf.TargetReadRampIntervalSecs = 600
+	conf.TargetReadRampMinReads = 100
+	conf.TargetReadRampMaxErrorRatio = 0.01
 	conf.AsyncHandshakeTimeoutMs = 4000
 	conf.ControlConnMaxProtocolVersion = "DseV2"
 
 	conf.ProxyRequestTimeoutMs = 10000
+	conf.ProxyClientWriteTimeoutMs = 30000
+	conf.ProxyClusterWriteTimeoutMs = 30000
+	conf.ProxyClientHandshakeTimeoutMs = 10000
+	conf.ProxyControlConnectionReadTimeoutMs = 10000
+	conf.ProxyControlConnectionWriteTimeoutMs = 5000
+	conf.ProxyHttpShutdownTimeoutMs = 5000
 
 	conf.LogLevel = "INFO"
 
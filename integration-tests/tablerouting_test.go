@@ -0,0 +1,73 @@
+package integration_tests
+
+import (
+	"context"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestTableReadRouting(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	lock := &sync.Mutex{}
+	receivedReads := map[common.ClusterType][]string{}
+	newReadHandler := func(cluster common.ClusterType) client.RequestHandler {
+		return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+			query, ok := request.Body.Message.(*message.Query)
+			if !ok || !strings.HasPrefix(query.Query, "SELECT * FROM ks.") {
+				return nil
+			}
+			lock.Lock()
+			receivedReads[cluster] = append(receivedReads[cluster], query.Query)
+			lock.Unlock()
+			return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+		}
+	}
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+		newReadHandler(common.ClusterTypeOrigin)}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+		newReadHandler(common.ClusterTypeTarget)}
+
+	err = testSetup.Start(conf, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	testClient := client.NewCqlClient("127.0.0.1:14002", &client.AuthCredentials{
+		Username: conf.TargetUsername,
+		Password: conf.TargetPassword,
+	})
+	conn, err := testClient.ConnectAndInit(context.Background(), primitive.ProtocolVersion4, 1)
+	require.Nil(t, err)
+	defer conn.Close()
+
+	read := func(query string) {
+		rsp, err := conn.SendAndReceive(frame.NewFrame(primitive.ProtocolVersion4, 2, &message.Query{Query: query}))
+		require.Nil(t, err)
+		require.Equal(t, primitive.OpCodeResult, rsp.Header.OpCode)
+	}
+
+	routing := testSetup.Proxy.GetTableReadRouting()
+	require.Nil(t, routing.Set("ks.cutover", common.ClusterTypeTarget))
+	read("SELECT * FROM ks.cutover")
+	read("SELECT * FROM ks.other")
+
+	require.True(t, routing.Remove("ks.cutover"))
+	read("SELECT * FROM ks.cutover")
+
+	lock.Lock()
+	defer lock.Unlock()
+	require.Equal(t, []string{"SELECT * FROM ks.other", "SELECT * FROM ks.cutover"}, receivedReads[common.ClusterTypeOrigin])
+	require.Equal(t, []string{"SELECT * FROM ks.cutover"}, receivedReads[common.ClusterTypeTarget])
+}
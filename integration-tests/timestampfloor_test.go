@@ -0,0 +1,80 @@
+package integration_tests
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteTimestampFloor(t *testing.T) {
+	floor := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.WriteTimestampFloor = floor.Format(time.RFC3339)
+	conf.WriteTimestampFloorMode = "REJECT"
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	insertHandler := func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		query, ok := request.Body.Message.(*message.Query)
+		if !ok || !strings.HasPrefix(query.Query, "INSERT") {
+			return nil
+		}
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+	}
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		insertHandler,
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {})}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		insertHandler,
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {})}
+
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	staleTimestamp := floor.Add(-time.Hour).UnixMicro()
+	recentTimestamp := floor.Add(time.Hour).UnixMicro()
+	tests := []struct {
+		name            string
+		query           *message.Query
+		expectedMessage message.Message
+	}{
+		{
+			name:            "no timestamp",
+			query:           &message.Query{Query: "INSERT INTO ks.tbl (pk) VALUES (1)"},
+			expectedMessage: &message.VoidResult{},
+		},
+		{
+			name:            "recent timestamp",
+			query:           &message.Query{Query: fmt.Sprintf("INSERT INTO ks.tbl (pk) VALUES (1) USING TIMESTAMP %d", recentTimestamp)},
+			expectedMessage: &message.VoidResult{},
+		},
+		{
+			name:            "stale timestamp",
+			query:           &message.Query{Query: fmt.Sprintf("INSERT INTO ks.tbl (pk) VALUES (1) USING TIMESTAMP %d", staleTimestamp)},
+			expectedMessage: &message.Invalid{},
+		},
+		{
+			name: "stale request timestamp",
+			query: &message.Query{
+				Query:   "INSERT INTO ks.tbl (pk) VALUES (1)",
+				Options: &message.QueryOptions{DefaultTimestamp: &staleTimestamp}},
+			expectedMessage: &message.Invalid{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := testSetup.Client.CqlConnection.SendAndReceive(
+				frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, tt.query))
+			require.Nil(t, err)
+			require.IsType(t, tt.expectedMessage, response.Body.Message)
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package integration_tests
+
+import (
+	"context"
+	"crypto/tls"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+const (
+	OriginNodeCertRelPath = "../integration-tests/resources/node1-zdm.crt"
+	OriginNodeKeyRelPath  = "../integration-tests/resources/node1-zdm.key"
+)
+
+// TestTls_OriginHostnameValidation uses a certificate that is only valid for 127.0.0.1 (and "node1") on the origin node.
+func TestTls_OriginHostnameValidation(t *testing.T) {
+	tests := []struct {
+		name               string
+		originAddress      string
+		hostnameValidation bool
+		errExpected        bool
+	}{
+		{"certificate valid for the node address", "127.0.0.1", true, false},
+		{"certificate not valid for the node address", "127.0.1.1", true, true},
+		{"hostname validation disabled", "127.0.1.1", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := setup.NewTestConfig(tt.originAddress, "127.0.1.2")
+			conf.OriginTlsServerCaPath = OriginCaCertRelPath
+			conf.OriginTlsHostnameValidation = tt.hostnameValidation
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			nodeCert, err := tls.LoadX509KeyPair(OriginNodeCertRelPath, OriginNodeKeyRelPath)
+			require.Nil(t, err)
+			testSetup.Origin.CqlServer.TLSConfig = &tls.Config{Certificates: []tls.Certificate{nodeCert}}
+
+			err = testSetup.Start(conf, false, primitive.ProtocolVersion4)
+			if tt.errExpected {
+				require.NotNil(t, err)
+				require.Contains(t, err.Error(), "could not open control connection to ORIGIN")
+				return
+			}
+			require.Nil(t, err)
+
+			testClient := client.NewCqlClient("127.0.0.1:14002", &client.AuthCredentials{
+				Username: conf.TargetUsername,
+				Password: conf.TargetPassword,
+			})
+			conn, err := testClient.ConnectAndInit(context.Background(), primitive.ProtocolVersion4, 1)
+			require.Nil(t, err)
+			defer conn.Close()
+			rsp, err := conn.SendAndReceive(frame.NewFrame(primitive.ProtocolVersion4, 2, &message.Options{}))
+			require.Nil(t, err)
+			require.Equal(t, primitive.OpCodeSupported, rsp.Header.OpCode)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSecondaryWriteRetry(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.SecondaryWriteRetryAttempts = 2
+	conf.SecondaryWriteRetryBackoffMs = 10
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	lock := &sync.Mutex{}
+	targetAttempts := map[string]int{}
+	targetFailures := map[string]int{
+		"INSERT INTO ks.tbl (pk) VALUES (1)": 1,
+		"INSERT INTO ks.tbl (pk) VALUES (2)": 100,
+	}
+	originHandler := func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		query, ok := request.Body.Message.(*message.Query)
+		if !ok || !strings.HasPrefix(query.Query, "INSERT") {
+			return nil
+		}
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+	}
+	// fails the number of times in targetFailures with OVERLOADED, fails the other writes with INVALID
+	targetHandler := func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		query, ok := request.Body.Message.(*message.Query)
+		if !ok || !strings.HasPrefix(query.Query, "INSERT") {
+			return nil
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		targetAttempts[query.Query]++
+		failures, ok := targetFailures[query.Query]
+		var response message.Message = &message.VoidResult{}
+		if !ok {
+			response = &message.Invalid{ErrorMessage: "invalid"}
+		} else if targetAttempts[query.Query] <= failures {
+			response = &message.Overloaded{ErrorMessage: "overloaded"}
+		}
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId, response)
+	}
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		originHandler,
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {})}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		targetHandler,
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {})}
+
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	tests := []struct {
+		name             string
+		query            string
+		expectedMessage  message.Message
+		expectedAttempts int
+	}{
+		{"succeeds after a retry", "INSERT INTO ks.tbl (pk) VALUES (1)", &message.VoidResult{}, 2},
+		{"fails after all the retries", "INSERT INTO ks.tbl (pk) VALUES (2)", &message.Overloaded{}, 3},
+		{"not retryable", "INSERT INTO ks.tbl (pk) VALUES (3)", &message.Invalid{}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := testSetup.Client.CqlConnection.SendAndReceive(
+				frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{Query: tt.query}))
+			require.Nil(t, err)
+			require.IsType(t, tt.expectedMessage, response.Body.Message)
+			lock.Lock()
+			defer lock.Unlock()
+			require.Equal(t, tt.expectedAttempts, targetAttempts[tt.query])
+		})
+	}
+}
@@ -4,17 +4,21 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/admin"
+	"github.com/datastax/zdm-proxy/proxy/pkg/buildinfo"
 	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/crash"
+	"github.com/datastax/zdm-proxy/proxy/pkg/loadgen"
 	"github.com/datastax/zdm-proxy/proxy/pkg/runner"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
 	log "github.com/sirupsen/logrus"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 )
 
-// TODO: to be managed externally
-const ZdmVersionString = "2.3.0"
-
 var displayVersion = flag.Bool("version", false, "display the ZDM proxy version and exit")
 var configFile = flag.String("config", "", "specify path to ZDM configuration file")
 
@@ -31,26 +35,172 @@ func runSignalListener(cancelFunc context.CancelFunc) {
 	}()
 }
 
+// runReadinessCheck runs the pre-flight checks against both clusters and prints the report, the returned exit code is
+// non-zero if there are blockers.
+func runReadinessCheck(args []string) int {
+	checkFlags := flag.NewFlagSet("check", flag.ExitOnError)
+	checkConfigFile := checkFlags.String("config", *configFile, "specify path to ZDM configuration file")
+	_ = checkFlags.Parse(args)
+
+	conf, err := config.New().LoadConfig(*checkConfigFile)
+	if err != nil {
+		fmt.Printf("[FAIL] Configuration: %v\n", err)
+		return 1
+	}
+
+	// the report is printed to stdout, only warnings and errors are logged unless a more verbose level is configured
+	logLevel, err := conf.ParseLogLevel()
+	if err != nil || logLevel < log.DebugLevel {
+		logLevel = log.WarnLevel
+	}
+	log.SetLevel(logLevel)
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	runSignalListener(cancelFunc)
+
+	report := zdmproxy.RunReadinessCheck(conf, ctx)
+	report.Print(os.Stdout)
+	if report.HasBlockers() {
+		return 1
+	}
+	return 0
+}
+
+// runTop renders a live status screen of a running proxy instance until SIGINT/SIGTERM.
+func runTop(args []string) int {
+	topFlags := flag.NewFlagSet("top", flag.ExitOnError)
+	url := topFlags.String("url", "http://localhost:14001", "base URL of the proxy metrics and admin API server")
+	token := topFlags.String("token", os.Getenv("ZDM_ADMIN_TOKEN"), "admin API token (default $ZDM_ADMIN_TOKEN)")
+	metricsPrefix := topFlags.String("metrics-prefix", "zdm", "metrics prefix configured on the proxy")
+	interval := topFlags.Duration("interval", 2*time.Second, "refresh interval")
+	_ = topFlags.Parse(args)
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	runSignalListener(cancelFunc)
+
+	err := admin.RunTop(ctx, os.Stdout, admin.TopOptions{
+		Url:           *url,
+		Token:         *token,
+		MetricsPrefix: *metricsPrefix,
+		Interval:      *interval,
+	})
+	if err != nil {
+		fmt.Printf("Could not connect to the proxy at %v: %v\n", *url, err)
+		return 1
+	}
+	return 0
+}
+
+// runExplain prints the routing decision that a running proxy instance would make for a CQL statement.
+func runExplain(args []string) int {
+	explainFlags := flag.NewFlagSet("explain", flag.ExitOnError)
+	url := explainFlags.String("url", "http://localhost:14001", "base URL of the proxy metrics and admin API server")
+	token := explainFlags.String("token", os.Getenv("ZDM_ADMIN_TOKEN"), "admin API token (default $ZDM_ADMIN_TOKEN)")
+	keyspace := explainFlags.String("keyspace", "", "current keyspace of the client (USE), for unqualified table names")
+	clientIp := explainFlags.String("client-ip", "", "IP address of the client, for ZDM_CLIENT_READ_ROUTING and ZDM_FEATURE_FLAGS")
+	jsonOutput := explainFlags.Bool("json", false, "print the routing decision as JSON")
+	_ = explainFlags.Parse(args)
+	if explainFlags.NArg() != 1 {
+		fmt.Println("Usage: explain [flags] <CQL statement>")
+		explainFlags.PrintDefaults()
+		return 2
+	}
+
+	err := admin.RunExplain(context.Background(), os.Stdout, admin.ExplainOptions{
+		Url:   *url,
+		Token: *token,
+		Request: admin.RoutingExplainRequest{
+			Statement: explainFlags.Arg(0),
+			Keyspace:  *keyspace,
+			ClientIp:  *clientIp,
+		},
+		Json: *jsonOutput,
+	})
+	if err != nil {
+		fmt.Printf("Could not explain the routing of the statement: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runLoadgen sends a synthetic CQL workload through a proxy instance (or any cluster) and reports the client observed
+// latencies until the duration elapses or SIGINT/SIGTERM.
+func runLoadgen(args []string) int {
+	loadgenFlags := flag.NewFlagSet("loadgen", flag.ExitOnError)
+	hosts := loadgenFlags.String("hosts", "localhost", "comma separated list of proxy (or cluster) addresses")
+	options := loadgen.Options{}
+	loadgenFlags.IntVar(&options.Port, "port", 9042, "CQL port")
+	loadgenFlags.StringVar(&options.Username, "username", "", "username")
+	loadgenFlags.StringVar(&options.Password, "password", os.Getenv("ZDM_LOADGEN_PASSWORD"), "password (default $ZDM_LOADGEN_PASSWORD)")
+	loadgenFlags.StringVar(&options.Keyspace, "keyspace", "zdm_loadgen", "keyspace of the table that is read and written")
+	loadgenFlags.StringVar(&options.Table, "table", "kv", "table that is read and written")
+	loadgenFlags.BoolVar(&options.CreateSchema, "create-schema", false, "create the keyspace and the table if they don't exist")
+	loadgenFlags.IntVar(&options.ReplicationFactor, "replication-factor", 3, "replication factor of the keyspace created by -create-schema")
+	loadgenFlags.StringVar(&options.Consistency, "consistency", "LOCAL_QUORUM", "consistency level of the reads and writes")
+	loadgenFlags.IntVar(&options.Concurrency, "concurrency", 32, "number of concurrent requests")
+	loadgenFlags.DurationVar(&options.Duration, "duration", time.Minute, "duration of the workload, 0 means until SIGINT/SIGTERM")
+	loadgenFlags.IntVar(&options.Rate, "rate", 0, "maximum number of operations per second, 0 means no limit")
+	loadgenFlags.Float64Var(&options.ReadRatio, "read-ratio", 0.8, "fraction (between 0 and 1) of the operations that are reads")
+	loadgenFlags.Int64Var(&options.Partitions, "partitions", 1000000, "number of partitions")
+	loadgenFlags.IntVar(&options.RowsPerPartition, "rows-per-partition", 10, "number of rows per partition")
+	loadgenFlags.StringVar(&options.Distribution, "distribution", loadgen.DistributionUniform,
+		fmt.Sprintf("partition key distribution: %v or %v (a few hot partitions)", loadgen.DistributionUniform, loadgen.DistributionZipf))
+	loadgenFlags.IntVar(&options.PayloadMinBytes, "payload-min-bytes", 100, "minimum size of the written payloads")
+	loadgenFlags.IntVar(&options.PayloadMaxBytes, "payload-max-bytes", 1000, "maximum size of the written payloads")
+	loadgenFlags.DurationVar(&options.ReportInterval, "report-interval", 10*time.Second, "interval of the latency reports")
+	_ = loadgenFlags.Parse(args)
+	options.Hosts = strings.Split(*hosts, ",")
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	runSignalListener(cancelFunc)
+
+	err := loadgen.Run(ctx, os.Stdout, options)
+	if err != nil {
+		fmt.Printf("Load generation failed: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
 func launchProxy(profilingSupported bool) {
+	defer crash.HandlePanic()
+
 	if *displayVersion {
-		fmt.Printf("ZDM proxy version %v\n", ZdmVersionString)
+		fmt.Printf("ZDM proxy version %v\n", buildinfo.Get())
 		return
 	}
 
+	switch flag.Arg(0) {
+	case "check":
+		os.Exit(runReadinessCheck(flag.Args()[1:]))
+	case "top":
+		os.Exit(runTop(flag.Args()[1:]))
+	case "loadgen":
+		os.Exit(runLoadgen(flag.Args()[1:]))
+	case "explain":
+		os.Exit(runExplain(flag.Args()[1:]))
+	}
+
 	// Always record version information (very) early in the log
-	log.Infof("Starting ZDM proxy version %v", ZdmVersionString)
+	log.Infof("Starting ZDM proxy version %v", buildinfo.Get())
 
 	conf, err := config.New().LoadConfig(*configFile)
 
 	if err != nil {
 		log.Errorf("Error loading configuration: %v. Aborting startup.", err)
-		os.Exit(-1)
+		os.Exit(runner.ExitCodeConfigError)
+	}
+	if conf.ProxyCrashReportFile != "" {
+		crash.SetReportFile(conf.ProxyCrashReportFile)
 	}
 
 	logLevel, err := conf.ParseLogLevel()
 	if err != nil {
 		log.Errorf("Error loading log level configuration: %v. Aborting startup.", err)
-		os.Exit(-1)
+		os.Exit(runner.ExitCodeConfigError)
 	}
 	log.SetLevel(logLevel)
 
@@ -65,5 +215,9 @@ func launchProxy(profilingSupported bool) {
 	log.Info("SIGINT/SIGTERM listener started.")
 
 	metricsHandler, readinessHandler := runner.SetupHandlers()
-	runner.RunMain(conf, ctx, metricsHandler, readinessHandler)
+	err = runner.RunMain(conf, ctx, metricsHandler, readinessHandler)
+	if err != nil {
+		log.Errorf("Error starting proxy: %v. Aborting startup.", err)
+		os.Exit(runner.ExitCodeOf(err))
+	}
 }
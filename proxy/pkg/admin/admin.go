@@ -0,0 +1,715 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/buildinfo"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/health"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const PathPrefix = "/admin/"
+
+// Api serves the admin HTTP endpoints. Every endpoint requires a bearer token:
+//   - read only tokens can call endpoints that don't change the state of the proxy
+//   - operator tokens can call every endpoint
+type Api struct {
+	proxy               *zdmproxy.ZdmProxy
+	tokens              map[string]common.AdminRole
+	auditLog            *AuditLog
+	routingChangeTokens *routingChangeTokens
+	mux                 *http.ServeMux
+}
+
+// DefaultHandler is used while the proxy is starting up or when the admin API is disabled.
+func DefaultHandler() http.Handler {
+	return http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		http.Error(rsp, "admin API is not available", http.StatusServiceUnavailable)
+	})
+}
+
+func NewApi(proxy *zdmproxy.ZdmProxy, tokens map[string]common.AdminRole, auditLog *AuditLog) *Api {
+	api := &Api{
+		proxy:               proxy,
+		tokens:              tokens,
+		auditLog:            auditLog,
+		routingChangeTokens: newRoutingChangeTokens(),
+		mux:                 http.NewServeMux(),
+	}
+	api.handle("/admin/status", common.AdminRoleReadOnly, api.statusHandler)
+	api.handle("/admin/log-level", common.AdminRoleReadOnly, api.logLevelHandler)
+	api.handle("/admin/audit", common.AdminRoleReadOnly, api.auditHandler)
+	api.handle("/admin/clients", common.AdminRoleReadOnly, api.clientsHandler)
+	api.handle("/admin/clients/drain", common.AdminRoleReadOnly, api.drainHandler)
+	api.handle("/admin/topology", common.AdminRoleReadOnly, api.topologyHandler)
+	api.handle("/admin/nodes/health", common.AdminRoleReadOnly, api.nodeHealthHandler)
+	api.handle("/admin/consistency", common.AdminRoleReadOnly, api.consistencyHandler)
+	api.handle("/admin/routing/tables", common.AdminRoleReadOnly, api.tableReadRoutingHandler)
+	api.handle("/admin/routing/weighted", common.AdminRoleReadOnly, api.readShiftHandler)
+	api.handle("/admin/routing/explain", common.AdminRoleReadOnly, api.routingExplainHandler)
+	api.handle("/admin/features", common.AdminRoleReadOnly, api.featureFlagsHandler)
+	api.handle("/admin/events", common.AdminRoleReadOnly, api.eventsHandler)
+	api.handle("/admin/config", common.AdminRoleReadOnly, api.configHandler)
+	api.handle("/admin/config/validate", common.AdminRoleReadOnly, api.configValidateHandler)
+	return api
+}
+
+func (recv *Api) ServeHTTP(rsp http.ResponseWriter, req *http.Request) {
+	recv.mux.ServeHTTP(rsp, req)
+}
+
+// handle registers an endpoint that requires at least the provided role. Handlers that change state must also call
+// requireOperator for the relevant HTTP methods and record the outcome in the audit log.
+func (recv *Api) handle(path string, role common.AdminRole, handler http.HandlerFunc) {
+	recv.mux.Handle(path, http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		if !recv.authorize(rsp, req, role) {
+			return
+		}
+		handler(rsp, req)
+	}))
+}
+
+// authorize checks the bearer token of the request and writes an error response if the token doesn't grant the
+// provided role. Operators are allowed to call read only endpoints.
+func (recv *Api) authorize(rsp http.ResponseWriter, req *http.Request, role common.AdminRole) bool {
+	callerRole := recv.roleOf(req)
+	if callerRole == common.AdminRoleNone {
+		rsp.Header().Set("WWW-Authenticate", `Bearer realm="zdm-proxy"`)
+		http.Error(rsp, "missing or invalid token", http.StatusUnauthorized)
+		return false
+	}
+	if role == common.AdminRoleOperator && callerRole != common.AdminRoleOperator {
+		log.Warnf("Admin API call %v %v from %v rejected: operator role required.", req.Method, req.URL.Path, req.RemoteAddr)
+		http.Error(rsp, "operator role required", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// requireOperator is used by endpoints that change state, rejected attempts are recorded in the audit log.
+func (recv *Api) requireOperator(rsp http.ResponseWriter, req *http.Request, action string) bool {
+	if recv.authorize(rsp, req, common.AdminRoleOperator) {
+		return true
+	}
+	entry := recv.newAuditEntry(req, action)
+	entry.Outcome = AuditOutcomeDenied
+	recv.auditLog.Record(entry)
+	return false
+}
+
+func bearerToken(req *http.Request) string {
+	authorization := req.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(authorization, "Bearer "))
+}
+
+func (recv *Api) roleOf(req *http.Request) common.AdminRole {
+	token := bearerToken(req)
+	if token == "" {
+		return common.AdminRoleNone
+	}
+
+	// compare against every token to avoid leaking which tokens exist through timing
+	role := common.AdminRoleNone
+	for configuredToken, configuredRole := range recv.tokens {
+		if subtle.ConstantTimeCompare([]byte(configuredToken), []byte(token)) == 1 {
+			role = configuredRole
+		}
+	}
+	return role
+}
+
+type StatusReport struct {
+	Build          *buildinfo.BuildInfo
+	Health         *health.StatusReport
+	PrimaryCluster string
+	ReadMode       string
+	ActiveClients  int
+	LogLevel       string
+}
+
+func (recv *Api) statusHandler(rsp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(rsp, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJson(rsp, http.StatusOK, &StatusReport{
+		Build:          buildinfo.Get(),
+		Health:         health.PerformHealthCheck(recv.proxy),
+		PrimaryCluster: string(recv.proxy.GetPrimaryCluster()),
+		ReadMode:       recv.proxy.GetReadMode().String(),
+		ActiveClients:  recv.proxy.GetActiveClients(),
+		LogLevel:       log.GetLevel().String(),
+	})
+}
+
+type LogLevel struct {
+	Level string
+}
+
+func (recv *Api) logLevelHandler(rsp http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		writeJson(rsp, http.StatusOK, &LogLevel{Level: log.GetLevel().String()})
+	case http.MethodPut:
+		const action = "SetLogLevel"
+		if !recv.requireOperator(rsp, req, action) {
+			return
+		}
+		entry := recv.newAuditEntry(req, action)
+		oldLevel := log.GetLevel()
+		entry.Before = &LogLevel{Level: oldLevel.String()}
+
+		var body LogLevel
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			err = fmt.Errorf("invalid request body: %w", err)
+		}
+		var level log.Level
+		if err == nil {
+			level, err = log.ParseLevel(body.Level)
+		}
+		if err != nil {
+			entry.Outcome = AuditOutcomeFailure
+			entry.Error = err.Error()
+			recv.auditLog.Record(entry)
+			http.Error(rsp, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		log.SetLevel(level)
+		log.Infof("Log level changed from %v to %v through the admin API by %v.", oldLevel, level, req.RemoteAddr)
+		entry.After = &LogLevel{Level: level.String()}
+		entry.Outcome = AuditOutcomeSuccess
+		recv.auditLog.Record(entry)
+		writeJson(rsp, http.StatusOK, &LogLevel{Level: level.String()})
+	default:
+		http.Error(rsp, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+type ClientConnections struct {
+	Addresses []string
+}
+
+func (recv *Api) clientsHandler(rsp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(rsp, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJson(rsp, http.StatusOK, &ClientConnections{Addresses: recv.proxy.GetClientConnections()})
+}
+
+// DrainRequest identifies the client connections to drain: either a single connection (IP:port as returned by
+// /admin/clients) or every connection from an IP address.
+type DrainRequest struct {
+	Address string
+}
+
+func (recv *Api) drainHandler(rsp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(rsp, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	const action = "DrainClientConnections"
+	if !recv.requireOperator(rsp, req, action) {
+		return
+	}
+	entry := recv.newAuditEntry(req, action)
+
+	var body DrainRequest
+	err := json.NewDecoder(req.Body).Decode(&body)
+	if err != nil {
+		err = fmt.Errorf("invalid request body: %w", err)
+	}
+	var drained []string
+	if err == nil {
+		entry.Before = &body
+		drained, err = recv.proxy.DrainClientConnections(strings.TrimSpace(body.Address))
+	}
+	if err != nil {
+		entry.Outcome = AuditOutcomeFailure
+		entry.Error = err.Error()
+		recv.auditLog.Record(entry)
+		http.Error(rsp, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := &ClientConnections{Addresses: drained}
+	entry.After = result
+	if len(drained) == 0 {
+		entry.Outcome = AuditOutcomeFailure
+		entry.Error = "no matching client connections"
+		recv.auditLog.Record(entry)
+		http.Error(rsp, fmt.Sprintf("no client connections match %v", body.Address), http.StatusNotFound)
+		return
+	}
+
+	log.Infof("Draining %d client connection(s) matching %v through the admin API by %v.",
+		len(drained), body.Address, req.RemoteAddr)
+	entry.Outcome = AuditOutcomeSuccess
+	recv.auditLog.Record(entry)
+	writeJson(rsp, http.StatusAccepted, result)
+}
+
+// ProxyTopology contains the addresses of the proxy instances returned to clients in system.peers and the index of
+// this proxy instance in that list.
+type ProxyTopology struct {
+	Addresses []string
+	Index     int
+}
+
+func newProxyTopology(topologyConfig *common.TopologyConfig) *ProxyTopology {
+	addresses := make([]string, 0, len(topologyConfig.Addresses))
+	for _, addr := range topologyConfig.Addresses {
+		addresses = append(addresses, addr.String())
+	}
+	return &ProxyTopology{Addresses: addresses, Index: topologyConfig.Index}
+}
+
+func (recv *Api) topologyHandler(rsp http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		writeJson(rsp, http.StatusOK, newProxyTopology(recv.proxy.GetTopologyConfig()))
+	case http.MethodPut:
+		const action = "UpdateProxyTopology"
+		if !recv.requireOperator(rsp, req, action) {
+			return
+		}
+		entry := recv.newAuditEntry(req, action)
+		entry.Before = newProxyTopology(recv.proxy.GetTopologyConfig())
+
+		var body ProxyTopology
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			err = fmt.Errorf("invalid request body: %w", err)
+		}
+		addresses := make([]net.IP, 0, len(body.Addresses))
+		for _, addr := range body.Addresses {
+			if err != nil {
+				break
+			}
+			parsedAddr := net.ParseIP(strings.TrimSpace(addr))
+			if parsedAddr == nil {
+				err = fmt.Errorf("invalid proxy address: %v", addr)
+			}
+			addresses = append(addresses, parsedAddr)
+		}
+		var topologyConfig *common.TopologyConfig
+		if err == nil {
+			topologyConfig, err = recv.proxy.UpdateProxyTopology(addresses)
+		}
+		if err != nil {
+			entry.Outcome = AuditOutcomeFailure
+			entry.Error = err.Error()
+			recv.auditLog.Record(entry)
+			http.Error(rsp, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		log.Infof("Proxy topology changed to %v through the admin API by %v.", topologyConfig, req.RemoteAddr)
+		entry.After = newProxyTopology(topologyConfig)
+		entry.Outcome = AuditOutcomeSuccess
+		recv.auditLog.Record(entry)
+		writeJson(rsp, http.StatusOK, entry.After)
+	default:
+		http.Error(rsp, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// NodeHealth contains the health of the nodes in the local datacenter of each cluster, every node is healthy if node
+// health scoring is disabled.
+type NodeHealth struct {
+	Enabled bool
+	Origin  []*zdmproxy.NodeHealthStatus
+	Target  []*zdmproxy.NodeHealthStatus
+}
+
+func (recv *Api) nodeHealthHandler(rsp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(rsp, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	originControlConn := recv.proxy.GetOriginControlConn()
+	targetControlConn := recv.proxy.GetTargetControlConn()
+	writeJson(rsp, http.StatusOK, &NodeHealth{
+		Enabled: originControlConn.IsNodeHealthEnabled(),
+		Origin:  originControlConn.GetNodeHealth(),
+		Target:  targetControlConn.GetNodeHealth(),
+	})
+}
+
+// consistencyHandler returns the consistency levels used by the clients for each table and the replication that the
+// target keyspaces should have, see ZDM_CONSISTENCY_STATS_ENABLED.
+func (recv *Api) consistencyHandler(rsp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(rsp, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJson(rsp, http.StatusOK, recv.proxy.GetConsistencyStats().Report())
+}
+
+// TableReadRouting contains the tables (keyspace.table) whose reads are forwarded to a specific cluster, reads of
+// every other table are forwarded to PrimaryCluster.
+type TableReadRouting struct {
+	PrimaryCluster string
+	Tables         map[string]string
+}
+
+// TableReadRoute routes the reads of a table (keyspace.table) to a cluster (ORIGIN or TARGET).
+type TableReadRoute struct {
+	Table   string
+	Cluster string
+}
+
+func (recv *Api) newTableReadRouting() *TableReadRouting {
+	tables := make(map[string]string)
+	for table, cluster := range recv.proxy.GetTableReadRouting().GetAll() {
+		tables[table] = string(cluster)
+	}
+	return &TableReadRouting{PrimaryCluster: string(recv.proxy.GetPrimaryCluster()), Tables: tables}
+}
+
+// tableReadRoutingHandler allows reads to be cut over (or rolled back) one table at a time:
+//   - PUT routes the reads of the table in the request body to a cluster
+//   - DELETE removes the routing of the table in the "table" query parameter
+//
+// Both are routing changes, see confirmRoutingChange.
+func (recv *Api) tableReadRoutingHandler(rsp http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		writeJson(rsp, http.StatusOK, recv.newTableReadRouting())
+	case http.MethodPut:
+		const action = "SetTableReadRouting"
+		if !recv.requireOperator(rsp, req, action) {
+			return
+		}
+		entry := recv.newAuditEntry(req, action)
+		entry.Before = recv.newTableReadRouting()
+
+		var body TableReadRoute
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			err = fmt.Errorf("invalid request body: %w", err)
+		}
+		table := strings.TrimSpace(body.Table)
+		cluster := common.ClusterType(strings.ToUpper(strings.TrimSpace(body.Cluster)))
+		if err == nil {
+			err = recv.proxy.GetTableReadRouting().Validate(table, cluster)
+		}
+		if err == nil {
+			after := recv.newTableReadRouting()
+			after.Tables[table] = string(cluster)
+			if !recv.confirmRoutingChange(rsp, req, entry, recv.getTableReadCluster(table), cluster, after) {
+				return
+			}
+			err = recv.proxy.GetTableReadRouting().Set(table, cluster)
+		}
+		if err != nil {
+			entry.Outcome = AuditOutcomeFailure
+			entry.Error = err.Error()
+			recv.auditLog.Record(entry)
+			http.Error(rsp, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		entry.After = recv.newTableReadRouting()
+		entry.Outcome = AuditOutcomeSuccess
+		recv.auditLog.Record(entry)
+		recv.logRoutingChange(entry)
+		writeJson(rsp, http.StatusOK, entry.After)
+	case http.MethodDelete:
+		const action = "RemoveTableReadRouting"
+		if !recv.requireOperator(rsp, req, action) {
+			return
+		}
+		entry := recv.newAuditEntry(req, action)
+		entry.Before = recv.newTableReadRouting()
+
+		table := strings.TrimSpace(req.URL.Query().Get("table"))
+		if _, ok := recv.proxy.GetTableReadRouting().Get(table); ok {
+			after := recv.newTableReadRouting()
+			delete(after.Tables, table)
+			if !recv.confirmRoutingChange(
+				rsp, req, entry, recv.getTableReadCluster(table), recv.proxy.GetPrimaryCluster(), after) {
+				return
+			}
+		}
+		if !recv.proxy.GetTableReadRouting().Remove(table) {
+			entry.Outcome = AuditOutcomeFailure
+			entry.Error = "table is not routed"
+			recv.auditLog.Record(entry)
+			http.Error(rsp, fmt.Sprintf("reads of table %v are not routed", table), http.StatusNotFound)
+			return
+		}
+
+		entry.After = recv.newTableReadRouting()
+		entry.Outcome = AuditOutcomeSuccess
+		recv.auditLog.Record(entry)
+		recv.logRoutingChange(entry)
+		writeJson(rsp, http.StatusOK, entry.After)
+	default:
+		http.Error(rsp, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// getTableReadCluster returns the cluster that the reads of a table are forwarded to without taking
+// ZDM_CLIENT_READ_ROUTING and the read shifting into account.
+func (recv *Api) getTableReadCluster(table string) common.ClusterType {
+	if cluster, ok := recv.proxy.GetTableReadRouting().Get(table); ok {
+		return cluster
+	}
+	return recv.proxy.GetPrimaryCluster()
+}
+
+// MissingWritesConflict is returned when reads would be moved to a cluster that misses writes that this instance
+// acknowledged after writing them to the other cluster only, see zdmproxy.SingleSidedWrites.
+type MissingWritesConflict struct {
+	Error         string
+	Cluster       string
+	MissingWrites int64
+}
+
+// checkMissingWrites is called before reads are moved from one cluster to the other. Moving reads to a cluster that
+// misses writes makes these writes invisible to the clients: rolling reads back to origin while it misses writes, or
+// cutting reads over to target while it misses more writes than ZDM_TARGET_READ_CUTOVER_MAX_MISSING_WRITES, must be
+// acknowledged with the acknowledge_missing_writes=true query parameter. Returns false if the change is refused, a
+// conflict response is written and the refusal is recorded in the audit log in that case.
+func (recv *Api) checkMissingWrites(
+	rsp http.ResponseWriter, req *http.Request, entry *AuditEntry, from common.ClusterType, to common.ClusterType) bool {
+	if from == to || (to != common.ClusterTypeOrigin && to != common.ClusterTypeTarget) {
+		return true
+	}
+	missingWrites, ok := recv.proxy.GetSingleSidedWrites().CheckReadsMovedTo(to)
+	if ok {
+		if missingWrites > 0 {
+			log.Warnf("Reads moved to %v through the admin API by %v although %v misses %d writes that were only "+
+				"written to %v.", to, req.RemoteAddr, to, missingWrites, from)
+		}
+		return true
+	}
+	if strings.TrimSpace(req.URL.Query().Get("acknowledge_missing_writes")) == "true" {
+		log.Warnf("Reads moved to %v through the admin API by %v although %v misses %d writes that were only "+
+			"written to %v.", to, req.RemoteAddr, to, missingWrites, from)
+		return true
+	}
+
+	conflict := &MissingWritesConflict{
+		Error: fmt.Sprintf("%v misses %d writes that this proxy instance only wrote to %v, reads moved to %v won't "+
+			"see them; repeat the request with acknowledge_missing_writes=true to move the reads anyway",
+			to, missingWrites, from, to),
+		Cluster:       string(to),
+		MissingWrites: missingWrites,
+	}
+	entry.Outcome = AuditOutcomeFailure
+	entry.Error = conflict.Error
+	recv.auditLog.Record(entry)
+	writeJson(rsp, http.StatusConflict, conflict)
+	return false
+}
+
+// ReadShift contains the percentage of the reads that are shifted to the target cluster while the primary cluster is
+// ORIGIN and what decides which reads are shifted (STATEMENT or PARTITION). Ramp is only set if the automatic ramp is
+// configured (ZDM_TARGET_READ_RAMP_STEPS).
+type ReadShift struct {
+	PrimaryCluster string
+	Percentage     int
+	Key            string
+	Ramp           *zdmproxy.ReadShiftRampStatus `json:",omitempty"`
+}
+
+func (recv *Api) newReadShift() *ReadShift {
+	readShift := recv.proxy.GetReadShift()
+	return &ReadShift{
+		PrimaryCluster: string(recv.proxy.GetPrimaryCluster()),
+		Percentage:     readShift.GetPercentage(),
+		Key:            readShift.GetKey().String(),
+		Ramp:           recv.proxy.GetReadShiftRamp().GetStatus(),
+	}
+}
+
+// readShiftHandler allows the read cutover to be ramped up (or rolled back) gradually, PUT changes the percentage of
+// the reads that are shifted to the target cluster and halts the automatic ramp if it is running. PUT is a routing
+// change, see confirmRoutingChange.
+func (recv *Api) readShiftHandler(rsp http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		writeJson(rsp, http.StatusOK, recv.newReadShift())
+	case http.MethodPut:
+		const action = "SetTargetReadPercentage"
+		if !recv.requireOperator(rsp, req, action) {
+			return
+		}
+		entry := recv.newAuditEntry(req, action)
+		entry.Before = recv.newReadShift()
+
+		var body ReadShift
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			err = fmt.Errorf("invalid request body: %w", err)
+		} else if body.Percentage < 0 || body.Percentage > 100 {
+			err = fmt.Errorf("invalid percentage %d, valid values are between 0 and 100", body.Percentage)
+		}
+		// the caller is identified by its token so that the previewed halt reason matches the applied one
+		haltReason := fmt.Sprintf(
+			"percentage set to %d%% through the admin API (token %v)", body.Percentage, entry.TokenId)
+		if err == nil {
+			from, to := common.ClusterTypeOrigin, common.ClusterTypeTarget
+			if recv.proxy.GetPrimaryCluster() != common.ClusterTypeOrigin {
+				// the percentage only applies while the primary cluster is ORIGIN
+				to = from
+			} else if body.Percentage < recv.proxy.GetReadShift().GetPercentage() {
+				from, to = to, from
+			} else if body.Percentage == recv.proxy.GetReadShift().GetPercentage() {
+				to = from
+			}
+			after := recv.newReadShift()
+			after.Percentage = body.Percentage
+			if after.Ramp != nil && after.Ramp.State == zdmproxy.ReadShiftRampStateRunning {
+				ramp := *after.Ramp
+				ramp.State = zdmproxy.ReadShiftRampStateHalted
+				ramp.HaltReason = haltReason
+				after.Ramp = &ramp
+			}
+			if !recv.confirmRoutingChange(rsp, req, entry, from, to, after) {
+				return
+			}
+			err = recv.proxy.GetReadShift().Set(body.Percentage)
+		}
+		if err != nil {
+			entry.Outcome = AuditOutcomeFailure
+			entry.Error = err.Error()
+			recv.auditLog.Record(entry)
+			http.Error(rsp, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		recv.proxy.GetReadShiftRamp().Halt(haltReason)
+		entry.After = recv.newReadShift()
+		entry.Outcome = AuditOutcomeSuccess
+		recv.auditLog.Record(entry)
+		recv.logRoutingChange(entry)
+		writeJson(rsp, http.StatusOK, entry.After)
+	default:
+		http.Error(rsp, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// RoutingExplainRequest contains a CQL statement and, optionally, the current keyspace and the IP address of the client
+// that would send it.
+type RoutingExplainRequest struct {
+	Statement string
+	Keyspace  string
+	ClientIp  string
+}
+
+// routingExplainHandler returns the routing decision that the proxy would make for a statement without forwarding it,
+// this allows routing rules (e.g. table routing, client read routing or read shifting) to be checked before they are
+// relied on.
+func (recv *Api) routingExplainHandler(rsp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(rsp, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body RoutingExplainRequest
+	err := json.NewDecoder(req.Body).Decode(&body)
+	if err != nil {
+		http.Error(rsp, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(body.Statement) == "" {
+		http.Error(rsp, "missing statement", http.StatusBadRequest)
+		return
+	}
+
+	explanation, err := recv.proxy.ExplainRouting(
+		body.Statement, strings.TrimSpace(body.Keyspace), strings.TrimSpace(body.ClientIp))
+	if err != nil {
+		http.Error(rsp, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJson(rsp, http.StatusOK, explanation)
+}
+
+// FeatureFlags contains the percentage of client connections (by client IP address) for which each feature is enabled.
+type FeatureFlags struct {
+	Features map[string]int
+}
+
+// FeatureRollout enables a feature for a percentage (0 to 100) of the client connections.
+type FeatureRollout struct {
+	Feature    string
+	Percentage int
+}
+
+func (recv *Api) newFeatureFlags() *FeatureFlags {
+	features := make(map[string]int)
+	for feature, percentage := range recv.proxy.GetFeatureFlags().GetAll() {
+		features[string(feature)] = percentage
+	}
+	return &FeatureFlags{Features: features}
+}
+
+func (recv *Api) featureFlagsHandler(rsp http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		writeJson(rsp, http.StatusOK, recv.newFeatureFlags())
+	case http.MethodPut:
+		const action = "SetFeatureRollout"
+		if !recv.requireOperator(rsp, req, action) {
+			return
+		}
+		entry := recv.newAuditEntry(req, action)
+		entry.Before = recv.newFeatureFlags()
+
+		var body FeatureRollout
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			err = fmt.Errorf("invalid request body: %w", err)
+		}
+		if err == nil {
+			err = recv.proxy.GetFeatureFlags().Set(common.Feature(strings.TrimSpace(body.Feature)), body.Percentage)
+		}
+		if err != nil {
+			entry.Outcome = AuditOutcomeFailure
+			entry.Error = err.Error()
+			recv.auditLog.Record(entry)
+			http.Error(rsp, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		log.Infof("Feature %v enabled for %d%% of the client connections through the admin API by %v.",
+			body.Feature, body.Percentage, req.RemoteAddr)
+		entry.After = recv.newFeatureFlags()
+		entry.Outcome = AuditOutcomeSuccess
+		recv.auditLog.Record(entry)
+		writeJson(rsp, http.StatusOK, entry.After)
+	default:
+		http.Error(rsp, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func writeJson(rsp http.ResponseWriter, statusCode int, body interface{}) {
+	bytes, err := json.Marshal(body)
+	if err != nil {
+		uid := uuid.New()
+		log.Errorf("Could not serialize admin API response (code: %v): %v", uid, err)
+		http.Error(rsp, fmt.Sprintf("Internal server error with code %v", uid), http.StatusInternalServerError)
+		return
+	}
+	rsp.Header().Set("Content-Type", "application/json")
+	rsp.WriteHeader(statusCode)
+	_, _ = rsp.Write(bytes)
+}
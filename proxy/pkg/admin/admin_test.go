@@ -0,0 +1,95 @@
+package admin
+
+import (
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApi_Authorization(t *testing.T) {
+	originalLevel := log.GetLevel()
+	defer log.SetLevel(originalLevel)
+
+	auditLog, err := NewAuditLog("")
+	require.Nil(t, err)
+	api := NewApi(nil, map[string]common.AdminRole{
+		"readonly-token": common.AdminRoleReadOnly,
+		"operator-token": common.AdminRoleOperator,
+	}, auditLog)
+
+	tests := []struct {
+		name          string
+		method        string
+		authorization string
+		body          string
+		expectedCode  int
+	}{
+		{"read without token", http.MethodGet, "", "", http.StatusUnauthorized},
+		{"read with unknown token", http.MethodGet, "Bearer unknown", "", http.StatusUnauthorized},
+		{"read with token but no bearer prefix", http.MethodGet, "readonly-token", "", http.StatusUnauthorized},
+		{"read with read only token", http.MethodGet, "Bearer readonly-token", "", http.StatusOK},
+		{"read with operator token", http.MethodGet, "Bearer operator-token", "", http.StatusOK},
+		{"write without token", http.MethodPut, "", `{"Level":"debug"}`, http.StatusUnauthorized},
+		{"write with read only token", http.MethodPut, "Bearer readonly-token", `{"Level":"debug"}`, http.StatusForbidden},
+		{"write with operator token", http.MethodPut, "Bearer operator-token", `{"Level":"debug"}`, http.StatusOK},
+		{"write invalid level", http.MethodPut, "Bearer operator-token", `{"Level":"verbose"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/admin/log-level", strings.NewReader(tt.body))
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rsp := httptest.NewRecorder()
+			api.ServeHTTP(rsp, req)
+			require.Equal(t, tt.expectedCode, rsp.Code, rsp.Body.String())
+		})
+	}
+
+	require.Equal(t, log.DebugLevel, log.GetLevel())
+
+	// denied, successful and failed state changes are audited, reads are not
+	entries := auditLog.Tail(0)
+	require.Equal(t, 3, len(entries))
+	require.Equal(t, AuditOutcomeDenied, entries[0].Outcome)
+	require.Equal(t, common.AdminRoleReadOnly.String(), entries[0].Role)
+	require.Equal(t, AuditOutcomeSuccess, entries[1].Outcome)
+	require.Equal(t, &LogLevel{Level: "debug"}, entries[1].After)
+	require.Equal(t, AuditOutcomeFailure, entries[2].Outcome)
+	require.NotContains(t, entries[1].TokenId, "operator-token")
+}
+
+func TestAuditLog_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	auditLog, err := NewAuditLog(path)
+	require.Nil(t, err)
+	for i := 0; i < 3; i++ {
+		auditLog.Record(&AuditEntry{Action: fmt.Sprintf("action%d", i), Outcome: AuditOutcomeSuccess})
+	}
+	require.Nil(t, auditLog.Close())
+
+	contents, err := os.ReadFile(path)
+	require.Nil(t, err)
+	require.Equal(t, 3, strings.Count(string(contents), "\n"))
+
+	// entries written before a restart are returned by the tail
+	auditLog, err = NewAuditLog(path)
+	require.Nil(t, err)
+	defer auditLog.Close()
+	auditLog.Record(&AuditEntry{Action: "action3", Outcome: AuditOutcomeSuccess})
+
+	entries := auditLog.Tail(2)
+	require.Equal(t, 2, len(entries))
+	require.Equal(t, "action2", entries[0].Action)
+	require.Equal(t, "action3", entries[1].Action)
+	require.Equal(t, 4, len(auditLog.Tail(0)))
+}
@@ -0,0 +1,196 @@
+package admin
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	auditTailCapacity     = 1000
+	auditDefaultTailLimit = 100
+)
+
+type AuditOutcome string
+
+const (
+	AuditOutcomeSuccess = AuditOutcome("SUCCESS")
+	AuditOutcomeFailure = AuditOutcome("FAILURE")
+	AuditOutcomeDenied  = AuditOutcome("DENIED")
+)
+
+// AuditEntry describes a control action that was performed (or attempted) through the admin API.
+type AuditEntry struct {
+	Timestamp time.Time
+	Source    string // remote address of the caller
+	TokenId   string // fingerprint of the token used by the caller, never the token itself
+	Role      string
+	Method    string
+	Path      string
+	Action    string
+	Before    interface{} `json:",omitempty"`
+	After     interface{} `json:",omitempty"`
+	Outcome   AuditOutcome
+	Error     string `json:",omitempty"`
+}
+
+// AuditLog appends audit entries to a file (one JSON document per line) and keeps the most recent ones in memory so
+// that they can be returned by the admin API. If no file is configured, entries are only kept in memory.
+type AuditLog struct {
+	lock *sync.Mutex
+	file *os.File
+	tail []*AuditEntry
+}
+
+func NewAuditLog(path string) (*AuditLog, error) {
+	auditLog := &AuditLog{
+		lock: &sync.Mutex{},
+		file: nil,
+		tail: make([]*AuditEntry, 0),
+	}
+	if path == "" {
+		return auditLog, nil
+	}
+
+	err := auditLog.loadTail(path)
+	if err != nil {
+		return nil, err
+	}
+
+	auditLog.file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("could not open admin audit log file %v: %w", path, err)
+	}
+	return auditLog, nil
+}
+
+// loadTail reads the most recent entries of an existing audit log file so that they survive restarts.
+func (recv *AuditLog) loadTail(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("could not read admin audit log file %v: %w", path, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		entry := &AuditEntry{}
+		if err = json.Unmarshal(scanner.Bytes(), entry); err != nil {
+			log.Warnf("Skipping invalid line in admin audit log file %v: %v", path, err)
+			continue
+		}
+		recv.appendToTail(entry)
+	}
+	return scanner.Err()
+}
+
+func (recv *AuditLog) Record(entry *AuditEntry) {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now().UTC()
+	}
+
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	recv.appendToTail(entry)
+	if recv.file == nil {
+		return
+	}
+
+	bytes, err := json.Marshal(entry)
+	if err != nil {
+		log.Errorf("Could not serialize admin audit entry %v: %v", entry, err)
+		return
+	}
+	_, err = recv.file.Write(append(bytes, '\n'))
+	if err == nil {
+		err = recv.file.Sync()
+	}
+	if err != nil {
+		log.Errorf("Could not write admin audit entry to %v: %v", recv.file.Name(), err)
+	}
+}
+
+func (recv *AuditLog) appendToTail(entry *AuditEntry) {
+	recv.tail = append(recv.tail, entry)
+	if len(recv.tail) > auditTailCapacity {
+		recv.tail = recv.tail[len(recv.tail)-auditTailCapacity:]
+	}
+}
+
+// Tail returns up to limit of the most recent entries, oldest first.
+func (recv *AuditLog) Tail(limit int) []*AuditEntry {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	if limit <= 0 || limit > len(recv.tail) {
+		limit = len(recv.tail)
+	}
+	entries := make([]*AuditEntry, limit)
+	copy(entries, recv.tail[len(recv.tail)-limit:])
+	return entries
+}
+
+func (recv *AuditLog) Close() error {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	if recv.file == nil {
+		return nil
+	}
+	err := recv.file.Close()
+	recv.file = nil
+	return err
+}
+
+// newAuditEntry creates an entry with the caller information of the provided request.
+func (recv *Api) newAuditEntry(req *http.Request, action string) *AuditEntry {
+	return &AuditEntry{
+		Source:  req.RemoteAddr,
+		TokenId: tokenId(bearerToken(req)),
+		Role:    recv.roleOf(req).String(),
+		Method:  req.Method,
+		Path:    req.URL.Path,
+		Action:  action,
+	}
+}
+
+func tokenId(token string) string {
+	if token == "" {
+		return ""
+	}
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:4])
+}
+
+func (recv *Api) auditHandler(rsp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(rsp, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := auditDefaultTailLimit
+	if limitParam := strings.TrimSpace(req.URL.Query().Get("limit")); limitParam != "" {
+		parsedLimit, err := strconv.Atoi(limitParam)
+		if err != nil || parsedLimit <= 0 {
+			http.Error(rsp, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = parsedLimit
+	}
+	writeJson(rsp, http.StatusOK, recv.auditLog.Tail(limit))
+}
@@ -0,0 +1,84 @@
+package admin
+
+import (
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"io"
+	"net/http"
+)
+
+// maximum size of a candidate configuration, configuration files are a few KB
+const maxCandidateConfigBytes = 1 << 20
+
+// settings that can be changed on a running proxy through the admin API, every other setting requires a restart
+var runtimeSettings = map[string]string{
+	"feature_flags":            "PUT /admin/features",
+	"log_level":                "PUT /admin/log-level",
+	"proxy_topology_addresses": "PUT /admin/topology",
+}
+
+// ConfigValidation is the result of validating a candidate configuration against the running one.
+// Values of secrets are never returned, only the fact that they changed.
+type ConfigValidation struct {
+	Valid    bool
+	Errors   []string
+	Changes  []config.SettingChange
+	Warnings []string
+}
+
+// configHandler returns the effective configuration of the proxy (defaults included, secrets redacted) so that the
+// configuration of different deployments can be compared without access to their environment.
+func (recv *Api) configHandler(rsp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(rsp, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJson(rsp, http.StatusOK, recv.proxy.Conf.Effective())
+}
+
+// configValidateHandler validates a candidate configuration (same YAML format as the configuration file, JSON is
+// also accepted) and returns the settings that would change compared to the running configuration. Nothing is
+// applied, this allows config rollouts to be checked before the proxy instances are restarted.
+func (recv *Api) configValidateHandler(rsp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(rsp, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	validation, err := validateConfig(recv.proxy.Conf, http.MaxBytesReader(rsp, req.Body, maxCandidateConfigBytes))
+	if err != nil {
+		http.Error(rsp, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJson(rsp, http.StatusOK, validation)
+}
+
+func validateConfig(running *config.Config, candidateYaml io.Reader) (*ConfigValidation, error) {
+	candidate, err := config.ParseYaml(candidateYaml)
+	if err != nil {
+		return nil, err
+	}
+
+	validation := &ConfigValidation{
+		Valid:    true,
+		Errors:   make([]string, 0),
+		Changes:  config.Diff(running, candidate),
+		Warnings: make([]string, 0),
+	}
+	if err = candidate.Validate(); err != nil {
+		validation.Valid = false
+		validation.Errors = append(validation.Errors, err.Error())
+	}
+
+	for _, change := range validation.Changes {
+		if endpoint, ok := runtimeSettings[change.Setting]; ok {
+			validation.Warnings = append(validation.Warnings, fmt.Sprintf(
+				"%v requires a restart to be applied from the configuration, it can be changed without a restart through %v",
+				change.Setting, endpoint))
+		} else {
+			validation.Warnings = append(validation.Warnings, fmt.Sprintf(
+				"%v requires a restart to be applied", change.Setting))
+		}
+	}
+	return validation, nil
+}
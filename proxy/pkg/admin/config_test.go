@@ -0,0 +1,58 @@
+package admin
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+const runningConfigYaml = `
+origin_username: foo1
+origin_password: bar1
+target_username: foo2
+target_password: bar2
+origin_contact_points: 192.168.100.101
+target_contact_points: 192.168.100.102
+`
+
+func TestValidateConfig(t *testing.T) {
+	running, err := config.ParseYaml(strings.NewReader(runningConfigYaml))
+	require.Nil(t, err)
+
+	validation, err := validateConfig(running, strings.NewReader(runningConfigYaml))
+	require.Nil(t, err)
+	require.True(t, validation.Valid)
+	require.Empty(t, validation.Changes)
+	require.Empty(t, validation.Warnings)
+
+	candidateYaml := strings.Replace(runningConfigYaml, "origin_password: bar1", "origin_password: changed", 1)
+	validation, err = validateConfig(running, strings.NewReader(candidateYaml+`
+log_level: DEBUG
+proxy_request_timeout_ms: 5000
+`))
+	require.Nil(t, err)
+	require.True(t, validation.Valid, validation.Errors)
+	require.Equal(t, []config.SettingChange{
+		{Setting: "log_level", Running: "INFO", Candidate: "DEBUG"},
+		{Setting: "origin_password", Secret: true},
+		{Setting: "proxy_request_timeout_ms", Running: "10000", Candidate: "5000"},
+	}, validation.Changes)
+	require.Equal(t, []string{
+		"log_level requires a restart to be applied from the configuration, it can be changed without a restart through PUT /admin/log-level",
+		"origin_password requires a restart to be applied",
+		"proxy_request_timeout_ms requires a restart to be applied",
+	}, validation.Warnings)
+
+	validation, err = validateConfig(running, strings.NewReader(runningConfigYaml+`
+primary_cluster: NONE
+`))
+	require.Nil(t, err)
+	require.False(t, validation.Valid)
+	require.Len(t, validation.Errors, 1)
+	require.Contains(t, validation.Errors[0], "ZDM_PRIMARY_CLUSTER")
+	require.Len(t, validation.Changes, 1)
+
+	_, err = validateConfig(running, strings.NewReader("origin_port: [not a port"))
+	require.NotNil(t, err)
+}
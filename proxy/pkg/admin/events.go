@@ -0,0 +1,62 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"time"
+)
+
+// comments are sent periodically so that idle streams are not closed by load balancers or proxies
+const eventStreamKeepAliveInterval = 15 * time.Second
+
+// eventsHandler streams the live proxy events as server-sent events (text/event-stream) until the client disconnects
+// or the proxy shuts down. The event name is the event type and the data is the JSON serialized event.
+func (recv *Api) eventsHandler(rsp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(rsp, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := rsp.(http.Flusher)
+	if !ok {
+		http.Error(rsp, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	events, unsubscribe := recv.proxy.GetEvents().Subscribe()
+	defer unsubscribe()
+	log.Debugf("Admin API event stream opened by %v.", req.RemoteAddr)
+	defer log.Debugf("Admin API event stream of %v closed.", req.RemoteAddr)
+
+	rsp.Header().Set("Content-Type", "text/event-stream")
+	rsp.Header().Set("Cache-Control", "no-cache")
+	rsp.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventStreamKeepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		var err error
+		select {
+		case <-req.Context().Done():
+			return
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(rsp, ": keep-alive\n\n")
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			var data []byte
+			data, err = json.Marshal(event)
+			if err == nil {
+				_, err = fmt.Fprintf(rsp, "event: %v\ndata: %s\n\n", event.Type, data)
+			}
+		}
+		if err != nil {
+			log.Debugf("Closing admin API event stream of %v: %v", req.RemoteAddr, err)
+			return
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,110 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type ExplainOptions struct {
+	// base URL of the http server (metrics, health checks and admin API) of the proxy, e.g. http://localhost:14001
+	Url     string
+	Token   string
+	Request RoutingExplainRequest
+	Json    bool
+}
+
+// RunExplain asks a proxy instance for the routing decision of a statement (see /admin/routing/explain) and writes it
+// to out, either as JSON or as a human readable report.
+func RunExplain(ctx context.Context, out io.Writer, options ExplainOptions) error {
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	explanation, err := fetchExplanation(ctx, httpClient, options)
+	if err != nil {
+		return err
+	}
+	if options.Json {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(explanation)
+	}
+	renderExplanation(out, explanation)
+	return nil
+}
+
+func fetchExplanation(
+	ctx context.Context, httpClient *http.Client, options ExplainOptions) (*zdmproxy.RoutingExplanation, error) {
+	const path = "/admin/routing/explain"
+	body, err := json.Marshal(&options.Request)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, strings.TrimSuffix(options.Url, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if options.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+options.Token)
+	}
+	rsp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(rsp.Body, 512))
+		return nil, fmt.Errorf("POST %v returned %v: %v", path, rsp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var explanation zdmproxy.RoutingExplanation
+	if err = json.NewDecoder(rsp.Body).Decode(&explanation); err != nil {
+		return nil, fmt.Errorf("could not decode response of %v: %w", path, err)
+	}
+	return &explanation, nil
+}
+
+func renderExplanation(w io.Writer, explanation *zdmproxy.RoutingExplanation) {
+	_, _ = fmt.Fprintf(w, "Statement: %v\n", explanation.Statement)
+	_, _ = fmt.Fprintf(w, "Type: %v", explanation.StatementType)
+	if explanation.Table != "" {
+		_, _ = fmt.Fprintf(w, "   Table: %v", explanation.Table)
+	}
+	_, _ = fmt.Fprintln(w)
+
+	switch {
+	case explanation.Intercepted:
+		_, _ = fmt.Fprintln(w, "Forwarded to: none, answered by the proxy")
+	case len(explanation.Clusters) == 0:
+		_, _ = fmt.Fprintln(w, "Forwarded to: none")
+	default:
+		_, _ = fmt.Fprintf(w, "Forwarded to: %v (sync)", strings.Join(explanation.Clusters, ", "))
+		if explanation.AsyncCluster != "" {
+			_, _ = fmt.Fprintf(w, ", %v (async)", explanation.AsyncCluster)
+		}
+		_, _ = fmt.Fprintln(w)
+	}
+
+	for _, section := range []struct {
+		title string
+		lines []string
+	}{
+		{"Rewrites", explanation.Rewrites},
+		{"Policies", explanation.Policies},
+	} {
+		if len(section.lines) == 0 {
+			_, _ = fmt.Fprintf(w, "%v: none\n", section.title)
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "%v:\n", section.title)
+		for _, line := range section.lines {
+			_, _ = fmt.Fprintf(w, "  - %v\n", line)
+		}
+	}
+}
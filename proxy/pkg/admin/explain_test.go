@@ -0,0 +1,54 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/admin/routing/explain", func(rsp http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer token" {
+			http.Error(rsp, "missing or invalid token", http.StatusUnauthorized)
+			return
+		}
+		var body RoutingExplainRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil || req.Method != http.MethodPost {
+			http.Error(rsp, "invalid request", http.StatusBadRequest)
+			return
+		}
+		_, _ = fmt.Fprintf(rsp, `{"Statement":%q,"StatementType":"select","Table":"%v.tbl","Intercepted":false,`+
+			`"Clusters":["TARGET"],"AsyncCluster":"","Rewrites":[],`+
+			`"Policies":["reads of client %v routed to TARGET (ZDM_CLIENT_READ_ROUTING)"]}`,
+			body.Statement, body.Keyspace, body.ClientIp)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	out := &strings.Builder{}
+	err := RunExplain(context.Background(), out, ExplainOptions{
+		Url:   srv.URL,
+		Token: "token",
+		Request: RoutingExplainRequest{
+			Statement: "SELECT * FROM tbl",
+			Keyspace:  "ks",
+			ClientIp:  "10.0.0.1",
+		},
+	})
+	require.Nil(t, err)
+	require.Contains(t, out.String(), "Statement: SELECT * FROM tbl\n")
+	require.Contains(t, out.String(), "Type: select   Table: ks.tbl\n")
+	require.Contains(t, out.String(), "Forwarded to: TARGET (sync)\n")
+	require.Contains(t, out.String(), "Rewrites: none\n")
+	require.Contains(t, out.String(), "  - reads of client 10.0.0.1 routed to TARGET (ZDM_CLIENT_READ_ROUTING)\n")
+
+	err = RunExplain(context.Background(), out, ExplainOptions{Url: srv.URL, Token: "wrong"})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "401")
+}
@@ -0,0 +1,163 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// confirmation tokens of routing changes expire if the change is not applied within this delay
+const routingChangeTokenTtl = 5 * time.Minute
+
+// RoutingChangePreview is returned instead of applying a routing change when the request has the dry_run=true query
+// parameter. MissingWrites is the number of writes that the cluster the reads are moved to misses, see
+// MissingWritesConflict. The change is applied by repeating the request with the confirm=<ConfirmationToken> query
+// parameter before ExpiresAt.
+type RoutingChangePreview struct {
+	Before                              interface{}
+	After                               interface{}
+	MissingWrites                       int64
+	MissingWritesRequireAcknowledgement bool
+	ConfirmationToken                   string
+	ExpiresAt                           time.Time
+}
+
+// pendingRoutingChange is a routing change that was previewed and can be applied with its confirmation token.
+type pendingRoutingChange struct {
+	action    string
+	tokenId   string // fingerprint of the admin API token of the caller that previewed the change
+	before    []byte
+	after     []byte
+	expiresAt time.Time
+}
+
+// routingChangeTokens keeps the confirmation tokens of the previewed routing changes, a token can only be used once.
+type routingChangeTokens struct {
+	lock    *sync.Mutex
+	pending map[string]*pendingRoutingChange
+}
+
+func newRoutingChangeTokens() *routingChangeTokens {
+	return &routingChangeTokens{
+		lock:    &sync.Mutex{},
+		pending: make(map[string]*pendingRoutingChange),
+	}
+}
+
+// issue returns a new confirmation token for the change and removes the expired ones.
+func (recv *routingChangeTokens) issue(change *pendingRoutingChange, now time.Time) string {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	for token, pending := range recv.pending {
+		if !now.Before(pending.expiresAt) {
+			delete(recv.pending, token)
+		}
+	}
+	token := uuid.New().String()
+	recv.pending[token] = change
+	return token
+}
+
+// take removes the change of the confirmation token and returns it, nil if the token is unknown or expired.
+func (recv *routingChangeTokens) take(token string, now time.Time) *pendingRoutingChange {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	change, ok := recv.pending[token]
+	if !ok {
+		return nil
+	}
+	delete(recv.pending, token)
+	if !now.Before(change.expiresAt) {
+		return nil
+	}
+	return change
+}
+
+// confirmRoutingChange is called before a routing change is applied. Routing changes take two steps so that a
+// premature cutover (or rollback) can't be triggered by a single mistyped request:
+//   - the request with the dry_run=true query parameter returns the routing before and after the change without
+//     applying it, with a confirmation token that can be used once within routingChangeTokenTtl
+//   - the change is only applied by the same request with the confirm=<token> query parameter, sent with the same
+//     admin API token, and only if the routing didn't change since the preview
+//
+// Reads moved to a cluster that misses writes must also be acknowledged, see checkMissingWrites. Returns false if the
+// change must not be applied, the response is written (and refusals are recorded in the audit log) in that case.
+func (recv *Api) confirmRoutingChange(
+	rsp http.ResponseWriter, req *http.Request, entry *AuditEntry, from common.ClusterType, to common.ClusterType,
+	after interface{}) bool {
+	beforeJson, err := json.Marshal(entry.Before)
+	var afterJson []byte
+	if err == nil {
+		afterJson, err = json.Marshal(after)
+	}
+	if err != nil {
+		http.Error(rsp, fmt.Sprintf("could not serialize the routing: %v", err), http.StatusInternalServerError)
+		return false
+	}
+	now := time.Now().UTC()
+
+	query := req.URL.Query()
+	if strings.TrimSpace(query.Get("dry_run")) == "true" {
+		preview := &RoutingChangePreview{Before: entry.Before, After: after}
+		if from != to {
+			var ok bool
+			preview.MissingWrites, ok = recv.proxy.GetSingleSidedWrites().CheckReadsMovedTo(to)
+			preview.MissingWritesRequireAcknowledgement = !ok
+		}
+		preview.ExpiresAt = now.Add(routingChangeTokenTtl)
+		preview.ConfirmationToken = recv.routingChangeTokens.issue(&pendingRoutingChange{
+			action:    entry.Action,
+			tokenId:   entry.TokenId,
+			before:    beforeJson,
+			after:     afterJson,
+			expiresAt: preview.ExpiresAt,
+		}, now)
+		writeJson(rsp, http.StatusOK, preview)
+		return false
+	}
+
+	code := http.StatusBadRequest
+	token := strings.TrimSpace(query.Get("confirm"))
+	change := recv.routingChangeTokens.take(token, now)
+	switch {
+	case token == "":
+		entry.Error = "routing changes must be confirmed with the confirm=<token> query parameter, " +
+			"the token is returned by the same request with dry_run=true"
+	case change == nil:
+		entry.Error = "unknown or expired confirmation token, preview the change again with dry_run=true"
+	case change.action != entry.Action || change.tokenId != entry.TokenId:
+		entry.Error = "the confirmation token was issued for another routing change"
+	case !bytes.Equal(change.before, beforeJson):
+		code = http.StatusConflict
+		entry.Error = "the routing changed since the preview, preview the change again with dry_run=true"
+	case !bytes.Equal(change.after, afterJson):
+		entry.Error = "the confirmation token was issued for another routing change"
+	default:
+		return recv.checkMissingWrites(rsp, req, entry, from, to)
+	}
+	entry.Outcome = AuditOutcomeFailure
+	recv.auditLog.Record(entry)
+	http.Error(rsp, entry.Error, code)
+	return false
+}
+
+// logRoutingChange logs a routing change applied through the admin API, the same change is recorded in the audit log.
+// Who applied the change is also published to the event stream, after the event of the change itself.
+func (recv *Api) logRoutingChange(entry *AuditEntry) {
+	before, _ := json.Marshal(entry.Before)
+	after, _ := json.Marshal(entry.After)
+	log.Infof("Routing changed through the admin API (%v) by %v (token %v), before: %s, after: %s.",
+		entry.Action, entry.Source, entry.TokenId, before, after)
+	recv.proxy.GetEvents().Publish(zdmproxy.ProxyEventRoutingChangeApplied,
+		"%v applied through the admin API by %v (token %v)", entry.Action, entry.Source, entry.TokenId)
+}
@@ -0,0 +1,27 @@
+package admin
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestRoutingChangeTokens(t *testing.T) {
+	now := time.Now()
+	tokens := newRoutingChangeTokens()
+	change := &pendingRoutingChange{action: "SetTableReadRouting", expiresAt: now.Add(routingChangeTokenTtl)}
+
+	token := tokens.issue(change, now)
+	require.Nil(t, tokens.take("unknown", now))
+	require.Same(t, change, tokens.take(token, now))
+	// tokens can only be used once
+	require.Nil(t, tokens.take(token, now))
+
+	token = tokens.issue(change, now)
+	require.Nil(t, tokens.take(token, now.Add(routingChangeTokenTtl)))
+
+	// expired tokens are removed when a new one is issued
+	tokens.issue(change, now)
+	tokens.issue(&pendingRoutingChange{expiresAt: now.Add(2 * routingChangeTokenTtl)}, now.Add(routingChangeTokenTtl))
+	require.Len(t, tokens.pending, 1)
+}
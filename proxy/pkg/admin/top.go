@@ -0,0 +1,242 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const clearScreen = "\033[H\033[2J"
+
+type TopOptions struct {
+	// base URL of the http server (metrics, health checks and admin API) of the proxy, e.g. http://localhost:14001
+	Url           string
+	Token         string
+	MetricsPrefix string
+	Interval      time.Duration
+}
+
+type topSample struct {
+	timestamp time.Time
+	status    *StatusReport
+	routing   *TableReadRouting
+	families  map[string]*dto.MetricFamily
+}
+
+// RunTop polls the admin API and the metrics of a proxy instance and renders a status screen (request rates,
+// latencies, error rates, health and read routing) every interval until the context is canceled.
+func RunTop(ctx context.Context, out io.Writer, options TopOptions) error {
+	httpClient := &http.Client{Timeout: options.Interval}
+	previous, err := fetchTopSample(ctx, httpClient, options)
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprint(out, clearScreen)
+	renderTop(out, options, previous, nil)
+
+	ticker := time.NewTicker(options.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		current, err := fetchTopSample(ctx, httpClient, options)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			_, _ = fmt.Fprintf(out, "Could not refresh: %v\n", err)
+			continue
+		}
+		_, _ = fmt.Fprint(out, clearScreen)
+		renderTop(out, options, current, previous)
+		previous = current
+	}
+}
+
+func fetchTopSample(ctx context.Context, httpClient *http.Client, options TopOptions) (*topSample, error) {
+	sample := &topSample{timestamp: time.Now()}
+	if err := getAdminJson(ctx, httpClient, options, "/admin/status", &sample.status); err != nil {
+		return nil, err
+	}
+	if err := getAdminJson(ctx, httpClient, options, "/admin/routing/tables", &sample.routing); err != nil {
+		return nil, err
+	}
+
+	rsp, err := doGet(ctx, httpClient, options, "/metrics")
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+	sample.families, err = (&expfmt.TextParser{}).TextToMetricFamilies(rsp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse metrics: %w", err)
+	}
+	return sample, nil
+}
+
+func getAdminJson(ctx context.Context, httpClient *http.Client, options TopOptions, path string, body interface{}) error {
+	rsp, err := doGet(ctx, httpClient, options, path)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	if err = json.NewDecoder(rsp.Body).Decode(body); err != nil {
+		return fmt.Errorf("could not decode response of %v: %w", path, err)
+	}
+	return nil
+}
+
+func doGet(ctx context.Context, httpClient *http.Client, options TopOptions, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(options.Url, "/")+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if options.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+options.Token)
+	}
+	rsp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if rsp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(rsp.Body, 512))
+		_ = rsp.Body.Close()
+		return nil, fmt.Errorf("GET %v returned %v: %v", path, rsp.Status, strings.TrimSpace(string(msg)))
+	}
+	return rsp, nil
+}
+
+// renderTop writes the status screen, rates are computed against the previous sample (if any).
+func renderTop(w io.Writer, options TopOptions, current *topSample, previous *topSample) {
+	_, _ = fmt.Fprintf(w, "ZDM proxy %v - %v (refresh every %v)\n\n",
+		options.Url, current.timestamp.UTC().Format(time.RFC3339), options.Interval)
+
+	status := current.status
+	if status.Build != nil {
+		_, _ = fmt.Fprintf(w, "Version: %v\n", status.Build)
+	}
+	if status.Health != nil {
+		_, _ = fmt.Fprintf(w, "Status: %v", status.Health.Status)
+		if status.Health.OriginStatus != nil && status.Health.TargetStatus != nil {
+			_, _ = fmt.Fprintf(w, "   Origin: %v   Target: %v", status.Health.OriginStatus.Status, status.Health.TargetStatus.Status)
+		}
+		_, _ = fmt.Fprintln(w)
+	}
+	_, _ = fmt.Fprintf(w, "Primary cluster: %v   Read mode: %v   Client connections: %v   Log level: %v\n",
+		status.PrimaryCluster, status.ReadMode, status.ActiveClients, status.LogLevel)
+	_, _ = fmt.Fprintf(w, "Load: %.2f   CPU: %.2f\n\n",
+		current.gauge(options, "proxy_load_utilization_ratio", "", ""),
+		current.gauge(options, "proxy_load_cpu_utilization_ratio", "", ""))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "\tRequests/s\tAvg latency\tErrors/s\tIn flight")
+	for _, row := range []struct {
+		name         string
+		requestType  string
+		errorsMetric string
+		errorsLabel  string
+		errorsValue  string
+	}{
+		{"Reads origin", "reads_origin", "proxy_failed_reads_total", "cluster", "origin"},
+		{"Reads target", "reads_target", "proxy_failed_reads_total", "cluster", "target"},
+		{"Writes", "writes", "proxy_failed_writes_total", "", ""},
+	} {
+		requestsPerSecond, avgLatency, errorsPerSecond := "-", "-", "-"
+		if previous != nil {
+			elapsed := current.timestamp.Sub(previous.timestamp).Seconds()
+			count, sum := current.histogram(options, "proxy_request_duration_seconds", "type", row.requestType)
+			previousCount, previousSum := previous.histogram(options, "proxy_request_duration_seconds", "type", row.requestType)
+			if elapsed > 0 && count >= previousCount {
+				requestsPerSecond = fmt.Sprintf("%.1f", float64(count-previousCount)/elapsed)
+				errors := current.counter(options, row.errorsMetric, row.errorsLabel, row.errorsValue) -
+					previous.counter(options, row.errorsMetric, row.errorsLabel, row.errorsValue)
+				errorsPerSecond = fmt.Sprintf("%.1f", errors/elapsed)
+			}
+			if count > previousCount {
+				avgLatency = fmt.Sprintf("%.1f ms", (sum-previousSum)/float64(count-previousCount)*1000)
+			}
+		}
+		_, _ = fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%.0f\n", row.name, requestsPerSecond, avgLatency, errorsPerSecond,
+			current.gauge(options, "proxy_inflight_requests_total", "type", row.requestType))
+	}
+	_ = tw.Flush()
+
+	_, _ = fmt.Fprintln(w)
+	if current.routing == nil || len(current.routing.Tables) == 0 {
+		_, _ = fmt.Fprintf(w, "Read routing: every table is read from %v\n", status.PrimaryCluster)
+		return
+	}
+	_, _ = fmt.Fprintf(w, "Read routing: %d table(s) routed, the other tables are read from %v\n",
+		len(current.routing.Tables), current.routing.PrimaryCluster)
+	tables := make([]string, 0, len(current.routing.Tables))
+	for table := range current.routing.Tables {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	tw = tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	for _, table := range tables {
+		_, _ = fmt.Fprintf(tw, "  %v\t%v\n", table, current.routing.Tables[table])
+	}
+	_ = tw.Flush()
+}
+
+// metrics returns the metrics of the family that have the provided label value, every metric if label is empty.
+func (recv *topSample) metrics(options TopOptions, name string, label string, value string) []*dto.Metric {
+	if options.MetricsPrefix != "" {
+		name = options.MetricsPrefix + "_" + name
+	}
+	family, ok := recv.families[name]
+	if !ok {
+		return nil
+	}
+	if label == "" {
+		return family.GetMetric()
+	}
+	matching := make([]*dto.Metric, 0, 1)
+	for _, metric := range family.GetMetric() {
+		for _, labelPair := range metric.GetLabel() {
+			if labelPair.GetName() == label && labelPair.GetValue() == value {
+				matching = append(matching, metric)
+			}
+		}
+	}
+	return matching
+}
+
+func (recv *topSample) counter(options TopOptions, name string, label string, value string) float64 {
+	total := 0.0
+	for _, metric := range recv.metrics(options, name, label, value) {
+		total += metric.GetCounter().GetValue()
+	}
+	return total
+}
+
+func (recv *topSample) gauge(options TopOptions, name string, label string, value string) float64 {
+	total := 0.0
+	for _, metric := range recv.metrics(options, name, label, value) {
+		total += metric.GetGauge().GetValue()
+	}
+	return total
+}
+
+func (recv *topSample) histogram(options TopOptions, name string, label string, value string) (uint64, float64) {
+	var count uint64
+	sum := 0.0
+	for _, metric := range recv.metrics(options, name, label, value) {
+		count += metric.GetHistogram().GetSampleCount()
+		sum += metric.GetHistogram().GetSampleSum()
+	}
+	return count, sum
+}
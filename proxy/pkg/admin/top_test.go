@@ -0,0 +1,64 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTop(t *testing.T) {
+	writes := 100
+	mux := http.NewServeMux()
+	mux.HandleFunc("/admin/status", func(rsp http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer token" {
+			http.Error(rsp, "missing or invalid token", http.StatusUnauthorized)
+			return
+		}
+		_, _ = fmt.Fprint(rsp, `{"Build":{"Version":"2.3.0","Commit":"abc","BuildDate":"unknown","GoVersion":"go1.19"},"PrimaryCluster":"ORIGIN","ReadMode":"PRIMARY_ONLY","ActiveClients":3,"LogLevel":"info"}`)
+	})
+	mux.HandleFunc("/admin/routing/tables", func(rsp http.ResponseWriter, req *http.Request) {
+		_, _ = fmt.Fprint(rsp, `{"PrimaryCluster":"ORIGIN","Tables":{"ks.t1":"TARGET"}}`)
+	})
+	mux.HandleFunc("/metrics", func(rsp http.ResponseWriter, req *http.Request) {
+		_, _ = fmt.Fprintf(rsp, `# TYPE zdm_proxy_request_duration_seconds histogram
+zdm_proxy_request_duration_seconds_bucket{type="writes",le="+Inf"} %d
+zdm_proxy_request_duration_seconds_sum{type="writes"} %v
+zdm_proxy_request_duration_seconds_count{type="writes"} %d
+# TYPE zdm_proxy_failed_writes_total counter
+zdm_proxy_failed_writes_total{failed_on="origin"} %d
+zdm_proxy_failed_writes_total{failed_on="target"} 0
+# TYPE zdm_proxy_inflight_requests_total gauge
+zdm_proxy_inflight_requests_total{type="writes"} 7
+# TYPE zdm_proxy_load_utilization_ratio gauge
+zdm_proxy_load_utilization_ratio 0.25
+`, writes, float64(writes)*0.002, writes, writes/100)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	options := TopOptions{Url: srv.URL, Token: "token", MetricsPrefix: "zdm", Interval: time.Second}
+	previous, err := fetchTopSample(context.Background(), srv.Client(), options)
+	require.Nil(t, err)
+	writes = 300
+	current, err := fetchTopSample(context.Background(), srv.Client(), options)
+	require.Nil(t, err)
+	current.timestamp = previous.timestamp.Add(10 * time.Second)
+
+	out := &strings.Builder{}
+	renderTop(out, options, current, previous)
+	require.Contains(t, out.String(), "Primary cluster: ORIGIN   Read mode: PRIMARY_ONLY   Client connections: 3")
+	require.Contains(t, out.String(), "Version: 2.3.0 (commit: abc, build date: unknown, go1.19)")
+	require.Contains(t, out.String(), "Load: 0.25")
+	require.Regexp(t, `Writes\s+20.0\s+2.0 ms\s+0.2\s+7`, out.String())
+	require.Regexp(t, `Reads origin\s+0.0\s+-\s+0.0\s+0`, out.String())
+	require.Regexp(t, `ks.t1\s+TARGET`, out.String())
+
+	options.Token = "invalid"
+	_, err = fetchTopSample(context.Background(), srv.Client(), options)
+	require.Contains(t, err.Error(), "401")
+}
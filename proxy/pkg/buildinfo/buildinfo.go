@@ -0,0 +1,57 @@
+package buildinfo
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Version is the ZDM proxy version, it has to be updated before every release (see RELEASE_PROCESS.md).
+const Version = "2.3.0"
+
+// Commit and BuildDate are stamped at build time, e.g.:
+//
+//	go build -ldflags "-X github.com/datastax/zdm-proxy/proxy/pkg/buildinfo.Commit=$(git rev-parse HEAD)" ./proxy
+//
+// If Commit is not stamped, the VCS revision recorded by the go toolchain is used (if any).
+var (
+	Commit    = ""
+	BuildDate = ""
+)
+
+// BuildInfo identifies the build of this proxy instance so that version skew between instances can be detected.
+type BuildInfo struct {
+	Version   string
+	Commit    string
+	BuildDate string
+	GoVersion string
+}
+
+func Get() *BuildInfo {
+	info := &BuildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+	if info.Commit == "" {
+		if goBuildInfo, ok := debug.ReadBuildInfo(); ok {
+			for _, setting := range goBuildInfo.Settings {
+				if setting.Key == "vcs.revision" {
+					info.Commit = setting.Value
+				}
+			}
+		}
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildDate == "" {
+		info.BuildDate = "unknown"
+	}
+	return info
+}
+
+func (recv *BuildInfo) String() string {
+	return fmt.Sprintf("%v (commit: %v, build date: %v, %v)", recv.Version, recv.Commit, recv.BuildDate, recv.GoVersion)
+}
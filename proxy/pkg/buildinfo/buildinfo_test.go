@@ -0,0 +1,28 @@
+package buildinfo
+
+import (
+	"github.com/stretchr/testify/require"
+	"runtime"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	defer func(commit string, buildDate string) {
+		Commit, BuildDate = commit, buildDate
+	}(Commit, BuildDate)
+
+	Commit, BuildDate = "abc123", "2024-01-02T03:04:05Z"
+	info := Get()
+	require.Equal(t, &BuildInfo{
+		Version:   Version,
+		Commit:    "abc123",
+		BuildDate: "2024-01-02T03:04:05Z",
+		GoVersion: runtime.Version(),
+	}, info)
+	require.Equal(t, Version+" (commit: abc123, build date: 2024-01-02T03:04:05Z, "+runtime.Version()+")", info.String())
+
+	Commit, BuildDate = "", ""
+	info = Get()
+	require.NotEmpty(t, info.Commit)
+	require.Equal(t, "unknown", info.BuildDate)
+}
@@ -2,7 +2,9 @@ package common
 
 import (
 	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
 	"net"
+	"net/url"
 )
 
 // TopologyConfig contains configuration parameters for 2 features related to multi zdm-proxy instance deployment:
@@ -25,17 +27,48 @@ func (recv *TopologyConfig) String() string {
 //   - TLS enabled is an internal flag that is automatically set based on the configuration provided
 //   - SCB and all other parameters are mutually exclusive: if SCB is provided, no other parameters must be specified. Doing so will result in a validation errExpected
 //   - When using a non-SCB configuration, all other three parameters must be specified (ServerCaPath, ClientCertPath, ClientKeyPath).
+//   - HostnameValidation only applies to non-SCB configurations, the certificate of each node must then be valid for the
+//     address that the proxy uses to connect to it (contact point or address of the node in the system tables).
 type ClusterTlsConfig struct {
 	TlsEnabled              bool
 	ServerCaPath            string
 	ClientCertPath          string
 	ClientKeyPath           string
 	SecureConnectBundlePath string
+	HostnameValidation      bool
 }
 
 func (recv *ClusterTlsConfig) String() string {
-	return fmt.Sprintf("ClusterTlsConfig{TlsEnabled=%v, ProxyCaPath=%v, ClientCertPath=%v, ClientKeyPath=%v}",
-		recv.TlsEnabled, recv.ServerCaPath, recv.ClientCertPath, recv.ClientKeyPath)
+	return fmt.Sprintf("ClusterTlsConfig{TlsEnabled=%v, ProxyCaPath=%v, ClientCertPath=%v, ClientKeyPath=%v, HostnameValidation=%v}",
+		recv.TlsEnabled, recv.ServerCaPath, recv.ClientCertPath, recv.ClientKeyPath, recv.HostnameValidation)
+}
+
+// ClusterDialConfig contains the parameters used to open TCP connections to the nodes of a cluster
+//   - LocalAddress is the source address of outbound connections, nil means that the OS picks it
+//   - NetworkProxyUrl is the SOCKS5 or HTTP CONNECT proxy that connections go through, nil means direct connections
+//   - SshJumpHost (host:port) is the SSH server that tunnels connections to the cluster, empty means no SSH tunnel.
+//     The connection to the jump host itself goes through NetworkProxyUrl if it is set. Its host key is verified
+//     with SshKnownHostsFile unless SshInsecureIgnoreHostKey is set.
+type ClusterDialConfig struct {
+	LocalAddress      net.IP
+	NetworkProxyUrl   *url.URL
+	SshJumpHost       string
+	SshUser           string
+	SshIdentityFile   string
+	SshKnownHostsFile string
+
+	SshInsecureIgnoreHostKey bool
+}
+
+func (recv *ClusterDialConfig) String() string {
+	networkProxyUrl := ""
+	if recv.NetworkProxyUrl != nil {
+		networkProxyUrl = recv.NetworkProxyUrl.Redacted()
+	}
+	return fmt.Sprintf("ClusterDialConfig{LocalAddress=%v, NetworkProxyUrl=%v, SshJumpHost=%v, SshUser=%v, "+
+		"SshIdentityFile=%v, SshKnownHostsFile=%v, SshInsecureIgnoreHostKey=%v}", recv.LocalAddress,
+		networkProxyUrl, recv.SshJumpHost, recv.SshUser, recv.SshIdentityFile, recv.SshKnownHostsFile,
+		recv.SshInsecureIgnoreHostKey)
 }
 
 // ProxyTlsConfig contains all TLS configuration parameters to enable TLS at proxy level
@@ -69,6 +102,85 @@ var (
 	ReadModeDualAsyncOnSecondary = ReadMode{"DUAL_ASYNC_ON_SECONDARY"}
 )
 
+// ReadShiftKey is what decides whether a read is shifted to the target cluster, see ZDM_TARGET_READ_SHIFT_KEY.
+type ReadShiftKey struct {
+	slug string
+}
+
+func (r ReadShiftKey) String() string {
+	return r.slug
+}
+
+var (
+	ReadShiftKeyUndefined = ReadShiftKey{""}
+	ReadShiftKeyStatement = ReadShiftKey{"STATEMENT"}
+	ReadShiftKeyPartition = ReadShiftKey{"PARTITION"}
+)
+
+// WriteTimestampFloorMode is what the proxy does with writes that have an explicit timestamp older than
+// ZDM_WRITE_TIMESTAMP_FLOOR, see ZDM_WRITE_TIMESTAMP_FLOOR_MODE.
+type WriteTimestampFloorMode struct {
+	slug string
+}
+
+func (r WriteTimestampFloorMode) String() string {
+	return r.slug
+}
+
+var (
+	WriteTimestampFloorModeUndefined = WriteTimestampFloorMode{""}
+	WriteTimestampFloorModeWarn      = WriteTimestampFloorMode{"WARN"}
+	WriteTimestampFloorModeReject    = WriteTimestampFloorMode{"REJECT"}
+)
+
+// DualWriteFailureMode decides which failures of a write sent to both clusters are returned to the client, see
+// ZDM_DUAL_WRITE_FAILURE_MODE.
+type DualWriteFailureMode struct {
+	slug string
+}
+
+func (r DualWriteFailureMode) String() string {
+	return r.slug
+}
+
+var (
+	DualWriteFailureModeUndefined = DualWriteFailureMode{""}
+	DualWriteFailureModeEither    = DualWriteFailureMode{"EITHER"}
+	DualWriteFailureModeOrigin    = DualWriteFailureMode{"ORIGIN"}
+	DualWriteFailureModeTarget    = DualWriteFailureMode{"TARGET"}
+)
+
+// LwtMode decides how conditional writes (lightweight transactions) are forwarded, see ZDM_LWT_MODE.
+type LwtMode struct {
+	slug string
+}
+
+func (r LwtMode) String() string {
+	return r.slug
+}
+
+var (
+	LwtModeUndefined   = LwtMode{""}
+	LwtModeDual        = LwtMode{"DUAL"}
+	LwtModePrimaryOnly = LwtMode{"PRIMARY_ONLY"}
+	LwtModeReject      = LwtMode{"REJECT"}
+)
+
+// CounterWriteMode decides how counter updates are forwarded, see ZDM_COUNTER_WRITE_MODE.
+type CounterWriteMode struct {
+	slug string
+}
+
+func (r CounterWriteMode) String() string {
+	return r.slug
+}
+
+var (
+	CounterWriteModeUndefined   = CounterWriteMode{""}
+	CounterWriteModeDual        = CounterWriteMode{"DUAL"}
+	CounterWriteModePrimaryOnly = CounterWriteMode{"PRIMARY_ONLY"}
+)
+
 type SystemQueriesMode struct {
 	slug string
 }
@@ -83,6 +195,20 @@ var (
 	SystemQueriesModeTarget    = SystemQueriesMode{"TARGET"}
 )
 
+type AdminRole struct {
+	slug string
+}
+
+func (r AdminRole) String() string {
+	return r.slug
+}
+
+var (
+	AdminRoleNone     = AdminRole{""}
+	AdminRoleReadOnly = AdminRole{"READ_ONLY"}
+	AdminRoleOperator = AdminRole{"OPERATOR"}
+)
+
 type ClusterType string
 
 const (
@@ -90,3 +216,43 @@ const (
 	ClusterTypeOrigin = ClusterType("ORIGIN")
 	ClusterTypeTarget = ClusterType("TARGET")
 )
+
+// Feature is a feature that can be rolled out to a percentage of the client connections, see ZDM_FEATURE_FLAGS.
+type Feature string
+
+const (
+	FeatureAsyncReads = Feature("async_reads")
+)
+
+// Features contains every feature that can be rolled out gradually.
+var Features = []Feature{FeatureAsyncReads}
+
+func ParseFeature(name string) (Feature, error) {
+	for _, feature := range Features {
+		if string(feature) == name {
+			return feature, nil
+		}
+	}
+	return "", fmt.Errorf("unknown feature %v, valid features are: %v", name, Features)
+}
+
+// ClientReadRoute routes the reads of the client connections whose IP address is in the subnet to the cluster instead
+// of the primary cluster, see ZDM_CLIENT_READ_ROUTING.
+type ClientReadRoute struct {
+	Subnet  *net.IPNet
+	Cluster ClusterType
+}
+
+func (recv *ClientReadRoute) String() string {
+	return fmt.Sprintf("%v=%v", recv.Subnet, recv.Cluster)
+}
+
+// EventForwarding maps the type of the protocol events to the cluster whose events of that type are forwarded to the
+// clients, see ZDM_EVENT_FORWARDING. Events of a type that is not in the map are not forwarded.
+type EventForwarding map[primitive.EventType]ClusterType
+
+// Forward returns true if the events of this type that are received from the cluster must be forwarded to the clients.
+func (recv EventForwarding) Forward(eventType primitive.EventType, cluster ClusterType) bool {
+	forwardedCluster, ok := recv[eventType]
+	return ok && forwardedCluster == cluster
+}
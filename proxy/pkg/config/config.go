@@ -1,6 +1,7 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"github.com/datastax/go-cassandra-native-protocol/primitive"
@@ -9,10 +10,14 @@ import (
 	def "github.com/mcuadros/go-defaults"
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
+	"io"
 	"net"
+	"net/url"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Config holds the values of environment variables necessary for proper Proxy function.
@@ -20,12 +25,40 @@ type Config struct {
 
 	// Global bucket
 
+	Profile                       string `split_words:"true" yaml:"profile"`
 	PrimaryCluster                string `default:"ORIGIN" split_words:"true" yaml:"primary_cluster"`
 	ReadMode                      string `default:"PRIMARY_ONLY" split_words:"true" yaml:"read_mode"`
 	ReplaceCqlFunctions           bool   `default:"false" split_words:"true" yaml:"replace_cql_functions"`
 	AsyncHandshakeTimeoutMs       int    `default:"4000" split_words:"true" yaml:"async_handshake_timeout_ms"`
 	LogLevel                      string `default:"INFO" split_words:"true" yaml:"log_level"`
 	ControlConnMaxProtocolVersion string `default:"DseV2" split_words:"true" yaml:"control_conn_max_protocol_version"` // Numeric Cassandra OSS protocol version or DseV1 / DseV2
+	FeatureFlags                  string `split_words:"true" yaml:"feature_flags"`
+	ClientReadRouting             string `split_words:"true" yaml:"client_read_routing"`
+	EventForwarding               string `split_words:"true" yaml:"event_forwarding"`
+	TargetReadPercentage          int    `default:"0" split_words:"true" yaml:"target_read_percentage"`
+	TargetReadShiftKey            string `default:"STATEMENT" split_words:"true" yaml:"target_read_shift_key"`
+
+	TargetReadRampSteps         string  `split_words:"true" yaml:"target_read_ramp_steps"`
+	TargetReadRampIntervalSecs  int     `default:"600" split_words:"true" yaml:"target_read_ramp_interval_secs"`
+	TargetReadRampMinReads      int     `default:"100" split_words:"true" yaml:"target_read_ramp_min_reads"`
+	TargetReadRampMaxErrorRatio float64 `default:"0.01" split_words:"true" yaml:"target_read_ramp_max_error_ratio"`
+
+	TargetReadCutoverMaxMissingWrites int `default:"0" split_words:"true" yaml:"target_read_cutover_max_missing_writes"`
+
+	WriteTimestampFloor     string `split_words:"true" yaml:"write_timestamp_floor"`
+	WriteTimestampFloorMode string `default:"WARN" split_words:"true" yaml:"write_timestamp_floor_mode"`
+
+	// ORIGIN and TARGET acknowledge writes that failed on the other cluster, like the PRIMARY_ONLY modes of LwtMode and
+	// CounterWriteMode acknowledge writes that are only sent to the primary cluster. The two clusters differ after
+	// these writes until the data is migrated again, they are counted in proxy_single_sided_writes_total.
+	DualWriteFailureMode string `default:"EITHER" split_words:"true" yaml:"dual_write_failure_mode"`
+
+	LwtMode          string `default:"DUAL" split_words:"true" yaml:"lwt_mode"`
+	CounterWriteMode string `default:"DUAL" split_words:"true" yaml:"counter_write_mode"`
+
+	SecondaryWriteRetryAttempts   int    `default:"0" split_words:"true" yaml:"secondary_write_retry_attempts"`
+	SecondaryWriteRetryBackoffMs  int    `default:"100" split_words:"true" yaml:"secondary_write_retry_backoff_ms"`
+	SecondaryWriteRetryErrorCodes string `default:"OVERLOADED,IS_BOOTSTRAPPING,UNAVAILABLE" split_words:"true" yaml:"secondary_write_retry_error_codes"`
 
 	// Proxy Topology (also known as system.peers "virtualization") bucket
 
@@ -43,9 +76,21 @@ type Config struct {
 	OriginPassword                string `required:"true" split_words:"true" json:"-" yaml:"origin_password"`
 	OriginConnectionTimeoutMs     int    `default:"30000" split_words:"true" yaml:"origin_connection_timeout_ms"`
 
-	OriginTlsServerCaPath   string `split_words:"true" yaml:"origin_tls_server_ca_path"`
-	OriginTlsClientCertPath string `split_words:"true" yaml:"origin_tls_client_cert_path"`
-	OriginTlsClientKeyPath  string `split_words:"true" yaml:"origin_tls_client_key_path"`
+	OriginTlsServerCaPath       string `split_words:"true" yaml:"origin_tls_server_ca_path"`
+	OriginTlsClientCertPath     string `split_words:"true" yaml:"origin_tls_client_cert_path"`
+	OriginTlsClientKeyPath      string `split_words:"true" yaml:"origin_tls_client_key_path"`
+	OriginTlsHostnameValidation bool   `default:"false" split_words:"true" yaml:"origin_tls_hostname_validation"`
+
+	OriginLocalAddress    string `split_words:"true" yaml:"origin_local_address"`
+	OriginNetworkProxyUrl string `split_words:"true" json:"-" yaml:"origin_network_proxy_url"`
+
+	OriginSshJumpHost       string `split_words:"true" yaml:"origin_ssh_jump_host"`
+	OriginSshIdentityFile   string `split_words:"true" yaml:"origin_ssh_identity_file"`
+	OriginSshKnownHostsFile string `split_words:"true" yaml:"origin_ssh_known_hosts_file"`
+
+	OriginSshInsecureIgnoreHostKey bool `default:"false" split_words:"true" yaml:"origin_ssh_insecure_ignore_host_key"`
+
+	OriginIsZdmProxy bool `default:"false" split_words:"true" yaml:"origin_is_zdm_proxy"`
 
 	// Target bucket
 
@@ -57,17 +102,52 @@ type Config struct {
 	TargetPassword                string `required:"true" split_words:"true" json:"-" yaml:"target_password"`
 	TargetConnectionTimeoutMs     int    `default:"30000" split_words:"true" yaml:"target_connection_timeout_ms"`
 
-	TargetTlsServerCaPath   string `split_words:"true" yaml:"target_tls_server_ca_path"`
-	TargetTlsClientCertPath string `split_words:"true" yaml:"target_tls_client_cert_path"`
-	TargetTlsClientKeyPath  string `split_words:"true" yaml:"target_tls_client_key_path"`
+	TargetTlsServerCaPath       string `split_words:"true" yaml:"target_tls_server_ca_path"`
+	TargetTlsClientCertPath     string `split_words:"true" yaml:"target_tls_client_cert_path"`
+	TargetTlsClientKeyPath      string `split_words:"true" yaml:"target_tls_client_key_path"`
+	TargetTlsHostnameValidation bool   `default:"false" split_words:"true" yaml:"target_tls_hostname_validation"`
+
+	TargetLocalAddress    string `split_words:"true" yaml:"target_local_address"`
+	TargetNetworkProxyUrl string `split_words:"true" json:"-" yaml:"target_network_proxy_url"`
+
+	TargetSshJumpHost       string `split_words:"true" yaml:"target_ssh_jump_host"`
+	TargetSshIdentityFile   string `split_words:"true" yaml:"target_ssh_identity_file"`
+	TargetSshKnownHostsFile string `split_words:"true" yaml:"target_ssh_known_hosts_file"`
+
+	TargetSshInsecureIgnoreHostKey bool `default:"false" split_words:"true" yaml:"target_ssh_insecure_ignore_host_key"`
+
+	TargetQualifyTableNames bool `default:"false" split_words:"true" yaml:"target_qualify_table_names"`
 
 	// Proxy bucket
 
-	ProxyListenAddress        string `default:"localhost" split_words:"true" yaml:"proxy_listen_address"`
-	ProxyListenPort           int    `default:"14002" split_words:"true" yaml:"proxy_listen_port"`
-	ProxyRequestTimeoutMs     int    `default:"10000" split_words:"true" yaml:"proxy_request_timeout_ms"`
-	ProxyMaxClientConnections int    `default:"1000" split_words:"true" yaml:"proxy_max_client_connections"`
-	ProxyMaxStreamIds         int    `default:"2048" split_words:"true" yaml:"proxy_max_stream_ids"`
+	ProxyListenAddress            string `default:"localhost" split_words:"true" yaml:"proxy_listen_address"`
+	ProxyListenPort               int    `default:"14002" split_words:"true" yaml:"proxy_listen_port"`
+	ProxyRequestTimeoutMs         int    `default:"10000" split_words:"true" yaml:"proxy_request_timeout_ms"`
+	ProxyMaxClientConnections     int    `default:"1000" split_words:"true" yaml:"proxy_max_client_connections"`
+	ProxyClientWriteTimeoutMs     int    `default:"30000" split_words:"true" yaml:"proxy_client_write_timeout_ms"`
+	ProxyClusterWriteTimeoutMs    int    `default:"30000" split_words:"true" yaml:"proxy_cluster_write_timeout_ms"`
+	ProxyClientHandshakeTimeoutMs int    `default:"10000" split_words:"true" yaml:"proxy_client_handshake_timeout_ms"`
+	ProxyMaxStreamIds             int    `default:"2048" split_words:"true" yaml:"proxy_max_stream_ids"`
+
+	ProxyControlConnectionReadTimeoutMs  int  `default:"10000" split_words:"true" yaml:"proxy_control_connection_read_timeout_ms"`
+	ProxyControlConnectionWriteTimeoutMs int  `default:"5000" split_words:"true" yaml:"proxy_control_connection_write_timeout_ms"`
+	ProxyHttpShutdownTimeoutMs           int  `default:"5000" split_words:"true" yaml:"proxy_http_shutdown_timeout_ms"`
+	ProxyRequestTimeoutErrors            bool `default:"false" split_words:"true" yaml:"proxy_request_timeout_errors"`
+
+	ProxyClientConnectionMaxBufferedBytes int  `default:"0" split_words:"true" yaml:"proxy_client_connection_max_buffered_bytes"`
+	ProxyMaxFrameSizeBytes                int  `default:"268435456" split_words:"true" yaml:"proxy_max_frame_size_bytes"`
+	ProxyLocalHeartbeats                  bool `default:"false" split_words:"true" yaml:"proxy_local_heartbeats"`
+
+	ProxyRequestIdPayloadKey string `split_words:"true" yaml:"proxy_request_id_payload_key"`
+
+	ProxyCapacityInFlightRequests  int `default:"1000" split_words:"true" yaml:"proxy_capacity_in_flight_requests"`
+	ProxyCapacityRequestsPerSecond int `default:"0" split_words:"true" yaml:"proxy_capacity_requests_per_second"`
+	ProxyMaxInFlightRequests       int `default:"0" split_words:"true" yaml:"proxy_max_in_flight_requests"`
+
+	ProxyPidFile               string `split_words:"true" yaml:"proxy_pid_file"`
+	ProxyIgnoreRunningInstance bool   `default:"false" split_words:"true" yaml:"proxy_ignore_running_instance"`
+	ProxyStartupTimeoutMs      int    `default:"0" split_words:"true" yaml:"proxy_startup_timeout_ms"`
+	ProxyCrashReportFile       string `split_words:"true" yaml:"proxy_crash_report_file"`
 
 	ProxyTlsCaPath            string `split_words:"true" yaml:"proxy_tls_ca_path"`
 	ProxyTlsCertPath          string `split_words:"true" yaml:"proxy_tls_cert_path"`
@@ -85,15 +165,42 @@ type Config struct {
 	MetricsTargetLatencyBucketsMs    string `default:"1, 4, 7, 10, 25, 40, 60, 80, 100, 150, 250, 500, 1000, 2500, 5000, 10000, 15000" split_words:"true" yaml:"metrics_target_latency_buckets_ms"`
 	MetricsAsyncReadLatencyBucketsMs string `default:"1, 4, 7, 10, 25, 40, 60, 80, 100, 150, 250, 500, 1000, 2500, 5000, 10000, 15000" split_words:"true" yaml:"metrics_async_read_latency_buckets_ms"`
 
+	MetricsHistoryIntervalMs int    `default:"10000" split_words:"true" yaml:"metrics_history_interval_ms"`
+	MetricsHistorySize       int    `default:"360" split_words:"true" yaml:"metrics_history_size"`
+	MetricsHistoryFile       string `split_words:"true" yaml:"metrics_history_file"`
+
+	RequestSamplingRate float64 `default:"0" split_words:"true" yaml:"request_sampling_rate"`
+	RequestSamplingFile string  `split_words:"true" yaml:"request_sampling_file"`
+
+	ConsistencyStatsEnabled bool `default:"false" split_words:"true" yaml:"consistency_stats_enabled"`
+
+	TrafficRecordingFile        string `split_words:"true" yaml:"traffic_recording_file"`
+	TrafficRecordingMaxRequests int    `default:"10000" split_words:"true" yaml:"traffic_recording_max_requests"`
+
+	// Admin bucket
+
+	AdminReadOnlyTokens string `split_words:"true" json:"-" yaml:"admin_read_only_tokens"`
+	AdminOperatorTokens string `split_words:"true" json:"-" yaml:"admin_operator_tokens"`
+	AdminAuditLogFile   string `split_words:"true" yaml:"admin_audit_log_file"`
+
 	// Heartbeat bucket
 
 	HeartbeatIntervalMs int `default:"30000" split_words:"true" yaml:"heartbeat_interval_ms"`
+	HeartbeatTimeoutMs  int `default:"10000" split_words:"true" yaml:"heartbeat_timeout_ms"`
 
 	HeartbeatRetryIntervalMinMs int     `default:"250" split_words:"true" yaml:"heartbeat_retry_interval_min_ms"`
 	HeartbeatRetryIntervalMaxMs int     `default:"30000" split_words:"true" yaml:"heartbeat_retry_interval_max_ms"`
 	HeartbeatRetryBackoffFactor float64 `default:"2" split_words:"true" yaml:"heartbeat_retry_backoff_factor"`
 	HeartbeatFailureThreshold   int     `default:"1" split_words:"true" yaml:"heartbeat_failure_threshold"`
 
+	// Node health bucket
+
+	NodeHealthQuarantineThreshold float64 `default:"0" split_words:"true" yaml:"node_health_quarantine_threshold"`
+	NodeHealthScoreHalfLifeMs     int     `default:"30000" split_words:"true" yaml:"node_health_score_half_life_ms"`
+	NodeHealthQuarantineMinMs     int     `default:"30000" split_words:"true" yaml:"node_health_quarantine_min_ms"`
+	NodeHealthQuarantineMaxMs     int     `default:"300000" split_words:"true" yaml:"node_health_quarantine_max_ms"`
+	NodeHealthReintroductionMs    int     `default:"60000" split_words:"true" yaml:"node_health_reintroduction_ms"`
+
 	//////////////////////////////////////////////////////////////////////
 	/// THE SETTINGS BELOW AREN'T SUPPORTED AND MAY CHANGE AT ANY TIME ///
 	//////////////////////////////////////////////////////////////////////
@@ -145,14 +252,41 @@ func (c *Config) loadFromFile(configFile string) error {
 	}
 	defer file.Close()
 
-	def.SetDefaults(c) // apply default tag, it is not supported by YAML decoder
-	dec := yaml.NewDecoder(file)
-	if err = dec.Decode(c); err != nil {
+	if err = c.loadFromYaml(file); err != nil {
 		return fmt.Errorf("could not parse yaml file %v: %w", configFile, err)
 	}
 	return nil
 }
 
+// ParseYaml returns the configuration contained in the provided YAML document with defaults applied to the settings
+// that are not set. Environment variables are not taken into account and the configuration is not validated.
+func ParseYaml(r io.Reader) (*Config, error) {
+	c := New()
+	if err := c.loadFromYaml(r); err != nil {
+		return nil, fmt.Errorf("could not parse yaml: %w", err)
+	}
+	return c, nil
+}
+
+func (c *Config) loadFromYaml(r io.Reader) error {
+	def.SetDefaults(c) // apply default tag, it is not supported by YAML decoder
+	document, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if err = yaml.NewDecoder(bytes.NewReader(document)).Decode(c); err != nil {
+		return err
+	}
+	settings := map[string]interface{}{}
+	if err = yaml.Unmarshal(document, &settings); err != nil {
+		return err
+	}
+	return c.applyProfile(func(setting string) bool {
+		_, ok := settings[setting]
+		return ok
+	})
+}
+
 // ParseEnvVars fills out the fields of the Config struct according to envconfig rules
 // See: Usage @ https://github.com/kelseyhightower/envconfig
 func (c *Config) parseEnvVars() error {
@@ -161,7 +295,7 @@ func (c *Config) parseEnvVars() error {
 		return fmt.Errorf("could not load environment variables: %w", err)
 	}
 
-	return nil
+	return c.applyProfile(isSetInEnvVars)
 }
 
 func (c *Config) LoadConfig(configFile string) (*Config, error) {
@@ -181,7 +315,7 @@ func (c *Config) LoadConfig(configFile string) (*Config, error) {
 		return nil, err
 	}
 
-	log.Infof("Parsed configuration: %v", c)
+	log.Infof("Parsed configuration (defaults applied, secrets redacted): %v", c.EffectiveJson())
 
 	return c, nil
 }
@@ -302,6 +436,26 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	_, err = c.ParseOriginDialConfig()
+	if err != nil {
+		return fmt.Errorf("invalid origin configuration: %w", err)
+	}
+
+	_, err = c.ParseTargetDialConfig()
+	if err != nil {
+		return fmt.Errorf("invalid target configuration: %w", err)
+	}
+
+	profile, err := c.parseProfile()
+	if err != nil {
+		return err
+	}
+	if profile != nil && profile.validate != nil {
+		if err = profile.validate(c); err != nil {
+			return err
+		}
+	}
+
 	_, err = c.ParsePrimaryCluster()
 	if err != nil {
 		return err
@@ -317,11 +471,177 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	_, err = c.ParseWriteTimestampFloor()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseWriteTimestampFloorMode()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseDualWriteFailureMode()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseLwtMode()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseCounterWriteMode()
+	if err != nil {
+		return err
+	}
+
+	if c.SecondaryWriteRetryAttempts < 0 {
+		return fmt.Errorf("invalid value for ZDM_SECONDARY_WRITE_RETRY_ATTEMPTS (%v), it must not be negative",
+			c.SecondaryWriteRetryAttempts)
+	}
+
+	if c.SecondaryWriteRetryBackoffMs <= 0 {
+		return fmt.Errorf("invalid value for ZDM_SECONDARY_WRITE_RETRY_BACKOFF_MS (%v), it must be positive",
+			c.SecondaryWriteRetryBackoffMs)
+	}
+
+	_, err = c.ParseSecondaryWriteRetryErrorCodes()
+	if err != nil {
+		return err
+	}
+
 	_, err = c.ParseControlConnMaxProtocolVersion()
 	if err != nil {
 		return err
 	}
 
+	_, err = c.ParseAdminTokens()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseFeatureFlags()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseClientReadRouting()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseEventForwarding()
+	if err != nil {
+		return err
+	}
+
+	if c.TargetReadPercentage < 0 || c.TargetReadPercentage > 100 {
+		return fmt.Errorf("invalid value for ZDM_TARGET_READ_PERCENTAGE (%v), it must be between 0 and 100", c.TargetReadPercentage)
+	}
+	_, err = c.ParseTargetReadShiftKey()
+	if err != nil {
+		return err
+	}
+	_, err = c.ParseTargetReadRampSteps()
+	if err != nil {
+		return err
+	}
+	if c.TargetReadRampIntervalSecs <= 0 {
+		return fmt.Errorf("invalid value for ZDM_TARGET_READ_RAMP_INTERVAL_SECS (%v), it must be positive", c.TargetReadRampIntervalSecs)
+	}
+	if c.TargetReadRampMinReads < 0 {
+		return fmt.Errorf("invalid value for ZDM_TARGET_READ_RAMP_MIN_READS (%v), it must not be negative", c.TargetReadRampMinReads)
+	}
+	if c.TargetReadRampMaxErrorRatio < 0 || c.TargetReadRampMaxErrorRatio > 1 {
+		return fmt.Errorf("invalid value for ZDM_TARGET_READ_RAMP_MAX_ERROR_RATIO (%v), it must be between 0 and 1", c.TargetReadRampMaxErrorRatio)
+	}
+	if c.TargetReadCutoverMaxMissingWrites < -1 {
+		return fmt.Errorf("invalid value for ZDM_TARGET_READ_CUTOVER_MAX_MISSING_WRITES (%v), it must be -1 (disabled) or more", c.TargetReadCutoverMaxMissingWrites)
+	}
+
+	if c.MetricsHistoryIntervalMs < 0 {
+		return fmt.Errorf("invalid value for ZDM_METRICS_HISTORY_INTERVAL_MS (%v), it must not be negative", c.MetricsHistoryIntervalMs)
+	}
+	if c.MetricsHistoryIntervalMs > 0 && c.MetricsHistorySize <= 0 {
+		return fmt.Errorf("invalid value for ZDM_METRICS_HISTORY_SIZE (%v), it must be positive", c.MetricsHistorySize)
+	}
+
+	if c.OriginConnectionTimeoutMs <= 0 {
+		return fmt.Errorf("invalid value for ZDM_ORIGIN_CONNECTION_TIMEOUT_MS (%v), it must be positive", c.OriginConnectionTimeoutMs)
+	}
+	if c.TargetConnectionTimeoutMs <= 0 {
+		return fmt.Errorf("invalid value for ZDM_TARGET_CONNECTION_TIMEOUT_MS (%v), it must be positive", c.TargetConnectionTimeoutMs)
+	}
+	if c.ProxyRequestTimeoutMs <= 0 {
+		return fmt.Errorf("invalid value for ZDM_PROXY_REQUEST_TIMEOUT_MS (%v), it must be positive", c.ProxyRequestTimeoutMs)
+	}
+	if c.AsyncHandshakeTimeoutMs <= 0 {
+		return fmt.Errorf("invalid value for ZDM_ASYNC_HANDSHAKE_TIMEOUT_MS (%v), it must be positive", c.AsyncHandshakeTimeoutMs)
+	}
+	if c.ProxyControlConnectionReadTimeoutMs <= 0 {
+		return fmt.Errorf("invalid value for ZDM_PROXY_CONTROL_CONNECTION_READ_TIMEOUT_MS (%v), it must be positive", c.ProxyControlConnectionReadTimeoutMs)
+	}
+	if c.ProxyControlConnectionWriteTimeoutMs <= 0 {
+		return fmt.Errorf("invalid value for ZDM_PROXY_CONTROL_CONNECTION_WRITE_TIMEOUT_MS (%v), it must be positive", c.ProxyControlConnectionWriteTimeoutMs)
+	}
+	if c.ProxyHttpShutdownTimeoutMs < 0 {
+		return fmt.Errorf("invalid value for ZDM_PROXY_HTTP_SHUTDOWN_TIMEOUT_MS (%v), it must not be negative", c.ProxyHttpShutdownTimeoutMs)
+	}
+	if c.ProxyClientWriteTimeoutMs < 0 {
+		return fmt.Errorf("invalid value for ZDM_PROXY_CLIENT_WRITE_TIMEOUT_MS (%v), it must not be negative", c.ProxyClientWriteTimeoutMs)
+	}
+	if c.ProxyClusterWriteTimeoutMs < 0 {
+		return fmt.Errorf("invalid value for ZDM_PROXY_CLUSTER_WRITE_TIMEOUT_MS (%v), it must not be negative", c.ProxyClusterWriteTimeoutMs)
+	}
+	if c.HeartbeatTimeoutMs < 0 {
+		return fmt.Errorf("invalid value for ZDM_HEARTBEAT_TIMEOUT_MS (%v), it must not be negative", c.HeartbeatTimeoutMs)
+	}
+	if c.ProxyClientConnectionMaxBufferedBytes < 0 {
+		return fmt.Errorf("invalid value for ZDM_PROXY_CLIENT_CONNECTION_MAX_BUFFERED_BYTES (%v), it must not be negative", c.ProxyClientConnectionMaxBufferedBytes)
+	}
+	if c.ProxyMaxFrameSizeBytes < 0 {
+		return fmt.Errorf("invalid value for ZDM_PROXY_MAX_FRAME_SIZE_BYTES (%v), it must not be negative", c.ProxyMaxFrameSizeBytes)
+	}
+	if c.ProxyMaxInFlightRequests < 0 {
+		return fmt.Errorf("invalid value for ZDM_PROXY_MAX_IN_FLIGHT_REQUESTS (%v), it must not be negative", c.ProxyMaxInFlightRequests)
+	}
+	if c.ProxyClientHandshakeTimeoutMs < 0 {
+		return fmt.Errorf("invalid value for ZDM_PROXY_CLIENT_HANDSHAKE_TIMEOUT_MS (%v), it must not be negative", c.ProxyClientHandshakeTimeoutMs)
+	}
+	if c.ProxyStartupTimeoutMs < 0 {
+		return fmt.Errorf("invalid value for ZDM_PROXY_STARTUP_TIMEOUT_MS (%v), it must not be negative", c.ProxyStartupTimeoutMs)
+	}
+
+	if c.RequestSamplingRate < 0 || c.RequestSamplingRate > 1 {
+		return fmt.Errorf("invalid value for ZDM_REQUEST_SAMPLING_RATE (%v), it must be between 0 and 1", c.RequestSamplingRate)
+	}
+	if c.RequestSamplingRate > 0 && c.RequestSamplingFile == "" {
+		return fmt.Errorf("ZDM_REQUEST_SAMPLING_FILE is required when ZDM_REQUEST_SAMPLING_RATE is set")
+	}
+	if c.TrafficRecordingMaxRequests < 0 {
+		return fmt.Errorf("invalid value for ZDM_TRAFFIC_RECORDING_MAX_REQUESTS (%v), it must not be negative", c.TrafficRecordingMaxRequests)
+	}
+
+	if c.NodeHealthQuarantineThreshold < 0 {
+		return fmt.Errorf("invalid value for ZDM_NODE_HEALTH_QUARANTINE_THRESHOLD (%v), it must not be negative", c.NodeHealthQuarantineThreshold)
+	}
+	if c.NodeHealthQuarantineThreshold > 0 {
+		if c.NodeHealthScoreHalfLifeMs <= 0 {
+			return fmt.Errorf("invalid value for ZDM_NODE_HEALTH_SCORE_HALF_LIFE_MS (%v), it must be positive", c.NodeHealthScoreHalfLifeMs)
+		}
+		if c.NodeHealthQuarantineMinMs <= 0 {
+			return fmt.Errorf("invalid value for ZDM_NODE_HEALTH_QUARANTINE_MIN_MS (%v), it must be positive", c.NodeHealthQuarantineMinMs)
+		}
+		if c.NodeHealthQuarantineMaxMs < c.NodeHealthQuarantineMinMs {
+			return fmt.Errorf("invalid value for ZDM_NODE_HEALTH_QUARANTINE_MAX_MS (%v), it must not be lower than ZDM_NODE_HEALTH_QUARANTINE_MIN_MS (%v)",
+				c.NodeHealthQuarantineMaxMs, c.NodeHealthQuarantineMinMs)
+		}
+		if c.NodeHealthReintroductionMs < 0 {
+			return fmt.Errorf("invalid value for ZDM_NODE_HEALTH_REINTRODUCTION_MS (%v), it must not be negative", c.NodeHealthReintroductionMs)
+		}
+	}
+
 	return nil
 }
 
@@ -347,6 +667,9 @@ const (
 	PrimaryClusterTarget = "TARGET"
 )
 
+// EventForwardingNone disables the forwarding of an event type in ZDM_EVENT_FORWARDING.
+const EventForwardingNone = "NONE"
+
 func (c *Config) ParsePrimaryCluster() (common.ClusterType, error) {
 	switch strings.ToUpper(c.PrimaryCluster) {
 	case PrimaryClusterOrigin:
@@ -376,6 +699,164 @@ func (c *Config) ParseReadMode() (common.ReadMode, error) {
 	}
 }
 
+// ParseWriteTimestampFloor returns the time of ZDM_WRITE_TIMESTAMP_FLOOR, or the zero time if it is not set.
+func (c *Config) ParseWriteTimestampFloor() (time.Time, error) {
+	if isNotDefined(c.WriteTimestampFloor) {
+		return time.Time{}, nil
+	}
+	floor, err := time.Parse(time.RFC3339, c.WriteTimestampFloor)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid value for ZDM_WRITE_TIMESTAMP_FLOOR (%v), it must be a RFC 3339 "+
+			"date and time such as 2006-01-02T15:04:05Z: %w", c.WriteTimestampFloor, err)
+	}
+	return floor, nil
+}
+
+const (
+	WriteTimestampFloorModeWarn   = "WARN"
+	WriteTimestampFloorModeReject = "REJECT"
+)
+
+func (c *Config) ParseWriteTimestampFloorMode() (common.WriteTimestampFloorMode, error) {
+	switch strings.ToUpper(c.WriteTimestampFloorMode) {
+	case WriteTimestampFloorModeWarn:
+		return common.WriteTimestampFloorModeWarn, nil
+	case WriteTimestampFloorModeReject:
+		return common.WriteTimestampFloorModeReject, nil
+	default:
+		return common.WriteTimestampFloorModeUndefined, fmt.Errorf("invalid value for ZDM_WRITE_TIMESTAMP_FLOOR_MODE; possible values are: %v and %v",
+			WriteTimestampFloorModeWarn, WriteTimestampFloorModeReject)
+	}
+}
+
+const (
+	DualWriteFailureModeEither = "EITHER"
+	DualWriteFailureModeOrigin = "ORIGIN"
+	DualWriteFailureModeTarget = "TARGET"
+)
+
+func (c *Config) ParseDualWriteFailureMode() (common.DualWriteFailureMode, error) {
+	switch strings.ToUpper(c.DualWriteFailureMode) {
+	case DualWriteFailureModeEither:
+		return common.DualWriteFailureModeEither, nil
+	case DualWriteFailureModeOrigin:
+		return common.DualWriteFailureModeOrigin, nil
+	case DualWriteFailureModeTarget:
+		return common.DualWriteFailureModeTarget, nil
+	default:
+		return common.DualWriteFailureModeUndefined, fmt.Errorf("invalid value for ZDM_DUAL_WRITE_FAILURE_MODE; possible values are: %v, %v and %v",
+			DualWriteFailureModeEither, DualWriteFailureModeOrigin, DualWriteFailureModeTarget)
+	}
+}
+
+const (
+	LwtModeDual        = "DUAL"
+	LwtModePrimaryOnly = "PRIMARY_ONLY"
+	LwtModeReject      = "REJECT"
+)
+
+func (c *Config) ParseLwtMode() (common.LwtMode, error) {
+	switch strings.ToUpper(c.LwtMode) {
+	case LwtModeDual:
+		return common.LwtModeDual, nil
+	case LwtModePrimaryOnly:
+		return common.LwtModePrimaryOnly, nil
+	case LwtModeReject:
+		return common.LwtModeReject, nil
+	default:
+		return common.LwtModeUndefined, fmt.Errorf("invalid value for ZDM_LWT_MODE; possible values are: %v, %v and %v",
+			LwtModeDual, LwtModePrimaryOnly, LwtModeReject)
+	}
+}
+
+const (
+	CounterWriteModeDual        = "DUAL"
+	CounterWriteModePrimaryOnly = "PRIMARY_ONLY"
+)
+
+func (c *Config) ParseCounterWriteMode() (common.CounterWriteMode, error) {
+	switch strings.ToUpper(c.CounterWriteMode) {
+	case CounterWriteModeDual:
+		return common.CounterWriteModeDual, nil
+	case CounterWriteModePrimaryOnly:
+		return common.CounterWriteModePrimaryOnly, nil
+	default:
+		return common.CounterWriteModeUndefined, fmt.Errorf("invalid value for ZDM_COUNTER_WRITE_MODE; possible values are: %v and %v",
+			CounterWriteModeDual, CounterWriteModePrimaryOnly)
+	}
+}
+
+// secondaryWriteRetryErrorCodes are the error codes that ZDM_SECONDARY_WRITE_RETRY_ERROR_CODES can contain, the other
+// errors (e.g. INVALID or UNAUTHORIZED) would fail again.
+var secondaryWriteRetryErrorCodes = map[string]primitive.ErrorCode{
+	"SERVER_ERROR":     primitive.ErrorCodeServerError,
+	"UNAVAILABLE":      primitive.ErrorCodeUnavailable,
+	"OVERLOADED":       primitive.ErrorCodeOverloaded,
+	"IS_BOOTSTRAPPING": primitive.ErrorCodeIsBootstrapping,
+	"TRUNCATE_ERROR":   primitive.ErrorCodeTruncateError,
+	"WRITE_TIMEOUT":    primitive.ErrorCodeWriteTimeout,
+	"WRITE_FAILURE":    primitive.ErrorCodeWriteFailure,
+}
+
+func (c *Config) ParseSecondaryWriteRetryErrorCodes() ([]primitive.ErrorCode, error) {
+	var errorCodes []primitive.ErrorCode
+	for _, name := range strings.Split(c.SecondaryWriteRetryErrorCodes, ",") {
+		name = strings.ToUpper(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		errorCode, ok := secondaryWriteRetryErrorCodes[name]
+		if !ok {
+			names := make([]string, 0, len(secondaryWriteRetryErrorCodes))
+			for name := range secondaryWriteRetryErrorCodes {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("invalid value for ZDM_SECONDARY_WRITE_RETRY_ERROR_CODES (%v); possible values are: %v",
+				c.SecondaryWriteRetryErrorCodes, strings.Join(names, ", "))
+		}
+		errorCodes = append(errorCodes, errorCode)
+	}
+	return errorCodes, nil
+}
+
+const (
+	TargetReadShiftKeyStatement = "STATEMENT"
+	TargetReadShiftKeyPartition = "PARTITION"
+)
+
+func (c *Config) ParseTargetReadShiftKey() (common.ReadShiftKey, error) {
+	switch strings.ToUpper(c.TargetReadShiftKey) {
+	case TargetReadShiftKeyStatement:
+		return common.ReadShiftKeyStatement, nil
+	case TargetReadShiftKeyPartition:
+		return common.ReadShiftKeyPartition, nil
+	default:
+		return common.ReadShiftKeyUndefined, fmt.Errorf("invalid value for ZDM_TARGET_READ_SHIFT_KEY; possible values are: %v and %v",
+			TargetReadShiftKeyStatement, TargetReadShiftKeyPartition)
+	}
+}
+
+// ParseTargetReadRampSteps parses the comma separated percentages of the reads that are shifted to the target cluster
+// at each step of the automatic ramp, e.g. "1,10,50,100". It returns an empty slice if the ramp is disabled.
+func (c *Config) ParseTargetReadRampSteps() ([]int, error) {
+	steps := make([]int, 0)
+	if strings.TrimSpace(c.TargetReadRampSteps) == "" {
+		return steps, nil
+	}
+	previous := c.TargetReadPercentage
+	for _, stepStr := range strings.Split(c.TargetReadRampSteps, ",") {
+		step, err := strconv.Atoi(strings.TrimSpace(stepStr))
+		if err != nil || step <= previous || step > 100 {
+			return nil, fmt.Errorf("invalid value for ZDM_TARGET_READ_RAMP_STEPS (%v), expected increasing percentages "+
+				"between ZDM_TARGET_READ_PERCENTAGE (%v) and 100", c.TargetReadRampSteps, c.TargetReadPercentage)
+		}
+		steps = append(steps, step)
+		previous = step
+	}
+	return steps, nil
+}
+
 func (c *Config) ParseControlConnMaxProtocolVersion() (primitive.ProtocolVersion, error) {
 	if strings.EqualFold(c.ControlConnMaxProtocolVersion, "DseV2") {
 		return primitive.ProtocolVersionDse2, nil
@@ -516,6 +997,10 @@ func (c *Config) ParseOriginTlsConfig(displayLogMessages bool) (*common.ClusterT
 		isNotDefined(c.OriginTlsServerCaPath) &&
 		isNotDefined(c.OriginTlsClientCertPath) &&
 		isNotDefined(c.OriginTlsClientKeyPath) {
+		if c.OriginTlsHostnameValidation {
+			return &common.ClusterTlsConfig{}, fmt.Errorf("invalid TLS configuration for Origin: " +
+				"hostname validation is enabled but TLS is not configured, please specify the Server CA path")
+		}
 		if displayLogMessages {
 			log.Infof("TLS was not configured for Origin")
 		}
@@ -544,23 +1029,25 @@ func (c *Config) ParseOriginTlsConfig(displayLogMessages bool) (*common.ClusterT
 
 	if isDefined(c.OriginTlsServerCaPath) && (isNotDefined(c.OriginTlsClientCertPath) && isNotDefined(c.OriginTlsClientKeyPath)) {
 		if displayLogMessages {
-			log.Infof("One-way TLS configured for Origin. Please note that hostname verification is not currently supported.")
+			log.Infof("One-way TLS configured for Origin (hostname validation %v).", hostnameValidationStatus(c.OriginTlsHostnameValidation))
 		}
 		return &common.ClusterTlsConfig{
-			TlsEnabled:   true,
-			ServerCaPath: c.OriginTlsServerCaPath,
+			TlsEnabled:         true,
+			ServerCaPath:       c.OriginTlsServerCaPath,
+			HostnameValidation: c.OriginTlsHostnameValidation,
 		}, nil
 	}
 
 	if isDefined(c.OriginTlsServerCaPath) && isDefined(c.OriginTlsClientCertPath) && isDefined(c.OriginTlsClientKeyPath) {
 		if displayLogMessages {
-			log.Infof("Mutual TLS configured for Origin. Please note that hostname verification is not currently supported.")
+			log.Infof("Mutual TLS configured for Origin (hostname validation %v).", hostnameValidationStatus(c.OriginTlsHostnameValidation))
 		}
 		return &common.ClusterTlsConfig{
-			TlsEnabled:     true,
-			ServerCaPath:   c.OriginTlsServerCaPath,
-			ClientCertPath: c.OriginTlsClientCertPath,
-			ClientKeyPath:  c.OriginTlsClientKeyPath,
+			TlsEnabled:         true,
+			ServerCaPath:       c.OriginTlsServerCaPath,
+			ClientCertPath:     c.OriginTlsClientCertPath,
+			ClientKeyPath:      c.OriginTlsClientKeyPath,
+			HostnameValidation: c.OriginTlsHostnameValidation,
 		}, nil
 	}
 
@@ -577,6 +1064,10 @@ func (c *Config) ParseTargetTlsConfig(displayLogMessages bool) (*common.ClusterT
 		isNotDefined(c.TargetTlsServerCaPath) &&
 		isNotDefined(c.TargetTlsClientCertPath) &&
 		isNotDefined(c.TargetTlsClientKeyPath) {
+		if c.TargetTlsHostnameValidation {
+			return &common.ClusterTlsConfig{}, fmt.Errorf("invalid TLS configuration for Target: " +
+				"hostname validation is enabled but TLS is not configured, please specify the Server CA path")
+		}
 		if displayLogMessages {
 			log.Infof("TLS was not configured for Target")
 		}
@@ -602,23 +1093,25 @@ func (c *Config) ParseTargetTlsConfig(displayLogMessages bool) (*common.ClusterT
 
 	if isDefined(c.TargetTlsServerCaPath) && (isNotDefined(c.TargetTlsClientCertPath) && isNotDefined(c.TargetTlsClientKeyPath)) {
 		if displayLogMessages {
-			log.Infof("One-way TLS configured for Target. Please note that hostname verification is not currently supported.")
+			log.Infof("One-way TLS configured for Target (hostname validation %v).", hostnameValidationStatus(c.TargetTlsHostnameValidation))
 		}
 		return &common.ClusterTlsConfig{
-			TlsEnabled:   true,
-			ServerCaPath: c.TargetTlsServerCaPath,
+			TlsEnabled:         true,
+			ServerCaPath:       c.TargetTlsServerCaPath,
+			HostnameValidation: c.TargetTlsHostnameValidation,
 		}, nil
 	}
 
 	if isDefined(c.TargetTlsServerCaPath) && isDefined(c.TargetTlsClientCertPath) && isDefined(c.TargetTlsClientKeyPath) {
 		if displayLogMessages {
-			log.Infof("Mutual TLS configured for Target. Please note that hostname verification is not currently supported.")
+			log.Infof("Mutual TLS configured for Target (hostname validation %v).", hostnameValidationStatus(c.TargetTlsHostnameValidation))
 		}
 		return &common.ClusterTlsConfig{
-			TlsEnabled:     true,
-			ServerCaPath:   c.TargetTlsServerCaPath,
-			ClientCertPath: c.TargetTlsClientCertPath,
-			ClientKeyPath:  c.TargetTlsClientKeyPath,
+			TlsEnabled:         true,
+			ServerCaPath:       c.TargetTlsServerCaPath,
+			ClientCertPath:     c.TargetTlsClientCertPath,
+			ClientKeyPath:      c.TargetTlsClientKeyPath,
+			HostnameValidation: c.TargetTlsHostnameValidation,
 		}, nil
 	}
 
@@ -654,6 +1147,256 @@ func (c *Config) ParseProxyTlsConfig(displayLogMessages bool) (*common.ProxyTlsC
 	return &common.ProxyTlsConfig{}, fmt.Errorf("incomplete Proxy TLS configuration: when enabling proxy TLS, please specify CA path, Cert path and Key path")
 }
 
+// ParseAdminTokens returns the role of each admin API token. The admin API is disabled if no tokens are configured.
+func (c *Config) ParseAdminTokens() (map[string]common.AdminRole, error) {
+	tokens := make(map[string]common.AdminRole)
+	for _, token := range parseTokens(c.AdminReadOnlyTokens) {
+		tokens[token] = common.AdminRoleReadOnly
+	}
+	for _, token := range parseTokens(c.AdminOperatorTokens) {
+		if _, exists := tokens[token]; exists {
+			return nil, fmt.Errorf("invalid admin configuration: the same token can't be in both " +
+				"ZDM_ADMIN_READ_ONLY_TOKENS and ZDM_ADMIN_OPERATOR_TOKENS")
+		}
+		tokens[token] = common.AdminRoleOperator
+	}
+	return tokens, nil
+}
+
+// ParseFeatureFlags returns the percentage of client connections for which each feature is enabled. The setting is a
+// comma separated list of feature=percentage, features that are not listed are enabled for every client connection.
+func (c *Config) ParseFeatureFlags() (map[common.Feature]int, error) {
+	featureFlags := make(map[common.Feature]int)
+	for _, featureFlag := range parseTokens(c.FeatureFlags) {
+		name, percentage, found := strings.Cut(featureFlag, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid value for ZDM_FEATURE_FLAGS (%v), expected feature=percentage", featureFlag)
+		}
+		feature, err := common.ParseFeature(strings.TrimSpace(name))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for ZDM_FEATURE_FLAGS: %w", err)
+		}
+		parsedPercentage, err := strconv.Atoi(strings.TrimSpace(percentage))
+		if err != nil || parsedPercentage < 0 || parsedPercentage > 100 {
+			return nil, fmt.Errorf("invalid value for ZDM_FEATURE_FLAGS (%v), the percentage must be between 0 and 100", featureFlag)
+		}
+		featureFlags[feature] = parsedPercentage
+	}
+	return featureFlags, nil
+}
+
+// ParseClientReadRouting returns the subnets whose client connections read from a different cluster than the primary
+// cluster. The setting is a comma separated list of cidr=cluster, e.g. 10.1.0.0/16=TARGET.
+func (c *Config) ParseClientReadRouting() ([]*common.ClientReadRoute, error) {
+	routes := make([]*common.ClientReadRoute, 0)
+	for _, route := range parseTokens(c.ClientReadRouting) {
+		cidr, cluster, found := strings.Cut(route, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid value for ZDM_CLIENT_READ_ROUTING (%v), expected cidr=cluster", route)
+		}
+		_, subnet, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for ZDM_CLIENT_READ_ROUTING (%v): %w", route, err)
+		}
+		var clusterType common.ClusterType
+		switch strings.ToUpper(strings.TrimSpace(cluster)) {
+		case PrimaryClusterOrigin:
+			clusterType = common.ClusterTypeOrigin
+		case PrimaryClusterTarget:
+			clusterType = common.ClusterTypeTarget
+		default:
+			return nil, fmt.Errorf("invalid value for ZDM_CLIENT_READ_ROUTING (%v), possible clusters are: %v and %v",
+				route, PrimaryClusterOrigin, PrimaryClusterTarget)
+		}
+		for _, existing := range routes {
+			if existing.Subnet.String() == subnet.String() {
+				return nil, fmt.Errorf("invalid value for ZDM_CLIENT_READ_ROUTING, subnet %v is routed more than once", subnet)
+			}
+		}
+		routes = append(routes, &common.ClientReadRoute{Subnet: subnet, Cluster: clusterType})
+	}
+	return routes, nil
+}
+
+// ParseEventForwarding returns the cluster whose events are forwarded to the clients for each event type, the event
+// types that are not in ZDM_EVENT_FORWARDING keep the default: schema changes come from ORIGIN, topology and status
+// changes come from TARGET.
+func (c *Config) ParseEventForwarding() (common.EventForwarding, error) {
+	eventForwarding := common.EventForwarding{
+		primitive.EventTypeSchemaChange:   common.ClusterTypeOrigin,
+		primitive.EventTypeTopologyChange: common.ClusterTypeTarget,
+		primitive.EventTypeStatusChange:   common.ClusterTypeTarget,
+	}
+	for _, entry := range parseTokens(c.EventForwarding) {
+		event, cluster, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid value for ZDM_EVENT_FORWARDING (%v), expected event=cluster", entry)
+		}
+		eventType := primitive.EventType(strings.ToUpper(strings.TrimSpace(event)))
+		switch eventType {
+		case primitive.EventTypeSchemaChange, primitive.EventTypeTopologyChange, primitive.EventTypeStatusChange:
+		default:
+			return nil, fmt.Errorf("invalid value for ZDM_EVENT_FORWARDING (%v), possible events are: %v, %v and %v",
+				entry, primitive.EventTypeSchemaChange, primitive.EventTypeTopologyChange, primitive.EventTypeStatusChange)
+		}
+		switch strings.ToUpper(strings.TrimSpace(cluster)) {
+		case PrimaryClusterOrigin:
+			eventForwarding[eventType] = common.ClusterTypeOrigin
+		case PrimaryClusterTarget:
+			eventForwarding[eventType] = common.ClusterTypeTarget
+		case EventForwardingNone:
+			delete(eventForwarding, eventType)
+		default:
+			return nil, fmt.Errorf("invalid value for ZDM_EVENT_FORWARDING (%v), possible clusters are: %v, %v and %v",
+				entry, PrimaryClusterOrigin, PrimaryClusterTarget, EventForwardingNone)
+		}
+	}
+	return eventForwarding, nil
+}
+
+func parseTokens(setting string) []string {
+	tokens := make([]string, 0)
+	for _, token := range strings.Split(setting, ",") {
+		token = strings.TrimSpace(token)
+		if token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+func (c *Config) ParseOriginDialConfig() (*common.ClusterDialConfig, error) {
+	return parseClusterDialConfig("ORIGIN", c.OriginLocalAddress, c.OriginNetworkProxyUrl,
+		c.OriginSshJumpHost, c.OriginSshIdentityFile, c.OriginSshKnownHostsFile, c.OriginSshInsecureIgnoreHostKey)
+}
+
+func (c *Config) ParseTargetDialConfig() (*common.ClusterDialConfig, error) {
+	return parseClusterDialConfig("TARGET", c.TargetLocalAddress, c.TargetNetworkProxyUrl,
+		c.TargetSshJumpHost, c.TargetSshIdentityFile, c.TargetSshKnownHostsFile, c.TargetSshInsecureIgnoreHostKey)
+}
+
+func parseClusterDialConfig(
+	cluster string, localAddress string, networkProxyUrl string,
+	sshJumpHost string, sshIdentityFile string, sshKnownHostsFile string,
+	sshInsecureIgnoreHostKey bool) (*common.ClusterDialConfig, error) {
+	localIp, err := parseLocalAddress(localAddress, fmt.Sprintf("ZDM_%v_LOCAL_ADDRESS", cluster))
+	if err != nil {
+		return nil, err
+	}
+	proxyUrl, err := parseNetworkProxyUrl(networkProxyUrl, fmt.Sprintf("ZDM_%v_NETWORK_PROXY_URL", cluster))
+	if err != nil {
+		return nil, err
+	}
+	dialConfig := &common.ClusterDialConfig{
+		LocalAddress:    localIp,
+		NetworkProxyUrl: proxyUrl,
+	}
+
+	sshJumpHost = strings.TrimSpace(sshJumpHost)
+	if sshJumpHost == "" {
+		if isDefined(sshIdentityFile) || isDefined(sshKnownHostsFile) || sshInsecureIgnoreHostKey {
+			return nil, fmt.Errorf("ZDM_%v_SSH_IDENTITY_FILE, ZDM_%v_SSH_KNOWN_HOSTS_FILE and "+
+				"ZDM_%v_SSH_INSECURE_IGNORE_HOST_KEY require ZDM_%v_SSH_JUMP_HOST", cluster, cluster, cluster, cluster)
+		}
+		return dialConfig, nil
+	}
+
+	user, hostPort, found := strings.Cut(sshJumpHost, "@")
+	if !found || user == "" || hostPort == "" {
+		return nil, fmt.Errorf("ZDM_%v_SSH_JUMP_HOST must be in the format user@host[:port] but got %v",
+			cluster, sshJumpHost)
+	}
+	if _, _, err = net.SplitHostPort(hostPort); err != nil {
+		hostPort = net.JoinHostPort(hostPort, "22")
+	}
+	if isNotDefined(sshIdentityFile) {
+		return nil, fmt.Errorf("ZDM_%v_SSH_IDENTITY_FILE is required when ZDM_%v_SSH_JUMP_HOST is set",
+			cluster, cluster)
+	}
+	// the jump host receives the cluster credentials and traffic, its host key is verified unless explicitly disabled
+	if isNotDefined(sshKnownHostsFile) && !sshInsecureIgnoreHostKey {
+		return nil, fmt.Errorf("ZDM_%v_SSH_KNOWN_HOSTS_FILE is required when ZDM_%v_SSH_JUMP_HOST is set, "+
+			"set ZDM_%v_SSH_INSECURE_IGNORE_HOST_KEY to true to connect without verifying the host key of the jump host",
+			cluster, cluster, cluster)
+	}
+	if isDefined(sshKnownHostsFile) && sshInsecureIgnoreHostKey {
+		return nil, fmt.Errorf("ZDM_%v_SSH_KNOWN_HOSTS_FILE and ZDM_%v_SSH_INSECURE_IGNORE_HOST_KEY "+
+			"can't be set at the same time", cluster, cluster)
+	}
+	dialConfig.SshJumpHost = hostPort
+	dialConfig.SshUser = user
+	dialConfig.SshIdentityFile = sshIdentityFile
+	dialConfig.SshKnownHostsFile = sshKnownHostsFile
+	dialConfig.SshInsecureIgnoreHostKey = sshInsecureIgnoreHostKey
+	return dialConfig, nil
+}
+
+// parseNetworkProxyUrl parses the URL of a SOCKS5 (socks5://[user:password@]host[:port]) or
+// HTTP CONNECT (http://[user:password@]host[:port]) proxy.
+func parseNetworkProxyUrl(setting string, settingName string) (*url.URL, error) {
+	setting = strings.TrimSpace(setting)
+	if setting == "" {
+		return nil, nil
+	}
+
+	proxyUrl, err := url.Parse(setting)
+	if err != nil {
+		// don't include the setting in the error message because it can contain credentials
+		return nil, fmt.Errorf("could not parse %v as an URL", settingName)
+	}
+	switch proxyUrl.Scheme {
+	case "socks5", "socks5h", "http":
+	default:
+		return nil, fmt.Errorf("invalid %v scheme %v, valid values are socks5, socks5h and http",
+			settingName, proxyUrl.Scheme)
+	}
+	if proxyUrl.Hostname() == "" {
+		return nil, fmt.Errorf("%v does not contain a host: %v", settingName, proxyUrl.Redacted())
+	}
+	return proxyUrl, nil
+}
+
+// parseLocalAddress accepts either an IP address or the name of a network interface. When an interface name is
+// provided, its first IPv4 address is used (or its first IPv6 address if it has no IPv4 addresses).
+func parseLocalAddress(setting string, settingName string) (net.IP, error) {
+	setting = strings.TrimSpace(setting)
+	if setting == "" {
+		return nil, nil
+	}
+
+	if ip := net.ParseIP(setting); ip != nil {
+		return ip, nil
+	}
+
+	iface, err := net.InterfaceByName(setting)
+	if err != nil {
+		return nil, fmt.Errorf("%v must be an IP address or a network interface name but got %v: %w",
+			settingName, setting, err)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("could not get addresses of network interface %v (%v): %w", setting, settingName, err)
+	}
+
+	var firstIp net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if ipNet.IP.To4() != nil {
+			return ipNet.IP, nil
+		}
+		if firstIp == nil {
+			firstIp = ipNet.IP
+		}
+	}
+	if firstIp == nil {
+		return nil, fmt.Errorf("network interface %v (%v) has no IP addresses", setting, settingName)
+	}
+	return firstIp, nil
+}
+
 func isDefined(propertyValue string) bool {
 	return propertyValue != ""
 }
@@ -661,3 +1404,10 @@ func isDefined(propertyValue string) bool {
 func isNotDefined(propertyValue string) bool {
 	return !isDefined(propertyValue)
 }
+
+func hostnameValidationStatus(enabled bool) string {
+	if enabled {
+		return "enabled"
+	}
+	return "disabled"
+}
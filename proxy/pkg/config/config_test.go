@@ -2,8 +2,13 @@ package config
 
 import (
 	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
 	"github.com/stretchr/testify/require"
+	"net"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestTargetConfig_WithBundleOnly(t *testing.T) {
@@ -187,6 +192,177 @@ func TestTargetConfig_ParsingControlConnMaxProtocolVersion(t *testing.T) {
 	}
 }
 
+func TestTargetConfig_ParsingLocalAddress(t *testing.T) {
+	defer clearAllEnvVars()
+
+	// general setup
+	clearAllEnvVars()
+	setOriginCredentialsEnvVars()
+	setTargetCredentialsEnvVars()
+	setOriginContactPointsAndPortEnvVars()
+
+	// test-specific setup
+	setTargetContactPointsAndPortEnvVars()
+
+	conf := New()
+	err := conf.parseEnvVars()
+	require.Nil(t, err)
+
+	tests := []struct {
+		name         string
+		localAddress string
+		parsedIp     net.IP
+		errorMessage string
+	}{
+		{
+			name:         "NotSet",
+			localAddress: "",
+			parsedIp:     nil,
+			errorMessage: "",
+		},
+		{
+			name:         "Ip4",
+			localAddress: "10.0.0.5",
+			parsedIp:     net.ParseIP("10.0.0.5"),
+			errorMessage: "",
+		},
+		{
+			name:         "Ip6",
+			localAddress: "::1",
+			parsedIp:     net.ParseIP("::1"),
+			errorMessage: "",
+		},
+		{
+			name:         "LoopbackInterface",
+			localAddress: "lo",
+			parsedIp:     net.ParseIP("127.0.0.1"),
+			errorMessage: "",
+		},
+		{
+			name:         "UnknownInterface",
+			localAddress: "notaninterface0",
+			parsedIp:     nil,
+			errorMessage: "ZDM_TARGET_LOCAL_ADDRESS must be an IP address or a network interface name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.name == "LoopbackInterface" {
+				if _, err := net.InterfaceByName("lo"); err != nil {
+					t.Skip("loopback interface lo not available")
+				}
+			}
+			conf.TargetLocalAddress = tt.localAddress
+			dialConfig, err := conf.ParseTargetDialConfig()
+			if tt.errorMessage != "" {
+				require.NotNil(t, err)
+				require.Contains(t, err.Error(), tt.errorMessage)
+			} else {
+				require.Nil(t, err)
+				require.True(t, tt.parsedIp.Equal(dialConfig.LocalAddress))
+			}
+		})
+	}
+}
+
+func TestTargetConfig_ParsingSshJumpHost(t *testing.T) {
+	tests := []struct {
+		name                  string
+		jumpHost              string
+		identityFile          string
+		knownHostsFile        string
+		insecureIgnoreHostKey bool
+		expectedJumpHost      string
+		expectedUser          string
+		errorMessage          string
+	}{
+		{
+			name:             "WithPort",
+			jumpHost:         "ubuntu@bastion.example.com:2222",
+			identityFile:     "/path/to/key",
+			knownHostsFile:   "/path/to/known_hosts",
+			expectedJumpHost: "bastion.example.com:2222",
+			expectedUser:     "ubuntu",
+		},
+		{
+			name:             "DefaultPort",
+			jumpHost:         "ubuntu@10.0.0.1",
+			identityFile:     "/path/to/key",
+			knownHostsFile:   "/path/to/known_hosts",
+			expectedJumpHost: "10.0.0.1:22",
+			expectedUser:     "ubuntu",
+		},
+		{
+			name:                  "InsecureIgnoreHostKey",
+			jumpHost:              "ubuntu@10.0.0.1",
+			identityFile:          "/path/to/key",
+			insecureIgnoreHostKey: true,
+			expectedJumpHost:      "10.0.0.1:22",
+			expectedUser:          "ubuntu",
+		},
+		{
+			name:           "MissingUser",
+			jumpHost:       "bastion.example.com",
+			identityFile:   "/path/to/key",
+			knownHostsFile: "/path/to/known_hosts",
+			errorMessage:   "ZDM_TARGET_SSH_JUMP_HOST must be in the format user@host[:port]",
+		},
+		{
+			name:           "MissingIdentityFile",
+			jumpHost:       "ubuntu@bastion.example.com",
+			knownHostsFile: "/path/to/known_hosts",
+			errorMessage:   "ZDM_TARGET_SSH_IDENTITY_FILE is required",
+		},
+		{
+			name:         "MissingKnownHostsFile",
+			jumpHost:     "ubuntu@bastion.example.com",
+			identityFile: "/path/to/key",
+			errorMessage: "ZDM_TARGET_SSH_KNOWN_HOSTS_FILE is required",
+		},
+		{
+			name:                  "KnownHostsFileAndInsecureIgnoreHostKey",
+			jumpHost:              "ubuntu@bastion.example.com",
+			identityFile:          "/path/to/key",
+			knownHostsFile:        "/path/to/known_hosts",
+			insecureIgnoreHostKey: true,
+			errorMessage:          "can't be set at the same time",
+		},
+		{
+			name:         "IdentityFileWithoutJumpHost",
+			identityFile: "/path/to/key",
+			errorMessage: "require ZDM_TARGET_SSH_JUMP_HOST",
+		},
+		{
+			name:                  "InsecureIgnoreHostKeyWithoutJumpHost",
+			insecureIgnoreHostKey: true,
+			errorMessage:          "require ZDM_TARGET_SSH_JUMP_HOST",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := New()
+			conf.TargetSshJumpHost = tt.jumpHost
+			conf.TargetSshIdentityFile = tt.identityFile
+			conf.TargetSshKnownHostsFile = tt.knownHostsFile
+			conf.TargetSshInsecureIgnoreHostKey = tt.insecureIgnoreHostKey
+			dialConfig, err := conf.ParseTargetDialConfig()
+			if tt.errorMessage != "" {
+				require.NotNil(t, err)
+				require.Contains(t, err.Error(), tt.errorMessage)
+			} else {
+				require.Nil(t, err)
+				require.Equal(t, tt.expectedJumpHost, dialConfig.SshJumpHost)
+				require.Equal(t, tt.expectedUser, dialConfig.SshUser)
+				require.Equal(t, tt.identityFile, dialConfig.SshIdentityFile)
+				require.Equal(t, tt.knownHostsFile, dialConfig.SshKnownHostsFile)
+				require.Equal(t, tt.insecureIgnoreHostKey, dialConfig.SshInsecureIgnoreHostKey)
+			}
+		})
+	}
+}
+
 func TestConfig_LoadNotExistingFile(t *testing.T) {
 	defer clearAllEnvVars()
 	clearAllEnvVars()
@@ -231,3 +407,311 @@ proxy_listen_port: 39042
 	require.Equal(t, 39042, c.ProxyListenPort)
 	require.Equal(t, 4000, c.AsyncHandshakeTimeoutMs) // verify that defaults were applied
 }
+
+func TestConfig_ParseFeatureFlags(t *testing.T) {
+	tests := []struct {
+		name        string
+		setting     string
+		expected    map[common.Feature]int
+		expectedErr string
+	}{
+		{"not set", "", map[common.Feature]int{}, ""},
+		{"percentage", " async_reads = 25 ", map[common.Feature]int{common.FeatureAsyncReads: 25}, ""},
+		{"missing percentage", "async_reads", nil, "expected feature=percentage"},
+		{"invalid percentage", "async_reads=150", nil, "between 0 and 100"},
+		{"unknown feature", "async_writes=10", nil, "unknown feature async_writes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New()
+			c.FeatureFlags = tt.setting
+			featureFlags, err := c.ParseFeatureFlags()
+			if tt.expectedErr != "" {
+				require.NotNil(t, err)
+				require.Contains(t, err.Error(), tt.expectedErr)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, featureFlags)
+		})
+	}
+}
+
+func TestConfig_ParseClientReadRouting(t *testing.T) {
+	tests := []struct {
+		name        string
+		setting     string
+		expected    []string
+		expectedErr string
+	}{
+		{"not set", "", []string{}, ""},
+		{"routes", " 10.1.0.0/16 = target, 10.1.2.3/32=ORIGIN", []string{"10.1.0.0/16=TARGET", "10.1.2.3/32=ORIGIN"}, ""},
+		{"host bits are masked", "10.1.2.3/16=TARGET", []string{"10.1.0.0/16=TARGET"}, ""},
+		{"missing cluster", "10.1.0.0/16", nil, "expected cidr=cluster"},
+		{"invalid cidr", "10.1.0.0=TARGET", nil, "invalid CIDR address"},
+		{"invalid cluster", "10.1.0.0/16=ASYNC", nil, "possible clusters are"},
+		{"duplicate subnet", "10.1.0.0/16=TARGET,10.1.0.0/16=ORIGIN", nil, "routed more than once"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New()
+			c.ClientReadRouting = tt.setting
+			routes, err := c.ParseClientReadRouting()
+			if tt.expectedErr != "" {
+				require.NotNil(t, err)
+				require.Contains(t, err.Error(), tt.expectedErr)
+				return
+			}
+			require.Nil(t, err)
+			actual := make([]string, 0)
+			for _, route := range routes {
+				actual = append(actual, route.String())
+			}
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestConfig_ParseEventForwarding(t *testing.T) {
+	tests := []struct {
+		name        string
+		setting     string
+		expected    common.EventForwarding
+		expectedErr string
+	}{
+		{"not set", "", common.EventForwarding{
+			primitive.EventTypeSchemaChange:   common.ClusterTypeOrigin,
+			primitive.EventTypeTopologyChange: common.ClusterTypeTarget,
+			primitive.EventTypeStatusChange:   common.ClusterTypeTarget}, ""},
+		{"overrides", " schema_change = target, TOPOLOGY_CHANGE=NONE", common.EventForwarding{
+			primitive.EventTypeSchemaChange: common.ClusterTypeTarget,
+			primitive.EventTypeStatusChange: common.ClusterTypeTarget}, ""},
+		{"missing cluster", "SCHEMA_CHANGE", nil, "expected event=cluster"},
+		{"invalid event", "KEYSPACE_CHANGE=ORIGIN", nil, "possible events are"},
+		{"invalid cluster", "STATUS_CHANGE=ASYNC", nil, "possible clusters are"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New()
+			c.EventForwarding = tt.setting
+			eventForwarding, err := c.ParseEventForwarding()
+			if tt.expectedErr != "" {
+				require.NotNil(t, err)
+				require.Contains(t, err.Error(), tt.expectedErr)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, eventForwarding)
+		})
+	}
+}
+
+func TestConfig_TargetReadShift(t *testing.T) {
+	defer clearAllEnvVars()
+
+	tests := []struct {
+		name        string
+		percentage  string
+		key         string
+		expectedKey common.ReadShiftKey
+		expectedErr string
+	}{
+		{"defaults", "", "", common.ReadShiftKeyStatement, ""},
+		{"partition", "10", "partition", common.ReadShiftKeyPartition, ""},
+		{"all reads", "100", "STATEMENT", common.ReadShiftKeyStatement, ""},
+		{"negative percentage", "-1", "", common.ReadShiftKeyUndefined, "ZDM_TARGET_READ_PERCENTAGE"},
+		{"percentage above 100", "101", "", common.ReadShiftKeyUndefined, "ZDM_TARGET_READ_PERCENTAGE"},
+		{"invalid key", "10", "TOKEN", common.ReadShiftKeyUndefined, "ZDM_TARGET_READ_SHIFT_KEY"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+			if tt.percentage != "" {
+				setEnvVar("ZDM_TARGET_READ_PERCENTAGE", tt.percentage)
+			}
+			if tt.key != "" {
+				setEnvVar("ZDM_TARGET_READ_SHIFT_KEY", tt.key)
+			}
+			conf, err := New().LoadConfig("")
+			if tt.expectedErr != "" {
+				require.NotNil(t, err)
+				require.Contains(t, err.Error(), tt.expectedErr)
+				return
+			}
+			require.Nil(t, err)
+			key, err := conf.ParseTargetReadShiftKey()
+			require.Nil(t, err)
+			require.Equal(t, tt.expectedKey, key)
+		})
+	}
+}
+
+func TestConfig_Timeouts(t *testing.T) {
+	defer clearAllEnvVars()
+
+	tests := []struct {
+		name        string
+		envVar      string
+		value       string
+		expectedErr string
+	}{
+		{"defaults", "", "", ""},
+		{"control connection read timeout", "ZDM_PROXY_CONTROL_CONNECTION_READ_TIMEOUT_MS", "2000", ""},
+		{"zero control connection read timeout", "ZDM_PROXY_CONTROL_CONNECTION_READ_TIMEOUT_MS", "0", "ZDM_PROXY_CONTROL_CONNECTION_READ_TIMEOUT_MS"},
+		{"zero control connection write timeout", "ZDM_PROXY_CONTROL_CONNECTION_WRITE_TIMEOUT_MS", "0", "ZDM_PROXY_CONTROL_CONNECTION_WRITE_TIMEOUT_MS"},
+		{"zero http shutdown timeout", "ZDM_PROXY_HTTP_SHUTDOWN_TIMEOUT_MS", "0", ""},
+		{"negative http shutdown timeout", "ZDM_PROXY_HTTP_SHUTDOWN_TIMEOUT_MS", "-1", "ZDM_PROXY_HTTP_SHUTDOWN_TIMEOUT_MS"},
+		{"zero request timeout", "ZDM_PROXY_REQUEST_TIMEOUT_MS", "0", "ZDM_PROXY_REQUEST_TIMEOUT_MS"},
+		{"zero origin connection timeout", "ZDM_ORIGIN_CONNECTION_TIMEOUT_MS", "0", "ZDM_ORIGIN_CONNECTION_TIMEOUT_MS"},
+		{"negative target connection timeout", "ZDM_TARGET_CONNECTION_TIMEOUT_MS", "-1", "ZDM_TARGET_CONNECTION_TIMEOUT_MS"},
+		{"zero async handshake timeout", "ZDM_ASYNC_HANDSHAKE_TIMEOUT_MS", "0", "ZDM_ASYNC_HANDSHAKE_TIMEOUT_MS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+			if tt.envVar != "" {
+				setEnvVar(tt.envVar, tt.value)
+			}
+			conf, err := New().LoadConfig("")
+			if tt.expectedErr != "" {
+				require.NotNil(t, err)
+				require.Contains(t, err.Error(), tt.expectedErr)
+				return
+			}
+			require.Nil(t, err)
+			if tt.envVar == "ZDM_PROXY_CONTROL_CONNECTION_READ_TIMEOUT_MS" {
+				require.Equal(t, 2000, conf.ProxyControlConnectionReadTimeoutMs)
+			}
+		})
+	}
+}
+
+func TestConfig_ParseTargetReadRampSteps(t *testing.T) {
+	tests := []struct {
+		name        string
+		percentage  int
+		setting     string
+		expected    []int
+		expectedErr string
+	}{
+		{"not set", 0, "", []int{}, ""},
+		{"steps", 0, " 1, 10,50 ,100", []int{1, 10, 50, 100}, ""},
+		{"steps after the initial percentage", 5, "10,100", []int{10, 100}, ""},
+		{"step below the initial percentage", 10, "5,100", nil, "expected increasing percentages"},
+		{"decreasing steps", 0, "10,5", nil, "expected increasing percentages"},
+		{"step above 100", 0, "10,101", nil, "expected increasing percentages"},
+		{"not a number", 0, "10,all", nil, "expected increasing percentages"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New()
+			c.TargetReadPercentage = tt.percentage
+			c.TargetReadRampSteps = tt.setting
+			steps, err := c.ParseTargetReadRampSteps()
+			if tt.expectedErr != "" {
+				require.NotNil(t, err)
+				require.Contains(t, err.Error(), tt.expectedErr)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, steps)
+		})
+	}
+}
+
+func TestConfig_WriteTimestampFloor(t *testing.T) {
+	tests := []struct {
+		name          string
+		floor         string
+		mode          string
+		expectedFloor time.Time
+		expectedMode  common.WriteTimestampFloorMode
+		expectedErr   string
+	}{
+		{"not set", "", "WARN", time.Time{}, common.WriteTimestampFloorModeWarn, ""},
+		{"floor", "2024-05-01T10:00:00Z", "reject",
+			time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), common.WriteTimestampFloorModeReject, ""},
+		{"invalid floor", "2024-05-01", "WARN", time.Time{}, common.WriteTimestampFloorModeWarn, "ZDM_WRITE_TIMESTAMP_FLOOR"},
+		{"invalid mode", "", "DROP", time.Time{}, common.WriteTimestampFloorModeUndefined, "ZDM_WRITE_TIMESTAMP_FLOOR_MODE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New()
+			c.WriteTimestampFloor = tt.floor
+			c.WriteTimestampFloorMode = tt.mode
+			floor, floorErr := c.ParseWriteTimestampFloor()
+			mode, modeErr := c.ParseWriteTimestampFloorMode()
+			if tt.expectedErr != "" {
+				err := floorErr
+				if err == nil {
+					err = modeErr
+				}
+				require.NotNil(t, err)
+				require.Contains(t, err.Error(), tt.expectedErr)
+				return
+			}
+			require.Nil(t, floorErr)
+			require.Nil(t, modeErr)
+			require.True(t, tt.expectedFloor.Equal(floor))
+			require.Equal(t, tt.expectedMode, mode)
+		})
+	}
+}
+
+func TestConfig_ParseSecondaryWriteRetryErrorCodes(t *testing.T) {
+	tests := []struct {
+		name        string
+		setting     string
+		expected    []primitive.ErrorCode
+		expectedErr string
+	}{
+		{"not set", "", nil, ""},
+		{"error codes", "overloaded, WRITE_TIMEOUT", []primitive.ErrorCode{primitive.ErrorCodeOverloaded, primitive.ErrorCodeWriteTimeout}, ""},
+		{"not retryable", "OVERLOADED,INVALID", nil, "ZDM_SECONDARY_WRITE_RETRY_ERROR_CODES"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New()
+			c.SecondaryWriteRetryErrorCodes = tt.setting
+			errorCodes, err := c.ParseSecondaryWriteRetryErrorCodes()
+			if tt.expectedErr != "" {
+				require.NotNil(t, err)
+				require.Contains(t, err.Error(), tt.expectedErr)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, errorCodes)
+		})
+	}
+}
+
+func TestConfig_Effective(t *testing.T) {
+	conf, err := ParseYaml(strings.NewReader(`
+origin_username: foo1
+origin_password: bar1
+target_username: foo2
+target_contact_points: 192.168.100.102
+`))
+	require.Nil(t, err)
+
+	settings := conf.Effective()
+	require.Equal(t, "foo1", settings["origin_username"])
+	require.Equal(t, RedactedValue, settings["origin_password"])
+	require.Equal(t, "", settings["target_password"])
+	require.Equal(t, "192.168.100.102", settings["target_contact_points"])
+	require.Equal(t, 10000, settings["proxy_request_timeout_ms"])
+	require.Equal(t, true, settings["metrics_enabled"])
+	require.Equal(t, len(settings), reflect.TypeOf(*conf).NumField())
+
+	dump := conf.EffectiveJson()
+	require.NotContains(t, dump, "bar1")
+	require.Less(t, strings.Index(dump, `"origin_password"`), strings.Index(dump, `"origin_username"`))
+}
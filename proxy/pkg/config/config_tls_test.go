@@ -15,6 +15,7 @@ type tlsTest struct {
 	clientCertPath     string
 	clientKeyPath      string
 	scbPath            string
+	hostnameValidation bool
 	errExpected        bool
 	errMsg             string
 }
@@ -57,6 +58,26 @@ func TestOriginConfig_ClusterTlsConfig(t *testing.T) {
 			errExpected:    false,
 			errMsg:         "",
 		},
+		{name: "Custom TLS config with hostname validation",
+			needsContactPoints: true,
+			envVars: []envVar{
+				{"ZDM_ORIGIN_TLS_SERVER_CA_PATH", "/path/to/origin/server/ca"},
+				{"ZDM_ORIGIN_TLS_HOSTNAME_VALIDATION", "true"},
+			},
+			tlsEnabled:         true,
+			serverCaPath:       "/path/to/origin/server/ca",
+			hostnameValidation: true,
+			errExpected:        false,
+			errMsg:             "",
+		},
+		{name: "Hostname validation without TLS",
+			needsContactPoints: true,
+			envVars: []envVar{
+				{"ZDM_ORIGIN_TLS_HOSTNAME_VALIDATION", "true"},
+			},
+			errExpected: true,
+			errMsg:      "invalid TLS configuration for Origin: hostname validation is enabled but TLS is not configured, please specify the Server CA path",
+		},
 		{name: "Custom TLS config only for mutual TLS",
 			needsContactPoints: true,
 			envVars: []envVar{
@@ -212,6 +233,7 @@ func TestOriginConfig_ClusterTlsConfig(t *testing.T) {
 				require.Equal(t, tt.clientCertPath, tlsConf.ClientCertPath)
 				require.Equal(t, tt.clientKeyPath, tlsConf.ClientKeyPath)
 				require.Equal(t, tt.scbPath, tlsConf.SecureConnectBundlePath)
+				require.Equal(t, tt.hostnameValidation, tlsConf.HostnameValidation)
 			}
 		})
 	}
@@ -255,6 +277,26 @@ func TestTargetConfig_ClusterTlsConfig(t *testing.T) {
 			errExpected:    false,
 			errMsg:         "",
 		},
+		{name: "Custom TLS config with hostname validation",
+			needsContactPoints: true,
+			envVars: []envVar{
+				{"ZDM_TARGET_TLS_SERVER_CA_PATH", "/path/to/target/server/ca"},
+				{"ZDM_TARGET_TLS_HOSTNAME_VALIDATION", "true"},
+			},
+			tlsEnabled:         true,
+			serverCaPath:       "/path/to/target/server/ca",
+			hostnameValidation: true,
+			errExpected:        false,
+			errMsg:             "",
+		},
+		{name: "Hostname validation without TLS",
+			needsContactPoints: true,
+			envVars: []envVar{
+				{"ZDM_TARGET_TLS_HOSTNAME_VALIDATION", "true"},
+			},
+			errExpected: true,
+			errMsg:      "invalid TLS configuration for Target: hostname validation is enabled but TLS is not configured, please specify the Server CA path",
+		},
 		{name: "Custom TLS config only for mutual TLS",
 			needsContactPoints: true,
 			envVars: []envVar{
@@ -411,6 +453,7 @@ func TestTargetConfig_ClusterTlsConfig(t *testing.T) {
 				require.Equal(t, tt.clientCertPath, tlsConf.ClientCertPath)
 				require.Equal(t, tt.clientKeyPath, tlsConf.ClientKeyPath)
 				require.Equal(t, tt.scbPath, tlsConf.SecureConnectBundlePath)
+				require.Equal(t, tt.hostnameValidation, tlsConf.HostnameValidation)
 			}
 		})
 	}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// SettingChange is a setting whose value differs between two configurations. Values of secrets (settings that are
+// not serialized to JSON like passwords and tokens) are not included.
+type SettingChange struct {
+	Setting   string
+	Running   string
+	Candidate string
+	Secret    bool
+}
+
+// Diff returns the settings whose value in the candidate configuration is different than in the running one, in the
+// order in which they are declared. Settings are identified by their name in the YAML configuration file.
+func Diff(running *Config, candidate *Config) []SettingChange {
+	changes := make([]SettingChange, 0)
+	runningValue := reflect.ValueOf(running).Elem()
+	candidateValue := reflect.ValueOf(candidate).Elem()
+	configType := runningValue.Type()
+	for i := 0; i < configType.NumField(); i++ {
+		field := configType.Field(i)
+		runningField := runningValue.Field(i).Interface()
+		candidateField := candidateValue.Field(i).Interface()
+		if reflect.DeepEqual(runningField, candidateField) {
+			continue
+		}
+
+		change := SettingChange{
+			Setting: settingName(field),
+			Secret:  isSecret(field),
+		}
+		if !change.Secret {
+			change.Running = fmt.Sprint(runningField)
+			change.Candidate = fmt.Sprint(candidateField)
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// RedactedValue replaces the value of the secrets that are set in the effective configuration.
+const RedactedValue = "<redacted>"
+
+// Effective returns the value of every setting (defaults included) identified by its name in the YAML configuration
+// file, secrets that are set are replaced by RedactedValue. Maps are encoded to JSON with sorted keys so dumps of
+// different proxy instances can be compared with a plain diff.
+func (c *Config) Effective() map[string]interface{} {
+	settings := make(map[string]interface{})
+	configValue := reflect.ValueOf(c).Elem()
+	configType := configValue.Type()
+	for i := 0; i < configType.NumField(); i++ {
+		field := configType.Field(i)
+		value := configValue.Field(i).Interface()
+		if isSecret(field) && !configValue.Field(i).IsZero() {
+			value = RedactedValue
+		}
+		settings[settingName(field)] = value
+	}
+	return settings
+}
+
+// EffectiveJson returns the effective configuration encoded to JSON, see Effective.
+func (c *Config) EffectiveJson() string {
+	serializedConfig, _ := json.Marshal(c.Effective())
+	return string(serializedConfig)
+}
+
+func settingName(field reflect.StructField) string {
+	return strings.Split(field.Tag.Get("yaml"), ",")[0]
+}
+
+// secrets are the settings that are not serialized to JSON like passwords and tokens
+func isSecret(field reflect.StructField) bool {
+	return field.Tag.Get("json") == "-"
+}
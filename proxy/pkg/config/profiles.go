@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	ProfileOssToAstra      = "oss-to-astra"
+	ProfileDseToOss        = "dse-to-oss"
+	ProfileOssToOssMultiDc = "oss-to-oss-multidc"
+)
+
+// profile is a set of settings for a common migration topology. The settings of a profile replace the defaults but
+// not the settings that are set explicitly in the configuration file or with environment variables.
+type profile struct {
+	// settings by name in the YAML configuration file, in addition to baseProfileSettings
+	settings map[string]interface{}
+	// validate checks the settings that the profile requires the user to provide, it can be nil
+	validate func(c *Config) error
+}
+
+// baseProfileSettings are set by every profile: none of the topologies has DSE on both sides so the clients must not
+// negotiate a DSE protocol version, and a single client connection can't hold more than 64 MiB in the proxy.
+var baseProfileSettings = map[string]interface{}{
+	"control_conn_max_protocol_version":          "4",
+	"proxy_client_connection_max_buffered_bytes": 64 * 1024 * 1024,
+}
+
+var profiles = map[string]*profile{
+	ProfileOssToAstra: {
+		// Astra throttles requests with OVERLOADED errors that the origin cluster doesn't return for the same writes
+		settings: map[string]interface{}{
+			"secondary_write_retry_attempts": 3,
+		},
+		validate: func(c *Config) error {
+			if isNotDefined(c.TargetSecureConnectBundlePath) {
+				return fmt.Errorf("profile %v requires ZDM_TARGET_SECURE_CONNECT_BUNDLE_PATH", ProfileOssToAstra)
+			}
+			return nil
+		},
+	},
+	ProfileDseToOss: {
+		// the applications keep the credentials of their DSE cluster, the proxy uses target_username for the OSS cluster
+		settings: map[string]interface{}{
+			"forward_client_credentials_to_origin": true,
+		},
+	},
+	ProfileOssToOssMultiDc: {
+		// the connections are limited to the nodes of the local datacenters so a failing node is quarantined instead
+		// of failing the requests of the connections that are assigned to it
+		settings: map[string]interface{}{
+			"node_health_quarantine_threshold": 10,
+		},
+		validate: func(c *Config) error {
+			// without a local datacenter the proxy could connect to nodes of a remote datacenter
+			if isNotDefined(c.OriginLocalDatacenter) {
+				return fmt.Errorf("profile %v requires ZDM_ORIGIN_LOCAL_DATACENTER", ProfileOssToOssMultiDc)
+			}
+			if isNotDefined(c.TargetLocalDatacenter) {
+				return fmt.Errorf("profile %v requires ZDM_TARGET_LOCAL_DATACENTER", ProfileOssToOssMultiDc)
+			}
+			return nil
+		},
+	},
+}
+
+func (c *Config) parseProfile() (*profile, error) {
+	if isNotDefined(c.Profile) {
+		return nil, nil
+	}
+	p, ok := profiles[strings.ToLower(c.Profile)]
+	if !ok {
+		names := make([]string, 0, len(profiles))
+		for name := range profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("invalid value for ZDM_PROFILE (%v); possible values are: %v",
+			c.Profile, strings.Join(names, ", "))
+	}
+	return p, nil
+}
+
+// applyProfile sets the settings of the selected profile that are not set explicitly, isSet returns whether a setting
+// (identified by its name in the YAML configuration file) was set explicitly.
+func (c *Config) applyProfile(isSet func(setting string) bool) error {
+	p, err := c.parseProfile()
+	if err != nil || p == nil {
+		return err
+	}
+	settings := make(map[string]interface{})
+	for _, profileSettings := range []map[string]interface{}{baseProfileSettings, p.settings} {
+		for setting, value := range profileSettings {
+			if !isSet(setting) {
+				settings[setting] = value
+			}
+		}
+	}
+	if len(settings) == 0 {
+		return nil
+	}
+	document, err := yaml.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("could not apply profile %v: %w", c.Profile, err)
+	}
+	if err = yaml.Unmarshal(document, c); err != nil {
+		return fmt.Errorf("could not apply profile %v: %w", c.Profile, err)
+	}
+	return nil
+}
+
+func isSetInEnvVars(setting string) bool {
+	_, ok := os.LookupEnv("ZDM_" + strings.ToUpper(setting))
+	return ok
+}
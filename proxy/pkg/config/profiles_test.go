@@ -0,0 +1,140 @@
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ProfilesFromEnvVars(t *testing.T) {
+	defer clearAllEnvVars()
+
+	tests := []struct {
+		name                     string
+		envVars                  map[string]string
+		expectedProtocolVersion  string
+		expectedMaxBufferedBytes int
+		expectedErr              string
+	}{
+		{"no profile", map[string]string{}, "DseV2", 0, ""},
+		{"profile", map[string]string{"ZDM_PROFILE": "dse-to-oss"}, "4", 64 * 1024 * 1024, ""},
+		{"profile names are case insensitive", map[string]string{"ZDM_PROFILE": "DSE-TO-OSS"}, "4", 64 * 1024 * 1024, ""},
+		{"explicit settings win", map[string]string{
+			"ZDM_PROFILE":                                    "dse-to-oss",
+			"ZDM_CONTROL_CONN_MAX_PROTOCOL_VERSION":          "3",
+			"ZDM_PROXY_CLIENT_CONNECTION_MAX_BUFFERED_BYTES": "0"}, "3", 0, ""},
+		{"unknown profile", map[string]string{"ZDM_PROFILE": "oss-to-dse"}, "", 0, "invalid value for ZDM_PROFILE"},
+		{"multi dc without local datacenters", map[string]string{"ZDM_PROFILE": "oss-to-oss-multidc"}, "", 0,
+			"profile oss-to-oss-multidc requires ZDM_ORIGIN_LOCAL_DATACENTER"},
+		{"multi dc", map[string]string{
+			"ZDM_PROFILE":                 "oss-to-oss-multidc",
+			"ZDM_ORIGIN_LOCAL_DATACENTER": "dc1",
+			"ZDM_TARGET_LOCAL_DATACENTER": "dc2"}, "4", 64 * 1024 * 1024, ""},
+		{"astra without bundle", map[string]string{"ZDM_PROFILE": "oss-to-astra"}, "", 0,
+			"profile oss-to-astra requires ZDM_TARGET_SECURE_CONNECT_BUNDLE_PATH"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+			for name, value := range tt.envVars {
+				setEnvVar(name, value)
+			}
+			conf, err := New().LoadConfig("")
+			if tt.expectedErr != "" {
+				require.NotNil(t, err)
+				require.Contains(t, err.Error(), tt.expectedErr)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tt.expectedProtocolVersion, conf.ControlConnMaxProtocolVersion)
+			require.Equal(t, tt.expectedMaxBufferedBytes, conf.ProxyClientConnectionMaxBufferedBytes)
+		})
+	}
+}
+
+func TestConfig_ProfileFromFile(t *testing.T) {
+	defer clearAllEnvVars()
+	clearAllEnvVars()
+
+	f, err := createConfigFile(`
+profile: oss-to-oss-multidc
+control_conn_max_protocol_version: 3
+
+origin_username: foo1
+origin_password: bar1
+target_username: foo2
+target_password: bar2
+
+origin_contact_points: 192.168.100.101
+origin_local_datacenter: dc1
+target_contact_points: 192.168.100.102
+target_local_datacenter: dc2
+`)
+	defer removeConfigFile(f)
+	require.Nil(t, err)
+
+	c, err := New().LoadConfig(f.Name())
+	require.Nil(t, err)
+	require.Equal(t, "3", c.ControlConnMaxProtocolVersion)
+	require.Equal(t, 64*1024*1024, c.ProxyClientConnectionMaxBufferedBytes)
+	require.Equal(t, 4000, c.AsyncHandshakeTimeoutMs)
+}
+
+func TestConfig_ProfilesHaveDistinctSettings(t *testing.T) {
+	defer clearAllEnvVars()
+
+	tests := []struct {
+		profile string
+		envVars map[string]string
+		check   func(t *testing.T, c *Config)
+	}{
+		{ProfileOssToAstra, map[string]string{
+			"ZDM_TARGET_CONTACT_POINTS":             "",
+			"ZDM_TARGET_SECURE_CONNECT_BUNDLE_PATH": "/path/to/bundle"},
+			func(t *testing.T, c *Config) { require.Equal(t, 3, c.SecondaryWriteRetryAttempts) }},
+		{ProfileDseToOss, map[string]string{},
+			func(t *testing.T, c *Config) { require.True(t, c.ForwardClientCredentialsToOrigin) }},
+		{ProfileOssToOssMultiDc, map[string]string{
+			"ZDM_ORIGIN_LOCAL_DATACENTER": "dc1",
+			"ZDM_TARGET_LOCAL_DATACENTER": "dc2"},
+			func(t *testing.T, c *Config) { require.Equal(t, float64(10), c.NodeHealthQuarantineThreshold) }},
+	}
+	loadConfig := func(t *testing.T, profile string, envVars map[string]string) *Config {
+		clearAllEnvVars()
+		setOriginCredentialsEnvVars()
+		setTargetCredentialsEnvVars()
+		setOriginContactPointsAndPortEnvVars()
+		setTargetContactPointsAndPortEnvVars()
+		setEnvVar("ZDM_PROFILE", profile)
+		for name, value := range envVars {
+			setEnvVar(name, value)
+		}
+		conf, err := New().LoadConfig("")
+		require.Nil(t, err)
+		return conf
+	}
+
+	// the effective configurations are compared without the settings that the test sets for each profile
+	effectiveSettings := map[string]*Config{}
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			conf := loadConfig(t, tt.profile, tt.envVars)
+			tt.check(t, conf)
+			effective := *conf
+			effective.Profile = ""
+			effective.TargetContactPoints, effective.TargetSecureConnectBundlePath = "", ""
+			effective.OriginLocalDatacenter, effective.TargetLocalDatacenter = "", ""
+			effectiveSettings[tt.profile] = &effective
+		})
+	}
+	for first, firstSettings := range effectiveSettings {
+		for second, secondSettings := range effectiveSettings {
+			if first != second {
+				require.NotEqual(t, firstSettings, secondSettings, "%v and %v", first, second)
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package crash
+
+import (
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/buildinfo"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// ExitCode is the exit code of the process after a panic. It is the code that the Go runtime uses for panics that are
+// not recovered so every panic ends the process with the same code, with or without a crash report.
+const ExitCode = 2
+
+var (
+	reportFile     = filepath.Join(os.TempDir(), "zdm-proxy-crash-report.txt")
+	reportFileLock = &sync.Mutex{}
+)
+
+// SetReportFile changes the file where the crash report is written, it is written to the temporary directory until
+// the configuration is loaded.
+func SetReportFile(path string) {
+	reportFileLock.Lock()
+	defer reportFileLock.Unlock()
+	reportFile = path
+}
+
+// HandlePanic has to be deferred at the top of a goroutine: if the goroutine panics, a crash report with the panic,
+// the build information and the stack of every goroutine is written and the process exits with ExitCode.
+func HandlePanic() {
+	if r := recover(); r != nil {
+		path, err := WriteReport(r)
+		if err != nil {
+			log.Errorf("Proxy crashed: %v. Could not write crash report: %v.", r, err)
+		} else {
+			log.Errorf("Proxy crashed: %v. Crash report written to %v.", r, path)
+		}
+		os.Exit(ExitCode)
+	}
+}
+
+// WriteReport writes the crash report of the provided panic value and returns the path of the report file.
+func WriteReport(panicValue interface{}) (string, error) {
+	reportFileLock.Lock()
+	defer reportFileLock.Unlock()
+
+	stack := make([]byte, 1<<20)
+	stack = stack[:runtime.Stack(stack, true)]
+	report := fmt.Sprintf("ZDM proxy crashed at %v\nVersion: %v\nPanic: %v\n\n%s",
+		time.Now().UTC().Format(time.RFC3339), buildinfo.Get(), panicValue, stack)
+	return reportFile, os.WriteFile(reportFile, []byte(report), 0644)
+}
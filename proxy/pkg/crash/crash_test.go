@@ -0,0 +1,23 @@
+package crash
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crash.txt")
+	SetReportFile(path)
+	defer SetReportFile(filepath.Join(os.TempDir(), "zdm-proxy-crash-report.txt"))
+
+	reportPath, err := WriteReport("index out of range")
+	require.Nil(t, err)
+	require.Equal(t, path, reportPath)
+	report, err := os.ReadFile(path)
+	require.Nil(t, err)
+	require.Contains(t, string(report), "Panic: index out of range")
+	require.Contains(t, string(report), "Version: ")
+	require.Contains(t, string(report), "TestWriteReport")
+}
@@ -0,0 +1,89 @@
+package loadgen
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	histogramMinLatency = time.Microsecond
+	histogramMaxLatency = time.Minute
+	histogramGrowth     = 1.02 // bucket upper bounds grow by 2% so percentiles are accurate within 2%
+)
+
+var histogramNumBuckets = int(math.Ceil(
+	math.Log(float64(histogramMaxLatency/histogramMinLatency))/math.Log(histogramGrowth))) + 1
+
+// latencyHistogram records latencies in exponential buckets, it can be updated concurrently without locks.
+type latencyHistogram struct {
+	buckets []uint64 // atomic
+}
+
+func newLatencyHistogram() *latencyHistogram {
+	return &latencyHistogram{buckets: make([]uint64, histogramNumBuckets)}
+}
+
+func (recv *latencyHistogram) record(latency time.Duration) {
+	atomic.AddUint64(&recv.buckets[latencyBucket(latency)], 1)
+}
+
+// snapshot returns a copy of the histogram and resets it.
+func (recv *latencyHistogram) snapshot() *latencyHistogram {
+	snapshot := newLatencyHistogram()
+	for i := range recv.buckets {
+		snapshot.buckets[i] = atomic.SwapUint64(&recv.buckets[i], 0)
+	}
+	return snapshot
+}
+
+// merge adds the latencies of another histogram, it must not be called concurrently with record.
+func (recv *latencyHistogram) merge(other *latencyHistogram) {
+	for i := range recv.buckets {
+		recv.buckets[i] += other.buckets[i]
+	}
+}
+
+func (recv *latencyHistogram) count() uint64 {
+	total := uint64(0)
+	for i := range recv.buckets {
+		total += atomic.LoadUint64(&recv.buckets[i])
+	}
+	return total
+}
+
+// percentile returns the upper bound of the bucket that contains the provided percentile (between 0 and 100) or 0 if
+// no latency was recorded.
+func (recv *latencyHistogram) percentile(percentile float64) time.Duration {
+	total := recv.count()
+	if total == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(percentile / 100 * float64(total)))
+	if rank == 0 {
+		rank = 1
+	}
+	seen := uint64(0)
+	for i := range recv.buckets {
+		seen += atomic.LoadUint64(&recv.buckets[i])
+		if seen >= rank {
+			return bucketUpperBound(i)
+		}
+	}
+	return histogramMaxLatency
+}
+
+func latencyBucket(latency time.Duration) int {
+	if latency <= histogramMinLatency {
+		return 0
+	}
+	bucket := int(math.Ceil(math.Log(float64(latency)/float64(histogramMinLatency)) / math.Log(histogramGrowth)))
+	if bucket >= histogramNumBuckets {
+		return histogramNumBuckets - 1
+	}
+	return bucket
+}
+
+func bucketUpperBound(bucket int) time.Duration {
+	return time.Duration(float64(histogramMinLatency) * math.Pow(histogramGrowth, float64(bucket)))
+}
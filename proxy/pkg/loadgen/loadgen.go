@@ -0,0 +1,313 @@
+package loadgen
+
+import (
+	"context"
+	"fmt"
+	"github.com/gocql/gocql"
+	"io"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DistributionUniform = "uniform"
+	DistributionZipf    = "zipf"
+)
+
+type Options struct {
+	Hosts             []string
+	Port              int
+	Username          string
+	Password          string
+	Keyspace          string
+	Table             string
+	CreateSchema      bool
+	ReplicationFactor int
+	Consistency       string
+
+	Concurrency      int
+	Duration         time.Duration
+	Rate             int     // maximum number of operations per second, 0 means no limit
+	ReadRatio        float64 // fraction (between 0 and 1) of the operations that are reads
+	Partitions       int64
+	RowsPerPartition int
+	Distribution     string // uniform or zipf (a few hot partitions receive most of the operations)
+	PayloadMinBytes  int
+	PayloadMaxBytes  int
+	ReportInterval   time.Duration
+}
+
+func (recv *Options) validate() error {
+	if len(recv.Hosts) == 0 {
+		return fmt.Errorf("at least one host is required")
+	}
+	if recv.Concurrency <= 0 {
+		return fmt.Errorf("concurrency must be positive")
+	}
+	if recv.Rate < 0 {
+		return fmt.Errorf("rate must not be negative")
+	}
+	if recv.ReadRatio < 0 || recv.ReadRatio > 1 {
+		return fmt.Errorf("read ratio must be between 0 and 1")
+	}
+	if recv.Partitions <= 0 || recv.RowsPerPartition <= 0 {
+		return fmt.Errorf("partitions and rows per partition must be positive")
+	}
+	if recv.Distribution != DistributionUniform && recv.Distribution != DistributionZipf {
+		return fmt.Errorf("unknown distribution %v, possible values are %v and %v",
+			recv.Distribution, DistributionUniform, DistributionZipf)
+	}
+	if recv.PayloadMinBytes < 0 || recv.PayloadMaxBytes < recv.PayloadMinBytes {
+		return fmt.Errorf("invalid payload size range %d-%d", recv.PayloadMinBytes, recv.PayloadMaxBytes)
+	}
+	if recv.ReportInterval <= 0 {
+		return fmt.Errorf("report interval must be positive")
+	}
+	_, err := gocql.ParseConsistencyWrapper(recv.Consistency)
+	return err
+}
+
+// operationStats contains the client observed latencies and errors of one type of operation.
+type operationStats struct {
+	name      string
+	latencies *latencyHistogram
+	errors    uint64 // atomic
+	lastError *atomic.Value
+}
+
+func newOperationStats(name string) *operationStats {
+	return &operationStats{name: name, latencies: newLatencyHistogram(), lastError: &atomic.Value{}}
+}
+
+func (recv *operationStats) record(start time.Time, err error) {
+	if err != nil {
+		atomic.AddUint64(&recv.errors, 1)
+		recv.lastError.Store(err.Error())
+		return
+	}
+	recv.latencies.record(time.Since(start))
+}
+
+// Run connects to the provided hosts (usually a proxy instance) and sends a mix of reads and writes until the duration
+// elapses or the context is canceled. The client observed latencies are written to out every report interval and a
+// summary is written at the end.
+func Run(ctx context.Context, out io.Writer, options Options) error {
+	if err := options.validate(); err != nil {
+		return err
+	}
+	session, err := connect(options)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	if options.CreateSchema {
+		if err = createSchema(session, options); err != nil {
+			return err
+		}
+	}
+
+	if options.Duration > 0 {
+		var cancelFn context.CancelFunc
+		ctx, cancelFn = context.WithTimeout(ctx, options.Duration)
+		defer cancelFn()
+	}
+
+	reads := newOperationStats("reads")
+	writes := newOperationStats("writes")
+	var permits <-chan time.Time
+	if options.Rate > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(options.Rate))
+		defer ticker.Stop()
+		permits = ticker.C
+	}
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < options.Concurrency; i++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			newWorker(session, options, seed).run(ctx, permits, reads, writes)
+		}(time.Now().UnixNano() + int64(i))
+	}
+
+	start := time.Now()
+	totalReads := newLatencyHistogram()
+	totalWrites := newLatencyHistogram()
+	report := func(now time.Time, previous time.Time) {
+		readsSnapshot := reads.latencies.snapshot()
+		writesSnapshot := writes.latencies.snapshot()
+		totalReads.merge(readsSnapshot)
+		totalWrites.merge(writesSnapshot)
+		if readsSnapshot.count()+writesSnapshot.count() == 0 && now.Sub(previous) < options.ReportInterval {
+			return // nothing was received since the last report because the workload ended
+		}
+		writeReportLine(out, now.Sub(start), now.Sub(previous), readsSnapshot, writesSnapshot,
+			atomic.LoadUint64(&reads.errors)+atomic.LoadUint64(&writes.errors))
+	}
+
+	_, _ = fmt.Fprintf(out, "%-8s %9s %9s %9s %9s %9s %9s %9s %9s\n",
+		"elapsed", "ops/s", "reads/s", "r-p50", "r-p99", "writes/s", "w-p50", "w-p99", "errors")
+	ticker := time.NewTicker(options.ReportInterval)
+	previous := start
+	for done := false; !done; {
+		select {
+		case <-ctx.Done():
+			done = true
+		case now := <-ticker.C:
+			report(now, previous)
+			previous = now
+		}
+	}
+	ticker.Stop()
+	wg.Wait()
+	report(time.Now(), previous)
+
+	elapsed := time.Since(start)
+	_, _ = fmt.Fprintf(out, "\nSummary after %v:\n", elapsed.Round(time.Second))
+	writeSummary(out, reads, totalReads, elapsed)
+	writeSummary(out, writes, totalWrites, elapsed)
+	return nil
+}
+
+func connect(options Options) (*gocql.Session, error) {
+	cluster := gocql.NewCluster(options.Hosts...)
+	cluster.Port = options.Port
+	cluster.ProtoVersion = 4
+	cluster.Timeout = 10 * time.Second
+	cluster.ConnectTimeout = 10 * time.Second
+	cluster.Consistency, _ = gocql.ParseConsistencyWrapper(options.Consistency)
+	if options.Username != "" {
+		cluster.Authenticator = gocql.PasswordAuthenticator{Username: options.Username, Password: options.Password}
+	}
+	session, err := cluster.CreateSession()
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to %v: %w", strings.Join(options.Hosts, ","), err)
+	}
+	return session, nil
+}
+
+func createSchema(session *gocql.Session, options Options) error {
+	statements := []string{
+		fmt.Sprintf("CREATE KEYSPACE IF NOT EXISTS %v WITH replication = {'class': 'SimpleStrategy', 'replication_factor': %d}",
+			options.Keyspace, options.ReplicationFactor),
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %v.%v (pk bigint, ck int, payload blob, PRIMARY KEY (pk, ck))",
+			options.Keyspace, options.Table),
+	}
+	for _, statement := range statements {
+		if err := session.Query(statement).Exec(); err != nil {
+			return fmt.Errorf("could not create schema (%v): %w", statement, err)
+		}
+	}
+	return nil
+}
+
+type worker struct {
+	session     *gocql.Session
+	options     Options
+	rand        *rand.Rand
+	zipf        *rand.Zipf
+	payload     []byte
+	selectQuery string
+	insertQuery string
+}
+
+func newWorker(session *gocql.Session, options Options, seed int64) *worker {
+	w := &worker{
+		session:     session,
+		options:     options,
+		rand:        rand.New(rand.NewSource(seed)),
+		payload:     make([]byte, options.PayloadMaxBytes),
+		selectQuery: fmt.Sprintf("SELECT payload FROM %v.%v WHERE pk = ? AND ck = ?", options.Keyspace, options.Table),
+		insertQuery: fmt.Sprintf("INSERT INTO %v.%v (pk, ck, payload) VALUES (?, ?, ?)", options.Keyspace, options.Table),
+	}
+	if options.Distribution == DistributionZipf {
+		w.zipf = rand.NewZipf(w.rand, 1.1, 1, uint64(options.Partitions-1))
+	}
+	w.rand.Read(w.payload)
+	return w
+}
+
+func (recv *worker) run(ctx context.Context, permits <-chan time.Time, reads *operationStats, writes *operationStats) {
+	for {
+		if permits != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-permits:
+			}
+		} else if ctx.Err() != nil {
+			return
+		}
+
+		pk, ck := recv.nextKey()
+		start := time.Now()
+		if recv.rand.Float64() < recv.options.ReadRatio {
+			var payload []byte
+			err := recv.session.Query(recv.selectQuery, pk, ck).WithContext(ctx).Scan(&payload)
+			if err == gocql.ErrNotFound {
+				err = nil
+			}
+			if ctx.Err() == nil {
+				reads.record(start, err)
+			}
+		} else {
+			err := recv.session.Query(recv.insertQuery, pk, ck, recv.nextPayload()).WithContext(ctx).Exec()
+			if ctx.Err() == nil {
+				writes.record(start, err)
+			}
+		}
+	}
+}
+
+func (recv *worker) nextKey() (int64, int) {
+	var pk int64
+	if recv.zipf != nil {
+		pk = int64(recv.zipf.Uint64())
+	} else {
+		pk = recv.rand.Int63n(recv.options.Partitions)
+	}
+	return pk, recv.rand.Intn(recv.options.RowsPerPartition)
+}
+
+func (recv *worker) nextPayload() []byte {
+	size := recv.options.PayloadMinBytes
+	if recv.options.PayloadMaxBytes > size {
+		size += recv.rand.Intn(recv.options.PayloadMaxBytes - size + 1)
+	}
+	return recv.payload[:size]
+}
+
+func writeReportLine(
+	out io.Writer, elapsed time.Duration, interval time.Duration, reads *latencyHistogram, writes *latencyHistogram,
+	errors uint64) {
+	seconds := interval.Seconds()
+	readCount := float64(reads.count())
+	writeCount := float64(writes.count())
+	_, _ = fmt.Fprintf(out, "%-8v %9.0f %9.0f %9v %9v %9.0f %9v %9v %9d\n",
+		elapsed.Round(100*time.Millisecond), (readCount+writeCount)/seconds,
+		readCount/seconds, formatLatency(reads.percentile(50)), formatLatency(reads.percentile(99)),
+		writeCount/seconds, formatLatency(writes.percentile(50)), formatLatency(writes.percentile(99)),
+		errors)
+}
+
+func writeSummary(out io.Writer, stats *operationStats, latencies *latencyHistogram, elapsed time.Duration) {
+	_, _ = fmt.Fprintf(out, "  %-6v count=%d rate=%.0f/s errors=%d p50=%v p95=%v p99=%v p99.9=%v max=%v\n",
+		stats.name, latencies.count(), float64(latencies.count())/elapsed.Seconds(), atomic.LoadUint64(&stats.errors),
+		formatLatency(latencies.percentile(50)), formatLatency(latencies.percentile(95)),
+		formatLatency(latencies.percentile(99)), formatLatency(latencies.percentile(99.9)),
+		formatLatency(latencies.percentile(100)))
+	if lastError, ok := stats.lastError.Load().(string); ok {
+		_, _ = fmt.Fprintf(out, "         last error: %v\n", lastError)
+	}
+}
+
+func formatLatency(latency time.Duration) string {
+	if latency >= time.Second {
+		return latency.Round(time.Millisecond).String()
+	}
+	return latency.Round(10 * time.Microsecond).String()
+}
@@ -0,0 +1,87 @@
+package loadgen
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestLatencyHistogram(t *testing.T) {
+	histogram := newLatencyHistogram()
+	require.Equal(t, time.Duration(0), histogram.percentile(50))
+
+	for i := 1; i <= 100; i++ {
+		histogram.record(time.Duration(i) * time.Millisecond)
+	}
+	histogram.record(2 * time.Minute)
+	require.Equal(t, uint64(101), histogram.count())
+	require.InEpsilon(t, float64(51*time.Millisecond), float64(histogram.percentile(50)), 0.02)
+	require.InEpsilon(t, float64(100*time.Millisecond), float64(histogram.percentile(99)), 0.02)
+	require.InEpsilon(t, float64(time.Minute), float64(histogram.percentile(100)), 0.02)
+
+	snapshot := histogram.snapshot()
+	require.Equal(t, uint64(101), snapshot.count())
+	require.Equal(t, uint64(0), histogram.count())
+	histogram.record(time.Nanosecond)
+	snapshot.merge(histogram)
+	require.Equal(t, uint64(102), snapshot.count())
+	require.Equal(t, histogramMinLatency, snapshot.percentile(0))
+}
+
+func TestWorker(t *testing.T) {
+	options := Options{Partitions: 1000, RowsPerPartition: 10, PayloadMinBytes: 10, PayloadMaxBytes: 20}
+
+	options.Distribution = DistributionUniform
+	uniform := newWorker(nil, options, 1)
+	options.Distribution = DistributionZipf
+	zipf := newWorker(nil, options, 1)
+	hotUniform, hotZipf := 0, 0
+	for i := 0; i < 10000; i++ {
+		pk, ck := uniform.nextKey()
+		require.True(t, pk >= 0 && pk < 1000)
+		require.True(t, ck >= 0 && ck < 10)
+		if pk < 10 {
+			hotUniform++
+		}
+		pk, _ = zipf.nextKey()
+		require.True(t, pk >= 0 && pk < 1000)
+		if pk < 10 {
+			hotZipf++
+		}
+
+		size := len(uniform.nextPayload())
+		require.True(t, size >= 10 && size <= 20)
+	}
+	// with zipf a large share of the operations are on a few hot partitions
+	require.Less(t, hotUniform, 200)
+	require.Greater(t, hotZipf, 4000)
+}
+
+func TestOptions_Validate(t *testing.T) {
+	valid := Options{
+		Hosts: []string{"localhost"}, Concurrency: 1, ReadRatio: 0.5, Partitions: 1, RowsPerPartition: 1,
+		Distribution: DistributionUniform, PayloadMaxBytes: 1, ReportInterval: time.Second, Consistency: "ONE",
+	}
+	require.Nil(t, valid.validate())
+
+	tests := []struct {
+		name   string
+		modify func(options *Options)
+	}{
+		{"no hosts", func(options *Options) { options.Hosts = nil }},
+		{"no concurrency", func(options *Options) { options.Concurrency = 0 }},
+		{"negative rate", func(options *Options) { options.Rate = -1 }},
+		{"read ratio above 1", func(options *Options) { options.ReadRatio = 1.5 }},
+		{"no partitions", func(options *Options) { options.Partitions = 0 }},
+		{"unknown distribution", func(options *Options) { options.Distribution = "gaussian" }},
+		{"invalid payload range", func(options *Options) { options.PayloadMinBytes = 2 }},
+		{"invalid consistency", func(options *Options) { options.Consistency = "MOST" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := valid
+			tt.modify(&options)
+			require.NotNil(t, options.validate())
+		})
+	}
+}
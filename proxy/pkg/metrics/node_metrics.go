@@ -261,6 +261,16 @@ var (
 	AsyncUsedStreamIds = NewMetric(
 		"async_used_stream_ids_total",
 		"Number of used stream ids in Async connections")
+
+	OriginSlowWrites = NewMetric(
+		"origin_slow_writes_total",
+		"Running total of writes to Origin connections that were blocked for longer than the cluster write timeout")
+	TargetSlowWrites = NewMetric(
+		"target_slow_writes_total",
+		"Running total of writes to Target connections that were blocked for longer than the cluster write timeout")
+	AsyncSlowWrites = NewMetric(
+		"async_slow_writes_total",
+		"Running total of writes to Async connections that were blocked for longer than the cluster write timeout")
 )
 
 type NodeMetrics struct {
@@ -288,6 +298,8 @@ type NodeMetricsInstance struct {
 	InFlightRequests Gauge
 
 	UsedStreamIds Gauge
+
+	SlowWrites Counter
 }
 
 func CreateCounterNodeMetric(metricFactory MetricFactory, nodeDescription string, mn Metric) (Counter, error) {
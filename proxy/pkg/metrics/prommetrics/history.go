@@ -0,0 +1,252 @@
+package prommetrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metrics that are recorded in the history, node level metrics are not included to keep the snapshots small
+var historyMetrics = []metrics.Metric{
+	metrics.FailedReadsOrigin,
+	metrics.FailedWritesOnOrigin,
+	metrics.ProxyWritesDuration,
+	metrics.InFlightWrites,
+	metrics.OpenClientConnections,
+	metrics.PSCacheMissCount,
+	metrics.LoadUtilization,
+	metrics.LoadCpuUtilization,
+}
+
+// MetricsSnapshot contains the values of the key proxy metrics at a point in time. Series are identified by the metric
+// name (without prefix) and its labels, e.g. proxy_failed_writes_total{failed_on="target"}. The value of a histogram
+// is its number of observations.
+type MetricsSnapshot struct {
+	Timestamp time.Time
+	Values    map[string]float64
+	// Per second rates of the counters and histograms since the previous snapshot, e.g. the rate of
+	// proxy_request_duration_seconds{type="writes"} is the write request rate.
+	Rates map[string]float64
+}
+
+// MetricsHistory periodically snapshots the key proxy metrics into a ring of fixed size so that short-lived anomalies
+// remain inspectable even if Prometheus didn't scrape the proxy while they happened. The ring is optionally persisted
+// to a file after every snapshot so that it survives restarts.
+type MetricsHistory struct {
+	gatherer      prometheus.Gatherer
+	metricsPrefix string
+	interval      time.Duration
+	size          int
+	file          string
+	lock          *sync.RWMutex
+	snapshots     []*MetricsSnapshot // oldest first
+}
+
+func NewMetricsHistory(
+	gatherer prometheus.Gatherer, metricsPrefix string, interval time.Duration, size int, file string) (*MetricsHistory, error) {
+	history := &MetricsHistory{
+		gatherer:      gatherer,
+		metricsPrefix: metricsPrefix,
+		interval:      interval,
+		size:          size,
+		file:          file,
+		lock:          &sync.RWMutex{},
+		snapshots:     make([]*MetricsSnapshot, 0, size),
+	}
+	if file == "" {
+		return history, nil
+	}
+
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return history, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not read metrics history file %v: %w", file, err)
+	}
+	var snapshots []*MetricsSnapshot
+	if err = json.Unmarshal(data, &snapshots); err != nil {
+		log.Warnf("Ignoring the content of metrics history file %v because it could not be parsed: %v", file, err)
+		return history, nil
+	}
+	history.append(snapshots...)
+	log.Infof("Loaded %d metrics snapshot(s) from %v.", len(history.snapshots), file)
+	return history, nil
+}
+
+// Run takes a snapshot every interval until the context is canceled.
+func (recv *MetricsHistory) Run(ctx context.Context) {
+	ticker := time.NewTicker(recv.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := recv.TakeSnapshot(time.Now()); err != nil {
+				log.Warnf("Could not take metrics snapshot: %v", err)
+			}
+		}
+	}
+}
+
+func (recv *MetricsHistory) TakeSnapshot(now time.Time) error {
+	families, err := recv.gatherer.Gather()
+	if err != nil {
+		return fmt.Errorf("could not gather metrics: %w", err)
+	}
+
+	trackedNames := make(map[string]bool)
+	for _, metric := range historyMetrics {
+		trackedNames[recv.prefixedName(metric.GetName())] = true
+	}
+	snapshot := &MetricsSnapshot{
+		Timestamp: now.UTC(),
+		Values:    make(map[string]float64),
+		Rates:     make(map[string]float64),
+	}
+	cumulativeSeries := make(map[string]bool)
+	for _, family := range families {
+		if !trackedNames[family.GetName()] {
+			continue
+		}
+		name := strings.TrimPrefix(family.GetName(), recv.prefixedName(""))
+		for _, metric := range family.GetMetric() {
+			series := seriesName(name, metric.GetLabel())
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				snapshot.Values[series] = metric.GetCounter().GetValue()
+				cumulativeSeries[series] = true
+			case dto.MetricType_HISTOGRAM:
+				snapshot.Values[series] = float64(metric.GetHistogram().GetSampleCount())
+				cumulativeSeries[series] = true
+			case dto.MetricType_GAUGE:
+				snapshot.Values[series] = metric.GetGauge().GetValue()
+			}
+		}
+	}
+
+	recv.lock.Lock()
+	if len(recv.snapshots) > 0 {
+		previous := recv.snapshots[len(recv.snapshots)-1]
+		elapsed := snapshot.Timestamp.Sub(previous.Timestamp).Seconds()
+		for series := range cumulativeSeries {
+			previousValue, ok := previous.Values[series]
+			// counters are reset when the proxy restarts
+			if ok && elapsed > 0 && snapshot.Values[series] >= previousValue {
+				snapshot.Rates[series] = (snapshot.Values[series] - previousValue) / elapsed
+			}
+		}
+	}
+	recv.append(snapshot)
+	var data []byte
+	if recv.file != "" {
+		data, err = json.Marshal(recv.snapshots)
+	}
+	recv.lock.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("could not serialize metrics history: %w", err)
+	}
+	if data != nil {
+		return writeFileAtomically(recv.file, data)
+	}
+	return nil
+}
+
+// GetSnapshots returns the snapshots taken after the provided time, oldest first.
+func (recv *MetricsHistory) GetSnapshots(since time.Time) []*MetricsSnapshot {
+	recv.lock.RLock()
+	defer recv.lock.RUnlock()
+
+	snapshots := make([]*MetricsSnapshot, 0, len(recv.snapshots))
+	for _, snapshot := range recv.snapshots {
+		if snapshot.Timestamp.After(since) {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	return snapshots
+}
+
+type metricsHistoryResponse struct {
+	IntervalMs int64
+	Snapshots  []*MetricsSnapshot
+}
+
+// HttpHandler serves the snapshots, the optional "since" query parameter (RFC 3339) filters out older snapshots.
+func (recv *MetricsHistory) HttpHandler() http.Handler {
+	return http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(rsp, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var since time.Time
+		if sinceParam := req.URL.Query().Get("since"); sinceParam != "" {
+			var err error
+			since, err = time.Parse(time.RFC3339, sinceParam)
+			if err != nil {
+				http.Error(rsp, fmt.Sprintf("invalid since parameter: %v", err), http.StatusBadRequest)
+				return
+			}
+		}
+
+		data, err := json.Marshal(&metricsHistoryResponse{
+			IntervalMs: recv.interval.Milliseconds(),
+			Snapshots:  recv.GetSnapshots(since),
+		})
+		if err != nil {
+			http.Error(rsp, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		rsp.Header().Set("Content-Type", "application/json")
+		_, _ = rsp.Write(data)
+	})
+}
+
+// append must be called with the lock held (or before the history is shared)
+func (recv *MetricsHistory) append(snapshots ...*MetricsSnapshot) {
+	recv.snapshots = append(recv.snapshots, snapshots...)
+	if len(recv.snapshots) > recv.size {
+		recv.snapshots = append(make([]*MetricsSnapshot, 0, recv.size), recv.snapshots[len(recv.snapshots)-recv.size:]...)
+	}
+}
+
+func (recv *MetricsHistory) prefixedName(name string) string {
+	if recv.metricsPrefix == "" {
+		return name
+	}
+	return recv.metricsPrefix + "_" + name
+}
+
+func seriesName(name string, labels []*dto.LabelPair) string {
+	if len(labels) == 0 {
+		return name
+	}
+	pairs := make([]string, 0, len(labels))
+	for _, label := range labels {
+		pairs = append(pairs, fmt.Sprintf("%v=%q", label.GetName(), label.GetValue()))
+	}
+	sort.Strings(pairs)
+	return fmt.Sprintf("%v{%v}", name, strings.Join(pairs, ","))
+}
+
+func writeFileAtomically(file string, data []byte) error {
+	tmpFile := file + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
+		return fmt.Errorf("could not write metrics history file: %w", err)
+	}
+	if err := os.Rename(tmpFile, file); err != nil {
+		return fmt.Errorf("could not write metrics history file: %w", err)
+	}
+	return nil
+}
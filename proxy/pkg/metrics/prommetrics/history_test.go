@@ -0,0 +1,78 @@
+package prommetrics
+
+import (
+	"encoding/json"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMetricsHistory(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	factory := NewPrometheusMetricFactory(registry, "zdm")
+	failedWrites, err := factory.GetOrCreateCounter(metrics.FailedWritesOnTarget)
+	require.Nil(t, err)
+	writes, err := factory.GetOrCreateHistogram(metrics.ProxyWritesDuration, []float64{0.1})
+	require.Nil(t, err)
+	openConnections := 3.0
+	_, err = factory.GetOrCreateGaugeFunc(metrics.OpenClientConnections, func() float64 { return openConnections })
+	require.Nil(t, err)
+	_, err = factory.GetOrCreateCounter(newTestMetric("not_tracked"))
+	require.Nil(t, err)
+
+	file := filepath.Join(t.TempDir(), "history.json")
+	history, err := NewMetricsHistory(registry, "zdm", 10*time.Second, 2, file)
+	require.Nil(t, err)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.Nil(t, history.TakeSnapshot(start))
+	failedWrites.Add(5)
+	for i := 0; i < 20; i++ {
+		writes.Track(time.Now())
+	}
+	openConnections = 4
+	require.Nil(t, history.TakeSnapshot(start.Add(10*time.Second)))
+
+	snapshots := history.GetSnapshots(time.Time{})
+	require.Equal(t, 2, len(snapshots))
+	require.Equal(t, map[string]float64{
+		`proxy_failed_writes_total{failed_on="target"}`: 5,
+		`proxy_request_duration_seconds{type="writes"}`: 20,
+		`client_connections_total`:                      4,
+	}, snapshots[1].Values)
+	require.Equal(t, map[string]float64{
+		`proxy_failed_writes_total{failed_on="target"}`: 0.5,
+		`proxy_request_duration_seconds{type="writes"}`: 2,
+	}, snapshots[1].Rates)
+
+	// the oldest snapshot is discarded
+	require.Nil(t, history.TakeSnapshot(start.Add(20*time.Second)))
+	snapshots = history.GetSnapshots(time.Time{})
+	require.Equal(t, 2, len(snapshots))
+	require.Equal(t, start.Add(10*time.Second), snapshots[0].Timestamp)
+
+	// the history survives restarts
+	reloaded, err := NewMetricsHistory(registry, "zdm", 10*time.Second, 2, file)
+	require.Nil(t, err)
+	require.Equal(t, snapshots, reloaded.GetSnapshots(time.Time{}))
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics/history?since="+start.Add(15*time.Second).Format(time.RFC3339), nil)
+	rsp := httptest.NewRecorder()
+	reloaded.HttpHandler().ServeHTTP(rsp, req)
+	require.Equal(t, http.StatusOK, rsp.Code)
+	var body metricsHistoryResponse
+	require.Nil(t, json.Unmarshal(rsp.Body.Bytes(), &body))
+	require.Equal(t, int64(10000), body.IntervalMs)
+	require.Equal(t, 1, len(body.Snapshots))
+	require.Equal(t, start.Add(20*time.Second), body.Snapshots[0].Timestamp)
+
+	req = httptest.NewRequest(http.MethodGet, "/metrics/history?since=yesterday", nil)
+	rsp = httptest.NewRecorder()
+	reloaded.HttpHandler().ServeHTTP(rsp, req)
+	require.Equal(t, http.StatusBadRequest, rsp.Code)
+}
@@ -1,10 +1,15 @@
 package metrics
 
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/buildinfo"
+)
+
 const (
 	typeReadsOrigin = "reads_origin"
 	typeReadsTarget = "reads_target"
 	TypeWrites      = "writes"
 	TypeReads       = "reads"
+	typeMonitoring  = "monitoring"
 
 	failedRequestsClusterOrigin = "origin"
 	failedRequestsClusterTarget = "target"
@@ -18,6 +23,10 @@ const (
 	failedWritesDescription              = "Running total of failed writes"
 	failedWritesFailedOnClusterTypeLabel = "failed_on"
 
+	singleSidedWritesName         = "proxy_single_sided_writes_total"
+	singleSidedWritesDescription  = "Running total of writes acknowledged to the client that were only written to one cluster"
+	singleSidedWritesClusterLabel = "written_to"
+
 	requestDurationName        = "proxy_request_duration_seconds"
 	RequestDurationTypeLabel   = "type"
 	requestDurationDescription = "Histogram that tracks the latency of requests at proxy entry point"
@@ -63,6 +72,20 @@ var (
 			failedWritesFailedOnClusterTypeLabel: failedRequestsClusterBoth,
 		},
 	)
+	SingleSidedWritesOrigin = NewMetricWithLabels(
+		singleSidedWritesName,
+		singleSidedWritesDescription,
+		map[string]string{
+			singleSidedWritesClusterLabel: failedRequestsClusterOrigin,
+		},
+	)
+	SingleSidedWritesTarget = NewMetricWithLabels(
+		singleSidedWritesName,
+		singleSidedWritesDescription,
+		map[string]string{
+			singleSidedWritesClusterLabel: failedRequestsClusterTarget,
+		},
+	)
 
 	PSCacheSize = NewMetric(
 		"pscache_entries_total",
@@ -94,6 +117,13 @@ var (
 			RequestDurationTypeLabel: TypeWrites,
 		},
 	)
+	ProxyMonitoringDuration = NewMetricWithLabels(
+		requestDurationName,
+		requestDurationDescription,
+		map[string]string{
+			RequestDurationTypeLabel: typeMonitoring,
+		},
+	)
 
 	InFlightReadsOrigin = NewMetricWithLabels(
 		inFlightRequestsName,
@@ -116,11 +146,90 @@ var (
 			inFlightRequestsTypeLabel: TypeWrites,
 		},
 	)
+	InFlightMonitoring = NewMetricWithLabels(
+		inFlightRequestsName,
+		inFlightRequestsDescription,
+		map[string]string{
+			inFlightRequestsTypeLabel: typeMonitoring,
+		},
+	)
 
 	OpenClientConnections = NewMetric(
 		"client_connections_total",
 		"Number of client connections currently open",
 	)
+	ClientSlowWrites = NewMetric(
+		"client_slow_writes_total",
+		"Running total of writes to client connections that were blocked for longer than the client write timeout",
+	)
+	ClientQueuedRequests = NewMetric(
+		"client_queued_requests_total",
+		"Number of requests read from client connections that are waiting to be processed",
+	)
+	ClientOverloadedRequests = NewMetric(
+		"client_overloaded_requests_total",
+		"Running total of client requests rejected with an OVERLOADED error because the proxy was saturated",
+	)
+	StaleTimestampWrites = NewMetric(
+		"proxy_stale_timestamp_writes_total",
+		"Running total of writes with an explicit timestamp older than the write timestamp floor",
+	)
+	SecondaryWriteRetries = NewMetric(
+		"proxy_secondary_write_retries_total",
+		"Running total of retries of writes that failed on the secondary cluster",
+	)
+	ConditionalWrites = NewMetric(
+		"proxy_conditional_writes_total",
+		"Running total of conditional writes (lightweight transactions)",
+	)
+	ConditionalMismatches = NewMetric(
+		"proxy_conditional_write_mismatches_total",
+		"Running total of conditional writes forwarded to both clusters that were applied on only one of them",
+	)
+	CounterWrites = NewMetric(
+		"proxy_counter_writes_total",
+		"Running total of counter updates",
+	)
+	ClientWriteTimeouts = NewMetric(
+		"client_write_timeouts_total",
+		"Running total of client connections closed because the client did not read responses within the client write timeout",
+	)
+
+	LoadUtilization = NewMetric(
+		"proxy_load_utilization_ratio",
+		"Highest of the in flight, request rate and CPU utilizations relative to the capacity targets of this instance",
+	)
+	LoadInFlightUtilization = NewMetric(
+		"proxy_load_inflight_utilization_ratio",
+		"Number of requests in flight relative to the in flight requests capacity target of this instance",
+	)
+	LoadRequestsPerSecond = NewMetric(
+		"proxy_load_requests_per_second",
+		"Rate of requests received by this instance since the previous scrape",
+	)
+	LoadCpuUtilization = NewMetric(
+		"proxy_load_cpu_utilization_ratio",
+		"CPU usage of the proxy process relative to the CPUs available to it since the previous scrape",
+	)
+	LoadQpsHeadroom = NewMetric(
+		"proxy_load_qps_headroom_ratio",
+		"Fraction of the request rate capacity target that is still available, adjusted by CPU utilization",
+	)
+
+	TargetReadPercentage = NewMetric(
+		"proxy_target_read_percentage",
+		"Percentage of the reads that are shifted to the target cluster while the primary cluster is ORIGIN",
+	)
+
+	BuildInfo = NewMetricWithLabels(
+		"proxy_build_info",
+		"Build of this instance (version, commit and go version labels), the value is always 1",
+		map[string]string{
+			"version":    buildinfo.Get().Version,
+			"commit":     buildinfo.Get().Commit,
+			"go_version": buildinfo.Get().GoVersion,
+		},
+	)
 )
 
 type ProxyMetrics struct {
@@ -130,16 +239,42 @@ type ProxyMetrics struct {
 	FailedWritesOnTarget Counter
 	FailedWritesOnBoth   Counter
 
+	SingleSidedWritesOrigin Counter
+	SingleSidedWritesTarget Counter
+
 	PSCacheSize      GaugeFunc
 	PSCacheMissCount Counter
 
 	ProxyReadsOriginDuration Histogram
 	ProxyReadsTargetDuration Histogram
 	ProxyWritesDuration      Histogram
+	ProxyMonitoringDuration  Histogram
 
 	InFlightReadsOrigin Gauge
 	InFlightReadsTarget Gauge
 	InFlightWrites      Gauge
+	InFlightMonitoring  Gauge
+
+	StaleTimestampWrites  Counter
+	SecondaryWriteRetries Counter
+	ConditionalWrites     Counter
+	ConditionalMismatches Counter
+	CounterWrites         Counter
 
 	OpenClientConnections GaugeFunc
+	ClientSlowWrites      Counter
+	ClientWriteTimeouts   Counter
+
+	ClientQueuedRequests     Gauge
+	ClientOverloadedRequests Counter
+
+	LoadUtilization         GaugeFunc
+	LoadInFlightUtilization GaugeFunc
+	LoadRequestsPerSecond   GaugeFunc
+	LoadCpuUtilization      GaugeFunc
+	LoadQpsHeadroom         GaugeFunc
+
+	TargetReadPercentage GaugeFunc
+
+	BuildInfo Gauge
 }
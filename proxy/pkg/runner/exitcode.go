@@ -0,0 +1,49 @@
+package runner
+
+import (
+	"errors"
+	"github.com/datastax/zdm-proxy/proxy/pkg/crash"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+)
+
+// Exit codes of the proxy process so that orchestration systems and runbooks can tell failures apart.
+const (
+	ExitCodeSuccess             = 0
+	ExitCodeFailure             = 1 // failures that don't fall in the categories below
+	ExitCodePanic               = crash.ExitCode
+	ExitCodeConfigError         = 3 // the configuration is invalid
+	ExitCodeBindFailure         = 4 // a port is not available, e.g. another proxy instance is running
+	ExitCodeClusterConnectivity = 5 // the proxy couldn't connect to the clusters before ZDM_PROXY_STARTUP_TIMEOUT_MS
+)
+
+// StartupError is an error that ends the process with a specific exit code.
+type StartupError struct {
+	ExitCode int
+	Err      error
+}
+
+func (recv *StartupError) Error() string {
+	return recv.Err.Error()
+}
+
+func (recv *StartupError) Unwrap() error {
+	return recv.Err
+}
+
+// ExitCodeOf returns the exit code of the process for the error returned by RunMain.
+func ExitCodeOf(err error) int {
+	if err == nil {
+		return ExitCodeSuccess
+	}
+	var startupErr *StartupError
+	if errors.As(err, &startupErr) {
+		return startupErr.ExitCode
+	}
+	if errors.Is(err, zdmproxy.StartupTimeoutErr) {
+		return ExitCodeClusterConnectivity
+	}
+	if errors.Is(err, zdmproxy.ListenErr) {
+		return ExitCodeBindFailure
+	}
+	return ExitCodeFailure
+}
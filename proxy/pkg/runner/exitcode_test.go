@@ -0,0 +1,30 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+)
+
+func TestExitCodeOf(t *testing.T) {
+	require.Equal(t, ExitCodeSuccess, ExitCodeOf(nil))
+	require.Equal(t, ExitCodeFailure, ExitCodeOf(errors.New("failure")))
+	require.Equal(t, ExitCodeClusterConnectivity, ExitCodeOf(fmt.Errorf("%w: connection refused", zdmproxy.StartupTimeoutErr)))
+	require.Equal(t, ExitCodeBindFailure, ExitCodeOf(fmt.Errorf("%w on 127.0.0.1:9042: address already in use", zdmproxy.ListenErr)))
+	require.Equal(t, ExitCodeConfigError, ExitCodeOf(fmt.Errorf("wrapped: %w",
+		&StartupError{ExitCode: ExitCodeConfigError, Err: errors.New("invalid")})))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer l.Close()
+	_, err = checkNoRunningInstance(&config.Config{
+		ProxyListenAddress: "127.0.0.1",
+		ProxyListenPort:    l.Addr().(*net.TCPAddr).Port,
+	})
+	require.NotNil(t, err)
+	require.Equal(t, ExitCodeBindFailure, ExitCodeOf(err))
+}
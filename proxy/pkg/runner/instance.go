@@ -0,0 +1,139 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const instanceCheckTimeout = 2 * time.Second
+
+// checkNoRunningInstance verifies that the configured ports are available and that the PID file (if configured)
+// doesn't belong to a running process. It returns a function that removes the PID file on shutdown.
+//
+// This detects other proxy instances before any listener is opened so that a misconfigured instance doesn't
+// partially start (e.g. health checks answering while the CQL port belongs to someone else).
+func checkNoRunningInstance(conf *config.Config) (func(), error) {
+	if conf.ProxyIgnoreRunningInstance {
+		log.Warnf("ZDM_PROXY_IGNORE_RUNNING_INSTANCE is enabled, skipping detection of other running proxy instances.")
+	} else {
+		err := checkPortAvailable(conf.ProxyListenAddress, conf.ProxyListenPort, "ZDM_PROXY_LISTEN_PORT", false)
+		if err != nil {
+			return nil, &StartupError{ExitCode: ExitCodeBindFailure, Err: err}
+		}
+		err = checkPortAvailable(conf.MetricsAddress, conf.MetricsPort, "ZDM_METRICS_PORT", true)
+		if err != nil {
+			return nil, &StartupError{ExitCode: ExitCodeBindFailure, Err: err}
+		}
+	}
+
+	if conf.ProxyPidFile == "" {
+		return func() {}, nil
+	}
+	return acquirePidFile(conf.ProxyPidFile, conf.ProxyIgnoreRunningInstance)
+}
+
+func checkPortAvailable(address string, port int, setting string, isHttpPort bool) error {
+	addr := net.JoinHostPort(address, strconv.Itoa(port))
+	l, err := net.Listen("tcp", addr)
+	if err == nil {
+		return l.Close()
+	}
+
+	if !errors.Is(err, syscall.EADDRINUSE) {
+		return fmt.Errorf("could not bind to %v (%v): %w", addr, setting, err)
+	}
+
+	if isHttpPort && isProxyHttpEndpoint(addr) {
+		return fmt.Errorf("another ZDM proxy instance is already running on %v (%v); stop it, "+
+			"change %v or set ZDM_PROXY_IGNORE_RUNNING_INSTANCE=true to skip this check", addr, setting, setting)
+	}
+	return fmt.Errorf("%v is already in use by another process, possibly another ZDM proxy instance (%v); "+
+		"stop that process, change %v or set ZDM_PROXY_IGNORE_RUNNING_INSTANCE=true to skip this check",
+		addr, setting, setting)
+}
+
+// isProxyHttpEndpoint returns true if the liveness endpoint of a ZDM proxy answers on the provided address.
+func isProxyHttpEndpoint(addr string) bool {
+	client := &http.Client{Timeout: instanceCheckTimeout}
+	rsp, err := client.Get(fmt.Sprintf("http://%v/health/liveness", addr))
+	if err != nil {
+		return false
+	}
+	_ = rsp.Body.Close()
+	return rsp.StatusCode == http.StatusOK
+}
+
+// acquirePidFile writes the PID of this process to the provided file. If the file already exists and belongs to
+// a running process then an error is returned unless force is true. Stale PID files are overwritten.
+func acquirePidFile(path string, force bool) (func(), error) {
+	existingPid, err := readPidFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if existingPid > 0 && existingPid != os.Getpid() && isProcessRunning(existingPid) {
+		if !force {
+			err = fmt.Errorf("PID file %v belongs to running process %d, another ZDM proxy instance is probably "+
+				"running; stop it, change ZDM_PROXY_PID_FILE or set ZDM_PROXY_IGNORE_RUNNING_INSTANCE=true to override",
+				path, existingPid)
+			return nil, &StartupError{ExitCode: ExitCodeBindFailure, Err: err}
+		}
+		log.Warnf("PID file %v belongs to running process %d, overwriting it because "+
+			"ZDM_PROXY_IGNORE_RUNNING_INSTANCE is enabled.", path, existingPid)
+	} else if existingPid > 0 {
+		log.Infof("Found stale PID file %v (process %d is not running), overwriting it.", path, existingPid)
+	}
+
+	pid := os.Getpid()
+	err = os.WriteFile(path, []byte(strconv.Itoa(pid)+"\n"), 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not write PID file %v: %w", path, err)
+	}
+
+	return func() {
+		currentPid, err := readPidFile(path)
+		if err != nil || currentPid != pid {
+			// the file was modified by someone else, leave it alone
+			return
+		}
+		if err = os.Remove(path); err != nil {
+			log.Warnf("Could not remove PID file %v: %v", path, err)
+		}
+	}, nil
+}
+
+// readPidFile returns the PID stored in the provided file or 0 if the file doesn't exist.
+func readPidFile(path string) (int, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("could not read PID file %v: %w", path, err)
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(contents)))
+	if err != nil {
+		log.Warnf("PID file %v has unexpected contents, ignoring it: %v", path, err)
+		return 0, nil
+	}
+	return pid, nil
+}
+
+func isProcessRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
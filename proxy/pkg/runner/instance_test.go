@@ -0,0 +1,64 @@
+package runner
+
+import (
+	"github.com/stretchr/testify/require"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestAcquirePidFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		contents    string
+		force       bool
+		expectedErr bool
+	}{
+		{"no file", "", false, false},
+		{"stale file", "2147483646", false, false},
+		{"invalid contents", "abc", false, false},
+		{"running process", strconv.Itoa(os.Getppid()), false, true},
+		{"running process forced", strconv.Itoa(os.Getppid()), true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "zdm-proxy.pid")
+			if tt.contents != "" {
+				require.Nil(t, os.WriteFile(path, []byte(tt.contents), 0644))
+			}
+
+			release, err := acquirePidFile(path, tt.force)
+			if tt.expectedErr {
+				require.NotNil(t, err)
+				require.Contains(t, err.Error(), "ZDM_PROXY_IGNORE_RUNNING_INSTANCE")
+				require.Equal(t, ExitCodeBindFailure, ExitCodeOf(err))
+				return
+			}
+			require.Nil(t, err)
+
+			pid, err := readPidFile(path)
+			require.Nil(t, err)
+			require.Equal(t, os.Getpid(), pid)
+
+			release()
+			_, err = os.Stat(path)
+			require.True(t, os.IsNotExist(err))
+		})
+	}
+}
+
+func TestCheckPortAvailable(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+
+	err = checkPortAvailable("127.0.0.1", port, "ZDM_PROXY_LISTEN_PORT", false)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "already in use")
+	require.Contains(t, err.Error(), "ZDM_PROXY_LISTEN_PORT")
+
+	require.Nil(t, l.Close())
+	require.Nil(t, checkPortAvailable("127.0.0.1", port, "ZDM_PROXY_LISTEN_PORT", false))
+}
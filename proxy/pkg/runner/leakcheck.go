@@ -0,0 +1,38 @@
+package runner
+
+import (
+	log "github.com/sirupsen/logrus"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"time"
+)
+
+// goroutines that are done may take a moment to actually exit after signaling it
+const goroutineLeakCheckTimeout = 2 * time.Second
+
+// logGoroutineLeaks logs a warning if more goroutines are running than before the proxy was started, the stack traces
+// of every goroutine are logged at DEBUG level to help finding the leaked ones. Returns the number of leaked
+// goroutines.
+func logGoroutineLeaks(baseline int, timeout time.Duration) int {
+	deadline := time.Now().Add(timeout)
+	current := runtime.NumGoroutine()
+	for current > baseline && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+		current = runtime.NumGoroutine()
+	}
+	if current <= baseline {
+		log.Debugf("No goroutine leak detected (%d goroutines running).", current)
+		return 0
+	}
+
+	log.Warnf("Goroutine leak detected: %d goroutines running after shutdown, %d were running before startup.",
+		current, baseline)
+	if log.IsLevelEnabled(log.DebugLevel) {
+		stacks := &strings.Builder{}
+		if err := pprof.Lookup("goroutine").WriteTo(stacks, 1); err == nil {
+			log.Debugf("Running goroutines:\n%v", stacks)
+		}
+	}
+	return current - baseline
+}
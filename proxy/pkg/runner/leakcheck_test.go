@@ -0,0 +1,21 @@
+package runner
+
+import (
+	"github.com/stretchr/testify/require"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestLogGoroutineLeaks(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	done := make(chan bool)
+	go func() {
+		<-done
+	}()
+	require.Equal(t, 1, logGoroutineLeaks(baseline, 100*time.Millisecond))
+
+	close(done)
+	require.Equal(t, 0, logGoroutineLeaks(baseline, time.Second))
+}
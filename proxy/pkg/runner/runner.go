@@ -4,14 +4,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/admin"
 	"github.com/datastax/zdm-proxy/proxy/pkg/config"
 	"github.com/datastax/zdm-proxy/proxy/pkg/health"
 	"github.com/datastax/zdm-proxy/proxy/pkg/httpzdmproxy"
 	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics/prommetrics"
 	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
 	"github.com/jpillora/backoff"
+	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 	"net/http"
+	"runtime"
 	"sync"
 	"time"
 )
@@ -19,6 +23,8 @@ import (
 var (
 	metricsHandler   = httpzdmproxy.NewHandlerWithFallback(metrics.DefaultHttpHandler())
 	readinessHandler = httpzdmproxy.NewHandlerWithFallback(health.DefaultReadinessHandler())
+	adminHandler     = httpzdmproxy.NewHandlerWithFallback(admin.DefaultHandler())
+	historyHandler   = httpzdmproxy.NewHandlerWithFallback(metrics.DefaultHttpHandler())
 	registerHandler  = &sync.Mutex{}
 	registered       = false
 )
@@ -31,16 +37,51 @@ func SetupHandlers() (*httpzdmproxy.HandlerWithFallback, *httpzdmproxy.HandlerWi
 	}
 	registered = true
 	http.Handle("/metrics", metricsHandler.Handler())
+	http.Handle("/metrics/history", historyHandler.Handler())
 	http.Handle("/health/readiness", readinessHandler.Handler())
 	http.Handle("/health/liveness", health.LivenessHandler())
+	http.Handle(admin.PathPrefix, adminHandler.Handler())
 	return metricsHandler, readinessHandler
 }
 
+// RunMain starts the http server and the proxy and blocks until the provided context is canceled.
+// An error is returned if the proxy can't start, e.g. because another instance seems to be running or because it
+// couldn't connect to the clusters before ZDM_PROXY_STARTUP_TIMEOUT_MS. See ExitCodeOf.
 func RunMain(
 	conf *config.Config,
 	ctx context.Context,
 	metricsHandler *httpzdmproxy.HandlerWithFallback,
-	readinessHandler *httpzdmproxy.HandlerWithFallback) {
+	readinessHandler *httpzdmproxy.HandlerWithFallback) error {
+
+	goroutinesBeforeStartup := runtime.NumGoroutine()
+
+	releaseInstance, err := checkNoRunningInstance(conf)
+	if err != nil {
+		return err
+	}
+	defer releaseInstance()
+
+	auditLog, err := admin.NewAuditLog(conf.AdminAuditLogFile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := auditLog.Close(); err != nil {
+			log.Warnf("Failed to close admin audit log: %v", err)
+		}
+	}()
+
+	var metricsHistory *prommetrics.MetricsHistory
+	if conf.MetricsEnabled && conf.MetricsHistoryIntervalMs > 0 {
+		metricsHistory, err = prommetrics.NewMetricsHistory(
+			prometheus.DefaultGatherer, conf.MetricsPrefix, time.Duration(conf.MetricsHistoryIntervalMs)*time.Millisecond,
+			conf.MetricsHistorySize, conf.MetricsHistoryFile)
+		if err != nil {
+			return err
+		}
+		historyHandler.SetHandler(metricsHistory.HttpHandler())
+		defer historyHandler.ClearHandler()
+	}
 
 	log.Infof("Starting http server (metrics and health checks) on %v:%d", conf.MetricsAddress, conf.MetricsPort)
 	wg := &sync.WaitGroup{}
@@ -53,11 +94,30 @@ func RunMain(
 		Jitter: true,
 	}
 
+	var startupErr error
 	zdmProxy, err := zdmproxy.RunWithRetries(conf, ctx, b)
 
 	if err == nil {
 		metricsHandler.SetHandler(zdmProxy.GetMetricHandler().GetHttpHandler())
 		readinessHandler.SetHandler(health.ReadinessHandler(zdmProxy))
+		adminTokens, _ := conf.ParseAdminTokens() // already validated
+		if len(adminTokens) > 0 {
+			log.Infof("Admin API enabled on %v:%d%v", conf.MetricsAddress, conf.MetricsPort, admin.PathPrefix)
+			adminHandler.SetHandler(admin.NewApi(zdmProxy, adminTokens, auditLog))
+		} else {
+			log.Info("Admin API disabled because no admin tokens were configured.")
+		}
+
+		if metricsHistory != nil {
+			zdmProxy.AddMetricsFlusher(func() error {
+				return metricsHistory.TakeSnapshot(time.Now())
+			})
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				metricsHistory.Run(ctx)
+			}()
+		}
 
 		log.Info("Proxy started. Waiting for SIGINT/SIGTERM to shutdown.")
 		<-ctx.Done()
@@ -65,16 +125,22 @@ func RunMain(
 		zdmProxy.Shutdown()
 		metricsHandler.ClearHandler()
 		readinessHandler.ClearHandler()
+		adminHandler.ClearHandler()
 	} else if !errors.Is(err, zdmproxy.ShutdownErr) {
 		log.Errorf("Error launching proxy: %v", err)
+		startupErr = err
 	}
 
-	log.Info("Shutting down httpzdmproxy server, waiting up to 5 seconds.")
-	srvShutdownCtx, _ := context.WithTimeout(context.Background(), 5*time.Second)
+	log.Infof("Shutting down httpzdmproxy server, waiting up to %d ms.", conf.ProxyHttpShutdownTimeoutMs)
+	srvShutdownCtx, _ := context.WithTimeout(
+		context.Background(), time.Duration(conf.ProxyHttpShutdownTimeoutMs)*time.Millisecond)
 	if err := srv.Shutdown(srvShutdownCtx); err != nil {
 		log.Errorf("Failed to gracefully shutdown httpzdmproxy server: %v", err)
 	}
 
 	wg.Wait()
 	log.Info("Http server shutdown.")
+
+	logGoroutineLeaks(goroutinesBeforeStartup, goroutineLeakCheckTimeout)
+	return startupErr
 }
@@ -27,7 +27,7 @@ type AstraMetadata struct {
 const AstraMetadataHttpTimeout = 30 * time.Second
 
 func retrieveAstraMetadata(astraMetadataServiceHostName string, astraMetadataServicePort string,
-	astraTlsConfig *tls.Config, ctx context.Context) (*AstraMetadata, error) {
+	astraTlsConfig *tls.Config, dialer Dialer, ctx context.Context) (*AstraMetadata, error) {
 	var metadata *AstraMetadata
 	// create an HTTP Client using TLS to point to the metadata service
 	//targetMetadataServiceUrl := "https://" + astraMetadataServiceHostName + ":" + astraMetadataServicePort + "/metadata"
@@ -35,6 +35,7 @@ func retrieveAstraMetadata(astraMetadataServiceHostName string, astraMetadataSer
 	httpsClient := &http.Client{
 		Transport: &http.Transport{
 			TLSClientConfig: astraTlsConfig,
+			DialContext:     dialer.DialContext,
 		},
 		Timeout: AstraMetadataHttpTimeout,
 	}
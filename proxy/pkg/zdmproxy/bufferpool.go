@@ -0,0 +1,91 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"io"
+	"sync"
+)
+
+const (
+	// v3+ frame header: version, flags, stream id (2 bytes), opcode and body length (4 bytes), v2 stream ids are 1 byte
+	maxFrameHeaderLength = 9
+
+	// bigger buffers are not returned to the pool so that a few large frames don't keep memory allocated forever
+	maxPooledBodyBufferBytes = 1024 * 1024
+)
+
+var (
+	headerBufferPool = sync.Pool{New: func() interface{} {
+		return &headerBuffer{reader: bytes.NewReader(nil)}
+	}}
+
+	// bodyBufferPool contains the buffers that frame bodies are read in or written from. Compressed bodies are only
+	// needed until they are decompressed or written. Decompressed bodies are still used after they are forwarded (async
+	// connector, prepared statement cache, retries, ...) so there is no single point where they could be released, they
+	// are read in a pooled buffer and copied to a slice of the exact size of the body.
+	bodyBufferPool = sync.Pool{New: func() interface{} {
+		return &bytes.Buffer{}
+	}}
+)
+
+type headerBuffer struct {
+	bytes  [maxFrameHeaderLength]byte
+	reader *bytes.Reader
+}
+
+func getBodyBuffer() *bytes.Buffer {
+	buf := bodyBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func putBodyBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBodyBufferBytes {
+		return
+	}
+	bodyBufferPool.Put(buf)
+}
+
+// decodeHeader reads the frame header in a pooled buffer and decodes it with the codec so that the codec doesn't
+// read each field of the header from the connection.
+func decodeHeader(reader io.Reader) (*frame.Header, error) {
+	buf := headerBufferPool.Get().(*headerBuffer)
+	defer headerBufferPool.Put(buf)
+
+	if _, err := io.ReadFull(reader, buf.bytes[:1]); err != nil {
+		return nil, fmt.Errorf("cannot decode header version: %w", err)
+	}
+	length := maxFrameHeaderLength
+	if primitive.ProtocolVersion(buf.bytes[0]&0b0111_1111) < primitive.ProtocolVersion3 {
+		length--
+	}
+	if _, err := io.ReadFull(reader, buf.bytes[1:length]); err != nil {
+		return nil, fmt.Errorf("cannot decode header: %w", err)
+	}
+	buf.reader.Reset(buf.bytes[:length])
+	return defaultCodec.DecodeHeader(buf.reader)
+}
+
+// readBody reads the uncompressed body of a frame in a pooled buffer and copies it to a slice of the exact size of the
+// body, it is equivalent to frame.RawDecoder.DecodeRawBody without the reallocations of the growing buffer. Bodies that
+// are too big to be pooled are read directly in their own slice so that they are not held twice in memory.
+func readBody(reader io.Reader, header *frame.Header) ([]byte, error) {
+	if header.BodyLength > maxPooledBodyBufferBytes {
+		body := make([]byte, header.BodyLength)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			return nil, fmt.Errorf("cannot read frame body: %w", err)
+		}
+		return body, nil
+	}
+	buf := getBodyBuffer()
+	defer putBodyBuffer(buf)
+	if err := readRawBody(reader, header, buf); err != nil {
+		return nil, err
+	}
+	body := make([]byte, buf.Len())
+	copy(body, buf.Bytes())
+	return body, nil
+}
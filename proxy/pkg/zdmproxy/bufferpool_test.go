@@ -0,0 +1,92 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+)
+
+func TestDecodeHeader(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame *frame.Frame
+	}{
+		{"v2 request", frame.NewFrame(primitive.ProtocolVersion2, -5, &message.Options{})},
+		{"v3 request", frame.NewFrame(primitive.ProtocolVersion3, 1000, &message.Query{Query: "SELECT * FROM ks.t"})},
+		{"v4 response", frame.NewFrame(primitive.ProtocolVersion4, 12, &message.VoidResult{})},
+		{"dse v2 request", frame.NewFrame(primitive.ProtocolVersionDse2, -1, &message.Options{})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded := &bytes.Buffer{}
+			require.Nil(t, defaultCodec.EncodeFrame(tt.frame, encoded))
+			expected, err := defaultCodec.DecodeHeader(bytes.NewReader(encoded.Bytes()))
+			require.Nil(t, err)
+
+			header, err := decodeHeader(bytes.NewReader(encoded.Bytes()))
+			require.Nil(t, err)
+			require.Equal(t, expected, header)
+		})
+	}
+
+	_, err := decodeHeader(bytes.NewReader([]byte{0x01, 0x00, 0x00, 0x01, 0x05, 0x00, 0x00, 0x00, 0x00}))
+	var protocolVersionErr *frame.ProtocolVersionErr
+	require.True(t, errors.As(err, &protocolVersionErr))
+	require.Equal(t, primitive.ProtocolVersion(1), protocolVersionErr.Version)
+
+	_, err = decodeHeader(bytes.NewReader([]byte{0x04, 0x00, 0x00, 0x01, 0x20, 0x00, 0x00, 0x00, 0x00}))
+	require.NotNil(t, err)
+
+	_, err = decodeHeader(bytes.NewReader([]byte{0x04, 0x00, 0x00}))
+	require.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+}
+
+func TestReadRawFrameCompressed(t *testing.T) {
+	compression := newFrameCompression()
+	require.Nil(t, compression.set(message.NewStartup(message.StartupOptionCompression, "SNAPPY")))
+	query, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(
+		primitive.ProtocolVersion4, 1, &message.Query{Query: "SELECT * FROM ks.t WHERE a = 'aaaaaaaaaaaaaaaaaaaaaaaa'"}))
+	require.Nil(t, err)
+
+	encoded := &bytes.Buffer{}
+	for i := 0; i < 2; i++ {
+		compressed, err := compression.compressInto(query, getBodyBuffer())
+		require.Nil(t, err)
+		require.Nil(t, defaultCodec.EncodeRawFrame(compressed, encoded))
+	}
+
+	reader := bytes.NewReader(encoded.Bytes())
+	first, err := readRawFrame(reader, "", context.Background(), compression, 0)
+	require.Nil(t, err)
+	second, err := readRawFrame(reader, "", context.Background(), compression, 0)
+	require.Nil(t, err)
+	// the compressed bodies are read in the same pooled buffer, the decompressed bodies must not be affected
+	require.Equal(t, query, first)
+	require.Equal(t, query, second)
+}
+
+func TestReadRawFrameAllocs(t *testing.T) {
+	query, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(
+		primitive.ProtocolVersion4, 1, &message.Query{Query: "SELECT * FROM ks.t WHERE a = 'aaaaaaaaaaaaaaaaaaaaaaaa'"}))
+	require.Nil(t, err)
+	encoded := &bytes.Buffer{}
+	require.Nil(t, defaultCodec.EncodeRawFrame(query, encoded))
+	reader := bytes.NewReader(encoded.Bytes())
+
+	codecAllocs := testing.AllocsPerRun(100, func() {
+		reader.Reset(encoded.Bytes())
+		_, _ = defaultCodec.DecodeRawFrame(reader)
+	})
+	allocs := testing.AllocsPerRun(100, func() {
+		reader.Reset(encoded.Bytes())
+		_, _ = readRawFrame(reader, "", context.Background(), nil, 0)
+	})
+	t.Logf("allocations per frame: codec %v, readRawFrame %v", codecAllocs, allocs)
+	require.Less(t, allocs, codecAllocs)
+}
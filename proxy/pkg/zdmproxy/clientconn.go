@@ -3,15 +3,20 @@ package zdmproxy
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/datastax/go-cassandra-native-protocol/frame"
 	"github.com/datastax/go-cassandra-native-protocol/message"
 	"github.com/datastax/go-cassandra-native-protocol/primitive"
 	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/crash"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
 	log "github.com/sirupsen/logrus"
 	"net"
+	"os"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 const ClientConnectorLogPrefix = "CLIENT-CONNECTOR"
@@ -58,6 +63,18 @@ type ClientConnector struct {
 	shutdownRequestCtx context.Context
 
 	minProtoVer primitive.ProtocolVersion
+
+	compression *frameCompression
+
+	memory *connectionMemory
+
+	loadTracker        *LoadTracker
+	queuedRequests     metrics.Gauge
+	overloadedRequests metrics.Counter
+	handshakeDone      *atomic.Value
+	monitoring         *atomic.Value // see ClientHandler.trackMonitoringConnection
+
+	logger *log.Entry
 }
 
 func NewClientConnector(
@@ -74,8 +91,20 @@ func NewClientConnector(
 	writeScheduler *Scheduler,
 	shutdownRequestCtx context.Context,
 	clientHandlerShutdownRequestCancelFn context.CancelFunc,
-	minProtoVer primitive.ProtocolVersion) *ClientConnector {
-
+	minProtoVer primitive.ProtocolVersion,
+	compression *frameCompression,
+	slowWrites metrics.Counter,
+	loadTracker *LoadTracker,
+	queuedRequests metrics.Gauge,
+	overloadedRequests metrics.Counter,
+	logger *log.Entry,
+	onWriteTimeout func()) *ClientConnector {
+
+	memory := newConnectionMemory(conf.ProxyClientConnectionMaxBufferedBytes)
+	handshakeDone := &atomic.Value{}
+	handshakeDone.Store(false)
+	monitoring := &atomic.Value{}
+	monitoring.Store(false)
 	return &ClientConnector{
 		connection:              connection,
 		conf:                    conf,
@@ -92,7 +121,12 @@ func NewClientConnector(
 			ClientConnectorLogPrefix,
 			false,
 			false,
-			writeScheduler),
+			writeScheduler,
+			compression,
+			time.Duration(conf.ProxyClientWriteTimeoutMs)*time.Millisecond,
+			slowWrites,
+			onWriteTimeout,
+			memory.releaseResponse),
 		responsesDoneChan:                    responsesDoneChan,
 		requestsDoneCtx:                      requestsDoneCtx,
 		eventsDoneChan:                       eventsDoneChan,
@@ -101,6 +135,14 @@ func NewClientConnector(
 		shutdownRequestCtx:                   shutdownRequestCtx,
 		clientHandlerShutdownRequestCancelFn: clientHandlerShutdownRequestCancelFn,
 		minProtoVer:                          minProtoVer,
+		compression:                          compression,
+		memory:                               memory,
+		loadTracker:                          loadTracker,
+		queuedRequests:                       queuedRequests,
+		overloadedRequests:                   overloadedRequests,
+		handshakeDone:                        handshakeDone,
+		monitoring:                           monitoring,
+		logger:                               logger,
 	}
 }
 
@@ -108,46 +150,67 @@ func NewClientConnector(
  *	Starts two listening loops: one for receiving requests from the client, one for the responses that must be sent to the client
  */
 func (cc *ClientConnector) run(activeClients *int32) {
+	if cc.conf.ProxyClientHandshakeTimeoutMs > 0 {
+		// cleared by handshakeCompleted, connections that never complete the handshake (e.g. port scanners) are closed
+		handshakeDeadline := time.Now().Add(time.Duration(cc.conf.ProxyClientHandshakeTimeoutMs) * time.Millisecond)
+		if err := cc.connection.SetReadDeadline(handshakeDeadline); err != nil {
+			cc.logger.Warnf("[%s] Could not set handshake deadline on client connection %v: %v",
+				ClientConnectorLogPrefix, cc.connection.RemoteAddr(), err)
+		}
+	}
 	cc.listenForRequests()
 	cc.writeCoalescer.RunWriteQueueLoop()
 	cc.clientHandlerWg.Add(1)
 	go func() {
+		defer crash.HandlePanic()
 		defer cc.clientHandlerWg.Done()
 		<-cc.responsesDoneChan
 		<-cc.requestsDoneCtx.Done()
 		<-cc.eventsDoneChan
 
-		log.Debugf("[%s] All in flight requests are done, requesting cluster connections of client handler %v "+
+		cc.logger.Debugf("[%s] All in flight requests are done, requesting cluster connections of client handler %v "+
 			"to be terminated.", ClientConnectorLogPrefix, cc.connection.RemoteAddr())
 		cc.clientHandlerCancelFunc()
 
-		log.Infof("[%s] Shutting down client connection to %v", ClientConnectorLogPrefix, cc.connection.RemoteAddr())
+		cc.logger.Infof("[%s] Shutting down client connection to %v", ClientConnectorLogPrefix, cc.connection.RemoteAddr())
 		err := cc.connection.Close()
 		if err != nil {
-			log.Warnf("[%s] Error received while closing connection to %v: %v", ClientConnectorLogPrefix, cc.connection.RemoteAddr(), err)
+			cc.logger.Warnf("[%s] Error received while closing connection to %v: %v", ClientConnectorLogPrefix, cc.connection.RemoteAddr(), err)
 		}
 
-		log.Debugf("[%s] Waiting until request listener is done.", ClientConnectorLogPrefix)
+		cc.logger.Debugf("[%s] Waiting until request listener is done.", ClientConnectorLogPrefix)
 		<-cc.clientConnectorRequestsDoneChan
-		log.Debugf("[%s] Shutting down write coalescer.", ClientConnectorLogPrefix)
+		cc.logger.Debugf("[%s] Shutting down write coalescer.", ClientConnectorLogPrefix)
 		cc.writeCoalescer.Close()
 
 		atomic.AddInt32(activeClients, -1)
 	}()
 }
 
+// handshakeCompleted removes the handshake deadline, the client can be idle for as long as it wants from now on.
+func (cc *ClientConnector) handshakeCompleted() {
+	cc.handshakeDone.Store(true)
+	if cc.conf.ProxyClientHandshakeTimeoutMs > 0 {
+		if err := cc.connection.SetReadDeadline(time.Time{}); err != nil {
+			cc.logger.Warnf("[%s] Could not clear handshake deadline on client connection %v: %v",
+				ClientConnectorLogPrefix, cc.connection.RemoteAddr(), err)
+		}
+	}
+}
+
 func (cc *ClientConnector) listenForRequests() {
 
-	log.Tracef("[%s] listenForRequests for client %v", ClientConnectorLogPrefix, cc.connection.RemoteAddr())
+	cc.logger.Tracef("[%s] listenForRequests for client %v", ClientConnectorLogPrefix, cc.connection.RemoteAddr())
 
 	cc.clientHandlerWg.Add(1)
 	go func() {
+		defer crash.HandlePanic()
 		defer cc.clientHandlerWg.Done()
 		defer close(cc.clientConnectorRequestsDoneChan)
 
 		wg := &sync.WaitGroup{}
 		defer wg.Wait()
-		defer log.Debugf("[%s] Shutting down request listener, waiting until request listener tasks are done...", ClientConnectorLogPrefix)
+		defer cc.logger.Debugf("[%s] Shutting down request listener, waiting until request listener tasks are done...", ClientConnectorLogPrefix)
 
 		lock := &sync.RWMutex{}
 		closed := false
@@ -163,11 +226,12 @@ func (cc *ClientConnector) listenForRequests() {
 
 		cc.clientHandlerWg.Add(1)
 		go func() {
+			defer crash.HandlePanic()
 			defer cc.clientHandlerWg.Done()
 			select {
 			case <-cc.clientHandlerContext.Done():
 			case <-cc.shutdownRequestCtx.Done():
-				log.Debugf("[%s] Entering \"draining\" mode of request listener %v", ClientConnectorLogPrefix, cc.connection.RemoteAddr())
+				cc.logger.Debugf("[%s] Entering \"draining\" mode of request listener %v", ClientConnectorLogPrefix, cc.connection.RemoteAddr())
 			}
 
 			setDrainModeNowFunc()
@@ -177,11 +241,28 @@ func (cc *ClientConnector) listenForRequests() {
 		connectionAddr := cc.connection.RemoteAddr().String()
 		protocolErrOccurred := false
 		var alreadySentProtocolErr *frame.RawFrame
+		memoryLimitReached := false
+		inFlightLimitReached := false
 		for cc.clientHandlerContext.Err() == nil {
-			f, err := readRawFrame(bufferedReader, connectionAddr, cc.clientHandlerContext)
+			f, err := readRawFrame(
+				bufferedReader, connectionAddr, cc.clientHandlerContext, cc.compression, cc.conf.ProxyMaxFrameSizeBytes)
+
+			var frameTooLargeErr *frameTooLargeError
+			if errors.As(err, &frameTooLargeErr) {
+				cc.logger.Warnf("[%s] Client %v sent a request larger than ZDM_PROXY_MAX_FRAME_SIZE_BYTES: %v",
+					ClientConnectorLogPrefix, connectionAddr, frameTooLargeErr)
+				cc.sendInvalidMessageToClient(frameTooLargeErr.header, frameTooLargeErr.Error())
+				continue
+			}
 
 			protocolErrResponseFrame, err, _ := checkProtocolError(f, cc.minProtoVer, err, protocolErrOccurred, ClientConnectorLogPrefix)
 			if err != nil {
+				if errors.Is(err, os.ErrDeadlineExceeded) {
+					cc.logger.Infof("[%s] Client %v did not complete the handshake within %d ms, closing the connection.",
+						ClientConnectorLogPrefix, connectionAddr, cc.conf.ProxyClientHandshakeTimeoutMs)
+					cc.clientHandlerCancelFunc()
+					break
+				}
 				handleConnectionError(
 					err, cc.clientHandlerContext, cc.clientHandlerCancelFunc, ClientConnectorLogPrefix, "reading", connectionAddr)
 				break
@@ -197,32 +278,93 @@ func (cc *ClientConnector) listenForRequests() {
 				continue
 			}
 
+			if cc.isSaturated() {
+				if !inFlightLimitReached {
+					inFlightLimitReached = true
+					cc.logger.Warnf("[%s] Proxy reached ZDM_PROXY_MAX_IN_FLIGHT_REQUESTS (%d), "+
+						"returning OVERLOADED to requests of client %v until requests complete.",
+						ClientConnectorLogPrefix, cc.conf.ProxyMaxInFlightRequests, connectionAddr)
+				}
+				cc.overloadedRequests.Add(1)
+				cc.sendOverloadedMessageToClient(f, "Too many requests in flight on this proxy, please retry later.")
+				continue
+			}
+
+			if !cc.memory.acquireRequest(f) {
+				if !memoryLimitReached {
+					memoryLimitReached = true
+					cc.logger.Warnf("[%s] Client %v reached ZDM_PROXY_CLIENT_CONNECTION_MAX_BUFFERED_BYTES (%d), "+
+						"returning OVERLOADED to the requests that don't fit.",
+						ClientConnectorLogPrefix, connectionAddr, cc.conf.ProxyClientConnectionMaxBufferedBytes)
+				}
+				cc.overloadedRequests.Add(1)
+				cc.sendOverloadedMessageToClient(f, "Too many bytes buffered for this connection, please retry later.")
+				continue
+			}
+
 			wg.Add(1)
+			cc.queuedRequests.Add(1)
 			cc.readScheduler.Schedule(func() {
 				defer wg.Done()
-				log.Tracef("[%s] Received request on client connector: %v", ClientConnectorLogPrefix, f.Header)
+				defer cc.queuedRequests.Subtract(1)
+				cc.logger.Tracef("[%s] Received request on client connector: %v", ClientConnectorLogPrefix, f.Header)
 				lock.RLock()
 				if closed {
 					lock.RUnlock()
+					cc.memory.releaseRequest(f.Header.StreamId)
 					cc.sendOverloadedToClient(f)
 					return
 				}
-				cc.requestChannel <- f
+				select {
+				case cc.requestChannel <- f:
+				default:
+					sent := watchBlockedSend("client request channel")
+					cc.requestChannel <- f
+					sent()
+				}
 				lock.RUnlock()
-				log.Tracef("[%s] Request sent to client connector's request channel: %v", ClientConnectorLogPrefix, f.Header)
+				cc.logger.Tracef("[%s] Request sent to client connector's request channel: %v", ClientConnectorLogPrefix, f.Header)
 			})
 		}
 	}()
 }
 
+// isSaturated returns true if the handshake is done and the proxy has ZDM_PROXY_MAX_IN_FLIGHT_REQUESTS requests in
+// flight. Handshake requests are never rejected so clients can still connect to a saturated proxy, neither are the
+// requests of monitoring connections so that drivers keep receiving events and refreshing their metadata.
+func (cc *ClientConnector) isSaturated() bool {
+	if cc.conf.ProxyMaxInFlightRequests <= 0 || cc.loadTracker == nil || !cc.handshakeDone.Load().(bool) ||
+		cc.monitoring.Load().(bool) {
+		return false
+	}
+	return cc.loadTracker.InFlightRequests() >= int64(cc.conf.ProxyMaxInFlightRequests)
+}
+
 func (cc *ClientConnector) sendOverloadedToClient(request *frame.RawFrame) {
+	cc.sendOverloadedMessageToClient(request, "Shutting down, please retry on next host.")
+}
+
+func (cc *ClientConnector) sendOverloadedMessageToClient(request *frame.RawFrame, errorMessage string) {
 	msg := &message.Overloaded{
-		ErrorMessage: "Shutting down, please retry on next host.",
+		ErrorMessage: errorMessage,
 	}
 	response := frame.NewFrame(request.Header.Version, request.Header.StreamId, msg)
 	rawResponse, err := defaultCodec.ConvertToRawFrame(response)
 	if err != nil {
-		log.Errorf("[%s] Could not convert frame (%v) to raw frame: %v", ClientConnectorLogPrefix, response, err)
+		cc.logger.Errorf("[%s] Could not convert frame (%v) to raw frame: %v", ClientConnectorLogPrefix, response, err)
+	} else {
+		cc.sendResponseToClient(rawResponse)
+	}
+}
+
+func (cc *ClientConnector) sendInvalidMessageToClient(requestHeader *frame.Header, errorMessage string) {
+	msg := &message.Invalid{
+		ErrorMessage: errorMessage,
+	}
+	response := frame.NewFrame(requestHeader.Version, requestHeader.StreamId, msg)
+	rawResponse, err := defaultCodec.ConvertToRawFrame(response)
+	if err != nil {
+		cc.logger.Errorf("[%s] Could not convert frame (%v) to raw frame: %v", ClientConnectorLogPrefix, response, err)
 	} else {
 		cc.sendResponseToClient(rawResponse)
 	}
@@ -233,12 +375,12 @@ func checkProtocolError(f *frame.RawFrame, protoVer primitive.ProtocolVersion, c
 	var streamId int16
 	var logMsg string
 	if connErr != nil {
-		protocolErrMsg = checkUnsupportedProtocolError(connErr)
+		protocolErrMsg = checkUnsupportedProtocolError(connErr, protoVer)
 		logMsg = fmt.Sprintf("Protocol error detected while decoding a frame: %v.", connErr)
 		streamId = 0
 		errorCode = ProtocolErrorDecodeError
 	} else {
-		protocolErrMsg = checkProtocolVersion(f.Header.Version)
+		protocolErrMsg = checkProtocolVersion(f.Header.Version, protoVer)
 		logMsg = "Protocol v5 detected while decoding a frame."
 		streamId = f.Header.StreamId
 		errorCode = ProtocolErrorUnsupportedVersion
@@ -270,5 +412,13 @@ func generateProtocolErrorResponseFrame(streamId int16, protoVer primitive.Proto
 }
 
 func (cc *ClientConnector) sendResponseToClient(frame *frame.RawFrame) {
+	if !cc.memory.acquireResponse(frame) {
+		cc.logger.Warnf("[%s] Client %v is not reading its responses fast enough, %d bytes are buffered for it "+
+			"(ZDM_PROXY_CLIENT_CONNECTION_MAX_BUFFERED_BYTES is %d). Closing the connection.",
+			ClientConnectorLogPrefix, cc.connection.RemoteAddr(), cc.memory.usedBytes(),
+			cc.conf.ProxyClientConnectionMaxBufferedBytes)
+		cc.clientHandlerCancelFunc()
+		return
+	}
 	cc.writeCoalescer.Enqueue(frame)
 }
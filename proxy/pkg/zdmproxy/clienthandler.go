@@ -3,6 +3,7 @@ package zdmproxy
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -11,6 +12,7 @@ import (
 	"github.com/datastax/go-cassandra-native-protocol/primitive"
 	"github.com/datastax/zdm-proxy/proxy/pkg/common"
 	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/crash"
 	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
@@ -22,6 +24,8 @@ import (
 	"time"
 )
 
+const proxyEventsChannelSize = 64
+
 /*
 	ClientHandler holds the 1:1:1 pairing:
     	- a client connector (+ a channel on which the connector sends the requests coming from the client)
@@ -55,6 +59,7 @@ type ClientHandler struct {
 
 	currentKeyspaceName *atomic.Value
 	handshakeDone       *atomic.Value
+	compression         *frameCompression
 
 	authErrorMessage *message.AuthenticationError
 
@@ -89,6 +94,19 @@ type ClientHandler struct {
 	responsesDoneChan chan<- bool
 	eventsDoneChan    chan<- bool
 
+	// events generated by the proxy itself (e.g. proxy instances added to the topology)
+	proxyEventsChan chan *frame.RawFrame
+
+	// protocol version used by the client when it registered for TOPOLOGY_CHANGE events, nil if it didn't register
+	topologyEventsProtoVer *atomic.Value
+
+	// event types that the client registered for (map[primitive.EventType]bool), nil if it didn't register
+	registeredEvents *atomic.Value
+	eventForwarding  common.EventForwarding
+
+	// last SUPPORTED response sent to the client, used to answer OPTIONS requests when ZDM_PROXY_LOCAL_HEARTBEATS is enabled
+	supportedResponse *atomic.Value
+
 	requestsDoneCancelFn context.CancelFunc
 
 	requestResponseScheduler  *Scheduler
@@ -107,6 +125,26 @@ type ClientHandler struct {
 	targetObserver *protocolEventObserverImpl
 
 	primaryCluster               common.ClusterType
+	readCluster                  common.ClusterType // primary cluster unless the client is routed by ZDM_CLIENT_READ_ROUTING
+	dualWriteFailureMode         common.DualWriteFailureMode
+	lwtMode                      common.LwtMode
+	lwtMismatchWarned            int32
+	counterWriteMode             common.CounterWriteMode
+	singleSidedWrites            *SingleSidedWrites
+	singleSidedWriteWarned       int32
+	monitoringState              int32
+	tableReadRouting             *TableReadRouting
+	readShift                    *ReadShift
+	featureFlags                 *FeatureFlags
+	requestSampler               *RequestSampler
+	consistencyStats             *ConsistencyStats
+	trafficRecorder              *TrafficRecorder
+	recordingConnection          uint64
+	writeTimestampFloor          *WriteTimestampFloor
+	staleTimestampWarned         int32
+	secondaryWriteRetry          *SecondaryWriteRetry
+	connLogger                   *atomic.Value
+	clientIp                     string
 	forwardSystemQueriesToTarget bool
 	forwardAuthToTarget          bool
 	targetCredsOnClientRequest   bool
@@ -115,7 +153,7 @@ type ClientHandler struct {
 	parameterModifier *ParameterModifier
 	timeUuidGenerator TimeUuidGenerator
 
-	// not used atm but should be used when a protocol error occurs after #68 has been addressed
+	// used to drain the connection, should also be used when a protocol error occurs after #68 has been addressed
 	clientHandlerShutdownRequestCancelFn context.CancelFunc
 
 	clientHandlerShutdownRequestContext context.Context
@@ -146,7 +184,23 @@ func NewClientHandler(
 	timeUuidGenerator TimeUuidGenerator,
 	readMode common.ReadMode,
 	primaryCluster common.ClusterType,
-	systemQueriesMode common.SystemQueriesMode) (*ClientHandler, error) {
+	tableReadRouting *TableReadRouting,
+	clientReadRouting []*common.ClientReadRoute,
+	eventForwarding common.EventForwarding,
+	readShift *ReadShift,
+	featureFlags *FeatureFlags,
+	requestSampler *RequestSampler,
+	consistencyStats *ConsistencyStats,
+	trafficRecorder *TrafficRecorder,
+	systemQueriesMode common.SystemQueriesMode,
+	dualWriteFailureMode common.DualWriteFailureMode,
+	lwtMode common.LwtMode,
+	counterWriteMode common.CounterWriteMode,
+	singleSidedWrites *SingleSidedWrites,
+	loadTracker *LoadTracker,
+	writeTimestampFloor *WriteTimestampFloor,
+	secondaryWriteRetry *SecondaryWriteRetry,
+	connLogger *log.Entry) (*ClientHandler, error) {
 
 	originEndpointId := originCassandraConnInfo.endpoint.GetEndpointIdentifier()
 	targetEndpointId := targetCassandraConnInfo.endpoint.GetEndpointIdentifier()
@@ -191,34 +245,42 @@ func NewClientHandler(
 	localClientHandlerWg := &sync.WaitGroup{}
 	globalClientHandlersWg.Add(1)
 	go func() {
+		defer crash.HandlePanic()
 		defer globalClientHandlersWg.Done()
 		<-clientHandlerContext.Done()
 		clientHandlerShutdownRequestCancelFn()
 		localClientHandlerWg.Wait()
 		closeFrameProcessors()
 		requestsDoneCancelFn() // make sure this ctx is not leaked but it should be canceled before this
-		log.Debugf("Client Handler is shutdown.")
+		connLogger.Debugf("Client Handler is shutdown.")
 	}()
 
 	respChannel := make(chan *Response, numWorkers)
 	clientHandlerRequestWg := &sync.WaitGroup{}
 	handshakeDone := &atomic.Value{}
+	compression := newFrameCompression()
 
 	originConnector, err := NewClusterConnector(
 		originCassandraConnInfo, conf, psCache, nodeMetrics, localClientHandlerWg, clientHandlerRequestWg,
 		clientHandlerContext, clientHandlerCancelFunc, respChannel, readScheduler, writeScheduler, requestsDoneCtx,
-		false, nil, handshakeDone, originFrameProcessor, originCCProtoVer)
+		false, nil, handshakeDone, originFrameProcessor, originCCProtoVer, compression)
 	if err != nil {
 		clientHandlerCancelFunc()
+		if !errors.Is(err, ShutdownErr) {
+			originControlConn.ReportNodeFailure(originHost, NodeFailureConnect)
+		}
 		return nil, err
 	}
 
 	targetConnector, err := NewClusterConnector(
 		targetCassandraConnInfo, conf, psCache, nodeMetrics, localClientHandlerWg, clientHandlerRequestWg,
 		clientHandlerContext, clientHandlerCancelFunc, respChannel, readScheduler, writeScheduler, requestsDoneCtx,
-		false, nil, handshakeDone, targetFrameProcessor, targetCCProtoVer)
+		false, nil, handshakeDone, targetFrameProcessor, targetCCProtoVer, compression)
 	if err != nil {
 		clientHandlerCancelFunc()
+		if !errors.Is(err, ShutdownErr) {
+			targetControlConn.ReportNodeFailure(targetHost, NodeFailureConnect)
+		}
 		return nil, err
 	}
 
@@ -234,9 +296,9 @@ func NewClientHandler(
 		asyncConnector, err = NewClusterConnector(
 			asyncConnInfo, conf, psCache, nodeMetrics, localClientHandlerWg, clientHandlerRequestWg,
 			clientHandlerContext, clientHandlerCancelFunc, respChannel, readScheduler, writeScheduler, requestsDoneCtx,
-			true, asyncPendingRequests, handshakeDone, asyncFrameProcessor, originCCProtoVer)
+			true, asyncPendingRequests, handshakeDone, asyncFrameProcessor, originCCProtoVer, compression)
 		if err != nil {
-			log.Errorf("Could not create async cluster connector to %s, async requests will not be forwarded: %s", asyncConnInfo.connConfig.GetClusterType(), err.Error())
+			connLogger.Errorf("Could not create async cluster connector to %s, async requests will not be forwarded: %s", asyncConnInfo.connConfig.GetClusterType(), err.Error())
 			asyncConnector = nil
 		}
 	}
@@ -256,6 +318,19 @@ func NewClientHandler(
 	forwardAuthToTarget, targetCredsOnClientRequest := forwardAuthToTarget(
 		originControlConn, targetControlConn, conf.ForwardClientCredentialsToOrigin)
 
+	// feature flags and client read routing are evaluated per client IP address
+	clientIp, _, err := net.SplitHostPort(clientTcpConn.RemoteAddr().String())
+	if err != nil {
+		clientIp = clientTcpConn.RemoteAddr().String()
+	}
+	readCluster := getClientReadCluster(clientReadRouting, clientIp, primaryCluster)
+	if readCluster != primaryCluster {
+		connLogger.Infof("Reads of this client connection are forwarded to %v (ZDM_CLIENT_READ_ROUTING).", readCluster)
+	}
+
+	connLoggerValue := &atomic.Value{}
+	connLoggerValue.Store(connLogger)
+
 	return &ClientHandler{
 		clientConnector: NewClientConnector(
 			clientTcpConn,
@@ -271,7 +346,16 @@ func NewClientHandler(
 			writeScheduler,
 			clientHandlerShutdownRequestContext,
 			clientHandlerShutdownRequestCancelFn,
-			minProtoVer(originCCProtoVer, targetCCProtoVer)),
+			minProtoVer(originCCProtoVer, targetCCProtoVer),
+			compression,
+			metricHandler.GetProxyMetrics().ClientSlowWrites,
+			loadTracker,
+			metricHandler.GetProxyMetrics().ClientQueuedRequests,
+			metricHandler.GetProxyMetrics().ClientOverloadedRequests,
+			connLogger,
+			func() {
+				metricHandler.GetProxyMetrics().ClientWriteTimeouts.Add(1)
+			}),
 
 		asyncConnector:                       asyncConnector,
 		originCassandraConnector:             originConnector,
@@ -285,6 +369,7 @@ func NewClientHandler(
 		clientHandlerCancelFunc:              clientHandlerCancelFunc,
 		currentKeyspaceName:                  &atomic.Value{},
 		handshakeDone:                        handshakeDone,
+		compression:                          compression,
 		authErrorMessage:                     nil,
 		startupRequest:                       &atomic.Value{},
 		targetUsername:                       targetUsername,
@@ -301,6 +386,11 @@ func NewClientHandler(
 		closedRespChannelLock:                &sync.RWMutex{},
 		responsesDoneChan:                    responsesDoneChan,
 		eventsDoneChan:                       eventsDoneChan,
+		proxyEventsChan:                      make(chan *frame.RawFrame, proxyEventsChannelSize),
+		topologyEventsProtoVer:               &atomic.Value{},
+		registeredEvents:                     &atomic.Value{},
+		eventForwarding:                      eventForwarding,
+		supportedResponse:                    &atomic.Value{},
 		requestsDoneCancelFn:                 requestsDoneCancelFn,
 		requestResponseScheduler:             requestResponseScheduler,
 		conf:                                 conf,
@@ -311,6 +401,22 @@ func NewClientHandler(
 		originObserver:                       originObserver,
 		targetObserver:                       targetObserver,
 		primaryCluster:                       primaryCluster,
+		readCluster:                          readCluster,
+		dualWriteFailureMode:                 dualWriteFailureMode,
+		lwtMode:                              lwtMode,
+		counterWriteMode:                     counterWriteMode,
+		singleSidedWrites:                    singleSidedWrites,
+		tableReadRouting:                     tableReadRouting,
+		readShift:                            readShift,
+		featureFlags:                         featureFlags,
+		requestSampler:                       requestSampler,
+		consistencyStats:                     consistencyStats,
+		trafficRecorder:                      trafficRecorder,
+		recordingConnection:                  trafficRecorder.NextConnection(),
+		writeTimestampFloor:                  writeTimestampFloor,
+		secondaryWriteRetry:                  secondaryWriteRetry,
+		connLogger:                           connLoggerValue,
+		clientIp:                             clientIp,
 		forwardSystemQueriesToTarget:         systemQueriesMode == common.SystemQueriesModeTarget,
 		forwardAuthToTarget:                  forwardAuthToTarget,
 		targetCredsOnClientRequest:           targetCredsOnClientRequest,
@@ -340,6 +446,7 @@ func (ch *ClientHandler) run(activeClients *int32) {
 	addObserver(ch.targetObserver, ch.targetControlConn)
 
 	go func() {
+		defer crash.HandlePanic()
 		<-ch.originCassandraConnector.doneChan
 		<-ch.targetCassandraConnector.doneChan
 		if ch.asyncConnector != nil {
@@ -355,6 +462,30 @@ func (ch *ClientHandler) run(activeClients *int32) {
 	}()
 }
 
+// logger returns the logger of this client connection, its fields identify the connection (and the negotiated
+// protocol version once the handshake is done).
+func (ch *ClientHandler) logger() *log.Entry {
+	return ch.connLogger.Load().(*log.Entry)
+}
+
+// sendToResponseChannel must only be called while holding closedRespChannelLock and if the channel is not closed.
+func (ch *ClientHandler) sendToResponseChannel(response *Response) {
+	select {
+	case ch.respChannel <- response:
+	default:
+		sent := watchBlockedSend("client handler response channel")
+		ch.respChannel <- response
+		sent()
+	}
+}
+
+// Drain gracefully closes the client connection: requests that are in flight are completed and new requests are
+// rejected with an OVERLOADED error that tells the client to retry on another host. The connection is closed once
+// the in flight requests are done.
+func (ch *ClientHandler) Drain() {
+	ch.clientHandlerShutdownRequestCancelFn()
+}
+
 func addObserver(observer *protocolEventObserverImpl, controlConn *ControlConn) {
 	if observer != nil {
 		host := observer.GetHost()
@@ -380,19 +511,20 @@ func (ch *ClientHandler) requestLoop() {
 	ready := false
 	var err error
 	ch.localClientHandlerWg.Add(1)
-	log.Debugf("requestLoop starting now")
+	ch.logger().Debugf("requestLoop starting now")
 	go func() {
+		defer crash.HandlePanic()
 		defer ch.localClientHandlerWg.Done()
 		connectionAddr := ch.clientConnector.connection.RemoteAddr().String()
-		defer log.Debugf("Client Handler request loop %v shutdown.", connectionAddr)
+		defer ch.logger().Debugf("Client Handler request loop %v shutdown.", connectionAddr)
 		defer ch.requestsDoneCancelFn()
 		defer ch.originCassandraConnector.writeCoalescer.Close()
-		defer log.Debugf("Waiting for origin write coalescer to finish...")
+		defer ch.logger().Debugf("Waiting for origin write coalescer to finish...")
 		defer ch.targetCassandraConnector.writeCoalescer.Close()
-		defer log.Debugf("Waiting for target write coalescer to finish...")
+		defer ch.logger().Debugf("Waiting for target write coalescer to finish...")
 		if ch.asyncConnector != nil {
 			defer ch.asyncConnector.writeCoalescer.Close()
-			defer log.Debugf("Waiting for async %s write coalescer to finish...", ch.asyncConnector.clusterType)
+			defer ch.logger().Debugf("Waiting for async %s write coalescer to finish...", ch.asyncConnector.clusterType)
 		}
 
 		wg := &sync.WaitGroup{}
@@ -403,24 +535,32 @@ func (ch *ClientHandler) requestLoop() {
 			}
 
 			if ch.clientHandlerShutdownRequestContext.Err() != nil {
+				ch.clientConnector.memory.releaseRequest(f.Header.StreamId)
 				ch.clientConnector.sendOverloadedToClient(f)
 				continue
 			}
 
-			log.Tracef("Request received on client handler: %v", f.Header)
+			ch.logger().Tracef("Request received on client handler: %v", f.Header)
 			if !ready {
-				log.Tracef("not ready")
+				ch.logger().Tracef("not ready")
 				// Handle client authentication
 				ready, err = ch.handleHandshakeRequest(f, wg)
+				ch.clientConnector.memory.releaseRequest(f.Header.StreamId)
 				if err != nil && !errors.Is(err, ShutdownErr) {
-					log.Error(err)
+					ch.logger().Error(err)
 				}
 				if ready {
 					ch.handshakeDone.Store(true)
-					log.Infof(
+					ch.clientConnector.handshakeCompleted()
+					if startupRequest, ok := ch.startupRequest.Load().(*frame.RawFrame); ok {
+						ch.connLogger.Store(ch.logger().WithField("protocol_version", startupRequest.Header.Version))
+						ch.originCassandraConnector.runIdleHeartbeatLoop(startupRequest.Header.Version)
+						ch.targetCassandraConnector.runIdleHeartbeatLoop(startupRequest.Header.Version)
+					}
+					ch.logger().Infof(
 						"Handshake successful with client %s", connectionAddr)
 				}
-				log.Tracef("ready? %t", ready)
+				ch.logger().Tracef("ready? %t", ready)
 			} else {
 				wg.Add(1)
 				ch.requestResponseScheduler.Schedule(func() {
@@ -430,11 +570,12 @@ func (ch *ClientHandler) requestLoop() {
 			}
 		}
 
-		log.Debugf("Shutting down client handler request listener %v.", connectionAddr)
+		ch.logger().Debugf("Shutting down client handler request listener %v.", connectionAddr)
 
 		wg.Wait()
 
 		go func() {
+			defer crash.HandlePanic()
 			<-ch.clientHandlerContext.Done()
 			ch.clearRequestContexts(ch.requestContextHolders)
 			ch.clearRequestContexts(ch.asyncRequestContextHolders)
@@ -442,7 +583,7 @@ func (ch *ClientHandler) requestLoop() {
 				ch.asyncPendingRequests.clear(func(ctx RequestContext) {
 					typedReqCtx, ok := ctx.(*asyncRequestContextImpl)
 					if !ok {
-						log.Errorf("Failed to cancel async request because request context conversion failed. "+
+						ch.logger().Errorf("Failed to cancel async request because request context conversion failed. "+
 							"This is most likely a bug, please report. AsyncRequestContext: %v", ctx)
 					} else {
 						if !typedReqCtx.expectedResponse {
@@ -453,7 +594,7 @@ func (ch *ClientHandler) requestLoop() {
 			}
 		}()
 
-		log.Debugf("Waiting for all in flight requests from %v to finish.", connectionAddr)
+		ch.logger().Debugf("Waiting for all in flight requests from %v to finish.", connectionAddr)
 		ch.clientHandlerRequestWaitGroup.Wait()
 	}()
 }
@@ -469,7 +610,7 @@ func (ch *ClientHandler) clearRequestContexts(contextHoldersMap *sync.Map) {
 		if canceled {
 			typedReqCtx, ok := reqCtx.(*requestContextImpl)
 			if !ok {
-				log.Errorf("Failed to cancel request because request context conversion failed. "+
+				ch.logger().Errorf("Failed to cancel request because request context conversion failed. "+
 					"This is most likely a bug, please report. RequestContext: %v", reqCtx)
 			} else {
 				ch.cancelRequest(reqCtxHolder, typedReqCtx)
@@ -482,11 +623,15 @@ func (ch *ClientHandler) clearRequestContexts(contextHoldersMap *sync.Map) {
 // Infinite loop that blocks on receiving from both cluster connector event channels.
 //
 // Event messages that come through will only be routed if
-//   - it's a schema change from origin
+//   - the client registered for the event type
+//   - the event comes from the cluster that ZDM_EVENT_FORWARDING selects for the event type (by default schema changes
+//     from origin, topology and status changes from target)
+//   - it's not a topology or status change while virtualization is enabled, the client must only see the proxy instances
 func (ch *ClientHandler) listenForEventMessages() {
 	ch.localClientHandlerWg.Add(1)
-	log.Debugf("listenForEventMessages loop starting now")
+	ch.logger().Debugf("listenForEventMessages loop starting now")
 	go func() {
+		defer crash.HandlePanic()
 		defer ch.localClientHandlerWg.Done()
 		defer close(ch.eventsDoneChan)
 		shutDownChannels := 0
@@ -505,7 +650,7 @@ func (ch *ClientHandler) listenForEventMessages() {
 			select {
 			case event, ok = <-targetChannel:
 				if !ok {
-					log.Debugf("Target event channel closed")
+					ch.logger().Debugf("Target event channel closed")
 					shutDownChannels++
 					targetChannel = nil
 					continue
@@ -513,57 +658,77 @@ func (ch *ClientHandler) listenForEventMessages() {
 				fromTarget = true
 			case event, ok = <-originChannel:
 				if !ok {
-					log.Debugf("Origin event channel closed")
+					ch.logger().Debugf("Origin event channel closed")
 					shutDownChannels++
 					originChannel = nil
 					continue
 				}
 				fromTarget = false
+			case event = <-ch.proxyEventsChan:
+				ch.logger().Debugf("Sending proxy generated event to the client: %v", event.Header)
+				ch.clientConnector.sendResponseToClient(event)
+				continue
 			}
 
-			log.Debugf("Message received (fromTarget: %v) on event listener of the client handler: %v", fromTarget, event.Header)
+			ch.logger().Debugf("Message received (fromTarget: %v) on event listener of the client handler: %v", fromTarget, event.Header)
 
 			body, err := defaultCodec.DecodeBody(event.Header, bytes.NewReader(event.Body))
 			if err != nil {
-				log.Warnf("Error decoding event response: %v", err)
+				ch.logger().Warnf("Error decoding event response: %v", err)
 				continue
 			}
 
+			var eventType primitive.EventType
 			switch msgType := body.Message.(type) {
 			case *message.ProtocolError:
-				log.Debug("Received protocol error on event body listener, forwarding to client: ", body.Message)
+				ch.logger().Debug("Received protocol error on event body listener, forwarding to client: ", body.Message)
 			case *message.SchemaChangeEvent:
-				if fromTarget {
-					log.Infof("Received schema change event from target, skipping: %v", msgType)
-					continue
-				}
+				eventType = primitive.EventTypeSchemaChange
 			case *message.StatusChangeEvent:
-				if ch.topologyConfig.VirtualizationEnabled {
-					log.Infof("Received status change event (fromTarget=%v) but virtualization is enabled, skipping: %v", fromTarget, msgType)
+				if !fromTarget && ch.conf.OriginIsZdmProxy {
+					ch.logger().Infof("Received status change event from origin but origin is a ZDM proxy, skipping: %v", msgType)
 					continue
 				}
-				if !fromTarget {
-					log.Infof("Received status change event from origin, skipping: %v", msgType)
+				if ch.topologyConfig.VirtualizationEnabled {
+					ch.logger().Infof("Received status change event (fromTarget=%v) but virtualization is enabled, skipping: %v", fromTarget, msgType)
 					continue
 				}
+				eventType = primitive.EventTypeStatusChange
 			case *message.TopologyChangeEvent:
-				if ch.topologyConfig.VirtualizationEnabled {
-					log.Infof("Received topology change event (fromTarget=%v) but virtualization is enabled, skipping: %v", fromTarget, msgType)
+				if !fromTarget && ch.conf.OriginIsZdmProxy {
+					ch.logger().Infof("Received topology change event from origin but origin is a ZDM proxy, skipping: %v", msgType)
 					continue
 				}
-				if !fromTarget {
-					log.Infof("Received topology change event from origin, skipping: %v", msgType)
+				if ch.topologyConfig.VirtualizationEnabled {
+					ch.logger().Infof("Received topology change event (fromTarget=%v) but virtualization is enabled, skipping: %v", fromTarget, msgType)
 					continue
 				}
+				eventType = primitive.EventTypeTopologyChange
 			default:
-				log.Infof("Expected event body (fromTarget: %v) but got: %v", fromTarget, msgType)
+				ch.logger().Infof("Expected event body (fromTarget: %v) but got: %v", fromTarget, msgType)
 				continue
 			}
 
+			if eventType != "" {
+				clusterType := common.ClusterTypeOrigin
+				if fromTarget {
+					clusterType = common.ClusterTypeTarget
+				}
+				if !ch.isRegisteredForEvent(eventType) {
+					ch.logger().Infof("Received %v event from %v but the client didn't register for it, skipping: %v",
+						eventType, clusterType, body.Message)
+					continue
+				}
+				if !ch.eventForwarding.Forward(eventType, clusterType) {
+					ch.logger().Infof("Received %v event from %v, skipping: %v", eventType, clusterType, body.Message)
+					continue
+				}
+			}
+
 			ch.clientConnector.sendResponseToClient(event)
 		}
 
-		log.Debugf("Shutting down client event messages listener.")
+		ch.logger().Debugf("Shutting down client event messages listener.")
 	}()
 }
 
@@ -571,8 +736,9 @@ func (ch *ClientHandler) listenForEventMessages() {
 // (which is written by both cluster connectors).
 func (ch *ClientHandler) responseLoop() {
 	ch.localClientHandlerWg.Add(1)
-	log.Debugf("responseLoop starting now")
+	ch.logger().Debugf("responseLoop starting now")
 	go func() {
+		defer crash.HandlePanic()
 		defer ch.localClientHandlerWg.Done()
 		defer close(ch.responsesDoneChan)
 
@@ -618,7 +784,7 @@ func (ch *ClientHandler) responseLoop() {
 				reqCtx := holder.Get()
 				if reqCtx == nil {
 					if ch.clientHandlerContext.Err() == nil {
-						log.Warnf("Could not find request context for stream id %d received from %v. "+
+						ch.logger().Warnf("Could not find request context for stream id %d received from %v. "+
 							"It either timed out or a protocol error occurred.", streamId, response.connectorType)
 					}
 					return
@@ -628,16 +794,19 @@ func (ch *ClientHandler) responseLoop() {
 				if response.responseFrame == nil {
 					finished = reqCtx.SetTimeout(ch.nodeMetrics, response.requestFrame)
 				} else {
-					finished = reqCtx.SetResponse(ch.nodeMetrics, response.responseFrame, responseClusterType, response.connectorType)
+					if !ch.handleSecondaryWriteRetry(reqCtx, response.responseFrame, responseClusterType) {
+						finished = reqCtx.SetResponse(ch.nodeMetrics, response.responseFrame, responseClusterType, response.connectorType)
+					}
 					if reqCtx.GetRequestInfo().ShouldBeTrackedInMetrics() {
 						trackClusterErrorMetrics(response.responseFrame, response.connectorType, ch.nodeMetrics)
 					}
 				}
+				ch.reportNodeFailure(response)
 
 				if finished {
 					typedReqCtx, ok := reqCtx.(*requestContextImpl)
 					if !ok {
-						log.Errorf("Failed to finish request because request context conversion failed. "+
+						ch.logger().Errorf("Failed to finish request because request context conversion failed. "+
 							"This is most likely a bug, please report. RequestContext: %v", reqCtx)
 					} else {
 						ch.finishRequest(holder, typedReqCtx)
@@ -646,25 +815,100 @@ func (ch *ClientHandler) responseLoop() {
 			})
 		}
 
-		log.Debugf("Shutting down responseLoop.")
+		ch.logger().Debugf("Shutting down responseLoop.")
 	}()
 }
 
+// handleSecondaryWriteRetry schedules a retry of the secondary request of a write if needed, see SecondaryWriteRetry.
+// Returns true if the response was consumed and must not be set on the request context.
+func (ch *ClientHandler) handleSecondaryWriteRetry(
+	reqCtx RequestContext, response *frame.RawFrame, clusterType common.ClusterType) bool {
+	if ch.secondaryWriteRetry == nil {
+		return false
+	}
+	typedReqCtx, ok := reqCtx.(*requestContextImpl)
+	if !ok {
+		return false
+	}
+	retry, consumed, secondaryFailure := typedReqCtx.checkSecondaryWriteRetry(response, clusterType, ch.secondaryWriteRetry)
+	if secondaryFailure != nil {
+		connectorType := ClusterConnectorTypeTarget
+		if typedReqCtx.secondaryCluster == common.ClusterTypeOrigin {
+			connectorType = ClusterConnectorTypeOrigin
+		}
+		typedReqCtx.SetResponse(ch.nodeMetrics, secondaryFailure, typedReqCtx.secondaryCluster, connectorType)
+	}
+	if retry == 0 {
+		return consumed
+	}
+
+	connector := ch.targetCassandraConnector
+	if typedReqCtx.secondaryCluster == common.ClusterTypeOrigin {
+		connector = ch.originCassandraConnector
+	}
+	backoff := ch.secondaryWriteRetry.getBackoff(retry)
+	ch.logger().Debugf("Write %v failed on %v, retrying in %v (retry %d of %d).",
+		typedReqCtx.id, typedReqCtx.secondaryCluster, backoff, retry, ch.secondaryWriteRetry.attempts)
+	ch.metricHandler.GetProxyMetrics().SecondaryWriteRetries.Add(1)
+	time.AfterFunc(backoff, func() {
+		if !typedReqCtx.isPending() {
+			return
+		}
+		if err := connector.sendRequestToCluster(typedReqCtx.secondaryRequest); err != nil {
+			ch.logger().Warnf("Could not retry write %v on %v: %v", typedReqCtx.id, typedReqCtx.secondaryCluster, err)
+		}
+	})
+	return consumed
+}
+
+// reportNodeFailure adds a failure to the health score of the node that the request was sent to if the response is
+// a timeout or an error caused by the node.
+func (ch *ClientHandler) reportNodeFailure(response *Response) {
+	controlConn, host := ch.originControlConn, ch.originHost
+	clusterType := common.ClusterTypeOrigin
+	switch response.connectorType {
+	case ClusterConnectorTypeTarget:
+		clusterType = common.ClusterTypeTarget
+	case ClusterConnectorTypeAsync:
+		if ch.primaryCluster == common.ClusterTypeOrigin {
+			clusterType = common.ClusterTypeTarget
+		}
+	}
+	if clusterType == common.ClusterTypeTarget {
+		controlConn, host = ch.targetControlConn, ch.targetHost
+	}
+	if host == nil || !controlConn.IsNodeHealthEnabled() {
+		return
+	}
+
+	if response.responseFrame == nil {
+		controlConn.ReportNodeFailure(host, NodeFailureTimeout)
+		return
+	}
+	if response.responseFrame.Header.OpCode != primitive.OpCodeError {
+		return
+	}
+	errMsg, err := decodeError(response.responseFrame)
+	if err == nil && errMsg != nil && isNodeFailureErrorCode(errMsg.GetErrorCode()) {
+		controlConn.ReportNodeFailure(host, NodeFailureError)
+	}
+}
+
 // Checks if response is a protocol error. Returns true if it processes this response. If it returns false,
 // then the response wasn't processed and it should be processed by another function.
 func (ch *ClientHandler) tryProcessProtocolError(response *Response, protocolErrOccurred *int32) bool {
 	errMsg, err := decodeError(response.responseFrame)
 	if err != nil {
-		log.Errorf("Could not check if error from %v was protocol error: %v, skipping it.",
+		ch.logger().Errorf("Could not check if error from %v was protocol error: %v, skipping it.",
 			response.connectorType, response.responseFrame.Header)
 		return false
 	} else if errMsg != nil && errMsg.GetErrorCode() == primitive.ErrorCodeProtocolError {
 		if atomic.CompareAndSwapInt32(protocolErrOccurred, 0, 1) {
 			if ch.handshakeDone.Load() != nil {
-				log.Errorf("[ClientHandler] Protocol error detected (%v) on %v, forwarding it to the client.",
+				ch.logger().Errorf("[ClientHandler] Protocol error detected (%v) on %v, forwarding it to the client.",
 					errMsg, response.connectorType)
 			} else {
-				log.Debugf("[ClientHandler] Protocol version downgrade detected (%v) on %v, forwarding it to the client.",
+				ch.logger().Debugf("[ClientHandler] Protocol version downgrade detected (%v) on %v, forwarding it to the client.",
 					errMsg, response.connectorType)
 			}
 			ch.clientConnector.sendResponseToClient(response.responseFrame)
@@ -701,10 +945,15 @@ func (ch *ClientHandler) finishRequest(holder *requestContextHolder, reqCtx *req
 
 	err := holder.Clear(reqCtx)
 	if err != nil {
-		log.Debugf("Could not free stream id: %v", err)
+		ch.logger().Debugf("Could not free stream id: %v", err)
 	}
+	ch.releaseRequestMemory(reqCtx)
 
-	if reqCtx.requestInfo.ShouldBeTrackedInMetrics() {
+	if reqCtx.monitoring && reqCtx.requestInfo.ShouldBeTrackedInMetrics() {
+		proxyMetrics := ch.metricHandler.GetProxyMetrics()
+		proxyMetrics.ProxyMonitoringDuration.Track(reqCtx.startTime)
+		proxyMetrics.InFlightMonitoring.Subtract(1)
+	} else if reqCtx.requestInfo.ShouldBeTrackedInMetrics() {
 		proxyMetrics := ch.metricHandler.GetProxyMetrics()
 		switch reqCtx.requestInfo.GetForwardDecision() {
 		case forwardToBoth:
@@ -718,10 +967,14 @@ func (ch *ClientHandler) finishRequest(holder *requestContextHolder, reqCtx *req
 			proxyMetrics.InFlightReadsTarget.Subtract(1)
 		case forwardToAsyncOnly, forwardToNone:
 		default:
-			log.Errorf("unexpected forwardDecision %v, unable to track proxy level metrics", reqCtx.requestInfo.GetForwardDecision())
+			ch.logger().Errorf("unexpected forwardDecision %v, unable to track proxy level metrics", reqCtx.requestInfo.GetForwardDecision())
 		}
 	}
 
+	if reqCtx.sample != nil {
+		ch.requestSampler.Record(reqCtx.sample.complete(reqCtx))
+	}
+
 	aggregatedResponse, responseClusterType, err := ch.computeClientResponse(reqCtx)
 	finalResponse := aggregatedResponse
 	if err == nil && reqCtx.requestInfo.GetForwardDecision() != forwardToAsyncOnly {
@@ -732,10 +985,17 @@ func (ch *ClientHandler) finishRequest(holder *requestContextHolder, reqCtx *req
 	if err != nil {
 		if reqCtx.customResponseChannel != nil {
 			close(reqCtx.customResponseChannel)
+		} else if reqCtx.state == RequestTimedOut && ch.conf.ProxyRequestTimeoutErrors {
+			ch.sendRequestTimeoutToClient(reqCtx)
 		}
-		log.Errorf("Error handling request (%v): %v", reqCtx.request.Header, err)
+		ch.logger().Errorf("Error handling request %v (%v): %v", reqCtx.id, reqCtx.request.Header, err)
 		return
 	}
+	ch.trackPrimaryOnlyWrite(reqCtx, aggregatedResponse)
+
+	if reqCtx.recording != nil && reqCtx.state == RequestDone {
+		ch.trafficRecorder.Record(reqCtx.recording.complete(reqCtx, finalResponse))
+	}
 
 	reqCtx.request = nil
 	originResponse := reqCtx.originResponse
@@ -743,6 +1003,10 @@ func (ch *ClientHandler) finishRequest(holder *requestContextHolder, reqCtx *req
 	targetResponse := reqCtx.targetResponse
 	reqCtx.targetResponse = nil
 
+	if ch.conf.ProxyLocalHeartbeats && finalResponse.Header.OpCode == primitive.OpCodeSupported {
+		ch.supportedResponse.Store(finalResponse.DeepCopy())
+	}
+
 	if reqCtx.customResponseChannel != nil {
 		reqCtx.customResponseChannel <- &customResponse{
 			originResponse:     originResponse,
@@ -754,16 +1018,73 @@ func (ch *ClientHandler) finishRequest(holder *requestContextHolder, reqCtx *req
 	}
 }
 
+// releaseRequestMemory returns the bytes of a client request to ZDM_PROXY_CLIENT_CONNECTION_MAX_BUFFERED_BYTES once
+// the request is done, timed out or canceled, i.e. whether or not the client gets a response. It must be called
+// before the response is sent, the client can reuse the stream id as soon as it gets the response.
+func (ch *ClientHandler) releaseRequestMemory(reqCtx *requestContextImpl) {
+	if reqCtx.customResponseChannel != nil {
+		return // handshake requests are released by requestLoop, internal requests don't use client memory
+	}
+	ch.clientConnector.memory.releaseRequest(reqCtx.request.Header.StreamId)
+}
+
+// sendRequestTimeoutToClient answers a request that timed out at proxy level with a server side timeout error so that
+// the client doesn't have to wait for its own timeout. Statements get a READ_TIMEOUT or WRITE_TIMEOUT error (that the
+// default retry policies of the drivers don't retry) and other requests get a SERVER_ERROR.
+func (ch *ClientHandler) sendRequestTimeoutToClient(reqCtx *requestContextImpl) {
+	request := reqCtx.request
+	errorMessage := fmt.Sprintf("Request timed out after %v ms at proxy level.", ch.conf.ProxyRequestTimeoutMs)
+	var msg message.Message = &message.ServerError{ErrorMessage: errorMessage}
+	body, err := defaultCodec.DecodeBody(request.Header, bytes.NewReader(request.Body))
+	if err != nil {
+		ch.logger().Warnf("Could not decode request %v to build the timeout error, returning a server error: %v",
+			reqCtx.id, err)
+	} else {
+		isWrite := reqCtx.requestInfo.GetForwardDecision() == forwardToBoth
+		switch typedMsg := body.Message.(type) {
+		case *message.Batch:
+			msg = &message.WriteTimeout{ErrorMessage: errorMessage, Consistency: typedMsg.Consistency,
+				BlockFor: 1, WriteType: primitive.WriteTypeBatch}
+		case *message.Query:
+			msg = newStatementTimeoutMessage(errorMessage, typedMsg.Options, isWrite)
+		case *message.Execute:
+			msg = newStatementTimeoutMessage(errorMessage, typedMsg.Options, isWrite)
+		}
+	}
+	response := frame.NewFrame(request.Header.Version, request.Header.StreamId, msg)
+	rawResponse, err := defaultCodec.ConvertToRawFrame(response)
+	if err != nil {
+		ch.logger().Errorf("Could not convert frame (%v) to raw frame: %v", response, err)
+		return
+	}
+	ch.clientConnector.sendResponseToClient(rawResponse)
+}
+
+func newStatementTimeoutMessage(errorMessage string, options *message.QueryOptions, isWrite bool) message.Message {
+	consistency := primitive.ConsistencyLevelOne
+	if options != nil {
+		consistency = options.Consistency
+	}
+	if isWrite {
+		return &message.WriteTimeout{ErrorMessage: errorMessage, Consistency: consistency,
+			BlockFor: 1, WriteType: primitive.WriteTypeSimple}
+	}
+	return &message.ReadTimeout{ErrorMessage: errorMessage, Consistency: consistency, BlockFor: 1}
+}
+
 // should only be called after Cancel returns true
 func (ch *ClientHandler) cancelRequest(holder *requestContextHolder, reqCtx *requestContextImpl) {
 	defer ch.clientHandlerRequestWaitGroup.Done()
 
 	err := holder.Clear(reqCtx)
 	if err != nil {
-		log.Debugf("Could not free stream id: %v", err)
+		ch.logger().Debugf("Could not free stream id: %v", err)
 	}
+	ch.releaseRequestMemory(reqCtx)
 
-	if reqCtx.requestInfo.ShouldBeTrackedInMetrics() {
+	if reqCtx.monitoring && reqCtx.requestInfo.ShouldBeTrackedInMetrics() {
+		ch.metricHandler.GetProxyMetrics().InFlightMonitoring.Subtract(1)
+	} else if reqCtx.requestInfo.ShouldBeTrackedInMetrics() {
 		proxyMetrics := ch.metricHandler.GetProxyMetrics()
 		switch reqCtx.requestInfo.GetForwardDecision() {
 		case forwardToBoth:
@@ -774,7 +1095,7 @@ func (ch *ClientHandler) cancelRequest(holder *requestContextHolder, reqCtx *req
 			proxyMetrics.InFlightReadsTarget.Subtract(1)
 		case forwardToAsyncOnly, forwardToNone:
 		default:
-			log.Errorf("unexpected forwardDecision %v, unable to track proxy level metrics", reqCtx.requestInfo.GetForwardDecision())
+			ch.logger().Errorf("unexpected forwardDecision %v, unable to track proxy level metrics", reqCtx.requestInfo.GetForwardDecision())
 		}
 	}
 
@@ -782,7 +1103,7 @@ func (ch *ClientHandler) cancelRequest(holder *requestContextHolder, reqCtx *req
 		close(reqCtx.customResponseChannel)
 	}
 
-	log.Tracef("Canceled request %v.", reqCtx.request.Header)
+	ch.logger().Tracef("Canceled request %v.", reqCtx.request.Header)
 }
 
 // Computes the response to be sent to the client based on the forward decision of the request.
@@ -795,10 +1116,11 @@ func (ch *ClientHandler) computeClientResponse(requestContext *requestContextImp
 				"did not receive response from origin cassandra channel, stream: %d",
 				requestContext.request.Header.StreamId)
 		}
-		log.Tracef("Forward to origin: just returning the response received from %v: %d",
+		ch.logger().Tracef("Forward to origin: just returning the response received from %v: %d",
 			common.ClusterTypeOrigin, requestContext.originResponse.Header.OpCode)
 
-		if requestContext.requestInfo.ShouldBeTrackedInMetrics() && !isResponseSuccessful(requestContext.originResponse) {
+		if requestContext.requestInfo.ShouldBeTrackedInMetrics() && !requestContext.monitoring &&
+			!isResponseSuccessful(requestContext.originResponse) {
 			ch.metricHandler.GetProxyMetrics().FailedReadsOrigin.Add(1)
 		}
 		return requestContext.originResponse, common.ClusterTypeOrigin, nil
@@ -808,10 +1130,11 @@ func (ch *ClientHandler) computeClientResponse(requestContext *requestContextImp
 				"did not receive response from target cassandra channel, stream: %d",
 				requestContext.request.Header.StreamId)
 		}
-		log.Tracef("Forward to target: just returning the response received from %v: %d",
+		ch.logger().Tracef("Forward to target: just returning the response received from %v: %d",
 			common.ClusterTypeTarget, requestContext.targetResponse.Header.OpCode)
 
-		if requestContext.requestInfo.ShouldBeTrackedInMetrics() && !isResponseSuccessful(requestContext.targetResponse) {
+		if requestContext.requestInfo.ShouldBeTrackedInMetrics() && !requestContext.monitoring &&
+			!isResponseSuccessful(requestContext.targetResponse) {
 			ch.metricHandler.GetProxyMetrics().FailedReadsTarget.Add(1)
 		}
 		return requestContext.targetResponse, common.ClusterTypeTarget, nil
@@ -828,6 +1151,18 @@ func (ch *ClientHandler) computeClientResponse(requestContext *requestContextImp
 		}
 		aggregatedResponse, responseClusterType := ch.aggregateAndTrackResponses(
 			requestContext.requestInfo, requestContext.request, requestContext.originResponse, requestContext.targetResponse)
+		aggregatedResponse, err := ch.addSchemaChangeWarnings(
+			aggregatedResponse, responseClusterType, requestContext.originResponse, requestContext.targetResponse)
+		if err != nil {
+			return nil, common.ClusterTypeNone, err
+		}
+		if requestContext.conditional {
+			aggregatedResponse, err = ch.addConditionalWriteWarnings(
+				aggregatedResponse, responseClusterType, requestContext.originResponse, requestContext.targetResponse)
+			if err != nil {
+				return nil, common.ClusterTypeNone, err
+			}
+		}
 		return aggregatedResponse, responseClusterType, nil
 	case forwardToAsyncOnly:
 		switch ch.asyncConnector.clusterType {
@@ -837,7 +1172,7 @@ func (ch *ClientHandler) computeClientResponse(requestContext *requestContextImp
 					"did not receive response from async target cassandra channel, stream: %d",
 					requestContext.request.Header.StreamId)
 			}
-			log.Tracef("Forward to async: just returning the response received from %v: %d",
+			ch.logger().Tracef("Forward to async: just returning the response received from %v: %d",
 				common.ClusterTypeTarget, requestContext.targetResponse.Header.OpCode)
 			return requestContext.targetResponse, common.ClusterTypeTarget, nil
 		case common.ClusterTypeOrigin:
@@ -846,11 +1181,11 @@ func (ch *ClientHandler) computeClientResponse(requestContext *requestContextImp
 					"did not receive response from async origin cassandra channel, stream: %d",
 					requestContext.request.Header.StreamId)
 			}
-			log.Tracef("Forward to async: just returning the response received from %v: %d",
+			ch.logger().Tracef("Forward to async: just returning the response received from %v: %d",
 				common.ClusterTypeOrigin, requestContext.originResponse.Header.OpCode)
 			return requestContext.originResponse, common.ClusterTypeOrigin, nil
 		default:
-			log.Errorf("Unknown cluster type: %v. This is a bug, please report.", ch.asyncConnector.clusterType)
+			ch.logger().Errorf("Unknown cluster type: %v. This is a bug, please report.", ch.asyncConnector.clusterType)
 			return nil, common.ClusterTypeNone, fmt.Errorf("unknown cluster type: %v; this is a bug, please report", ch.asyncConnector.clusterType)
 		}
 	case forwardToNone:
@@ -883,7 +1218,7 @@ func (ch *ClientHandler) processClientResponse(
 			}
 		case *message.SetKeyspaceResult:
 			if bodyMsg.Keyspace == "" {
-				log.Warnf("unexpected set keyspace empty")
+				ch.logger().Warnf("unexpected set keyspace empty")
 			} else {
 				ch.StoreCurrentKeyspace(bodyMsg.Keyspace)
 			}
@@ -911,7 +1246,7 @@ func (ch *ClientHandler) processClientResponse(
 			}
 			newFrame.Body.Message = newUnprepared
 
-			log.Infof("Received UNPREPARED from %v, generating UNPREPARED response with prepared ID %s. "+
+			ch.logger().Infof("Received UNPREPARED from %v, generating UNPREPARED response with prepared ID %s. "+
 				"Prepared ID in response from %v: %v. Original error: %v",
 				responseClusterType, hex.EncodeToString(unpreparedId),
 				responseClusterType, hex.EncodeToString(bodyMsg.Id), bodyMsg.ErrorMessage)
@@ -1063,6 +1398,12 @@ type startHandshakeResult struct {
 // When the Origin handshake ends, this function blocks, waiting until Target handshake is done.
 // This ensures that the client connection is Ready only when both Cluster Connector connections are ready.
 func (ch *ClientHandler) handleHandshakeRequest(request *frame.RawFrame, wg *sync.WaitGroup) (bool, error) {
+	if request.Header.OpCode == primitive.OpCodeStartup {
+		if protocolErr, err := ch.setCompression(request); err != nil || protocolErr != nil {
+			return false, err
+		}
+	}
+
 	scheduledTaskChannel := make(chan *handshakeRequestResult, 1)
 	wg.Add(1)
 	ch.requestResponseScheduler.Schedule(func() {
@@ -1096,7 +1437,7 @@ func (ch *ClientHandler) handleHandshakeRequest(request *frame.RawFrame, wg *syn
 		}
 
 		responseChan := make(chan *customResponse, 1)
-		err := ch.forwardRequest(request, responseChan)
+		_, err := ch.forwardRequest(request, responseChan)
 		if err != nil {
 			scheduledTaskChannel <- &handshakeRequestResult{
 				authSuccess: false,
@@ -1193,7 +1534,7 @@ func (ch *ClientHandler) handleHandshakeRequest(request *frame.RawFrame, wg *syn
 			if ch.asyncConnector != nil {
 				asyncConnectorHandshakeChannel, err = ch.startSecondaryHandshake(true)
 				if err != nil {
-					log.Errorf("Error occured in async connector (%v) handshake: %v. "+
+					ch.logger().Errorf("Error occured in async connector (%v) handshake: %v. "+
 						"Async requests will not be forwarded.", ch.asyncConnector.clusterType, err.Error())
 					ch.asyncConnector.Shutdown()
 					asyncConnectorHandshakeChannel = nil
@@ -1242,7 +1583,7 @@ func (ch *ClientHandler) handleHandshakeRequest(request *frame.RawFrame, wg *syn
 		}
 		if handshakeInitiated {
 			if errAsync != nil {
-				log.Errorf("Async connector (%v) handshake failed, async requests will not be forwarded: %s",
+				ch.logger().Errorf("Async connector (%v) handshake failed, async requests will not be forwarded: %s",
 					ch.asyncConnector.clusterType, errAsync.Error())
 				ch.asyncConnector.Shutdown()
 			}
@@ -1256,7 +1597,7 @@ func (ch *ClientHandler) handleHandshakeRequest(request *frame.RawFrame, wg *syn
 					return
 				}
 
-				log.Errorf("Secondary (%v) handshake failed (client: %v), shutting down the client handler and connectors: %s",
+				ch.logger().Errorf("Secondary (%v) handshake failed (client: %v), shutting down the client handler and connectors: %s",
 					secondaryClusterType, ch.clientConnector.connection.RemoteAddr().String(), errSecondary.Error())
 				ch.clientHandlerCancelFunc()
 				tempResult.err = fmt.Errorf("handshake failed: %w", ShutdownErr)
@@ -1282,11 +1623,39 @@ func (ch *ClientHandler) handleHandshakeRequest(request *frame.RawFrame, wg *syn
 	return result.authSuccess, result.err
 }
 
+// setCompression enables the frame body compression requested by the STARTUP request on the client connection and on
+// the cluster connections. If the compression is not supported a protocol error is sent to the client (like Cassandra
+// does) so that the driver can retry without compression.
+func (ch *ClientHandler) setCompression(startupRequest *frame.RawFrame) (*message.ProtocolError, error) {
+	decodedFrame, err := defaultCodec.ConvertFromRawFrame(startupRequest)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode STARTUP request: %w", err)
+	}
+	startup, ok := decodedFrame.Body.Message.(*message.Startup)
+	if !ok {
+		return nil, fmt.Errorf("expected STARTUP but got %v", decodedFrame.Body.Message)
+	}
+	protocolErr := ch.compression.set(startup)
+	if protocolErr != nil {
+		ch.logger().Warnf("Client requested compression %v which is not supported, returning a protocol error.",
+			startup.GetCompression())
+		response, err := generateProtocolErrorResponseFrame(
+			startupRequest.Header.StreamId, startupRequest.Header.Version, protocolErr)
+		if err != nil {
+			return protocolErr, fmt.Errorf("could not generate protocol error response raw frame (%v): %w", protocolErr, err)
+		}
+		ch.clientConnector.sendResponseToClient(response)
+	} else if compression := ch.compression.get(); compression != primitive.CompressionNone {
+		ch.logger().Debugf("Client connection uses %v compression.", compression)
+	}
+	return protocolErr, nil
+}
+
 // Builds auth error response and sends it to the client.
 func (ch *ClientHandler) sendAuthErrorToClient(requestFrame *frame.RawFrame, secondaryClusterType common.ClusterType) error {
 	authErrorResponse, err := ch.buildAuthErrorResponse(requestFrame, ch.authErrorMessage)
 	if err == nil {
-		log.Warnf("Secondary (%v) handshake failed with an auth error, returning %v to client.", secondaryClusterType, ch.authErrorMessage)
+		ch.logger().Warnf("Secondary (%v) handshake failed with an auth error, returning %v to client.", secondaryClusterType, ch.authErrorMessage)
 		ch.clientConnector.sendResponseToClient(authErrorResponse)
 		return nil
 	} else {
@@ -1298,10 +1667,6 @@ func (ch *ClientHandler) sendAuthErrorToClient(requestFrame *frame.RawFrame, sec
 func (ch *ClientHandler) buildAuthErrorResponse(
 	requestFrame *frame.RawFrame, authenticationError *message.AuthenticationError) (*frame.RawFrame, error) {
 	f := frame.NewFrame(requestFrame.Header.Version, requestFrame.Header.StreamId, authenticationError)
-	if requestFrame.Header.Flags.Contains(primitive.HeaderFlagCompressed) {
-		f.SetCompress(true)
-	}
-
 	return defaultCodec.ConvertToRawFrame(f)
 }
 
@@ -1328,6 +1693,7 @@ func (ch *ClientHandler) startSecondaryHandshake(asyncConnector bool) (chan erro
 	channel := make(chan error)
 	ch.clientHandlerRequestWaitGroup.Add(1)
 	go func() {
+		defer crash.HandlePanic()
 		defer ch.clientHandlerRequestWaitGroup.Done()
 		defer close(channel)
 		var err error
@@ -1340,19 +1706,280 @@ func (ch *ClientHandler) startSecondaryHandshake(asyncConnector bool) (chan erro
 // Handles a request, see the docs for the forwardRequest() function, as handleRequest is pretty much a wrapper
 // around forwardRequest.
 func (ch *ClientHandler) handleRequest(f *frame.RawFrame) {
-	err := ch.forwardRequest(f, nil)
+	inFlight, err := ch.forwardRequest(f, nil)
+	if !inFlight {
+		// the request was answered by the proxy or rejected, requests in flight are released by their request context
+		ch.clientConnector.memory.releaseRequest(f.Header.StreamId)
+	}
 
 	if err != nil {
-		log.Warnf("error sending request with opcode %02x and streamid %d: %s", f.Header.OpCode, f.Header.StreamId, err.Error())
+		ch.logger().Warnf("error sending request with opcode %02x and streamid %d: %s", f.Header.OpCode, f.Header.StreamId, err.Error())
 		return
 	}
 }
 
+// trackEventRegistration records the event types that the client registered for so that cluster events of other types
+// are not forwarded and so that the proxy can send its own topology events to this client when virtualization is
+// enabled.
+func (ch *ClientHandler) trackEventRegistration(frameContext *frameDecodeContext) {
+	decodedFrame, err := frameContext.GetOrDecodeFrame()
+	if err != nil {
+		ch.logger().Warnf("Could not decode REGISTER request: %v", err)
+		return
+	}
+	registerMsg, ok := decodedFrame.Body.Message.(*message.Register)
+	if !ok {
+		return
+	}
+	registeredEvents := map[primitive.EventType]bool{}
+	for _, eventType := range registerMsg.EventTypes {
+		registeredEvents[eventType] = true
+		if eventType == primitive.EventTypeTopologyChange {
+			ch.topologyEventsProtoVer.Store(decodedFrame.Header.Version)
+		}
+	}
+	ch.registeredEvents.Store(registeredEvents)
+}
+
+const (
+	monitoringStateUndecided = int32(iota)
+	monitoringStateMonitoring
+	monitoringStateRegular
+)
+
+// trackMonitoringConnection detects monitoring connections, i.e. connections that register for events (usually the
+// control connection of a driver) and otherwise only send heartbeats and queries of system tables. Their reads have
+// their own metrics (type "monitoring") and are not rejected by ZDM_PROXY_MAX_IN_FLIGHT_REQUESTS so that they don't
+// skew the limits and the latencies of the application requests. A connection that sends any other request is a
+// regular connection for good.
+func (ch *ClientHandler) trackMonitoringConnection(frameContext *frameDecodeContext, currentKeyspace string) {
+	if atomic.LoadInt32(&ch.monitoringState) == monitoringStateRegular {
+		return
+	}
+	switch frameContext.GetRawFrame().Header.OpCode {
+	case primitive.OpCodeStartup, primitive.OpCodeAuthResponse, primitive.OpCodeOptions:
+		return
+	case primitive.OpCodeRegister:
+		if atomic.CompareAndSwapInt32(&ch.monitoringState, monitoringStateUndecided, monitoringStateMonitoring) {
+			ch.clientConnector.monitoring.Store(true)
+			ch.logger().Debugf("Client connection registered for events, tracking it as a monitoring connection.")
+		}
+		return
+	case primitive.OpCodeQuery:
+		stmtQueryData, err := frameContext.GetOrInspectStatement(currentKeyspace, ch.timeUuidGenerator)
+		if err == nil && isSystemQuery(stmtQueryData.queryData) {
+			return
+		}
+	}
+	if atomic.SwapInt32(&ch.monitoringState, monitoringStateRegular) == monitoringStateMonitoring {
+		ch.clientConnector.monitoring.Store(false)
+		ch.logger().Debugf("Client connection sent a request that is not a system query, " +
+			"it is no longer tracked as a monitoring connection.")
+	}
+}
+
+func (ch *ClientHandler) isMonitoringConnection() bool {
+	return atomic.LoadInt32(&ch.monitoringState) == monitoringStateMonitoring
+}
+
+func (ch *ClientHandler) isRegisteredForEvent(eventType primitive.EventType) bool {
+	registeredEvents, ok := ch.registeredEvents.Load().(map[primitive.EventType]bool)
+	return ok && registeredEvents[eventType]
+}
+
+// handleLocalHeartbeat answers an OPTIONS request with the SUPPORTED response that the clusters returned to a previous
+// OPTIONS request of this client. Returns false if ZDM_PROXY_LOCAL_HEARTBEATS is disabled or if the client didn't
+// send an OPTIONS request yet, the request has to be forwarded in that case.
+func (ch *ClientHandler) handleLocalHeartbeat(request *frame.RawFrame, customResponseChannel chan *customResponse) bool {
+	if !ch.conf.ProxyLocalHeartbeats {
+		return false
+	}
+	supported, ok := ch.supportedResponse.Load().(*frame.RawFrame)
+	if !ok {
+		return false
+	}
+	response := supported.DeepCopy()
+	response.Header.Version = request.Header.Version
+	response.Header.StreamId = request.Header.StreamId
+	ch.logger().Tracef("Answering OPTIONS request %v with the cached SUPPORTED response", request.Header)
+	if customResponseChannel != nil {
+		customResponseChannel <- &customResponse{aggregatedResponse: response}
+	} else {
+		ch.clientConnector.sendResponseToClient(response)
+	}
+	return true
+}
+
+// checkWriteTimestampFloor looks for an explicit timestamp older than ZDM_WRITE_TIMESTAMP_FLOOR in a write. Returns
+// true if the write was rejected (ZDM_WRITE_TIMESTAMP_FLOOR_MODE is REJECT), an INVALID error was sent to the client in
+// that case. Otherwise the first stale write of the client connection is logged.
+func (ch *ClientHandler) checkWriteTimestampFloor(
+	frameContext *frameDecodeContext, requestInfo RequestInfo, currentKeyspace string,
+	customResponseChannel chan *customResponse) bool {
+	timestamp, found, err := ch.writeTimestampFloor.findTimestampBelowFloor(
+		frameContext, requestInfo, currentKeyspace, ch.timeUuidGenerator)
+	if err != nil {
+		ch.logger().Warnf("Could not check the timestamps of write %v against ZDM_WRITE_TIMESTAMP_FLOOR: %v",
+			frameContext.GetRawFrame().Header, err)
+		return false
+	}
+	if !found {
+		return false
+	}
+	ch.metricHandler.GetProxyMetrics().StaleTimestampWrites.Add(1)
+
+	if !ch.writeTimestampFloor.shouldReject() {
+		if atomic.CompareAndSwapInt32(&ch.staleTimestampWarned, 0, 1) {
+			ch.logger().Warnf("Client %v sent a write with timestamp %v which is older than ZDM_WRITE_TIMESTAMP_FLOOR "+
+				"(%v), the data loaded into %v may shadow it. Other writes of this client connection that are older "+
+				"than the floor are only counted in the metrics.", ch.clientIp, timestamp,
+				ch.writeTimestampFloor.floor.Format(time.RFC3339), common.ClusterTypeTarget)
+		}
+		return false
+	}
+
+	ch.sendInvalidResponse(frameContext.GetRawFrame(), fmt.Sprintf(
+		"Write timestamp %v is older than the write timestamp floor of the proxy (%v).",
+		timestamp, ch.writeTimestampFloor.floor.Format(time.RFC3339)), customResponseChannel)
+	return true
+}
+
+// checkConditionalWrite applies ZDM_LWT_MODE to a write sent to both clusters. Returns the request info to use for the
+// write, whether it is a conditional write and whether it was rejected (ZDM_LWT_MODE is REJECT), an INVALID error was
+// sent to the client in that case.
+func (ch *ClientHandler) checkConditionalWrite(
+	frameContext *frameDecodeContext, requestInfo RequestInfo, currentKeyspace string,
+	customResponseChannel chan *customResponse) (RequestInfo, bool, bool) {
+	conditional, err := isConditionalWrite(frameContext, requestInfo, currentKeyspace, ch.timeUuidGenerator)
+	if err != nil {
+		ch.logger().Warnf("Could not check whether write %v is a conditional write: %v",
+			frameContext.GetRawFrame().Header, err)
+		return requestInfo, false, false
+	}
+	if !conditional {
+		return requestInfo, false, false
+	}
+	ch.metricHandler.GetProxyMetrics().ConditionalWrites.Add(1)
+
+	switch ch.lwtMode {
+	case common.LwtModeReject:
+		ch.sendInvalidResponse(frameContext.GetRawFrame(),
+			"Conditional writes (lightweight transactions) are rejected by the proxy during the migration.",
+			customResponseChannel)
+		return requestInfo, true, true
+	case common.LwtModePrimaryOnly:
+		return withPrimaryOnlyForwardDecision(requestInfo, ch.primaryCluster), true, false
+	default:
+		return requestInfo, true, false
+	}
+}
+
+// checkCounterWrite applies ZDM_COUNTER_WRITE_MODE to a write sent to both clusters. Returns the request info to use
+// for the write and whether it is a counter update.
+func (ch *ClientHandler) checkCounterWrite(
+	frameContext *frameDecodeContext, requestInfo RequestInfo, currentKeyspace string) (RequestInfo, bool) {
+	counterWrite, err := isCounterWrite(frameContext, requestInfo, currentKeyspace, ch.timeUuidGenerator)
+	if err != nil {
+		ch.logger().Warnf("Could not check whether write %v is a counter update: %v",
+			frameContext.GetRawFrame().Header, err)
+		return requestInfo, false
+	}
+	if !counterWrite {
+		return requestInfo, false
+	}
+	ch.metricHandler.GetProxyMetrics().CounterWrites.Add(1)
+
+	if ch.counterWriteMode != common.CounterWriteModePrimaryOnly {
+		return requestInfo, true
+	}
+	return withPrimaryOnlyForwardDecision(requestInfo, ch.primaryCluster), true
+}
+
+// trackPrimaryOnlyWrite is called by finishRequest with the response of a write that was forwarded to the primary
+// cluster only (see ZDM_LWT_MODE and ZDM_COUNTER_WRITE_MODE), the write is only counted as a single sided write if it was acknowledged: writes
+// that failed, timed out or were not applied didn't change the data of the primary cluster either.
+func (ch *ClientHandler) trackPrimaryOnlyWrite(reqCtx *requestContextImpl, response *frame.RawFrame) {
+	if reqCtx.primaryOnlyWrite == "" || reqCtx.state != RequestDone || !isResponseSuccessful(response) {
+		return
+	}
+	if reqCtx.primaryOnlyConditional {
+		applied, found, err := getAppliedFlag(response)
+		if err != nil {
+			ch.logger().Warnf("Could not check whether conditional write %v was applied: %v", reqCtx.id, err)
+			return
+		}
+		if !found || !applied {
+			return
+		}
+	}
+	ch.trackSingleSidedWrite(ch.primaryCluster, reqCtx.primaryOnlyWrite)
+}
+
+// trackSingleSidedWrite counts a write that is acknowledged to the client although it is only written to the given
+// cluster, the data of the two clusters differs after it until it is repaired or migrated again. The first one of the
+// client connection is logged, the others are only counted in proxy_single_sided_writes_total.
+func (ch *ClientHandler) trackSingleSidedWrite(writtenTo common.ClusterType, reason string) {
+	ch.singleSidedWrites.add(writtenTo)
+	proxyMetrics := ch.metricHandler.GetProxyMetrics()
+	if writtenTo == common.ClusterTypeTarget {
+		proxyMetrics.SingleSidedWritesTarget.Add(1)
+	} else {
+		proxyMetrics.SingleSidedWritesOrigin.Add(1)
+	}
+	if atomic.CompareAndSwapInt32(&ch.singleSidedWriteWarned, 0, 1) {
+		ch.logger().Warnf("Client %v got a write acknowledged that was only written to %v (%v), the data of the two "+
+			"clusters now differs until it is repaired or migrated again. Other single sided writes of this client "+
+			"connection are only counted in the proxy_single_sided_writes_total metric.", ch.clientIp, writtenTo, reason)
+	}
+}
+
+// sendInvalidResponse answers a request with an INVALID error without forwarding it to the clusters.
+func (ch *ClientHandler) sendInvalidResponse(
+	request *frame.RawFrame, errorMessage string, customResponseChannel chan *customResponse) {
+	response := frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.Invalid{
+		ErrorMessage: errorMessage,
+	})
+	rawResponse, err := defaultCodec.ConvertToRawFrame(response)
+	if err != nil {
+		ch.logger().Errorf("Could not convert frame (%v) to raw frame: %v", response, err)
+		return
+	}
+	if customResponseChannel != nil {
+		customResponseChannel <- &customResponse{aggregatedResponse: rawResponse}
+	} else {
+		ch.clientConnector.sendResponseToClient(rawResponse)
+	}
+}
+
+// sendTopologyChangeEvent sends a TOPOLOGY_CHANGE event about a proxy instance to the client if it registered for them.
+func (ch *ClientHandler) sendTopologyChangeEvent(changeType primitive.TopologyChangeType, addr net.IP, port int) {
+	protoVer, ok := ch.topologyEventsProtoVer.Load().(primitive.ProtocolVersion)
+	if !ok {
+		return
+	}
+	event := frame.NewFrame(protoVer, -1, &message.TopologyChangeEvent{
+		ChangeType: changeType,
+		Address:    &primitive.Inet{Addr: addr, Port: int32(port)},
+	})
+	rawEvent, err := defaultCodec.ConvertToRawFrame(event)
+	if err != nil {
+		ch.logger().Errorf("Could not convert frame (%v) to raw frame: %v", event, err)
+		return
+	}
+	select {
+	case ch.proxyEventsChan <- rawEvent:
+	default:
+		ch.logger().Warnf("Discarding %v because the proxy events queue of client %v is full.",
+			event.Body.Message, ch.clientConnector.connection.RemoteAddr())
+	}
+}
+
 // Forwards the request, parsing it and enqueuing it to the appropriate cluster connector(s)' write queue(s).
-func (ch *ClientHandler) forwardRequest(request *frame.RawFrame, customResponseChannel chan *customResponse) error {
+// Returns true if the request is in flight, see executeRequest.
+func (ch *ClientHandler) forwardRequest(request *frame.RawFrame, customResponseChannel chan *customResponse) (bool, error) {
 	overallRequestStartTime := time.Now()
 
-	log.Tracef("Request frame: %v", request)
+	ch.logger().Tracef("Request frame: %v", request)
 
 	currentKeyspace := ch.LoadCurrentKeyspace()
 	context := NewFrameDecodeContext(request)
@@ -1363,44 +1990,73 @@ func (ch *ClientHandler) forwardRequest(request *frame.RawFrame, customResponseC
 	}
 
 	if err != nil {
-		return err
+		return false, err
 	}
+	if request.Header.OpCode == primitive.OpCodeRegister {
+		ch.trackEventRegistration(context)
+	}
+	ch.trackMonitoringConnection(context, currentKeyspace)
+	if request.Header.OpCode == primitive.OpCodeOptions && ch.handleLocalHeartbeat(request, customResponseChannel) {
+		return false, nil
+	}
+
 	requestInfo, err := buildRequestInfo(
 		context, replacedTerms, ch.preparedStatementCache, ch.metricHandler, currentKeyspace, ch.primaryCluster,
-		ch.forwardSystemQueriesToTarget, ch.topologyConfig.VirtualizationEnabled, ch.forwardAuthToTarget, ch.timeUuidGenerator)
+		ch.readCluster, ch.tableReadRouting, ch.readShift, ch.forwardSystemQueriesToTarget, ch.topologyConfig.VirtualizationEnabled, ch.forwardAuthToTarget, ch.timeUuidGenerator)
 	if err != nil {
 		if errVal, ok := err.(*UnpreparedExecuteError); ok {
 			unpreparedFrame, err := createUnpreparedFrame(errVal)
 			if err != nil {
-				return err
+				return false, err
 			}
-			log.Debugf(
+			ch.logger().Debugf(
 				"PS Cache miss, created unprepared response with version %v, streamId %v and preparedId %s",
 				errVal.Header.Version, errVal.Header.StreamId, errVal.preparedId)
 
 			// send it back to client
 			ch.clientConnector.sendResponseToClient(unpreparedFrame)
-			log.Debugf("Unprepared Response sent, exiting handleRequest now")
-			return nil
+			ch.logger().Debugf("Unprepared Response sent, exiting handleRequest now")
+			return false, nil
 		}
-		return err
+		return false, err
 	}
 
-	requestTimeout := time.Duration(ch.conf.ProxyRequestTimeoutMs) * time.Millisecond
-	err = ch.executeRequest(context, requestInfo, currentKeyspace, overallRequestStartTime, customResponseChannel, requestTimeout)
-	if err != nil {
-		return err
+	requestInfo = ch.skipTargetUse(context, requestInfo, currentKeyspace)
+
+	if ch.writeTimestampFloor != nil && requestInfo.GetForwardDecision() == forwardToBoth &&
+		ch.checkWriteTimestampFloor(context, requestInfo, currentKeyspace, customResponseChannel) {
+		return false, nil
 	}
-	return nil
+
+	conditionalWrite, counterWrite := false, false
+	if requestInfo.GetForwardDecision() == forwardToBoth && requestInfo.ShouldBeTrackedInMetrics() {
+		var rejected bool
+		requestInfo, conditionalWrite, rejected = ch.checkConditionalWrite(
+			context, requestInfo, currentKeyspace, customResponseChannel)
+		if rejected {
+			return false, nil
+		}
+		if !conditionalWrite { // counters can't be updated by conditional writes
+			requestInfo, counterWrite = ch.checkCounterWrite(context, requestInfo, currentKeyspace)
+		}
+	}
+
+	requestTimeout := time.Duration(ch.conf.ProxyRequestTimeoutMs) * time.Millisecond
+	return ch.executeRequest(context, requestInfo, currentKeyspace, conditionalWrite, counterWrite,
+		overallRequestStartTime, customResponseChannel, requestTimeout)
 }
 
 // executeRequest executes the forward decision and waits for one or two responses, then returns the response
 // that should be sent back to the client.
+//
+// Returns true if a request context was created for the request, the request is completed by finishRequest or
+// cancelRequest in that case (even if an error is returned).
 func (ch *ClientHandler) executeRequest(
-	frameContext *frameDecodeContext, requestInfo RequestInfo, currentKeyspace string,
-	overallRequestStartTime time.Time, customResponseChannel chan *customResponse, requestTimeout time.Duration) error {
+	frameContext *frameDecodeContext, requestInfo RequestInfo, currentKeyspace string, conditionalWrite bool,
+	counterWrite bool, overallRequestStartTime time.Time, customResponseChannel chan *customResponse,
+	requestTimeout time.Duration) (bool, error) {
 	fwdDecision := requestInfo.GetForwardDecision()
-	log.Tracef("Opcode: %v, Forward decision: %v", frameContext.GetRawFrame().Header.OpCode, fwdDecision)
+	ch.logger().Tracef("Opcode: %v, Forward decision: %v", frameContext.GetRawFrame().Header.OpCode, fwdDecision)
 
 	f := frameContext.GetRawFrame()
 	originRequest := f
@@ -1420,12 +2076,20 @@ func (ch *ClientHandler) executeRequest(
 	}
 
 	if err != nil {
-		return err
+		return false, err
+	}
+
+	if targetRequest != nil && ch.isSentToTarget(requestInfo) && (ch.conf.TargetQualifyTableNames ||
+		protocolSupportsKeyspaceInRequest(targetRequest.Header.Version)) {
+		targetRequest, err = ch.qualifyTargetRequest(frameContext, targetRequest, currentKeyspace)
+		if err != nil {
+			return false, err
+		}
 	}
 
 	if fwdDecision == forwardToNone {
 		if clientResponse == nil {
-			return fmt.Errorf("forwardDecision is NONE but client response is nil")
+			return false, fmt.Errorf("forwardDecision is NONE but client response is nil")
 		}
 
 		if customResponseChannel != nil {
@@ -1434,10 +2098,48 @@ func (ch *ClientHandler) executeRequest(
 			ch.clientConnector.sendResponseToClient(clientResponse)
 		}
 
-		return nil
+		return false, nil
 	}
 
 	reqCtx := NewRequestContext(f, requestInfo, overallRequestStartTime, customResponseChannel)
+	reqCtx.monitoring = (fwdDecision == forwardToOrigin || fwdDecision == forwardToTarget) && ch.isMonitoringConnection()
+	reqCtx.conditional = conditionalWrite && fwdDecision == forwardToBoth
+	if conditionalWrite && ch.lwtMode == common.LwtModePrimaryOnly {
+		reqCtx.primaryOnlyWrite = fmt.Sprintf(
+			"conditional write forwarded to the primary cluster only, ZDM_LWT_MODE is %v", ch.lwtMode)
+		reqCtx.primaryOnlyConditional = true
+	} else if counterWrite && ch.counterWriteMode == common.CounterWriteModePrimaryOnly {
+		reqCtx.primaryOnlyWrite = fmt.Sprintf(
+			"counter update forwarded to the primary cluster only, ZDM_COUNTER_WRITE_MODE is %v", ch.counterWriteMode)
+	}
+	if ch.conf.ProxyRequestIdPayloadKey != "" {
+		originRequest, targetRequest, err = ch.addRequestIdPayloads(reqCtx.id, originRequest, targetRequest)
+		if err != nil {
+			return false, err
+		}
+	}
+	// counter updates are not retried, the retried update could also be applied by the failed attempt
+	if ch.secondaryWriteRetry != nil && fwdDecision == forwardToBoth && requestInfo.ShouldBeTrackedInMetrics() &&
+		!counterWrite {
+		reqCtx.secondaryCluster = common.ClusterTypeTarget
+		reqCtx.secondaryRequest = targetRequest
+		if ch.primaryCluster == common.ClusterTypeTarget {
+			reqCtx.secondaryCluster = common.ClusterTypeOrigin
+			reqCtx.secondaryRequest = originRequest
+		}
+	}
+	if requestInfo.ShouldBeTrackedInMetrics() && fwdDecision != forwardToAsyncOnly {
+		err := recordRequestConsistency(ch.consistencyStats, frameContext, requestInfo, currentKeyspace, ch.timeUuidGenerator)
+		if err != nil {
+			ch.logger().Debugf("Could not record the consistency level of the request: %v", err)
+		}
+	}
+	if requestInfo.ShouldBeTrackedInMetrics() && ch.requestSampler.ShouldSample() {
+		reqCtx.sample = newRequestSample(frameContext, requestInfo, currentKeyspace, ch.timeUuidGenerator, overallRequestStartTime)
+	}
+	if customResponseChannel == nil && fwdDecision != forwardToAsyncOnly && ch.trafficRecorder.ShouldRecord(f.Header.OpCode) {
+		reqCtx.recording = newRecordedExchange(ch.recordingConnection, f)
+	}
 	var contextHoldersMap *sync.Map
 	if fwdDecision == forwardToAsyncOnly {
 		contextHoldersMap = ch.asyncRequestContextHolders // different map because of stream id collision
@@ -1446,10 +2148,12 @@ func (ch *ClientHandler) executeRequest(
 	}
 	holder, err := storeRequestContext(contextHoldersMap, reqCtx)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	if requestInfo.ShouldBeTrackedInMetrics() {
+	if reqCtx.monitoring && requestInfo.ShouldBeTrackedInMetrics() {
+		ch.metricHandler.GetProxyMetrics().InFlightMonitoring.Add(1)
+	} else if requestInfo.ShouldBeTrackedInMetrics() {
 		proxyMetrics := ch.metricHandler.GetProxyMetrics()
 		switch fwdDecision {
 		case forwardToBoth:
@@ -1460,7 +2164,7 @@ func (ch *ClientHandler) executeRequest(
 			proxyMetrics.InFlightReadsTarget.Add(1)
 		case forwardToAsyncOnly:
 		default:
-			log.Errorf("unexpected forwardDecision %v, unable to track proxy level metrics", fwdDecision)
+			ch.logger().Errorf("unexpected forwardDecision %v, unable to track proxy level metrics", fwdDecision)
 		}
 	}
 
@@ -1476,7 +2180,7 @@ func (ch *ClientHandler) executeRequest(
 				}
 				return
 			}
-			ch.respChannel <- NewTimeoutResponse(f, false)
+			ch.sendToResponseChannel(NewTimeoutResponse(f, false))
 		})
 		reqCtx.SetTimer(timer)
 	}
@@ -1488,10 +2192,12 @@ func (ch *ClientHandler) executeRequest(
 		startupFrameVersion = startupFrameInterface.(*frame.RawFrame).Header.Version
 	}
 
-	sendAlsoToAsync := requestInfo.ShouldAlsoBeSentAsync() && ch.asyncConnector != nil
+	// continuation pages are not mirrored because the paging state was generated by the primary cluster
+	sendAlsoToAsync := requestInfo.ShouldAlsoBeSentAsync() && ch.asyncConnector != nil &&
+		!isContinuationPageRequest(frameContext) && ch.isAsyncReadEnabled(f, fwdDecision)
 	switch fwdDecision {
 	case forwardToBoth:
-		log.Tracef("Forwarding request with opcode %v for stream %v to %v and %v",
+		ch.logger().Tracef("Forwarding request with opcode %v for stream %v to %v and %v",
 			f.Header.OpCode, f.Header.StreamId, common.ClusterTypeOrigin, common.ClusterTypeTarget)
 		sendErr := ch.originCassandraConnector.sendRequestToCluster(originRequest)
 		if sendErr != nil {
@@ -1500,7 +2206,7 @@ func (ch *ClientHandler) executeRequest(
 			ch.targetCassandraConnector.sendRequestToCluster(targetRequest)
 		}
 	case forwardToOrigin:
-		log.Tracef("Forwarding request with opcode %v for stream %v to %v",
+		ch.logger().Tracef("Forwarding request with opcode %v for stream %v to %v",
 			f.Header.OpCode, f.Header.StreamId, common.ClusterTypeOrigin)
 		sendErr := ch.originCassandraConnector.sendRequestToCluster(originRequest)
 		if sendErr != nil {
@@ -1508,7 +2214,7 @@ func (ch *ClientHandler) executeRequest(
 		}
 		ch.targetCassandraConnector.sendHeartbeat(startupFrameVersion, ch.conf.HeartbeatIntervalMs)
 	case forwardToTarget:
-		log.Tracef("Forwarding request with opcode %v for stream %v to %v",
+		ch.logger().Tracef("Forwarding request with opcode %v for stream %v to %v",
 			f.Header.OpCode, f.Header.StreamId, common.ClusterTypeTarget)
 		sendErr := ch.targetCassandraConnector.sendRequestToCluster(targetRequest)
 		if sendErr != nil {
@@ -1517,11 +2223,11 @@ func (ch *ClientHandler) executeRequest(
 		ch.originCassandraConnector.sendHeartbeat(startupFrameVersion, ch.conf.HeartbeatIntervalMs)
 	case forwardToAsyncOnly:
 	default:
-		return fmt.Errorf("unknown forward decision %v, stream: %d", fwdDecision, f.Header.StreamId)
+		return true, fmt.Errorf("unknown forward decision %v, stream: %d", fwdDecision, f.Header.StreamId)
 	}
 
 	if !sendAlsoToAsync && fwdDecision != forwardToAsyncOnly {
-		return nil
+		return true, nil
 	}
 
 	// from this point onwards, the request is meant to be sent to async connector
@@ -1529,11 +2235,111 @@ func (ch *ClientHandler) executeRequest(
 	// or a request that ONLY needs to be sent to the async connector (and a response is expected, i.e. not fire and forget)
 	// like async connector handshake requests
 
-	return ch.sendToAsyncConnector(
+	return true, ch.sendToAsyncConnector(
 		frameContext, originRequest, targetRequest, fwdDecision, reqCtx, holder, sendAlsoToAsync,
 		overallRequestStartTime, requestTimeout)
 }
 
+// isAsyncReadEnabled returns false if the request is a read and async reads are not enabled for this client
+// connection (see ZDM_FEATURE_FLAGS). Other requests that are sent to the async connector (e.g. USE) are not affected
+// because the async connection must stay in sync with the other connections.
+func (ch *ClientHandler) isAsyncReadEnabled(f *frame.RawFrame, fwdDecision forwardDecision) bool {
+	isRead := (f.Header.OpCode == primitive.OpCodeQuery || f.Header.OpCode == primitive.OpCodeExecute) &&
+		(fwdDecision == forwardToOrigin || fwdDecision == forwardToTarget)
+	return !isRead || ch.featureFlags.IsEnabled(common.FeatureAsyncReads, ch.clientIp)
+}
+
+// isSentToTarget returns true if the request will be sent to the target cluster, either synchronously
+// or through the async connector.
+func (ch *ClientHandler) isSentToTarget(requestInfo RequestInfo) bool {
+	switch requestInfo.GetForwardDecision() {
+	case forwardToBoth, forwardToTarget:
+		return true
+	case forwardToOrigin:
+		return requestInfo.ShouldAlsoBeSentAsync() &&
+			ch.asyncConnector != nil && ch.asyncConnector.clusterType == common.ClusterTypeTarget
+	default:
+		return false
+	}
+}
+
+// qualifyTargetRequest makes the target request independent of the USE state of the client connection.
+//
+// With protocol versions that support it, the keyspace flag of the request is set to the current keyspace, this is
+// always done because USE statements are not sent to target with these versions (see skipTargetUse).
+// With older versions and ZDM_TARGET_QUALIFY_TABLE_NAMES, unqualified table names are prefixed with the keyspace that
+// applies to the request.
+func (ch *ClientHandler) qualifyTargetRequest(
+	frameContext *frameDecodeContext, targetRequest *frame.RawFrame, currentKeyspace string) (*frame.RawFrame, error) {
+	if protocolSupportsKeyspaceInRequest(targetRequest.Header.Version) {
+		keyspaceRequest, err := ch.queryModifier.addKeyspaceToRequest(targetRequest, currentKeyspace)
+		if err != nil {
+			return nil, fmt.Errorf("could not add keyspace to target request: %w", err)
+		}
+		return keyspaceRequest, nil
+	}
+
+	statementsQueryData, err := frameContext.GetOrInspectAllStatements(currentKeyspace, ch.timeUuidGenerator)
+	if err != nil {
+		if errors.Is(err, NotInspectableErr) {
+			return targetRequest, nil
+		}
+		return nil, fmt.Errorf("could not inspect request to qualify table names: %w", err)
+	}
+
+	qualifiedRequest, err := ch.queryModifier.qualifyTableNames(targetRequest, statementsQueryData)
+	if err != nil {
+		return nil, fmt.Errorf("could not qualify table names of target request: %w", err)
+	}
+	return qualifiedRequest, nil
+}
+
+// skipTargetUse returns the request info of a USE statement that is not sent to target if the protocol version
+// supports the keyspace flag: every request sent to target carries the keyspace of the client connection in that case
+// (see qualifyTargetRequest) so the target connections don't depend on the USE state and can't get out of sync with
+// it. With older versions USE is still sent to both clusters because only some statements can be rewritten.
+func (ch *ClientHandler) skipTargetUse(
+	frameContext *frameDecodeContext, requestInfo RequestInfo, currentKeyspace string) RequestInfo {
+	f := frameContext.GetRawFrame()
+	if f.Header.OpCode != primitive.OpCodeQuery || !protocolSupportsKeyspaceInRequest(f.Header.Version) {
+		return requestInfo
+	}
+	if _, ok := requestInfo.(*GenericRequestInfo); !ok || requestInfo.GetForwardDecision() != forwardToBoth {
+		return requestInfo
+	}
+	stmtQueryData, err := frameContext.GetOrInspectStatement(currentKeyspace, ch.timeUuidGenerator)
+	if err != nil || stmtQueryData.queryData.getStatementType() != statementTypeUse {
+		return requestInfo
+	}
+	// the async connector still needs the USE statement if it is connected to origin
+	sendAlsoToAsync := ch.asyncConnector != nil && ch.asyncConnector.clusterType == common.ClusterTypeOrigin
+	return NewGenericRequestInfo(forwardToOrigin, sendAlsoToAsync, requestInfo.ShouldBeTrackedInMetrics())
+}
+
+// addRequestIdPayloads adds the request id to the custom payload of the requests that are sent to origin and target
+// (ZDM_PROXY_REQUEST_ID_PAYLOAD_KEY). Both requests are often the same frame, it is only modified once in that case.
+func (ch *ClientHandler) addRequestIdPayloads(
+	id RequestId, originRequest *frame.RawFrame, targetRequest *frame.RawFrame) (*frame.RawFrame, *frame.RawFrame, error) {
+	sameRequest := originRequest == targetRequest
+	var err error
+	if originRequest != nil {
+		originRequest, err = addRequestIdPayload(originRequest, ch.conf.ProxyRequestIdPayloadKey, id)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if sameRequest {
+		return originRequest, originRequest, nil
+	}
+	if targetRequest != nil {
+		targetRequest, err = addRequestIdPayload(targetRequest, ch.conf.ProxyRequestIdPayloadKey, id)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return originRequest, targetRequest, nil
+}
+
 func (ch *ClientHandler) handleRequestSendFailure(err error, frameContext *frameDecodeContext) {
 	if strings.Contains(err.Error(), "no stream id available") {
 		ch.clientConnector.sendOverloadedToClient(frameContext.frame)
@@ -1542,7 +2348,7 @@ func (ch *ClientHandler) handleRequestSendFailure(err error, frameContext *frame
 		responseFrame, err := generateProtocolErrorResponseFrame(
 			frameContext.frame.Header.StreamId, frameContext.frame.Header.Version, responseMessage)
 		if err != nil {
-			log.Errorf("could not generate protocol error response raw frame (%v): %v", responseMessage, err)
+			ch.logger().Errorf("could not generate protocol error response raw frame (%v): %v", responseMessage, err)
 		} else {
 			ch.clientConnector.sendResponseToClient(responseFrame)
 		}
@@ -1577,7 +2383,7 @@ func (ch *ClientHandler) handleInterceptedRequest(
 	} else {
 		controlConn = ch.originControlConn
 	}
-	virtualHosts, err := controlConn.GetVirtualHosts()
+	virtualHosts, localVirtualHostIndex, err := controlConn.GetVirtualHostsAndLocalIndex()
 	if err != nil {
 		return nil, err
 	}
@@ -1596,13 +2402,13 @@ func (ch *ClientHandler) handleInterceptedRequest(
 		}
 		interceptedQueryResponse, err = NewSystemPeersResult(prepareRequestInfo, currentKeyspace,
 			typeCodec, f.Header.Version, controlConn.GetSystemPeersColumnNames(), controlConn.GetSystemLocalColumnData(),
-			parsedSelectClause, virtualHosts, controlConn.GetLocalVirtualHostIndex(), ch.conf.ProxyListenPort)
+			parsedSelectClause, virtualHosts, localVirtualHostIndex, ch.conf.ProxyListenPort)
 	case local:
 		parsedSelectClause := interceptedRequestInfo.GetParsedSelectClause()
 		if parsedSelectClause == nil {
 			return nil, fmt.Errorf("unable to intercept system.local query (prepared=%v) because parsed select clause is nil", prepared)
 		}
-		localVirtualHost := virtualHosts[controlConn.GetLocalVirtualHostIndex()]
+		localVirtualHost := virtualHosts[localVirtualHostIndex]
 		interceptedQueryResponse, err = NewSystemLocalResult(prepareRequestInfo, currentKeyspace,
 			typeCodec, f.Header.Version, controlConn.GetSystemLocalColumnData(), parsedSelectClause,
 			localVirtualHost, ch.conf.ProxyListenPort)
@@ -1734,7 +2540,7 @@ func (ch *ClientHandler) handleExecuteRequest(
 
 		originalQueryId := newTargetExecuteMsg.QueryId
 		newTargetExecuteMsg.QueryId = preparedData.GetTargetPreparedId()
-		log.Tracef("Replacing prepared ID %s with %s for target cluster.",
+		ch.logger().Tracef("Replacing prepared ID %s with %s for target cluster.",
 			hex.EncodeToString(originalQueryId), hex.EncodeToString(newTargetExecuteMsg.QueryId))
 
 		newTargetRequestRaw, err := defaultCodec.ConvertToRawFrame(newTargetRequest)
@@ -1792,7 +2598,7 @@ func (ch *ClientHandler) handleBatchRequest(
 
 		originalQueryId := newTargetBatchMsg.Children[stmtIdx].Id
 		newTargetBatchMsg.Children[stmtIdx].Id = preparedData.GetTargetPreparedId()
-		log.Tracef("Replacing prepared ID %s within a BATCH with %s for target cluster.",
+		ch.logger().Tracef("Replacing prepared ID %s within a BATCH with %s for target cluster.",
 			hex.EncodeToString(originalQueryId), hex.EncodeToString(preparedData.GetTargetPreparedId()))
 	}
 
@@ -1859,7 +2665,7 @@ func (ch *ClientHandler) sendToAsyncConnector(
 						ch.finishRequest(holder, reqCtx)
 					}
 				} else {
-					ch.respChannel <- NewTimeoutResponse(f, true)
+					ch.sendToResponseChannel(NewTimeoutResponse(f, true))
 				}
 			} else {
 				ch.clientHandlerRequestWaitGroup.Done()
@@ -1881,7 +2687,8 @@ func (ch *ClientHandler) sendToAsyncConnector(
 
 // Aggregates the responses received from the two clusters as follows:
 //   - if both responses are a success OR both responses are a failure: return responseFromOC
-//   - if either response is a failure, the failure "wins": return the failed response
+//   - if either response is a failure, the failure "wins": return the failed response, except for writes that only
+//     fail on the cluster that ZDM_DUAL_WRITE_FAILURE_MODE ignores, these return the successful response
 //
 // Also updates metrics appropriately.
 func (ch *ClientHandler) aggregateAndTrackResponses(
@@ -1891,13 +2698,13 @@ func (ch *ClientHandler) aggregateAndTrackResponses(
 	responseFromTargetCassandra *frame.RawFrame) (*frame.RawFrame, common.ClusterType) {
 
 	originOpCode := responseFromOriginCassandra.Header.OpCode
-	log.Tracef("Aggregating responses. %v opcode %d, %v opcode %d",
+	ch.logger().Tracef("Aggregating responses. %v opcode %d, %v opcode %d",
 		common.ClusterTypeOrigin, originOpCode, common.ClusterTypeTarget, responseFromTargetCassandra.Header.OpCode)
 
 	// aggregate responses and update relevant aggregate metrics for general failed or successful responses
 	if isResponseSuccessful(responseFromOriginCassandra) && isResponseSuccessful(responseFromTargetCassandra) {
 		if originOpCode == primitive.OpCodeSupported {
-			log.Tracef("Aggregated response: both successes, sending back %v response with opcode %d",
+			ch.logger().Tracef("Aggregated response: both successes, sending back %v response with opcode %d",
 				common.ClusterTypeTarget, originOpCode)
 			return responseFromTargetCassandra, common.ClusterTypeTarget
 		} else if request.Header.OpCode == primitive.OpCodePrepare {
@@ -1905,11 +2712,11 @@ func (ch *ClientHandler) aggregateAndTrackResponses(
 			return responseFromOriginCassandra, common.ClusterTypeOrigin
 		} else {
 			if ch.primaryCluster == common.ClusterTypeTarget {
-				log.Tracef("Aggregated response: both successes, sending back %v response with opcode %d",
+				ch.logger().Tracef("Aggregated response: both successes, sending back %v response with opcode %d",
 					common.ClusterTypeTarget, responseFromTargetCassandra.Header.OpCode)
 				return responseFromTargetCassandra, common.ClusterTypeTarget
 			} else {
-				log.Tracef("Aggregated response: both successes, sending back %v response with opcode %d",
+				ch.logger().Tracef("Aggregated response: both successes, sending back %v response with opcode %d",
 					common.ClusterTypeOrigin, originOpCode)
 				return responseFromOriginCassandra, common.ClusterTypeOrigin
 			}
@@ -1918,7 +2725,7 @@ func (ch *ClientHandler) aggregateAndTrackResponses(
 
 	proxyMetrics := ch.metricHandler.GetProxyMetrics()
 	if !isResponseSuccessful(responseFromOriginCassandra) && !isResponseSuccessful(responseFromTargetCassandra) {
-		log.Debugf("Aggregated response: both failures, sending back %v response with opcode %d",
+		ch.logger().Debugf("Aggregated response: both failures, sending back %v response with opcode %d",
 			common.ClusterTypeOrigin, originOpCode)
 		if requestInfo.ShouldBeTrackedInMetrics() {
 			proxyMetrics.FailedWritesOnBoth.Add(1)
@@ -1927,23 +2734,136 @@ func (ch *ClientHandler) aggregateAndTrackResponses(
 	}
 
 	// if either response is a failure, the failure "wins" --> return the failed response
+	// unless ZDM_DUAL_WRITE_FAILURE_MODE says that the write only fails on the other cluster
 	if !isResponseSuccessful(responseFromOriginCassandra) {
-		log.Debugf("Aggregated response: failure only on %v, sending back %v response with opcode %d",
-			common.ClusterTypeOrigin, common.ClusterTypeOrigin, originOpCode)
 		if requestInfo.ShouldBeTrackedInMetrics() {
 			proxyMetrics.FailedWritesOnOrigin.Add(1)
+			if ch.dualWriteFailureMode == common.DualWriteFailureModeTarget {
+				ch.trackSingleSidedWrite(common.ClusterTypeTarget, fmt.Sprintf(
+					"failed on %v, ZDM_DUAL_WRITE_FAILURE_MODE is %v", common.ClusterTypeOrigin, ch.dualWriteFailureMode))
+				ch.logger().Debugf("Aggregated response: failure only on %v, ignored because of the dual write "+
+					"failure mode %v, sending back %v response with opcode %d",
+					common.ClusterTypeOrigin, ch.dualWriteFailureMode, common.ClusterTypeTarget,
+					responseFromTargetCassandra.Header.OpCode)
+				return responseFromTargetCassandra, common.ClusterTypeTarget
+			}
 		}
+		ch.logger().Debugf("Aggregated response: failure only on %v, sending back %v response with opcode %d",
+			common.ClusterTypeOrigin, common.ClusterTypeOrigin, originOpCode)
 		return responseFromOriginCassandra, common.ClusterTypeOrigin
 	} else {
-		log.Debugf("Aggregated response: failure only on %v, sending back %v response with opcode %d",
-			common.ClusterTypeTarget, common.ClusterTypeTarget, originOpCode)
 		if requestInfo.ShouldBeTrackedInMetrics() {
 			proxyMetrics.FailedWritesOnTarget.Add(1)
+			if ch.dualWriteFailureMode == common.DualWriteFailureModeOrigin {
+				ch.trackSingleSidedWrite(common.ClusterTypeOrigin, fmt.Sprintf(
+					"failed on %v, ZDM_DUAL_WRITE_FAILURE_MODE is %v", common.ClusterTypeTarget, ch.dualWriteFailureMode))
+				ch.logger().Debugf("Aggregated response: failure only on %v, ignored because of the dual write "+
+					"failure mode %v, sending back %v response with opcode %d",
+					common.ClusterTypeTarget, ch.dualWriteFailureMode, common.ClusterTypeOrigin, originOpCode)
+				return responseFromOriginCassandra, common.ClusterTypeOrigin
+			}
 		}
+		ch.logger().Debugf("Aggregated response: failure only on %v, sending back %v response with opcode %d",
+			common.ClusterTypeTarget, common.ClusterTypeTarget, originOpCode)
 		return responseFromTargetCassandra, common.ClusterTypeTarget
 	}
 }
 
+// Adds the outcome of a schema change on the cluster whose response is not returned to the client as warnings of the
+// aggregated response:
+//   - if the schema change succeeded on both clusters, the warnings of the other cluster are added
+//   - if the schema change failed on only one cluster, the returned error gets a warning saying that the schema change
+//     was applied on the other cluster so the schemas of the two clusters may now differ
+//
+// Warnings are only supported by protocol v4 and later, older protocol versions get the aggregated response as is.
+func (ch *ClientHandler) addSchemaChangeWarnings(
+	aggregatedResponse *frame.RawFrame,
+	aggregatedClusterType common.ClusterType,
+	responseFromOriginCassandra *frame.RawFrame,
+	responseFromTargetCassandra *frame.RawFrame) (*frame.RawFrame, error) {
+
+	if aggregatedResponse.Header.Version < primitive.ProtocolVersion4 {
+		return aggregatedResponse, nil
+	}
+	if !isSchemaChangeResult(responseFromOriginCassandra) && !isSchemaChangeResult(responseFromTargetCassandra) {
+		return aggregatedResponse, nil
+	}
+
+	otherResponse, otherClusterType := responseFromTargetCassandra, common.ClusterTypeTarget
+	if aggregatedClusterType == common.ClusterTypeTarget {
+		otherResponse, otherClusterType = responseFromOriginCassandra, common.ClusterTypeOrigin
+	}
+
+	var warnings []string
+	if isResponseSuccessful(aggregatedResponse) {
+		if !otherResponse.Header.Flags.Contains(primitive.HeaderFlagWarning) {
+			return aggregatedResponse, nil
+		}
+		decodedOtherResponse, err := defaultCodec.ConvertFromRawFrame(otherResponse)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode %v schema change response: %w", otherClusterType, err)
+		}
+		for _, warning := range decodedOtherResponse.Body.Warnings {
+			warnings = append(warnings, fmt.Sprintf("%v: %v", otherClusterType, warning))
+		}
+	} else {
+		warning := fmt.Sprintf("Schema change was applied on %v but failed on %v, "+
+			"the schemas of the two clusters may now differ.", otherClusterType, aggregatedClusterType)
+		ch.logger().Warn(warning)
+		warnings = []string{warning}
+	}
+
+	decodedResponse, err := defaultCodec.ConvertFromRawFrame(aggregatedResponse)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode %v schema change response: %w", aggregatedClusterType, err)
+	}
+	decodedResponse.SetWarnings(append(decodedResponse.Body.Warnings, warnings...))
+	return defaultCodec.ConvertToRawFrame(decodedResponse)
+}
+
+// Compares the [applied] flags of the responses of a conditional write sent to both clusters. If the write was applied
+// on only one cluster, the mismatch is counted in the metrics and a warning is added to the aggregated response
+// (protocol v4 and later), the first mismatch of the client connection is also logged.
+func (ch *ClientHandler) addConditionalWriteWarnings(
+	aggregatedResponse *frame.RawFrame,
+	aggregatedClusterType common.ClusterType,
+	responseFromOriginCassandra *frame.RawFrame,
+	responseFromTargetCassandra *frame.RawFrame) (*frame.RawFrame, error) {
+
+	originApplied, originFound, err := getAppliedFlag(responseFromOriginCassandra)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %v conditional write response: %w", common.ClusterTypeOrigin, err)
+	}
+	targetApplied, targetFound, err := getAppliedFlag(responseFromTargetCassandra)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %v conditional write response: %w", common.ClusterTypeTarget, err)
+	}
+	if !originFound || !targetFound || originApplied == targetApplied {
+		return aggregatedResponse, nil
+	}
+	ch.metricHandler.GetProxyMetrics().ConditionalMismatches.Add(1)
+
+	appliedClusterType, notAppliedClusterType := common.ClusterTypeOrigin, common.ClusterTypeTarget
+	if targetApplied {
+		appliedClusterType, notAppliedClusterType = common.ClusterTypeTarget, common.ClusterTypeOrigin
+	}
+	warning := fmt.Sprintf("Conditional write was applied on %v but not on %v, the data of the two clusters may "+
+		"now differ.", appliedClusterType, notAppliedClusterType)
+	if atomic.CompareAndSwapInt32(&ch.lwtMismatchWarned, 0, 1) {
+		ch.logger().Warnf("%v Other mismatches of this client connection are only counted in the metrics.", warning)
+	}
+
+	if aggregatedResponse.Header.Version < primitive.ProtocolVersion4 {
+		return aggregatedResponse, nil
+	}
+	decodedResponse, err := defaultCodec.ConvertFromRawFrame(aggregatedResponse)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode %v conditional write response: %w", aggregatedClusterType, err)
+	}
+	decodedResponse.SetWarnings(append(decodedResponse.Body.Warnings, warning))
+	return defaultCodec.ConvertToRawFrame(decodedResponse)
+}
+
 // Replaces the credentials in the provided auth frame (which are the Target credentials) with
 // the Origin credentials that are provided to the proxy in the configuration.
 func (ch *ClientHandler) handleClientCredentials(f *frame.RawFrame) (*frame.RawFrame, error) {
@@ -1965,11 +2885,11 @@ func (ch *ClientHandler) handleClientCredentials(f *frame.RawFrame) (*frame.RawF
 	}
 
 	if clientCreds == nil {
-		log.Debugf("Found auth response frame without creds: %v", authResponse)
+		ch.logger().Debugf("Found auth response frame without creds: %v", authResponse)
 		return f, nil
 	}
 
-	log.Debugf("Successfully extracted credentials from client auth frame: %v", clientCreds)
+	ch.logger().Debugf("Successfully extracted credentials from client auth frame: %v", clientCreds)
 
 	var primaryHandshakeCreds *AuthCredentials
 	if ch.forwardAuthToTarget {
@@ -2067,6 +2987,29 @@ func isResponseSuccessful(response *frame.RawFrame) bool {
 	return response.Header.OpCode != primitive.OpCodeError
 }
 
+// Checks whether the provided response is a SCHEMA_CHANGE result without decoding the body unless the result kind is
+// preceded by a tracing id, warnings or a custom payload.
+func isSchemaChangeResult(response *frame.RawFrame) bool {
+	if response.Header.OpCode != primitive.OpCodeResult {
+		return false
+	}
+	flags := response.Header.Flags
+	if flags.Contains(primitive.HeaderFlagTracing) ||
+		flags.Contains(primitive.HeaderFlagWarning) ||
+		flags.Contains(primitive.HeaderFlagCustomPayload) {
+		decodedResponse, err := defaultCodec.ConvertFromRawFrame(response)
+		if err != nil {
+			return false
+		}
+		_, ok := decodedResponse.Body.Message.(*message.SchemaChangeResult)
+		return ok
+	}
+	if len(response.Body) < 4 {
+		return false
+	}
+	return primitive.ResultType(binary.BigEndian.Uint32(response.Body)) == primitive.ResultTypeSchemaChange
+}
+
 func createUnpreparedFrame(errVal *UnpreparedExecuteError) (*frame.RawFrame, error) {
 	unpreparedMsg := &message.Unprepared{
 		ErrorMessage: fmt.Sprintf("Prepared query with ID %s not found (either the query was not prepared "+
@@ -2193,7 +3136,7 @@ func forwardAuthToTarget(
 }
 
 // checkUnsupportedProtocolError handles the case where the protocol library throws an error while decoding the version (maybe the client tries to use v1 or v6)
-func checkUnsupportedProtocolError(err error) *message.ProtocolError {
+func checkUnsupportedProtocolError(err error, maxVersion primitive.ProtocolVersion) *message.ProtocolError {
 	protocolVersionErr := &frame.ProtocolVersionErr{}
 	if errors.As(err, &protocolVersionErr) {
 		var protocolErrMsg *message.ProtocolError
@@ -2202,8 +3145,7 @@ func checkUnsupportedProtocolError(err error) *message.ProtocolError {
 				ErrorMessage: fmt.Sprintf("Beta version of the protocol used (%d/v%d-beta), but USE_BETA flag is unset",
 					protocolVersionErr.Version, protocolVersionErr.Version)}
 		} else {
-			protocolErrMsg = &message.ProtocolError{
-				ErrorMessage: fmt.Sprintf("Invalid or unsupported protocol version (%d)", protocolVersionErr.Version)}
+			protocolErrMsg = newUnsupportedProtocolVersionError(protocolVersionErr.Version, maxVersion)
 		}
 
 		return protocolErrMsg
@@ -2213,15 +3155,42 @@ func checkUnsupportedProtocolError(err error) *message.ProtocolError {
 }
 
 // checkProtocolVersion handles the case where the protocol library does not return an error but the proxy does not support a specific version
-func checkProtocolVersion(version primitive.ProtocolVersion) *message.ProtocolError {
+func checkProtocolVersion(version primitive.ProtocolVersion, maxVersion primitive.ProtocolVersion) *message.ProtocolError {
 	if version < primitive.ProtocolVersion5 || version.IsDse() {
 		return nil
 	}
 
-	protocolErrMsg := &message.ProtocolError{
-		ErrorMessage: fmt.Sprintf("Invalid or unsupported protocol version (%d)", version)}
+	return newUnsupportedProtocolVersionError(version, maxVersion)
+}
 
-	return protocolErrMsg
+// newUnsupportedProtocolVersionError returns the protocol error that Cassandra returns for an unsupported protocol
+// version, it lists the versions supported by the proxy up to maxVersion (the version negotiated with the clusters)
+// so that drivers can downgrade to one of them.
+func newUnsupportedProtocolVersionError(
+	version primitive.ProtocolVersion, maxVersion primitive.ProtocolVersion) *message.ProtocolError {
+	maxOssVersion := maxVersion
+	if maxVersion.IsDse() || maxVersion > primitive.ProtocolVersion4 {
+		maxOssVersion = primitive.ProtocolVersion4
+	}
+	var supportedVersions []string
+	for _, supportedVersion := range []primitive.ProtocolVersion{
+		primitive.ProtocolVersion2, primitive.ProtocolVersion3, primitive.ProtocolVersion4} {
+		if supportedVersion <= maxOssVersion {
+			supportedVersions = append(supportedVersions, fmt.Sprintf("%d/v%d", supportedVersion, supportedVersion))
+		}
+	}
+	if maxVersion.IsDse() {
+		for _, supportedVersion := range []primitive.ProtocolVersion{
+			primitive.ProtocolVersionDse1, primitive.ProtocolVersionDse2} {
+			if supportedVersion <= maxVersion {
+				supportedVersions = append(supportedVersions,
+					fmt.Sprintf("%d/dse-v%d", supportedVersion, supportedVersion-primitive.ProtocolVersionDse1+1))
+			}
+		}
+	}
+	return &message.ProtocolError{ErrorMessage: fmt.Sprintf(
+		"Invalid or unsupported protocol version (%d); supported versions are (%v)",
+		version, strings.Join(supportedVersions, ", "))}
 }
 
 type customResponse struct {
@@ -1,10 +1,19 @@
 package zdmproxy
 
 import (
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
 	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
 	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics/noopmetrics"
+	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestMaxStreamIds(t *testing.T) {
@@ -32,3 +41,289 @@ func TestMaxStreamIds(t *testing.T) {
 		})
 	}
 }
+
+func TestClientHandler_TrackMonitoringConnection(t *testing.T) {
+	newClientHandler := func() *ClientHandler {
+		connLogger := &atomic.Value{}
+		connLogger.Store(log.NewEntry(log.StandardLogger()))
+		monitoring := &atomic.Value{}
+		monitoring.Store(false)
+		return &ClientHandler{
+			clientConnector:   &ClientConnector{monitoring: monitoring},
+			connLogger:        connLogger,
+			timeUuidGenerator: &fakeTimeUuidGenerator{},
+		}
+	}
+	send := func(ch *ClientHandler, msg message.Message) {
+		rawFrame, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 1, msg))
+		require.Nil(t, err)
+		ch.trackMonitoringConnection(NewFrameDecodeContext(rawFrame), "")
+	}
+	register := &message.Register{EventTypes: []primitive.EventType{primitive.EventTypeSchemaChange}}
+	systemQuery := &message.Query{Query: "SELECT * FROM system.peers_v2"}
+	tableQuery := &message.Query{Query: "SELECT * FROM ks.tbl"}
+
+	t.Run("driver control connection", func(t *testing.T) {
+		ch := newClientHandler()
+		send(ch, &message.Startup{})
+		send(ch, register)
+		send(ch, systemQuery)
+		send(ch, &message.Options{})
+		require.True(t, ch.isMonitoringConnection())
+		require.True(t, ch.clientConnector.monitoring.Load().(bool))
+	})
+
+	t.Run("other requests after registering", func(t *testing.T) {
+		ch := newClientHandler()
+		send(ch, register)
+		send(ch, tableQuery)
+		require.False(t, ch.isMonitoringConnection())
+		require.False(t, ch.clientConnector.monitoring.Load().(bool))
+		send(ch, register)
+		require.False(t, ch.isMonitoringConnection())
+	})
+
+	t.Run("other requests before registering", func(t *testing.T) {
+		ch := newClientHandler()
+		send(ch, systemQuery)
+		send(ch, &message.Prepare{Query: "SELECT * FROM system.local"})
+		send(ch, register)
+		require.False(t, ch.isMonitoringConnection())
+	})
+}
+
+func TestClientHandler_QualifyTargetRequest(t *testing.T) {
+	ch := &ClientHandler{
+		queryModifier:     NewQueryModifier(&fakeTimeUuidGenerator{}),
+		timeUuidGenerator: &fakeTimeUuidGenerator{},
+	}
+	tests := []struct {
+		name             string
+		version          primitive.ProtocolVersion
+		expectedQuery    string
+		expectedKeyspace string
+	}{
+		{"V4 rewrite", primitive.ProtocolVersion4, `SELECT * FROM "ks1".tbl`, ""},
+		{"DseV1 rewrite", primitive.ProtocolVersionDse1, `SELECT * FROM "ks1".tbl`, ""},
+		{"V5 keyspace flag", primitive.ProtocolVersion5, "SELECT * FROM tbl", "ks1"},
+		{"DseV2 keyspace flag", primitive.ProtocolVersionDse2, "SELECT * FROM tbl", "ks1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := mockFrame(t, &message.Query{Query: "SELECT * FROM tbl"}, tt.version)
+			qualified, err := ch.qualifyTargetRequest(NewFrameDecodeContext(request), request, "ks1")
+			require.Nil(t, err)
+			decodedFrame, err := defaultCodec.ConvertFromRawFrame(qualified)
+			require.Nil(t, err)
+			query := decodedFrame.Body.Message.(*message.Query)
+			require.Equal(t, tt.expectedQuery, query.Query)
+			keyspace := ""
+			if query.Options != nil {
+				keyspace = query.Options.Keyspace
+			}
+			require.Equal(t, tt.expectedKeyspace, keyspace)
+		})
+	}
+}
+
+func TestClientHandler_SkipTargetUse(t *testing.T) {
+	ch := &ClientHandler{timeUuidGenerator: &fakeTimeUuidGenerator{}}
+	tests := []struct {
+		name     string
+		query    string
+		version  primitive.ProtocolVersion
+		expected forwardDecision
+	}{
+		{"USE V4", "USE ks1", primitive.ProtocolVersion4, forwardToBoth},
+		{"USE DseV1", "USE ks1", primitive.ProtocolVersionDse1, forwardToBoth},
+		{"USE V5", "USE ks1", primitive.ProtocolVersion5, forwardToOrigin},
+		{"USE DseV2", "USE ks1", primitive.ProtocolVersionDse2, forwardToOrigin},
+		{"INSERT V5", "INSERT INTO tbl (a) VALUES (1)", primitive.ProtocolVersion5, forwardToBoth},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := mockFrame(t, &message.Query{Query: tt.query}, tt.version)
+			requestInfo := ch.skipTargetUse(
+				NewFrameDecodeContext(request), NewGenericRequestInfo(forwardToBoth, true, true), "")
+			require.Equal(t, tt.expected, requestInfo.GetForwardDecision())
+			require.True(t, requestInfo.ShouldBeTrackedInMetrics())
+		})
+	}
+}
+
+func TestClientHandler_SingleSidedWrites(t *testing.T) {
+	newClientHandler := func() (*ClientHandler, *countingCounter, *countingCounter) {
+		connLogger := &atomic.Value{}
+		connLogger.Store(log.NewEntry(log.StandardLogger()))
+		origin, target := &countingCounter{}, &countingCounter{}
+		proxyMetrics := newFakeProxyMetrics()
+		proxyMetrics.SingleSidedWritesOrigin = origin
+		proxyMetrics.SingleSidedWritesTarget = target
+		proxyMetrics.ConditionalWrites = newFakeCounter()
+		proxyMetrics.CounterWrites = newFakeCounter()
+		return &ClientHandler{
+			connLogger:        connLogger,
+			primaryCluster:    common.ClusterTypeOrigin,
+			timeUuidGenerator: &fakeTimeUuidGenerator{},
+			metricHandler: metrics.NewMetricHandler(noopmetrics.NewNoopMetricFactory(), []float64{}, []float64{},
+				[]float64{}, proxyMetrics, nil, nil, nil),
+		}, origin, target
+	}
+	request := mockFrame(t, &message.Query{Query: "INSERT INTO ks.tbl (pk) VALUES (1)"}, primitive.ProtocolVersion4)
+	success := mockFrame(t, &message.VoidResult{}, primitive.ProtocolVersion4)
+	failure := mockFrame(t, &message.Invalid{ErrorMessage: "invalid"}, primitive.ProtocolVersion4)
+	requestInfo := NewGenericRequestInfo(forwardToBoth, false, true)
+
+	t.Run("dual write failure modes", func(t *testing.T) {
+		tests := []struct {
+			mode           common.DualWriteFailureMode
+			expectedOrigin int
+			expectedTarget int
+		}{
+			{common.DualWriteFailureModeEither, 0, 0},
+			{common.DualWriteFailureModeOrigin, 1, 0},
+			{common.DualWriteFailureModeTarget, 0, 1},
+		}
+		for _, tt := range tests {
+			t.Run(tt.mode.String(), func(t *testing.T) {
+				ch, origin, target := newClientHandler()
+				ch.dualWriteFailureMode = tt.mode
+				ch.singleSidedWrites = NewSingleSidedWrites(0)
+				ch.aggregateAndTrackResponses(requestInfo, request, success, failure)
+				ch.aggregateAndTrackResponses(requestInfo, request, failure, success)
+				ch.aggregateAndTrackResponses(requestInfo, request, success, success)
+				ch.aggregateAndTrackResponses(requestInfo, request, failure, failure)
+				require.Equal(t, tt.expectedOrigin, origin.value)
+				require.Equal(t, tt.expectedTarget, target.value)
+				require.Equal(t, int64(tt.expectedOrigin), ch.singleSidedWrites.GetMissingOn(common.ClusterTypeTarget))
+				require.Equal(t, int64(tt.expectedTarget), ch.singleSidedWrites.GetMissingOn(common.ClusterTypeOrigin))
+			})
+		}
+	})
+
+	t.Run("conditional writes", func(t *testing.T) {
+		lwt := mockFrame(t, &message.Query{Query: "INSERT INTO ks.tbl (pk) VALUES (1) IF NOT EXISTS"},
+			primitive.ProtocolVersion4)
+		newAppliedResult := func(applied bool) *frame.RawFrame {
+			value := []byte{0}
+			if applied {
+				value = []byte{1}
+			}
+			return mockFrame(t, &message.RowsResult{
+				Metadata: &message.RowsMetadata{ColumnCount: 1, Columns: []*message.ColumnMetadata{
+					{Keyspace: "ks", Table: "tbl", Name: appliedColumnName, Type: datatype.Boolean}}},
+				Data: message.RowSet{{value}},
+			}, primitive.ProtocolVersion4)
+		}
+		tests := []struct {
+			name     string
+			state    int
+			response *frame.RawFrame
+			expected int
+		}{
+			{"applied", RequestDone, newAppliedResult(true), 1},
+			{"not applied", RequestDone, newAppliedResult(false), 0},
+			{"failed", RequestDone, failure, 0},
+			{"timed out", RequestTimedOut, newAppliedResult(true), 0},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				ch, origin, target := newClientHandler()
+				ch.lwtMode = common.LwtModePrimaryOnly
+				lwtRequestInfo, conditional, rejected := ch.checkConditionalWrite(
+					NewFrameDecodeContext(lwt), requestInfo, "", nil)
+				require.True(t, conditional)
+				require.False(t, rejected)
+				require.Equal(t, forwardToOrigin, lwtRequestInfo.GetForwardDecision())
+				// the write is only counted once it is acknowledged
+				require.Equal(t, 0, origin.value)
+
+				reqCtx := NewRequestContext(lwt, lwtRequestInfo, time.Now(), nil)
+				reqCtx.primaryOnlyWrite = "conditional write forwarded to the primary cluster only"
+				reqCtx.primaryOnlyConditional = true
+				reqCtx.state = tt.state
+				ch.trackPrimaryOnlyWrite(reqCtx, tt.response)
+				require.Equal(t, tt.expected, origin.value)
+				require.Equal(t, 0, target.value)
+			})
+		}
+
+		// writes sent to both clusters are never counted
+		ch, origin, _ := newClientHandler()
+		ch.lwtMode = common.LwtModeDual
+		reqCtx := NewRequestContext(lwt, requestInfo, time.Now(), nil)
+		reqCtx.state = RequestDone
+		ch.trackPrimaryOnlyWrite(reqCtx, newAppliedResult(true))
+		require.Equal(t, 0, origin.value)
+	})
+
+	t.Run("counter updates", func(t *testing.T) {
+		counter := mockFrame(t, &message.Query{Query: "UPDATE ks.tbl SET c = c + 1 WHERE pk = 1"},
+			primitive.ProtocolVersion4)
+		tests := []struct {
+			name     string
+			state    int
+			response *frame.RawFrame
+			expected int
+		}{
+			{"succeeded", RequestDone, success, 1},
+			{"failed", RequestDone, failure, 0},
+			{"timed out", RequestTimedOut, success, 0},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				ch, origin, target := newClientHandler()
+				ch.primaryCluster = common.ClusterTypeTarget
+				ch.counterWriteMode = common.CounterWriteModePrimaryOnly
+				counterRequestInfo, counterWrite := ch.checkCounterWrite(NewFrameDecodeContext(counter), requestInfo, "")
+				require.True(t, counterWrite)
+				require.Equal(t, forwardToTarget, counterRequestInfo.GetForwardDecision())
+				// the update is only counted once it is acknowledged
+				require.Equal(t, 0, target.value)
+
+				reqCtx := NewRequestContext(counter, counterRequestInfo, time.Now(), nil)
+				reqCtx.primaryOnlyWrite = "counter update forwarded to the primary cluster only"
+				reqCtx.state = tt.state
+				ch.trackPrimaryOnlyWrite(reqCtx, tt.response)
+				require.Equal(t, 0, origin.value)
+				require.Equal(t, tt.expected, target.value)
+			})
+		}
+	})
+}
+
+type countingCounter struct {
+	value int
+}
+
+func (recv *countingCounter) Add(valueToAdd int) {
+	recv.value += valueToAdd
+}
+
+func TestCheckProtocolVersion(t *testing.T) {
+	tests := []struct {
+		name            string
+		version         primitive.ProtocolVersion
+		maxVersion      primitive.ProtocolVersion
+		expectedMessage string
+	}{
+		{"supported", primitive.ProtocolVersion4, primitive.ProtocolVersion4, ""},
+		{"supported dse", primitive.ProtocolVersionDse2, primitive.ProtocolVersionDse2, ""},
+		{"v5", primitive.ProtocolVersion5, primitive.ProtocolVersion4,
+			"Invalid or unsupported protocol version (5); supported versions are (2/v2, 3/v3, 4/v4)"},
+		{"v5 with clusters on v3", primitive.ProtocolVersion5, primitive.ProtocolVersion3,
+			"Invalid or unsupported protocol version (5); supported versions are (2/v2, 3/v3)"},
+		{"v5 with dse clusters", primitive.ProtocolVersion5, primitive.ProtocolVersionDse2,
+			"Invalid or unsupported protocol version (5); supported versions are (2/v2, 3/v3, 4/v4, 65/dse-v1, 66/dse-v2)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			protocolErr := checkProtocolVersion(tt.version, tt.maxVersion)
+			if tt.expectedMessage == "" {
+				require.Nil(t, protocolErr)
+			} else {
+				require.Equal(t, tt.expectedMessage, protocolErr.ErrorMessage)
+			}
+		})
+	}
+}
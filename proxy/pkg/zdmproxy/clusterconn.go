@@ -11,6 +11,7 @@ import (
 	"github.com/datastax/go-cassandra-native-protocol/primitive"
 	"github.com/datastax/zdm-proxy/proxy/pkg/common"
 	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/crash"
 	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
 	log "github.com/sirupsen/logrus"
 	"io"
@@ -66,6 +67,7 @@ type ClusterConnector struct {
 	frameProcessor              FrameProcessor
 
 	handshakeDone *atomic.Value
+	compression   *frameCompression
 
 	asyncConnector       bool
 	asyncConnectorState  ConnectorState
@@ -75,6 +77,8 @@ type ClusterConnector struct {
 
 	lastHeartbeatTime *atomic.Value
 	lastHeartbeatLock sync.Mutex
+	lastReadTime      *atomic.Value
+	heartbeatStreamId int32 // stream id of the heartbeat that is waiting for a response, -1 if there is none
 
 	ccProtoVer primitive.ProtocolVersion
 }
@@ -104,7 +108,8 @@ func NewClusterConnector(
 	asyncPendingRequests *pendingRequests,
 	handshakeDone *atomic.Value,
 	frameProcessor FrameProcessor,
-	ccProtoVer primitive.ProtocolVersion) (*ClusterConnector, error) {
+	ccProtoVer primitive.ProtocolVersion,
+	compression *frameCompression) (*ClusterConnector, error) {
 
 	var connectorType ClusterConnectorType
 	var clusterType common.ClusterType
@@ -120,6 +125,11 @@ func NewClusterConnector(
 		connectorType = ClusterConnectorTypeAsync
 	}
 
+	connectorMetrics, err := GetNodeMetricsByClusterConnector(nodeMetrics, connectorType)
+	if err != nil {
+		return nil, err
+	}
+
 	conn, timeoutCtx, err := openConnectionToCluster(connInfo, clientHandlerContext, connectorType, nodeMetrics)
 	if err != nil {
 		if errors.Is(err, ShutdownErr) {
@@ -133,6 +143,7 @@ func NewClusterConnector(
 	clusterConnCtx, clusterConnCancelFn := context.WithCancel(clientHandlerContext)
 
 	go func() {
+		defer crash.HandlePanic()
 		select {
 		case <-requestsDoneCtx.Done():
 			clusterConnCancelFn()
@@ -151,6 +162,8 @@ func NewClusterConnector(
 	// Initialize heartbeat time
 	lastHeartbeatTime := &atomic.Value{}
 	lastHeartbeatTime.Store(time.Now())
+	lastReadTime := &atomic.Value{}
+	lastReadTime.Store(time.Now())
 
 	return &ClusterConnector{
 		conf:                   conf,
@@ -173,7 +186,12 @@ func NewClusterConnector(
 			string(connectorType),
 			true,
 			asyncConnector,
-			writeScheduler),
+			writeScheduler,
+			compression,
+			time.Duration(conf.ProxyClusterWriteTimeoutMs)*time.Millisecond,
+			connectorMetrics.SlowWrites,
+			nil,
+			nil),
 		responseChan:                responseChan,
 		frameProcessor:              frameProcessor,
 		responseReadBufferSizeBytes: conf.ResponseReadBufferSizeBytes,
@@ -183,7 +201,10 @@ func NewClusterConnector(
 		asyncConnectorState:         ConnectorStateHandshake,
 		asyncPendingRequests:        asyncPendingRequests,
 		handshakeDone:               handshakeDone,
+		compression:                 compression,
 		lastHeartbeatTime:           lastHeartbeatTime,
+		lastReadTime:                lastReadTime,
+		heartbeatStreamId:           -1,
 		ccProtoVer:                  ccProtoVer,
 	}, nil
 }
@@ -237,6 +258,7 @@ func (cc *ClusterConnector) runResponseListeningLoop() {
 	cc.clientHandlerWg.Add(1)
 	log.Debugf("[%s] Listening to replies sent by node %v", cc.connectorType, cc.connection.RemoteAddr())
 	go func() {
+		defer crash.HandlePanic()
 		defer cc.clientHandlerWg.Done()
 		if cc.clusterConnEventsChan != nil {
 			defer close(cc.clusterConnEventsChan)
@@ -250,7 +272,8 @@ func (cc *ClusterConnector) runResponseListeningLoop() {
 		defer wg.Wait()
 		protocolErrOccurred := false
 		for {
-			response, err := readRawFrame(bufferedReader, connectionAddr, cc.clusterConnContext)
+			// the size of the responses is not limited, the clusters enforce their own limits on the requests
+			response, err := readRawFrame(bufferedReader, connectionAddr, cc.clusterConnContext, cc.compression, 0)
 			protocolErrResponseFrame, err, errCode := checkProtocolError(response, cc.ccProtoVer, err, protocolErrOccurred, string(cc.connectorType))
 
 			if err != nil {
@@ -258,6 +281,7 @@ func (cc *ClusterConnector) runResponseListeningLoop() {
 					err, cc.clusterConnContext, cc.cancelFunc, string(cc.connectorType), "reading", connectionAddr)
 				break
 			} else {
+				cc.lastReadTime.Store(time.Now())
 				if protocolErrOccurred {
 					log.Debugf("[%v] Data received after protocol error occured, ignoring it.", string(cc.connectorType))
 					continue
@@ -267,6 +291,12 @@ func (cc *ClusterConnector) runResponseListeningLoop() {
 				}
 			}
 
+			if response.Header.StreamId >= 0 &&
+				atomic.CompareAndSwapInt32(&cc.heartbeatStreamId, int32(response.Header.StreamId), -1) {
+				cc.handleHeartbeatResponse(response, connectionAddr)
+				continue
+			}
+
 			// when there's a protocol error, we cannot rely on the returned stream id, the only exception is
 			// when it's a UnsupportedVersion error, which means the Frame was properly parsed by the native protocol library
 			// but the proxy doesn't support the protocol version and in that case we can proceed with releasing the stream id in the mapper
@@ -304,9 +334,21 @@ func (cc *ClusterConnector) runResponseListeningLoop() {
 				}
 
 				if response.Header.OpCode == primitive.OpCodeEvent {
-					cc.clusterConnEventsChan <- response
+					select {
+					case cc.clusterConnEventsChan <- response:
+					default:
+						sent := watchBlockedSend(fmt.Sprintf("%v event channel", cc.connectorType))
+						cc.clusterConnEventsChan <- response
+						sent()
+					}
 				} else {
-					cc.responseChan <- NewResponse(response, cc.connectorType)
+					select {
+					case cc.responseChan <- NewResponse(response, cc.connectorType):
+					default:
+						sent := watchBlockedSend(fmt.Sprintf("%v response channel", cc.connectorType))
+						cc.responseChan <- NewResponse(response, cc.connectorType)
+						sent()
+					}
 				}
 				log.Tracef("[%s] Response sent to response channel: %v", cc.connectorType, response.Header)
 			})
@@ -530,15 +572,98 @@ func (cc *ClusterConnector) sendHeartbeat(version primitive.ProtocolVersion, hea
 		return
 	}
 	cc.lastHeartbeatTime.Store(time.Now())
-	optionsMsg := &message.Options{}
-	heartBeatFrame := frame.NewFrame(version, -1, optionsMsg)
+	cc.writeHeartbeat(version)
+}
+
+// writeHeartbeat sends an OPTIONS request with a stream id taken from the frame processor so that it doesn't overlap
+// with client requests. Only one heartbeat is in flight at a time, its response is consumed by handleHeartbeatResponse.
+func (cc *ClusterConnector) writeHeartbeat(version primitive.ProtocolVersion) {
+	if atomic.LoadInt32(&cc.heartbeatStreamId) >= 0 {
+		log.Debugf("[%s] Previous heartbeat to %v is still pending, not sending another one.", cc.connectorType, cc.clusterType)
+		return
+	}
+	heartBeatFrame := frame.NewFrame(version, 0, &message.Options{})
 	rawFrame, err := defaultCodec.ConvertToRawFrame(heartBeatFrame)
 	if err != nil {
 		log.Errorf("Cannot convert heartbeat frame to raw frame: %v", err)
 		return
 	}
+	rawFrame, err = cc.frameProcessor.AssignUniqueId(rawFrame)
+	if err != nil {
+		log.Warnf("[%s] Couldn't assign stream id to heartbeat for %v: %v", cc.connectorType, cc.clusterType, err)
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&cc.heartbeatStreamId, -1, int32(rawFrame.Header.StreamId)) {
+		// another heartbeat was sent in the meantime
+		if _, err = cc.frameProcessor.ReleaseId(rawFrame); err != nil {
+			log.Errorf("[%s] Error releasing heartbeat stream id: %v.", cc.connectorType, err)
+		}
+		return
+	}
 	log.Debugf("Sending heartbeat to cluster %v", cc.clusterType)
-	cc.sendRequestToCluster(rawFrame)
+	cc.writeCoalescer.Enqueue(rawFrame)
+}
+
+func (cc *ClusterConnector) handleHeartbeatResponse(response *frame.RawFrame, connectionAddr string) {
+	if _, err := cc.frameProcessor.ReleaseId(response); err != nil {
+		log.Errorf("[%s] Error releasing heartbeat stream id: %v.", cc.connectorType, err)
+	}
+	if response.Header.OpCode != primitive.OpCodeSupported {
+		log.Warnf("[%s] Received unexpected %v response to heartbeat from %v (%v).",
+			cc.connectorType, response.Header.OpCode, cc.clusterType, connectionAddr)
+		return
+	}
+	log.Debugf("[%s] Received heartbeat response from %v (%v).", cc.connectorType, cc.clusterType, connectionAddr)
+}
+
+// runIdleHeartbeatLoop sends a heartbeat when nothing was received from the node for ZDM_HEARTBEAT_INTERVAL_MS so that
+// idle connections are not closed by firewalls. If the node doesn't send anything back within ZDM_HEARTBEAT_TIMEOUT_MS
+// after a heartbeat, the connection is considered broken and the client connection is closed so that the client
+// reconnects.
+func (cc *ClusterConnector) runIdleHeartbeatLoop(version primitive.ProtocolVersion) {
+	interval := time.Duration(cc.conf.HeartbeatIntervalMs) * time.Millisecond
+	timeout := time.Duration(cc.conf.HeartbeatTimeoutMs) * time.Millisecond
+	if interval <= 0 {
+		return
+	}
+	checkPeriod := interval
+	if timeout > 0 && timeout < checkPeriod {
+		checkPeriod = timeout
+	}
+	checkPeriod /= 2
+
+	cc.clientHandlerWg.Add(1)
+	go func() {
+		defer crash.HandlePanic()
+		defer cc.clientHandlerWg.Done()
+		ticker := time.NewTicker(checkPeriod)
+		defer ticker.Stop()
+		var heartbeatSentAt time.Time
+		for {
+			select {
+			case <-cc.clusterConnContext.Done():
+				return
+			case <-ticker.C:
+			}
+
+			lastReadTime := cc.lastReadTime.Load().(time.Time)
+			if !heartbeatSentAt.IsZero() {
+				if lastReadTime.After(heartbeatSentAt) {
+					heartbeatSentAt = time.Time{}
+				} else if timeout > 0 && time.Since(heartbeatSentAt) > timeout {
+					log.Warnf("[%s] %v (%v) did not answer the heartbeat within %v, closing the connection.",
+						cc.connectorType, cc.clusterType, cc.connection.RemoteAddr(), timeout)
+					cc.cancelFunc()
+					return
+				}
+			}
+			if heartbeatSentAt.IsZero() && time.Since(lastReadTime) >= interval {
+				heartbeatSentAt = time.Now()
+				cc.lastHeartbeatTime.Store(heartbeatSentAt)
+				cc.writeHeartbeat(version)
+			}
+		}
+	}()
 }
 
 // shouldSendHeartbeat looks up the value of the last heartbeat time in the atomic value
@@ -3,11 +3,17 @@ package zdmproxy
 import (
 	"bytes"
 	"context"
+	"errors"
 	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
 	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/crash"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
 	log "github.com/sirupsen/logrus"
 	"net"
+	"os"
 	"sync"
+	"time"
 )
 
 const (
@@ -32,6 +38,21 @@ type writeCoalescer struct {
 	writeBufferSizeBytes int
 
 	scheduler *Scheduler
+
+	// frames are compressed with the compression negotiated by the client, see frameCompression
+	compression *frameCompression
+	isRequest   bool
+	// cluster connections only expect compressed frames after the STARTUP request, only accessed by the write loop
+	startupWritten bool
+
+	// max time a write on the connection can block without making progress, 0 means no limit
+	writeTimeout time.Duration
+	// incremented every time a write reaches writeTimeout, nil if writeTimeout is 0
+	slowWrites metrics.Counter
+	// called when a write reached writeTimeout without making progress, i.e. the peer stopped reading
+	onWriteTimeout func()
+	// called with every frame that is taken from the write queue (even if it is discarded), can be nil
+	onDequeue func(f *frame.RawFrame)
 }
 
 func NewWriteCoalescer(
@@ -43,7 +64,12 @@ func NewWriteCoalescer(
 	logPrefix string,
 	isRequest bool,
 	isAsync bool,
-	scheduler *Scheduler) *writeCoalescer {
+	scheduler *Scheduler,
+	compression *frameCompression,
+	writeTimeout time.Duration,
+	slowWrites metrics.Counter,
+	onWriteTimeout func(),
+	onDequeue func(f *frame.RawFrame)) *writeCoalescer {
 
 	writeQueueSizeFrames := conf.RequestWriteQueueSizeFrames
 	if !isRequest {
@@ -71,6 +97,12 @@ func NewWriteCoalescer(
 		waitGroup:              &sync.WaitGroup{},
 		writeBufferSizeBytes:   writeBufferSizeBytes,
 		scheduler:              scheduler,
+		compression:            compression,
+		isRequest:              isRequest,
+		writeTimeout:           writeTimeout,
+		slowWrites:             slowWrites,
+		onWriteTimeout:         onWriteTimeout,
+		onDequeue:              onDequeue,
 	}
 }
 
@@ -81,6 +113,7 @@ func (recv *writeCoalescer) RunWriteQueueLoop() {
 	recv.clientHandlerWaitGroup.Add(1)
 	recv.waitGroup.Add(1)
 	go func() {
+		defer crash.HandlePanic()
 		defer recv.clientHandlerWaitGroup.Done()
 		defer recv.waitGroup.Done()
 
@@ -125,6 +158,7 @@ func (recv *writeCoalescer) RunWriteQueueLoop() {
 							return
 						}
 
+						recv.dequeued(f)
 						if tempDraining {
 							// continue draining the write queue without writing on connection until it is closed
 							log.Tracef("[%v] Discarding frame from write queue because shutdown was requested: %v", recv.logPrefix, f.Header)
@@ -134,10 +168,16 @@ func (recv *writeCoalescer) RunWriteQueueLoop() {
 						firstFrameRead = true
 						f = firstFrame
 						ok = true
+						recv.dequeued(f)
 					}
 
 					log.Tracef("[%v] Writing %v on %v", recv.logPrefix, f.Header, connectionAddr)
-					err := writeRawFrame(tempBuffer, connectionAddr, recv.shutdownContext, f)
+					compressedBody := getBodyBuffer()
+					f, err := recv.compressFrame(f, compressedBody)
+					if err == nil {
+						err = writeRawFrame(tempBuffer, connectionAddr, recv.shutdownContext, f)
+					}
+					putBodyBuffer(compressedBody)
 					if err != nil {
 						tempDraining = true
 						handleConnectionError(err, recv.shutdownContext, recv.cancelFunc, recv.logPrefix, "writing", connectionAddr)
@@ -163,10 +203,14 @@ func (recv *writeCoalescer) RunWriteQueueLoop() {
 			draining = result.draining
 			bufferedWriter = result.buffer
 			if bufferedWriter.Len() > 0 && !draining {
-				_, err := recv.connection.Write(bufferedWriter.Bytes())
+				err := recv.write(bufferedWriter.Bytes())
 				bufferedWriter.Reset()
 				if err != nil {
-					handleConnectionError(err, recv.shutdownContext, recv.cancelFunc, recv.logPrefix, "writing", connectionAddr)
+					if errors.Is(err, os.ErrDeadlineExceeded) {
+						recv.handleWriteTimeout(connectionAddr)
+					} else {
+						handleConnectionError(err, recv.shutdownContext, recv.cancelFunc, recv.logPrefix, "writing", connectionAddr)
+					}
 					draining = true
 				}
 			}
@@ -174,9 +218,73 @@ func (recv *writeCoalescer) RunWriteQueueLoop() {
 	}()
 }
 
+func (recv *writeCoalescer) dequeued(f *frame.RawFrame) {
+	if recv.onDequeue != nil {
+		recv.onDequeue(f)
+	}
+}
+
+// compressFrame compresses the body of the frame in the provided buffer if the client negotiated compression. The
+// STARTUP request (and the requests before it) are never compressed because the cluster doesn't know the compression
+// yet.
+func (recv *writeCoalescer) compressFrame(f *frame.RawFrame, body *bytes.Buffer) (*frame.RawFrame, error) {
+	if recv.isRequest && !recv.startupWritten {
+		recv.startupWritten = f.Header.OpCode == primitive.OpCodeStartup
+		return f, nil
+	}
+	return recv.compression.compressInto(f, body)
+}
+
+// write writes the whole buffer on the connection. If a write reaches writeTimeout after writing part of the buffer
+// the peer is slow but still reading so the remaining bytes are written with a new deadline, os.ErrDeadlineExceeded
+// is only returned if nothing could be written before the deadline.
+func (recv *writeCoalescer) write(buf []byte) error {
+	for {
+		if recv.writeTimeout > 0 {
+			err := recv.connection.SetWriteDeadline(time.Now().Add(recv.writeTimeout))
+			if err != nil {
+				return err
+			}
+		}
+		n, err := recv.connection.Write(buf)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, os.ErrDeadlineExceeded) {
+			return err
+		}
+		recv.slowWrites.Add(1)
+		if n == 0 {
+			return err
+		}
+		log.Debugf("[%v] Slow write on %v, %d of %d bytes were written within %v.",
+			recv.logPrefix, recv.connection.RemoteAddr(), n, len(buf), recv.writeTimeout)
+		buf = buf[n:]
+	}
+}
+
+// handleWriteTimeout closes the connection of a peer that stopped reading, otherwise the write queue stays full and
+// every response (or request) sent to this connection stays in memory until the peer reads again or disconnects.
+func (recv *writeCoalescer) handleWriteTimeout(connectionAddr string) {
+	log.Warnf("[%v] %v did not read from the connection for %v, closing the connection.",
+		recv.logPrefix, connectionAddr, recv.writeTimeout)
+	if recv.onWriteTimeout != nil {
+		recv.onWriteTimeout()
+	}
+	if recv.shutdownContext.Err() == nil {
+		recv.cancelFunc()
+	}
+}
+
 func (recv *writeCoalescer) Enqueue(frame *frame.RawFrame) {
 	log.Tracef("[%v] Sending %v to write queue on %v", recv.logPrefix, frame.Header, recv.connection.RemoteAddr())
-	recv.writeQueue <- frame
+	select {
+	case recv.writeQueue <- frame:
+	default:
+		sent := watchBlockedSend(recv.logPrefix + " write queue")
+		recv.writeQueue <- frame
+		sent()
+	}
 	log.Tracef("[%v] Sent %v to write queue on %v", recv.logPrefix, frame.Header, recv.connection.RemoteAddr())
 }
 
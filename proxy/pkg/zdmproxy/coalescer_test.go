@@ -0,0 +1,103 @@
+package zdmproxy
+
+import (
+	"context"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/stretchr/testify/require"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWriteCoalescerWriteTimeout(t *testing.T) {
+	conf := &config.Config{ResponseWriteQueueSizeFrames: 8, ResponseWriteBufferSizeBytes: 1024}
+	// writes on a pipe block until the other end reads, the client never reads
+	proxyConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+	defer proxyConn.Close()
+
+	scheduler := NewScheduler(1)
+	defer scheduler.Shutdown()
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	writeTimeouts := int32(0)
+	slowWrites := &testCounter{}
+	coalescer := NewWriteCoalescer(
+		conf, proxyConn, &sync.WaitGroup{}, ctx, cancelFn, ClientConnectorLogPrefix, false, false, scheduler, nil,
+		100*time.Millisecond, slowWrites, func() {
+			atomic.AddInt32(&writeTimeouts, 1)
+		}, nil)
+	coalescer.RunWriteQueueLoop()
+
+	rawFrame, err := defaultCodec.ConvertToRawFrame(
+		frame.NewFrame(primitive.ProtocolVersion4, 1, &message.VoidResult{}))
+	require.Nil(t, err)
+	coalescer.Enqueue(rawFrame)
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("client handler was not cancelled after the write timeout")
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&writeTimeouts))
+	require.Equal(t, int32(1), slowWrites.get())
+
+	// frames sent after the timeout are discarded instead of blocking the sender
+	for i := 0; i < 2*conf.ResponseWriteQueueSizeFrames; i++ {
+		coalescer.Enqueue(rawFrame)
+	}
+	coalescer.Close()
+	require.Equal(t, int32(1), atomic.LoadInt32(&writeTimeouts))
+}
+
+func TestWriteCoalescerPartialWrite(t *testing.T) {
+	proxyConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+	defer proxyConn.Close()
+
+	slowWrites := &testCounter{}
+	coalescer := &writeCoalescer{
+		connection:   proxyConn,
+		logPrefix:    ClientConnectorLogPrefix,
+		writeTimeout: 100 * time.Millisecond,
+		slowWrites:   slowWrites,
+	}
+
+	// the client reads a few bytes at a time so the write times out after making some progress
+	buf := []byte("0123456789")
+	received := make(chan []byte, 1)
+	go func() {
+		data := make([]byte, 0, len(buf))
+		chunk := make([]byte, 4)
+		for len(data) < len(buf) {
+			n, err := clientConn.Read(chunk)
+			if err != nil {
+				break
+			}
+			data = append(data, chunk[:n]...)
+			time.Sleep(60 * time.Millisecond)
+		}
+		received <- data
+	}()
+
+	require.Nil(t, coalescer.write(buf))
+	require.Equal(t, buf, <-received)
+	require.Greater(t, slowWrites.get(), int32(0))
+}
+
+type testCounter struct {
+	value int32
+}
+
+func (recv *testCounter) Add(valueToAdd int) {
+	atomic.AddInt32(&recv.value, int32(valueToAdd))
+}
+
+func (recv *testCounter) get() int32 {
+	return atomic.LoadInt32(&recv.value)
+}
@@ -0,0 +1,125 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/compression/lz4"
+	"github.com/datastax/go-cassandra-native-protocol/compression/snappy"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"strings"
+	"sync/atomic"
+)
+
+// bodyCompressors contains the frame body compression algorithms that clients can negotiate in their STARTUP request.
+// Protocol v5 replaced frame body compression with compressed segments (LZ4 only) but the proxy does not support v5.
+var bodyCompressors = map[primitive.Compression]frame.BodyCompressor{
+	primitive.CompressionLz4:    lz4.Compressor{},
+	primitive.CompressionSnappy: snappy.Compressor{},
+}
+
+// frameCompression is the frame body compression that the client negotiated in its STARTUP request. It is shared by
+// the client connector and the cluster connectors of a client connection: frames are decompressed right after they are
+// read so that the rest of the proxy can decode (and modify) them and they are compressed again by the write
+// coalescers. The clusters receive the same STARTUP request so they use the same compression as the client.
+type frameCompression struct {
+	compression *atomic.Value // primitive.Compression, nil until the STARTUP request is received
+}
+
+func newFrameCompression() *frameCompression {
+	return &frameCompression{compression: &atomic.Value{}}
+}
+
+// set enables the compression requested by the STARTUP request, it returns a protocol error message that should be
+// sent to the client if the compression is not supported.
+func (recv *frameCompression) set(startup *message.Startup) *message.ProtocolError {
+	compression := primitive.Compression(strings.ToUpper(string(startup.GetCompression())))
+	if compression == primitive.CompressionNone {
+		return nil
+	}
+	if _, ok := bodyCompressors[compression]; !ok {
+		return &message.ProtocolError{
+			ErrorMessage: fmt.Sprintf("Unsupported compression algorithm: %v", startup.GetCompression())}
+	}
+	recv.compression.Store(compression)
+	return nil
+}
+
+// get returns the negotiated compression or primitive.CompressionNone.
+func (recv *frameCompression) get() primitive.Compression {
+	if recv == nil {
+		return primitive.CompressionNone
+	}
+	compression, ok := recv.compression.Load().(primitive.Compression)
+	if !ok {
+		return primitive.CompressionNone
+	}
+	return compression
+}
+
+// decompress returns a frame with the decompressed body of the provided frame or the same frame if its body is not
+// compressed.
+func (recv *frameCompression) decompress(f *frame.RawFrame) (*frame.RawFrame, error) {
+	if !f.Header.Flags.Contains(primitive.HeaderFlagCompressed) {
+		return f, nil
+	}
+	compression := recv.get()
+	compressor, ok := bodyCompressors[compression]
+	if !ok {
+		return nil, fmt.Errorf("received a frame with a compressed body but compression %v is not supported", compression)
+	}
+	body := &bytes.Buffer{}
+	if err := compressor.DecompressWithLength(bytes.NewReader(f.Body), body); err != nil {
+		return nil, fmt.Errorf("could not decompress %v frame body: %w", compression, err)
+	}
+	header := f.Header.DeepCopy()
+	header.Flags = header.Flags.Remove(primitive.HeaderFlagCompressed)
+	header.BodyLength = int32(body.Len())
+	return &frame.RawFrame{Header: header, Body: body.Bytes()}, nil
+}
+
+// decompressedLength returns the length of the body of a compressed frame once it is decompressed without
+// decompressing it: LZ4 bodies start with the decompressed length as a 4 byte integer and SNAPPY blocks start with it
+// as a varint.
+func (recv *frameCompression) decompressedLength(f *frame.RawFrame) (int64, error) {
+	switch compression := recv.get(); compression {
+	case primitive.CompressionLz4:
+		if len(f.Body) < 4 {
+			return 0, errors.New("could not read LZ4 decompressed length")
+		}
+		return int64(binary.BigEndian.Uint32(f.Body)), nil
+	case primitive.CompressionSnappy:
+		length, n := binary.Uvarint(f.Body)
+		if n <= 0 {
+			return 0, errors.New("could not read SNAPPY decompressed length")
+		}
+		return int64(length), nil
+	default:
+		return 0, fmt.Errorf("received a frame with a compressed body but compression %v is not supported", compression)
+	}
+}
+
+// compress returns a frame with the compressed body of the provided frame or the same frame if no compression was
+// negotiated. The provided frame is not modified because the same frame can be written on multiple connections.
+func (recv *frameCompression) compress(f *frame.RawFrame) (*frame.RawFrame, error) {
+	return recv.compressInto(f, &bytes.Buffer{})
+}
+
+// compressInto is the same as compress but the compressed body is written to the provided buffer, the returned frame
+// must not be used after the buffer is reused.
+func (recv *frameCompression) compressInto(f *frame.RawFrame, body *bytes.Buffer) (*frame.RawFrame, error) {
+	compressor, ok := bodyCompressors[recv.get()]
+	if !ok || f.Header.Flags.Contains(primitive.HeaderFlagCompressed) {
+		return f, nil
+	}
+	if err := compressor.CompressWithLength(bytes.NewReader(f.Body), body); err != nil {
+		return nil, fmt.Errorf("could not compress %v frame body: %w", recv.get(), err)
+	}
+	header := f.Header.DeepCopy()
+	header.Flags = header.Flags.Add(primitive.HeaderFlagCompressed)
+	header.BodyLength = int32(body.Len())
+	return &frame.RawFrame{Header: header, Body: body.Bytes()}, nil
+}
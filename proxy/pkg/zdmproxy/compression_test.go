@@ -0,0 +1,138 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestFrameCompression(t *testing.T) {
+	query, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(
+		primitive.ProtocolVersion4, 1, &message.Query{Query: "SELECT * FROM ks.t WHERE a = 'aaaaaaaaaaaaaaaaaaaaaaaa'"}))
+	require.Nil(t, err)
+
+	var nilCompression *frameCompression
+	require.Equal(t, primitive.CompressionNone, nilCompression.get())
+	uncompressed, err := nilCompression.compress(query)
+	require.Nil(t, err)
+	require.Same(t, query, uncompressed)
+
+	compression := newFrameCompression()
+	protocolErr := compression.set(message.NewStartup(message.StartupOptionCompression, "zstd"))
+	require.NotNil(t, protocolErr)
+	require.Contains(t, protocolErr.ErrorMessage, "zstd")
+	require.Equal(t, primitive.CompressionNone, compression.get())
+
+	// drivers send the algorithm in lower case
+	require.Nil(t, compression.set(message.NewStartup(message.StartupOptionCompression, "lz4")))
+	require.Equal(t, primitive.CompressionLz4, compression.get())
+
+	compressed, err := compression.compress(query)
+	require.Nil(t, err)
+	require.True(t, compressed.Header.Flags.Contains(primitive.HeaderFlagCompressed))
+	require.False(t, query.Header.Flags.Contains(primitive.HeaderFlagCompressed))
+	require.NotEqual(t, query.Body, compressed.Body)
+
+	decompressed, err := compression.decompress(compressed)
+	require.Nil(t, err)
+	require.Equal(t, query, decompressed)
+	decoded, err := defaultCodec.ConvertFromRawFrame(decompressed)
+	require.Nil(t, err)
+	require.Equal(t, "SELECT * FROM ks.t WHERE a = 'aaaaaaaaaaaaaaaaaaaaaaaa'", decoded.Body.Message.(*message.Query).Query)
+
+	// compressed frames are rejected if no compression was negotiated
+	_, err = newFrameCompression().decompress(compressed)
+	require.NotNil(t, err)
+}
+
+func TestFrameCompression_Snappy(t *testing.T) {
+	query, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(
+		primitive.ProtocolVersion3, 1, &message.Query{Query: "SELECT * FROM ks.t WHERE a = 'aaaaaaaaaaaaaaaaaaaaaaaa'"}))
+	require.Nil(t, err)
+
+	compression := newFrameCompression()
+	require.Nil(t, compression.set(message.NewStartup(message.StartupOptionCompression, "snappy")))
+	require.Equal(t, primitive.CompressionSnappy, compression.get())
+
+	compressed, err := compression.compress(query)
+	require.Nil(t, err)
+	require.True(t, compressed.Header.Flags.Contains(primitive.HeaderFlagCompressed))
+	require.NotEqual(t, query.Body, compressed.Body)
+
+	decompressed, err := compression.decompress(compressed)
+	require.Nil(t, err)
+	require.Equal(t, query, decompressed)
+
+	// a frame compressed with another algorithm can't be decompressed
+	lz4Compression := newFrameCompression()
+	require.Nil(t, lz4Compression.set(message.NewStartup(message.StartupOptionCompression, "lz4")))
+	lz4Compressed, err := lz4Compression.compress(query)
+	require.Nil(t, err)
+	_, err = compression.decompress(lz4Compressed)
+	require.NotNil(t, err)
+}
+
+func TestWriteCoalescerCompressesAfterStartup(t *testing.T) {
+	compression := newFrameCompression()
+	require.Nil(t, compression.set(message.NewStartup(message.StartupOptionCompression, "LZ4")))
+	options, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 0, &message.Options{}))
+	require.Nil(t, err)
+	startup, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 0, message.NewStartup()))
+	require.Nil(t, err)
+
+	requestCoalescer := &writeCoalescer{compression: compression, isRequest: true}
+	for _, f := range []*frame.RawFrame{options, startup} {
+		written, err := requestCoalescer.compressFrame(f, &bytes.Buffer{})
+		require.Nil(t, err)
+		require.Same(t, f, written)
+	}
+	written, err := requestCoalescer.compressFrame(options, &bytes.Buffer{})
+	require.Nil(t, err)
+	require.True(t, written.Header.Flags.Contains(primitive.HeaderFlagCompressed))
+
+	responseCoalescer := &writeCoalescer{compression: compression}
+	written, err = responseCoalescer.compressFrame(options, &bytes.Buffer{})
+	require.Nil(t, err)
+	require.True(t, written.Header.Flags.Contains(primitive.HeaderFlagCompressed))
+}
+
+func TestReadRawFrame_DecompressedTooLarge(t *testing.T) {
+	// a query that compresses well so that the compressed frame is below the limit but not the decompressed one
+	query, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(
+		primitive.ProtocolVersion4, 1, &message.Query{Query: "SELECT * FROM ks.t WHERE a = '" + strings.Repeat("a", 4096) + "'"}))
+	require.Nil(t, err)
+	options, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 2, &message.Options{}))
+	require.Nil(t, err)
+
+	for _, algorithm := range []string{"LZ4", "SNAPPY"} {
+		t.Run(algorithm, func(t *testing.T) {
+			compression := newFrameCompression()
+			require.Nil(t, compression.set(message.NewStartup(message.StartupOptionCompression, algorithm)))
+			encoded := &bytes.Buffer{}
+			for _, f := range []*frame.RawFrame{query, options} {
+				compressed, err := compression.compress(f)
+				require.Nil(t, err)
+				require.Less(t, int(compressed.Header.BodyLength), 1024)
+				require.Nil(t, defaultCodec.EncodeRawFrame(compressed, encoded))
+			}
+
+			reader := bytes.NewReader(encoded.Bytes())
+			_, err = readRawFrame(reader, "", context.Background(), compression, 1024)
+			var frameTooLargeErr *frameTooLargeError
+			require.True(t, errors.As(err, &frameTooLargeErr))
+			require.Equal(t, int64(len(query.Body)), frameTooLargeErr.length)
+			require.Equal(t, query.Header.StreamId, frameTooLargeErr.header.StreamId)
+
+			// the rejected frame was fully read, the next frame can be read from the connection
+			next, err := readRawFrame(reader, "", context.Background(), compression, 1024)
+			require.Nil(t, err)
+			require.Equal(t, options, next)
+		})
+	}
+}
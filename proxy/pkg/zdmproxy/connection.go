@@ -4,12 +4,40 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
 	"github.com/jpillora/backoff"
 	log "github.com/sirupsen/logrus"
 	"net"
 	"time"
 )
 
+// Dialer opens the underlying TCP connections to the nodes (or SNI proxy) of a cluster.
+type Dialer interface {
+	DialContext(ctx context.Context, network string, address string) (net.Conn, error)
+}
+
+func newClusterDialer(dialConfig *common.ClusterDialConfig) (Dialer, error) {
+	dialer := &net.Dialer{}
+	if dialConfig == nil {
+		return dialer, nil
+	}
+	if dialConfig.LocalAddress != nil {
+		dialer.LocalAddr = &net.TCPAddr{IP: dialConfig.LocalAddress}
+	}
+	var clusterDialer Dialer = dialer
+	if dialConfig.NetworkProxyUrl != nil {
+		proxyDialer, err := newProxyDialer(dialConfig.NetworkProxyUrl, dialer)
+		if err != nil {
+			return nil, err
+		}
+		clusterDialer = proxyDialer
+	}
+	if dialConfig.SshJumpHost != "" {
+		return newSshTunnelDialer(dialConfig, clusterDialer)
+	}
+	return clusterDialer, nil
+}
+
 func openConnection(cc ConnectionConfig, ec Endpoint, ctx context.Context, useBackoff bool) (net.Conn, context.Context, error) {
 	var connection net.Conn
 	var err error
@@ -19,7 +47,7 @@ func openConnection(cc ConnectionConfig, ec Endpoint, ctx context.Context, useBa
 
 	if cc.GetTlsConfig() != nil {
 		// open connection using TLS
-		connection, err = openTLSConnection(ec, openConnectionTimeoutCtx, useBackoff)
+		connection, err = openTLSConnection(cc.GetDialer(), ec, openConnectionTimeoutCtx, useBackoff)
 		if err != nil {
 			return nil, openConnectionTimeoutCtx, err
 		}
@@ -28,15 +56,15 @@ func openConnection(cc ConnectionConfig, ec Endpoint, ctx context.Context, useBa
 
 	// open plain TCP connection using contact points
 	if useBackoff {
-		connection, err = openTCPConnectionWithBackoff(ec.GetSocketEndpoint(), openConnectionTimeoutCtx)
+		connection, err = openTCPConnectionWithBackoff(cc.GetDialer(), ec.GetSocketEndpoint(), openConnectionTimeoutCtx)
 	} else {
-		connection, err = openTCPConnection(ec.GetSocketEndpoint(), openConnectionTimeoutCtx)
+		connection, err = openTCPConnection(cc.GetDialer(), ec.GetSocketEndpoint(), openConnectionTimeoutCtx)
 	}
 
 	return connection, openConnectionTimeoutCtx, err
 }
 
-func openTCPConnectionWithBackoff(addr string, ctx context.Context) (net.Conn, error) {
+func openTCPConnectionWithBackoff(dialer Dialer, addr string, ctx context.Context) (net.Conn, error) {
 	b := &backoff.Backoff{
 		Min:    100 * time.Millisecond,
 		Max:    10 * time.Second,
@@ -45,7 +73,6 @@ func openTCPConnectionWithBackoff(addr string, ctx context.Context) (net.Conn, e
 	}
 
 	log.Debugf("[openTCPConnectionWithBackoff] Attempting to connect to %v...", addr)
-	dialer := net.Dialer{}
 	for {
 		conn, err := dialer.DialContext(ctx, "tcp", addr)
 		if err != nil {
@@ -62,11 +89,10 @@ func openTCPConnectionWithBackoff(addr string, ctx context.Context) (net.Conn, e
 	}
 }
 
-func openTCPConnection(addr string, ctx context.Context) (net.Conn, error) {
+func openTCPConnection(dialer Dialer, addr string, ctx context.Context) (net.Conn, error) {
 	log.Infof("[openTCPConnection] Opening connection to %v", addr)
 
 	// Wait until the source database is up and ready to accept TCP connections.
-	dialer := net.Dialer{}
 	conn, err := dialer.DialContext(ctx, "tcp", addr)
 	if err != nil {
 		if ctx.Err() == context.Canceled {
@@ -79,14 +105,14 @@ func openTCPConnection(addr string, ctx context.Context) (net.Conn, error) {
 	return conn, nil
 }
 
-func openTLSConnection(endpoint Endpoint, ctx context.Context, useBackoff bool) (*tls.Conn, error) {
+func openTLSConnection(dialer Dialer, endpoint Endpoint, ctx context.Context, useBackoff bool) (*tls.Conn, error) {
 
 	var tcpConn net.Conn
 	var err error
 	if useBackoff {
-		tcpConn, err = openTCPConnectionWithBackoff(endpoint.GetSocketEndpoint(), ctx)
+		tcpConn, err = openTCPConnectionWithBackoff(dialer, endpoint.GetSocketEndpoint(), ctx)
 	} else {
-		tcpConn, err = openTCPConnection(endpoint.GetSocketEndpoint(), ctx)
+		tcpConn, err = openTCPConnection(dialer, endpoint.GetSocketEndpoint(), ctx)
 	}
 	if err != nil {
 		return nil, err
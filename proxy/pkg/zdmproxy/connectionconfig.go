@@ -16,19 +16,25 @@ type ConnectionConfig interface {
 	GetTlsConfig() *tls.Config
 	UsesSNI() bool
 	GetConnectionTimeoutMs() int
+	GetDialer() Dialer
 	GetContactPoints() []Endpoint
 	RefreshContactPoints(ctx context.Context) ([]Endpoint, error)
 	CreateEndpoint(h *Host) Endpoint
 }
 
-func InitializeConnectionConfig(clusterTlsConfig *common.ClusterTlsConfig, contactPointsFromConfig []string, port int,
-	connTimeoutInMs int, clusterType common.ClusterType, datacenterFromConfig string, ctx context.Context) (ConnectionConfig, error) {
+func InitializeConnectionConfig(clusterTlsConfig *common.ClusterTlsConfig, clusterDialConfig *common.ClusterDialConfig,
+	contactPointsFromConfig []string, port int, connTimeoutInMs int, clusterType common.ClusterType,
+	datacenterFromConfig string, ctx context.Context) (ConnectionConfig, error) {
 
+	dialer, err := newClusterDialer(clusterDialConfig)
+	if err != nil {
+		return nil, err
+	}
 	var tlsConfig *tls.Config
-	var err error
 	if clusterTlsConfig.TlsEnabled {
 		if clusterTlsConfig.SecureConnectBundlePath != "" {
-			return initializeAstraConnectionConfig(connTimeoutInMs, clusterType, clusterTlsConfig.SecureConnectBundlePath, ctx)
+			return initializeAstraConnectionConfig(
+				dialer, connTimeoutInMs, clusterType, clusterTlsConfig.SecureConnectBundlePath, ctx)
 		} else {
 			tlsConfig, err = getClientSideTlsConfigFromProxyClusterTlsConfig(clusterTlsConfig, clusterType)
 			if err != nil {
@@ -39,21 +45,25 @@ func InitializeConnectionConfig(clusterTlsConfig *common.ClusterTlsConfig, conta
 
 	contactPoints := make([]Endpoint, 0)
 	for _, contactPoint := range contactPointsFromConfig {
-		contactPoints = append(contactPoints, NewDefaultEndpoint(contactPoint, port, tlsConfig))
+		contactPoints = append(contactPoints, NewDefaultEndpoint(
+			contactPoint, port, getClientSideTlsConfigForHost(tlsConfig, clusterTlsConfig.HostnameValidation, contactPoint)))
 	}
-	return newGenericConnectionConfig(tlsConfig, connTimeoutInMs, clusterType, datacenterFromConfig, contactPoints), nil
+	return newGenericConnectionConfig(
+		dialer, tlsConfig, clusterTlsConfig.HostnameValidation, connTimeoutInMs, clusterType, datacenterFromConfig, contactPoints), nil
 
 }
 
 type baseConnectionConfig struct {
+	dialer              Dialer
 	tlsConfig           *tls.Config
 	connectionTimeoutMs int
 	clusterType         common.ClusterType
 }
 
 func newBaseConnectionConfig(
-	tlsConfig *tls.Config, connectionTimeoutMs int, clusterType common.ClusterType) *baseConnectionConfig {
+	dialer Dialer, tlsConfig *tls.Config, connectionTimeoutMs int, clusterType common.ClusterType) *baseConnectionConfig {
 	return &baseConnectionConfig{
+		dialer:              dialer,
 		tlsConfig:           tlsConfig,
 		connectionTimeoutMs: connectionTimeoutMs,
 		clusterType:         clusterType,
@@ -64,6 +74,10 @@ func (cc *baseConnectionConfig) GetConnectionTimeoutMs() int {
 	return cc.connectionTimeoutMs
 }
 
+func (cc *baseConnectionConfig) GetDialer() Dialer {
+	return cc.dialer
+}
+
 func (cc *baseConnectionConfig) GetTlsConfig() *tls.Config {
 	return cc.tlsConfig
 }
@@ -74,14 +88,17 @@ func (cc *baseConnectionConfig) GetClusterType() common.ClusterType {
 
 type genericConnectionConfig struct {
 	*baseConnectionConfig
-	datacenter    string
-	contactPoints []Endpoint
+	hostnameValidation bool
+	datacenter         string
+	contactPoints      []Endpoint
 }
 
 func newGenericConnectionConfig(
-	tlsConfig *tls.Config, connectionTimeoutMs int, clusterType common.ClusterType, datacenter string, contactPoints []Endpoint) *genericConnectionConfig {
+	dialer Dialer, tlsConfig *tls.Config, hostnameValidation bool, connectionTimeoutMs int, clusterType common.ClusterType,
+	datacenter string, contactPoints []Endpoint) *genericConnectionConfig {
 	return &genericConnectionConfig{
-		baseConnectionConfig: newBaseConnectionConfig(tlsConfig, connectionTimeoutMs, clusterType),
+		baseConnectionConfig: newBaseConnectionConfig(dialer, tlsConfig, connectionTimeoutMs, clusterType),
+		hostnameValidation:   hostnameValidation,
 		datacenter:           datacenter,
 		contactPoints:        contactPoints,
 	}
@@ -105,7 +122,8 @@ func (cc *genericConnectionConfig) RefreshContactPoints(ctx context.Context) ([]
 }
 
 func (cc *genericConnectionConfig) CreateEndpoint(h *Host) Endpoint {
-	return NewDefaultEndpoint(h.Address.String(), h.Port, cc.tlsConfig)
+	address := h.Address.String()
+	return NewDefaultEndpoint(address, h.Port, getClientSideTlsConfigForHost(cc.tlsConfig, cc.hostnameValidation, address))
 }
 
 type AstraConnectionConfig interface {
@@ -127,7 +145,7 @@ type astraConnectionConfigImpl struct {
 }
 
 func initializeAstraConnectionConfig(
-	connectionTimeoutMs int, clusterType common.ClusterType, secureConnectBundlePath string, ctx context.Context) (*astraConnectionConfigImpl, error) {
+	dialer Dialer, connectionTimeoutMs int, clusterType common.ClusterType, secureConnectBundlePath string, ctx context.Context) (*astraConnectionConfigImpl, error) {
 	fileMap, err := extractFilesFromZipArchive(secureConnectBundlePath)
 	if err != nil {
 		return nil, err
@@ -148,7 +166,7 @@ func initializeAstraConnectionConfig(
 	}
 
 	connConfig := &astraConnectionConfigImpl{
-		baseConnectionConfig: newBaseConnectionConfig(tlsConfig, connectionTimeoutMs, clusterType),
+		baseConnectionConfig: newBaseConnectionConfig(dialer, tlsConfig, connectionTimeoutMs, clusterType),
 		datacenter:           "",
 		metadataServiceName:  metadataServiceHostName,
 		metadataServicePort:  metadataServicePort,
@@ -211,7 +229,7 @@ func (cc *astraConnectionConfigImpl) createEndpointFromString(hostId string) End
 }
 
 func (cc *astraConnectionConfigImpl) refreshMetadata(ctx context.Context) (*AstraMetadata, []Endpoint, error) {
-	metadata, err := retrieveAstraMetadata(cc.metadataServiceName, cc.metadataServicePort, cc.GetTlsConfig(), ctx)
+	metadata, err := retrieveAstraMetadata(cc.metadataServiceName, cc.metadataServicePort, cc.GetTlsConfig(), cc.GetDialer(), ctx)
 	if err != nil {
 		return nil, nil, err
 	}
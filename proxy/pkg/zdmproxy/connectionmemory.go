@@ -0,0 +1,96 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"sync"
+)
+
+// connectionMemory enforces ZDM_PROXY_CLIENT_CONNECTION_MAX_BUFFERED_BYTES: it tracks the bytes that a client
+// connection holds in the proxy, i.e. the bodies of the requests that were received but not completed yet and the
+// responses that are queued to be written to the client.
+//
+// A nil connectionMemory (the limit is disabled) accepts everything.
+type connectionMemory struct {
+	budget        int64
+	lock          *sync.Mutex
+	requests      map[int16]int64 // body size of the in-flight requests by stream id
+	requestBytes  int64
+	responseBytes int64
+}
+
+func newConnectionMemory(budget int) *connectionMemory {
+	if budget <= 0 {
+		return nil
+	}
+	return &connectionMemory{
+		budget:   int64(budget),
+		lock:     &sync.Mutex{},
+		requests: map[int16]int64{},
+	}
+}
+
+// acquireRequest returns false if the request doesn't fit in the budget, it should be rejected in that case.
+func (recv *connectionMemory) acquireRequest(request *frame.RawFrame) bool {
+	if recv == nil {
+		return true
+	}
+	size := int64(len(request.Body))
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if recv.requestBytes+recv.responseBytes+size > recv.budget {
+		return false
+	}
+	recv.requestBytes += size - recv.requests[request.Header.StreamId]
+	recv.requests[request.Header.StreamId] = size
+	return true
+}
+
+// releaseRequest is called once the request with this stream id is done, timed out or canceled, whether or not a
+// response is sent to the client.
+func (recv *connectionMemory) releaseRequest(streamId int16) {
+	if recv == nil {
+		return
+	}
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if requestSize, ok := recv.requests[streamId]; ok {
+		recv.requestBytes -= requestSize
+		delete(recv.requests, streamId)
+	}
+}
+
+// acquireResponse is called before a response is queued. Returns false if the response doesn't fit in the budget
+// while other responses are still queued, i.e. the client doesn't read its responses fast enough, the response must
+// not be queued in that case.
+func (recv *connectionMemory) acquireResponse(response *frame.RawFrame) bool {
+	if recv == nil {
+		return true
+	}
+	size := int64(len(response.Body))
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if recv.responseBytes > 0 && recv.requestBytes+recv.responseBytes+size > recv.budget {
+		return false
+	}
+	recv.responseBytes += size
+	return true
+}
+
+// releaseResponse is called once a response is taken from the write queue.
+func (recv *connectionMemory) releaseResponse(response *frame.RawFrame) {
+	if recv == nil {
+		return
+	}
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	recv.responseBytes -= int64(len(response.Body))
+}
+
+func (recv *connectionMemory) usedBytes() int64 {
+	if recv == nil {
+		return 0
+	}
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return recv.requestBytes + recv.responseBytes
+}
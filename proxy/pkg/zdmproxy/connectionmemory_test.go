@@ -0,0 +1,104 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConnectionMemory(t *testing.T) {
+	newFrame := func(streamId int16, size int) *frame.RawFrame {
+		return &frame.RawFrame{Header: &frame.Header{StreamId: streamId}, Body: make([]byte, size)}
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		memory := newConnectionMemory(0)
+		require.Nil(t, memory)
+		require.True(t, memory.acquireRequest(newFrame(1, 1000)))
+		require.True(t, memory.acquireResponse(newFrame(1, 1000)))
+		memory.releaseResponse(newFrame(1, 1000))
+		memory.releaseRequest(1)
+		require.Equal(t, int64(0), memory.usedBytes())
+	})
+
+	t.Run("requests are released when they complete", func(t *testing.T) {
+		memory := newConnectionMemory(100)
+		require.True(t, memory.acquireRequest(newFrame(1, 60)))
+		require.False(t, memory.acquireRequest(newFrame(2, 50)))
+		require.True(t, memory.acquireRequest(newFrame(2, 40)))
+		require.Equal(t, int64(100), memory.usedBytes())
+
+		memory.releaseRequest(1)
+		require.Equal(t, int64(40), memory.usedBytes())
+		memory.releaseRequest(1)
+		require.Equal(t, int64(40), memory.usedBytes())
+
+		response := newFrame(2, 10)
+		require.True(t, memory.acquireResponse(response))
+		require.Equal(t, int64(50), memory.usedBytes())
+		memory.releaseRequest(2)
+		memory.releaseResponse(response)
+		require.Equal(t, int64(0), memory.usedBytes())
+	})
+
+	t.Run("responses that don't fit are rejected only if other responses are queued", func(t *testing.T) {
+		memory := newConnectionMemory(100)
+		first := newFrame(1, 150)
+		require.True(t, memory.acquireResponse(first))
+		require.False(t, memory.acquireResponse(newFrame(2, 10)))
+		require.False(t, memory.acquireRequest(newFrame(3, 10)))
+		memory.releaseResponse(first)
+		require.Equal(t, int64(0), memory.usedBytes())
+		require.True(t, memory.acquireResponse(newFrame(2, 10)))
+	})
+}
+
+func TestClientHandler_ReleaseRequestMemory(t *testing.T) {
+	newClientHandler := func() *ClientHandler {
+		connLogger := &atomic.Value{}
+		connLogger.Store(log.NewEntry(log.StandardLogger()))
+		return &ClientHandler{
+			conf:                          &config.Config{ProxyRequestTimeoutErrors: false},
+			clientConnector:               &ClientConnector{memory: newConnectionMemory(1000)},
+			connLogger:                    connLogger,
+			clientHandlerRequestWaitGroup: &sync.WaitGroup{},
+			requestContextHolders:         &sync.Map{},
+		}
+	}
+	startRequest := func(t *testing.T, ch *ClientHandler) (*requestContextHolder, *requestContextImpl) {
+		query := &message.Query{Query: "SELECT * FROM ks.tbl"}
+		request, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 1, query))
+		require.Nil(t, err)
+		require.True(t, ch.clientConnector.memory.acquireRequest(request))
+		require.Equal(t, int64(len(request.Body)), ch.clientConnector.memory.usedBytes())
+
+		reqCtx := NewRequestContext(request, NewGenericRequestInfo(forwardToOrigin, false, false), time.Now(), nil)
+		holder, err := storeRequestContext(ch.requestContextHolders, reqCtx)
+		require.Nil(t, err)
+		ch.clientHandlerRequestWaitGroup.Add(1)
+		return holder, reqCtx
+	}
+
+	t.Run("timed out request without response", func(t *testing.T) {
+		ch := newClientHandler()
+		holder, reqCtx := startRequest(t, ch)
+		require.True(t, reqCtx.SetTimeout(nil, reqCtx.request))
+		ch.finishRequest(holder, reqCtx)
+		require.Equal(t, int64(0), ch.clientConnector.memory.usedBytes())
+	})
+
+	t.Run("canceled request", func(t *testing.T) {
+		ch := newClientHandler()
+		holder, reqCtx := startRequest(t, ch)
+		require.True(t, reqCtx.Cancel(nil))
+		ch.cancelRequest(holder, reqCtx)
+		require.Equal(t, int64(0), ch.clientConnector.memory.usedBytes())
+	})
+}
@@ -0,0 +1,344 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"sync"
+)
+
+// consistencyStatsMaxTables limits the memory used by the statistics, requests to tables that are seen once this many
+// tables are tracked are not counted.
+const consistencyStatsMaxTables = 10000
+
+var consistencyLevelNames = map[primitive.ConsistencyLevel]string{
+	primitive.ConsistencyLevelAny:         "ANY",
+	primitive.ConsistencyLevelOne:         "ONE",
+	primitive.ConsistencyLevelTwo:         "TWO",
+	primitive.ConsistencyLevelThree:       "THREE",
+	primitive.ConsistencyLevelQuorum:      "QUORUM",
+	primitive.ConsistencyLevelAll:         "ALL",
+	primitive.ConsistencyLevelLocalQuorum: "LOCAL_QUORUM",
+	primitive.ConsistencyLevelEachQuorum:  "EACH_QUORUM",
+	primitive.ConsistencyLevelSerial:      "SERIAL",
+	primitive.ConsistencyLevelLocalSerial: "LOCAL_SERIAL",
+	primitive.ConsistencyLevelLocalOne:    "LOCAL_ONE",
+}
+
+// ConsistencyStats counts the consistency levels of the reads and writes of each table (keyspace.table) since the
+// proxy started, see ZDM_CONSISTENCY_STATS_ENABLED. Applications often assume a replication that the new cluster
+// doesn't have (e.g. LOCAL_QUORUM with a replication factor of 1) so Report recommends the replication settings of
+// each keyspace based on the consistency levels that were actually used.
+type ConsistencyStats struct {
+	lock   *sync.Mutex
+	tables map[string]*tableConsistencyCounts
+	full   bool
+}
+
+type tableConsistencyCounts struct {
+	reads  map[primitive.ConsistencyLevel]uint64
+	writes map[primitive.ConsistencyLevel]uint64
+}
+
+// ConsistencyReport is the consistency level report returned by the admin API.
+type ConsistencyReport struct {
+	Enabled   bool
+	Tables    []*TableConsistency
+	Keyspaces []*ReplicationRecommendation
+}
+
+// TableConsistency contains the number of reads and writes of a table by consistency level.
+type TableConsistency struct {
+	Table  string
+	Reads  map[string]uint64
+	Writes map[string]uint64
+}
+
+// ReplicationRecommendation is the replication that the target keyspace should have so that the consistency levels
+// used by the clients still succeed when one replica is down.
+type ReplicationRecommendation struct {
+	Keyspace string
+	Strategy string
+	// per datacenter when the strategy is NetworkTopologyStrategy, 0 if no replication factor can tolerate a replica
+	// being down (consistency level ALL)
+	MinReplicationFactor int
+	Notes                []string
+}
+
+// NewConsistencyStats returns nil if the statistics are disabled.
+func NewConsistencyStats(enabled bool) *ConsistencyStats {
+	if !enabled {
+		return nil
+	}
+	return &ConsistencyStats{
+		lock:   &sync.Mutex{},
+		tables: make(map[string]*tableConsistencyCounts),
+	}
+}
+
+// Record counts a read or a write of the table (keyspace.table) with the provided consistency level.
+func (recv *ConsistencyStats) Record(table string, write bool, consistency primitive.ConsistencyLevel) {
+	if recv == nil {
+		return
+	}
+	if _, _, err := parseQualifiedTableName(table); err != nil {
+		return // e.g. no keyspace, the request fails anyway
+	}
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	counts, ok := recv.tables[table]
+	if !ok {
+		if len(recv.tables) >= consistencyStatsMaxTables {
+			if !recv.full {
+				recv.full = true
+				log.Warnf("Consistency level statistics are tracked for %d tables already, requests to other tables "+
+					"are not counted.", consistencyStatsMaxTables)
+			}
+			return
+		}
+		counts = &tableConsistencyCounts{
+			reads:  make(map[primitive.ConsistencyLevel]uint64),
+			writes: make(map[primitive.ConsistencyLevel]uint64),
+		}
+		recv.tables[table] = counts
+	}
+	if write {
+		counts.writes[consistency]++
+	} else {
+		counts.reads[consistency]++
+	}
+}
+
+// Report returns the statistics of every table sorted by name and the replication recommendation of their keyspaces.
+func (recv *ConsistencyStats) Report() *ConsistencyReport {
+	report := &ConsistencyReport{
+		Enabled:   recv != nil,
+		Tables:    []*TableConsistency{},
+		Keyspaces: []*ReplicationRecommendation{},
+	}
+	if recv == nil {
+		return report
+	}
+	recv.lock.Lock()
+	keyspaces := make(map[string]*tableConsistencyCounts)
+	for table, counts := range recv.tables {
+		report.Tables = append(report.Tables, &TableConsistency{
+			Table:  table,
+			Reads:  consistencyCountsByName(counts.reads),
+			Writes: consistencyCountsByName(counts.writes),
+		})
+		keyspace, _, _ := parseQualifiedTableName(table) // validated by Record
+		keyspaceCounts, ok := keyspaces[keyspace]
+		if !ok {
+			keyspaceCounts = &tableConsistencyCounts{
+				reads:  make(map[primitive.ConsistencyLevel]uint64),
+				writes: make(map[primitive.ConsistencyLevel]uint64),
+			}
+			keyspaces[keyspace] = keyspaceCounts
+		}
+		for consistency, count := range counts.reads {
+			keyspaceCounts.reads[consistency] += count
+		}
+		for consistency, count := range counts.writes {
+			keyspaceCounts.writes[consistency] += count
+		}
+	}
+	recv.lock.Unlock()
+
+	for keyspace, counts := range keyspaces {
+		report.Keyspaces = append(report.Keyspaces, recommendReplication(keyspace, counts))
+	}
+	sort.Slice(report.Tables, func(i, j int) bool {
+		return report.Tables[i].Table < report.Tables[j].Table
+	})
+	sort.Slice(report.Keyspaces, func(i, j int) bool {
+		return report.Keyspaces[i].Keyspace < report.Keyspaces[j].Keyspace
+	})
+	return report
+}
+
+func consistencyCountsByName(counts map[primitive.ConsistencyLevel]uint64) map[string]uint64 {
+	countsByName := make(map[string]uint64, len(counts))
+	for consistency, count := range counts {
+		countsByName[consistencyLevelName(consistency)] += count
+	}
+	return countsByName
+}
+
+func consistencyLevelName(consistency primitive.ConsistencyLevel) string {
+	if name, ok := consistencyLevelNames[consistency]; ok {
+		return name
+	}
+	return fmt.Sprintf("UNKNOWN(%d)", uint16(consistency))
+}
+
+// requiredReplicas returns the number of replicas that must respond for a request to succeed with the provided
+// consistency level and replication factor. The serial consistency levels are used by the reads of lightweight
+// transactions which need a quorum.
+func requiredReplicas(consistency primitive.ConsistencyLevel, replicationFactor int) int {
+	switch consistency {
+	case primitive.ConsistencyLevelAny:
+		return 0 // the coordinator stores a hint if no replica is available
+	case primitive.ConsistencyLevelOne, primitive.ConsistencyLevelLocalOne:
+		return 1
+	case primitive.ConsistencyLevelTwo:
+		return 2
+	case primitive.ConsistencyLevelThree:
+		return 3
+	case primitive.ConsistencyLevelAll:
+		return replicationFactor
+	default:
+		return replicationFactor/2 + 1
+	}
+}
+
+// minReplicationFactor returns the smallest replication factor that still allows the consistency level to succeed
+// when one replica is down, 0 if there is none (ALL).
+func minReplicationFactor(consistency primitive.ConsistencyLevel) int {
+	if consistency == primitive.ConsistencyLevelAll {
+		return 0
+	}
+	replicationFactor := 1
+	for requiredReplicas(consistency, replicationFactor) > replicationFactor-1 {
+		replicationFactor++
+	}
+	return replicationFactor
+}
+
+func recommendReplication(keyspace string, counts *tableConsistencyCounts) *ReplicationRecommendation {
+	recommendation := &ReplicationRecommendation{
+		Keyspace: keyspace,
+		Strategy: "NetworkTopologyStrategy",
+		Notes:    []string{},
+	}
+	used := make(map[primitive.ConsistencyLevel]bool)
+	for consistency := range counts.reads {
+		used[consistency] = true
+	}
+	for consistency := range counts.writes {
+		used[consistency] = true
+	}
+
+	for consistency := range used {
+		if replicationFactor := minReplicationFactor(consistency); replicationFactor > recommendation.MinReplicationFactor {
+			recommendation.MinReplicationFactor = replicationFactor
+		}
+	}
+	if used[primitive.ConsistencyLevelAll] {
+		recommendation.Notes = append(recommendation.Notes,
+			"ALL fails as soon as one replica is down whatever the replication factor is.")
+	}
+	if used[primitive.ConsistencyLevelQuorum] || used[primitive.ConsistencyLevelSerial] {
+		recommendation.Notes = append(recommendation.Notes,
+			"QUORUM and SERIAL count the replicas of every datacenter: if the target cluster has more datacenters "+
+				"than origin the requests wait for remote replicas, LOCAL_QUORUM and LOCAL_SERIAL don't.")
+	}
+	if used[primitive.ConsistencyLevelEachQuorum] {
+		recommendation.Notes = append(recommendation.Notes,
+			"EACH_QUORUM needs a quorum in every datacenter of the keyspace, every datacenter needs the replication factor.")
+	}
+	if note := readYourWritesNote(counts, recommendation.MinReplicationFactor); note != "" {
+		recommendation.Notes = append(recommendation.Notes, note)
+	}
+	sort.Strings(recommendation.Notes)
+	return recommendation
+}
+
+// readYourWritesNote returns a note if the weakest read and write consistency levels don't overlap with the provided
+// replication factor, i.e. a read may not see a write that succeeded.
+func readYourWritesNote(counts *tableConsistencyCounts, replicationFactor int) string {
+	if replicationFactor == 0 || len(counts.reads) == 0 || len(counts.writes) == 0 {
+		return ""
+	}
+	weakest := func(consistencies map[primitive.ConsistencyLevel]uint64) primitive.ConsistencyLevel {
+		var weakestConsistency primitive.ConsistencyLevel
+		weakestReplicas := -1
+		for consistency := range consistencies {
+			replicas := requiredReplicas(consistency, replicationFactor)
+			if weakestReplicas == -1 || replicas < weakestReplicas ||
+				(replicas == weakestReplicas && consistency < weakestConsistency) {
+				weakestConsistency, weakestReplicas = consistency, replicas
+			}
+		}
+		return weakestConsistency
+	}
+	read, write := weakest(counts.reads), weakest(counts.writes)
+	if requiredReplicas(read, replicationFactor)+requiredReplicas(write, replicationFactor) > replicationFactor {
+		return ""
+	}
+	return fmt.Sprintf("With a replication factor of %d, reads at %v may not see the writes done at %v.",
+		replicationFactor, consistencyLevelName(read), consistencyLevelName(write))
+}
+
+// recordRequestConsistency counts the consistency level of a QUERY, EXECUTE or BATCH request for every table that it
+// reads or writes. Requests that don't target a table (e.g. system queries or DDL) are not counted.
+func recordRequestConsistency(
+	stats *ConsistencyStats, frameContext *frameDecodeContext, requestInfo RequestInfo, currentKeyspace string,
+	timeUuidGenerator TimeUuidGenerator) error {
+	if stats == nil {
+		return nil
+	}
+	opCode := frameContext.GetRawFrame().Header.OpCode
+	if opCode != primitive.OpCodeQuery && opCode != primitive.OpCodeExecute && opCode != primitive.OpCodeBatch {
+		return nil
+	}
+	decodedFrame, err := frameContext.GetOrDecodeFrame()
+	if err != nil {
+		return err
+	}
+
+	switch typedMsg := decodedFrame.Body.Message.(type) {
+	case *message.Query:
+		if typedMsg.Options == nil {
+			return nil
+		}
+		stmtQueryData, err := frameContext.GetOrInspectStatement(currentKeyspace, timeUuidGenerator)
+		if err != nil {
+			return err
+		}
+		queryInfo := stmtQueryData.queryData
+		table := qualifiedTableName(queryInfo.getApplicableKeyspace(), queryInfo.getTableName())
+		if isTableRead(queryInfo) {
+			stats.Record(table, false, typedMsg.Options.Consistency)
+		} else if isTableWrite(queryInfo) {
+			stats.Record(table, true, typedMsg.Options.Consistency)
+		}
+	case *message.Execute:
+		executeRequestInfo, ok := requestInfo.(*ExecuteRequestInfo)
+		if !ok || typedMsg.Options == nil {
+			return nil
+		}
+		prepareRequestInfo := executeRequestInfo.GetPreparedData().GetPrepareRequestInfo()
+		if table := prepareRequestInfo.GetReadTable(); table != "" {
+			stats.Record(table, false, typedMsg.Options.Consistency)
+		} else if table = prepareRequestInfo.GetWriteTable(); table != "" {
+			stats.Record(table, true, typedMsg.Options.Consistency)
+		}
+	case *message.Batch:
+		tables := make(map[string]bool)
+		if batchRequestInfo, ok := requestInfo.(*BatchRequestInfo); ok {
+			for _, preparedData := range batchRequestInfo.GetPreparedDataByStmtIdx() {
+				if table := preparedData.GetPrepareRequestInfo().GetWriteTable(); table != "" {
+					tables[table] = true
+				}
+			}
+		}
+		stmtsQueryData, err := frameContext.GetOrInspectAllStatements(currentKeyspace, timeUuidGenerator)
+		if err != nil {
+			return err
+		}
+		for _, stmtQueryData := range stmtsQueryData {
+			if isTableWrite(stmtQueryData.queryData) {
+				tables[qualifiedTableName(
+					stmtQueryData.queryData.getApplicableKeyspace(), stmtQueryData.queryData.getTableName())] = true
+			}
+		}
+		for table := range tables {
+			stats.Record(table, true, typedMsg.Consistency)
+		}
+	}
+	return nil
+}
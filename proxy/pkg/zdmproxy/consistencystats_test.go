@@ -0,0 +1,129 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConsistencyStats_Report(t *testing.T) {
+	require.Equal(t, &ConsistencyReport{
+		Tables: []*TableConsistency{}, Keyspaces: []*ReplicationRecommendation{}}, NewConsistencyStats(false).Report())
+
+	stats := NewConsistencyStats(true)
+	stats.Record("ks1.tbl", false, primitive.ConsistencyLevelLocalOne)
+	stats.Record("ks1.tbl", false, primitive.ConsistencyLevelLocalOne)
+	stats.Record("ks1.tbl", true, primitive.ConsistencyLevelLocalQuorum)
+	stats.Record("ks1.other", true, primitive.ConsistencyLevelLocalQuorum)
+	stats.Record("ks2.tbl", true, primitive.ConsistencyLevelLocalQuorum)
+	stats.Record("ks2.tbl", false, primitive.ConsistencyLevelLocalQuorum)
+	stats.Record(".tbl", false, primitive.ConsistencyLevelOne)
+
+	report := stats.Report()
+	require.True(t, report.Enabled)
+	require.Equal(t, []*TableConsistency{
+		{Table: "ks1.other", Reads: map[string]uint64{}, Writes: map[string]uint64{"LOCAL_QUORUM": 1}},
+		{Table: "ks1.tbl", Reads: map[string]uint64{"LOCAL_ONE": 2}, Writes: map[string]uint64{"LOCAL_QUORUM": 1}},
+		{Table: "ks2.tbl", Reads: map[string]uint64{"LOCAL_QUORUM": 1}, Writes: map[string]uint64{"LOCAL_QUORUM": 1}},
+	}, report.Tables)
+	require.Equal(t, []*ReplicationRecommendation{
+		{Keyspace: "ks1", Strategy: "NetworkTopologyStrategy", MinReplicationFactor: 3, Notes: []string{
+			"With a replication factor of 3, reads at LOCAL_ONE may not see the writes done at LOCAL_QUORUM."}},
+		{Keyspace: "ks2", Strategy: "NetworkTopologyStrategy", MinReplicationFactor: 3, Notes: []string{}},
+	}, report.Keyspaces)
+}
+
+func TestMinReplicationFactor(t *testing.T) {
+	tests := []struct {
+		consistency primitive.ConsistencyLevel
+		expected    int
+	}{
+		{primitive.ConsistencyLevelAny, 1},
+		{primitive.ConsistencyLevelOne, 2},
+		{primitive.ConsistencyLevelLocalOne, 2},
+		{primitive.ConsistencyLevelTwo, 3},
+		{primitive.ConsistencyLevelThree, 4},
+		{primitive.ConsistencyLevelQuorum, 3},
+		{primitive.ConsistencyLevelLocalQuorum, 3},
+		{primitive.ConsistencyLevelEachQuorum, 3},
+		{primitive.ConsistencyLevelLocalSerial, 3},
+		{primitive.ConsistencyLevelAll, 0},
+	}
+	for _, tt := range tests {
+		t.Run(consistencyLevelName(tt.consistency), func(t *testing.T) {
+			require.Equal(t, tt.expected, minReplicationFactor(tt.consistency))
+		})
+	}
+
+	recommendation := recommendReplication("ks", &tableConsistencyCounts{
+		reads:  map[primitive.ConsistencyLevel]uint64{primitive.ConsistencyLevelAll: 1},
+		writes: map[primitive.ConsistencyLevel]uint64{primitive.ConsistencyLevelQuorum: 1},
+	})
+	require.Equal(t, 3, recommendation.MinReplicationFactor)
+	require.Equal(t, 2, len(recommendation.Notes))
+}
+
+func TestRecordRequestConsistency(t *testing.T) {
+	preparedData := func(query string) PreparedData {
+		queryInfo := inspectCqlQuery(query, "", &fakeTimeUuidGenerator{})
+		prepareRequestInfo := NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, true), nil, false,
+			query, "")
+		table := qualifiedTableName(queryInfo.getApplicableKeyspace(), queryInfo.getTableName())
+		if isTableRead(queryInfo) {
+			prepareRequestInfo = prepareRequestInfo.withReadTable(table)
+		} else if isTableWrite(queryInfo) {
+			prepareRequestInfo = prepareRequestInfo.withWriteTable(table)
+		}
+		return NewPreparedData(
+			&message.PreparedResult{PreparedQueryId: []byte("origin")},
+			&message.PreparedResult{PreparedQueryId: []byte("target")},
+			prepareRequestInfo)
+	}
+	localQuorum := &message.QueryOptions{Consistency: primitive.ConsistencyLevelLocalQuorum}
+
+	tests := []struct {
+		name        string
+		msg         message.Message
+		requestInfo RequestInfo
+		expected    []*TableConsistency
+	}{
+		{"read query",
+			&message.Query{Query: "SELECT * FROM ks.tbl", Options: localQuorum},
+			NewGenericRequestInfo(forwardToOrigin, false, true),
+			[]*TableConsistency{{Table: "ks.tbl", Reads: map[string]uint64{"LOCAL_QUORUM": 1}, Writes: map[string]uint64{}}}},
+		{"write query",
+			&message.Query{Query: "INSERT INTO ks.tbl (pk) VALUES (1)", Options: localQuorum},
+			NewGenericRequestInfo(forwardToBoth, false, true),
+			[]*TableConsistency{{Table: "ks.tbl", Reads: map[string]uint64{}, Writes: map[string]uint64{"LOCAL_QUORUM": 1}}}},
+		{"system query",
+			&message.Query{Query: "SELECT * FROM system.local", Options: localQuorum},
+			NewGenericRequestInfo(forwardToOrigin, false, true),
+			[]*TableConsistency{}},
+		{"execute",
+			&message.Execute{QueryId: []byte("origin"), Options: localQuorum},
+			NewExecuteRequestInfo(preparedData("SELECT * FROM ks.tbl WHERE pk = ?")),
+			[]*TableConsistency{{Table: "ks.tbl", Reads: map[string]uint64{"LOCAL_QUORUM": 1}, Writes: map[string]uint64{}}}},
+		{"batch",
+			&message.Batch{Consistency: primitive.ConsistencyLevelQuorum, Children: []*message.BatchChild{
+				{Query: "INSERT INTO ks.tbl1 (pk) VALUES (1)"},
+				{Query: "INSERT INTO ks.tbl1 (pk) VALUES (2)"},
+				{Id: []byte("origin")}}},
+			NewBatchRequestInfo(map[int]PreparedData{2: preparedData("DELETE FROM ks.tbl2 WHERE pk = ?")}),
+			[]*TableConsistency{
+				{Table: "ks.tbl1", Reads: map[string]uint64{}, Writes: map[string]uint64{"QUORUM": 1}},
+				{Table: "ks.tbl2", Reads: map[string]uint64{}, Writes: map[string]uint64{"QUORUM": 1}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rawFrame, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 1, tt.msg))
+			require.Nil(t, err)
+			stats := NewConsistencyStats(true)
+			err = recordRequestConsistency(
+				stats, NewFrameDecodeContext(rawFrame), tt.requestInfo, "", &fakeTimeUuidGenerator{})
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, stats.Report().Tables)
+		})
+	}
+}
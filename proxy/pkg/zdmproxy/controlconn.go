@@ -9,6 +9,7 @@ import (
 	"github.com/datastax/go-cassandra-native-protocol/primitive"
 	"github.com/datastax/zdm-proxy/proxy/pkg/common"
 	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/crash"
 	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
 	"github.com/google/uuid"
 	"github.com/jpillora/backoff"
@@ -55,12 +56,11 @@ type ControlConn struct {
 	protocolEventSubscribers map[ProtocolEventObserver]interface{}
 	authEnabled              *atomic.Value
 	metricsHandler           *metrics.MetricHandler
+	nodeHealth               *NodeHealthTracker
 }
 
 const ProxyVirtualRack = "rack0"
 const ProxyVirtualPartitioner = "org.apache.cassandra.dht.Murmur3Partitioner"
-const ccWriteTimeout = 5 * time.Second
-const ccReadTimeout = 10 * time.Second
 
 func NewControlConn(ctx context.Context, defaultPort int, connConfig ConnectionConfig,
 	username string, password string, conf *config.Config, topologyConfig *common.TopologyConfig, proxyRand *rand.Rand,
@@ -102,6 +102,7 @@ func NewControlConn(ctx context.Context, defaultPort int, connConfig ConnectionC
 		protocolEventSubscribers: map[ProtocolEventObserver]interface{}{},
 		authEnabled:              authEnabled,
 		metricsHandler:           metricsHandler,
+		nodeHealth:               NewNodeHealthTracker(connConfig.GetClusterType(), conf, proxyRand),
 	}
 }
 
@@ -113,6 +114,7 @@ func (cc *ControlConn) Start(wg *sync.WaitGroup, ctx context.Context) error {
 
 	wg.Add(1)
 	go func() {
+		defer crash.HandlePanic()
 		defer wg.Done()
 		defer log.Infof("Shutting down refresh topology debouncer of control connection %v.", cc.connConfig.GetClusterType())
 		for cc.context.Err() == nil {
@@ -145,6 +147,7 @@ func (cc *ControlConn) Start(wg *sync.WaitGroup, ctx context.Context) error {
 
 	wg.Add(1)
 	go func() {
+		defer crash.HandlePanic()
 		defer wg.Done()
 		defer cc.Close()
 		defer log.Infof("Shutting down control connection to %v,", cc.connConfig.GetClusterType())
@@ -220,6 +223,13 @@ func (cc *ControlConn) Start(wg *sync.WaitGroup, ctx context.Context) error {
 	return nil
 }
 
+// isUpstreamZdmProxy returns true if this control connection is connected to another ZDM proxy instead of a cluster.
+// Topology events received from an upstream proxy describe its target cluster (or are suppressed when virtualization
+// is enabled) so they don't match the hosts returned by its virtualized system tables.
+func (cc *ControlConn) isUpstreamZdmProxy() bool {
+	return cc.connConfig.GetClusterType() == common.ClusterTypeOrigin && cc.conf.OriginIsZdmProxy
+}
+
 func (cc *ControlConn) IsAuthEnabled() (bool, error) {
 	if authEnabled := cc.authEnabled.Load(); authEnabled != nil {
 		return authEnabled.(bool), nil
@@ -324,7 +334,11 @@ func (cc *ControlConn) openInternal(endpoints []Endpoint, ctx context.Context) (
 		maxProtoVer, _ := cc.conf.ParseControlConnMaxProtocolVersion()
 		newConn, err := cc.connAndNegotiateProtoVer(endpoint, maxProtoVer, ctx)
 
-		if err == nil {
+		if err == nil && cc.isUpstreamZdmProxy() {
+			log.Debugf("%v contact points are ZDM proxy instances, skipping topology event subscription.",
+				cc.connConfig.GetClusterType())
+			_, err = cc.RefreshHosts(newConn, ctx)
+		} else if err == nil {
 			newConn.SetEventHandler(func(f *frame.Frame, c CqlConnection) {
 				switch f.Body.Message.(type) {
 				case *message.TopologyChangeEvent:
@@ -378,7 +392,9 @@ func (cc *ControlConn) connAndNegotiateProtoVer(endpoint Endpoint, initialProtoV
 				cc.connConfig.GetClusterType(), endpoint.GetEndpointIdentifier(), err)
 			return nil, err
 		}
-		newConn := NewCqlConnection(endpoint, tcpConn, cc.username, cc.password, ccReadTimeout, ccWriteTimeout, cc.conf, protoVer)
+		newConn := NewCqlConnection(endpoint, tcpConn, cc.username, cc.password,
+			time.Duration(cc.conf.ProxyControlConnectionReadTimeoutMs)*time.Millisecond,
+			time.Duration(cc.conf.ProxyControlConnectionWriteTimeoutMs)*time.Millisecond, cc.conf, protoVer)
 		err = newConn.InitializeContext(protoVer, ctx)
 		var respErr *ResponseError
 		if err != nil && errors.As(err, &respErr) && respErr.IsProtocolError() && strings.Contains(err.Error(), "Invalid or unsupported protocol version") {
@@ -440,12 +456,17 @@ func (cc *ControlConn) RefreshHosts(conn CqlConnection, ctx context.Context) ([]
 		return nil, err
 	}
 
+	cc.topologyLock.RLock()
+	topologyConfig := cc.topologyConfig
+	currentDc := cc.datacenter
+	cc.topologyLock.RUnlock()
+
 	partitionerColValue, partitionerExists := localInfo[partitionerColumn.Name]
 	var partitioner *string
 	if partitionerExists {
 		partitioner = partitionerColValue.AsNillableString()
 	}
-	if partitioner != nil && !strings.Contains(*partitioner, "Murmur3Partitioner") && cc.topologyConfig.VirtualizationEnabled {
+	if partitioner != nil && !strings.Contains(*partitioner, "Murmur3Partitioner") && topologyConfig.VirtualizationEnabled {
 		if strings.Contains(*partitioner, "RandomPartitioner") {
 			log.Debugf("Cluster %v uses the Random partitioner, but the proxy will return Murmur3 to the client instead. This is the expected behaviour.", cc.connConfig.GetClusterType())
 		} else {
@@ -485,10 +506,6 @@ func (cc *ControlConn) RefreshHosts(conn CqlConnection, ctx context.Context) ([]
 		orderedLocalHosts = append(orderedLocalHosts, h)
 	}
 
-	cc.topologyLock.RLock()
-	currentDc := cc.datacenter
-	cc.topologyLock.RUnlock()
-
 	orderedLocalHosts, currentDc, err = filterHosts(orderedLocalHosts, currentDc, cc.connConfig, localHost)
 	if err != nil {
 		return nil, err
@@ -502,23 +519,23 @@ func (cc *ControlConn) RefreshHosts(conn CqlConnection, ctx context.Context) ([]
 		return orderedLocalHosts[i].Rack < orderedLocalHosts[j].Rack
 	})
 
-	assignedHosts := computeAssignedHosts(cc.topologyConfig.Index, cc.topologyConfig.Count, orderedLocalHosts)
-	shuffleHosts(cc.proxyRand, assignedHosts)
-
-	var virtualHosts []*VirtualHost
-	if cc.topologyConfig.VirtualizationEnabled {
-		virtualHosts, err = computeVirtualHosts(cc.topologyConfig, orderedLocalHosts)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		virtualHosts = make([]*VirtualHost, 0)
+	assignedHosts, virtualHosts, err := cc.computeHostsForTopology(topologyConfig, orderedLocalHosts)
+	if err != nil {
+		return nil, err
 	}
 
 	log.Infof("Refreshed %v orderedHostsInLocalDc. Assigned Hosts: %v, VirtualHosts: %v, ProxyTopologyIndex: %v",
-		cc.connConfig.GetClusterType(), assignedHosts, virtualHosts, cc.topologyConfig.Index)
+		cc.connConfig.GetClusterType(), assignedHosts, virtualHosts, topologyConfig.Index)
 
 	cc.topologyLock.Lock()
+	if cc.topologyConfig != topologyConfig {
+		// the proxy topology was updated while the hosts were being refreshed
+		assignedHosts, virtualHosts, err = cc.computeHostsForTopology(cc.topologyConfig, orderedLocalHosts)
+		if err != nil {
+			cc.topologyLock.Unlock()
+			return nil, err
+		}
+	}
 	if cc.datacenter == "" {
 		cc.datacenter = currentDc
 	}
@@ -549,6 +566,41 @@ func (cc *ControlConn) RefreshHosts(conn CqlConnection, ctx context.Context) ([]
 	return orderedLocalHosts, nil
 }
 
+func (cc *ControlConn) computeHostsForTopology(
+	topologyConfig *common.TopologyConfig, orderedLocalHosts []*Host) ([]*Host, []*VirtualHost, error) {
+	assignedHosts := computeAssignedHosts(topologyConfig.Index, topologyConfig.Count, orderedLocalHosts)
+	shuffleHosts(cc.proxyRand, assignedHosts)
+
+	if !topologyConfig.VirtualizationEnabled {
+		return assignedHosts, make([]*VirtualHost, 0), nil
+	}
+	virtualHosts, err := computeVirtualHosts(topologyConfig, orderedLocalHosts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return assignedHosts, virtualHosts, nil
+}
+
+// UpdateTopologyConfig replaces the proxy topology (e.g. when proxy instances are added or removed) and recomputes
+// the assigned hosts and the virtual hosts returned to clients in system.local and system.peers.
+func (cc *ControlConn) UpdateTopologyConfig(topologyConfig *common.TopologyConfig) error {
+	cc.topologyLock.Lock()
+	defer cc.topologyLock.Unlock()
+
+	if cc.orderedHostsInLocalDc != nil {
+		assignedHosts, virtualHosts, err := cc.computeHostsForTopology(topologyConfig, cc.orderedHostsInLocalDc)
+		if err != nil {
+			return err
+		}
+		cc.assignedHosts = assignedHosts
+		cc.virtualHosts = virtualHosts
+		log.Infof("Updated %v proxy topology. Assigned Hosts: %v, VirtualHosts: %v, ProxyTopologyIndex: %v",
+			cc.connConfig.GetClusterType(), assignedHosts, virtualHosts, topologyConfig.Index)
+	}
+	cc.topologyConfig = topologyConfig
+	return nil
+}
+
 func (cc *ControlConn) GetHostsInLocalDatacenter() (map[uuid.UUID]*Host, error) {
 	cc.topologyLock.RLock()
 	defer cc.topologyLock.RUnlock()
@@ -587,9 +639,28 @@ func (cc *ControlConn) GetVirtualHosts() ([]*VirtualHost, error) {
 }
 
 func (cc *ControlConn) GetLocalVirtualHostIndex() int {
+	cc.topologyLock.RLock()
+	defer cc.topologyLock.RUnlock()
+
 	return cc.topologyConfig.Index
 }
 
+// GetVirtualHostsAndLocalIndex returns the virtual hosts and the index of the local one, both from the same topology.
+func (cc *ControlConn) GetVirtualHostsAndLocalIndex() ([]*VirtualHost, int, error) {
+	cc.topologyLock.RLock()
+	defer cc.topologyLock.RUnlock()
+
+	if !cc.topologyConfig.VirtualizationEnabled {
+		return nil, 0, fmt.Errorf("could not get virtual hosts because virtualization is not enabled")
+	}
+
+	if cc.virtualHosts == nil {
+		return nil, 0, fmt.Errorf("could not get virtual hosts because topology information has not been retrieved yet")
+	}
+
+	return cc.virtualHosts, cc.topologyConfig.Index, nil
+}
+
 func (cc *ControlConn) GetAssignedHosts() ([]*Host, error) {
 	cc.topologyLock.RLock()
 	defer cc.topologyLock.RUnlock()
@@ -610,8 +681,44 @@ func (cc *ControlConn) NextAssignedHost() (*Host, error) {
 	}
 
 	assignment := cc.incCurrentAssignmentCounter(len(cc.assignedHosts))
+	host := cc.assignedHosts[assignment]
+	if cc.nodeHealth.Allow(host) {
+		return host, nil
+	}
+
+	// the connections of a quarantined node are spread randomly across the other nodes,
+	// every node is used if all of them are quarantined
+	allowedHosts := make([]*Host, 0, len(cc.assignedHosts))
+	for _, candidate := range cc.assignedHosts {
+		if candidate != host && cc.nodeHealth.Allow(candidate) {
+			allowedHosts = append(allowedHosts, candidate)
+		}
+	}
+	if len(allowedHosts) == 0 {
+		return host, nil
+	}
+	return allowedHosts[cc.proxyRand.Intn(len(allowedHosts))], nil
+}
 
-	return cc.assignedHosts[assignment], nil
+// ReportNodeFailure adds a failure to the health score of the node, it does nothing if node health scoring is disabled.
+func (cc *ControlConn) ReportNodeFailure(host *Host, failure NodeFailure) {
+	cc.nodeHealth.RecordFailure(host, failure)
+}
+
+func (cc *ControlConn) IsNodeHealthEnabled() bool {
+	return cc.nodeHealth != nil
+}
+
+// GetNodeHealth returns the health of the nodes in the local datacenter.
+func (cc *ControlConn) GetNodeHealth() []*NodeHealthStatus {
+	cc.topologyLock.RLock()
+	defer cc.topologyLock.RUnlock()
+
+	statuses := make([]*NodeHealthStatus, 0, len(cc.orderedHostsInLocalDc))
+	for _, host := range cc.orderedHostsInLocalDc {
+		statuses = append(statuses, cc.nodeHealth.Status(host))
+	}
+	return statuses
 }
 
 func (cc *ControlConn) GetClusterName() string {
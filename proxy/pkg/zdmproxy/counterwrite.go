@@ -0,0 +1,53 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+)
+
+// isCounterWrite returns true if the QUERY, EXECUTE or BATCH request updates counter columns. Counter updates are not
+// idempotent: a counter update that is retried, or that is applied on a cluster whose counters were already migrated,
+// changes the counters of the two clusters by different amounts.
+func isCounterWrite(
+	frameContext *frameDecodeContext, requestInfo RequestInfo, currentKeyspace string,
+	timeUuidGenerator TimeUuidGenerator) (bool, error) {
+	if executeRequestInfo, ok := requestInfo.(*ExecuteRequestInfo); ok {
+		return isPreparedCounterUpdate(executeRequestInfo.GetPreparedData()), nil
+	}
+
+	decodedFrame, err := frameContext.GetOrDecodeFrame()
+	if err != nil {
+		return false, err
+	}
+	switch typedMsg := decodedFrame.Body.Message.(type) {
+	case *message.Query:
+		stmtQueryData, err := frameContext.GetOrInspectStatement(currentKeyspace, timeUuidGenerator)
+		if err != nil {
+			return false, err
+		}
+		return stmtQueryData.queryData.isCounterUpdate(), nil
+	case *message.Batch:
+		// counter updates are only allowed in COUNTER batches and COUNTER batches only allow counter updates
+		return typedMsg.Type == primitive.BatchTypeCounter, nil
+	default:
+		return false, nil
+	}
+}
+
+// isPreparedCounterUpdate returns true if the prepared statement increments a counter column by an integer literal or
+// if one of its bind markers is a counter value.
+func isPreparedCounterUpdate(preparedData PreparedData) bool {
+	if preparedData.GetPrepareRequestInfo().IsCounterUpdate() {
+		return true
+	}
+	variablesMetadata := preparedData.GetOriginVariablesMetadata()
+	if variablesMetadata == nil {
+		return false
+	}
+	for _, column := range variablesMetadata.Columns {
+		if column.Type != nil && column.Type.Code() == primitive.DataTypeCodeCounter {
+			return true
+		}
+	}
+	return false
+}
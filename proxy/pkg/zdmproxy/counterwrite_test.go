@@ -0,0 +1,73 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestIsCounterWrite(t *testing.T) {
+	preparedData := func(query string, variableTypes ...datatype.DataType) PreparedData {
+		queryInfo := inspectCqlQuery(query, "", &fakeTimeUuidGenerator{})
+		prepareRequestInfo := NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, true), nil, false,
+			query, "")
+		if queryInfo.isCounterUpdate() {
+			prepareRequestInfo = prepareRequestInfo.withCounterUpdate()
+		}
+		variablesMetadata := &message.VariablesMetadata{}
+		for _, variableType := range variableTypes {
+			variablesMetadata.Columns = append(variablesMetadata.Columns,
+				&message.ColumnMetadata{Keyspace: "ks", Table: "tbl", Name: "col", Type: variableType})
+		}
+		return NewPreparedData(
+			&message.PreparedResult{PreparedQueryId: []byte("origin"), VariablesMetadata: variablesMetadata},
+			&message.PreparedResult{PreparedQueryId: []byte("target"), VariablesMetadata: variablesMetadata},
+			prepareRequestInfo)
+	}
+
+	tests := []struct {
+		name        string
+		msg         message.Message
+		requestInfo RequestInfo
+		expected    bool
+	}{
+		{"query",
+			&message.Query{Query: "UPDATE ks.tbl SET v = 1 WHERE pk = 1"},
+			NewGenericRequestInfo(forwardToBoth, false, true), false},
+		{"counter query",
+			&message.Query{Query: "UPDATE ks.tbl SET c = c + 1 WHERE pk = 1"},
+			NewGenericRequestInfo(forwardToBoth, false, true), true},
+		{"execute",
+			&message.Execute{QueryId: []byte("origin"), Options: &message.QueryOptions{}},
+			NewExecuteRequestInfo(preparedData("UPDATE ks.tbl SET v = ? WHERE pk = ?", datatype.Int, datatype.Int)),
+			false},
+		{"counter execute with literal",
+			&message.Execute{QueryId: []byte("origin"), Options: &message.QueryOptions{}},
+			NewExecuteRequestInfo(preparedData("UPDATE ks.tbl SET c = c + 1 WHERE pk = ?", datatype.Int)), true},
+		{"counter execute with bind marker",
+			&message.Execute{QueryId: []byte("origin"), Options: &message.QueryOptions{}},
+			NewExecuteRequestInfo(preparedData("UPDATE ks.tbl SET c = c + ? WHERE pk = ?", datatype.Counter, datatype.Int)),
+			true},
+		{"batch",
+			&message.Batch{Type: primitive.BatchTypeLogged, Children: []*message.BatchChild{
+				{Query: "UPDATE ks.tbl SET v = 1 WHERE pk = 1"}}},
+			NewBatchRequestInfo(map[int]PreparedData{}), false},
+		{"counter batch",
+			&message.Batch{Type: primitive.BatchTypeCounter, Children: []*message.BatchChild{
+				{Query: "UPDATE ks.tbl SET c = c + 1 WHERE pk = 1"}}},
+			NewBatchRequestInfo(map[int]PreparedData{}), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rawFrame, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 1, tt.msg))
+			require.Nil(t, err)
+			counterWrite, err := isCounterWrite(
+				NewFrameDecodeContext(rawFrame), tt.requestInfo, "", &fakeTimeUuidGenerator{})
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, counterWrite)
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/datastax/go-cassandra-native-protocol/message"
 	"github.com/datastax/go-cassandra-native-protocol/primitive"
 	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/crash"
 	log "github.com/sirupsen/logrus"
 	"io"
 	"net"
@@ -141,6 +142,7 @@ func (c *cqlConn) SubscribeToProtocolEvents(ctx context.Context, eventTypes []pr
 func (c *cqlConn) StartResponseLoop() {
 	c.wg.Add(1)
 	go func() {
+		defer crash.HandlePanic()
 		defer c.wg.Done()
 		defer close(c.eventsQueue)
 		defer log.Debugf("Shutting down response loop on %v.", c)
@@ -197,6 +199,7 @@ func (c *cqlConn) StartResponseLoop() {
 func (c *cqlConn) StartRequestLoop() {
 	c.wg.Add(1)
 	go func() {
+		defer crash.HandlePanic()
 		defer c.wg.Done()
 		defer log.Debug("Shutting down request loop on ", c)
 		for c.ctx.Err() == nil {
@@ -220,6 +223,7 @@ func (c *cqlConn) StartRequestLoop() {
 func (c *cqlConn) StartEventLoop() {
 	c.wg.Add(1)
 	go func() {
+		defer crash.HandlePanic()
 		defer c.wg.Done()
 		defer log.Debugf("Shutting down event loop on %v.", c)
 
@@ -59,6 +59,39 @@ func (uee *UnpreparedExecuteError) Error() string {
 	return fmt.Sprintf("The preparedID of the statement to be executed (%s) does not exist in the proxy cache", hex.EncodeToString(uee.preparedId))
 }
 
+// requestInfoBuildContext contains everything that the request info builders need to build the RequestInfo of a request.
+type requestInfoBuildContext struct {
+	frameContext                 *frameDecodeContext
+	stmtsReplacedTerms           []*statementReplacedTerms
+	psCache                      *PreparedStatementCache
+	mh                           *metrics.MetricHandler
+	currentKeyspaceName          string
+	primaryCluster               common.ClusterType
+	readCluster                  common.ClusterType
+	tableReadRouting             *TableReadRouting
+	readShift                    *ReadShift
+	forwardSystemQueriesToTarget bool
+	virtualizationEnabled        bool
+	forwardAuthToTarget          bool
+	timeUuidGenerator            TimeUuidGenerator
+}
+
+// requestInfoBuilder builds the RequestInfo (i.e. the forward decision) of a request with a specific opcode.
+type requestInfoBuilder func(ctx *requestInfoBuildContext) (RequestInfo, error)
+
+// requestInfoBuilders contains the builder of every opcode that needs special handling, requests with any other opcode
+// are passed through to both clusters (see buildPassthroughRequestInfo). Supporting a new opcode (e.g. a DSE extension)
+// only requires adding its builder here.
+var requestInfoBuilders = map[primitive.OpCode]requestInfoBuilder{
+	primitive.OpCodeQuery:        buildQueryRequestInfo,
+	primitive.OpCodePrepare:      buildPrepareRequestInfo,
+	primitive.OpCodeBatch:        buildBatchRequestInfo,
+	primitive.OpCodeExecute:      buildExecuteRequestInfo,
+	primitive.OpCodeAuthResponse: buildAuthResponseRequestInfo,
+	primitive.OpCodeRegister:     buildHandshakeRequestInfo,
+	primitive.OpCodeStartup:      buildHandshakeRequestInfo,
+}
+
 func buildRequestInfo(
 	frameContext *frameDecodeContext,
 	stmtsReplacedTerms []*statementReplacedTerms,
@@ -66,91 +99,150 @@ func buildRequestInfo(
 	mh *metrics.MetricHandler,
 	currentKeyspaceName string,
 	primaryCluster common.ClusterType,
+	readCluster common.ClusterType,
+	tableReadRouting *TableReadRouting,
+	readShift *ReadShift,
 	forwardSystemQueriesToTarget bool,
 	virtualizationEnabled bool,
 	forwardAuthToTarget bool,
 	timeUuidGenerator TimeUuidGenerator) (RequestInfo, error) {
 
-	f := frameContext.GetRawFrame()
-	switch f.Header.OpCode {
-	case primitive.OpCodeQuery:
-		stmtQueryData, err := frameContext.GetOrInspectStatement(currentKeyspaceName, timeUuidGenerator)
-		if err != nil {
-			return nil, fmt.Errorf("could not inspect QUERY frame: %w", err)
-		}
-		return getRequestInfoFromQueryInfo(
-			frameContext.GetRawFrame(), primaryCluster,
-			forwardSystemQueriesToTarget, virtualizationEnabled, stmtQueryData.queryData), nil
-	case primitive.OpCodePrepare:
-		stmtQueryData, err := frameContext.GetOrInspectStatement(currentKeyspaceName, timeUuidGenerator)
-		if err != nil {
-			return nil, fmt.Errorf("could not inspect PREPARE frame: %w", err)
-		}
-		decodedFrame, err := frameContext.GetOrDecodeFrame()
-		if err != nil {
-			return nil, fmt.Errorf("could not decode frame: %w", err)
-		}
-		prepareMsg, ok := decodedFrame.Body.Message.(*message.Prepare)
-		if !ok {
-			return nil, fmt.Errorf("unexpected message type when decoding PREPARE message: %v", decodedFrame.Body.Message)
-		}
-		baseRequestInfo := getRequestInfoFromQueryInfo(
-			frameContext.GetRawFrame(), primaryCluster,
-			forwardSystemQueriesToTarget, virtualizationEnabled, stmtQueryData.queryData)
-		replacedTerms := make([]*term, 0)
-		if len(stmtsReplacedTerms) > 1 {
-			return nil, fmt.Errorf("expected single list of replaced terms for prepare message but got %v", len(stmtsReplacedTerms))
-		} else if len(stmtsReplacedTerms) == 1 {
-			replacedTerms = stmtsReplacedTerms[0].replacedTerms
-		}
-		return NewPrepareRequestInfo(baseRequestInfo, replacedTerms, stmtQueryData.queryData.hasPositionalBindMarkers(), prepareMsg.Query, prepareMsg.Keyspace), nil
-	case primitive.OpCodeBatch:
-		decodedFrame, err := frameContext.GetOrDecodeFrame()
-		if err != nil {
-			return nil, fmt.Errorf("could not decode batch raw frame: %w", err)
-		}
-		batchMsg, ok := decodedFrame.Body.Message.(*message.Batch)
-		if !ok {
-			return nil, fmt.Errorf("could not convert message with batch op code to batch type, got %v instead", decodedFrame.Body.Message)
-		}
-		preparedDataByStmtIdxMap := make(map[int]PreparedData)
-		for childIdx, child := range batchMsg.Children {
-			if child.Id != nil {
-				preparedData, err := getPreparedData(psCache, mh, child.Id, primitive.OpCodeBatch, decodedFrame)
-				if err != nil {
-					return nil, err
-				} else {
-					preparedDataByStmtIdxMap[childIdx] = preparedData
-				}
+	ctx := &requestInfoBuildContext{
+		frameContext:                 frameContext,
+		stmtsReplacedTerms:           stmtsReplacedTerms,
+		psCache:                      psCache,
+		mh:                           mh,
+		currentKeyspaceName:          currentKeyspaceName,
+		primaryCluster:               primaryCluster,
+		readCluster:                  readCluster,
+		tableReadRouting:             tableReadRouting,
+		readShift:                    readShift,
+		forwardSystemQueriesToTarget: forwardSystemQueriesToTarget,
+		virtualizationEnabled:        virtualizationEnabled,
+		forwardAuthToTarget:          forwardAuthToTarget,
+		timeUuidGenerator:            timeUuidGenerator,
+	}
+	builder, ok := requestInfoBuilders[frameContext.GetRawFrame().Header.OpCode]
+	if !ok {
+		builder = buildPassthroughRequestInfo
+	}
+	return builder(ctx)
+}
+
+func buildQueryRequestInfo(ctx *requestInfoBuildContext) (RequestInfo, error) {
+	stmtQueryData, err := ctx.frameContext.GetOrInspectStatement(ctx.currentKeyspaceName, ctx.timeUuidGenerator)
+	if err != nil {
+		return nil, fmt.Errorf("could not inspect QUERY frame: %w", err)
+	}
+	return getRequestInfoFromQueryInfo(
+		ctx.frameContext.GetRawFrame(), ctx.primaryCluster, ctx.readCluster, ctx.tableReadRouting, ctx.readShift,
+		ctx.forwardSystemQueriesToTarget, ctx.virtualizationEnabled, stmtQueryData.queryData), nil
+}
+
+func buildPrepareRequestInfo(ctx *requestInfoBuildContext) (RequestInfo, error) {
+	stmtQueryData, err := ctx.frameContext.GetOrInspectStatement(ctx.currentKeyspaceName, ctx.timeUuidGenerator)
+	if err != nil {
+		return nil, fmt.Errorf("could not inspect PREPARE frame: %w", err)
+	}
+	decodedFrame, err := ctx.frameContext.GetOrDecodeFrame()
+	if err != nil {
+		return nil, fmt.Errorf("could not decode frame: %w", err)
+	}
+	prepareMsg, ok := decodedFrame.Body.Message.(*message.Prepare)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type when decoding PREPARE message: %v", decodedFrame.Body.Message)
+	}
+	baseRequestInfo := getRequestInfoFromQueryInfo(
+		ctx.frameContext.GetRawFrame(), ctx.primaryCluster, ctx.readCluster, ctx.tableReadRouting, ctx.readShift,
+		ctx.forwardSystemQueriesToTarget, ctx.virtualizationEnabled, stmtQueryData.queryData)
+	replacedTerms := make([]*term, 0)
+	if len(ctx.stmtsReplacedTerms) > 1 {
+		return nil, fmt.Errorf("expected single list of replaced terms for prepare message but got %v", len(ctx.stmtsReplacedTerms))
+	} else if len(ctx.stmtsReplacedTerms) == 1 {
+		replacedTerms = ctx.stmtsReplacedTerms[0].replacedTerms
+	}
+	prepareRequestInfo := NewPrepareRequestInfo(baseRequestInfo, replacedTerms, stmtQueryData.queryData.hasPositionalBindMarkers(), prepareMsg.Query, prepareMsg.Keyspace)
+	if isTableRead(stmtQueryData.queryData) {
+		// the read routing of the table can change after the statement is prepared so it is evaluated on every EXECUTE
+		prepareRequestInfo = prepareRequestInfo.withReadTable(qualifiedTableName(
+			stmtQueryData.queryData.getApplicableKeyspace(), stmtQueryData.queryData.getTableName()))
+	} else if isTableWrite(stmtQueryData.queryData) {
+		prepareRequestInfo = prepareRequestInfo.withWriteTable(qualifiedTableName(
+			stmtQueryData.queryData.getApplicableKeyspace(), stmtQueryData.queryData.getTableName()))
+	}
+	if literalTimestamps := stmtQueryData.queryData.getLiteralTimestamps(); len(literalTimestamps) > 0 {
+		prepareRequestInfo = prepareRequestInfo.withLiteralTimestamps(literalTimestamps)
+	}
+	if stmtQueryData.queryData.isConditional() {
+		prepareRequestInfo = prepareRequestInfo.withConditional()
+	}
+	if stmtQueryData.queryData.isCounterUpdate() {
+		prepareRequestInfo = prepareRequestInfo.withCounterUpdate()
+	}
+	return prepareRequestInfo, nil
+}
+
+func buildBatchRequestInfo(ctx *requestInfoBuildContext) (RequestInfo, error) {
+	decodedFrame, err := ctx.frameContext.GetOrDecodeFrame()
+	if err != nil {
+		return nil, fmt.Errorf("could not decode batch raw frame: %w", err)
+	}
+	batchMsg, ok := decodedFrame.Body.Message.(*message.Batch)
+	if !ok {
+		return nil, fmt.Errorf("could not convert message with batch op code to batch type, got %v instead", decodedFrame.Body.Message)
+	}
+	preparedDataByStmtIdxMap := make(map[int]PreparedData)
+	for childIdx, child := range batchMsg.Children {
+		if child.Id != nil {
+			preparedData, err := getPreparedData(ctx.psCache, ctx.mh, child.Id, primitive.OpCodeBatch, decodedFrame)
+			if err != nil {
+				return nil, err
+			} else {
+				preparedDataByStmtIdxMap[childIdx] = preparedData
 			}
 		}
-		return NewBatchRequestInfo(preparedDataByStmtIdxMap), nil
-	case primitive.OpCodeExecute:
-		decodedFrame, err := frameContext.GetOrDecodeFrame()
-		if err != nil {
-			return nil, fmt.Errorf("could not decode execute raw frame: %w", err)
-		}
-		executeMsg, ok := decodedFrame.Body.Message.(*message.Execute)
-		if !ok {
-			return nil, fmt.Errorf("expected Execute but got %v instead", decodedFrame.Body.Message.GetOpCode())
-		}
-		preparedData, err := getPreparedData(psCache, mh, executeMsg.QueryId, primitive.OpCodeExecute, decodedFrame)
-		if err != nil {
-			return nil, err
-		} else {
-			return NewExecuteRequestInfo(preparedData), nil
-		}
-	case primitive.OpCodeAuthResponse:
-		if forwardAuthToTarget {
-			return NewGenericRequestInfo(forwardToTarget, false, false), nil
-		} else {
-			return NewGenericRequestInfo(forwardToOrigin, false, false), nil
-		}
-	case primitive.OpCodeRegister, primitive.OpCodeStartup:
-		return NewGenericRequestInfo(forwardToBoth, false, false), nil
-	default:
-		return NewGenericRequestInfo(forwardToBoth, true, false), nil
 	}
+	return NewBatchRequestInfo(preparedDataByStmtIdxMap), nil
+}
+
+func buildExecuteRequestInfo(ctx *requestInfoBuildContext) (RequestInfo, error) {
+	decodedFrame, err := ctx.frameContext.GetOrDecodeFrame()
+	if err != nil {
+		return nil, fmt.Errorf("could not decode execute raw frame: %w", err)
+	}
+	executeMsg, ok := decodedFrame.Body.Message.(*message.Execute)
+	if !ok {
+		return nil, fmt.Errorf("expected Execute but got %v instead", decodedFrame.Body.Message.GetOpCode())
+	}
+	preparedData, err := getPreparedData(ctx.psCache, ctx.mh, executeMsg.QueryId, primitive.OpCodeExecute, decodedFrame)
+	if err != nil {
+		return nil, err
+	}
+	executeRequestInfo := NewExecuteRequestInfo(preparedData)
+	if readTable := preparedData.GetPrepareRequestInfo().GetReadTable(); readTable != "" {
+		readCluster := ctx.readShift.getReadCluster(ctx.readCluster, ctx.primaryCluster, func() []byte {
+			return executeRoutingKey(ctx.readShift.GetKey(), preparedData, executeMsg)
+		})
+		executeRequestInfo = executeRequestInfo.withForwardDecision(
+			ctx.tableReadRouting.getReadForwardDecision(readTable, readCluster, ctx.primaryCluster))
+	}
+	return executeRequestInfo, nil
+}
+
+func buildAuthResponseRequestInfo(ctx *requestInfoBuildContext) (RequestInfo, error) {
+	if ctx.forwardAuthToTarget {
+		return NewGenericRequestInfo(forwardToTarget, false, false), nil
+	} else {
+		return NewGenericRequestInfo(forwardToOrigin, false, false), nil
+	}
+}
+
+func buildHandshakeRequestInfo(_ *requestInfoBuildContext) (RequestInfo, error) {
+	return NewGenericRequestInfo(forwardToBoth, false, false), nil
+}
+
+func buildPassthroughRequestInfo(_ *requestInfoBuildContext) (RequestInfo, error) {
+	return NewGenericRequestInfo(forwardToBoth, true, false), nil
 }
 
 func getPreparedData(
@@ -174,6 +266,9 @@ func getPreparedData(
 func getRequestInfoFromQueryInfo(
 	f *frame.RawFrame,
 	primaryCluster common.ClusterType,
+	readCluster common.ClusterType,
+	tableReadRouting *TableReadRouting,
+	readShift *ReadShift,
 	forwardSystemQueriesToTarget bool,
 	virtualizationEnabled bool,
 	queryInfo QueryInfo) RequestInfo {
@@ -204,12 +299,11 @@ func getRequestInfoFromQueryInfo(
 				forwardDecision = forwardToOrigin
 			}
 		} else {
-			sendAlsoToAsync = true
-			if primaryCluster == common.ClusterTypeTarget {
-				forwardDecision = forwardToTarget
-			} else {
-				forwardDecision = forwardToOrigin
-			}
+			shiftedReadCluster := readShift.getReadCluster(readCluster, primaryCluster, func() []byte {
+				return []byte(queryInfo.getQuery())
+			})
+			forwardDecision, sendAlsoToAsync = tableReadRouting.getReadForwardDecision(
+				qualifiedTableName(queryInfo.getApplicableKeyspace(), queryInfo.getTableName()), shiftedReadCluster, primaryCluster)
 		}
 	} else if queryInfo.getStatementType() == statementTypeUse {
 		sendAlsoToAsync = true
@@ -223,12 +317,30 @@ func getRequestInfoFromQueryInfo(
 }
 
 func isSystemQuery(info QueryInfo) bool {
-	keyspace := info.getApplicableKeyspace()
+	return isSystemOrDseKeyspace(info.getApplicableKeyspace())
+}
+
+func isSystemOrDseKeyspace(keyspace string) bool {
 	return isSystemKeyspace(keyspace) ||
 		strings.HasPrefix(keyspace, "system_") ||
 		strings.HasPrefix(keyspace, "dse_")
 }
 
+// isTableRead returns true for SELECT statements that are forwarded according to the read routing (primary cluster
+// and TableReadRouting), i.e. reads of tables that are not system tables.
+func isTableRead(info QueryInfo) bool {
+	return info.getStatementType() == statementTypeSelect && !isSystemQuery(info)
+}
+
+func isTableWrite(info QueryInfo) bool {
+	switch info.getStatementType() {
+	case statementTypeInsert, statementTypeUpdate, statementTypeDelete:
+		return info.getTableName() != "" && !isSystemQuery(info)
+	default:
+		return false
+	}
+}
+
 func isSystemPeersV1(info QueryInfo) bool {
 	return isSystemKeyspace(info.getApplicableKeyspace()) && isPeersV1Table(info.getTableName())
 }
@@ -257,6 +369,34 @@ func isSystemKeyspace(keyspace string) bool {
 	return keyspace == systemKeyspaceName
 }
 
+// isContinuationPageRequest returns true if the request is a QUERY or EXECUTE that carries a paging state,
+// i.e., the client is fetching a page other than the first one of a paged read.
+//
+// Paging states are opaque and only valid on the cluster that generated them (with protocol v3 the format
+// even depends on the server version) so these requests must not be mirrored to the other cluster.
+func isContinuationPageRequest(frameContext *frameDecodeContext) bool {
+	opCode := frameContext.GetRawFrame().Header.OpCode
+	if opCode != primitive.OpCodeQuery && opCode != primitive.OpCodeExecute {
+		return false
+	}
+
+	decodedFrame, err := frameContext.GetOrDecodeFrame()
+	if err != nil {
+		log.Debugf("Could not decode %v frame to check paging state: %v", opCode, err)
+		return false
+	}
+
+	var options *message.QueryOptions
+	switch typedMsg := decodedFrame.Body.Message.(type) {
+	case *message.Query:
+		options = typedMsg.Options
+	case *message.Execute:
+		options = typedMsg.Options
+	}
+
+	return options != nil && len(options.PagingState) > 0
+}
+
 type frameDecodeContext struct {
 	frame               *frame.RawFrame       // always non nil
 	decodedFrame        *frame.Frame          // nil until first decode
@@ -80,6 +80,9 @@ func parseEncodedRequestForTests(queryRawFrame *frame.RawFrame, t *testing.T) (R
 		generalParams.mh,
 		generalParams.kn,
 		generalParams.primaryCluster,
+		generalParams.primaryCluster,
+		nil,
+		nil,
 		generalParams.forwardSystemQueriesToTarget,
 		generalParams.virtualizationEnabled,
 		generalParams.forwardAuthToTarget,
@@ -95,7 +95,7 @@ func TestInspectFrame(t *testing.T) {
 		{"OpCodeQuery UNKNOWN", args{mockQueryFrame(t, "UNKNOWN"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewGenericRequestInfo(forwardToBoth, false, true)},
 
 		// PREPARE
-		{"OpCodePrepare SELECT", args{mockPrepareFrame(t, "SELECT blah FROM ks1.t1"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewPrepareRequestInfo(NewGenericRequestInfo(forwardToOrigin, true, true), []*term{}, false, "SELECT blah FROM ks1.t1", "")},
+		{"OpCodePrepare SELECT", args{mockPrepareFrame(t, "SELECT blah FROM ks1.t1"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewPrepareRequestInfo(NewGenericRequestInfo(forwardToOrigin, true, true), []*term{}, false, "SELECT blah FROM ks1.t1", "").withReadTable("ks1.t1")},
 		{"OpCodePrepare SELECT system.local forwardSystemQueriesToOrigin", args{mockPrepareFrame(t, "SELECT * FROM system.local"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewPrepareRequestInfo(NewInterceptedRequestInfo(local, newStarSelectClause()), []*term{}, false, "SELECT * FROM system.local", "")},
 		{"OpCodePrepare SELECT system.peers forwardSystemQueriesToOrigin", args{mockPrepareFrame(t, "SELECT * FROM system.peers"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewPrepareRequestInfo(NewInterceptedRequestInfo(peersV1, newStarSelectClause()), []*term{}, false, "SELECT * FROM system.peers", "")},
 		{"OpCodePrepare SELECT system.local", args{mockPrepareFrame(t, "SELECT * FROM system.local"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToTarget, forwardAuthToOrigin}, NewPrepareRequestInfo(NewInterceptedRequestInfo(local, newStarSelectClause()), []*term{}, false, "SELECT * FROM system.local", "")},
@@ -106,8 +106,8 @@ func TestInspectFrame(t *testing.T) {
 		{"OpCodePrepare SELECT system.peers_v2 forwardSystemQueriesToOrigin", args{mockPrepareFrame(t, "SELECT * FROM system.peers_v2"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewPrepareRequestInfo(NewInterceptedRequestInfo(peersV2, newStarSelectClause()), []*term{}, false, "SELECT * FROM system.peers_v2", "")},
 		{"OpCodePrepare SELECT system_auth.roles", args{mockPrepareFrame(t, "SELECT * FROM system_auth.roles"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToTarget, forwardAuthToOrigin}, NewPrepareRequestInfo(NewGenericRequestInfo(forwardToTarget, false, true), []*term{}, false, "SELECT * FROM system_auth.roles", "")},
 		{"OpCodePrepare SELECT dse_insights.tokens", args{mockPrepareFrame(t, "SELECT * FROM dse_insights.tokens"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToTarget, forwardAuthToOrigin}, NewPrepareRequestInfo(NewGenericRequestInfo(forwardToTarget, false, true), []*term{}, false, "SELECT * FROM dse_insights.tokens", "")},
-		{"OpCodePrepare INSERT INTO asd (a, b) VALUES (1, 2)", args{mockPrepareFrame(t, "INSERT INTO asd (a, b) VALUES (1, 2)"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, true), []*term{}, false, "INSERT INTO asd (a, b) VALUES (1, 2)", "")},
-		{"OpCodePrepare UPDATE asd SET b = 2 WHERE a = 1", args{mockPrepareFrame(t, "UPDATE asd SET b = 2 WHERE a = 1"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, true), []*term{}, false, "UPDATE asd SET b = 2 WHERE a = 1", "")},
+		{"OpCodePrepare INSERT INTO asd (a, b) VALUES (1, 2)", args{mockPrepareFrame(t, "INSERT INTO asd (a, b) VALUES (1, 2)"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, true), []*term{}, false, "INSERT INTO asd (a, b) VALUES (1, 2)", "").withWriteTable(".asd")},
+		{"OpCodePrepare UPDATE asd SET b = 2 WHERE a = 1", args{mockPrepareFrame(t, "UPDATE asd SET b = 2 WHERE a = 1"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, true), []*term{}, false, "UPDATE asd SET b = 2 WHERE a = 1", "").withWriteTable(".asd")},
 		{"OpCodePrepare UNKNOWN", args{mockPrepareFrame(t, "UNKNOWN"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, true), []*term{}, false, "UNKNOWN", "")},
 
 		// EXECUTE
@@ -138,7 +138,7 @@ func TestInspectFrame(t *testing.T) {
 			actual, err := buildRequestInfo(&frameDecodeContext{frame: tt.args.f}, []*statementReplacedTerms{{
 				statementIndex: 0,
 				replacedTerms:  tt.args.replacedTerms,
-			}}, psCache, mh, km, tt.args.primaryCluster, tt.args.forwardSystemQueriesToTarget, true, tt.args.forwardAuthToTarget, timeUuidGenerator)
+			}}, psCache, mh, km, tt.args.primaryCluster, tt.args.primaryCluster, nil, nil, tt.args.forwardSystemQueriesToTarget, true, tt.args.forwardAuthToTarget, timeUuidGenerator)
 			if err != nil {
 				if !reflect.DeepEqual(err.Error(), tt.expected) {
 					t.Errorf("buildRequestInfo() actual = %v, expected %v", err, tt.expected)
@@ -150,6 +150,30 @@ func TestInspectFrame(t *testing.T) {
 	}
 }
 
+func TestIsContinuationPageRequest(t *testing.T) {
+	pagingState := []byte{0xca, 0xfe}
+	tests := []struct {
+		name     string
+		f        *frame.RawFrame
+		expected bool
+	}{
+		{"QueryV3 NoOptions", mockFrame(t, &message.Query{Query: "SELECT * FROM ks.t"}, primitive.ProtocolVersion3), false},
+		{"QueryV3 FirstPage", mockFrame(t, &message.Query{Query: "SELECT * FROM ks.t", Options: &message.QueryOptions{PageSize: 100}}, primitive.ProtocolVersion3), false},
+		{"QueryV3 NextPage", mockFrame(t, &message.Query{Query: "SELECT * FROM ks.t", Options: &message.QueryOptions{PageSize: 100, PagingState: pagingState}}, primitive.ProtocolVersion3), true},
+		{"QueryV4 NextPage", mockFrame(t, &message.Query{Query: "SELECT * FROM ks.t", Options: &message.QueryOptions{PageSize: 100, PagingState: pagingState}}, primitive.ProtocolVersion4), true},
+		{"ExecuteV3 FirstPage", mockFrame(t, &message.Execute{QueryId: []byte("ID"), Options: &message.QueryOptions{PageSize: 100}}, primitive.ProtocolVersion3), false},
+		{"ExecuteV3 NextPage", mockFrame(t, &message.Execute{QueryId: []byte("ID"), Options: &message.QueryOptions{PageSize: 100, PagingState: pagingState}}, primitive.ProtocolVersion3), true},
+		{"ExecuteV4 NextPage", mockFrame(t, &message.Execute{QueryId: []byte("ID"), Options: &message.QueryOptions{PagingState: pagingState}}, primitive.ProtocolVersion4), true},
+		{"BatchV3", mockFrame(t, &message.Batch{Children: []*message.BatchChild{{Query: "INSERT INTO ks.t (a) VALUES (1)"}}}, primitive.ProtocolVersion3), false},
+		{"OptionsV3", mockFrame(t, &message.Options{}, primitive.ProtocolVersion3), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, isContinuationPageRequest(NewFrameDecodeContext(tt.f)))
+		})
+	}
+}
+
 func mockPrepareFrame(t *testing.T, query string) *frame.RawFrame {
 	prepareMsg := &message.Prepare{
 		Query:    query,
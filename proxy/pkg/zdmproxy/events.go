@@ -0,0 +1,99 @@
+package zdmproxy
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+type ProxyEventType string
+
+const (
+	ProxyEventClientConnected      = ProxyEventType("CLIENT_CONNECTED")
+	ProxyEventClientDisconnected   = ProxyEventType("CLIENT_DISCONNECTED")
+	ProxyEventClientDraining       = ProxyEventType("CLIENT_DRAINING")
+	ProxyEventTopologyChanged      = ProxyEventType("PROXY_TOPOLOGY_CHANGED")
+	ProxyEventReadRoutingChanged   = ProxyEventType("READ_ROUTING_CHANGED")
+	ProxyEventRoutingChangeApplied = ProxyEventType("ROUTING_CHANGE_APPLIED")
+	ProxyEventFeatureFlagChanged   = ProxyEventType("FEATURE_FLAG_CHANGED")
+	ProxyEventProxyShuttingDown    = ProxyEventType("PROXY_SHUTTING_DOWN")
+)
+
+const proxyEventSubscriberQueueSize = 256
+
+type ProxyEvent struct {
+	Timestamp time.Time
+	Type      ProxyEventType
+	Message   string
+}
+
+// EventBroadcaster sends live proxy events (client connections, state changes) to every subscriber, e.g. the admin
+// API event stream. Publishing never blocks: events are dropped for subscribers that don't keep up.
+type EventBroadcaster struct {
+	lock        *sync.Mutex
+	subscribers map[chan *ProxyEvent]bool
+	closed      bool
+}
+
+func NewEventBroadcaster() *EventBroadcaster {
+	return &EventBroadcaster{
+		lock:        &sync.Mutex{},
+		subscribers: make(map[chan *ProxyEvent]bool),
+	}
+}
+
+// Subscribe returns a channel that receives the events published from now on and a function that must be called
+// to unsubscribe. The channel is closed when the subscriber unsubscribes or when the broadcaster is closed.
+func (recv *EventBroadcaster) Subscribe() (<-chan *ProxyEvent, func()) {
+	ch := make(chan *ProxyEvent, proxyEventSubscriberQueueSize)
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	if recv.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	recv.subscribers[ch] = true
+	return ch, func() {
+		recv.lock.Lock()
+		defer recv.lock.Unlock()
+		if recv.subscribers[ch] {
+			delete(recv.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+func (recv *EventBroadcaster) Publish(eventType ProxyEventType, format string, args ...interface{}) {
+	if recv == nil {
+		return
+	}
+	event := &ProxyEvent{
+		Timestamp: time.Now().UTC(),
+		Type:      eventType,
+		Message:   fmt.Sprintf(format, args...),
+	}
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	for ch := range recv.subscribers {
+		select {
+		case ch <- event:
+		default:
+			log.Debugf("Dropping proxy event %v because the subscriber is not keeping up.", event.Type)
+		}
+	}
+}
+
+// Close closes the channels of every subscriber, subsequent subscriptions receive a closed channel.
+func (recv *EventBroadcaster) Close() {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	recv.closed = true
+	for ch := range recv.subscribers {
+		delete(recv.subscribers, ch)
+		close(ch)
+	}
+}
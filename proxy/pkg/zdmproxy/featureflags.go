@@ -0,0 +1,89 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"hash/fnv"
+	"sync"
+)
+
+// FeatureFlags contains the percentage of client connections for which each feature is enabled so that a feature can
+// be rolled out to a fraction of the clients first. Whether a feature is enabled for a client connection depends on a
+// hash of the client IP address so the same clients keep the feature while the percentage is increased. Features that
+// are not configured are enabled for every client connection.
+type FeatureFlags struct {
+	lock        *sync.RWMutex
+	percentages map[common.Feature]int
+	events      *EventBroadcaster
+}
+
+func NewFeatureFlags(percentages map[common.Feature]int, events *EventBroadcaster) *FeatureFlags {
+	featureFlags := &FeatureFlags{
+		lock:        &sync.RWMutex{},
+		percentages: make(map[common.Feature]int),
+		events:      events,
+	}
+	for _, feature := range common.Features {
+		featureFlags.percentages[feature] = 100
+	}
+	for feature, percentage := range percentages {
+		featureFlags.percentages[feature] = percentage
+	}
+	return featureFlags
+}
+
+// GetAll returns a copy of the percentage of client connections for which each feature is enabled.
+func (recv *FeatureFlags) GetAll() map[common.Feature]int {
+	recv.lock.RLock()
+	defer recv.lock.RUnlock()
+
+	percentages := make(map[common.Feature]int)
+	for feature, percentage := range recv.percentages {
+		percentages[feature] = percentage
+	}
+	return percentages
+}
+
+// Set changes the percentage of client connections for which the feature is enabled, the change applies to the
+// requests that are received after it (including requests of client connections that are already open).
+func (recv *FeatureFlags) Set(feature common.Feature, percentage int) error {
+	if _, err := common.ParseFeature(string(feature)); err != nil {
+		return err
+	}
+	if percentage < 0 || percentage > 100 {
+		return fmt.Errorf("invalid percentage %v, it must be between 0 and 100", percentage)
+	}
+
+	recv.lock.Lock()
+	recv.percentages[feature] = percentage
+	recv.lock.Unlock()
+	recv.events.Publish(ProxyEventFeatureFlagChanged, "feature %v enabled for %d%% of the client connections",
+		feature, percentage)
+	return nil
+}
+
+// IsEnabled returns true if the feature is enabled for client connections from the provided IP address.
+func (recv *FeatureFlags) IsEnabled(feature common.Feature, clientIp string) bool {
+	if recv == nil {
+		return true
+	}
+	recv.lock.RLock()
+	percentage, ok := recv.percentages[feature]
+	recv.lock.RUnlock()
+	if !ok || percentage >= 100 {
+		return true
+	}
+	if percentage <= 0 {
+		return false
+	}
+	return featureBucket(feature, clientIp) < percentage
+}
+
+// featureBucket maps a client IP address to a number between 0 and 99. The feature is part of the hash so that the
+// same clients aren't always the first ones to get every feature.
+func featureBucket(feature common.Feature, clientIp string) int {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(feature))
+	_, _ = hash.Write([]byte(clientIp))
+	return int(hash.Sum32() % 100)
+}
@@ -0,0 +1,48 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestFeatureFlags(t *testing.T) {
+	featureFlags := NewFeatureFlags(map[common.Feature]int{}, nil)
+	require.Equal(t, map[common.Feature]int{common.FeatureAsyncReads: 100}, featureFlags.GetAll())
+	require.True(t, featureFlags.IsEnabled(common.FeatureAsyncReads, "10.0.0.1"))
+
+	require.Nil(t, featureFlags.Set(common.FeatureAsyncReads, 0))
+	require.False(t, featureFlags.IsEnabled(common.FeatureAsyncReads, "10.0.0.1"))
+
+	require.Nil(t, featureFlags.Set(common.FeatureAsyncReads, 25))
+	enabled := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		clientIp := fmt.Sprintf("10.0.%d.%d", i/256, i%256)
+		enabled[clientIp] = featureFlags.IsEnabled(common.FeatureAsyncReads, clientIp)
+		// the same client always gets the same result
+		require.Equal(t, enabled[clientIp], featureFlags.IsEnabled(common.FeatureAsyncReads, clientIp))
+	}
+	count := 0
+	for _, isEnabled := range enabled {
+		if isEnabled {
+			count++
+		}
+	}
+	require.InDelta(t, 250, count, 50)
+
+	// clients that had the feature keep it when the percentage is increased
+	require.Nil(t, featureFlags.Set(common.FeatureAsyncReads, 50))
+	for clientIp, isEnabled := range enabled {
+		if isEnabled {
+			require.True(t, featureFlags.IsEnabled(common.FeatureAsyncReads, clientIp), clientIp)
+		}
+	}
+
+	require.NotNil(t, featureFlags.Set(common.FeatureAsyncReads, 101))
+	require.NotNil(t, featureFlags.Set(common.Feature("unknown"), 50))
+	require.Equal(t, map[common.Feature]int{common.FeatureAsyncReads: 50}, featureFlags.GetAll())
+
+	var nilFeatureFlags *FeatureFlags
+	require.True(t, nilFeatureFlags.IsEnabled(common.FeatureAsyncReads, "10.0.0.1"))
+}
@@ -1,9 +1,12 @@
 package zdmproxy
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
 	"io"
 )
 
@@ -19,6 +22,14 @@ var defaultCodec = frame.NewRawCodec()
 
 var ShutdownErr = &shutdownError{err: "aborted due to shutdown request"}
 
+// StartupTimeoutErr is returned by RunWithRetries if the proxy couldn't start (usually because a cluster is not
+// reachable) before ZDM_PROXY_STARTUP_TIMEOUT_MS.
+var StartupTimeoutErr = errors.New("proxy could not start before ZDM_PROXY_STARTUP_TIMEOUT_MS elapsed")
+
+// ListenErr is returned by Run and RunWithRetries if the proxy couldn't listen on ZDM_PROXY_LISTEN_PORT (usually because
+// the port is used by another process). RunWithRetries doesn't retry it.
+var ListenErr = errors.New("proxy could not listen for client connections")
+
 func adaptConnErr(connectionAddr string, clientHandlerContext context.Context, err error) error {
 	if err != nil {
 		if clientHandlerContext.Err() != nil {
@@ -37,12 +48,73 @@ func writeRawFrame(writer io.Writer, connectionAddr string, clientHandlerContext
 	return adaptConnErr(connectionAddr, clientHandlerContext, err)
 }
 
-// Simple function that reads data from a connection and builds a frame
-func readRawFrame(reader io.Reader, connectionAddr string, clientHandlerContext context.Context) (*frame.RawFrame, error) {
-	rawFrame, err := defaultCodec.DecodeRawFrame(reader)
+// frameTooLargeError is returned by readRawFrame when the body of a frame, or its decompressed body, is larger than the
+// max frame size. The body is skipped without being buffered or decompressed so the next frames can still be read from
+// the connection.
+type frameTooLargeError struct {
+	header            *frame.Header
+	length            int64
+	maxFrameSizeBytes int
+}
+
+func (e *frameTooLargeError) Error() string {
+	return fmt.Sprintf("Request is too big: length %d exceeds maximum allowed length %d.",
+		e.length, e.maxFrameSizeBytes)
+}
+
+// Simple function that reads data from a connection and builds a frame, the body of the frame is decompressed.
+// Frames with a body larger than maxFrameSizeBytes, before or after decompression, are skipped and a
+// frameTooLargeError is returned, set it to 0 to disable the limit.
+func readRawFrame(
+	reader io.Reader, connectionAddr string, clientHandlerContext context.Context,
+	compression *frameCompression, maxFrameSizeBytes int) (*frame.RawFrame, error) {
+	header, err := decodeHeader(reader)
+	if err != nil {
+		return nil, adaptConnErr(connectionAddr, clientHandlerContext, fmt.Errorf("cannot decode frame header: %w", err))
+	}
+
+	if maxFrameSizeBytes > 0 && int64(header.BodyLength) > int64(maxFrameSizeBytes) {
+		if _, err = io.CopyN(io.Discard, reader, int64(header.BodyLength)); err != nil {
+			return nil, adaptConnErr(connectionAddr, clientHandlerContext, fmt.Errorf("cannot skip frame body: %w", err))
+		}
+		return nil, &frameTooLargeError{
+			header: header, length: int64(header.BodyLength), maxFrameSizeBytes: maxFrameSizeBytes}
+	}
+
+	if header.Flags.Contains(primitive.HeaderFlagCompressed) {
+		// the compressed body is discarded once it is decompressed so it can be read in a pooled buffer
+		body := getBodyBuffer()
+		defer putBodyBuffer(body)
+		if err = readRawBody(reader, header, body); err != nil {
+			return nil, adaptConnErr(connectionAddr, clientHandlerContext, err)
+		}
+		compressedFrame := &frame.RawFrame{Header: header, Body: body.Bytes()}
+		if maxFrameSizeBytes > 0 {
+			length, err := compression.decompressedLength(compressedFrame)
+			if err != nil {
+				return nil, err
+			}
+			if length > int64(maxFrameSizeBytes) {
+				return nil, &frameTooLargeError{header: header, length: length, maxFrameSizeBytes: maxFrameSizeBytes}
+			}
+		}
+		return compression.decompress(compressedFrame)
+	}
+
+	body, err := readBody(reader, header)
 	if err != nil {
 		return nil, adaptConnErr(connectionAddr, clientHandlerContext, err)
 	}
+	return &frame.RawFrame{Header: header, Body: body}, nil
+}
 
-	return rawFrame, nil
+func readRawBody(reader io.Reader, header *frame.Header, body *bytes.Buffer) error {
+	if header.BodyLength < 0 {
+		return fmt.Errorf("cannot read frame body: invalid body length: %d", header.BodyLength)
+	}
+	body.Grow(int(header.BodyLength))
+	if _, err := io.CopyN(body, reader, int64(header.BodyLength)); err != nil {
+		return fmt.Errorf("cannot read frame body: %w", err)
+	}
+	return nil
 }
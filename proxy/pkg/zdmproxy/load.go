@@ -0,0 +1,140 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const loadMinSampleInterval = time.Second
+
+// LoadTracker computes normalized load signals meant to drive autoscaling (e.g. Kubernetes HPA) of the proxy fleet.
+// Ratios are relative to the per instance capacity targets so 1.0 means that the instance is at its target capacity.
+//
+// Request rate and CPU usage are sampled lazily (at most once per loadMinSampleInterval) when the signals are read,
+// which is usually when the metrics endpoint is scraped, so each sample covers the time since the previous scrape.
+type LoadTracker struct {
+	inFlightRequests          int64  // atomic, first field for 64-bit alignment
+	requestsTotal             uint64 // atomic
+	capacityInFlightRequests  int
+	capacityRequestsPerSecond int
+	lock                      *sync.Mutex
+	lastSampleTime            time.Time
+	lastRequestsTotal         uint64
+	lastCpuTime               time.Duration
+	requestsPerSecond         float64
+	cpuUtilization            float64
+	cpuUtilizationIsSupported bool
+	numCpus                   int
+	nowFunc                   func() time.Time
+	processCpuTimeFunc        func() (time.Duration, bool)
+}
+
+func NewLoadTracker(capacityInFlightRequests int, capacityRequestsPerSecond int) *LoadTracker {
+	tracker := &LoadTracker{
+		capacityInFlightRequests:  capacityInFlightRequests,
+		capacityRequestsPerSecond: capacityRequestsPerSecond,
+		lock:                      &sync.Mutex{},
+		numCpus:                   runtime.GOMAXPROCS(0),
+		nowFunc:                   time.Now,
+		processCpuTimeFunc:        processCpuTime,
+	}
+	tracker.lastSampleTime = tracker.nowFunc()
+	tracker.lastCpuTime, tracker.cpuUtilizationIsSupported = tracker.processCpuTimeFunc()
+	return tracker
+}
+
+// TrackInFlightGauge wraps an in flight requests gauge so that every request tracked by it is also counted by this
+// LoadTracker.
+func (recv *LoadTracker) TrackInFlightGauge(gauge metrics.Gauge) metrics.Gauge {
+	return &loadTrackingGauge{Gauge: gauge, tracker: recv}
+}
+
+// InFlightRequests returns the number of requests currently in flight in the proxy.
+func (recv *LoadTracker) InFlightRequests() int64 {
+	return atomic.LoadInt64(&recv.inFlightRequests)
+}
+
+func (recv *LoadTracker) InFlightUtilization() float64 {
+	if recv.capacityInFlightRequests <= 0 {
+		return 0
+	}
+	return float64(atomic.LoadInt64(&recv.inFlightRequests)) / float64(recv.capacityInFlightRequests)
+}
+
+func (recv *LoadTracker) RequestsPerSecond() float64 {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	recv.sample()
+	return recv.requestsPerSecond
+}
+
+// CpuUtilization returns the CPU usage of the proxy process relative to the CPUs it can use (GOMAXPROCS).
+func (recv *LoadTracker) CpuUtilization() float64 {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	recv.sample()
+	return recv.cpuUtilization
+}
+
+// RequestsPerSecondUtilization returns the request rate relative to the configured capacity or 0 if there is no
+// configured capacity.
+func (recv *LoadTracker) RequestsPerSecondUtilization() float64 {
+	if recv.capacityRequestsPerSecond <= 0 {
+		return 0
+	}
+	return recv.RequestsPerSecond() / float64(recv.capacityRequestsPerSecond)
+}
+
+// Utilization returns the highest of the in flight, request rate and CPU utilizations. This is the signal that
+// autoscalers should target (e.g. scale out when it stays above 0.7).
+func (recv *LoadTracker) Utilization() float64 {
+	return math.Max(recv.InFlightUtilization(), math.Max(recv.RequestsPerSecondUtilization(), recv.CpuUtilization()))
+}
+
+// QpsHeadroom returns the fraction of the request rate capacity that is still available, reduced when the CPU
+// utilization is higher than the request rate utilization (i.e. requests are more expensive than the capacity target
+// assumes). The value is between 0 and 1.
+func (recv *LoadTracker) QpsHeadroom() float64 {
+	used := math.Max(recv.RequestsPerSecondUtilization(), recv.CpuUtilization())
+	return math.Max(0, 1-used)
+}
+
+func (recv *LoadTracker) sample() {
+	now := recv.nowFunc()
+	elapsed := now.Sub(recv.lastSampleTime)
+	if elapsed < loadMinSampleInterval {
+		return
+	}
+
+	requestsTotal := atomic.LoadUint64(&recv.requestsTotal)
+	recv.requestsPerSecond = float64(requestsTotal-recv.lastRequestsTotal) / elapsed.Seconds()
+	recv.lastRequestsTotal = requestsTotal
+
+	if recv.cpuUtilizationIsSupported {
+		cpuTime, _ := recv.processCpuTimeFunc()
+		recv.cpuUtilization = float64(cpuTime-recv.lastCpuTime) / float64(elapsed) / float64(recv.numCpus)
+		recv.lastCpuTime = cpuTime
+	}
+
+	recv.lastSampleTime = now
+}
+
+type loadTrackingGauge struct {
+	metrics.Gauge
+	tracker *LoadTracker
+}
+
+func (recv *loadTrackingGauge) Add(valueToAdd int) {
+	atomic.AddInt64(&recv.tracker.inFlightRequests, int64(valueToAdd))
+	atomic.AddUint64(&recv.tracker.requestsTotal, uint64(valueToAdd))
+	recv.Gauge.Add(valueToAdd)
+}
+
+func (recv *loadTrackingGauge) Subtract(valueToSubtract int) {
+	atomic.AddInt64(&recv.tracker.inFlightRequests, -int64(valueToSubtract))
+	recv.Gauge.Subtract(valueToSubtract)
+}
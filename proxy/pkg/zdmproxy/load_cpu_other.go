@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !freebsd
+// +build !linux,!darwin,!freebsd
+
+package zdmproxy
+
+import "time"
+
+// processCpuTime is not supported on this platform so the CPU utilization load signal is always 0.
+func processCpuTime() (time.Duration, bool) {
+	return 0, false
+}
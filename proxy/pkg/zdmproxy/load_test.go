@@ -0,0 +1,84 @@
+package zdmproxy
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+type fakeGauge struct {
+	value int
+}
+
+func (recv *fakeGauge) Add(valueToAdd int) {
+	recv.value += valueToAdd
+}
+
+func (recv *fakeGauge) Subtract(valueToSubtract int) {
+	recv.value -= valueToSubtract
+}
+
+func (recv *fakeGauge) Set(value int) {
+	recv.value = value
+}
+
+func TestLoadTracker(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cpuTime := time.Duration(0)
+
+	tracker := NewLoadTracker(10, 100)
+	tracker.nowFunc = func() time.Time { return now }
+	tracker.processCpuTimeFunc = func() (time.Duration, bool) { return cpuTime, true }
+	tracker.numCpus = 2
+	tracker.lastSampleTime = now
+	tracker.lastCpuTime = 0
+	tracker.cpuUtilizationIsSupported = true
+
+	reads := &fakeGauge{}
+	writes := &fakeGauge{}
+	trackedReads := tracker.TrackInFlightGauge(reads)
+	trackedWrites := tracker.TrackInFlightGauge(writes)
+
+	// 120 requests in 2 seconds, 4 of them still in flight, 1 second of CPU time on 2 CPUs
+	for i := 0; i < 60; i++ {
+		trackedReads.Add(1)
+		trackedWrites.Add(1)
+	}
+	trackedReads.Subtract(58)
+	trackedWrites.Subtract(58)
+	now = now.Add(2 * time.Second)
+	cpuTime = time.Second
+
+	require.Equal(t, 2, reads.value)
+	require.Equal(t, 2, writes.value)
+	require.InDelta(t, 0.4, tracker.InFlightUtilization(), 0.0001)
+	require.InDelta(t, 60.0, tracker.RequestsPerSecond(), 0.0001)
+	require.InDelta(t, 0.6, tracker.RequestsPerSecondUtilization(), 0.0001)
+	require.InDelta(t, 0.25, tracker.CpuUtilization(), 0.0001)
+	require.InDelta(t, 0.6, tracker.Utilization(), 0.0001)
+	require.InDelta(t, 0.4, tracker.QpsHeadroom(), 0.0001)
+
+	// samples are not recomputed more than once per interval
+	trackedReads.Add(100)
+	now = now.Add(100 * time.Millisecond)
+	require.InDelta(t, 60.0, tracker.RequestsPerSecond(), 0.0001)
+
+	// headroom is reduced by the CPU utilization when requests are more expensive than the capacity target assumes
+	now = now.Add(900 * time.Millisecond)
+	cpuTime += 1800 * time.Millisecond
+	require.InDelta(t, 0.9, tracker.CpuUtilization(), 0.0001)
+	require.InDelta(t, 1.0, tracker.RequestsPerSecondUtilization(), 0.0001)
+	require.InDelta(t, 0.0, tracker.QpsHeadroom(), 0.0001)
+	require.InDelta(t, 10.4, tracker.InFlightUtilization(), 0.0001)
+}
+
+func TestLoadTracker_NoRequestsPerSecondCapacity(t *testing.T) {
+	tracker := NewLoadTracker(0, 0)
+	tracker.processCpuTimeFunc = func() (time.Duration, bool) { return 0, false }
+	tracker.cpuUtilizationIsSupported = false
+
+	tracker.TrackInFlightGauge(&fakeGauge{}).Add(5)
+	require.Equal(t, 0.0, tracker.InFlightUtilization())
+	require.Equal(t, 0.0, tracker.RequestsPerSecondUtilization())
+	require.Equal(t, 1.0, tracker.QpsHeadroom())
+}
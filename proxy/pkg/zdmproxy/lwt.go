@@ -0,0 +1,88 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+)
+
+const appliedColumnName = "[applied]"
+
+// isConditionalWrite returns true if the QUERY, EXECUTE or BATCH request contains a conditional statement (lightweight
+// transaction), i.e. an INSERT, UPDATE or DELETE with an IF clause. Sending such a write to both clusters doesn't
+// guarantee that it is applied on both of them since the conditions are evaluated independently on each cluster.
+func isConditionalWrite(
+	frameContext *frameDecodeContext, requestInfo RequestInfo, currentKeyspace string,
+	timeUuidGenerator TimeUuidGenerator) (bool, error) {
+	switch typedRequestInfo := requestInfo.(type) {
+	case *ExecuteRequestInfo:
+		return typedRequestInfo.GetPreparedData().GetPrepareRequestInfo().IsConditional(), nil
+	case *BatchRequestInfo:
+		for _, preparedData := range typedRequestInfo.GetPreparedDataByStmtIdx() {
+			if preparedData.GetPrepareRequestInfo().IsConditional() {
+				return true, nil
+			}
+		}
+	}
+
+	opCode := frameContext.GetRawFrame().Header.OpCode
+	if opCode != primitive.OpCodeQuery && opCode != primitive.OpCodeBatch {
+		return false, nil
+	}
+	stmtsQueryData, err := frameContext.GetOrInspectAllStatements(currentKeyspace, timeUuidGenerator)
+	if err != nil {
+		return false, err
+	}
+	for _, stmtQueryData := range stmtsQueryData {
+		if stmtQueryData.queryData.isConditional() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// withPrimaryOnlyForwardDecision returns a copy of the request info of a write that is forwarded to the primary
+// cluster only (ZDM_LWT_MODE is PRIMARY_ONLY).
+func withPrimaryOnlyForwardDecision(requestInfo RequestInfo, primaryCluster common.ClusterType) RequestInfo {
+	decision := forwardToOrigin
+	if primaryCluster == common.ClusterTypeTarget {
+		decision = forwardToTarget
+	}
+	switch typedRequestInfo := requestInfo.(type) {
+	case *ExecuteRequestInfo:
+		return NewExecuteRequestInfo(typedRequestInfo.GetPreparedData()).withForwardDecision(decision, false)
+	case *BatchRequestInfo:
+		return NewBatchRequestInfo(typedRequestInfo.GetPreparedDataByStmtIdx()).withForwardDecision(decision)
+	default:
+		return NewGenericRequestInfo(decision, false, requestInfo.ShouldBeTrackedInMetrics())
+	}
+}
+
+// getAppliedFlag returns the value of the [applied] column of the response of a conditional write. Returns false
+// (not found) if the response is not a ROWS result with such a column.
+func getAppliedFlag(response *frame.RawFrame) (applied bool, found bool, err error) {
+	if response.Header.OpCode != primitive.OpCodeResult {
+		return false, false, nil
+	}
+	decodedResponse, err := defaultCodec.ConvertFromRawFrame(response)
+	if err != nil {
+		return false, false, fmt.Errorf("could not decode response: %w", err)
+	}
+	rows, ok := decodedResponse.Body.Message.(*message.RowsResult)
+	if !ok || rows.Metadata == nil || len(rows.Data) == 0 {
+		return false, false, nil
+	}
+	for i, column := range rows.Metadata.Columns {
+		if column.Name != appliedColumnName {
+			continue
+		}
+		if i >= len(rows.Data[0]) {
+			return false, false, nil
+		}
+		value := rows.Data[0][i]
+		return len(value) > 0 && value[0] != 0, true, nil
+	}
+	return false, false, nil
+}
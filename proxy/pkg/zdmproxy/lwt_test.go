@@ -0,0 +1,129 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestIsConditionalWrite(t *testing.T) {
+	preparedData := func(query string) PreparedData {
+		queryInfo := inspectCqlQuery(query, "", &fakeTimeUuidGenerator{})
+		prepareRequestInfo := NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, true), nil, false,
+			query, "")
+		if queryInfo.isConditional() {
+			prepareRequestInfo = prepareRequestInfo.withConditional()
+		}
+		return NewPreparedData(
+			&message.PreparedResult{PreparedQueryId: []byte("origin")},
+			&message.PreparedResult{PreparedQueryId: []byte("target")},
+			prepareRequestInfo)
+	}
+
+	tests := []struct {
+		name        string
+		msg         message.Message
+		requestInfo RequestInfo
+		expected    bool
+	}{
+		{"query",
+			&message.Query{Query: "INSERT INTO ks.tbl (pk) VALUES (1)"},
+			NewGenericRequestInfo(forwardToBoth, false, true), false},
+		{"conditional query",
+			&message.Query{Query: "INSERT INTO ks.tbl (pk) VALUES (1) IF NOT EXISTS"},
+			NewGenericRequestInfo(forwardToBoth, false, true), true},
+		{"execute",
+			&message.Execute{QueryId: []byte("origin"), Options: &message.QueryOptions{}},
+			NewExecuteRequestInfo(preparedData("UPDATE ks.tbl SET v = ? WHERE pk = ?")), false},
+		{"conditional execute",
+			&message.Execute{QueryId: []byte("origin"), Options: &message.QueryOptions{}},
+			NewExecuteRequestInfo(preparedData("UPDATE ks.tbl SET v = ? WHERE pk = ? IF v = ?")), true},
+		{"batch",
+			&message.Batch{Children: []*message.BatchChild{
+				{Query: "INSERT INTO ks.tbl (pk) VALUES (1)"},
+				{Id: []byte("origin")}}},
+			NewBatchRequestInfo(map[int]PreparedData{1: preparedData("DELETE FROM ks.tbl WHERE pk = ?")}), false},
+		{"batch with conditional child",
+			&message.Batch{Children: []*message.BatchChild{
+				{Query: "INSERT INTO ks.tbl (pk) VALUES (1)"},
+				{Query: "DELETE FROM ks.tbl WHERE pk = 2 IF EXISTS"}}},
+			NewBatchRequestInfo(map[int]PreparedData{}), true},
+		{"batch with conditional prepared child",
+			&message.Batch{Children: []*message.BatchChild{{Id: []byte("origin")}}},
+			NewBatchRequestInfo(map[int]PreparedData{0: preparedData("DELETE FROM ks.tbl WHERE pk = ? IF EXISTS")}), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rawFrame, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 1, tt.msg))
+			require.Nil(t, err)
+			conditional, err := isConditionalWrite(
+				NewFrameDecodeContext(rawFrame), tt.requestInfo, "", &fakeTimeUuidGenerator{})
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, conditional)
+		})
+	}
+}
+
+func TestWithPrimaryOnlyForwardDecision(t *testing.T) {
+	preparedData := NewPreparedData(
+		&message.PreparedResult{PreparedQueryId: []byte("origin")},
+		&message.PreparedResult{PreparedQueryId: []byte("target")},
+		NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, true), nil, false, "", "").withConditional())
+
+	requestInfo := withPrimaryOnlyForwardDecision(NewGenericRequestInfo(forwardToBoth, false, true), common.ClusterTypeOrigin)
+	require.Equal(t, forwardToOrigin, requestInfo.GetForwardDecision())
+	require.True(t, requestInfo.ShouldBeTrackedInMetrics())
+
+	executeRequestInfo := NewExecuteRequestInfo(preparedData)
+	requestInfo = withPrimaryOnlyForwardDecision(executeRequestInfo, common.ClusterTypeTarget)
+	require.IsType(t, &ExecuteRequestInfo{}, requestInfo)
+	require.Equal(t, forwardToTarget, requestInfo.GetForwardDecision())
+	require.False(t, requestInfo.ShouldAlsoBeSentAsync())
+	require.Equal(t, forwardToBoth, executeRequestInfo.GetForwardDecision())
+
+	requestInfo = withPrimaryOnlyForwardDecision(
+		NewBatchRequestInfo(map[int]PreparedData{0: preparedData}), common.ClusterTypeOrigin)
+	require.IsType(t, &BatchRequestInfo{}, requestInfo)
+	require.Equal(t, forwardToOrigin, requestInfo.GetForwardDecision())
+}
+
+func TestGetAppliedFlag(t *testing.T) {
+	rowsResult := func(columnName string, value []byte) *frame.RawFrame {
+		rawFrame, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 1, &message.RowsResult{
+			Metadata: &message.RowsMetadata{
+				ColumnCount: 1,
+				Columns: []*message.ColumnMetadata{
+					{Keyspace: "ks", Table: "tbl", Name: columnName, Type: datatype.Boolean}},
+			},
+			Data: message.RowSet{{value}},
+		}))
+		require.Nil(t, err)
+		return rawFrame
+	}
+	voidResult, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 1, &message.VoidResult{}))
+	require.Nil(t, err)
+
+	tests := []struct {
+		name            string
+		response        *frame.RawFrame
+		expectedApplied bool
+		expectedFound   bool
+	}{
+		{"applied", rowsResult(appliedColumnName, []byte{1}), true, true},
+		{"not applied", rowsResult(appliedColumnName, []byte{0}), false, true},
+		{"other column", rowsResult("v", []byte{1}), false, false},
+		{"void result", voidResult, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applied, found, err := getAppliedFlag(tt.response)
+			require.Nil(t, err)
+			require.Equal(t, tt.expectedApplied, applied)
+			require.Equal(t, tt.expectedFound, found)
+		})
+	}
+}
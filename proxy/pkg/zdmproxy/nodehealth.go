@@ -0,0 +1,223 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+type NodeFailure string
+
+const (
+	NodeFailureError   = NodeFailure("ERROR")   // node level error response, see isNodeFailureErrorCode
+	NodeFailureTimeout = NodeFailure("TIMEOUT") // no response within ZDM_PROXY_REQUEST_TIMEOUT_MS
+	NodeFailureConnect = NodeFailure("CONNECT") // a client connection could not be opened to the node
+)
+
+var nodeFailurePenalties = map[NodeFailure]float64{
+	NodeFailureError:   1,
+	NodeFailureTimeout: 2,
+	NodeFailureConnect: 5,
+}
+
+type NodeHealthState string
+
+const (
+	NodeHealthStateHealthy       = NodeHealthState("HEALTHY")
+	NodeHealthStateQuarantined   = NodeHealthState("QUARANTINED")
+	NodeHealthStateReintroducing = NodeHealthState("REINTRODUCING")
+)
+
+// NodeHealthStatus is the health of a node as reported by the admin API.
+type NodeHealthStatus struct {
+	Node             string
+	HostId           string
+	State            NodeHealthState
+	Score            float64
+	Quarantines      int        // consecutive quarantines, reset once the node is fully reintroduced
+	QuarantinedUntil *time.Time `json:",omitempty"`
+	Share            float64    // share of the new client connections that the node gets compared to a healthy node
+}
+
+// NodeHealthTracker keeps a health score for each node of a cluster. Failures add a penalty to the score of the node
+// and the score decays exponentially over time. Once the score of a node reaches the threshold, the node is
+// quarantined: it isn't assigned to new client connections until the quarantine ends. After that the node gets a
+// growing share of the new connections until it is fully reintroduced. A node that is quarantined again before it is
+// fully reintroduced stays in quarantine twice as long as the previous time.
+//
+// Existing client connections to a quarantined node are not closed.
+type NodeHealthTracker struct {
+	clusterType    common.ClusterType
+	threshold      float64
+	halfLife       time.Duration
+	minQuarantine  time.Duration
+	maxQuarantine  time.Duration
+	reintroduction time.Duration
+	rand           *rand.Rand
+	now            func() time.Time
+	lock           *sync.Mutex
+	nodes          map[uuid.UUID]*nodeHealth
+}
+
+type nodeHealth struct {
+	score            float64
+	updated          time.Time
+	quarantines      int
+	quarantinedUntil time.Time
+	reintroducedAt   time.Time
+}
+
+// NewNodeHealthTracker returns nil if ZDM_NODE_HEALTH_QUARANTINE_THRESHOLD is 0.
+func NewNodeHealthTracker(clusterType common.ClusterType, conf *config.Config, proxyRand *rand.Rand) *NodeHealthTracker {
+	if conf.NodeHealthQuarantineThreshold <= 0 {
+		return nil
+	}
+	return &NodeHealthTracker{
+		clusterType:    clusterType,
+		threshold:      conf.NodeHealthQuarantineThreshold,
+		halfLife:       time.Duration(conf.NodeHealthScoreHalfLifeMs) * time.Millisecond,
+		minQuarantine:  time.Duration(conf.NodeHealthQuarantineMinMs) * time.Millisecond,
+		maxQuarantine:  time.Duration(conf.NodeHealthQuarantineMaxMs) * time.Millisecond,
+		reintroduction: time.Duration(conf.NodeHealthReintroductionMs) * time.Millisecond,
+		rand:           proxyRand,
+		now:            time.Now,
+		lock:           &sync.Mutex{},
+		nodes:          map[uuid.UUID]*nodeHealth{},
+	}
+}
+
+// RecordFailure adds the penalty of the failure to the score of the node and quarantines it if the score reaches the
+// threshold. Failures of a node that is already quarantined are ignored.
+func (recv *NodeHealthTracker) RecordFailure(host *Host, failure NodeFailure) {
+	if recv == nil || host == nil {
+		return
+	}
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	now := recv.now()
+	node, ok := recv.nodes[host.HostId]
+	if !ok {
+		node = &nodeHealth{updated: now}
+		recv.nodes[host.HostId] = node
+	}
+	if now.Before(node.quarantinedUntil) {
+		return
+	}
+	recv.decay(node, now)
+	node.score += nodeFailurePenalties[failure]
+	if node.score < recv.threshold {
+		return
+	}
+
+	if node.quarantines > 0 && !now.Before(node.reintroducedAt) {
+		node.quarantines = 0
+	}
+	node.quarantines++
+	quarantine := recv.maxQuarantine
+	if node.quarantines < 32 && recv.minQuarantine<<(node.quarantines-1) < recv.maxQuarantine {
+		quarantine = recv.minQuarantine << (node.quarantines - 1)
+	}
+	node.score = 0
+	node.quarantinedUntil = now.Add(quarantine)
+	node.reintroducedAt = node.quarantinedUntil.Add(recv.reintroduction)
+	log.Warnf("%v node %v was quarantined for %v because its health score reached %v (last failure: %v).",
+		recv.clusterType, host, quarantine, recv.threshold, failure)
+}
+
+// Allow returns true if the node can be assigned to a new client connection. Quarantined nodes are never allowed and
+// nodes that are being reintroduced are allowed with a probability that grows linearly with the time that passed since
+// the end of their quarantine.
+func (recv *NodeHealthTracker) Allow(host *Host) bool {
+	if recv == nil || host == nil {
+		return true
+	}
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	node, ok := recv.nodes[host.HostId]
+	if !ok || node.quarantines == 0 {
+		return true
+	}
+	now := recv.now()
+	share := recv.share(node, now)
+	if share == 1 {
+		node.quarantines = 0
+		log.Infof("%v node %v was fully reintroduced.", recv.clusterType, host)
+		return true
+	}
+	return share > 0 && recv.rand.Float64() < share
+}
+
+// Status returns the current health of the node.
+func (recv *NodeHealthTracker) Status(host *Host) *NodeHealthStatus {
+	status := &NodeHealthStatus{
+		Node:   host.String(),
+		HostId: host.HostId.String(),
+		State:  NodeHealthStateHealthy,
+		Share:  1,
+	}
+	if recv == nil {
+		return status
+	}
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	node, ok := recv.nodes[host.HostId]
+	if !ok {
+		return status
+	}
+	now := recv.now()
+	if now.Before(node.quarantinedUntil) {
+		status.State = NodeHealthStateQuarantined
+		quarantinedUntil := node.quarantinedUntil
+		status.QuarantinedUntil = &quarantinedUntil
+	} else {
+		recv.decay(node, now)
+		status.Score = node.score
+		if node.quarantines > 0 && now.Before(node.reintroducedAt) {
+			status.State = NodeHealthStateReintroducing
+		}
+	}
+	status.Quarantines = node.quarantines
+	if node.quarantines > 0 {
+		status.Share = recv.share(node, now)
+	}
+	return status
+}
+
+func (recv *NodeHealthTracker) decay(node *nodeHealth, now time.Time) {
+	elapsed := now.Sub(node.updated)
+	if elapsed > 0 {
+		node.score *= math.Pow(0.5, float64(elapsed)/float64(recv.halfLife))
+		node.updated = now
+	}
+}
+
+func (recv *NodeHealthTracker) share(node *nodeHealth, now time.Time) float64 {
+	if now.Before(node.quarantinedUntil) {
+		return 0
+	}
+	if !now.Before(node.reintroducedAt) {
+		return 1
+	}
+	return float64(now.Sub(node.quarantinedUntil)) / float64(recv.reintroduction)
+}
+
+// isNodeFailureErrorCode returns true if the error is caused by the state of the coordinator rather than by the
+// request itself (e.g. syntax errors or unavailable replicas aren't the fault of the node).
+func isNodeFailureErrorCode(errorCode primitive.ErrorCode) bool {
+	switch errorCode {
+	case primitive.ErrorCodeOverloaded, primitive.ErrorCodeIsBootstrapping, primitive.ErrorCodeServerError,
+		primitive.ErrorCodeReadTimeout, primitive.ErrorCodeWriteTimeout:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,137 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNodeHealthTracker(t *testing.T) {
+	conf := &config.Config{
+		NodeHealthQuarantineThreshold: 10,
+		NodeHealthScoreHalfLifeMs:     10000,
+		NodeHealthQuarantineMinMs:     30000,
+		NodeHealthQuarantineMaxMs:     100000,
+		NodeHealthReintroductionMs:    60000,
+	}
+	newTracker := func() (*NodeHealthTracker, *time.Time) {
+		tracker := NewNodeHealthTracker(common.ClusterTypeOrigin, conf, NewThreadSafeRand())
+		now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+		tracker.now = func() time.Time { return now }
+		return tracker, &now
+	}
+	host := newTestNodeHealthHost(1)
+
+	t.Run("disabled", func(t *testing.T) {
+		tracker := NewNodeHealthTracker(common.ClusterTypeOrigin, &config.Config{}, NewThreadSafeRand())
+		require.Nil(t, tracker)
+		tracker.RecordFailure(host, NodeFailureConnect)
+		require.True(t, tracker.Allow(host))
+		require.Equal(t, NodeHealthStateHealthy, tracker.Status(host).State)
+	})
+
+	t.Run("score decays", func(t *testing.T) {
+		tracker, now := newTracker()
+		tracker.RecordFailure(host, NodeFailureConnect)
+		tracker.RecordFailure(host, NodeFailureTimeout)
+		require.Equal(t, 7.0, tracker.Status(host).Score)
+		*now = now.Add(10 * time.Second)
+		require.Equal(t, 3.5, tracker.Status(host).Score)
+		tracker.RecordFailure(host, NodeFailureConnect)
+		require.Equal(t, NodeHealthStateHealthy, tracker.Status(host).State)
+		require.True(t, tracker.Allow(host))
+	})
+
+	t.Run("quarantine and reintroduction", func(t *testing.T) {
+		tracker, now := newTracker()
+		tracker.RecordFailure(host, NodeFailureConnect)
+		tracker.RecordFailure(host, NodeFailureConnect)
+		status := tracker.Status(host)
+		require.Equal(t, NodeHealthStateQuarantined, status.State)
+		require.Equal(t, 1, status.Quarantines)
+		require.Equal(t, now.Add(30*time.Second), *status.QuarantinedUntil)
+		require.False(t, tracker.Allow(host))
+
+		*now = now.Add(60 * time.Second)
+		status = tracker.Status(host)
+		require.Equal(t, NodeHealthStateReintroducing, status.State)
+		require.Equal(t, 0.5, status.Share)
+
+		*now = now.Add(30 * time.Second)
+		require.True(t, tracker.Allow(host))
+		require.Equal(t, &NodeHealthStatus{
+			Node: host.String(), HostId: host.HostId.String(), State: NodeHealthStateHealthy, Share: 1}, tracker.Status(host))
+	})
+
+	t.Run("quarantine doubles until the node is fully reintroduced", func(t *testing.T) {
+		tracker, now := newTracker()
+		for _, expected := range []time.Duration{30 * time.Second, 60 * time.Second, 100 * time.Second} {
+			tracker.RecordFailure(host, NodeFailureConnect)
+			tracker.RecordFailure(host, NodeFailureConnect)
+			status := tracker.Status(host)
+			require.Equal(t, NodeHealthStateQuarantined, status.State)
+			require.Equal(t, now.Add(expected), *status.QuarantinedUntil)
+			// failures during the quarantine are ignored
+			tracker.RecordFailure(host, NodeFailureConnect)
+			*now = status.QuarantinedUntil.Add(time.Second)
+		}
+
+		*now = now.Add(time.Minute)
+		tracker.RecordFailure(host, NodeFailureConnect)
+		tracker.RecordFailure(host, NodeFailureConnect)
+		status := tracker.Status(host)
+		require.Equal(t, 1, status.Quarantines)
+		require.Equal(t, now.Add(30*time.Second), *status.QuarantinedUntil)
+	})
+}
+
+func TestNodeHealthTracker_AssignedHosts(t *testing.T) {
+	conf := &config.Config{
+		NodeHealthQuarantineThreshold: 1,
+		NodeHealthScoreHalfLifeMs:     10000,
+		NodeHealthQuarantineMinMs:     30000,
+		NodeHealthQuarantineMaxMs:     30000,
+	}
+	hosts := []*Host{newTestNodeHealthHost(1), newTestNodeHealthHost(2), newTestNodeHealthHost(3)}
+	cc := &ControlConn{
+		topologyLock:  &sync.RWMutex{},
+		assignedHosts: hosts,
+		proxyRand:     NewThreadSafeRand(),
+		nodeHealth:    NewNodeHealthTracker(common.ClusterTypeTarget, conf, NewThreadSafeRand()),
+	}
+
+	cc.ReportNodeFailure(hosts[1], NodeFailureError)
+	for i := 0; i < 10; i++ {
+		host, err := cc.NextAssignedHost()
+		require.Nil(t, err)
+		require.NotEqual(t, hosts[1], host)
+	}
+
+	// every node is used if all of them are quarantined
+	cc.ReportNodeFailure(hosts[0], NodeFailureError)
+	cc.ReportNodeFailure(hosts[2], NodeFailureError)
+	assigned := map[*Host]bool{}
+	for i := 0; i < 3; i++ {
+		host, err := cc.NextAssignedHost()
+		require.Nil(t, err)
+		assigned[host] = true
+	}
+	require.Equal(t, 3, len(assigned))
+}
+
+func TestIsNodeFailureErrorCode(t *testing.T) {
+	require.True(t, isNodeFailureErrorCode(primitive.ErrorCodeOverloaded))
+	require.True(t, isNodeFailureErrorCode(primitive.ErrorCodeWriteTimeout))
+	require.False(t, isNodeFailureErrorCode(primitive.ErrorCodeSyntaxError))
+	require.False(t, isNodeFailureErrorCode(primitive.ErrorCodeUnavailable))
+}
+
+func newTestNodeHealthHost(id byte) *Host {
+	return NewHost(net.IPv4(127, 0, 0, id), 9042, uuid.UUID{id}, "dc1", "rack1", nil, nil, nil)
+}
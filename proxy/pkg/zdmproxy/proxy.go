@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"github.com/datastax/zdm-proxy/proxy/pkg/common"
 	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/crash"
 	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
 	"github.com/datastax/zdm-proxy/proxy/pkg/metrics/noopmetrics"
 	"github.com/datastax/zdm-proxy/proxy/pkg/metrics/prommetrics"
@@ -16,6 +17,7 @@ import (
 	"math/rand"
 	"net"
 	"runtime"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -35,6 +37,23 @@ type ZdmProxy struct {
 	primaryCluster    common.ClusterType
 	readMode          common.ReadMode
 	systemQueriesMode common.SystemQueriesMode
+	tableReadRouting  *TableReadRouting
+	clientReadRouting []*common.ClientReadRoute
+	eventForwarding   common.EventForwarding
+	readShift         *ReadShift
+	readShiftRamp     *ReadShiftRamp
+	featureFlags      *FeatureFlags
+	requestSampler    *RequestSampler
+	consistencyStats  *ConsistencyStats
+	trafficRecorder   *TrafficRecorder
+	events            *EventBroadcaster
+
+	dualWriteFailureMode common.DualWriteFailureMode
+	lwtMode              common.LwtMode
+	counterWriteMode     common.CounterWriteMode
+	singleSidedWrites    *SingleSidedWrites
+	writeTimestampFloor  *WriteTimestampFloor
+	secondaryWriteRetry  *SecondaryWriteRetry
 
 	proxyRand *rand.Rand
 
@@ -61,6 +80,8 @@ type ZdmProxy struct {
 	asyncBuckets  []float64
 
 	activeClients int32
+	// incremented for every accepted client connection, identifies the connection in the logs
+	lastClientConnId uint64
 
 	requestResponseNumWorkers int
 	readNumWorkers            int
@@ -76,7 +97,13 @@ type ZdmProxy struct {
 	clientHandlersShutdownRequestCancelFn context.CancelFunc
 	globalClientHandlersWg                *sync.WaitGroup
 
-	metricHandler *metrics.MetricHandler
+	// client handlers that are currently running, keyed on the remote address of the client connection
+	clientHandlers *sync.Map
+
+	metricHandler   *metrics.MetricHandler
+	metricsFlushers []func() error
+
+	loadTracker *LoadTracker
 }
 
 func NewZdmProxy(conf *config.Config) (*ZdmProxy, error) {
@@ -166,6 +193,8 @@ func (p *ZdmProxy) Start(ctx context.Context) error {
 	log.Infof("Initialized target control connection. Cluster Name: %v, Hosts: %v, Assigned Hosts: %v.",
 		p.targetControlConn.GetClusterName(), targetHosts, targetAssignedHosts)
 
+	p.readShiftRamp.Start(p.clientHandlersShutdownRequestCtx)
+
 	err = p.acceptConnectionsFromClients(p.Conf.ProxyListenAddress, p.Conf.ProxyListenPort, serverSideTlsConfig)
 	if err != nil {
 		return err
@@ -211,8 +240,24 @@ func (p *ZdmProxy) initializeControlConnections(ctx context.Context) error {
 		return err
 	}
 
+	originDialConfig, err := p.Conf.ParseOriginDialConfig()
+	if err != nil {
+		return err
+	}
+	if originDialConfig.LocalAddress != nil {
+		log.Infof("Connections to Origin will use local address %v", originDialConfig.LocalAddress)
+	}
+	if originDialConfig.NetworkProxyUrl != nil {
+		log.Infof("Connections to Origin will go through proxy %v", originDialConfig.NetworkProxyUrl.Redacted())
+	}
+	if originDialConfig.SshJumpHost != "" {
+		log.Infof("Connections to Origin will be tunneled through SSH jump host %v@%v",
+			originDialConfig.SshUser, originDialConfig.SshJumpHost)
+	}
+
 	// Initialize origin connection configuration and control connection endpoint configuration
 	originConnectionConfig, err := InitializeConnectionConfig(originTlsConfig,
+		originDialConfig,
 		parsedOriginContactPoints,
 		p.Conf.OriginPort,
 		p.Conf.OriginConnectionTimeoutMs,
@@ -232,8 +277,24 @@ func (p *ZdmProxy) initializeControlConnections(ctx context.Context) error {
 		return err
 	}
 
+	targetDialConfig, err := p.Conf.ParseTargetDialConfig()
+	if err != nil {
+		return err
+	}
+	if targetDialConfig.LocalAddress != nil {
+		log.Infof("Connections to Target will use local address %v", targetDialConfig.LocalAddress)
+	}
+	if targetDialConfig.NetworkProxyUrl != nil {
+		log.Infof("Connections to Target will go through proxy %v", targetDialConfig.NetworkProxyUrl.Redacted())
+	}
+	if targetDialConfig.SshJumpHost != "" {
+		log.Infof("Connections to Target will be tunneled through SSH jump host %v@%v",
+			targetDialConfig.SshUser, targetDialConfig.SshJumpHost)
+	}
+
 	// Initialize target connection configuration and control connection endpoint configuration
 	targetConnectionConfig, err := InitializeConnectionConfig(targetTlsConfig,
+		targetDialConfig,
 		parsedTargetContactPoints,
 		p.Conf.TargetPort,
 		p.Conf.TargetConnectionTimeoutMs,
@@ -327,6 +388,21 @@ func (p *ZdmProxy) initializeGlobalStructures() error {
 		return err
 	}
 
+	p.dualWriteFailureMode, err = p.Conf.ParseDualWriteFailureMode()
+	if err != nil {
+		return err
+	}
+
+	p.lwtMode, err = p.Conf.ParseLwtMode()
+	if err != nil {
+		return err
+	}
+
+	p.counterWriteMode, err = p.Conf.ParseCounterWriteMode()
+	if err != nil {
+		return err
+	}
+
 	defaultReadWorkers := maxProcs * 8
 	defaultWriteWorkers := maxProcs * 4
 	if p.readMode == common.ReadModeDualAsyncOnSecondary {
@@ -380,6 +456,92 @@ func (p *ZdmProxy) initializeGlobalStructures() error {
 
 	p.globalClientHandlersWg = &sync.WaitGroup{}
 	p.clientHandlersShutdownRequestCtx, p.clientHandlersShutdownRequestCancelFn = context.WithCancel(context.Background())
+	p.clientHandlers = &sync.Map{}
+	p.events = NewEventBroadcaster()
+	p.tableReadRouting = NewTableReadRouting(p.events)
+	p.singleSidedWrites = NewSingleSidedWrites(p.Conf.TargetReadCutoverMaxMissingWrites)
+
+	featureFlags, err := p.Conf.ParseFeatureFlags()
+	if err != nil {
+		return fmt.Errorf("failed to parse feature flags: %w", err)
+	}
+	p.featureFlags = NewFeatureFlags(featureFlags, p.events)
+
+	p.clientReadRouting, err = p.Conf.ParseClientReadRouting()
+	if err != nil {
+		return fmt.Errorf("failed to parse client read routing: %w", err)
+	}
+	if len(p.clientReadRouting) > 0 {
+		log.Infof("Client read routing: %v.", p.clientReadRouting)
+	}
+
+	p.eventForwarding, err = p.Conf.ParseEventForwarding()
+	if err != nil {
+		return fmt.Errorf("failed to parse event forwarding: %w", err)
+	}
+
+	readShiftKey, err := p.Conf.ParseTargetReadShiftKey()
+	if err != nil {
+		return err
+	}
+	p.readShift = NewReadShift(p.Conf.TargetReadPercentage, readShiftKey, p.events)
+	if p.Conf.TargetReadPercentage > 0 {
+		if p.primaryCluster == common.ClusterTypeOrigin {
+			log.Infof("Shifting %d%% of the reads to %v by %v.", p.Conf.TargetReadPercentage, common.ClusterTypeTarget, readShiftKey)
+		} else {
+			log.Warnf("ZDM_TARGET_READ_PERCENTAGE is %d but it only applies while ZDM_PRIMARY_CLUSTER is %v.",
+				p.Conf.TargetReadPercentage, common.ClusterTypeOrigin)
+		}
+	}
+
+	readShiftRampSteps, err := p.Conf.ParseTargetReadRampSteps()
+	if err != nil {
+		return err
+	}
+	if len(readShiftRampSteps) > 0 {
+		if p.primaryCluster != common.ClusterTypeOrigin {
+			log.Warnf("ZDM_TARGET_READ_RAMP_STEPS is set but reads are only shifted while ZDM_PRIMARY_CLUSTER is %v.",
+				common.ClusterTypeOrigin)
+		}
+		p.readShiftRamp = NewReadShiftRamp(
+			p.readShift, readShiftRampSteps, time.Duration(p.Conf.TargetReadRampIntervalSecs)*time.Second,
+			p.Conf.TargetReadRampMinReads, p.Conf.TargetReadRampMaxErrorRatio, p.singleSidedWrites, p.events)
+	}
+
+	writeTimestampFloor, err := p.Conf.ParseWriteTimestampFloor()
+	if err != nil {
+		return err
+	}
+	writeTimestampFloorMode, err := p.Conf.ParseWriteTimestampFloorMode()
+	if err != nil {
+		return err
+	}
+	p.writeTimestampFloor = NewWriteTimestampFloor(writeTimestampFloor, writeTimestampFloorMode)
+	if p.writeTimestampFloor != nil {
+		log.Infof("Checking the explicit timestamps of writes against %v.", p.writeTimestampFloor)
+	}
+
+	secondaryWriteRetryErrorCodes, err := p.Conf.ParseSecondaryWriteRetryErrorCodes()
+	if err != nil {
+		return err
+	}
+	p.secondaryWriteRetry = NewSecondaryWriteRetry(p.Conf.SecondaryWriteRetryAttempts,
+		time.Duration(p.Conf.SecondaryWriteRetryBackoffMs)*time.Millisecond, secondaryWriteRetryErrorCodes)
+	if p.secondaryWriteRetry != nil {
+		log.Infof("Retrying writes that fail on the secondary cluster: %v.", p.secondaryWriteRetry)
+	}
+
+	p.requestSampler, err = NewRequestSampler(p.Conf.RequestSamplingRate, p.Conf.RequestSamplingFile)
+	if err != nil {
+		return err
+	}
+
+	p.consistencyStats = NewConsistencyStats(p.Conf.ConsistencyStatsEnabled)
+
+	p.trafficRecorder, err = NewTrafficRecorder(p.Conf.TrafficRecordingFile, p.Conf.TrafficRecordingMaxRequests)
+	if err != nil {
+		return err
+	}
 
 	p.PreparedStatementCache = NewPreparedStatementCache()
 
@@ -409,6 +571,8 @@ func (p *ZdmProxy) initializeGlobalStructures() error {
 		log.Infof("Parsed Async latency buckets: %v", p.asyncBuckets)
 	}
 
+	p.loadTracker = NewLoadTracker(p.Conf.ProxyCapacityInFlightRequests, p.Conf.ProxyCapacityRequestsPerSecond)
+
 	p.activeClients = 0
 	return nil
 }
@@ -429,7 +593,7 @@ func (p *ZdmProxy) acceptConnectionsFromClients(address string, port int, server
 	}
 
 	if err != nil {
-		return err
+		return fmt.Errorf("%w on %v: %v", ListenErr, listenAddr, err)
 	}
 
 	p.listenerLock.Lock()
@@ -439,6 +603,7 @@ func (p *ZdmProxy) acceptConnectionsFromClients(address string, port int, server
 	p.listenerShutdownWg.Add(1)
 
 	go func() {
+		defer crash.HandlePanic()
 		defer p.listenerShutdownWg.Done()
 		defer func() {
 			p.listenerLock.Lock()
@@ -479,12 +644,16 @@ func (p *ZdmProxy) acceptConnectionsFromClients(address string, port int, server
 			}
 
 			atomic.AddInt32(&p.activeClients, 1)
-			log.Infof("Accepted connection from %v", conn.RemoteAddr())
+			connLogger := log.WithFields(log.Fields{
+				"client":  conn.RemoteAddr().String(),
+				"conn_id": atomic.AddUint64(&p.lastClientConnId, 1),
+			})
+			connLogger.Infof("Accepted connection from %v", conn.RemoteAddr())
 
 			wg.Add(1)
 			p.listenerScheduler.Schedule(func() {
 				defer wg.Done()
-				p.handleNewConnection(conn)
+				p.handleNewConnection(conn, connLogger)
 			})
 		}
 	}()
@@ -493,10 +662,12 @@ func (p *ZdmProxy) acceptConnectionsFromClients(address string, port int, server
 }
 
 // handleNewConnection creates the client handler and connectors for the new client connection
-func (p *ZdmProxy) handleNewConnection(clientConn net.Conn) {
+// connLogger contains the fields that identify the client connection, it is used for every log message of the
+// connection so that its whole lifecycle can be found in the logs.
+func (p *ZdmProxy) handleNewConnection(clientConn net.Conn, connLogger *log.Entry) {
 
 	errFunc := func(e error) {
-		log.Errorf("Client Handler could not be created: %v", e)
+		connLogger.Errorf("Client Handler could not be created: %v", e)
 		clientConn.Close()
 		atomic.AddInt32(&p.activeClients, -1)
 	}
@@ -516,7 +687,7 @@ func (p *ZdmProxy) handleNewConnection(clientConn net.Conn) {
 	} else {
 		originEndpoint = p.originControlConn.GetCurrentContactPoint()
 		if originEndpoint == nil {
-			log.Warnf("Origin ControlConnection current endpoint is nil, "+
+			connLogger.Warnf("Origin ControlConnection current endpoint is nil, "+
 				"falling back to first origin contact point (%v) for client connection %v.",
 				p.originConnectionConfig.GetContactPoints()[0].String(), clientConn.RemoteAddr().String())
 		}
@@ -534,7 +705,7 @@ func (p *ZdmProxy) handleNewConnection(clientConn net.Conn) {
 	} else {
 		targetEndpoint = p.targetControlConn.GetCurrentContactPoint()
 		if targetEndpoint == nil {
-			log.Warnf("Target ControlConnection current endpoint is nil, "+
+			connLogger.Warnf("Target ControlConnection current endpoint is nil, "+
 				"falling back to first target contact point (%v) for client connection %v.",
 				p.targetConnectionConfig.GetContactPoints()[0].String(), clientConn.RemoteAddr().String())
 		}
@@ -549,7 +720,7 @@ func (p *ZdmProxy) handleNewConnection(clientConn net.Conn) {
 		p.originControlConn,
 		p.targetControlConn,
 		p.Conf,
-		p.TopologyConfig,
+		p.GetTopologyConfig(),
 		p.Conf.TargetUsername,
 		p.Conf.TargetPassword,
 		p.Conf.OriginUsername,
@@ -567,19 +738,55 @@ func (p *ZdmProxy) handleNewConnection(clientConn net.Conn) {
 		p.timeUuidGenerator,
 		p.readMode,
 		p.primaryCluster,
-		p.systemQueriesMode)
+		p.tableReadRouting,
+		p.clientReadRouting,
+		p.eventForwarding,
+		p.readShift,
+		p.featureFlags,
+		p.requestSampler,
+		p.consistencyStats,
+		p.trafficRecorder,
+		p.systemQueriesMode,
+		p.dualWriteFailureMode,
+		p.lwtMode,
+		p.counterWriteMode,
+		p.singleSidedWrites,
+		p.loadTracker,
+		p.writeTimestampFloor,
+		p.secondaryWriteRetry,
+		connLogger)
 
 	if err != nil {
 		errFunc(err)
 		return
 	}
 
-	log.Tracef("ClientHandler created")
+	connLogger.Tracef("ClientHandler created")
+	clientAddr := clientConn.RemoteAddr().String()
+	p.clientHandlers.Store(clientAddr, clientHandler)
+	p.events.Publish(ProxyEventClientConnected, "client connection %v opened", clientAddr)
+	p.globalClientHandlersWg.Add(1)
+	go func() {
+		defer crash.HandlePanic()
+		defer p.globalClientHandlersWg.Done()
+		<-clientHandler.clientHandlerContext.Done()
+		p.clientHandlers.Delete(clientAddr)
+		p.events.Publish(ProxyEventClientDisconnected, "client connection %v closed", clientAddr)
+	}()
 	clientHandler.run(&p.activeClients)
 }
 
+// Shutdown stops the proxy in a fixed order so that no component is stopped while another one still depends on it:
+//  1. the client listener is closed so no new client connections are accepted
+//  2. the request listeners of the client connections stop reading new requests
+//  3. in flight requests are completed and the client handlers (and their cluster connections) are closed
+//  4. the control connections are closed
+//  5. the metrics are flushed (see AddMetricsFlusher) and unregistered, the request samples are written
+//
+// Client connections that are still tracked after the last step are logged as leaks.
 func (p *ZdmProxy) Shutdown() {
 	log.Info("Initiating proxy shutdown...")
+	p.events.Publish(ProxyEventProxyShuttingDown, "proxy is shutting down")
 
 	log.Debug("Requesting shutdown of the client listener...")
 	p.listenerLock.Lock()
@@ -612,17 +819,57 @@ func (p *ZdmProxy) Shutdown() {
 	p.listenerScheduler.Shutdown()
 
 	p.lock.Lock()
+	for _, flush := range p.metricsFlushers {
+		if err := flush(); err != nil {
+			log.Warnf("Failed to flush metrics: %v.", err)
+		}
+	}
 	if p.metricHandler != nil {
 		err := p.metricHandler.UnregisterAllMetrics()
 		if err != nil {
 			log.Warnf("Failed to unregister metrics: %v.", err)
 		}
 	}
+	p.requestSampler.Close()
+	p.trafficRecorder.Close()
 	p.lock.Unlock()
 
+	if p.events != nil {
+		p.events.Close()
+	}
+	p.logConnectionLeaks()
 	log.Info("Proxy shutdown complete.")
 }
 
+// AddMetricsFlusher registers a function that is called during shutdown once every client and cluster connection is
+// closed and before the metrics are unregistered, e.g. to persist the final value of the metrics.
+func (p *ZdmProxy) AddMetricsFlusher(flush func() error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.metricsFlushers = append(p.metricsFlushers, flush)
+}
+
+// logConnectionLeaks logs the client connections that were not released even though every client handler is done,
+// this means that a client handler was not tracked properly which would otherwise go unnoticed until the max number
+// of client connections is reached.
+func (p *ZdmProxy) logConnectionLeaks() {
+	if activeClients := atomic.LoadInt32(&p.activeClients); activeClients != 0 {
+		log.Warnf("Connection leak detected: %d client connection(s) still counted as active after shutdown.", activeClients)
+	}
+	if p.clientHandlers == nil {
+		return
+	}
+	if leaked := p.GetClientConnections(); len(leaked) > 0 {
+		log.Warnf("Connection leak detected: client handlers of %v still registered after shutdown.", leaked)
+	}
+}
+
+// GetEvents returns the broadcaster of the live proxy events.
+func (p *ZdmProxy) GetEvents() *EventBroadcaster {
+	return p.events
+}
+
 func (p *ZdmProxy) GetOriginControlConn() *ControlConn {
 	p.lock.RLock()
 	defer p.lock.RUnlock()
@@ -637,6 +884,85 @@ func (p *ZdmProxy) GetTargetControlConn() *ControlConn {
 	return p.targetControlConn
 }
 
+func (p *ZdmProxy) GetActiveClients() int {
+	return int(atomic.LoadInt32(&p.activeClients))
+}
+
+// GetClientConnections returns the remote addresses of the client connections that are currently open.
+func (p *ZdmProxy) GetClientConnections() []string {
+	addresses := make([]string, 0)
+	p.clientHandlers.Range(func(key, _ interface{}) bool {
+		addresses = append(addresses, key.(string))
+		return true
+	})
+	sort.Strings(addresses)
+	return addresses
+}
+
+// DrainClientConnections gracefully closes the client connection with the provided remote address (host:port) or
+// every client connection from the provided IP address. It returns the addresses of the connections that are being
+// drained, draining happens in the background.
+func (p *ZdmProxy) DrainClientConnections(address string) ([]string, error) {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			return nil, fmt.Errorf("invalid client address %v, expected an IP address or IP:port", address)
+		}
+	}
+
+	drained := make([]string, 0)
+	p.clientHandlers.Range(func(key, value interface{}) bool {
+		clientAddr := key.(string)
+		if ip != nil {
+			host, _, err := net.SplitHostPort(clientAddr)
+			if err != nil || !ip.Equal(net.ParseIP(host)) {
+				return true
+			}
+		} else if clientAddr != address {
+			return true
+		}
+		log.Infof("Draining client connection %v.", clientAddr)
+		value.(*ClientHandler).Drain()
+		p.events.Publish(ProxyEventClientDraining, "client connection %v is draining", clientAddr)
+		drained = append(drained, clientAddr)
+		return true
+	})
+	sort.Strings(drained)
+	return drained, nil
+}
+
+func (p *ZdmProxy) GetPrimaryCluster() common.ClusterType {
+	return p.primaryCluster
+}
+
+func (p *ZdmProxy) GetReadMode() common.ReadMode {
+	return p.readMode
+}
+
+func (p *ZdmProxy) GetTableReadRouting() *TableReadRouting {
+	return p.tableReadRouting
+}
+
+func (p *ZdmProxy) GetSingleSidedWrites() *SingleSidedWrites {
+	return p.singleSidedWrites
+}
+
+func (p *ZdmProxy) GetConsistencyStats() *ConsistencyStats {
+	return p.consistencyStats
+}
+
+func (p *ZdmProxy) GetReadShift() *ReadShift {
+	return p.readShift
+}
+
+func (p *ZdmProxy) GetReadShiftRamp() *ReadShiftRamp {
+	return p.readShiftRamp
+}
+
+func (p *ZdmProxy) GetFeatureFlags() *FeatureFlags {
+	return p.featureFlags
+}
+
 func Run(conf *config.Config, ctx context.Context) (*ZdmProxy, error) {
 	zdmProxy, err := NewZdmProxy(conf)
 	if err != nil {
@@ -654,15 +980,27 @@ func Run(conf *config.Config, ctx context.Context) (*ZdmProxy, error) {
 	return zdmProxy, nil
 }
 
+// RunWithRetries retries to start the proxy until it succeeds, the context is canceled or ZDM_PROXY_STARTUP_TIMEOUT_MS
+// elapses (StartupTimeoutErr is returned in that case). Listen failures (ListenErr) are not retried.
 func RunWithRetries(conf *config.Config, ctx context.Context, b *backoff.Backoff) (*ZdmProxy, error) {
 	log.Info("Attempting to start the proxy...")
+	var deadline time.Time
+	if conf.ProxyStartupTimeoutMs > 0 {
+		deadline = time.Now().Add(time.Duration(conf.ProxyStartupTimeoutMs) * time.Millisecond)
+	}
 	for {
 		zdmProxy, err := Run(conf, ctx)
 		if zdmProxy != nil {
 			return zdmProxy, nil
 		}
+		if errors.Is(err, ListenErr) {
+			return nil, err
+		}
 
 		nextDuration := b.Duration()
+		if !deadline.IsZero() && !errors.Is(err, ShutdownErr) && time.Now().Add(nextDuration).After(deadline) {
+			return nil, fmt.Errorf("%w: %v", StartupTimeoutErr, err)
+		}
 		if !errors.Is(err, ShutdownErr) {
 			log.Errorf("Couldn't start proxy, retrying in %v: %v.", nextDuration, err)
 		}
@@ -722,6 +1060,26 @@ func (p *ZdmProxy) CreateProxyMetrics(metricFactory metrics.MetricFactory) (*met
 		return nil, err
 	}
 
+	clientSlowWrites, err := metricFactory.GetOrCreateCounter(metrics.ClientSlowWrites)
+	if err != nil {
+		return nil, err
+	}
+
+	clientWriteTimeouts, err := metricFactory.GetOrCreateCounter(metrics.ClientWriteTimeouts)
+	if err != nil {
+		return nil, err
+	}
+
+	clientQueuedRequests, err := metricFactory.GetOrCreateGauge(metrics.ClientQueuedRequests)
+	if err != nil {
+		return nil, err
+	}
+
+	clientOverloadedRequests, err := metricFactory.GetOrCreateCounter(metrics.ClientOverloadedRequests)
+	if err != nil {
+		return nil, err
+	}
+
 	proxyReadsOriginDuration, err := metricFactory.GetOrCreateHistogram(metrics.ProxyReadsOriginDuration, p.originBuckets)
 	if err != nil {
 		return nil, err
@@ -752,6 +1110,51 @@ func (p *ZdmProxy) CreateProxyMetrics(metricFactory metrics.MetricFactory) (*met
 		return nil, err
 	}
 
+	proxyMonitoringDuration, err := metricFactory.GetOrCreateHistogram(metrics.ProxyMonitoringDuration, p.originBuckets)
+	if err != nil {
+		return nil, err
+	}
+
+	inFlightMonitoring, err := metricFactory.GetOrCreateGauge(metrics.InFlightMonitoring)
+	if err != nil {
+		return nil, err
+	}
+
+	staleTimestampWrites, err := metricFactory.GetOrCreateCounter(metrics.StaleTimestampWrites)
+	if err != nil {
+		return nil, err
+	}
+
+	secondaryWriteRetries, err := metricFactory.GetOrCreateCounter(metrics.SecondaryWriteRetries)
+	if err != nil {
+		return nil, err
+	}
+
+	singleSidedWritesOrigin, err := metricFactory.GetOrCreateCounter(metrics.SingleSidedWritesOrigin)
+	if err != nil {
+		return nil, err
+	}
+
+	singleSidedWritesTarget, err := metricFactory.GetOrCreateCounter(metrics.SingleSidedWritesTarget)
+	if err != nil {
+		return nil, err
+	}
+
+	conditionalWrites, err := metricFactory.GetOrCreateCounter(metrics.ConditionalWrites)
+	if err != nil {
+		return nil, err
+	}
+
+	conditionalMismatches, err := metricFactory.GetOrCreateCounter(metrics.ConditionalMismatches)
+	if err != nil {
+		return nil, err
+	}
+
+	counterWrites, err := metricFactory.GetOrCreateCounter(metrics.CounterWrites)
+	if err != nil {
+		return nil, err
+	}
+
 	openClientConnections, err := metricFactory.GetOrCreateGaugeFunc(metrics.OpenClientConnections, func() float64 {
 		return float64(atomic.LoadInt32(&p.activeClients))
 	})
@@ -759,21 +1162,81 @@ func (p *ZdmProxy) CreateProxyMetrics(metricFactory metrics.MetricFactory) (*met
 		return nil, err
 	}
 
+	loadUtilization, err := metricFactory.GetOrCreateGaugeFunc(metrics.LoadUtilization, p.loadTracker.Utilization)
+	if err != nil {
+		return nil, err
+	}
+
+	loadInFlightUtilization, err := metricFactory.GetOrCreateGaugeFunc(
+		metrics.LoadInFlightUtilization, p.loadTracker.InFlightUtilization)
+	if err != nil {
+		return nil, err
+	}
+
+	loadRequestsPerSecond, err := metricFactory.GetOrCreateGaugeFunc(
+		metrics.LoadRequestsPerSecond, p.loadTracker.RequestsPerSecond)
+	if err != nil {
+		return nil, err
+	}
+
+	loadCpuUtilization, err := metricFactory.GetOrCreateGaugeFunc(metrics.LoadCpuUtilization, p.loadTracker.CpuUtilization)
+	if err != nil {
+		return nil, err
+	}
+
+	loadQpsHeadroom, err := metricFactory.GetOrCreateGaugeFunc(metrics.LoadQpsHeadroom, p.loadTracker.QpsHeadroom)
+	if err != nil {
+		return nil, err
+	}
+
+	targetReadPercentage, err := metricFactory.GetOrCreateGaugeFunc(metrics.TargetReadPercentage, func() float64 {
+		return float64(p.readShift.GetPercentage())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	buildInfo, err := metricFactory.GetOrCreateGauge(metrics.BuildInfo)
+	if err != nil {
+		return nil, err
+	}
+	buildInfo.Set(1)
+
 	proxyMetrics := &metrics.ProxyMetrics{
 		FailedReadsOrigin:        failedReadsOrigin,
-		FailedReadsTarget:        failedReadsTarget,
+		FailedReadsTarget:        p.readShiftRamp.TrackFailedTargetReadsCounter(failedReadsTarget),
 		FailedWritesOnOrigin:     failedWritesOnOrigin,
 		FailedWritesOnTarget:     failedWritesOnTarget,
 		FailedWritesOnBoth:       failedWritesOnBoth,
+		SingleSidedWritesOrigin:  singleSidedWritesOrigin,
+		SingleSidedWritesTarget:  singleSidedWritesTarget,
 		PSCacheSize:              psCacheSize,
 		PSCacheMissCount:         psCacheMissCount,
 		ProxyReadsOriginDuration: proxyReadsOriginDuration,
 		ProxyReadsTargetDuration: proxyReadsTargetDuration,
 		ProxyWritesDuration:      proxyWritesDuration,
-		InFlightReadsOrigin:      inFlightReadsOrigin,
-		InFlightReadsTarget:      inFlightReadsTarget,
-		InFlightWrites:           inFlightWrites,
+		ProxyMonitoringDuration:  proxyMonitoringDuration,
+		InFlightReadsOrigin:      p.loadTracker.TrackInFlightGauge(inFlightReadsOrigin),
+		InFlightReadsTarget:      p.readShiftRamp.TrackTargetReadsGauge(p.loadTracker.TrackInFlightGauge(inFlightReadsTarget)),
+		InFlightWrites:           p.loadTracker.TrackInFlightGauge(inFlightWrites),
+		InFlightMonitoring:       inFlightMonitoring,
+		StaleTimestampWrites:     staleTimestampWrites,
+		SecondaryWriteRetries:    secondaryWriteRetries,
+		ConditionalWrites:        conditionalWrites,
+		ConditionalMismatches:    conditionalMismatches,
+		CounterWrites:            counterWrites,
 		OpenClientConnections:    openClientConnections,
+		ClientSlowWrites:         clientSlowWrites,
+		ClientWriteTimeouts:      clientWriteTimeouts,
+		ClientQueuedRequests:     clientQueuedRequests,
+		ClientOverloadedRequests: clientOverloadedRequests,
+		LoadUtilization:          loadUtilization,
+		LoadInFlightUtilization:  loadInFlightUtilization,
+		LoadRequestsPerSecond:    loadRequestsPerSecond,
+		LoadCpuUtilization:       loadCpuUtilization,
+		LoadQpsHeadroom:          loadQpsHeadroom,
+		TargetReadPercentage:     targetReadPercentage,
+		BuildInfo:                buildInfo,
 	}
 
 	return proxyMetrics, nil
@@ -852,6 +1315,11 @@ func (p *ZdmProxy) CreateOriginNodeMetrics(
 		return nil, err
 	}
 
+	originSlowWrites, err := metrics.CreateCounterNodeMetric(metricFactory, originNodeDescription, metrics.OriginSlowWrites)
+	if err != nil {
+		return nil, err
+	}
+
 	return &metrics.NodeMetricsInstance{
 		ClientTimeouts:    originClientTimeouts,
 		ReadTimeouts:      originReadTimeouts,
@@ -867,6 +1335,7 @@ func (p *ZdmProxy) CreateOriginNodeMetrics(
 		OpenConnections:   openOriginConnections,
 		InFlightRequests:  inflightRequests,
 		UsedStreamIds:     originUsedStreamIds,
+		SlowWrites:        originSlowWrites,
 	}, nil
 }
 
@@ -942,6 +1411,11 @@ func (p *ZdmProxy) CreateAsyncNodeMetrics(
 		return nil, err
 	}
 
+	asyncSlowWrites, err := metrics.CreateCounterNodeMetric(metricFactory, asyncNodeDescription, metrics.AsyncSlowWrites)
+	if err != nil {
+		return nil, err
+	}
+
 	return &metrics.NodeMetricsInstance{
 		ClientTimeouts:    asyncClientTimeouts,
 		ReadTimeouts:      asyncReadTimeouts,
@@ -957,6 +1431,7 @@ func (p *ZdmProxy) CreateAsyncNodeMetrics(
 		OpenConnections:   openAsyncConnections,
 		InFlightRequests:  inflightRequestsAsync,
 		UsedStreamIds:     asyncUsedStreamIds,
+		SlowWrites:        asyncSlowWrites,
 	}, nil
 }
 
@@ -1033,6 +1508,11 @@ func (p *ZdmProxy) CreateTargetNodeMetrics(
 		return nil, err
 	}
 
+	targetSlowWrites, err := metrics.CreateCounterNodeMetric(metricFactory, targetNodeDescription, metrics.TargetSlowWrites)
+	if err != nil {
+		return nil, err
+	}
+
 	return &metrics.NodeMetricsInstance{
 		ClientTimeouts:    targetClientTimeouts,
 		ReadTimeouts:      targetReadTimeouts,
@@ -1048,5 +1528,6 @@ func (p *ZdmProxy) CreateTargetNodeMetrics(
 		OpenConnections:   openTargetConnections,
 		InFlightRequests:  inflightRequests,
 		UsedStreamIds:     targetUsedStreamIds,
+		SlowWrites:        targetSlowWrites,
 	}, nil
 }
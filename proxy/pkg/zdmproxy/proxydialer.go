@@ -0,0 +1,253 @@
+package zdmproxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// newProxyDialer returns a Dialer that opens connections through the SOCKS5 or HTTP CONNECT proxy described by
+// proxyUrl. The connection to the proxy itself is opened with the provided forward Dialer.
+func newProxyDialer(proxyUrl *url.URL, forward Dialer) (Dialer, error) {
+	switch proxyUrl.Scheme {
+	case "socks5", "socks5h":
+		return &socks5Dialer{proxyUrl: proxyUrl, forward: forward}, nil
+	case "http":
+		return &httpConnectDialer{proxyUrl: proxyUrl, forward: forward}, nil
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %v, valid values are socks5, socks5h and http", proxyUrl.Scheme)
+	}
+}
+
+// dialProxy opens the connection to the proxy and sets a deadline for the handshake based on the context.
+func dialProxy(ctx context.Context, forward Dialer, proxyUrl *url.URL, defaultPort string) (net.Conn, error) {
+	proxyAddr := proxyUrl.Host
+	if proxyUrl.Port() == "" {
+		proxyAddr = net.JoinHostPort(proxyUrl.Hostname(), defaultPort)
+	}
+	conn, err := forward.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to proxy %v: %w", proxyAddr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	return conn, nil
+}
+
+const (
+	socks5Version             = 0x05
+	socks5AuthNone            = 0x00
+	socks5AuthUsernamePass    = 0x02
+	socks5AuthNoAcceptable    = 0xFF
+	socks5UsernamePassVersion = 0x01
+	socks5CmdConnect          = 0x01
+	socks5AddrTypeIPv4        = 0x01
+	socks5AddrTypeDomain      = 0x03
+	socks5AddrTypeIPv6        = 0x04
+	socks5ReplySucceeded      = 0x00
+)
+
+type socks5Dialer struct {
+	proxyUrl *url.URL
+	forward  Dialer
+}
+
+func (recv *socks5Dialer) DialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	conn, err := dialProxy(ctx, recv.forward, recv.proxyUrl, "1080")
+	if err != nil {
+		return nil, err
+	}
+	err = recv.handshake(conn, address)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("SOCKS5 proxy %v could not connect to %v: %w", recv.proxyUrl.Host, address, err)
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+func (recv *socks5Dialer) handshake(conn net.Conn, address string) error {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return fmt.Errorf("invalid port %v: %w", portStr, err)
+	}
+
+	methods := []byte{socks5AuthNone}
+	if recv.proxyUrl.User != nil {
+		methods = append(methods, socks5AuthUsernamePass)
+	}
+	_, err = conn.Write(append([]byte{socks5Version, byte(len(methods))}, methods...))
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 2)
+	if _, err = io.ReadFull(conn, buf); err != nil {
+		return err
+	}
+	if buf[0] != socks5Version {
+		return fmt.Errorf("unexpected SOCKS version %d", buf[0])
+	}
+
+	switch buf[1] {
+	case socks5AuthNone:
+	case socks5AuthUsernamePass:
+		if recv.proxyUrl.User == nil {
+			return errors.New("proxy requested username/password authentication but no credentials were provided")
+		}
+		if err = recv.authenticate(conn); err != nil {
+			return err
+		}
+	case socks5AuthNoAcceptable:
+		return errors.New("no acceptable authentication methods")
+	default:
+		return fmt.Errorf("unsupported authentication method %d", buf[1])
+	}
+
+	req := []byte{socks5Version, socks5CmdConnect, 0x00}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			req = append(req, socks5AddrTypeIPv4)
+			req = append(req, ip4...)
+		} else {
+			req = append(req, socks5AddrTypeIPv6)
+			req = append(req, ip.To16()...)
+		}
+	} else {
+		if len(host) > 255 {
+			return fmt.Errorf("host name too long: %v", host)
+		}
+		req = append(req, socks5AddrTypeDomain, byte(len(host)))
+		req = append(req, host...)
+	}
+	req = binary.BigEndian.AppendUint16(req, uint16(port))
+	if _, err = conn.Write(req); err != nil {
+		return err
+	}
+
+	// VER REP RSV ATYP
+	reply := make([]byte, 4)
+	if _, err = io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[1] != socks5ReplySucceeded {
+		return fmt.Errorf("connect request failed with reply code %d", reply[1])
+	}
+
+	var boundAddrLen int
+	switch reply[3] {
+	case socks5AddrTypeIPv4:
+		boundAddrLen = net.IPv4len
+	case socks5AddrTypeIPv6:
+		boundAddrLen = net.IPv6len
+	case socks5AddrTypeDomain:
+		lenBuf := make([]byte, 1)
+		if _, err = io.ReadFull(conn, lenBuf); err != nil {
+			return err
+		}
+		boundAddrLen = int(lenBuf[0])
+	default:
+		return fmt.Errorf("unexpected address type %d in reply", reply[3])
+	}
+
+	// BND.ADDR and BND.PORT are not used
+	_, err = io.ReadFull(conn, make([]byte, boundAddrLen+2))
+	return err
+}
+
+func (recv *socks5Dialer) authenticate(conn net.Conn) error {
+	username := recv.proxyUrl.User.Username()
+	password, _ := recv.proxyUrl.User.Password()
+	if len(username) > 255 || len(password) > 255 {
+		return errors.New("proxy username or password too long")
+	}
+
+	req := []byte{socks5UsernamePassVersion, byte(len(username))}
+	req = append(req, username...)
+	req = append(req, byte(len(password)))
+	req = append(req, password...)
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 2)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return err
+	}
+	if buf[1] != 0x00 {
+		return errors.New("username/password authentication failed")
+	}
+	return nil
+}
+
+type httpConnectDialer struct {
+	proxyUrl *url.URL
+	forward  Dialer
+}
+
+func (recv *httpConnectDialer) DialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	conn, err := dialProxy(ctx, recv.forward, recv.proxyUrl, "80")
+	if err != nil {
+		return nil, err
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if recv.proxyUrl.User != nil {
+		password, _ := recv.proxyUrl.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(recv.proxyUrl.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+
+	if err = req.Write(conn); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("HTTP proxy %v could not connect to %v: %w", recv.proxyUrl.Host, address, err)
+	}
+
+	reader := bufio.NewReader(conn)
+	rsp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("HTTP proxy %v could not connect to %v: %w", recv.proxyUrl.Host, address, err)
+	}
+	_ = rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		_ = conn.Close()
+		return nil, fmt.Errorf("HTTP proxy %v could not connect to %v: %v", recv.proxyUrl.Host, address, rsp.Status)
+	}
+
+	_ = conn.SetDeadline(time.Time{})
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn returns the bytes that were read ahead while parsing the proxy response before reading from the
+// underlying connection.
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (recv *bufferedConn) Read(b []byte) (int, error) {
+	return recv.reader.Read(b)
+}
@@ -0,0 +1,174 @@
+package zdmproxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestProxyDialer_Socks5(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     *url.Userinfo
+		address  string
+		expected string
+	}{
+		{"no auth ip", nil, "127.0.0.1:9042", "127.0.0.1:9042"},
+		{"no auth hostname", nil, "cassandra.local:9042", "cassandra.local:9042"},
+		{"username password", url.UserPassword("user1", "pass1"), "10.0.0.1:9043", "10.0.0.1:9043"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestedAddr := make(chan string, 1)
+			proxyAddr := startFakeProxy(t, func(conn net.Conn) {
+				requestedAddr <- fakeSocks5Handshake(t, conn, tt.user)
+			})
+
+			dialer, err := newProxyDialer(&url.URL{Scheme: "socks5", Host: proxyAddr, User: tt.user}, &net.Dialer{})
+			require.Nil(t, err)
+			assertEcho(t, dialer, tt.address)
+			require.Equal(t, tt.expected, <-requestedAddr)
+		})
+	}
+}
+
+func TestProxyDialer_HttpConnect(t *testing.T) {
+	requestedAddr := make(chan string, 1)
+	authorization := make(chan string, 1)
+	proxyAddr := startFakeProxy(t, func(conn net.Conn) {
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		require.Nil(t, err)
+		require.Equal(t, http.MethodConnect, req.Method)
+		requestedAddr <- req.Host
+		authorization <- req.Header.Get("Proxy-Authorization")
+		_, err = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		require.Nil(t, err)
+	})
+
+	proxyUrl := &url.URL{Scheme: "http", Host: proxyAddr, User: url.UserPassword("user1", "pass1")}
+	dialer, err := newProxyDialer(proxyUrl, &net.Dialer{})
+	require.Nil(t, err)
+	assertEcho(t, dialer, "127.0.0.1:9042")
+	require.Equal(t, "127.0.0.1:9042", <-requestedAddr)
+	require.Equal(t, "Basic dXNlcjE6cGFzczE=", <-authorization)
+}
+
+func TestProxyDialer_HttpConnectRejected(t *testing.T) {
+	proxyAddr := startFakeProxy(t, func(conn net.Conn) {
+		_, err := http.ReadRequest(bufio.NewReader(conn))
+		require.Nil(t, err)
+		_, err = conn.Write([]byte("HTTP/1.1 403 Forbidden\r\nContent-Length: 0\r\n\r\n"))
+		require.Nil(t, err)
+	})
+
+	dialer, err := newProxyDialer(&url.URL{Scheme: "http", Host: proxyAddr}, &net.Dialer{})
+	require.Nil(t, err)
+	_, err = dialer.DialContext(context.Background(), "tcp", "127.0.0.1:9042")
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "403 Forbidden")
+}
+
+// startFakeProxy accepts a single connection, runs the handshake and then echoes everything back to the client.
+func startFakeProxy(t *testing.T, handshake func(conn net.Conn)) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		handshake(conn)
+		_, _ = io.Copy(conn, conn)
+	}()
+	return l.Addr().String()
+}
+
+func fakeSocks5Handshake(t *testing.T, conn net.Conn, user *url.Userinfo) string {
+	buf := make([]byte, 2)
+	_, err := io.ReadFull(conn, buf)
+	require.Nil(t, err)
+	methods := make([]byte, buf[1])
+	_, err = io.ReadFull(conn, methods)
+	require.Nil(t, err)
+
+	if user == nil {
+		_, err = conn.Write([]byte{socks5Version, socks5AuthNone})
+		require.Nil(t, err)
+	} else {
+		require.Contains(t, methods, byte(socks5AuthUsernamePass))
+		_, err = conn.Write([]byte{socks5Version, socks5AuthUsernamePass})
+		require.Nil(t, err)
+
+		_, err = io.ReadFull(conn, buf)
+		require.Nil(t, err)
+		username := make([]byte, buf[1])
+		_, err = io.ReadFull(conn, username)
+		require.Nil(t, err)
+		_, err = io.ReadFull(conn, buf[:1])
+		require.Nil(t, err)
+		password := make([]byte, buf[0])
+		_, err = io.ReadFull(conn, password)
+		require.Nil(t, err)
+		expectedPassword, _ := user.Password()
+		require.Equal(t, user.Username(), string(username))
+		require.Equal(t, expectedPassword, string(password))
+		_, err = conn.Write([]byte{socks5UsernamePassVersion, 0x00})
+		require.Nil(t, err)
+	}
+
+	header := make([]byte, 4)
+	_, err = io.ReadFull(conn, header)
+	require.Nil(t, err)
+	require.Equal(t, byte(socks5CmdConnect), header[1])
+
+	var host string
+	switch header[3] {
+	case socks5AddrTypeIPv4:
+		ip := make([]byte, net.IPv4len)
+		_, err = io.ReadFull(conn, ip)
+		host = net.IP(ip).String()
+	case socks5AddrTypeDomain:
+		_, err = io.ReadFull(conn, buf[:1])
+		require.Nil(t, err)
+		name := make([]byte, buf[0])
+		_, err = io.ReadFull(conn, name)
+		host = string(name)
+	default:
+		t.Errorf("unexpected address type %d", header[3])
+	}
+	require.Nil(t, err)
+
+	portBuf := make([]byte, 2)
+	_, err = io.ReadFull(conn, portBuf)
+	require.Nil(t, err)
+
+	_, err = conn.Write([]byte{socks5Version, socks5ReplySucceeded, 0x00, socks5AddrTypeIPv4, 127, 0, 0, 1, 0, 0})
+	require.Nil(t, err)
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(portBuf))))
+}
+
+func assertEcho(t *testing.T, dialer Dialer, address string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	require.Nil(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("hello"))
+	require.Nil(t, err)
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(conn, buf)
+	require.Nil(t, err)
+	require.Equal(t, "hello", string(buf))
+}
@@ -0,0 +1,112 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"net"
+)
+
+func (p *ZdmProxy) GetTopologyConfig() *common.TopologyConfig {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.TopologyConfig
+}
+
+// UpdateProxyTopology replaces the addresses of the proxy instances (ZDM_PROXY_TOPOLOGY_ADDRESSES) without a restart,
+// e.g. when the proxy fleet is scaled out or in. The virtual hosts returned to clients in system.local and system.peers
+// are recomputed and clients that registered for TOPOLOGY_CHANGE events are notified about the proxy instances that
+// were added or removed so that drivers rebalance their connections.
+//
+// The address of this proxy instance must be part of the new topology. The new topology is not persisted so
+// the configuration should be updated as well to keep it after a restart.
+func (p *ZdmProxy) UpdateProxyTopology(addresses []net.IP) (*common.TopologyConfig, error) {
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("proxy topology must contain at least one address")
+	}
+
+	p.lock.Lock()
+	oldTopologyConfig := p.TopologyConfig
+	if oldTopologyConfig == nil {
+		p.lock.Unlock()
+		return nil, fmt.Errorf("proxy topology can not be updated before the proxy is started")
+	}
+
+	localAddress := oldTopologyConfig.Addresses[oldTopologyConfig.Index]
+	newIndex := -1
+	for i, addr := range addresses {
+		if addr.Equal(localAddress) {
+			newIndex = i
+		}
+		for _, otherAddr := range addresses[:i] {
+			if addr.Equal(otherAddr) {
+				p.lock.Unlock()
+				return nil, fmt.Errorf("duplicate address in proxy topology: %v", addr)
+			}
+		}
+	}
+	if newIndex == -1 {
+		p.lock.Unlock()
+		return nil, fmt.Errorf("the address of this proxy instance (%v) must be part of the proxy topology", localAddress)
+	}
+
+	newTopologyConfig := &common.TopologyConfig{
+		VirtualizationEnabled: oldTopologyConfig.VirtualizationEnabled,
+		Addresses:             addresses,
+		Count:                 len(addresses),
+		Index:                 newIndex,
+		NumTokens:             oldTopologyConfig.NumTokens,
+	}
+	for _, controlConn := range []*ControlConn{p.originControlConn, p.targetControlConn} {
+		if controlConn == nil {
+			continue
+		}
+		if err := controlConn.UpdateTopologyConfig(newTopologyConfig); err != nil {
+			p.lock.Unlock()
+			return nil, fmt.Errorf("could not update %v proxy topology: %w", controlConn.connConfig.GetClusterType(), err)
+		}
+	}
+	p.TopologyConfig = newTopologyConfig
+	p.lock.Unlock()
+
+	addedAddresses := diffAddresses(addresses, oldTopologyConfig.Addresses)
+	removedAddresses := diffAddresses(oldTopologyConfig.Addresses, addresses)
+	log.Infof("Proxy topology updated from %v to %v (added: %v, removed: %v).",
+		oldTopologyConfig, newTopologyConfig, addedAddresses, removedAddresses)
+	p.events.Publish(ProxyEventTopologyChanged, "proxy topology changed to %v (added: %v, removed: %v)",
+		addresses, addedAddresses, removedAddresses)
+
+	if newTopologyConfig.VirtualizationEnabled {
+		p.clientHandlers.Range(func(_, value interface{}) bool {
+			clientHandler := value.(*ClientHandler)
+			for _, addr := range removedAddresses {
+				clientHandler.sendTopologyChangeEvent(primitive.TopologyChangeTypeRemovedNode, addr, p.Conf.ProxyListenPort)
+			}
+			for _, addr := range addedAddresses {
+				clientHandler.sendTopologyChangeEvent(primitive.TopologyChangeTypeNewNode, addr, p.Conf.ProxyListenPort)
+			}
+			return true
+		})
+	}
+	return newTopologyConfig, nil
+}
+
+// diffAddresses returns the addresses that are in a but not in b.
+func diffAddresses(a []net.IP, b []net.IP) []net.IP {
+	diff := make([]net.IP, 0)
+	for _, addrA := range a {
+		found := false
+		for _, addrB := range b {
+			if addrA.Equal(addrB) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			diff = append(diff, addrA)
+		}
+	}
+	return diff
+}
@@ -0,0 +1,91 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/stretchr/testify/require"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestUpdateProxyTopology(t *testing.T) {
+	newProxy := func() *ZdmProxy {
+		return &ZdmProxy{
+			Conf: &config.Config{ProxyListenPort: 14002},
+			TopologyConfig: &common.TopologyConfig{
+				VirtualizationEnabled: true,
+				Addresses:             []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")},
+				Count:                 2,
+				Index:                 1,
+				NumTokens:             8,
+			},
+			lock:           &sync.RWMutex{},
+			clientHandlers: &sync.Map{},
+		}
+	}
+	newClientHandler := func(registered bool) *ClientHandler {
+		ch := &ClientHandler{
+			proxyEventsChan:        make(chan *frame.RawFrame, proxyEventsChannelSize),
+			topologyEventsProtoVer: &atomic.Value{},
+		}
+		if registered {
+			ch.topologyEventsProtoVer.Store(primitive.ProtocolVersion4)
+		}
+		return ch
+	}
+
+	t.Run("scale out and in", func(t *testing.T) {
+		proxy := newProxy()
+		registeredHandler := newClientHandler(true)
+		unregisteredHandler := newClientHandler(false)
+		proxy.clientHandlers.Store("127.0.0.1:50001", registeredHandler)
+		proxy.clientHandlers.Store("127.0.0.1:50002", unregisteredHandler)
+
+		topologyConfig, err := proxy.UpdateProxyTopology(
+			[]net.IP{net.ParseIP("10.0.0.2"), net.ParseIP("10.0.0.3"), net.ParseIP("10.0.0.4")})
+		require.Nil(t, err)
+		require.Equal(t, 3, topologyConfig.Count)
+		require.Equal(t, 0, topologyConfig.Index)
+		require.Equal(t, 8, topologyConfig.NumTokens)
+		require.Same(t, topologyConfig, proxy.GetTopologyConfig())
+
+		require.Equal(t, []*message.TopologyChangeEvent{
+			{ChangeType: primitive.TopologyChangeTypeRemovedNode, Address: &primitive.Inet{Addr: net.ParseIP("10.0.0.1"), Port: 14002}},
+			{ChangeType: primitive.TopologyChangeTypeNewNode, Address: &primitive.Inet{Addr: net.ParseIP("10.0.0.3"), Port: 14002}},
+			{ChangeType: primitive.TopologyChangeTypeNewNode, Address: &primitive.Inet{Addr: net.ParseIP("10.0.0.4"), Port: 14002}},
+		}, receiveTopologyEvents(t, registeredHandler))
+		require.Empty(t, receiveTopologyEvents(t, unregisteredHandler))
+	})
+
+	t.Run("invalid topologies", func(t *testing.T) {
+		proxy := newProxy()
+		_, err := proxy.UpdateProxyTopology([]net.IP{})
+		require.NotNil(t, err)
+		_, err = proxy.UpdateProxyTopology([]net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.3")})
+		require.Contains(t, err.Error(), "must be part of the proxy topology")
+		_, err = proxy.UpdateProxyTopology([]net.IP{net.ParseIP("10.0.0.2"), net.ParseIP("10.0.0.2")})
+		require.Contains(t, err.Error(), "duplicate address")
+		require.Equal(t, 2, proxy.GetTopologyConfig().Count)
+	})
+}
+
+func receiveTopologyEvents(t *testing.T, ch *ClientHandler) []*message.TopologyChangeEvent {
+	events := make([]*message.TopologyChangeEvent, 0)
+	for {
+		select {
+		case rawEvent := <-ch.proxyEventsChan:
+			require.Equal(t, int16(-1), rawEvent.Header.StreamId)
+			body, err := defaultCodec.DecodeBody(rawEvent.Header, bytes.NewReader(rawEvent.Body))
+			require.Nil(t, err)
+			events = append(events, body.Message.(*message.TopologyChangeEvent))
+		default:
+			return events
+		}
+	}
+}
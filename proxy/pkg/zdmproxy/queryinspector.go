@@ -5,6 +5,7 @@ import (
 	"github.com/antlr/antlr4/runtime/Go/antlr"
 	parser "github.com/datastax/zdm-proxy/antlr"
 	log "github.com/sirupsen/logrus"
+	"strconv"
 	"strings"
 	"sync"
 )
@@ -89,6 +90,31 @@ type QueryInfo interface {
 	replaceNowFunctionCallsWithLiteral() (QueryInfo, []*term)
 	replaceNowFunctionCallsWithPositionalBindMarkers() (QueryInfo, []*term)
 	replaceNowFunctionCallsWithNamedBindMarkers() (QueryInfo, []*term)
+
+	// Returns the values of the USING TIMESTAMP clauses of the query that are literals, clauses with bind markers
+	// are not included.
+	// This will always be empty for statements other than INSERT, UPDATE, DELETE and BATCH.
+	getLiteralTimestamps() []int64
+
+	// Whether the query contains at least one table name that is not qualified with a keyspace.
+	// This will always be false for statements other than INSERT, UPDATE, DELETE, SELECT and BATCH.
+	hasUnqualifiedTableNames() bool
+
+	// Whether the query contains at least one conditional statement (lightweight transaction), i.e. an INSERT, UPDATE
+	// or DELETE with an IF clause.
+	// This will always be false for statements other than INSERT, UPDATE, DELETE and BATCH.
+	isConditional() bool
+
+	// Whether the query updates counter columns, i.e. it is a COUNTER BATCH or an UPDATE that increments or decrements
+	// a column by an integer literal. Increments with bind markers can only be detected with the variables metadata
+	// of the prepared statement.
+	// This will always be false for statements other than UPDATE and BATCH.
+	isCounterUpdate() bool
+
+	// Returns a copy of this query where every table name that is not qualified with a keyspace is prefixed with
+	// the "current" keyspace (getRequestKeyspace()). Returns the same object if there is nothing to qualify
+	// or if the current keyspace is not known.
+	qualifyTableNames() QueryInfo
 }
 
 func inspectCqlQuery(query string, currentKeyspace string, timeUuidGenerator TimeUuidGenerator) QueryInfo {
@@ -319,6 +345,18 @@ type cqlListener struct {
 	namedBindMarkers      bool
 	nowFunctionCalls      bool
 
+	// Start index (in the query string) of every table name that is not qualified with a keyspace
+	unqualifiedTableNameIndexes []int
+
+	// Values of the USING TIMESTAMP clauses that are literals (clauses with bind markers are not included)
+	literalTimestamps []int64
+
+	// Whether at least one statement has an IF clause
+	conditional bool
+
+	// Whether the statement is a COUNTER BATCH or at least one UPDATE increments a column by an integer literal
+	counterUpdate bool
+
 	// internal counters
 	currentPositionalIndex int
 	currentBatchChildIndex int
@@ -376,6 +414,22 @@ func (l *cqlListener) hasNowFunctionCalls() bool {
 	return l.nowFunctionCalls
 }
 
+func (l *cqlListener) getLiteralTimestamps() []int64 {
+	return l.literalTimestamps
+}
+
+func (l *cqlListener) hasUnqualifiedTableNames() bool {
+	return len(l.unqualifiedTableNameIndexes) > 0
+}
+
+func (l *cqlListener) isConditional() bool {
+	return l.conditional
+}
+
+func (l *cqlListener) isCounterUpdate() bool {
+	return l.counterUpdate
+}
+
 func (l *cqlListener) EnterCqlStatement(ctx *parser.CqlStatementContext) {
 	if ctx.GetChildCount() == 0 {
 		return
@@ -436,6 +490,9 @@ func (l *cqlListener) ExitSelectStatement(ctx *parser.SelectStatementContext) {
 
 func (l *cqlListener) EnterInsertStatement(ctx *parser.InsertStatementContext) {
 	parsedStmt := &parsedStatement{statementIndex: l.currentBatchChildIndex, statementType: statementTypeInsert}
+	if ctx.K_IF() != nil {
+		l.conditional = true
+	}
 	for _, childCtx := range ctx.GetChildren() {
 		switch childCtx.(type) {
 		case parser.ITermsContext:
@@ -451,6 +508,9 @@ func (l *cqlListener) EnterInsertStatement(ctx *parser.InsertStatementContext) {
 
 func (l *cqlListener) EnterUpdateStatement(ctx *parser.UpdateStatementContext) {
 	parsedStmt := &parsedStatement{statementIndex: l.currentBatchChildIndex, statementType: statementTypeUpdate}
+	if ctx.K_IF() != nil {
+		l.conditional = true
+	}
 
 	for _, childCtx := range ctx.GetChildren() {
 		switch childCtx.(type) {
@@ -458,6 +518,9 @@ func (l *cqlListener) EnterUpdateStatement(ctx *parser.UpdateStatementContext) {
 			parsedStmt.terms = append(parsedStmt.terms, l.extractUsingClauseBindMarkers(childCtx)...)
 		case parser.IUpdateOperationsContext:
 			for _, updateOperation := range childCtx.GetChildren() {
+				if isCounterUpdateOperation(updateOperation) {
+					l.counterUpdate = true
+				}
 				for _, termCtx := range updateOperation.GetChildren() {
 					typedTermCtx, ok := termCtx.(*parser.TermContext)
 					if ok {
@@ -480,6 +543,9 @@ func (l *cqlListener) EnterUpdateStatement(ctx *parser.UpdateStatementContext) {
 
 func (l *cqlListener) EnterDeleteStatement(ctx *parser.DeleteStatementContext) {
 	parsedStmt := &parsedStatement{statementIndex: l.currentBatchChildIndex, statementType: statementTypeDelete}
+	if ctx.K_IF() != nil {
+		l.conditional = true
+	}
 
 	for _, childCtx := range ctx.GetChildren() {
 		switch childCtx.(type) {
@@ -513,6 +579,9 @@ func (l *cqlListener) EnterDeleteStatement(ctx *parser.DeleteStatementContext) {
 }
 
 func (l *cqlListener) EnterBatchStatement(ctx *parser.BatchStatementContext) {
+	if ctx.K_COUNTER() != nil {
+		l.counterUpdate = true
+	}
 	usingClauseCtx := ctx.UsingClause()
 	if usingClauseCtx != nil {
 		// ignore terms, just process the clause to update the current positional marker position that is used in the actual child statements
@@ -520,6 +589,47 @@ func (l *cqlListener) EnterBatchStatement(ctx *parser.BatchStatementContext) {
 	}
 }
 
+// isCounterUpdateOperation returns true for the update operations "c = c + 1", "c = c - 1", "c += 1" and "c -= 1"
+// where the value is an integer literal, only counter columns can be updated like this.
+func isCounterUpdateOperation(updateOperation antlr.Tree) bool {
+	children := updateOperation.GetChildren()
+	if len(children) < 3 {
+		return false
+	}
+	operator, ok := children[len(children)-2].(antlr.TerminalNode)
+	if !ok {
+		return false
+	}
+	switch operator.GetText() {
+	case "+", "-", "+=", "-=":
+	default:
+		return false
+	}
+	termCtx, ok := children[len(children)-1].(*parser.TermContext)
+	if !ok {
+		return false
+	}
+	literalCtx, ok := termCtx.Literal().(*parser.LiteralContext)
+	if !ok {
+		return false
+	}
+	primitiveLiteralCtx, ok := literalCtx.PrimitiveLiteral().(*parser.PrimitiveLiteralContext)
+	return ok && primitiveLiteralCtx.INTEGER() != nil
+}
+
+func (l *cqlListener) EnterTimestamp(ctx *parser.TimestampContext) {
+	integer := ctx.INTEGER()
+	if integer == nil {
+		return
+	}
+	timestamp, err := strconv.ParseInt(integer.GetText(), 10, 64)
+	if err != nil {
+		log.Warnf("Proxy could not parse USING TIMESTAMP value %v: %v", integer.GetText(), err)
+		return
+	}
+	l.literalTimestamps = append(l.literalTimestamps, timestamp)
+}
+
 func (l *cqlListener) EnterUseStatement(ctx *parser.UseStatementContext) {
 	l.keyspaceName = extractIdentifier(ctx.KeyspaceName().(*parser.KeyspaceNameContext).Identifier().(*parser.IdentifierContext))
 }
@@ -530,6 +640,7 @@ func (l *cqlListener) EnterTableName(ctx *parser.TableNameContext) {
 	if qualifiedId.GetChildCount() == 1 {
 		identifierContext := qualifiedId.GetChild(0).(*parser.IdentifierContext)
 		l.tableName = extractIdentifier(identifierContext)
+		l.unqualifiedTableNameIndexes = append(l.unqualifiedTableNameIndexes, ctx.GetStart().GetStart())
 	} else {
 		// 3 children: keyspaceName, token DOT, identifier
 		keyspaceNameContext := qualifiedId.GetChild(0)
@@ -814,6 +925,8 @@ func (l *cqlListener) replaceFunctionCalls(replacementFunc func(query string, fu
 	previousPositionalIndex := 0
 	namedMarkers := false
 	positionalMarkers := false
+	newUnqualifiedTableNameIndexes := make([]int, len(l.unqualifiedTableNameIndexes))
+	copy(newUnqualifiedTableNameIndexes, l.unqualifiedTableNameIndexes)
 	for _, parsedStmt := range l.parsedStatements {
 		newParsedStmt := parsedStmt.ShallowClone()
 		newTerms := make([]*term, 0)
@@ -825,6 +938,12 @@ func (l *cqlListener) replaceFunctionCalls(replacementFunc func(query string, fu
 					replacedTerms = append(replacedTerms, t)
 					result = result + l.query[i:t.functionCall.startIndex] + replacement
 					i = t.functionCall.stopIndex + 1
+					delta := len(replacement) - (t.functionCall.stopIndex - t.functionCall.startIndex + 1)
+					for idx, tableNameIdx := range l.unqualifiedTableNameIndexes {
+						if tableNameIdx > t.functionCall.stopIndex {
+							newUnqualifiedTableNameIndexes[idx] += delta
+						}
+					}
 					switch rType {
 					case literalReplacement:
 						newTerm = NewLiteralTerm(replacement, t.previousPositionalIndex)
@@ -856,9 +975,43 @@ func (l *cqlListener) replaceFunctionCalls(replacementFunc func(query string, fu
 	newQueryInfo.parsedStatements = newParsedStatements
 	newQueryInfo.namedBindMarkers = namedMarkers
 	newQueryInfo.positionalBindMarkers = positionalMarkers
+	newQueryInfo.unqualifiedTableNameIndexes = newUnqualifiedTableNameIndexes
 	return newQueryInfo, replacedTerms
 }
 
+func (l *cqlListener) qualifyTableNames() QueryInfo {
+	if !l.hasUnqualifiedTableNames() || l.getRequestKeyspace() == "" {
+		return l
+	}
+
+	prefix := quoteIdentifier(l.getRequestKeyspace()) + "."
+	var sb strings.Builder
+	sb.Grow(len(l.query) + len(l.unqualifiedTableNameIndexes)*len(prefix))
+	i := 0
+	for _, tableNameIdx := range l.unqualifiedTableNameIndexes {
+		sb.WriteString(l.query[i:tableNameIdx])
+		sb.WriteString(prefix)
+		i = tableNameIdx
+	}
+	sb.WriteString(l.query[i:])
+
+	newQueryInfo := l.shallowClone()
+	newQueryInfo.query = sb.String()
+	if newQueryInfo.keyspaceName == "" {
+		newQueryInfo.keyspaceName = l.getRequestKeyspace()
+	}
+	newQueryInfo.unqualifiedTableNameIndexes = nil
+	// function call indexes are stale after this so the parsed statements can't be used for replacements anymore
+	newQueryInfo.parsedStatements = nil
+	newQueryInfo.nowFunctionCalls = false
+	return newQueryInfo
+}
+
+// quoteIdentifier returns the provided identifier (in its internal form) as a quoted CQL identifier.
+func quoteIdentifier(identifier string) string {
+	return "\"" + strings.ReplaceAll(identifier, "\"", "\"\"") + "\""
+}
+
 func (l *cqlListener) replaceNowFunctionCallsWithLiteral() (QueryInfo, []*term) {
 	return l.replaceFunctionCalls(func(query string, functionCall *functionCall) (string, replacementType) {
 		if functionCall.isNow() {
@@ -891,20 +1044,24 @@ func (l *cqlListener) replaceNowFunctionCallsWithNamedBindMarkers() (QueryInfo,
 
 func (l *cqlListener) shallowClone() *cqlListener {
 	return &cqlListener{
-		BaseSimplifiedCqlListener: l.BaseSimplifiedCqlListener,
-		query:                     l.query,
-		statementType:             l.statementType,
-		keyspaceName:              l.keyspaceName,
-		tableName:                 l.tableName,
-		parsedStatements:          l.parsedStatements,
-		positionalBindMarkers:     l.positionalBindMarkers,
-		namedBindMarkers:          l.namedBindMarkers,
-		nowFunctionCalls:          l.nowFunctionCalls,
-		currentPositionalIndex:    l.currentPositionalIndex,
-		currentBatchChildIndex:    l.currentBatchChildIndex,
-		timeUuidGenerator:         l.timeUuidGenerator,
-		requestKeyspace:           l.requestKeyspace,
-		parsedSelectClause:        l.parsedSelectClause,
+		BaseSimplifiedCqlListener:   l.BaseSimplifiedCqlListener,
+		query:                       l.query,
+		statementType:               l.statementType,
+		keyspaceName:                l.keyspaceName,
+		tableName:                   l.tableName,
+		parsedStatements:            l.parsedStatements,
+		positionalBindMarkers:       l.positionalBindMarkers,
+		namedBindMarkers:            l.namedBindMarkers,
+		nowFunctionCalls:            l.nowFunctionCalls,
+		unqualifiedTableNameIndexes: l.unqualifiedTableNameIndexes,
+		literalTimestamps:           l.literalTimestamps,
+		conditional:                 l.conditional,
+		counterUpdate:               l.counterUpdate,
+		currentPositionalIndex:      l.currentPositionalIndex,
+		currentBatchChildIndex:      l.currentBatchChildIndex,
+		timeUuidGenerator:           l.timeUuidGenerator,
+		requestKeyspace:             l.requestKeyspace,
+		parsedSelectClause:          l.parsedSelectClause,
 	}
 }
 
@@ -590,3 +590,124 @@ type fakeTimeUuidGenerator struct {
 func (recv *fakeTimeUuidGenerator) GetTimeUuid() uuid.UUID {
 	return recv.uid
 }
+
+func TestQualifyTableNames(t *testing.T) {
+	tests := []struct {
+		name            string
+		query           string
+		currentKeyspace string
+		replaceNow      bool
+		expected        string
+	}{
+		{"SELECT", "SELECT * FROM table1 WHERE foo = 1", "ks1", false, `SELECT * FROM "ks1".table1 WHERE foo = 1`},
+		{"qualified SELECT", "SELECT * FROM ks2.table1 WHERE foo = 1", "ks1", false, "SELECT * FROM ks2.table1 WHERE foo = 1"},
+		{"SELECT no keyspace", "SELECT * FROM table1 WHERE foo = 1", "", false, "SELECT * FROM table1 WHERE foo = 1"},
+		{"quoted keyspace", `SELECT * FROM "Table1"`, `My"Ks`, false, `SELECT * FROM "My""Ks"."Table1"`},
+		{"INSERT", "INSERT INTO table1 (a, b) VALUES (1, 2)", "ks1", false, `INSERT INTO "ks1".table1 (a, b) VALUES (1, 2)`},
+		{"UPDATE", "UPDATE table1 SET b = 2 WHERE a = 1", "ks1", false, `UPDATE "ks1".table1 SET b = 2 WHERE a = 1`},
+		{"DELETE", "DELETE FROM table1 WHERE a = 1", "ks1", false, `DELETE FROM "ks1".table1 WHERE a = 1`},
+		{"BATCH",
+			"BEGIN BATCH INSERT INTO table1 (a) VALUES (1); UPDATE ks2.table2 SET b = 2 WHERE a = 1; DELETE FROM table3 WHERE a = 1; APPLY BATCH",
+			"ks1", false,
+			`BEGIN BATCH INSERT INTO "ks1".table1 (a) VALUES (1); UPDATE ks2.table2 SET b = 2 WHERE a = 1; DELETE FROM "ks1".table3 WHERE a = 1; APPLY BATCH`},
+		{"BATCH after now() replacement",
+			"BEGIN BATCH INSERT INTO table1 (a, b) VALUES (now(), 1); DELETE FROM table3 WHERE a = now(); APPLY BATCH",
+			"ks1", true,
+			`BEGIN BATCH INSERT INTO "ks1".table1 (a, b) VALUES (?, 1); DELETE FROM "ks1".table3 WHERE a = ?; APPLY BATCH`},
+		{"USE", "USE ks2", "ks1", false, "USE ks2"},
+		{"CREATE TABLE", "CREATE TABLE table1 (a int PRIMARY KEY)", "ks1", false, "CREATE TABLE table1 (a int PRIMARY KEY)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeUuidGenerator, err := GetDefaultTimeUuidGenerator()
+			require.Nil(t, err)
+			queryInfo := inspectCqlQuery(tt.query, tt.currentKeyspace, timeUuidGenerator)
+			if tt.replaceNow {
+				queryInfo, _ = queryInfo.replaceNowFunctionCallsWithPositionalBindMarkers()
+			}
+			qualifiedQueryInfo := queryInfo.qualifyTableNames()
+			require.Equal(t, tt.expected, qualifiedQueryInfo.getQuery())
+			require.False(t, qualifiedQueryInfo.hasUnqualifiedTableNames() && tt.currentKeyspace != "")
+			if tt.expected == tt.query {
+				require.Same(t, queryInfo, qualifiedQueryInfo)
+			}
+		})
+	}
+}
+
+func TestLiteralTimestamps(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected []int64
+	}{
+		{"INSERT", "INSERT INTO ks1.table1 (a, b) VALUES (1, 2) USING TIMESTAMP 1000", []int64{1000}},
+		{"INSERT with TTL", "INSERT INTO ks1.table1 (a, b) VALUES (1, 2) USING TTL 10 AND TIMESTAMP 1000", []int64{1000}},
+		{"INSERT bind marker", "INSERT INTO ks1.table1 (a, b) VALUES (1, 2) USING TIMESTAMP ?", nil},
+		{"INSERT no timestamp", "INSERT INTO ks1.table1 (a, b) VALUES (1, 2)", nil},
+		{"UPDATE", "UPDATE ks1.table1 USING TIMESTAMP 1000 SET b = 2 WHERE a = 1", []int64{1000}},
+		{"DELETE", "DELETE FROM ks1.table1 USING TIMESTAMP 1000 WHERE a = 1", []int64{1000}},
+		{"BATCH",
+			"BEGIN BATCH USING TIMESTAMP 1000 INSERT INTO ks1.table1 (a) VALUES (1); " +
+				"UPDATE ks1.table1 USING TIMESTAMP 2000 SET b = 2 WHERE a = 1; APPLY BATCH",
+			[]int64{1000, 2000}},
+		{"SELECT", "SELECT * FROM ks1.table1 WHERE a = 1", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queryInfo := inspectCqlQuery(tt.query, "", &fakeTimeUuidGenerator{})
+			require.Equal(t, tt.expected, queryInfo.getLiteralTimestamps())
+		})
+	}
+}
+
+func TestConditional(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected bool
+	}{
+		{"INSERT", "INSERT INTO ks1.table1 (a, b) VALUES (1, 2)", false},
+		{"INSERT IF NOT EXISTS", "INSERT INTO ks1.table1 (a, b) VALUES (1, 2) IF NOT EXISTS", true},
+		{"UPDATE IF condition", "UPDATE ks1.table1 SET b = 2 WHERE a = 1 IF b = 1", true},
+		{"UPDATE IF EXISTS", "UPDATE ks1.table1 SET b = 2 WHERE a = 1 IF EXISTS", true},
+		{"DELETE IF condition", "DELETE FROM ks1.table1 WHERE a = 1 IF b = ?", true},
+		{"BATCH",
+			"BEGIN BATCH INSERT INTO ks1.table1 (a) VALUES (1); " +
+				"UPDATE ks1.table1 SET b = 2 WHERE a = 1 IF b = 1; APPLY BATCH",
+			true},
+		{"SELECT", "SELECT * FROM ks1.table1 WHERE a = 1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queryInfo := inspectCqlQuery(tt.query, "", &fakeTimeUuidGenerator{})
+			require.Equal(t, tt.expected, queryInfo.isConditional())
+		})
+	}
+}
+
+func TestCounterUpdate(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected bool
+	}{
+		{"increment", "UPDATE ks1.table1 SET c = c + 1 WHERE a = 1", true},
+		{"decrement", "UPDATE ks1.table1 SET b = 'x', c = c - 2 WHERE a = 1", true},
+		{"compound increment", "UPDATE ks1.table1 SET c += 1 WHERE a = 1", true},
+		{"bind marker", "UPDATE ks1.table1 SET c = c + ? WHERE a = 1", false},
+		{"list append", "UPDATE ks1.table1 SET l = l + [1] WHERE a = 1", false},
+		{"list prepend", "UPDATE ks1.table1 SET l = [1] + l WHERE a = 1", false},
+		{"assignment", "UPDATE ks1.table1 SET c = 1 WHERE a = 1", false},
+		{"COUNTER BATCH",
+			"BEGIN COUNTER BATCH UPDATE ks1.table1 SET c = c + ? WHERE a = 1; APPLY BATCH", true},
+		{"BATCH", "BEGIN BATCH INSERT INTO ks1.table1 (a) VALUES (1); APPLY BATCH", false},
+		{"INSERT", "INSERT INTO ks1.table1 (a, c) VALUES (1, 1)", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queryInfo := inspectCqlQuery(tt.query, "", &fakeTimeUuidGenerator{})
+			require.Equal(t, tt.expected, queryInfo.isCounterUpdate())
+		})
+	}
+}
@@ -163,3 +163,106 @@ func queryOrPrepareRequiresQueryReplacement(statementsQueryData []*statementQuer
 
 	return requiresQueryReplacement(statementsQueryData[0]), statementsQueryData[0], nil
 }
+
+// qualifyTableNames returns a copy of the provided request where every table name that is not qualified with a
+// keyspace is prefixed with the keyspace that applies to the request (set by a previous USE statement or by the
+// keyspace flag of the request). The same request is returned if no statement needs to be qualified.
+//
+// The statements query data must come from the inspection of a request with the same query strings as the provided one
+// (the request sent to a cluster may differ from the client request in other ways, e.g., prepared ids and values).
+func (recv *QueryModifier) qualifyTableNames(
+	request *frame.RawFrame, statementsQueryData []*statementQueryData) (*frame.RawFrame, error) {
+	qualifiedStatementsQueryData := make([]*statementQueryData, 0)
+	for _, stmtQueryData := range statementsQueryData {
+		qualifiedQueryData := stmtQueryData.queryData.qualifyTableNames()
+		if qualifiedQueryData != stmtQueryData.queryData {
+			qualifiedStatementsQueryData = append(
+				qualifiedStatementsQueryData,
+				&statementQueryData{statementIndex: stmtQueryData.statementIndex, queryData: qualifiedQueryData})
+		}
+	}
+
+	if len(qualifiedStatementsQueryData) == 0 {
+		return request, nil
+	}
+
+	decodedFrame, err := defaultCodec.ConvertFromRawFrame(request)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode request to qualify table names: %w", err)
+	}
+
+	switch typedMsg := decodedFrame.Body.Message.(type) {
+	case *message.Query:
+		typedMsg.Query = qualifiedStatementsQueryData[0].queryData.getQuery()
+	case *message.Prepare:
+		typedMsg.Query = qualifiedStatementsQueryData[0].queryData.getQuery()
+	case *message.Batch:
+		for _, stmtQueryData := range qualifiedStatementsQueryData {
+			if stmtQueryData.statementIndex >= len(typedMsg.Children) {
+				return nil, fmt.Errorf("statement index (%v) is greater or equal than "+
+					"number of batch child statements (%v)", stmtQueryData.statementIndex, len(typedMsg.Children))
+			}
+			typedMsg.Children[stmtQueryData.statementIndex].Query = stmtQueryData.queryData.getQuery()
+		}
+	default:
+		return nil, fmt.Errorf("could not qualify table names of %v request", decodedFrame.Header.OpCode.String())
+	}
+
+	newRawFrame, err := defaultCodec.ConvertToRawFrame(decodedFrame)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert qualified frame to raw frame: %w", err)
+	}
+	return newRawFrame, nil
+}
+
+// addKeyspaceToRequest returns a copy of the provided request with the keyspace flag set to the provided keyspace
+// so that the request doesn't depend on the USE state of the connection it is sent on. The keyspace flag is only
+// supported by QUERY, PREPARE and BATCH requests with protocol v5 and DSE v2.
+//
+// The same request is returned if the keyspace is empty, if the request already has a keyspace
+// or if the request doesn't support the keyspace flag.
+func (recv *QueryModifier) addKeyspaceToRequest(request *frame.RawFrame, keyspace string) (*frame.RawFrame, error) {
+	if keyspace == "" || !protocolSupportsKeyspaceInRequest(request.Header.Version) {
+		return request, nil
+	}
+
+	switch request.Header.OpCode {
+	case primitive.OpCodeQuery, primitive.OpCodePrepare, primitive.OpCodeBatch:
+	default:
+		return request, nil
+	}
+
+	decodedFrame, err := defaultCodec.ConvertFromRawFrame(request)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode request to add keyspace: %w", err)
+	}
+
+	switch typedMsg := decodedFrame.Body.Message.(type) {
+	case *message.Query:
+		if typedMsg.Options == nil {
+			typedMsg.Options = &message.QueryOptions{}
+		}
+		if typedMsg.Options.Keyspace != "" {
+			return request, nil
+		}
+		typedMsg.Options.Keyspace = keyspace
+	case *message.Prepare:
+		if typedMsg.Keyspace != "" {
+			return request, nil
+		}
+		typedMsg.Keyspace = keyspace
+	case *message.Batch:
+		if typedMsg.Keyspace != "" {
+			return request, nil
+		}
+		typedMsg.Keyspace = keyspace
+	default:
+		return request, nil
+	}
+
+	newRawFrame, err := defaultCodec.ConvertToRawFrame(decodedFrame)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert frame with keyspace to raw frame: %w", err)
+	}
+	return newRawFrame, nil
+}
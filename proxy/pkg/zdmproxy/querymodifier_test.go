@@ -239,6 +239,69 @@ func TestReplaceQueryString(t *testing.T) {
 
 }
 
+func TestReplaceQueryString_ProtocolV3(t *testing.T) {
+	serialConsistency := primitive.ConsistencyLevelLocalSerial
+	defaultTimestamp := int64(1000)
+	tests := []struct {
+		name string
+		msg  message.Message
+	}{
+		{"OpCodeQuery Options", &message.Query{
+			Query: "UPDATE blah SET a = now() WHERE b = 1",
+			Options: &message.QueryOptions{
+				Consistency:       primitive.ConsistencyLevelQuorum,
+				PageSize:          50,
+				PagingState:       []byte{0xca, 0xfe},
+				SerialConsistency: &serialConsistency,
+				DefaultTimestamp:  &defaultTimestamp,
+			},
+		}},
+		{"OpCodeBatch Flags", &message.Batch{
+			Type: primitive.BatchTypeUnlogged,
+			Children: []*message.BatchChild{
+				{Query: "INSERT INTO blah (a, b) VALUES (now(), 1)"},
+				{Query: "INSERT INTO blah (a, b) VALUES (?, 2)", Values: []*primitive.Value{primitive.NewValue([]byte{0x01})}},
+			},
+			Consistency:       primitive.ConsistencyLevelLocalQuorum,
+			SerialConsistency: &serialConsistency,
+			DefaultTimestamp:  &defaultTimestamp,
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := mockFrame(t, test.msg, primitive.ProtocolVersion3)
+			timeUuidGenerator, err := GetDefaultTimeUuidGenerator()
+			require.Nil(t, err)
+			queryModifier := NewQueryModifier(timeUuidGenerator)
+			newContext, statementsReplacedTerms, err := queryModifier.replaceQueryString("", NewFrameDecodeContext(f))
+			require.Nil(t, err)
+			require.Equal(t, 1, len(statementsReplacedTerms))
+			require.Equal(t, primitive.ProtocolVersion3, newContext.GetRawFrame().Header.Version)
+
+			newFrame, err := defaultCodec.ConvertFromRawFrame(newContext.GetRawFrame())
+			require.Nil(t, err)
+			switch originalMsg := test.msg.(type) {
+			case *message.Query:
+				newMsg, ok := newFrame.Body.Message.(*message.Query)
+				require.True(t, ok)
+				require.NotEqual(t, originalMsg.Query, newMsg.Query)
+				require.Equal(t, originalMsg.Options, newMsg.Options)
+			case *message.Batch:
+				newMsg, ok := newFrame.Body.Message.(*message.Batch)
+				require.True(t, ok)
+				require.Equal(t, originalMsg.Flags(), newMsg.Flags())
+				require.Equal(t, originalMsg.Type, newMsg.Type)
+				require.Equal(t, originalMsg.Consistency, newMsg.Consistency)
+				require.Equal(t, originalMsg.SerialConsistency, newMsg.SerialConsistency)
+				require.Equal(t, originalMsg.DefaultTimestamp, newMsg.DefaultTimestamp)
+				require.NotEqual(t, originalMsg.Children[0].Query, newMsg.Children[0].Query)
+				require.Equal(t, originalMsg.Children[1], newMsg.Children[1])
+			}
+		})
+	}
+}
+
 func contains(s []int, e int) bool {
 	for _, a := range s {
 		if a == e {
@@ -247,3 +310,106 @@ func contains(s []int, e int) bool {
 	}
 	return false
 }
+
+func TestQualifyTableNames_Frames(t *testing.T) {
+	tests := []struct {
+		name     string
+		f        *frame.RawFrame
+		expected []string
+	}{
+		{"OpCodeQuery", mockQueryFrame(t, "SELECT * FROM blah"), []string{`SELECT * FROM "ks1".blah`}},
+		{"OpCodeQuery qualified", mockQueryFrame(t, "SELECT * FROM ks2.blah"), nil},
+		{"OpCodePrepare", mockPrepareFrame(t, "INSERT INTO blah (a, b) VALUES (?, ?)"), []string{`INSERT INTO "ks1".blah (a, b) VALUES (?, ?)`}},
+		{"OpCodeBatch", mockBatchWithChildren(t, []*message.BatchChild{
+			{Query: "INSERT INTO blah (a, b) VALUES (1, 2)"},
+			{Id: []byte("PREPARED_ID")},
+			{Query: "DELETE FROM ks2.blah WHERE a = 1"},
+			{Query: "UPDATE blah2 SET b = 1 WHERE a = 1"},
+		}), []string{`INSERT INTO "ks1".blah (a, b) VALUES (1, 2)`, "", "DELETE FROM ks2.blah WHERE a = 1", `UPDATE "ks1".blah2 SET b = 1 WHERE a = 1`}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			timeUuidGenerator, err := GetDefaultTimeUuidGenerator()
+			require.Nil(t, err)
+			context := NewFrameDecodeContext(test.f)
+			statementsQueryData, err := context.GetOrInspectAllStatements("ks1", timeUuidGenerator)
+			require.Nil(t, err)
+			queryModifier := NewQueryModifier(timeUuidGenerator)
+			qualifiedFrame, err := queryModifier.qualifyTableNames(test.f, statementsQueryData)
+			require.Nil(t, err)
+			if test.expected == nil {
+				require.Same(t, test.f, qualifiedFrame)
+				return
+			}
+
+			require.Equal(t, test.f.Header.StreamId, qualifiedFrame.Header.StreamId)
+			decodedFrame, err := defaultCodec.ConvertFromRawFrame(qualifiedFrame)
+			require.Nil(t, err)
+			switch msg := decodedFrame.Body.Message.(type) {
+			case *message.Query:
+				require.Equal(t, test.expected[0], msg.Query)
+			case *message.Prepare:
+				require.Equal(t, test.expected[0], msg.Query)
+			case *message.Batch:
+				require.Equal(t, len(test.expected), len(msg.Children))
+				for idx, child := range msg.Children {
+					require.Equal(t, test.expected[idx], child.Query)
+				}
+				require.Equal(t, []byte("PREPARED_ID"), msg.Children[1].Id)
+			default:
+				require.Fail(t, "unexpected message type", msg)
+			}
+		})
+	}
+}
+
+func TestAddKeyspaceToRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		f        *frame.RawFrame
+		keyspace string
+		expected string
+	}{
+		{"OpCodeQuery DseV2", mockFrame(t, &message.Query{Query: "SELECT * FROM blah"}, primitive.ProtocolVersionDse2), "ks1", "ks1"},
+		{"OpCodeQuery DseV2 With Keyspace", mockFrame(t, &message.Query{Query: "SELECT * FROM blah", Options: &message.QueryOptions{Keyspace: "ks2"}}, primitive.ProtocolVersionDse2), "ks1", ""},
+		{"OpCodeQuery DseV2 No Keyspace", mockFrame(t, &message.Query{Query: "SELECT * FROM blah"}, primitive.ProtocolVersionDse2), "", ""},
+		{"OpCodeQuery V4", mockFrame(t, &message.Query{Query: "SELECT * FROM blah"}, primitive.ProtocolVersion4), "ks1", ""},
+		{"OpCodeQuery DseV1", mockFrame(t, &message.Query{Query: "SELECT * FROM blah"}, primitive.ProtocolVersionDse1), "ks1", ""},
+		{"OpCodePrepare DseV2", mockFrame(t, &message.Prepare{Query: "SELECT * FROM blah"}, primitive.ProtocolVersionDse2), "ks1", "ks1"},
+		{"OpCodePrepare DseV2 With Keyspace", mockPrepareFrameWithKeyspace(t, "SELECT * FROM blah", "ks2"), "ks1", ""},
+		{"OpCodeBatch DseV2", mockFrame(t, &message.Batch{Children: []*message.BatchChild{{Query: "INSERT INTO blah (a) VALUES (1)"}}}, primitive.ProtocolVersionDse2), "ks1", "ks1"},
+		{"OpCodeExecute DseV2", mockFrame(t, &message.Execute{QueryId: []byte("ID"), ResultMetadataId: []byte("ID")}, primitive.ProtocolVersionDse2), "ks1", ""},
+		{"OpCodeQuery V5", mockFrame(t, &message.Query{Query: "SELECT * FROM blah"}, primitive.ProtocolVersion5), "ks1", "ks1"},
+		{"OpCodePrepare V5", mockFrame(t, &message.Prepare{Query: "SELECT * FROM blah"}, primitive.ProtocolVersion5), "ks1", "ks1"},
+		{"OpCodeBatch V5", mockFrame(t, &message.Batch{Children: []*message.BatchChild{{Query: "INSERT INTO blah (a) VALUES (1)"}}}, primitive.ProtocolVersion5), "ks1", "ks1"},
+		{"OpCodeBatch V4", mockFrame(t, &message.Batch{Children: []*message.BatchChild{{Query: "INSERT INTO blah (a) VALUES (1)"}}}, primitive.ProtocolVersion4), "ks1", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			timeUuidGenerator, err := GetDefaultTimeUuidGenerator()
+			require.Nil(t, err)
+			queryModifier := NewQueryModifier(timeUuidGenerator)
+			newFrame, err := queryModifier.addKeyspaceToRequest(test.f, test.keyspace)
+			require.Nil(t, err)
+			if test.expected == "" {
+				require.Same(t, test.f, newFrame)
+				return
+			}
+
+			decodedFrame, err := defaultCodec.ConvertFromRawFrame(newFrame)
+			require.Nil(t, err)
+			switch msg := decodedFrame.Body.Message.(type) {
+			case *message.Query:
+				require.Equal(t, test.expected, msg.Options.Keyspace)
+			case *message.Prepare:
+				require.Equal(t, test.expected, msg.Keyspace)
+			case *message.Batch:
+				require.Equal(t, test.expected, msg.Keyspace)
+			default:
+				require.Fail(t, "unexpected message type", msg)
+			}
+		})
+	}
+}
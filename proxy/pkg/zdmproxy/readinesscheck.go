@@ -0,0 +1,318 @@
+package zdmproxy
+
+import (
+	"context"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"io"
+	"sort"
+	"strings"
+)
+
+type CheckStatus string
+
+const (
+	CheckStatusPass = CheckStatus("PASS")
+	CheckStatusWarn = CheckStatus("WARN")
+	CheckStatusFail = CheckStatus("FAIL")
+)
+
+type CheckResult struct {
+	Name    string
+	Status  CheckStatus
+	Message string
+}
+
+// ReadinessReport is the result of the pre-flight checks that are run before a migration (see RunReadinessCheck).
+// Checks with the FAIL status are blockers: the proxy would not start or client requests would fail.
+type ReadinessReport struct {
+	Results []*CheckResult
+}
+
+func (recv *ReadinessReport) add(name string, status CheckStatus, format string, args ...interface{}) {
+	recv.Results = append(recv.Results, &CheckResult{
+		Name:    name,
+		Status:  status,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (recv *ReadinessReport) HasBlockers() bool {
+	for _, result := range recv.Results {
+		if result.Status == CheckStatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+func (recv *ReadinessReport) Print(w io.Writer) {
+	_, _ = fmt.Fprintln(w, "ZDM proxy migration readiness report")
+	_, _ = fmt.Fprintln(w)
+	counts := map[CheckStatus]int{}
+	for _, result := range recv.Results {
+		counts[result.Status]++
+		_, _ = fmt.Fprintf(w, "[%v] %v: %v\n", result.Status, result.Name, result.Message)
+	}
+	_, _ = fmt.Fprintln(w)
+	verdict := "READY"
+	if recv.HasBlockers() {
+		verdict = "NOT READY"
+	}
+	_, _ = fmt.Fprintf(w, "Result: %v (%d passed, %d warnings, %d blockers)\n",
+		verdict, counts[CheckStatusPass], counts[CheckStatusWarn], counts[CheckStatusFail])
+}
+
+// tableSchema maps column names to their CQL types (empty if the type is not known).
+type tableSchema map[string]string
+
+type clusterCheckResult struct {
+	protocolVersion primitive.ProtocolVersion
+	clusterName     string
+	schema          map[string]tableSchema // keyed on keyspace.table
+	typedSchema     bool
+}
+
+// RunReadinessCheck validates the configuration and checks that the proxy can connect and authenticate to both
+// clusters, that the protocol versions are compatible and that every table of origin also exists in target with
+// the same columns. It doesn't start the proxy.
+func RunReadinessCheck(conf *config.Config, ctx context.Context) *ReadinessReport {
+	report := &ReadinessReport{Results: make([]*CheckResult, 0)}
+
+	err := conf.Validate()
+	if err != nil {
+		report.add("Configuration", CheckStatusFail, "%v", err)
+		return report
+	}
+	report.add("Configuration", CheckStatusPass, "configuration is valid")
+
+	if conf.OriginContactPoints != "" && conf.OriginContactPoints == conf.TargetContactPoints &&
+		conf.OriginPort == conf.TargetPort {
+		report.add("Configuration", CheckStatusWarn, "origin and target use the same contact points and port")
+	}
+
+	topologyConfig, err := conf.ParseTopologyConfig()
+	if err != nil {
+		report.add("Configuration", CheckStatusFail, "%v", err)
+		return report
+	}
+
+	origin := checkCluster(conf, topologyConfig, common.ClusterTypeOrigin, report, ctx)
+	target := checkCluster(conf, topologyConfig, common.ClusterTypeTarget, report, ctx)
+	if origin == nil || target == nil {
+		return report
+	}
+
+	if origin.clusterName != "" && origin.clusterName == target.clusterName {
+		report.add("Cluster identity", CheckStatusWarn,
+			"origin and target report the same cluster name (%v), make sure they are different clusters", origin.clusterName)
+	} else {
+		report.add("Cluster identity", CheckStatusPass, "origin is %v and target is %v", origin.clusterName, target.clusterName)
+	}
+
+	if origin.protocolVersion != target.protocolVersion {
+		report.add("Protocol version", CheckStatusWarn,
+			"origin negotiated %v and target negotiated %v, clients will be limited to %v",
+			origin.protocolVersion, target.protocolVersion, minProtoVer(origin.protocolVersion, target.protocolVersion))
+	} else {
+		report.add("Protocol version", CheckStatusPass, "both clusters negotiated %v", origin.protocolVersion)
+	}
+
+	checkSchemaParity(origin, target, report)
+	return report
+}
+
+func checkCluster(
+	conf *config.Config, topologyConfig *common.TopologyConfig, clusterType common.ClusterType,
+	report *ReadinessReport, ctx context.Context) *clusterCheckResult {
+
+	var contactPoints []string
+	var tlsConfig *common.ClusterTlsConfig
+	var dialConfig *common.ClusterDialConfig
+	var port, timeoutMs int
+	var datacenter, username, password string
+	var err error
+	if clusterType == common.ClusterTypeOrigin {
+		contactPoints, err = conf.ParseOriginContactPoints()
+		if err == nil {
+			tlsConfig, err = conf.ParseOriginTlsConfig(true)
+		}
+		if err == nil {
+			dialConfig, err = conf.ParseOriginDialConfig()
+		}
+		port, timeoutMs, datacenter = conf.OriginPort, conf.OriginConnectionTimeoutMs, conf.OriginLocalDatacenter
+		username, password = conf.OriginUsername, conf.OriginPassword
+	} else {
+		contactPoints, err = conf.ParseTargetContactPoints()
+		if err == nil {
+			tlsConfig, err = conf.ParseTargetTlsConfig(true)
+		}
+		if err == nil {
+			dialConfig, err = conf.ParseTargetDialConfig()
+		}
+		port, timeoutMs, datacenter = conf.TargetPort, conf.TargetConnectionTimeoutMs, conf.TargetLocalDatacenter
+		username, password = conf.TargetUsername, conf.TargetPassword
+	}
+
+	var connConfig ConnectionConfig
+	if err == nil {
+		connConfig, err = InitializeConnectionConfig(
+			tlsConfig, dialConfig, contactPoints, port, timeoutMs, clusterType, datacenter, ctx)
+	}
+	if err != nil {
+		report.add(fmt.Sprintf("%v connectivity", clusterType), CheckStatusFail, "%v", err)
+		return nil
+	}
+
+	controlConn := NewControlConn(ctx, port, connConfig, username, password, conf, topologyConfig, NewThreadSafeRand(), nil)
+
+	// connectivity
+	var reachableEndpoint Endpoint
+	connErrors := make([]string, 0)
+	for _, endpoint := range connConfig.GetContactPoints() {
+		tcpConn, _, err := openConnection(connConfig, endpoint, ctx, false)
+		if err != nil {
+			connErrors = append(connErrors, err.Error())
+			continue
+		}
+		_ = tcpConn.Close()
+		reachableEndpoint = endpoint
+		break
+	}
+	if reachableEndpoint == nil {
+		report.add(fmt.Sprintf("%v connectivity", clusterType), CheckStatusFail,
+			"could not connect to any contact point: %v", strings.Join(connErrors, "; "))
+		return nil
+	}
+	report.add(fmt.Sprintf("%v connectivity", clusterType), CheckStatusPass, "connected to %v", reachableEndpoint)
+
+	// authentication and protocol version negotiation
+	maxProtoVer, _ := conf.ParseControlConnMaxProtocolVersion()
+	conn, err := controlConn.connAndNegotiateProtoVer(reachableEndpoint, maxProtoVer, ctx)
+	if err != nil {
+		if conn != nil {
+			_ = conn.Close()
+		}
+		report.add(fmt.Sprintf("%v authentication", clusterType), CheckStatusFail, "handshake failed: %v", err)
+		return nil
+	}
+	defer conn.Close()
+	authEnabled, _ := conn.IsAuthEnabled()
+	if authEnabled {
+		report.add(fmt.Sprintf("%v authentication", clusterType), CheckStatusPass,
+			"authenticated as %v with %v", username, conn.GetProtocolVersion())
+	} else {
+		report.add(fmt.Sprintf("%v authentication", clusterType), CheckStatusPass,
+			"authentication is not enabled, connected with %v", conn.GetProtocolVersion())
+	}
+
+	// topology (local datacenter, partitioner)
+	hosts, err := controlConn.RefreshHosts(conn, ctx)
+	if err == nil && len(hosts) == 0 {
+		err = fmt.Errorf("no hosts found in the local datacenter")
+	}
+	if err != nil {
+		report.add(fmt.Sprintf("%v topology", clusterType), CheckStatusFail, "%v", err)
+		return nil
+	}
+	report.add(fmt.Sprintf("%v topology", clusterType), CheckStatusPass,
+		"%d node(s) in datacenter %v", len(hosts), hosts[0].Datacenter)
+
+	result := &clusterCheckResult{
+		protocolVersion: conn.GetProtocolVersion(),
+		clusterName:     controlConn.GetClusterName(),
+	}
+	result.schema, result.typedSchema, err = readSchema(conn, ctx)
+	if err != nil {
+		report.add(fmt.Sprintf("%v schema", clusterType), CheckStatusFail, "could not read schema: %v", err)
+		return nil
+	}
+	return result
+}
+
+func readSchema(conn CqlConnection, ctx context.Context) (map[string]tableSchema, bool, error) {
+	typed := true
+	rows, err := conn.Query(
+		"SELECT keyspace_name, table_name, column_name, type FROM system_schema.columns", GetDefaultGenericTypeCodec(), ctx)
+	if err != nil {
+		// Cassandra 2.x
+		typed = false
+		rows, err = conn.Query(
+			"SELECT keyspace_name, columnfamily_name, column_name FROM system.schema_columns", GetDefaultGenericTypeCodec(), ctx)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	schema := make(map[string]tableSchema)
+	for _, row := range rows.Rows {
+		values := make([]string, len(row.Values))
+		for i, value := range row.Values {
+			if str, ok := value.(*string); ok && str != nil {
+				values[i] = *str
+			} else if str, ok := value.(string); ok {
+				values[i] = str
+			}
+		}
+		if isInternalKeyspace(values[0]) {
+			continue
+		}
+		table := fmt.Sprintf("%v.%v", values[0], values[1])
+		if schema[table] == nil {
+			schema[table] = make(tableSchema)
+		}
+		columnType := ""
+		if typed {
+			columnType = values[3]
+		}
+		schema[table][values[2]] = columnType
+	}
+	return schema, typed, nil
+}
+
+func isInternalKeyspace(keyspace string) bool {
+	if strings.HasPrefix(keyspace, "system") || strings.HasPrefix(keyspace, "dse_") {
+		return true
+	}
+	switch keyspace {
+	case "solr_admin", "OpsCenter", "cfs", "cfs_archive", "dsefs", "HiveMetaStore":
+		return true
+	}
+	return false
+}
+
+func checkSchemaParity(origin *clusterCheckResult, target *clusterCheckResult, report *ReadinessReport) {
+	compareTypes := origin.typedSchema && target.typedSchema
+	problems := make([]string, 0)
+	for table, originColumns := range origin.schema {
+		targetColumns, exists := target.schema[table]
+		if !exists {
+			problems = append(problems, fmt.Sprintf("table %v is missing in target", table))
+			continue
+		}
+		for column, originType := range originColumns {
+			targetType, exists := targetColumns[column]
+			if !exists {
+				problems = append(problems, fmt.Sprintf("column %v.%v is missing in target", table, column))
+			} else if compareTypes && originType != targetType {
+				problems = append(problems, fmt.Sprintf(
+					"column %v.%v is %v in origin but %v in target", table, column, originType, targetType))
+			}
+		}
+	}
+	sort.Strings(problems)
+
+	if len(problems) > 0 {
+		report.add("Schema parity", CheckStatusFail, "%d problem(s): %v", len(problems), strings.Join(problems, "; "))
+		return
+	}
+	if !compareTypes {
+		report.add("Schema parity", CheckStatusPass,
+			"all %d origin table(s) exist in target with the same columns (column types were not compared)", len(origin.schema))
+		return
+	}
+	report.add("Schema parity", CheckStatusPass,
+		"all %d origin table(s) exist in target with the same columns and types", len(origin.schema))
+}
@@ -0,0 +1,122 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"hash/fnv"
+	"sync"
+)
+
+// ReadShift forwards a percentage of the reads to the target cluster while the primary cluster is ORIGIN so that the
+// read cutover can be ramped up (e.g. 1%, 10% and then 100%) while comparing the metrics of both clusters at each step,
+// instead of switching every read at once with ZDM_PRIMARY_CLUSTER.
+//
+// Whether a read is shifted depends on a hash of its statement or of its partition key (see ZDM_TARGET_READ_SHIFT_KEY)
+// so the same statement or partition is always read from the same cluster and keeps being read from target while the
+// percentage is increased. Per table and per client read routing take precedence over the percentage.
+type ReadShift struct {
+	lock       *sync.RWMutex
+	percentage int
+	key        common.ReadShiftKey
+	events     *EventBroadcaster
+}
+
+func NewReadShift(percentage int, key common.ReadShiftKey, events *EventBroadcaster) *ReadShift {
+	return &ReadShift{
+		lock:       &sync.RWMutex{},
+		percentage: percentage,
+		key:        key,
+		events:     events,
+	}
+}
+
+// GetPercentage returns the percentage of the reads that are forwarded to the target cluster.
+func (recv *ReadShift) GetPercentage() int {
+	if recv == nil {
+		return 0
+	}
+	recv.lock.RLock()
+	defer recv.lock.RUnlock()
+	return recv.percentage
+}
+
+func (recv *ReadShift) GetKey() common.ReadShiftKey {
+	if recv == nil {
+		return common.ReadShiftKeyStatement
+	}
+	return recv.key
+}
+
+// Set changes the percentage of the reads that are forwarded to the target cluster, the change applies to the reads
+// that are received after it (including reads of client connections that are already open).
+func (recv *ReadShift) Set(percentage int) error {
+	if percentage < 0 || percentage > 100 {
+		return fmt.Errorf("invalid percentage %v, it must be between 0 and 100", percentage)
+	}
+
+	recv.lock.Lock()
+	recv.percentage = percentage
+	recv.lock.Unlock()
+	recv.events.Publish(ProxyEventReadRoutingChanged, "%d%% of the reads shifted to %v", percentage, common.ClusterTypeTarget)
+	return nil
+}
+
+// getReadCluster returns the cluster that a read should be forwarded to. Reads are only shifted if they would otherwise
+// be forwarded to the primary cluster and the primary cluster is ORIGIN. The routing key is only computed when the
+// percentage is between 0 and 100.
+func (recv *ReadShift) getReadCluster(
+	readCluster common.ClusterType, primaryCluster common.ClusterType, routingKey func() []byte) common.ClusterType {
+	if primaryCluster != common.ClusterTypeOrigin || readCluster != primaryCluster {
+		return readCluster
+	}
+	percentage := recv.GetPercentage()
+	if percentage <= 0 {
+		return readCluster
+	}
+	if percentage >= 100 || readShiftBucket(routingKey()) < percentage {
+		return common.ClusterTypeTarget
+	}
+	return readCluster
+}
+
+// readShiftBucket maps a routing key to a number between 0 and 99.
+func readShiftBucket(routingKey []byte) int {
+	hash := fnv.New32a()
+	_, _ = hash.Write(routingKey)
+	return int(hash.Sum32() % 100)
+}
+
+// executeRoutingKey returns the routing key of an EXECUTE of a prepared read. The partition key is only available for
+// EXECUTE requests with positional values whose partition key columns are bound, every other read is shifted by
+// statement.
+func executeRoutingKey(key common.ReadShiftKey, preparedData PreparedData, executeMsg *message.Execute) []byte {
+	prepareRequestInfo := preparedData.GetPrepareRequestInfo()
+	if key == common.ReadShiftKeyPartition && len(prepareRequestInfo.GetReplacedTerms()) == 0 {
+		routingKey := partitionRoutingKey(
+			prepareRequestInfo.GetReadTable(), preparedData.GetOriginVariablesMetadata(), executeMsg.Options)
+		if routingKey != nil {
+			return routingKey
+		}
+	}
+	return []byte(prepareRequestInfo.GetQuery())
+}
+
+func partitionRoutingKey(table string, variablesMetadata *message.VariablesMetadata, options *message.QueryOptions) []byte {
+	if variablesMetadata == nil || len(variablesMetadata.PkIndices) == 0 || options == nil {
+		return nil
+	}
+	routingKey := []byte(table)
+	for _, pkIndex := range variablesMetadata.PkIndices {
+		if int(pkIndex) >= len(options.PositionalValues) {
+			return nil
+		}
+		value := options.PositionalValues[pkIndex]
+		if value == nil {
+			return nil
+		}
+		routingKey = append(routingKey, 0)
+		routingKey = append(routingKey, value.Contents...)
+	}
+	return routingKey
+}
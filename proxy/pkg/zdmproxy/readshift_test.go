@@ -0,0 +1,108 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+)
+
+func TestReadShift(t *testing.T) {
+	readShift := NewReadShift(0, common.ReadShiftKeyStatement, nil)
+	require.NotNil(t, readShift.Set(-1))
+	require.NotNil(t, readShift.Set(101))
+
+	countShifted := func(primaryCluster common.ClusterType, readCluster common.ClusterType) int {
+		shifted := 0
+		for i := 0; i < 1000; i++ {
+			routingKey := func() []byte { return []byte(strconv.Itoa(i)) }
+			if readShift.getReadCluster(readCluster, primaryCluster, routingKey) == common.ClusterTypeTarget {
+				shifted++
+			}
+		}
+		return shifted
+	}
+
+	require.Equal(t, 0, countShifted(common.ClusterTypeOrigin, common.ClusterTypeOrigin))
+	require.Nil(t, readShift.Set(10))
+	require.Equal(t, 10, readShift.GetPercentage())
+	require.InDelta(t, 100, countShifted(common.ClusterTypeOrigin, common.ClusterTypeOrigin), 40)
+	require.Nil(t, readShift.Set(100))
+	require.Equal(t, 1000, countShifted(common.ClusterTypeOrigin, common.ClusterTypeOrigin))
+
+	// the shift only applies to reads that would be forwarded to ORIGIN as the primary cluster
+	require.Equal(t, 1000, countShifted(common.ClusterTypeTarget, common.ClusterTypeTarget))
+	require.Nil(t, readShift.Set(50))
+	require.Equal(t, 1000, countShifted(common.ClusterTypeTarget, common.ClusterTypeTarget))
+	require.Equal(t, 0, countShifted(common.ClusterTypeTarget, common.ClusterTypeOrigin))
+
+	// a read that is shifted keeps being shifted while the percentage is increased
+	routingKey := func() []byte { return []byte("SELECT * FROM ks1.t1") }
+	shiftedAt := readShiftBucket(routingKey()) + 1
+	for percentage := 0; percentage <= 100; percentage++ {
+		require.Nil(t, readShift.Set(percentage))
+		expected := common.ClusterTypeOrigin
+		if percentage >= shiftedAt {
+			expected = common.ClusterTypeTarget
+		}
+		require.Equal(t, expected, readShift.getReadCluster(common.ClusterTypeOrigin, common.ClusterTypeOrigin, routingKey))
+	}
+
+	var nilReadShift *ReadShift
+	require.Equal(t, 0, nilReadShift.GetPercentage())
+	require.Equal(t, common.ReadShiftKeyStatement, nilReadShift.GetKey())
+	require.Equal(t, common.ClusterTypeOrigin, nilReadShift.getReadCluster(common.ClusterTypeOrigin, common.ClusterTypeOrigin, routingKey))
+}
+
+func TestReadShift_RequestInfo(t *testing.T) {
+	timeUuidGenerator, err := GetDefaultTimeUuidGenerator()
+	require.Nil(t, err)
+	psCache := NewPreparedStatementCache()
+	mh := newFakeMetricHandler()
+	routing := NewTableReadRouting(nil)
+	build := func(readShift *ReadShift, frameContext *frameDecodeContext) RequestInfo {
+		requestInfo, err := buildRequestInfo(frameContext, nil, psCache, mh, "ks1", common.ClusterTypeOrigin, common.ClusterTypeOrigin, routing, readShift,
+			false, true, false, timeUuidGenerator)
+		require.Nil(t, err)
+		return requestInfo
+	}
+
+	t.Run("statement", func(t *testing.T) {
+		readShift := NewReadShift(100, common.ReadShiftKeyStatement, nil)
+		requestInfo := build(readShift, NewFrameDecodeContext(mockQueryFrame(t, "SELECT * FROM ks1.t1")))
+		require.Equal(t, forwardToTarget, requestInfo.GetForwardDecision())
+		require.False(t, requestInfo.ShouldAlsoBeSentAsync())
+		require.Equal(t, forwardToBoth, build(readShift, NewFrameDecodeContext(mockQueryFrame(t, "INSERT INTO ks1.t1 (a) VALUES (1)"))).GetForwardDecision())
+
+		// table routing takes precedence over the percentage
+		require.Nil(t, routing.Set("ks1.t1", common.ClusterTypeOrigin))
+		defer routing.Remove("ks1.t1")
+		require.Equal(t, forwardToOrigin, build(readShift, NewFrameDecodeContext(mockQueryFrame(t, "SELECT * FROM ks1.t1"))).GetForwardDecision())
+	})
+
+	t.Run("partition", func(t *testing.T) {
+		readShift := NewReadShift(50, common.ReadShiftKeyPartition, nil)
+		prepareRequestInfo, ok := build(readShift, NewFrameDecodeContext(mockPrepareFrame(t, "SELECT * FROM ks1.t1 WHERE a = ?"))).(*PrepareRequestInfo)
+		require.True(t, ok)
+		preparedResult := &message.PreparedResult{
+			PreparedQueryId:   []byte("PARTITION"),
+			VariablesMetadata: &message.VariablesMetadata{PkIndices: []uint16{0}},
+		}
+		psCache.Store(preparedResult, preparedResult, prepareRequestInfo)
+
+		for i := 0; i < 100; i++ {
+			pk := []byte(strconv.Itoa(i))
+			executeFrame := mockFrame(t, &message.Execute{
+				QueryId: []byte("PARTITION"),
+				Options: &message.QueryOptions{PositionalValues: []*primitive.Value{primitive.NewValue(pk)}},
+			}, primitive.ProtocolVersion4)
+			expected := forwardToOrigin
+			if readShiftBucket(append([]byte("ks1.t1\x00"), pk...)) < 50 {
+				expected = forwardToTarget
+			}
+			require.Equal(t, expected, build(readShift, NewFrameDecodeContext(executeFrame)).GetForwardDecision())
+		}
+	})
+}
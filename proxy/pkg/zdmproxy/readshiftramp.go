@@ -0,0 +1,236 @@
+package zdmproxy
+
+import (
+	"context"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/crash"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type ReadShiftRampState string
+
+const (
+	ReadShiftRampStateNotStarted = ReadShiftRampState("NOT_STARTED")
+	ReadShiftRampStateRunning    = ReadShiftRampState("RUNNING")
+	ReadShiftRampStateCompleted  = ReadShiftRampState("COMPLETED")
+	ReadShiftRampStateHalted     = ReadShiftRampState("HALTED")
+)
+
+// ReadShiftRamp increases the percentage of the reads that are shifted to the target cluster (see ReadShift) one step
+// every interval. Before moving to the next step the ratio of the target reads that failed during the current step is
+// checked: if it is above the threshold the ramp is halted and the percentage is rolled back to the one that was set
+// before the ramp started. A step is extended until enough target reads have been received to evaluate it.
+//
+// The ramp is also halted (without a rollback) when the percentage is changed through the admin API or when target
+// misses more writes than ZDM_TARGET_READ_CUTOVER_MAX_MISSING_WRITES allows (see SingleSidedWrites).
+type ReadShiftRamp struct {
+	targetReads       uint64 // atomic, first field for 64-bit alignment
+	failedTargetReads uint64 // atomic
+	readShift         *ReadShift
+	singleSidedWrites *SingleSidedWrites
+	events            *EventBroadcaster
+	steps             []int
+	interval          time.Duration
+	minReads          int
+	maxErrorRatio     float64
+	lock              *sync.Mutex
+	state             ReadShiftRampState
+	step              int // index of the current step, -1 until the ramp starts
+	haltReason        string
+	rollbackTo        int
+	stepTargetReads   uint64
+	stepFailedReads   uint64
+}
+
+// ReadShiftRampStatus is a snapshot of the state of a ReadShiftRamp.
+type ReadShiftRampStatus struct {
+	State      ReadShiftRampState
+	Steps      []int
+	Step       int
+	HaltReason string
+}
+
+func NewReadShiftRamp(
+	readShift *ReadShift, steps []int, interval time.Duration, minReads int, maxErrorRatio float64,
+	singleSidedWrites *SingleSidedWrites, events *EventBroadcaster) *ReadShiftRamp {
+	return &ReadShiftRamp{
+		readShift:         readShift,
+		singleSidedWrites: singleSidedWrites,
+		events:            events,
+		steps:             steps,
+		interval:          interval,
+		minReads:          minReads,
+		maxErrorRatio:     maxErrorRatio,
+		lock:              &sync.Mutex{},
+		state:             ReadShiftRampStateNotStarted,
+		step:              -1,
+	}
+}
+
+// TrackTargetReadsGauge wraps the in flight target reads gauge so that every target read is also counted by this ramp.
+func (recv *ReadShiftRamp) TrackTargetReadsGauge(gauge metrics.Gauge) metrics.Gauge {
+	if recv == nil {
+		return gauge
+	}
+	return &readShiftRampGauge{Gauge: gauge, ramp: recv}
+}
+
+// TrackFailedTargetReadsCounter wraps the failed target reads counter so that every failed target read is also counted
+// by this ramp.
+func (recv *ReadShiftRamp) TrackFailedTargetReadsCounter(counter metrics.Counter) metrics.Counter {
+	if recv == nil {
+		return counter
+	}
+	return &readShiftRampCounter{Counter: counter, ramp: recv}
+}
+
+// Start moves to the first step and then to the next step every interval until the ramp completes, is halted or the
+// context is canceled.
+func (recv *ReadShiftRamp) Start(ctx context.Context) {
+	if recv == nil {
+		return
+	}
+	recv.lock.Lock()
+	recv.rollbackTo = recv.readShift.GetPercentage()
+	recv.state = ReadShiftRampStateRunning
+	recv.lock.Unlock()
+	if !recv.advance() {
+		return
+	}
+
+	go func() {
+		defer crash.HandlePanic()
+		ticker := time.NewTicker(recv.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if !recv.advance() {
+					return
+				}
+			}
+		}
+	}()
+}
+
+// Halt stops the ramp at the current percentage, it returns false if the ramp was not running.
+func (recv *ReadShiftRamp) Halt(reason string) bool {
+	if recv == nil {
+		return false
+	}
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if recv.state != ReadShiftRampStateRunning {
+		return false
+	}
+	recv.halt(reason)
+	return true
+}
+
+func (recv *ReadShiftRamp) GetStatus() *ReadShiftRampStatus {
+	if recv == nil {
+		return nil
+	}
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return &ReadShiftRampStatus{
+		State:      recv.state,
+		Steps:      append([]int{}, recv.steps...),
+		Step:       recv.step,
+		HaltReason: recv.haltReason,
+	}
+}
+
+// advance evaluates the current step and moves to the next one, it returns false once the ramp is no longer running.
+func (recv *ReadShiftRamp) advance() bool {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if recv.state != ReadShiftRampStateRunning {
+		return false
+	}
+
+	targetReads := atomic.LoadUint64(&recv.targetReads)
+	failedReads := atomic.LoadUint64(&recv.failedTargetReads)
+	if recv.step >= 0 {
+		stepReads := targetReads - recv.stepTargetReads
+		stepFailedReads := failedReads - recv.stepFailedReads
+		if stepReads < uint64(recv.minReads) {
+			log.Infof("Extending read shift ramp step %d%% because only %d target reads were received (%d required).",
+				recv.steps[recv.step], stepReads, recv.minReads)
+			return true
+		}
+		errorRatio := 0.0
+		if stepReads > 0 {
+			errorRatio = float64(stepFailedReads) / float64(stepReads)
+		}
+		if errorRatio > recv.maxErrorRatio {
+			err := recv.readShift.Set(recv.rollbackTo)
+			if err != nil {
+				log.Errorf("Could not roll back the read shift ramp to %d%%: %v.", recv.rollbackTo, err)
+			}
+			recv.halt(fmt.Sprintf("%d of %d target reads failed at %d%% (error ratio %.4f is above %v), rolled back to %d%%",
+				stepFailedReads, stepReads, recv.steps[recv.step], errorRatio, recv.maxErrorRatio, recv.rollbackTo))
+			return false
+		}
+	}
+
+	if recv.step+1 >= len(recv.steps) {
+		recv.state = ReadShiftRampStateCompleted
+		log.Infof("Read shift ramp completed, %d%% of the reads are shifted to %v.",
+			recv.readShift.GetPercentage(), common.ClusterTypeTarget)
+		recv.events.Publish(ProxyEventReadRoutingChanged, "read shift ramp completed")
+		return false
+	}
+
+	if missingWrites, ok := recv.singleSidedWrites.CheckReadsMovedTo(common.ClusterTypeTarget); !ok {
+		recv.halt(fmt.Sprintf("%v misses %d writes that were only written to %v",
+			common.ClusterTypeTarget, missingWrites, common.ClusterTypeOrigin))
+		return false
+	}
+
+	recv.step++
+	recv.stepTargetReads = targetReads
+	recv.stepFailedReads = failedReads
+	err := recv.readShift.Set(recv.steps[recv.step])
+	if err != nil {
+		recv.halt(fmt.Sprintf("could not set %d%%: %v", recv.steps[recv.step], err))
+		return false
+	}
+	log.Infof("Read shift ramp moved to step %d of %d: %d%% of the reads are shifted to %v.",
+		recv.step+1, len(recv.steps), recv.steps[recv.step], common.ClusterTypeTarget)
+	return true
+}
+
+func (recv *ReadShiftRamp) halt(reason string) {
+	recv.state = ReadShiftRampStateHalted
+	recv.haltReason = reason
+	log.Warnf("Read shift ramp halted: %v.", reason)
+	recv.events.Publish(ProxyEventReadRoutingChanged, "read shift ramp halted: %v", reason)
+}
+
+type readShiftRampGauge struct {
+	metrics.Gauge
+	ramp *ReadShiftRamp
+}
+
+func (recv *readShiftRampGauge) Add(valueToAdd int) {
+	atomic.AddUint64(&recv.ramp.targetReads, uint64(valueToAdd))
+	recv.Gauge.Add(valueToAdd)
+}
+
+type readShiftRampCounter struct {
+	metrics.Counter
+	ramp *ReadShiftRamp
+}
+
+func (recv *readShiftRampCounter) Add(valueToAdd int) {
+	atomic.AddUint64(&recv.ramp.failedTargetReads, uint64(valueToAdd))
+	recv.Counter.Add(valueToAdd)
+}
@@ -0,0 +1,107 @@
+package zdmproxy
+
+import (
+	"context"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestReadShiftRamp(t *testing.T) {
+	newRamp := func() (*ReadShift, *ReadShiftRamp) {
+		readShift := NewReadShift(0, common.ReadShiftKeyStatement, nil)
+		ramp := NewReadShiftRamp(readShift, []int{1, 10, 100}, time.Hour, 10, 0.1, nil, nil)
+		return readShift, ramp
+	}
+
+	t.Run("completes", func(t *testing.T) {
+		readShift, ramp := newRamp()
+		targetReads := ramp.TrackTargetReadsGauge(newFakeGauge())
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ramp.Start(ctx)
+		require.Equal(t, 1, readShift.GetPercentage())
+
+		// the step is extended until enough target reads are received
+		targetReads.Add(9)
+		require.True(t, ramp.advance())
+		require.Equal(t, 1, readShift.GetPercentage())
+		targetReads.Add(1)
+		require.True(t, ramp.advance())
+		require.Equal(t, 10, readShift.GetPercentage())
+
+		targetReads.Add(10)
+		require.True(t, ramp.advance())
+		require.Equal(t, 100, readShift.GetPercentage())
+		targetReads.Add(10)
+		require.False(t, ramp.advance())
+		require.Equal(t, 100, readShift.GetPercentage())
+		require.Equal(t, &ReadShiftRampStatus{State: ReadShiftRampStateCompleted, Steps: []int{1, 10, 100}, Step: 2}, ramp.GetStatus())
+	})
+
+	t.Run("rolls back when the error ratio is breached", func(t *testing.T) {
+		readShift, ramp := newRamp()
+		targetReads := ramp.TrackTargetReadsGauge(newFakeGauge())
+		failedTargetReads := ramp.TrackFailedTargetReadsCounter(newFakeCounter())
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ramp.Start(ctx)
+
+		targetReads.Add(100)
+		failedTargetReads.Add(10)
+		require.True(t, ramp.advance())
+		require.Equal(t, 10, readShift.GetPercentage())
+
+		targetReads.Add(100)
+		failedTargetReads.Add(11)
+		require.False(t, ramp.advance())
+		require.Equal(t, 0, readShift.GetPercentage())
+		status := ramp.GetStatus()
+		require.Equal(t, ReadShiftRampStateHalted, status.State)
+		require.Equal(t, 1, status.Step)
+		require.Contains(t, status.HaltReason, "11 of 100 target reads failed at 10%")
+	})
+
+	t.Run("halted manually", func(t *testing.T) {
+		readShift, ramp := newRamp()
+		require.False(t, ramp.Halt("not started"))
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ramp.Start(ctx)
+		require.Nil(t, readShift.Set(5))
+		require.True(t, ramp.Halt("percentage set through the admin API"))
+		require.False(t, ramp.Halt("already halted"))
+		require.False(t, ramp.advance())
+		require.Equal(t, 5, readShift.GetPercentage())
+		require.Equal(t, "percentage set through the admin API", ramp.GetStatus().HaltReason)
+	})
+
+	t.Run("halted when target misses writes", func(t *testing.T) {
+		readShift := NewReadShift(0, common.ReadShiftKeyStatement, nil)
+		singleSidedWrites := NewSingleSidedWrites(1)
+		ramp := NewReadShiftRamp(readShift, []int{1, 10, 100}, time.Hour, 10, 0.1, singleSidedWrites, nil)
+		targetReads := ramp.TrackTargetReadsGauge(newFakeGauge())
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ramp.Start(ctx)
+		require.Equal(t, 1, readShift.GetPercentage())
+
+		singleSidedWrites.add(common.ClusterTypeOrigin)
+		singleSidedWrites.add(common.ClusterTypeOrigin)
+		targetReads.Add(10)
+		require.False(t, ramp.advance())
+		require.Equal(t, 1, readShift.GetPercentage())
+		require.Equal(t, ReadShiftRampStateHalted, ramp.GetStatus().State)
+		require.Equal(t, "TARGET misses 2 writes that were only written to ORIGIN", ramp.GetStatus().HaltReason)
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		var ramp *ReadShiftRamp
+		gauge := newFakeGauge()
+		require.Equal(t, gauge, ramp.TrackTargetReadsGauge(gauge))
+		ramp.Start(context.Background())
+		require.False(t, ramp.Halt("not configured"))
+		require.Nil(t, ramp.GetStatus())
+	})
+}
@@ -90,6 +90,7 @@ type RequestContext interface {
 }
 
 type requestContextImpl struct {
+	id                    RequestId
 	request               *frame.RawFrame
 	requestInfo           RequestInfo
 	originResponse        *frame.RawFrame
@@ -99,10 +100,25 @@ type requestContextImpl struct {
 	lock                  *sync.Mutex
 	startTime             time.Time
 	customResponseChannel chan *customResponse
+	sample                *RequestSample    // nil if the request is not sampled
+	recording             *RecordedExchange // nil if the request is not recorded
+	monitoring            bool              // read of a monitoring connection, see ClientHandler.trackMonitoringConnection
+	conditional           bool              // conditional write sent to both clusters, see ZDM_LWT_MODE
+
+	// only set for writes that can be retried on the secondary cluster, see SecondaryWriteRetry
+	secondaryRequest *frame.RawFrame
+	secondaryCluster common.ClusterType
+	secondaryRetries int
+	secondaryFailure *frame.RawFrame // failed response of the secondary cluster waiting for the primary response
+
+	// only set for writes forwarded to the primary cluster only, see ClientHandler.trackPrimaryOnlyWrite
+	primaryOnlyWrite       string // why the write is not sent to both clusters
+	primaryOnlyConditional bool
 }
 
 func NewRequestContext(req *frame.RawFrame, requestInfo RequestInfo, startTime time.Time, customResponseChannel chan *customResponse) *requestContextImpl {
 	return &requestContextImpl{
+		id:                    nextRequestId(),
 		request:               req,
 		requestInfo:           requestInfo,
 		originResponse:        nil,
@@ -213,6 +229,60 @@ func (recv *requestContextImpl) SetResponse(nodeMetrics *metrics.NodeMetrics, f
 	return finished
 }
 
+// checkSecondaryWriteRetry is called before SetResponse for the requests with a secondaryRequest. Returns the number
+// of the retry (starting at 1) if the secondary request has to be sent again, and whether the response was consumed in
+// which case SetResponse must not be called: a retryable failure of the secondary cluster is held until the primary
+// response is received and it is discarded if the write is retried. If the write failed on both clusters, the held
+// failure is returned and it must be set with SetResponse before the primary response.
+func (recv *requestContextImpl) checkSecondaryWriteRetry(
+	f *frame.RawFrame, cluster common.ClusterType, retryPolicy *SecondaryWriteRetry) (
+	retry int, consumed bool, secondaryFailure *frame.RawFrame) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	if recv.state != RequestPending || recv.secondaryRequest == nil {
+		return 0, false, nil
+	}
+
+	primaryResponse := recv.originResponse
+	if recv.secondaryCluster == common.ClusterTypeOrigin {
+		primaryResponse = recv.targetResponse
+	}
+
+	if cluster == recv.secondaryCluster {
+		if recv.secondaryRetries >= retryPolicy.attempts || !retryPolicy.isRetryable(f) {
+			return 0, false, nil
+		}
+		if primaryResponse == nil {
+			recv.secondaryFailure = f
+			return 0, true, nil
+		}
+		if !isResponseSuccessful(primaryResponse) {
+			return 0, false, nil
+		}
+		recv.secondaryRetries++
+		return recv.secondaryRetries, true, nil
+	}
+
+	if recv.secondaryFailure == nil {
+		return 0, false, nil
+	}
+	secondaryFailure = recv.secondaryFailure
+	recv.secondaryFailure = nil
+	if isResponseSuccessful(f) {
+		recv.secondaryRetries++
+		return recv.secondaryRetries, false, nil
+	}
+	// the write failed on both clusters, the held failure completes the request with the primary response
+	return 0, false, secondaryFailure
+}
+
+func (recv *requestContextImpl) isPending() bool {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return recv.state == RequestPending
+}
+
 func isWriteStatement(req RequestInfo) bool {
 	return req.GetForwardDecision() == forwardToBoth
 }
@@ -0,0 +1,50 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"sync/atomic"
+)
+
+// RequestId identifies a request forwarded by the proxy so that it can be followed across the request samples, the
+// traffic recording, the logs and (with ZDM_PROXY_REQUEST_ID_PAYLOAD_KEY) the custom payload sent to the clusters.
+// Ids are unique per proxy process: a random prefix chosen at startup followed by a counter.
+type RequestId uint64
+
+var requestIdPrefix = fmt.Sprintf("%08x", NewThreadSafeRand().Uint32())
+
+var lastRequestId uint64 // atomic
+
+func nextRequestId() RequestId {
+	return RequestId(atomic.AddUint64(&lastRequestId, 1))
+}
+
+func (recv RequestId) String() string {
+	return fmt.Sprintf("%s-%x", requestIdPrefix, uint64(recv))
+}
+
+// addRequestIdPayload returns a copy of the provided request with the request id added to its custom payload under the
+// provided key. Requests that can't have a custom payload (protocol versions older than v4 or requests other than
+// QUERY, PREPARE, EXECUTE and BATCH) are returned as is.
+func addRequestIdPayload(request *frame.RawFrame, key string, id RequestId) (*frame.RawFrame, error) {
+	if request.Header.Version < primitive.ProtocolVersion4 {
+		return request, nil
+	}
+	switch request.Header.OpCode {
+	case primitive.OpCodeQuery, primitive.OpCodePrepare, primitive.OpCodeExecute, primitive.OpCodeBatch:
+	default:
+		return request, nil
+	}
+	decodedRequest, err := defaultCodec.ConvertFromRawFrame(request)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode request to add the request id to its custom payload: %w", err)
+	}
+	customPayload := make(map[string][]byte, len(decodedRequest.Body.CustomPayload)+1)
+	for k, v := range decodedRequest.Body.CustomPayload {
+		customPayload[k] = v
+	}
+	customPayload[key] = []byte(id.String())
+	decodedRequest.SetCustomPayload(customPayload)
+	return defaultCodec.ConvertToRawFrame(decodedRequest)
+}
@@ -0,0 +1,42 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestRequestId(t *testing.T) {
+	first := nextRequestId()
+	second := nextRequestId()
+	require.NotEqual(t, first.String(), second.String())
+	require.True(t, strings.HasPrefix(first.String(), requestIdPrefix+"-"))
+}
+
+func TestAddRequestIdPayload(t *testing.T) {
+	id := nextRequestId()
+
+	query := frame.NewFrame(primitive.ProtocolVersion4, 1, &message.Query{Query: "SELECT * FROM ks.tb"})
+	query.SetCustomPayload(map[string][]byte{"existing": []byte("value")})
+	request, err := addRequestIdPayload(mustEncodeRawFrame(t, query), "request-id", id)
+	require.Nil(t, err)
+	decoded, err := defaultCodec.ConvertFromRawFrame(request)
+	require.Nil(t, err)
+	require.Equal(t, map[string][]byte{"existing": []byte("value"), "request-id": []byte(id.String())},
+		decoded.Body.CustomPayload)
+	require.Equal(t, "SELECT * FROM ks.tb", decoded.Body.Message.(*message.Query).Query)
+
+	// protocol v3 doesn't support custom payloads
+	v3Query := mustEncodeRawFrame(t, frame.NewFrame(primitive.ProtocolVersion3, 1, &message.Query{Query: "SELECT * FROM ks.tb"}))
+	request, err = addRequestIdPayload(v3Query, "request-id", id)
+	require.Nil(t, err)
+	require.Same(t, v3Query, request)
+
+	options := mustEncodeRawFrame(t, frame.NewFrame(primitive.ProtocolVersion4, 1, &message.Options{}))
+	request, err = addRequestIdPayload(options, "request-id", id)
+	require.Nil(t, err)
+	require.Same(t, options, request)
+}
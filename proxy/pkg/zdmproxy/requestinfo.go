@@ -49,6 +49,11 @@ type PrepareRequestInfo struct {
 	containsPositionalMarkers bool
 	query                     string
 	keyspace                  string
+	readTable                 string
+	writeTable                string
+	literalTimestamps         []int64
+	conditional               bool
+	counterUpdate             bool
 }
 
 func NewPrepareRequestInfo(
@@ -65,6 +70,31 @@ func NewPrepareRequestInfo(
 		keyspace:                  keyspace}
 }
 
+func (recv *PrepareRequestInfo) withReadTable(readTable string) *PrepareRequestInfo {
+	recv.readTable = readTable
+	return recv
+}
+
+func (recv *PrepareRequestInfo) withWriteTable(writeTable string) *PrepareRequestInfo {
+	recv.writeTable = writeTable
+	return recv
+}
+
+func (recv *PrepareRequestInfo) withLiteralTimestamps(literalTimestamps []int64) *PrepareRequestInfo {
+	recv.literalTimestamps = literalTimestamps
+	return recv
+}
+
+func (recv *PrepareRequestInfo) withConditional() *PrepareRequestInfo {
+	recv.conditional = true
+	return recv
+}
+
+func (recv *PrepareRequestInfo) withCounterUpdate() *PrepareRequestInfo {
+	recv.counterUpdate = true
+	return recv
+}
+
 func (recv *PrepareRequestInfo) String() string {
 	return fmt.Sprintf("PrepareRequestInfo{baseRequestInfo: %v, query: %v, keyspace: %v}",
 		recv.baseRequestInfo, recv.query, recv.keyspace)
@@ -93,6 +123,34 @@ func (recv *PrepareRequestInfo) GetForwardDecision() forwardDecision {
 	return forwardToBoth // always send PREPARE to both, use origin's ID
 }
 
+// GetReadTable returns the table (keyspace.table) read by the prepared statement, empty if the statement is not
+// forwarded according to the read routing.
+func (recv *PrepareRequestInfo) GetReadTable() string {
+	return recv.readTable
+}
+
+// GetWriteTable returns the table (keyspace.table) that the prepared INSERT, UPDATE or DELETE writes to, it is empty
+// for other statements and for writes to system tables.
+func (recv *PrepareRequestInfo) GetWriteTable() string {
+	return recv.writeTable
+}
+
+// GetLiteralTimestamps returns the USING TIMESTAMP values of the prepared statement that are literals.
+func (recv *PrepareRequestInfo) GetLiteralTimestamps() []int64 {
+	return recv.literalTimestamps
+}
+
+// IsConditional returns true if the prepared statement is a conditional write (lightweight transaction).
+func (recv *PrepareRequestInfo) IsConditional() bool {
+	return recv.conditional
+}
+
+// IsCounterUpdate returns true if the prepared statement increments or decrements a counter column by an integer
+// literal, see PreparedData for the increments with bind markers.
+func (recv *PrepareRequestInfo) IsCounterUpdate() bool {
+	return recv.counterUpdate
+}
+
 func (recv *PrepareRequestInfo) GetBaseRequestInfo() RequestInfo {
 	return recv.baseRequestInfo
 }
@@ -107,17 +165,31 @@ func (recv *PrepareRequestInfo) ContainsPositionalMarkers() bool {
 
 type ExecuteRequestInfo struct {
 	preparedData PreparedData
+
+	// set if the forward decision of the prepared statement is overridden for this EXECUTE: reads whose forward
+	// decision depends on the current TableReadRouting and conditional writes sent to the primary cluster only
+	forwardDecision forwardDecision
+	sendAlsoToAsync bool
 }
 
 func NewExecuteRequestInfo(preparedData PreparedData) *ExecuteRequestInfo {
 	return &ExecuteRequestInfo{preparedData: preparedData}
 }
 
+func (recv *ExecuteRequestInfo) withForwardDecision(decision forwardDecision, sendAlsoToAsync bool) *ExecuteRequestInfo {
+	recv.forwardDecision = decision
+	recv.sendAlsoToAsync = sendAlsoToAsync
+	return recv
+}
+
 func (recv *ExecuteRequestInfo) String() string {
 	return fmt.Sprintf("ExecuteRequestInfo{PreparedData: %v}", recv.preparedData)
 }
 
 func (recv *ExecuteRequestInfo) GetForwardDecision() forwardDecision {
+	if recv.forwardDecision != "" {
+		return recv.forwardDecision
+	}
 	return recv.preparedData.GetPrepareRequestInfo().GetBaseRequestInfo().GetForwardDecision()
 }
 
@@ -126,6 +198,9 @@ func (recv *ExecuteRequestInfo) GetPreparedData() PreparedData {
 }
 
 func (recv *ExecuteRequestInfo) ShouldAlsoBeSentAsync() bool {
+	if recv.forwardDecision != "" {
+		return recv.sendAlsoToAsync
+	}
 	return recv.preparedData.GetPrepareRequestInfo().GetBaseRequestInfo().ShouldAlsoBeSentAsync()
 }
 
@@ -165,17 +240,28 @@ func (recv *InterceptedRequestInfo) GetParsedSelectClause() *selectClause {
 
 type BatchRequestInfo struct {
 	preparedDataByStmtIdx map[int]PreparedData
+
+	// set if the BATCH is a conditional write sent to the primary cluster only
+	forwardDecision forwardDecision
 }
 
 func NewBatchRequestInfo(preparedDataByStmtIdx map[int]PreparedData) *BatchRequestInfo {
 	return &BatchRequestInfo{preparedDataByStmtIdx: preparedDataByStmtIdx}
 }
 
+func (recv *BatchRequestInfo) withForwardDecision(decision forwardDecision) *BatchRequestInfo {
+	recv.forwardDecision = decision
+	return recv
+}
+
 func (recv *BatchRequestInfo) String() string {
 	return fmt.Sprintf("BatchRequestInfo{PreparedDataByStmtIdx: %v}", recv.preparedDataByStmtIdx)
 }
 
 func (recv *BatchRequestInfo) GetForwardDecision() forwardDecision {
+	if recv.forwardDecision != "" {
+		return recv.forwardDecision
+	}
 	return forwardToBoth // always send BATCH to both, use origin's prepared IDs
 }
 
@@ -0,0 +1,227 @@
+package zdmproxy
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/crash"
+	log "github.com/sirupsen/logrus"
+	"hash/fnv"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	requestSamplerQueueSize = 1024
+
+	requestOutcomeSuccess = "SUCCESS"
+	requestOutcomeTimeout = "TIMEOUT"
+)
+
+var requestSampleCsvHeader = []string{
+	"timestamp", "opcode", "forward_decision", "statement_hash", "table", "latency_ms", "origin_outcome", "target_outcome",
+	"request_id"}
+
+// RequestSample contains the metadata of a sampled request, the statement itself and its values are not recorded.
+type RequestSample struct {
+	Timestamp       time.Time
+	OpCode          primitive.OpCode
+	ForwardDecision forwardDecision
+	// hash of the CQL statement (QUERY or prepared statement of an EXECUTE) so that requests of the same statement can
+	// be grouped without recording the statement
+	StatementHash string
+	Table         string
+	Latency       time.Duration
+	// SUCCESS, TIMEOUT or the error code returned by the cluster, empty if the request was not sent to the cluster
+	OriginOutcome string
+	TargetOutcome string
+	RequestId     string
+}
+
+// RequestSampler copies the metadata of a fraction of the requests (reads and writes) to a CSV file for offline
+// workload analysis, e.g. capacity planning of the target cluster. Samples are written in the background and dropped
+// if the writer doesn't keep up so that sampling never slows down requests.
+type RequestSampler struct {
+	rate    float64
+	rand    *rand.Rand
+	file    *os.File
+	writer  *csv.Writer
+	samples chan *RequestSample
+	dropped uint64
+	done    chan bool
+	once    *sync.Once
+}
+
+// NewRequestSampler returns nil if the rate is 0, samples are appended to the provided file.
+func NewRequestSampler(rate float64, fileName string) (*RequestSampler, error) {
+	if rate <= 0 {
+		return nil, nil
+	}
+
+	file, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open request sampling file %v: %w", fileName, err)
+	}
+	sampler := &RequestSampler{
+		rate:    rate,
+		rand:    NewThreadSafeRand(),
+		file:    file,
+		writer:  csv.NewWriter(file),
+		samples: make(chan *RequestSample, requestSamplerQueueSize),
+		done:    make(chan bool),
+		once:    &sync.Once{},
+	}
+	if info, err := file.Stat(); err == nil && info.Size() == 0 {
+		_ = sampler.writer.Write(requestSampleCsvHeader)
+	}
+	go sampler.run()
+	log.Infof("Sampling %v%% of the requests to %v.", rate*100, fileName)
+	return sampler, nil
+}
+
+// ShouldSample returns true if the next request should be sampled, it always returns false if the sampler is nil.
+func (recv *RequestSampler) ShouldSample() bool {
+	return recv != nil && recv.rand.Float64() < recv.rate
+}
+
+// Record queues the sample to be written, the sample is dropped if the queue is full.
+func (recv *RequestSampler) Record(sample *RequestSample) {
+	select {
+	case recv.samples <- sample:
+	default:
+		atomic.AddUint64(&recv.dropped, 1)
+	}
+}
+
+// Close writes the queued samples and closes the file. Must only be called once no more samples are recorded.
+func (recv *RequestSampler) Close() {
+	if recv == nil {
+		return
+	}
+	recv.once.Do(func() {
+		close(recv.samples)
+		<-recv.done
+		if dropped := atomic.LoadUint64(&recv.dropped); dropped > 0 {
+			log.Warnf("%d request samples were dropped because they couldn't be written fast enough.", dropped)
+		}
+		if err := recv.file.Close(); err != nil {
+			log.Warnf("Could not close request sampling file: %v", err)
+		}
+	})
+}
+
+func (recv *RequestSampler) run() {
+	defer close(recv.done)
+	defer crash.HandlePanic()
+	for sample := range recv.samples {
+		err := recv.writer.Write([]string{
+			sample.Timestamp.UTC().Format(time.RFC3339Nano),
+			protocolName(sample.OpCode),
+			string(sample.ForwardDecision),
+			sample.StatementHash,
+			sample.Table,
+			strconv.FormatFloat(float64(sample.Latency)/float64(time.Millisecond), 'f', 3, 64),
+			sample.OriginOutcome,
+			sample.TargetOutcome,
+			sample.RequestId,
+		})
+		if err == nil && len(recv.samples) == 0 {
+			recv.writer.Flush()
+			err = recv.writer.Error()
+		}
+		if err != nil {
+			log.Warnf("Could not write request sample: %v", err)
+		}
+	}
+	recv.writer.Flush()
+}
+
+// protocolName returns the short name of protocol constants, e.g. "QUERY" instead of "OpCode QUERY [0x07]".
+func protocolName(value fmt.Stringer) string {
+	if fields := strings.Fields(value.String()); len(fields) == 3 {
+		return fields[1]
+	}
+	return value.String()
+}
+
+func hashStatement(statement string) string {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(statement))
+	return strconv.FormatUint(hash.Sum64(), 16)
+}
+
+// newRequestSample returns the sample of a request that was just received, it is completed once the request is done.
+func newRequestSample(
+	frameContext *frameDecodeContext, requestInfo RequestInfo, currentKeyspace string,
+	timeUuidGenerator TimeUuidGenerator, startTime time.Time) *RequestSample {
+	sample := &RequestSample{
+		Timestamp:       startTime,
+		OpCode:          frameContext.GetRawFrame().Header.OpCode,
+		ForwardDecision: requestInfo.GetForwardDecision(),
+	}
+
+	var queryInfo QueryInfo
+	switch typedRequestInfo := requestInfo.(type) {
+	case *ExecuteRequestInfo:
+		prepareRequestInfo := typedRequestInfo.GetPreparedData().GetPrepareRequestInfo()
+		keyspace := prepareRequestInfo.GetKeyspace()
+		if keyspace == "" {
+			keyspace = currentKeyspace
+		}
+		queryInfo = inspectCqlQuery(prepareRequestInfo.GetQuery(), keyspace, timeUuidGenerator)
+	default:
+		if sample.OpCode == primitive.OpCodeQuery {
+			if stmtQueryData, err := frameContext.GetOrInspectStatement(currentKeyspace, timeUuidGenerator); err == nil {
+				queryInfo = stmtQueryData.queryData
+			}
+		}
+	}
+	if queryInfo != nil {
+		sample.StatementHash = hashStatement(queryInfo.getQuery())
+		if queryInfo.getTableName() != "" {
+			sample.Table = qualifiedTableName(queryInfo.getApplicableKeyspace(), queryInfo.getTableName())
+		}
+	}
+	return sample
+}
+
+// complete fills the latency and the outcome on each cluster of the request, it must only be called once the request
+// is done (or timed out).
+func (recv *RequestSample) complete(reqCtx *requestContextImpl) *RequestSample {
+	recv.Latency = time.Since(reqCtx.startTime)
+	recv.RequestId = reqCtx.id.String()
+	timedOut := reqCtx.state == RequestTimedOut
+	switch reqCtx.requestInfo.GetForwardDecision() {
+	case forwardToBoth:
+		recv.OriginOutcome = requestOutcome(reqCtx.originResponse, timedOut)
+		recv.TargetOutcome = requestOutcome(reqCtx.targetResponse, timedOut)
+	case forwardToOrigin:
+		recv.OriginOutcome = requestOutcome(reqCtx.originResponse, timedOut)
+	case forwardToTarget:
+		recv.TargetOutcome = requestOutcome(reqCtx.targetResponse, timedOut)
+	}
+	return recv
+}
+
+func requestOutcome(response *frame.RawFrame, timedOut bool) string {
+	if response == nil {
+		if timedOut {
+			return requestOutcomeTimeout
+		}
+		return ""
+	}
+	errMsg, err := decodeError(response)
+	if err != nil {
+		return "UNKNOWN_ERROR"
+	}
+	if errMsg != nil {
+		return protocolName(errMsg.GetErrorCode())
+	}
+	return requestOutcomeSuccess
+}
@@ -0,0 +1,61 @@
+package zdmproxy
+
+import (
+	"encoding/csv"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRequestSampler(t *testing.T) {
+	sampler, err := NewRequestSampler(0, "")
+	require.Nil(t, err)
+	require.Nil(t, sampler)
+	require.False(t, sampler.ShouldSample())
+	sampler.Close()
+
+	fileName := filepath.Join(t.TempDir(), "samples.csv")
+	sampler, err = NewRequestSampler(1, fileName)
+	require.Nil(t, err)
+	require.True(t, sampler.ShouldSample())
+	sampler.Record(&RequestSample{
+		Timestamp:       time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		OpCode:          primitive.OpCodeQuery,
+		ForwardDecision: forwardToBoth,
+		StatementHash:   hashStatement("INSERT INTO ks.tb (a) VALUES (1)"),
+		Table:           "ks.tb",
+		Latency:         1500 * time.Microsecond,
+		OriginOutcome:   requestOutcomeSuccess,
+		TargetOutcome:   requestOutcomeTimeout,
+		RequestId:       "0a1b2c3d-1",
+	})
+	sampler.Close()
+
+	// samples are appended to an existing file without repeating the header
+	sampler, err = NewRequestSampler(0.5, fileName)
+	require.Nil(t, err)
+	sampler.Record(&RequestSample{
+		Timestamp:       time.Date(2023, 1, 2, 3, 4, 6, 0, time.UTC),
+		OpCode:          primitive.OpCodeExecute,
+		ForwardDecision: forwardToOrigin,
+		StatementHash:   hashStatement("SELECT * FROM ks.tb"),
+		Table:           "ks.tb",
+		Latency:         2 * time.Millisecond,
+		OriginOutcome:   protocolName(primitive.ErrorCodeReadTimeout),
+	})
+	sampler.Close()
+
+	file, err := os.Open(fileName)
+	require.Nil(t, err)
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	require.Nil(t, err)
+	require.Equal(t, [][]string{
+		requestSampleCsvHeader,
+		{"2023-01-02T03:04:05Z", "QUERY", "both", hashStatement("INSERT INTO ks.tb (a) VALUES (1)"), "ks.tb", "1.500", "SUCCESS", "TIMEOUT", "0a1b2c3d-1"},
+		{"2023-01-02T03:04:06Z", "EXECUTE", "origin", hashStatement("SELECT * FROM ks.tb"), "ks.tb", "2.000", "ReadTimeout", "", ""},
+	}, rows)
+}
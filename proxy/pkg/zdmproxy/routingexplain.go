@@ -0,0 +1,221 @@
+package zdmproxy
+
+import (
+	"errors"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+)
+
+// RoutingExplanation is the routing decision that the proxy would make for a CQL statement sent as a QUERY request,
+// see ZdmProxy.ExplainRouting.
+type RoutingExplanation struct {
+	Statement     string // statement as it would be forwarded, i.e. after the rewrites
+	StatementType string
+	Table         string `json:",omitempty"`
+	Intercepted   bool   // answered by the proxy without forwarding it (system.local and system.peers virtualization)
+	Clusters      []string
+	AsyncCluster  string `json:",omitempty"` // cluster that the statement is also sent to, the response is not awaited
+	Rewrites      []string
+	Policies      []string
+}
+
+// ExplainRouting returns the routing decision for a statement sent by a client with the provided current keyspace and
+// IP address (both optional). Nothing is forwarded to the clusters and the state of the proxy is not changed, the
+// decision is only valid as long as the runtime routing (e.g. /admin/routing/tables) doesn't change.
+func (p *ZdmProxy) ExplainRouting(statement string, currentKeyspace string, clientIp string) (*RoutingExplanation, error) {
+	p.lock.RLock()
+	timeUuidGenerator := p.timeUuidGenerator
+	topologyConfig := p.TopologyConfig
+	p.lock.RUnlock()
+	if timeUuidGenerator == nil || topologyConfig == nil {
+		return nil, errors.New("the proxy is not started yet")
+	}
+
+	rawFrame, err := defaultCodec.ConvertToRawFrame(
+		frame.NewFrame(primitive.ProtocolVersion4, 0, &message.Query{Query: statement}))
+	if err != nil {
+		return nil, fmt.Errorf("could not encode statement: %w", err)
+	}
+	frameContext := NewFrameDecodeContext(rawFrame)
+	if _, err = frameContext.GetOrInspectStatement(currentKeyspace, timeUuidGenerator); err != nil {
+		return nil, fmt.Errorf("could not parse statement: %w", err)
+	}
+
+	explanation := &RoutingExplanation{
+		Clusters: make([]string, 0),
+		Rewrites: make([]string, 0),
+		Policies: make([]string, 0),
+	}
+	var replacedTerms []*statementReplacedTerms
+	if p.Conf.ReplaceCqlFunctions {
+		frameContext, replacedTerms, err = NewQueryModifier(timeUuidGenerator).replaceQueryString(currentKeyspace, frameContext)
+		if err != nil {
+			return nil, err
+		}
+		for _, stmtReplacedTerms := range replacedTerms {
+			if len(stmtReplacedTerms.replacedTerms) > 0 {
+				explanation.Rewrites = append(explanation.Rewrites, fmt.Sprintf(
+					"%d now() function call(s) replaced with a timeuuid generated by the proxy (ZDM_REPLACE_CQL_FUNCTIONS)",
+					len(stmtReplacedTerms.replacedTerms)))
+			}
+		}
+	}
+	stmtQueryData, err := frameContext.GetOrInspectStatement(currentKeyspace, timeUuidGenerator)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse statement: %w", err)
+	}
+	queryInfo := stmtQueryData.queryData
+	explanation.Statement = queryInfo.getQuery()
+	explanation.StatementType = string(queryInfo.getStatementType())
+	if queryInfo.getTableName() != "" {
+		explanation.Table = qualifiedTableName(queryInfo.getApplicableKeyspace(), queryInfo.getTableName())
+	}
+
+	readCluster := getClientReadCluster(p.clientReadRouting, clientIp, p.primaryCluster)
+	requestInfo, err := buildRequestInfo(
+		frameContext, replacedTerms, p.PreparedStatementCache, p.metricHandler, currentKeyspace, p.primaryCluster,
+		readCluster, p.tableReadRouting, p.readShift, p.systemQueriesMode == common.SystemQueriesModeTarget,
+		topologyConfig.VirtualizationEnabled, false, timeUuidGenerator)
+	if err != nil {
+		return nil, err
+	}
+
+	switch requestInfo.GetForwardDecision() {
+	case forwardToOrigin:
+		explanation.Clusters = append(explanation.Clusters, string(common.ClusterTypeOrigin))
+	case forwardToTarget:
+		explanation.Clusters = append(explanation.Clusters, string(common.ClusterTypeTarget))
+	case forwardToBoth:
+		explanation.Clusters = append(explanation.Clusters,
+			string(common.ClusterTypeOrigin), string(common.ClusterTypeTarget))
+	}
+
+	if _, ok := requestInfo.(*InterceptedRequestInfo); ok {
+		explanation.Intercepted = true
+		explanation.Policies = append(explanation.Policies,
+			"system.local and system.peers are answered by the proxy (ZDM_PROXY_TOPOLOGY_ADDRESSES)")
+		return explanation, nil
+	}
+
+	if queryInfo.getStatementType() == statementTypeSelect {
+		p.explainReadPolicies(explanation, queryInfo, readCluster, clientIp)
+	} else if requestInfo.GetForwardDecision() == forwardToBoth && requestInfo.ShouldBeTrackedInMetrics() {
+		p.explainWritePolicies(explanation, frameContext, requestInfo, currentKeyspace, timeUuidGenerator)
+	}
+
+	isRead := requestInfo.GetForwardDecision() == forwardToOrigin || requestInfo.GetForwardDecision() == forwardToTarget
+	if requestInfo.ShouldAlsoBeSentAsync() && p.readMode == common.ReadModeDualAsyncOnSecondary {
+		if isRead && !p.featureFlags.IsEnabled(common.FeatureAsyncReads, clientIp) {
+			explanation.Policies = append(explanation.Policies, fmt.Sprintf(
+				"async reads are not enabled for client %v (ZDM_FEATURE_FLAGS)", clientIp))
+		} else {
+			explanation.AsyncCluster = string(common.ClusterTypeTarget)
+			if p.primaryCluster == common.ClusterTypeTarget {
+				explanation.AsyncCluster = string(common.ClusterTypeOrigin)
+			}
+			explanation.Policies = append(explanation.Policies, fmt.Sprintf(
+				"also sent to the secondary cluster %v asynchronously (ZDM_READ_MODE=%v)",
+				explanation.AsyncCluster, p.readMode))
+		}
+	}
+	return explanation, nil
+}
+
+func (p *ZdmProxy) explainReadPolicies(
+	explanation *RoutingExplanation, queryInfo QueryInfo, readCluster common.ClusterType, clientIp string) {
+	if isSystemQuery(queryInfo) {
+		explanation.Policies = append(explanation.Policies, fmt.Sprintf(
+			"system table read forwarded to %v (ZDM_SYSTEM_QUERIES_MODE=%v)", explanation.Clusters[0], p.systemQueriesMode))
+		return
+	}
+
+	if cluster, ok := p.tableReadRouting.Get(explanation.Table); ok {
+		explanation.Policies = append(explanation.Policies, fmt.Sprintf(
+			"reads of table %v routed to %v (/admin/routing/tables)", explanation.Table, cluster))
+		return
+	}
+	if readCluster != p.primaryCluster {
+		explanation.Policies = append(explanation.Policies, fmt.Sprintf(
+			"reads of client %v routed to %v (ZDM_CLIENT_READ_ROUTING)", clientIp, readCluster))
+		return
+	}
+	shiftedReadCluster := p.readShift.getReadCluster(readCluster, p.primaryCluster, func() []byte {
+		return []byte(queryInfo.getQuery())
+	})
+	if shiftedReadCluster != readCluster {
+		explanation.Policies = append(explanation.Policies, fmt.Sprintf(
+			"read shifted to %v, %d%% of the reads are shifted (ZDM_TARGET_READ_PERCENTAGE)",
+			shiftedReadCluster, p.readShift.GetPercentage()))
+		return
+	}
+	explanation.Policies = append(explanation.Policies, fmt.Sprintf(
+		"read forwarded to the primary cluster %v (ZDM_PRIMARY_CLUSTER)", p.primaryCluster))
+}
+
+func (p *ZdmProxy) explainWritePolicies(
+	explanation *RoutingExplanation, frameContext *frameDecodeContext, requestInfo RequestInfo,
+	currentKeyspace string, timeUuidGenerator TimeUuidGenerator) {
+	conditional, err := isConditionalWrite(frameContext, requestInfo, currentKeyspace, timeUuidGenerator)
+	if err == nil && conditional {
+		switch p.lwtMode {
+		case common.LwtModeReject:
+			explanation.Clusters = explanation.Clusters[:0]
+			explanation.Policies = append(explanation.Policies, fmt.Sprintf(
+				"conditional write (lightweight transaction) rejected (ZDM_LWT_MODE=%v)", p.lwtMode))
+			return
+		case common.LwtModePrimaryOnly:
+			explanation.Clusters = []string{string(p.primaryCluster)}
+			explanation.Policies = append(explanation.Policies, fmt.Sprintf(
+				"conditional write (lightweight transaction) forwarded to the primary cluster %v only (ZDM_LWT_MODE=%v)",
+				p.primaryCluster, p.lwtMode))
+			return
+		}
+	}
+	counter := false
+	if !conditional {
+		counter, err = isCounterWrite(frameContext, requestInfo, currentKeyspace, timeUuidGenerator)
+		counter = err == nil && counter
+	}
+	if counter && p.counterWriteMode == common.CounterWriteModePrimaryOnly {
+		explanation.Clusters = []string{string(p.primaryCluster)}
+		explanation.Policies = append(explanation.Policies, fmt.Sprintf(
+			"counter update forwarded to the primary cluster %v only (ZDM_COUNTER_WRITE_MODE=%v)",
+			p.primaryCluster, p.counterWriteMode))
+		return
+	}
+
+	explanation.Policies = append(explanation.Policies, fmt.Sprintf(
+		"forwarded to both clusters, failures returned to the client: %v (ZDM_DUAL_WRITE_FAILURE_MODE)",
+		p.dualWriteFailureMode))
+	if conditional {
+		explanation.Policies = append(explanation.Policies, fmt.Sprintf(
+			"conditional write (lightweight transaction), a warning is added to the response if it is applied on "+
+				"only one cluster (ZDM_LWT_MODE=%v)", p.lwtMode))
+	}
+	if counter {
+		explanation.Policies = append(explanation.Policies, fmt.Sprintf(
+			"counter update, failures of the secondary cluster are not retried (ZDM_COUNTER_WRITE_MODE=%v)",
+			p.counterWriteMode))
+	} else if p.secondaryWriteRetry != nil {
+		explanation.Policies = append(explanation.Policies, fmt.Sprintf(
+			"transient failures of the secondary cluster are retried up to %d times (ZDM_SECONDARY_WRITE_RETRY_ATTEMPTS)",
+			p.secondaryWriteRetry.attempts))
+	}
+	if p.writeTimestampFloor != nil {
+		timestamp, found, err := p.writeTimestampFloor.findTimestampBelowFloor(
+			frameContext, requestInfo, currentKeyspace, timeUuidGenerator)
+		if err == nil && found {
+			action := "logged"
+			if p.writeTimestampFloor.shouldReject() {
+				action = "rejected"
+				explanation.Clusters = explanation.Clusters[:0]
+			}
+			explanation.Policies = append(explanation.Policies, fmt.Sprintf(
+				"write %v because its timestamp %d is older than ZDM_WRITE_TIMESTAMP_FLOOR (ZDM_WRITE_TIMESTAMP_FLOOR_MODE=%v)",
+				action, timestamp, p.writeTimestampFloor.mode))
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/stretchr/testify/require"
+	"net"
+	"sync"
+	"testing"
+)
+
+func TestZdmProxy_ExplainRouting(t *testing.T) {
+	events := NewEventBroadcaster()
+	_, subnet, err := net.ParseCIDR("10.0.0.0/8")
+	require.Nil(t, err)
+	conf := config.New()
+	conf.ReplaceCqlFunctions = true
+	proxy := &ZdmProxy{
+		Conf:                 conf,
+		lock:                 &sync.RWMutex{},
+		timeUuidGenerator:    &fakeTimeUuidGenerator{},
+		TopologyConfig:       &common.TopologyConfig{VirtualizationEnabled: true},
+		primaryCluster:       common.ClusterTypeOrigin,
+		readMode:             common.ReadModeDualAsyncOnSecondary,
+		systemQueriesMode:    common.SystemQueriesModeOrigin,
+		dualWriteFailureMode: common.DualWriteFailureModeEither,
+		lwtMode:              common.LwtModePrimaryOnly,
+		counterWriteMode:     common.CounterWriteModePrimaryOnly,
+		tableReadRouting:     NewTableReadRouting(events),
+		clientReadRouting:    []*common.ClientReadRoute{{Subnet: subnet, Cluster: common.ClusterTypeTarget}},
+		readShift:            NewReadShift(0, common.ReadShiftKeyStatement, events),
+		featureFlags:         NewFeatureFlags(map[common.Feature]int{}, events),
+	}
+	require.Nil(t, proxy.tableReadRouting.Set("ks.routed", common.ClusterTypeTarget))
+
+	tests := []struct {
+		name             string
+		statement        string
+		keyspace         string
+		clientIp         string
+		expectedClusters []string
+		expectedAsync    string
+		expectedRewrites int
+		expectedPolicy   string
+	}{
+		{"read", "SELECT * FROM ks.tbl", "", "", []string{"ORIGIN"}, "TARGET", 0, "primary cluster ORIGIN"},
+		{"read of a routed table", "SELECT * FROM routed", "ks", "", []string{"TARGET"}, "", 0, "/admin/routing/tables"},
+		{"read of a routed client", "SELECT * FROM ks.tbl", "", "10.1.2.3", []string{"TARGET"}, "", 0, "ZDM_CLIENT_READ_ROUTING"},
+		{"system read", "SELECT * FROM system.peers_v2", "", "", nil, "", 0, "answered by the proxy"},
+		{"system table read", "SELECT * FROM system_schema.tables", "", "", []string{"ORIGIN"}, "", 0, "ZDM_SYSTEM_QUERIES_MODE"},
+		{"write", "INSERT INTO ks.tbl (pk, ts) VALUES (1, now())", "", "", []string{"ORIGIN", "TARGET"}, "", 1, "ZDM_DUAL_WRITE_FAILURE_MODE"},
+		{"conditional write", "UPDATE ks.tbl SET v = 1 WHERE pk = 1 IF v = 0", "", "", []string{"ORIGIN"}, "", 0, "ZDM_LWT_MODE"},
+		{"counter update", "UPDATE ks.tbl SET c = c + 1 WHERE pk = 1", "", "", []string{"ORIGIN"}, "", 0, "ZDM_COUNTER_WRITE_MODE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			explanation, err := proxy.ExplainRouting(tt.statement, tt.keyspace, tt.clientIp)
+			require.Nil(t, err)
+			if tt.expectedClusters == nil {
+				require.True(t, explanation.Intercepted)
+			} else {
+				require.Equal(t, tt.expectedClusters, explanation.Clusters)
+			}
+			require.Equal(t, tt.expectedAsync, explanation.AsyncCluster)
+			require.Equal(t, tt.expectedRewrites, len(explanation.Rewrites))
+			require.NotEmpty(t, explanation.Policies)
+			require.Contains(t, explanation.Policies[0], tt.expectedPolicy)
+		})
+	}
+}
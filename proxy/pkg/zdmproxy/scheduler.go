@@ -1,6 +1,9 @@
 package zdmproxy
 
-import "sync"
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/crash"
+	"sync"
+)
 
 type Scheduler struct {
 	queue chan func()
@@ -17,6 +20,7 @@ func NewScheduler(workers int) *Scheduler {
 		scheduler.wg.Add(1)
 		go func() {
 			defer scheduler.wg.Done()
+			defer crash.HandlePanic()
 			for {
 				task, ok := <-scheduler.queue
 				if !ok {
@@ -0,0 +1,58 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"sync/atomic"
+)
+
+// SingleSidedWrites counts the writes that this instance acknowledged to its clients although they were only written
+// to one cluster (see ZDM_DUAL_WRITE_FAILURE_MODE, ZDM_LWT_MODE and ZDM_COUNTER_WRITE_MODE). The same writes are
+// exported as proxy_single_sided_writes_total, they are also kept here so that read routing changes can be checked:
+// reads moved to a cluster that misses some writes may return stale data.
+type SingleSidedWrites struct {
+	origin             int64
+	target             int64
+	maxMissingOnTarget int64 // ZDM_TARGET_READ_CUTOVER_MAX_MISSING_WRITES, negative if disabled
+}
+
+func NewSingleSidedWrites(maxMissingOnTarget int) *SingleSidedWrites {
+	return &SingleSidedWrites{maxMissingOnTarget: int64(maxMissingOnTarget)}
+}
+
+func (recv *SingleSidedWrites) add(writtenTo common.ClusterType) {
+	if recv == nil {
+		return
+	}
+	if writtenTo == common.ClusterTypeTarget {
+		atomic.AddInt64(&recv.target, 1)
+	} else {
+		atomic.AddInt64(&recv.origin, 1)
+	}
+}
+
+// GetMissingOn returns the number of writes acknowledged since this instance started that were only written to the
+// other cluster, i.e. an estimate of the writes that reads forwarded to the provided cluster won't see. Other proxy
+// instances keep their own count.
+func (recv *SingleSidedWrites) GetMissingOn(cluster common.ClusterType) int64 {
+	if recv == nil {
+		return 0
+	}
+	if cluster == common.ClusterTypeTarget {
+		return atomic.LoadInt64(&recv.origin)
+	}
+	return atomic.LoadInt64(&recv.target)
+}
+
+// CheckReadsMovedTo returns the number of writes that the provided cluster misses and whether reads can be moved to
+// it without acknowledging them: reads are not moved back to origin while it misses writes and they are only moved
+// to target while it misses at most ZDM_TARGET_READ_CUTOVER_MAX_MISSING_WRITES writes.
+func (recv *SingleSidedWrites) CheckReadsMovedTo(cluster common.ClusterType) (missingWrites int64, ok bool) {
+	if recv == nil {
+		return 0, true
+	}
+	missingWrites = recv.GetMissingOn(cluster)
+	if cluster == common.ClusterTypeTarget {
+		return missingWrites, recv.maxMissingOnTarget < 0 || missingWrites <= recv.maxMissingOnTarget
+	}
+	return missingWrites, missingWrites == 0
+}
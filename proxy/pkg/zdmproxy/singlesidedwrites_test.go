@@ -0,0 +1,37 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSingleSidedWrites_CheckReadsMovedTo(t *testing.T) {
+	check := func(singleSidedWrites *SingleSidedWrites, cluster common.ClusterType, expectedMissing int64, expectedOk bool) {
+		missingWrites, ok := singleSidedWrites.CheckReadsMovedTo(cluster)
+		require.Equal(t, expectedMissing, missingWrites, cluster)
+		require.Equal(t, expectedOk, ok, cluster)
+	}
+
+	var disabled *SingleSidedWrites
+	disabled.add(common.ClusterTypeOrigin)
+	check(disabled, common.ClusterTypeTarget, 0, true)
+
+	singleSidedWrites := NewSingleSidedWrites(1)
+	check(singleSidedWrites, common.ClusterTypeOrigin, 0, true)
+	check(singleSidedWrites, common.ClusterTypeTarget, 0, true)
+
+	singleSidedWrites.add(common.ClusterTypeOrigin)
+	check(singleSidedWrites, common.ClusterTypeTarget, 1, true)
+	singleSidedWrites.add(common.ClusterTypeOrigin)
+	check(singleSidedWrites, common.ClusterTypeTarget, 2, false)
+	check(singleSidedWrites, common.ClusterTypeOrigin, 0, true)
+
+	// reads are never moved back to origin silently while it misses writes
+	singleSidedWrites.add(common.ClusterTypeTarget)
+	check(singleSidedWrites, common.ClusterTypeOrigin, 1, false)
+
+	unlimited := NewSingleSidedWrites(-1)
+	unlimited.add(common.ClusterTypeOrigin)
+	check(unlimited, common.ClusterTypeTarget, 1, true)
+}
@@ -0,0 +1,164 @@
+package zdmproxy
+
+import (
+	"context"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/crash"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// sshTunnelDialer opens connections through an SSH jump host. A single SSH connection is shared by all connections
+// to the cluster (each one is a separate SSH channel) and it is re-established when it is closed.
+type sshTunnelDialer struct {
+	jumpHostAddr string
+	sshConfig    *ssh.ClientConfig
+	forward      Dialer
+
+	lock   *sync.Mutex
+	client *ssh.Client
+}
+
+func newSshTunnelDialer(dialConfig *common.ClusterDialConfig, forward Dialer) (*sshTunnelDialer, error) {
+	identity, err := os.ReadFile(dialConfig.SshIdentityFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read SSH identity file %v: %w", dialConfig.SshIdentityFile, err)
+	}
+	signer, err := ssh.ParsePrivateKey(identity)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse SSH identity file %v: %w", dialConfig.SshIdentityFile, err)
+	}
+
+	var hostKeyCallback ssh.HostKeyCallback
+	if dialConfig.SshKnownHostsFile != "" {
+		hostKeyCallback, err = knownhosts.New(dialConfig.SshKnownHostsFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read SSH known hosts file %v: %w", dialConfig.SshKnownHostsFile, err)
+		}
+	} else if dialConfig.SshInsecureIgnoreHostKey {
+		log.Warnf("The host key of SSH jump host %v will NOT be verified because SSH_INSECURE_IGNORE_HOST_KEY is "+
+			"set, a man in the middle could receive the cluster credentials and traffic.", dialConfig.SshJumpHost)
+		hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	} else {
+		return nil, fmt.Errorf("no SSH known hosts file was provided to verify the host key of jump host %v",
+			dialConfig.SshJumpHost)
+	}
+
+	return &sshTunnelDialer{
+		jumpHostAddr: dialConfig.SshJumpHost,
+		sshConfig: &ssh.ClientConfig{
+			User:            dialConfig.SshUser,
+			Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+			HostKeyCallback: hostKeyCallback,
+		},
+		forward: forward,
+		lock:    &sync.Mutex{},
+		client:  nil,
+	}, nil
+}
+
+func (recv *sshTunnelDialer) DialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	client, err := recv.getOrCreateClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := dialChannel(ctx, client, network, address)
+	if err == nil {
+		return conn, nil
+	}
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("SSH jump host %v could not connect to %v: %w", recv.jumpHostAddr, address, err)
+	}
+
+	// the SSH connection might be broken without the client noticing it yet, retry once with a new one
+	log.Debugf("Could not open SSH channel to %v through %v, reconnecting to the jump host: %v",
+		address, recv.jumpHostAddr, err)
+	recv.resetClient(client)
+	client, err = recv.getOrCreateClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn, err = dialChannel(ctx, client, network, address)
+	if err != nil {
+		return nil, fmt.Errorf("SSH jump host %v could not connect to %v: %w", recv.jumpHostAddr, address, err)
+	}
+	return conn, nil
+}
+
+// dialChannel opens an SSH channel to the address, ssh.Client.Dial doesn't take a context so the channel is abandoned
+// (and closed once it is opened) if the context is done first.
+func dialChannel(ctx context.Context, client *ssh.Client, network string, address string) (net.Conn, error) {
+	type dialResult struct {
+		conn net.Conn
+		err  error
+	}
+	result := make(chan dialResult, 1)
+	go func() {
+		defer crash.HandlePanic()
+		conn, err := client.Dial(network, address)
+		result <- dialResult{conn, err}
+	}()
+
+	select {
+	case r := <-result:
+		return r.conn, r.err
+	case <-ctx.Done():
+		go func() {
+			defer crash.HandlePanic()
+			if r := <-result; r.conn != nil {
+				_ = r.conn.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+func (recv *sshTunnelDialer) getOrCreateClient(ctx context.Context) (*ssh.Client, error) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	if recv.client != nil {
+		return recv.client, nil
+	}
+
+	log.Infof("Opening SSH connection to jump host %v", recv.jumpHostAddr)
+	conn, err := recv.forward.DialContext(ctx, "tcp", recv.jumpHostAddr)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to SSH jump host %v: %w", recv.jumpHostAddr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	clientConn, chans, reqs, err := ssh.NewClientConn(conn, recv.jumpHostAddr, recv.sshConfig)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("SSH handshake with jump host %v failed: %w", recv.jumpHostAddr, err)
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	client := ssh.NewClient(clientConn, chans, reqs)
+	recv.client = client
+	go func() {
+		defer crash.HandlePanic()
+		err := client.Wait()
+		log.Infof("SSH connection to jump host %v closed: %v", recv.jumpHostAddr, err)
+		recv.resetClient(client)
+	}()
+	return client, nil
+}
+
+func (recv *sshTunnelDialer) resetClient(client *ssh.Client) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if recv.client == client {
+		recv.client = nil
+		_ = client.Close()
+	}
+}
@@ -0,0 +1,201 @@
+package zdmproxy
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newSshIdentityFile writes a new private key to a file and returns the file and the signer of the key.
+func newSshIdentityFile(t *testing.T) (string, ssh.Signer) {
+	_, clientKey, err := ed25519.GenerateKey(rand.Reader)
+	require.Nil(t, err)
+	clientSigner, err := ssh.NewSignerFromKey(clientKey)
+	require.Nil(t, err)
+
+	pemBlock, err := ssh.MarshalPrivateKey(clientKey, "")
+	require.Nil(t, err)
+	identityFile := filepath.Join(t.TempDir(), "id_ed25519")
+	require.Nil(t, os.WriteFile(identityFile, pem.EncodeToMemory(pemBlock), 0600))
+	return identityFile, clientSigner
+}
+
+func newKnownHostsFile(t *testing.T, addr string, hostKey ssh.PublicKey) string {
+	knownHostsFile := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(addr)}, hostKey)
+	require.Nil(t, os.WriteFile(knownHostsFile, []byte(line+"\n"), 0600))
+	return knownHostsFile
+}
+
+func TestSshTunnelDialer(t *testing.T) {
+	identityFile, clientSigner := newSshIdentityFile(t)
+	requestedAddr := make(chan string, 2)
+	jumpHostAddr, hostKey := startFakeSshJumpHost(t, "user1", clientSigner.PublicKey(), requestedAddr)
+
+	dialer, err := newSshTunnelDialer(&common.ClusterDialConfig{
+		SshJumpHost:       jumpHostAddr,
+		SshUser:           "user1",
+		SshIdentityFile:   identityFile,
+		SshKnownHostsFile: newKnownHostsFile(t, jumpHostAddr, hostKey),
+	}, &net.Dialer{})
+	require.Nil(t, err)
+
+	assertEcho(t, dialer, "10.0.0.1:9042")
+	require.Equal(t, "10.0.0.1:9042", <-requestedAddr)
+
+	// the SSH connection is shared
+	assertEcho(t, dialer, "10.0.0.2:9042")
+	require.Equal(t, "10.0.0.2:9042", <-requestedAddr)
+}
+
+func TestSshTunnelDialer_HostKey(t *testing.T) {
+	identityFile, clientSigner := newSshIdentityFile(t)
+	jumpHostAddr, _ := startFakeSshJumpHost(t, "user1", clientSigner.PublicKey(), make(chan string, 1))
+
+	// the host key is verified unless explicitly disabled
+	_, err := newSshTunnelDialer(&common.ClusterDialConfig{
+		SshJumpHost:     jumpHostAddr,
+		SshUser:         "user1",
+		SshIdentityFile: identityFile,
+	}, &net.Dialer{})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "no SSH known hosts file was provided")
+
+	_, otherHostKey := newSshIdentityFile(t)
+	dialer, err := newSshTunnelDialer(&common.ClusterDialConfig{
+		SshJumpHost:       jumpHostAddr,
+		SshUser:           "user1",
+		SshIdentityFile:   identityFile,
+		SshKnownHostsFile: newKnownHostsFile(t, jumpHostAddr, otherHostKey.PublicKey()),
+	}, &net.Dialer{})
+	require.Nil(t, err)
+	_, err = dialer.DialContext(context.Background(), "tcp", "10.0.0.1:9042")
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "key mismatch")
+
+	dialer, err = newSshTunnelDialer(&common.ClusterDialConfig{
+		SshJumpHost:              jumpHostAddr,
+		SshUser:                  "user1",
+		SshIdentityFile:          identityFile,
+		SshInsecureIgnoreHostKey: true,
+	}, &net.Dialer{})
+	require.Nil(t, err)
+	assertEcho(t, dialer, "10.0.0.1:9042")
+}
+
+func TestSshTunnelDialer_WrongUser(t *testing.T) {
+	identityFile, clientSigner := newSshIdentityFile(t)
+	jumpHostAddr, hostKey := startFakeSshJumpHost(t, "user1", clientSigner.PublicKey(), make(chan string, 1))
+
+	dialer, err := newSshTunnelDialer(&common.ClusterDialConfig{
+		SshJumpHost:       jumpHostAddr,
+		SshUser:           "user2",
+		SshIdentityFile:   identityFile,
+		SshKnownHostsFile: newKnownHostsFile(t, jumpHostAddr, hostKey),
+	}, &net.Dialer{})
+	require.Nil(t, err)
+
+	_, err = dialer.DialContext(context.Background(), "tcp", "10.0.0.1:9042")
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "SSH handshake with jump host")
+}
+
+func TestSshTunnelDialer_ChannelOpenTimeout(t *testing.T) {
+	identityFile, clientSigner := newSshIdentityFile(t)
+	// nothing reads the requested addresses so the jump host never answers the channel open requests
+	jumpHostAddr, hostKey := startFakeSshJumpHost(t, "user1", clientSigner.PublicKey(), make(chan string))
+
+	dialer, err := newSshTunnelDialer(&common.ClusterDialConfig{
+		SshJumpHost:       jumpHostAddr,
+		SshUser:           "user1",
+		SshIdentityFile:   identityFile,
+		SshKnownHostsFile: newKnownHostsFile(t, jumpHostAddr, hostKey),
+	}, &net.Dialer{})
+	require.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = dialer.DialContext(ctx, "tcp", "10.0.0.1:9042")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), 5*time.Second)
+}
+
+// startFakeSshJumpHost starts an SSH server that accepts direct-tcpip channels and echoes the data sent on them.
+// Returns the address and the host key of the server.
+func startFakeSshJumpHost(
+	t *testing.T, user string, authorizedKey ssh.PublicKey, requestedAddr chan string) (string, ssh.PublicKey) {
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	require.Nil(t, err)
+	hostSigner, err := ssh.NewSignerFromKey(hostKey)
+	require.Nil(t, err)
+
+	serverConfig := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if conn.User() == user && string(key.Marshal()) == string(authorizedKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, io.ErrUnexpectedEOF
+		},
+	}
+	serverConfig.AddHostKey(hostSigner)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, chans, reqs, err := ssh.NewServerConn(conn, serverConfig)
+				if err != nil {
+					_ = conn.Close()
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for newChannel := range chans {
+					if newChannel.ChannelType() != "direct-tcpip" {
+						_ = newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+						continue
+					}
+					requestedAddr <- parseDirectTcpIpTarget(newChannel.ExtraData())
+					channel, channelReqs, err := newChannel.Accept()
+					if err != nil {
+						continue
+					}
+					go ssh.DiscardRequests(channelReqs)
+					go func() {
+						defer channel.Close()
+						_, _ = io.Copy(channel, channel)
+					}()
+				}
+			}()
+		}
+	}()
+	return l.Addr().String(), hostSigner.PublicKey()
+}
+
+// parseDirectTcpIpTarget returns host:port of the direct-tcpip channel request (RFC 4254 section 7.2).
+func parseDirectTcpIpTarget(extraData []byte) string {
+	hostLen := binary.BigEndian.Uint32(extraData)
+	host := string(extraData[4 : 4+hostLen])
+	port := binary.BigEndian.Uint32(extraData[4+hostLen:])
+	return net.JoinHostPort(host, strconv.Itoa(int(port)))
+}
@@ -110,10 +110,12 @@ func (ch *ClientHandler) handleSecondaryHandshakeStartup(
 		if !requestSent {
 			overallRequestStartTime := time.Now()
 			channel := make(chan *customResponse, 1)
-			err := ch.executeRequest(
+			_, err := ch.executeRequest(
 				NewFrameDecodeContext(request),
 				NewGenericRequestInfo(forwardToSecondary, asyncConnector, false),
 				ch.LoadCurrentKeyspace(),
+				false,
+				false,
 				overallRequestStartTime,
 				channel,
 				requestTimeout)
@@ -0,0 +1,149 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"net"
+	"strings"
+	"sync"
+)
+
+// TableReadRouting contains the tables whose reads are forwarded to a different cluster than the other reads, this
+// allows reads to be switched over to target (or back to origin) one table at a time instead of changing
+// ZDM_PRIMARY_CLUSTER for every table at once. Writes are not affected.
+//
+// Tables are identified by "keyspace.table" where both identifiers are in their internal form, i.e. lower case unless
+// they were created with a quoted identifier.
+type TableReadRouting struct {
+	lock   *sync.RWMutex
+	tables map[string]common.ClusterType
+	events *EventBroadcaster
+}
+
+func NewTableReadRouting(events *EventBroadcaster) *TableReadRouting {
+	return &TableReadRouting{
+		lock:   &sync.RWMutex{},
+		tables: make(map[string]common.ClusterType),
+		events: events,
+	}
+}
+
+func (recv *TableReadRouting) Get(table string) (common.ClusterType, bool) {
+	if recv == nil {
+		return "", false
+	}
+	recv.lock.RLock()
+	defer recv.lock.RUnlock()
+
+	cluster, ok := recv.tables[table]
+	return cluster, ok
+}
+
+// GetAll returns a copy of the tables whose reads are routed to a specific cluster.
+func (recv *TableReadRouting) GetAll() map[string]common.ClusterType {
+	tables := make(map[string]common.ClusterType)
+	if recv == nil {
+		return tables
+	}
+	recv.lock.RLock()
+	defer recv.lock.RUnlock()
+
+	for table, cluster := range recv.tables {
+		tables[table] = cluster
+	}
+	return tables
+}
+
+// Set routes the reads of the table to the provided cluster. Reads of system tables can not be routed per table,
+// see ZDM_SYSTEM_QUERIES_MODE.
+func (recv *TableReadRouting) Set(table string, cluster common.ClusterType) error {
+	err := recv.Validate(table, cluster)
+	if err != nil {
+		return err
+	}
+
+	recv.lock.Lock()
+	recv.tables[table] = cluster
+	recv.lock.Unlock()
+	recv.events.Publish(ProxyEventReadRoutingChanged, "reads of table %v routed to %v", table, cluster)
+	return nil
+}
+
+// Validate returns the error that Set would return for this table and cluster without changing the routing.
+func (recv *TableReadRouting) Validate(table string, cluster common.ClusterType) error {
+	keyspace, tableName, err := parseQualifiedTableName(table)
+	if err != nil {
+		return err
+	}
+	if isSystemOrDseKeyspace(keyspace) {
+		return fmt.Errorf("reads of system table %v.%v can not be routed per table", keyspace, tableName)
+	}
+	if cluster != common.ClusterTypeOrigin && cluster != common.ClusterTypeTarget {
+		return fmt.Errorf("invalid cluster %v, valid values are %v and %v",
+			cluster, common.ClusterTypeOrigin, common.ClusterTypeTarget)
+	}
+	return nil
+}
+
+// Remove removes the routing of the table so that its reads are forwarded like every other read again.
+// Returns false if the table was not routed.
+func (recv *TableReadRouting) Remove(table string) bool {
+	recv.lock.Lock()
+	_, ok := recv.tables[table]
+	delete(recv.tables, table)
+	recv.lock.Unlock()
+	if ok {
+		recv.events.Publish(ProxyEventReadRoutingChanged, "routing of the reads of table %v removed", table)
+	}
+	return ok
+}
+
+// getReadForwardDecision returns where a read of the provided table should be forwarded to and whether it should also
+// be sent to the async connector. Reads of tables without routing are forwarded to readCluster, i.e. the primary
+// cluster unless the client connection is routed by ZDM_CLIENT_READ_ROUTING. The async connector is connected to the
+// secondary cluster so reads that are routed to the secondary cluster are not sent to the async connector.
+func (recv *TableReadRouting) getReadForwardDecision(
+	table string, readCluster common.ClusterType, primaryCluster common.ClusterType) (decision forwardDecision, sendAlsoToAsync bool) {
+	if cluster, ok := recv.Get(table); ok {
+		readCluster = cluster
+	}
+	if readCluster == common.ClusterTypeTarget {
+		return forwardToTarget, readCluster == primaryCluster
+	}
+	return forwardToOrigin, readCluster == primaryCluster
+}
+
+// getClientReadCluster returns the cluster that the reads of a client connection are forwarded to. The most specific
+// subnet that contains the client IP address wins, reads of clients that are not in any subnet are forwarded to the
+// primary cluster. Per table routing (see TableReadRouting) takes precedence over the cluster returned here.
+func getClientReadCluster(
+	routes []*common.ClientReadRoute, clientIp string, primaryCluster common.ClusterType) common.ClusterType {
+	ip := net.ParseIP(clientIp)
+	if ip == nil {
+		return primaryCluster
+	}
+	readCluster := primaryCluster
+	longestPrefix := -1
+	for _, route := range routes {
+		if !route.Subnet.Contains(ip) {
+			continue
+		}
+		if prefix, _ := route.Subnet.Mask.Size(); prefix > longestPrefix {
+			readCluster = route.Cluster
+			longestPrefix = prefix
+		}
+	}
+	return readCluster
+}
+
+func parseQualifiedTableName(table string) (keyspace string, tableName string, err error) {
+	parts := strings.Split(table, ".")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid table %v, expected keyspace.table", table)
+	}
+	return parts[0], parts[1], nil
+}
+
+func qualifiedTableName(keyspace string, table string) string {
+	return keyspace + "." + table
+}
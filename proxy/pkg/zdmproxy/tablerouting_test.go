@@ -0,0 +1,100 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestTableReadRouting(t *testing.T) {
+	timeUuidGenerator, err := GetDefaultTimeUuidGenerator()
+	require.Nil(t, err)
+	psCache := NewPreparedStatementCache()
+	mh := newFakeMetricHandler()
+	routing := NewTableReadRouting(nil)
+	build := func(frameContext *frameDecodeContext) RequestInfo {
+		requestInfo, err := buildRequestInfo(frameContext, nil, psCache, mh, "ks1", common.ClusterTypeOrigin, common.ClusterTypeOrigin, routing, nil,
+			false, true, false, timeUuidGenerator)
+		require.Nil(t, err)
+		return requestInfo
+	}
+
+	require.NotNil(t, routing.Set("ks1", common.ClusterTypeTarget))
+	require.NotNil(t, routing.Set("system_auth.roles", common.ClusterTypeTarget))
+	require.NotNil(t, routing.Set("ks1.t1", common.ClusterType("BOTH")))
+	require.Nil(t, routing.Set("ks1.t1", common.ClusterTypeTarget))
+	require.Equal(t, map[string]common.ClusterType{"ks1.t1": common.ClusterTypeTarget}, routing.GetAll())
+
+	tests := []struct {
+		name            string
+		query           string
+		expected        forwardDecision
+		sendAlsoToAsync bool
+	}{
+		{"routed table", "SELECT * FROM ks1.t1", forwardToTarget, false},
+		{"routed table with current keyspace", "SELECT * FROM t1", forwardToTarget, false},
+		{"other table", "SELECT * FROM ks1.t2", forwardToOrigin, true},
+		{"write to routed table", "INSERT INTO ks1.t1 (a) VALUES (1)", forwardToBoth, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestInfo := build(NewFrameDecodeContext(mockQueryFrame(t, tt.query)))
+			require.Equal(t, tt.expected, requestInfo.GetForwardDecision())
+			require.Equal(t, tt.sendAlsoToAsync, requestInfo.ShouldAlsoBeSentAsync())
+		})
+	}
+
+	t.Run("prepared statements follow routing changes", func(t *testing.T) {
+		prepareRequestInfo, ok := build(NewFrameDecodeContext(mockPrepareFrame(t, "SELECT * FROM ks1.t1"))).(*PrepareRequestInfo)
+		require.True(t, ok)
+		require.Equal(t, "ks1.t1", prepareRequestInfo.GetReadTable())
+		psCache.Store(
+			&message.PreparedResult{PreparedQueryId: []byte("ID")}, &message.PreparedResult{PreparedQueryId: []byte("ID")},
+			prepareRequestInfo)
+		executeFrame := mockFrame(t, &message.Execute{QueryId: []byte("ID")}, primitive.ProtocolVersion4)
+
+		require.Equal(t, forwardToTarget, build(NewFrameDecodeContext(executeFrame)).GetForwardDecision())
+		require.True(t, routing.Remove("ks1.t1"))
+		require.False(t, routing.Remove("ks1.t1"))
+		requestInfo := build(NewFrameDecodeContext(executeFrame))
+		require.Equal(t, forwardToOrigin, requestInfo.GetForwardDecision())
+		require.True(t, requestInfo.ShouldAlsoBeSentAsync())
+	})
+}
+
+func TestClientReadRouting(t *testing.T) {
+	conf := config.New()
+	conf.ClientReadRouting = "10.1.0.0/16=TARGET,10.1.2.0/24=ORIGIN,fd00::/8=TARGET"
+	routes, err := conf.ParseClientReadRouting()
+	require.Nil(t, err)
+
+	tests := []struct {
+		name     string
+		clientIp string
+		expected common.ClusterType
+	}{
+		{"routed subnet", "10.1.3.4", common.ClusterTypeTarget},
+		{"most specific subnet wins", "10.1.2.3", common.ClusterTypeOrigin},
+		{"ipv6", "fd00::1", common.ClusterTypeTarget},
+		{"not routed", "10.2.0.1", common.ClusterTypeOrigin},
+		{"not an ip", "pipe", common.ClusterTypeOrigin},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, getClientReadCluster(routes, tt.clientIp, common.ClusterTypeOrigin))
+		})
+	}
+
+	// table routing takes precedence over client routing, reads of the secondary cluster are never sent async
+	routing := NewTableReadRouting(nil)
+	require.Nil(t, routing.Set("ks1.t1", common.ClusterTypeOrigin))
+	decision, sendAlsoToAsync := routing.getReadForwardDecision("ks1.t1", common.ClusterTypeTarget, common.ClusterTypeOrigin)
+	require.Equal(t, forwardToOrigin, decision)
+	require.True(t, sendAlsoToAsync)
+	decision, sendAlsoToAsync = routing.getReadForwardDecision("ks1.t2", common.ClusterTypeTarget, common.ClusterTypeOrigin)
+	require.Equal(t, forwardToTarget, decision)
+	require.False(t, sendAlsoToAsync)
+}
@@ -0,0 +1,98 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"time"
+)
+
+// WriteTimestampFloor finds writes with an explicit timestamp older than ZDM_WRITE_TIMESTAMP_FLOOR, usually the time
+// of the snapshot that is bulk loaded into the target cluster. Conflicts are resolved with the write timestamps so the
+// bulk load can silently shadow such a write on the target cluster and the two clusters would then differ.
+//
+// Explicit timestamps are the USING TIMESTAMP literals of the statements (prepared statements included) and the
+// timestamp of the request set by the client, timestamps set with bind markers are not checked.
+type WriteTimestampFloor struct {
+	floor       time.Time
+	floorMicros int64
+	mode        common.WriteTimestampFloorMode
+}
+
+// NewWriteTimestampFloor returns nil if the floor is the zero time.
+func NewWriteTimestampFloor(floor time.Time, mode common.WriteTimestampFloorMode) *WriteTimestampFloor {
+	if floor.IsZero() {
+		return nil
+	}
+	return &WriteTimestampFloor{
+		floor:       floor,
+		floorMicros: floor.UnixMicro(),
+		mode:        mode,
+	}
+}
+
+func (recv *WriteTimestampFloor) String() string {
+	return fmt.Sprintf("WriteTimestampFloor{floor=%v, mode=%v}", recv.floor.Format(time.RFC3339), recv.mode)
+}
+
+func (recv *WriteTimestampFloor) shouldReject() bool {
+	return recv.mode == common.WriteTimestampFloorModeReject
+}
+
+// findTimestampBelowFloor returns an explicit timestamp (in microseconds) of the QUERY, EXECUTE or BATCH request that
+// is older than the floor.
+func (recv *WriteTimestampFloor) findTimestampBelowFloor(
+	frameContext *frameDecodeContext, requestInfo RequestInfo, currentKeyspace string,
+	timeUuidGenerator TimeUuidGenerator) (int64, bool, error) {
+	decodedFrame, err := frameContext.GetOrDecodeFrame()
+	if err != nil {
+		return 0, false, err
+	}
+
+	var timestamps []int64
+	inspectStatements := false
+	switch typedMsg := decodedFrame.Body.Message.(type) {
+	case *message.Query:
+		if typedMsg.Options != nil && typedMsg.Options.DefaultTimestamp != nil {
+			timestamps = append(timestamps, *typedMsg.Options.DefaultTimestamp)
+		}
+		inspectStatements = true
+	case *message.Execute:
+		if typedMsg.Options != nil && typedMsg.Options.DefaultTimestamp != nil {
+			timestamps = append(timestamps, *typedMsg.Options.DefaultTimestamp)
+		}
+		if executeRequestInfo, ok := requestInfo.(*ExecuteRequestInfo); ok {
+			timestamps = append(timestamps,
+				executeRequestInfo.GetPreparedData().GetPrepareRequestInfo().GetLiteralTimestamps()...)
+		}
+	case *message.Batch:
+		if typedMsg.DefaultTimestamp != nil {
+			timestamps = append(timestamps, *typedMsg.DefaultTimestamp)
+		}
+		if batchRequestInfo, ok := requestInfo.(*BatchRequestInfo); ok {
+			for _, preparedData := range batchRequestInfo.GetPreparedDataByStmtIdx() {
+				timestamps = append(timestamps, preparedData.GetPrepareRequestInfo().GetLiteralTimestamps()...)
+			}
+		}
+		inspectStatements = true
+	default:
+		return 0, false, nil
+	}
+
+	if inspectStatements {
+		stmtsQueryData, err := frameContext.GetOrInspectAllStatements(currentKeyspace, timeUuidGenerator)
+		if err != nil {
+			return 0, false, err
+		}
+		for _, stmtQueryData := range stmtsQueryData {
+			timestamps = append(timestamps, stmtQueryData.queryData.getLiteralTimestamps()...)
+		}
+	}
+
+	for _, timestamp := range timestamps {
+		if timestamp < recv.floorMicros {
+			return timestamp, true, nil
+		}
+	}
+	return 0, false, nil
+}
@@ -0,0 +1,84 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestNewWriteTimestampFloor(t *testing.T) {
+	require.Nil(t, NewWriteTimestampFloor(time.Time{}, common.WriteTimestampFloorModeWarn))
+	floor := NewWriteTimestampFloor(time.Unix(1000, 0), common.WriteTimestampFloorModeReject)
+	require.Equal(t, int64(1000_000_000), floor.floorMicros)
+	require.True(t, floor.shouldReject())
+}
+
+func TestWriteTimestampFloor_FindTimestampBelowFloor(t *testing.T) {
+	floor := NewWriteTimestampFloor(time.UnixMicro(1000), common.WriteTimestampFloorModeWarn)
+	int64Ptr := func(v int64) *int64 { return &v }
+	preparedData := func(literalTimestamps []int64) PreparedData {
+		prepareRequestInfo := NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, true), nil, false,
+			"INSERT INTO ks.tbl (pk) VALUES (?) USING TIMESTAMP 1", "").withLiteralTimestamps(literalTimestamps)
+		return NewPreparedData(
+			&message.PreparedResult{PreparedQueryId: []byte("origin")},
+			&message.PreparedResult{PreparedQueryId: []byte("target")},
+			prepareRequestInfo)
+	}
+
+	tests := []struct {
+		name              string
+		msg               message.Message
+		requestInfo       RequestInfo
+		expectedTimestamp int64
+		expectedFound     bool
+	}{
+		{"query without timestamp",
+			&message.Query{Query: "INSERT INTO ks.tbl (pk) VALUES (1)"},
+			NewGenericRequestInfo(forwardToBoth, false, true), 0, false},
+		{"query with recent literal",
+			&message.Query{Query: "INSERT INTO ks.tbl (pk) VALUES (1) USING TIMESTAMP 2000"},
+			NewGenericRequestInfo(forwardToBoth, false, true), 0, false},
+		{"query with stale literal",
+			&message.Query{Query: "INSERT INTO ks.tbl (pk) VALUES (1) USING TIMESTAMP 500"},
+			NewGenericRequestInfo(forwardToBoth, false, true), 500, true},
+		{"query with stale default timestamp",
+			&message.Query{
+				Query:   "INSERT INTO ks.tbl (pk) VALUES (1)",
+				Options: &message.QueryOptions{DefaultTimestamp: int64Ptr(999)}},
+			NewGenericRequestInfo(forwardToBoth, false, true), 999, true},
+		{"execute with stale literal",
+			&message.Execute{QueryId: []byte("origin"), Options: &message.QueryOptions{}},
+			NewExecuteRequestInfo(preparedData([]int64{1})), 1, true},
+		{"execute with recent default timestamp",
+			&message.Execute{QueryId: []byte("origin"), Options: &message.QueryOptions{DefaultTimestamp: int64Ptr(1000)}},
+			NewExecuteRequestInfo(preparedData(nil)), 0, false},
+		{"batch with stale child literal",
+			&message.Batch{Children: []*message.BatchChild{
+				{Query: "INSERT INTO ks.tbl (pk) VALUES (1)"},
+				{Query: "DELETE FROM ks.tbl USING TIMESTAMP 10 WHERE pk = 2"}}},
+			NewBatchRequestInfo(map[int]PreparedData{}), 10, true},
+		{"batch with stale prepared child",
+			&message.Batch{Children: []*message.BatchChild{{Id: []byte("origin")}}},
+			NewBatchRequestInfo(map[int]PreparedData{0: preparedData([]int64{1})}), 1, true},
+		{"batch with stale default timestamp",
+			&message.Batch{
+				Children:         []*message.BatchChild{{Query: "INSERT INTO ks.tbl (pk) VALUES (1)"}},
+				DefaultTimestamp: int64Ptr(5)},
+			NewBatchRequestInfo(map[int]PreparedData{}), 5, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rawFrame, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 1, tt.msg))
+			require.Nil(t, err)
+			timestamp, found, err := floor.findTimestampBelowFloor(
+				NewFrameDecodeContext(rawFrame), tt.requestInfo, "", &fakeTimeUuidGenerator{})
+			require.Nil(t, err)
+			require.Equal(t, tt.expectedFound, found)
+			require.Equal(t, tt.expectedTimestamp, timestamp)
+		})
+	}
+}
@@ -36,10 +36,19 @@ func getClientSideTlsConfigFromProxyClusterTlsConfig(clusterTlsConfig *common.Cl
 	if err != nil {
 		return nil, err
 	}
-	// currently not supporting server hostname verification for non-Astra clusters
+	// hostnames are only verified if hostname validation is enabled, see getClientSideTlsConfigForHost
 	return getClientSideTlsConfig(serverCAFile, clientCertFile, clientKeyFile, "", "", clusterType)
 }
 
+// getClientSideTlsConfigForHost returns the TLS config used to connect to a node of a non-Astra cluster. If hostname
+// validation is enabled the certificate of the node must be valid for the provided host (DNS name or IP address).
+func getClientSideTlsConfigForHost(tlsConfig *tls.Config, hostnameValidation bool, host string) *tls.Config {
+	if tlsConfig == nil || !hostnameValidation {
+		return tlsConfig
+	}
+	return getClientSideTlsConfigFromParsedCerts(tlsConfig.RootCAs, tlsConfig.Certificates, host, host)
+}
+
 func getClientSideTlsConfig(
 	caCert []byte, cert []byte, key []byte, serverName string, dnsName string, clusterType common.ClusterType) (*tls.Config, error) {
 
@@ -0,0 +1,212 @@
+package zdmproxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/crash"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+const trafficRecorderQueueSize = 1024
+
+// RecordedExchange is a request that a client sent through the proxy, the responses that each cluster returned and the
+// response that the proxy sent back to the client. Frames are encoded with their header and an uncompressed body, the
+// response of a cluster is nil if the request was not sent to that cluster.
+type RecordedExchange struct {
+	Connection     uint64 `json:"connection"`
+	Request        []byte `json:"request"`
+	OriginResponse []byte `json:"origin_response,omitempty"`
+	TargetResponse []byte `json:"target_response,omitempty"`
+	Response       []byte `json:"response"`
+	RequestId      string `json:"request_id,omitempty"`
+}
+
+// TrafficRecorder writes the QUERY, PREPARE, EXECUTE and BATCH requests of the clients and the responses of both
+// clusters to a file, one JSON object per line. A recording of a known good deployment can be replayed against the
+// proxy in tests (see the golden tests of the integration tests) to check that the responses sent to the clients don't
+// change.
+//
+// Requests that time out are not recorded. Exchanges are written in the background, if the writer doesn't keep up the
+// recording stops so that the file never has gaps (e.g. an EXECUTE without the PREPARE of its statement).
+type TrafficRecorder struct {
+	recorded       uint64 // atomic, first field for 64-bit alignment
+	lastConnection uint64 // atomic
+	stopped        uint32 // atomic
+	maxRequests    uint64
+	file           *os.File
+	writer         *bufio.Writer
+	exchanges      chan *RecordedExchange
+	done           chan bool
+	once           *sync.Once
+}
+
+// NewTrafficRecorder returns nil if the file name is empty, exchanges are appended to the provided file until
+// maxRequests requests are recorded (0 means no limit).
+func NewTrafficRecorder(fileName string, maxRequests int) (*TrafficRecorder, error) {
+	if fileName == "" {
+		return nil, nil
+	}
+
+	file, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("could not open traffic recording file %v: %w", fileName, err)
+	}
+	recorder := &TrafficRecorder{
+		maxRequests: uint64(maxRequests),
+		file:        file,
+		writer:      bufio.NewWriter(file),
+		exchanges:   make(chan *RecordedExchange, trafficRecorderQueueSize),
+		done:        make(chan bool),
+		once:        &sync.Once{},
+	}
+	go recorder.run()
+	log.Warnf("Recording client requests and cluster responses to %v, "+
+		"the recording contains the statements, their values and the returned rows.", fileName)
+	return recorder, nil
+}
+
+// NextConnection returns the identifier of a new client connection, it is used to group the recorded exchanges of
+// each connection.
+func (recv *TrafficRecorder) NextConnection() uint64 {
+	if recv == nil {
+		return 0
+	}
+	return atomic.AddUint64(&recv.lastConnection, 1)
+}
+
+// ShouldRecord returns true if a request with the provided opcode should be recorded, it always returns false if the
+// recorder is nil.
+func (recv *TrafficRecorder) ShouldRecord(opCode primitive.OpCode) bool {
+	if recv == nil || atomic.LoadUint32(&recv.stopped) == 1 {
+		return false
+	}
+	switch opCode {
+	case primitive.OpCodeQuery, primitive.OpCodePrepare, primitive.OpCodeExecute, primitive.OpCodeBatch:
+	default:
+		return false
+	}
+	if recv.maxRequests == 0 {
+		return true
+	}
+	recorded := atomic.AddUint64(&recv.recorded, 1)
+	if recorded == recv.maxRequests {
+		log.Infof("Traffic recording reached %d requests, no more requests will be recorded.", recv.maxRequests)
+	}
+	return recorded <= recv.maxRequests
+}
+
+// Record queues the exchange to be written, the recording stops if the queue is full.
+func (recv *TrafficRecorder) Record(exchange *RecordedExchange) {
+	if exchange == nil || atomic.LoadUint32(&recv.stopped) == 1 {
+		return
+	}
+	select {
+	case recv.exchanges <- exchange:
+	default:
+		if atomic.CompareAndSwapUint32(&recv.stopped, 0, 1) {
+			log.Warnf("Traffic recording stopped because requests couldn't be written fast enough.")
+		}
+	}
+}
+
+// Close writes the queued exchanges and closes the file. Must only be called once no more exchanges are recorded.
+func (recv *TrafficRecorder) Close() {
+	if recv == nil {
+		return
+	}
+	recv.once.Do(func() {
+		close(recv.exchanges)
+		<-recv.done
+		if err := recv.file.Close(); err != nil {
+			log.Warnf("Could not close traffic recording file: %v", err)
+		}
+	})
+}
+
+func (recv *TrafficRecorder) run() {
+	defer close(recv.done)
+	defer crash.HandlePanic()
+	encoder := json.NewEncoder(recv.writer)
+	for exchange := range recv.exchanges {
+		err := encoder.Encode(exchange)
+		if err == nil && len(recv.exchanges) == 0 {
+			err = recv.writer.Flush()
+		}
+		if err != nil {
+			log.Warnf("Could not write recorded request: %v", err)
+		}
+	}
+	if err := recv.writer.Flush(); err != nil {
+		log.Warnf("Could not write recorded requests: %v", err)
+	}
+}
+
+// ReadTrafficRecording returns the exchanges of a file written by a TrafficRecorder in the order they were recorded.
+func ReadTrafficRecording(fileName string) ([]*RecordedExchange, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var exchanges []*RecordedExchange
+	decoder := json.NewDecoder(bufio.NewReader(file))
+	for {
+		exchange := &RecordedExchange{}
+		err = decoder.Decode(exchange)
+		if err == io.EOF {
+			return exchanges, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read exchange %d of %v: %w", len(exchanges)+1, fileName, err)
+		}
+		exchanges = append(exchanges, exchange)
+	}
+}
+
+// newRecordedExchange returns the exchange of a request that was just received, it is completed once the request is
+// done. Returns nil if the request can't be encoded.
+func newRecordedExchange(connection uint64, request *frame.RawFrame) *RecordedExchange {
+	encodedRequest, err := encodeRecordedFrame(request)
+	if err != nil {
+		log.Warnf("Could not record request: %v", err)
+		return nil
+	}
+	return &RecordedExchange{Connection: connection, Request: encodedRequest}
+}
+
+// complete adds the responses of the clusters and the response sent to the client, it returns nil if a response can't
+// be encoded.
+func (recv *RecordedExchange) complete(reqCtx *requestContextImpl, response *frame.RawFrame) *RecordedExchange {
+	var err error
+	recv.RequestId = reqCtx.id.String()
+	if recv.OriginResponse, err = encodeRecordedFrame(reqCtx.originResponse); err == nil {
+		if recv.TargetResponse, err = encodeRecordedFrame(reqCtx.targetResponse); err == nil {
+			recv.Response, err = encodeRecordedFrame(response)
+		}
+	}
+	if err != nil {
+		log.Warnf("Could not record response: %v", err)
+		return nil
+	}
+	return recv
+}
+
+func encodeRecordedFrame(f *frame.RawFrame) ([]byte, error) {
+	if f == nil {
+		return nil, nil
+	}
+	buf := &bytes.Buffer{}
+	if err := defaultCodec.EncodeRawFrame(f, buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
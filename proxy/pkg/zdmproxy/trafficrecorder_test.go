@@ -0,0 +1,62 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTrafficRecorder(t *testing.T) {
+	recorder, err := NewTrafficRecorder("", 0)
+	require.Nil(t, err)
+	require.Nil(t, recorder)
+	require.False(t, recorder.ShouldRecord(primitive.OpCodeQuery))
+	require.Equal(t, uint64(0), recorder.NextConnection())
+	recorder.Close()
+
+	fileName := filepath.Join(t.TempDir(), "recording.jsonl")
+	recorder, err = NewTrafficRecorder(fileName, 2)
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), recorder.NextConnection())
+	require.Equal(t, uint64(2), recorder.NextConnection())
+	require.False(t, recorder.ShouldRecord(primitive.OpCodeStartup))
+	require.False(t, recorder.ShouldRecord(primitive.OpCodeOptions))
+
+	request := mustEncodeRawFrame(t, frame.NewFrame(primitive.ProtocolVersion4, 3, &message.Query{Query: "SELECT * FROM ks.tb"}))
+	originResponse := mustEncodeRawFrame(t, frame.NewFrame(primitive.ProtocolVersion4, 10, &message.VoidResult{}))
+	response := mustEncodeRawFrame(t, frame.NewFrame(primitive.ProtocolVersion4, 3, &message.VoidResult{}))
+	reqCtx := NewRequestContext(request, NewGenericRequestInfo(forwardToOrigin, false, true), time.Now(), nil)
+	reqCtx.originResponse = originResponse
+
+	require.True(t, recorder.ShouldRecord(primitive.OpCodeQuery))
+	exchange := newRecordedExchange(2, request)
+	recorder.Record(exchange.complete(reqCtx, response))
+	require.True(t, recorder.ShouldRecord(primitive.OpCodeExecute))
+	// the maximum number of requests was reached
+	require.False(t, recorder.ShouldRecord(primitive.OpCodeBatch))
+	recorder.Close()
+
+	exchanges, err := ReadTrafficRecording(fileName)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(exchanges))
+	require.Equal(t, uint64(2), exchanges[0].Connection)
+	require.Equal(t, reqCtx.id.String(), exchanges[0].RequestId)
+	require.Nil(t, exchanges[0].TargetResponse)
+	for expected, actual := range map[*frame.RawFrame][]byte{
+		request: exchanges[0].Request, originResponse: exchanges[0].OriginResponse, response: exchanges[0].Response} {
+		decoded, err := defaultCodec.DecodeRawFrame(bytes.NewReader(actual))
+		require.Nil(t, err)
+		require.Equal(t, expected, decoded)
+	}
+}
+
+func mustEncodeRawFrame(t *testing.T, f *frame.Frame) *frame.RawFrame {
+	rawFrame, err := defaultCodec.ConvertToRawFrame(f)
+	require.Nil(t, err)
+	return rawFrame
+}
@@ -0,0 +1,51 @@
+package zdmproxy
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// sends on internal channels are expected to be blocked for at most the duration of the request and write timeouts,
+// a send that is blocked for longer than this most likely means that the receiving goroutine is stuck
+const blockedSendReportThreshold = time.Minute
+
+// watchBlockedSend must be called right before a channel send that could not complete immediately, i.e. the send in
+// the default case of a select. The send is reported if it is still blocked after blockedSendReportThreshold.
+// The returned function must be called once the send completes, it returns true if the send was reported.
+//
+//	select {
+//	case channel <- value:
+//	default:
+//		sent := watchBlockedSend("response channel")
+//		channel <- value
+//		sent()
+//	}
+func watchBlockedSend(channelDescription string) func() bool {
+	return watchBlockedSendWithThreshold(channelDescription, blockedSendReportThreshold)
+}
+
+func watchBlockedSendWithThreshold(channelDescription string, threshold time.Duration) func() bool {
+	caller := "unknown"
+	if _, file, line, ok := runtime.Caller(2); ok {
+		caller = fmt.Sprintf("%v:%d", file, line)
+	}
+	start := time.Now()
+	reported := int32(0)
+	timer := time.AfterFunc(threshold, func() {
+		atomic.StoreInt32(&reported, 1)
+		log.Warnf("Send on %v (%v) has been blocked for %v, the receiving goroutine might be stuck.",
+			channelDescription, caller, time.Since(start).Round(time.Millisecond))
+	})
+	return func() bool {
+		timer.Stop()
+		if atomic.LoadInt32(&reported) == 1 {
+			log.Infof("Send on %v (%v) completed after being blocked for %v.",
+				channelDescription, caller, time.Since(start).Round(time.Millisecond))
+			return true
+		}
+		return false
+	}
+}
@@ -0,0 +1,25 @@
+package zdmproxy
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestWatchBlockedSend(t *testing.T) {
+	channel := make(chan int)
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		<-channel
+	}()
+	sent := watchBlockedSendWithThreshold("test channel", 50*time.Millisecond)
+	channel <- 1
+	require.True(t, sent())
+
+	go func() {
+		<-channel
+	}()
+	sent = watchBlockedSendWithThreshold("test channel", time.Second)
+	channel <- 1
+	require.False(t, sent())
+}
@@ -0,0 +1,61 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// SecondaryWriteRetry is the retry policy of the writes that fail on the secondary cluster with a retryable error while
+// they succeed on the primary cluster, see ZDM_SECONDARY_WRITE_RETRY_ATTEMPTS. Without retries such a transient error
+// leaves the two clusters with different data.
+//
+// The failed response of the secondary cluster is held until the response of the primary cluster is received: the
+// write is only retried if it succeeded on the primary cluster. Retries are bound by ZDM_PROXY_REQUEST_TIMEOUT_MS.
+type SecondaryWriteRetry struct {
+	attempts   int
+	backoff    time.Duration
+	errorCodes map[primitive.ErrorCode]bool
+}
+
+// NewSecondaryWriteRetry returns nil if attempts is 0.
+func NewSecondaryWriteRetry(attempts int, backoff time.Duration, errorCodes []primitive.ErrorCode) *SecondaryWriteRetry {
+	if attempts <= 0 {
+		return nil
+	}
+	errorCodesSet := make(map[primitive.ErrorCode]bool, len(errorCodes))
+	for _, errorCode := range errorCodes {
+		errorCodesSet[errorCode] = true
+	}
+	return &SecondaryWriteRetry{
+		attempts:   attempts,
+		backoff:    backoff,
+		errorCodes: errorCodesSet,
+	}
+}
+
+func (recv *SecondaryWriteRetry) String() string {
+	return fmt.Sprintf("SecondaryWriteRetry{attempts=%v, backoff=%v, errorCodes=%v}",
+		recv.attempts, recv.backoff, len(recv.errorCodes))
+}
+
+// isRetryable returns true if the response is an error with one of the retryable error codes.
+func (recv *SecondaryWriteRetry) isRetryable(response *frame.RawFrame) bool {
+	if isResponseSuccessful(response) {
+		return false
+	}
+	errMsg, err := decodeErrorResult(response)
+	if err != nil {
+		log.Debugf("Could not decode error response %v to check whether the write can be retried: %v",
+			response.Header, err)
+		return false
+	}
+	return recv.errorCodes[errMsg.GetErrorCode()]
+}
+
+// getBackoff returns the delay before a retry (starting at 1), the delay doubles with each retry.
+func (recv *SecondaryWriteRetry) getBackoff(retry int) time.Duration {
+	return recv.backoff * time.Duration(1<<(retry-1))
+}
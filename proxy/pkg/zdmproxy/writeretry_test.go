@@ -0,0 +1,88 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestSecondaryWriteRetry_GetBackoff(t *testing.T) {
+	retryPolicy := NewSecondaryWriteRetry(3, 100*time.Millisecond, nil)
+	require.Equal(t, 100*time.Millisecond, retryPolicy.getBackoff(1))
+	require.Equal(t, 200*time.Millisecond, retryPolicy.getBackoff(2))
+	require.Equal(t, 400*time.Millisecond, retryPolicy.getBackoff(3))
+	require.Nil(t, NewSecondaryWriteRetry(0, 100*time.Millisecond, nil))
+}
+
+func TestRequestContext_CheckSecondaryWriteRetry(t *testing.T) {
+	retryPolicy := NewSecondaryWriteRetry(1, time.Millisecond, []primitive.ErrorCode{primitive.ErrorCodeOverloaded})
+	newResponse := func(msg message.Message) *frame.RawFrame {
+		response, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 1, msg))
+		require.Nil(t, err)
+		return response
+	}
+	success := newResponse(&message.VoidResult{})
+	overloaded := newResponse(&message.Overloaded{ErrorMessage: "overloaded"})
+	invalid := newResponse(&message.Invalid{ErrorMessage: "invalid"})
+	newRequestContext := func() *requestContextImpl {
+		request := newResponse(&message.Query{Query: "INSERT INTO ks.tbl (pk) VALUES (1)"})
+		reqCtx := NewRequestContext(request, NewGenericRequestInfo(forwardToBoth, false, true), time.Now(), nil)
+		reqCtx.secondaryRequest = request
+		reqCtx.secondaryCluster = common.ClusterTypeTarget
+		return reqCtx
+	}
+
+	t.Run("primary succeeded first", func(t *testing.T) {
+		reqCtx := newRequestContext()
+		require.False(t, reqCtx.SetResponse(nil, success, common.ClusterTypeOrigin, ClusterConnectorTypeAsync))
+		retry, consumed, secondaryFailure := reqCtx.checkSecondaryWriteRetry(
+			overloaded, common.ClusterTypeTarget, retryPolicy)
+		require.Equal(t, 1, retry)
+		require.True(t, consumed)
+		require.Nil(t, secondaryFailure)
+		// no more attempts
+		retry, consumed, secondaryFailure = reqCtx.checkSecondaryWriteRetry(
+			overloaded, common.ClusterTypeTarget, retryPolicy)
+		require.Equal(t, 0, retry)
+		require.False(t, consumed)
+		require.Nil(t, secondaryFailure)
+	})
+
+	t.Run("secondary failed first", func(t *testing.T) {
+		reqCtx := newRequestContext()
+		retry, consumed, _ := reqCtx.checkSecondaryWriteRetry(overloaded, common.ClusterTypeTarget, retryPolicy)
+		require.Equal(t, 0, retry)
+		require.True(t, consumed)
+		retry, consumed, secondaryFailure := reqCtx.checkSecondaryWriteRetry(success, common.ClusterTypeOrigin, retryPolicy)
+		require.Equal(t, 1, retry)
+		require.False(t, consumed)
+		require.Nil(t, secondaryFailure)
+		require.Nil(t, reqCtx.targetResponse)
+	})
+
+	t.Run("failed on both clusters", func(t *testing.T) {
+		reqCtx := newRequestContext()
+		_, consumed, _ := reqCtx.checkSecondaryWriteRetry(overloaded, common.ClusterTypeTarget, retryPolicy)
+		require.True(t, consumed)
+		retry, consumed, secondaryFailure := reqCtx.checkSecondaryWriteRetry(invalid, common.ClusterTypeOrigin, retryPolicy)
+		require.Equal(t, 0, retry)
+		require.False(t, consumed)
+		require.Same(t, overloaded, secondaryFailure)
+		require.Nil(t, reqCtx.targetResponse)
+		require.False(t, reqCtx.SetResponse(nil, secondaryFailure, common.ClusterTypeTarget, ClusterConnectorTypeAsync))
+		require.True(t, reqCtx.SetResponse(nil, invalid, common.ClusterTypeOrigin, ClusterConnectorTypeAsync))
+		require.Same(t, overloaded, reqCtx.targetResponse)
+	})
+
+	t.Run("not retryable", func(t *testing.T) {
+		reqCtx := newRequestContext()
+		retry, consumed, secondaryFailure := reqCtx.checkSecondaryWriteRetry(invalid, common.ClusterTypeTarget, retryPolicy)
+		require.Equal(t, 0, retry)
+		require.False(t, consumed)
+		require.Nil(t, secondaryFailure)
+	})
+}